@@ -0,0 +1,97 @@
+// Package client is a Go client for the Prompt Security web API.
+//
+// The request and response types and one method per API operation are
+// generated from the server's route registry (see zz_generated.go); this file
+// holds the hand-written transport.
+package client
+
+//go:generate go run ../internal/tools/clientgen -o zz_generated.go
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Client calls the Prompt Security web API
+type Client struct {
+	BaseURL    string       // e.g. http://localhost:8181
+	Token      string       // Bearer token, empty when the API is open
+	HTTPClient *http.Client // Defaults to http.DefaultClient
+}
+
+// New creates a client for the API served at baseURL
+func New(baseURL string) *Client {
+	return &Client{BaseURL: strings.TrimRight(baseURL, "/")}
+}
+
+// Error is returned when the API responds with a non-2xx status. It mirrors
+// the server's JSON error envelope.
+type Error struct {
+	StatusCode int             `json:"-"`
+	Code       string          `json:"code"`
+	Message    string          `json:"message"`
+	Details    json.RawMessage `json:"details,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("prompt-security API error (%d %s): %s", e.StatusCode, e.Code, e.Message)
+}
+
+// do sends a request and decodes the JSON response into out
+func (c *Client) do(ctx context.Context, method, path string, query url.Values, body, out interface{}) error {
+	target := c.BaseURL + path
+	if len(query) > 0 {
+		target += "?" + query.Encode()
+	}
+
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("failed to encode request: %w", err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, target, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.Token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.Token)
+	}
+
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		apiErr := &Error{StatusCode: resp.StatusCode}
+		if json.Unmarshal(msg, apiErr) != nil || apiErr.Message == "" {
+			apiErr.Message = strings.TrimSpace(string(msg))
+		}
+		return apiErr
+	}
+
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode response: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,1099 @@
+// Code generated by clientgen from the web route registry. DO NOT EDIT.
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"net/url"
+	"strconv"
+)
+
+// AppStats mirrors the server's web.AppStats type
+type AppStats struct {
+	App        string         `json:"app"`
+	Detections int            `json:"detections"`
+	Types      map[string]int `json:"types"`
+}
+
+// AuditPage mirrors the server's web.AuditPage type
+type AuditPage struct {
+	Entries    []AuditEntry `json:"entries"`
+	Page       int          `json:"page"`
+	PageSize   int          `json:"pageSize"`
+	TotalCount int          `json:"totalCount"`
+	TotalPages int          `json:"totalPages"`
+}
+
+// BreakerStatus mirrors the server's web.BreakerStatus type
+type BreakerStatus struct {
+	Enabled   bool   `json:"enabled"`
+	Tripped   bool   `json:"tripped"`
+	TrippedAt string `json:"tripped_at,omitempty"`
+	Until     string `json:"until,omitempty"`
+	Recent    int    `json:"recent"`
+}
+
+// Config mirrors the server's db.Config type
+type Config struct {
+	DetectEmails            bool                         `json:"detect_emails"`
+	DetectPhones            bool                         `json:"detect_phones"`
+	DetectCreditCards       bool                         `json:"detect_credit_cards"`
+	DetectSSNs              bool                         `json:"detect_ssns"`
+	DetectIPV4              bool                         `json:"detect_ipv4"`
+	StringMatchPatterns     []StringMatchPattern         `json:"string_match_patterns"`
+	Schedules               []Schedule                   `json:"schedules"`
+	DomainPolicies          []DomainPolicy               `json:"domain_policies"`
+	CustomEmailPattern      string                       `json:"custom_email_pattern"`
+	CustomPhonePattern      string                       `json:"custom_phone_pattern"`
+	CustomCreditCardPattern string                       `json:"custom_credit_card_pattern"`
+	CustomSSNPattern        string                       `json:"custom_ssn_pattern"`
+	CustomIPV4Pattern       string                       `json:"custom_ipv4_pattern"`
+	EmailReplacement        string                       `json:"email_replacement"`
+	PhoneReplacement        string                       `json:"phone_replacement"`
+	CreditCardReplacement   string                       `json:"credit_card_replacement"`
+	SSNReplacement          string                       `json:"ssn_replacement"`
+	IPV4Replacement         string                       `json:"ipv4_replacement"`
+	DetectLocations         bool                         `json:"detect_locations"`
+	LocationReplacement     string                       `json:"location_replacement"`
+	DetectTerraformSecrets  bool                         `json:"detect_terraform_secrets"`
+	TerraformReplacement    string                       `json:"terraform_replacement"`
+	DetectHTTPAuth          bool                         `json:"detect_http_auth"`
+	HTTPAuthReplacement     string                       `json:"http_auth_replacement"`
+	DetectCookies           bool                         `json:"detect_cookies"`
+	CookieReplacement       string                       `json:"cookie_replacement"`
+	SanitizeCurl            bool                         `json:"sanitize_curl"`
+	DetectHomePaths         bool                         `json:"detect_home_paths"`
+	HomePathReplacement     string                       `json:"home_path_replacement"`
+	DetectShellPrompts      bool                         `json:"detect_shell_prompts"`
+	ShellPromptReplacement  string                       `json:"shell_prompt_replacement"`
+	DetectInfrastructureIDs bool                         `json:"detect_infrastructure_ids"`
+	GCPProjectReplacement   string                       `json:"gcp_project_replacement"`
+	AzureIDReplacement      string                       `json:"azure_id_replacement"`
+	DetectLicenseKeys       bool                         `json:"detect_license_keys"`
+	LicenseKeyReplacement   string                       `json:"license_key_replacement"`
+	LicenseKeyFormats       []string                     `json:"license_key_formats"`
+	DetectMACAddresses      bool                         `json:"detect_mac_addresses"`
+	MACReplacement          string                       `json:"mac_replacement"`
+	MACAllowLocal           bool                         `json:"mac_allow_local"`
+	DetectTOTPSecrets       bool                         `json:"detect_totp_secrets"`
+	TOTPReplacement         string                       `json:"totp_replacement"`
+	DetectRecoveryCodes     bool                         `json:"detect_recovery_codes"`
+	RecoveryCodeReplacement string                       `json:"recovery_code_replacement"`
+	DetectPasswords         bool                         `json:"detect_passwords"`
+	PasswordReplacement     string                       `json:"password_replacement"`
+	PhoneLocales            []string                     `json:"phone_locales"`
+	SSNLocales              []string                     `json:"ssn_locales"`
+	ChinaPack               bool                         `json:"china_pack"`
+	EmailPriority           int                          `json:"email_priority"`
+	PhonePriority           int                          `json:"phone_priority"`
+	CreditCardPriority      int                          `json:"credit_card_priority"`
+	SSNPriority             int                          `json:"ssn_priority"`
+	IPV4Priority            int                          `json:"ipv4_priority"`
+	EmailAction             string                       `json:"email_action"`
+	PhoneAction             string                       `json:"phone_action"`
+	CreditCardAction        string                       `json:"credit_card_action"`
+	SSNAction               string                       `json:"ssn_action"`
+	IPV4Action              string                       `json:"ipv4_action"`
+	TOTPAction              string                       `json:"totp_action"`
+	BlockMessage            string                       `json:"block_message"`
+	AskTimeoutSeconds       int                          `json:"ask_timeout_seconds"`
+	EmailSeverity           string                       `json:"email_severity"`
+	PhoneSeverity           string                       `json:"phone_severity"`
+	CreditCardSeverity      string                       `json:"credit_card_severity"`
+	SSNSeverity             string                       `json:"ssn_severity"`
+	IPV4Severity            string                       `json:"ipv4_severity"`
+	TOTPSeverity            string                       `json:"totp_severity"`
+	Policies                map[string]map[string]string `json:"policies"`
+	ReviewThreshold         float64                      `json:"review_threshold"`
+	Allowlist               []string                     `json:"allowlist"`
+	ReviewConfirmed         []string                     `json:"review_confirmed"`
+	MonitoringInterval      int                          `json:"monitoring_interval_ms"`
+	NotifyOnFilter          bool                         `json:"notify_on_filter"`
+	MonitorClipboard        bool                         `json:"monitor_clipboard"`
+	MonitorPrimarySelection bool                         `json:"monitor_primary_selection"`
+	MaxClipboardBytes       int                          `json:"max_clipboard_bytes"`
+	LargeContentMode        string                       `json:"large_content_mode"`
+	ScanTimeoutMs           int                          `json:"scan_timeout_ms"`
+	IncrementalScan         bool                         `json:"incremental_scan"`
+	LogMode                 string                       `json:"log_mode"`
+	FileScanMode            string                       `json:"file_scan_mode"`
+	FileScanMaxBytes        int                          `json:"file_scan_max_bytes"`
+	PromptSafetyMode        string                       `json:"prompt_safety_mode"`
+	DetectSpecialCategories bool                         `json:"detect_special_categories"`
+	ClassifierMode          string                       `json:"classifier_mode"`
+	ClassifierEndpoint      string                       `json:"classifier_endpoint"`
+	BreakerThreshold        int                          `json:"breaker_threshold"`
+	BreakerWindowMinutes    int                          `json:"breaker_window_minutes"`
+	BreakerCooldownMinutes  int                          `json:"breaker_cooldown_minutes"`
+	BreakerWebhook          string                       `json:"breaker_webhook"`
+	ProxyBypass             []string                     `json:"proxy_bypass"`
+	KeyboardProtection      bool                         `json:"keyboard_protection"`
+	KeyboardConsent         bool                         `json:"keyboard_consent"`
+	KeyboardApps            []string                     `json:"keyboard_apps"`
+	UsageReporting          bool                         `json:"usage_reporting"`
+	UsageEndpoint           string                       `json:"usage_endpoint"`
+	ReportRecipients        []string                     `json:"report_recipients"`
+	SMTPHost                string                       `json:"smtp_host"`
+	SMTPPort                int                          `json:"smtp_port"`
+	SMTPFrom                string                       `json:"smtp_from"`
+	SMTPUsername            string                       `json:"smtp_username"`
+	SMTPTLS                 string                       `json:"smtp_tls"`
+	Language                string                       `json:"language"`
+	DisabledPatterns        []string                     `json:"disabled_patterns"`
+	NormalizeDetectors      []string                     `json:"normalize_detectors"`
+}
+
+// DomainPolicy mirrors the server's db.DomainPolicy type
+type DomainPolicy struct {
+	ID        int      `json:"id"`
+	Host      string   `json:"host"`
+	Allow     bool     `json:"allow"`
+	Detectors []string `json:"detectors"`
+}
+
+// EditorRedactRequest mirrors the server's web.EditorRedactRequest type
+type EditorRedactRequest struct {
+	URI       string  `json:"uri"`
+	Session   string  `json:"session,omitempty"`
+	Text      *string `json:"text,omitempty"`
+	Selection *Range  `json:"selection,omitempty"`
+}
+
+// EditorRedactResponse mirrors the server's web.EditorRedactResponse type
+type EditorRedactResponse struct {
+	Version int        `json:"version"`
+	Text    string     `json:"text"`
+	Edits   []TextEdit `json:"edits"`
+	Blocked bool       `json:"blocked"`
+}
+
+// EditorScanRequest mirrors the server's web.EditorScanRequest type
+type EditorScanRequest struct {
+	URI         string       `json:"uri"`
+	Session     string       `json:"session,omitempty"`
+	Version     int          `json:"version"`
+	Text        *string      `json:"text,omitempty"`
+	BaseVersion int          `json:"base_version,omitempty"`
+	Changes     []TextChange `json:"changes,omitempty"`
+	Selection   *Range       `json:"selection,omitempty"`
+}
+
+// EditorScanResponse mirrors the server's web.EditorScanResponse type
+type EditorScanResponse struct {
+	URI      string          `json:"uri"`
+	Version  int             `json:"version"`
+	Findings []EditorFinding `json:"findings"`
+}
+
+// FilterRequest mirrors the server's web.FilterRequest type
+type FilterRequest struct {
+	Text string `json:"text"`
+}
+
+// FilterResponse mirrors the server's web.FilterResponse type
+type FilterResponse struct {
+	Filtered     string        `json:"filtered"`
+	Changed      bool          `json:"changed"`
+	Action       string        `json:"action"`
+	Replacements []Replacement `json:"replacements"`
+}
+
+// HeldCopy mirrors the server's web.HeldCopy type
+type HeldCopy struct {
+	ID        string   `json:"id"`
+	Source    string   `json:"source"`
+	Types     []string `json:"types"`
+	Filtered  string   `json:"filtered"`
+	ExpiresAt string   `json:"expires_at"`
+}
+
+// ImportResult mirrors the server's config.ImportResult type
+type ImportResult struct {
+	DryRun     bool                 `json:"dry_run"`
+	Imported   []StringMatchPattern `json:"imported"`
+	Duplicates []string             `json:"duplicates"`
+	Errors     []ImportError        `json:"errors"`
+}
+
+// KeyboardStatus mirrors the server's web.KeyboardStatus type
+type KeyboardStatus struct {
+	Enabled bool   `json:"enabled"`
+	Active  bool   `json:"active"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Labels mirrors the server's web.Labels type
+type Labels struct {
+	Language string            `json:"language"`
+	Types    map[string]string `json:"types"`
+}
+
+// LogDetail mirrors the server's db.LogDetail type
+type LogDetail struct {
+	ID           int        `json:"id"`
+	Timestamp    string     `json:"timestamp"`
+	OriginalText string     `json:"original"`
+	FilteredText string     `json:"filtered"`
+	Detections   []string   `json:"detections"`
+	Count        int        `json:"count"`
+	LastSeen     string     `json:"last_seen"`
+	Hashed       bool       `json:"hashed"`
+	Kind         string     `json:"kind"`
+	RiskLabel    string     `json:"risk_label"`
+	RiskScore    float64    `json:"risk_score"`
+	App          string     `json:"app"`
+	Matches      []LogMatch `json:"matches"`
+}
+
+// LogsPage mirrors the server's web.LogsPage type
+type LogsPage struct {
+	Logs       []LogEntry `json:"logs"`
+	Page       int        `json:"page"`
+	PageSize   int        `json:"pageSize"`
+	TotalCount int        `json:"totalCount"`
+	TotalPages int        `json:"totalPages"`
+}
+
+// MonitorHealth mirrors the server's web.MonitorHealth type
+type MonitorHealth struct {
+	Status            string `json:"status"`
+	Running           bool   `json:"running"`
+	LastHeartbeat     string `json:"last_heartbeat,omitempty"`
+	Restarts          int    `json:"restarts"`
+	LastPanic         string `json:"last_panic,omitempty"`
+	ConsecutiveErrors int    `json:"consecutive_errors"`
+	LastError         string `json:"last_error,omitempty"`
+	BackoffMs         int64  `json:"backoff_ms"`
+}
+
+// MonitorStatus mirrors the server's web.MonitorStatus type
+type MonitorStatus struct {
+	Running  bool   `json:"running"`
+	Paused   bool   `json:"paused"`
+	Profile  string `json:"profile"`
+	Schedule string `json:"schedule,omitempty"`
+}
+
+// PatternImportRequest mirrors the server's web.PatternImportRequest type
+type PatternImportRequest struct {
+	Content         string `json:"content"`
+	Format          string `json:"format"`
+	DryRun          bool   `json:"dry_run"`
+	Name            string `json:"name"`
+	Replacement     string `json:"replacement"`
+	CaseInsensitive bool   `json:"case_insensitive"`
+	WholeWord       bool   `json:"whole_word"`
+	Fuzzy           bool   `json:"fuzzy"`
+	Action          string `json:"action"`
+	Severity        string `json:"severity"`
+}
+
+// ReplayReport mirrors the server's bench.ReplayReport type
+type ReplayReport struct {
+	Scanned int            `json:"scanned"`
+	Skipped int            `json:"skipped"`
+	Changed int            `json:"changed"`
+	Before  map[string]int `json:"before"`
+	After   map[string]int `json:"after"`
+	Entries []ReplayEntry  `json:"entries"`
+}
+
+// ReplayRequest mirrors the server's web.ReplayRequest type
+type ReplayRequest struct {
+	Limit  int     `json:"limit,omitempty"`
+	Config *Config `json:"config,omitempty"`
+}
+
+// Report mirrors the server's usage.Report type
+type Report struct {
+	Version string `json:"version"`
+	Days    []Day  `json:"days"`
+}
+
+// ReviewItem mirrors the server's db.ReviewItem type
+type ReviewItem struct {
+	ID         int     `json:"id"`
+	Type       string  `json:"type"`
+	Value      string  `json:"value"`
+	Confidence float64 `json:"confidence"`
+	Status     string  `json:"status"`
+	Count      int     `json:"count"`
+	CreatedAt  string  `json:"created_at"`
+	LastSeen   string  `json:"last_seen"`
+	DecidedBy  string  `json:"decided_by,omitempty"`
+	DecidedAt  string  `json:"decided_at,omitempty"`
+}
+
+// ReviewPage mirrors the server's web.ReviewPage type
+type ReviewPage struct {
+	Items      []ReviewItem `json:"items"`
+	Page       int          `json:"page"`
+	PageSize   int          `json:"pageSize"`
+	TotalCount int          `json:"totalCount"`
+	TotalPages int          `json:"totalPages"`
+}
+
+// SetupRequest mirrors the server's web.SetupRequest type
+type SetupRequest struct {
+	Detectors  []string `json:"detectors"`
+	Strategy   string   `json:"strategy"`
+	AdminToken string   `json:"admin_token,omitempty"`
+}
+
+// SetupResult mirrors the server's web.SetupResult type
+type SetupResult struct {
+	Config Config `json:"config"`
+	Token  string `json:"token,omitempty"`
+}
+
+// SetupStatus mirrors the server's web.SetupStatus type
+type SetupStatus struct {
+	Required         bool     `json:"required"`
+	Completed        bool     `json:"completed"`
+	CompletedAt      string   `json:"completed_at,omitempty"`
+	ConfigChanged    bool     `json:"config_changed"`
+	TokensConfigured bool     `json:"tokens_configured"`
+	Detectors        []string `json:"detectors"`
+	DefaultDetectors []string `json:"default_detectors"`
+	Strategies       []string `json:"strategies"`
+	DefaultStrategy  string   `json:"default_strategy"`
+}
+
+// StatsSummary mirrors the server's web.StatsSummary type
+type StatsSummary struct {
+	DetectionsToday int    `json:"detections_today"`
+	LeaksToday      int    `json:"leaks_today"`
+	LastEventAt     string `json:"last_event_at,omitempty"`
+	Monitor         string `json:"monitor"`
+	Health          string `json:"health,omitempty"`
+	Profile         string `json:"profile"`
+	Lockdown        bool   `json:"lockdown"`
+}
+
+// StatusResponse mirrors the server's web.StatusResponse type
+type StatusResponse struct {
+	Status string `json:"status"`
+}
+
+// StringMatchPattern mirrors the server's db.StringMatchPattern type
+type StringMatchPattern struct {
+	ID              int    `json:"id"`
+	Name            string `json:"name"`
+	Pattern         string `json:"pattern"`
+	Enabled         bool   `json:"enabled"`
+	Replacement     string `json:"replacement"`
+	CaseInsensitive bool   `json:"case_insensitive"`
+	WholeWord       bool   `json:"whole_word"`
+	Fuzzy           bool   `json:"fuzzy"`
+	Normalize       bool   `json:"normalize"`
+	Priority        int    `json:"priority"`
+	Action          string `json:"action"`
+	Severity        string `json:"severity"`
+}
+
+// Summary mirrors the server's report.Summary type
+type Summary struct {
+	Period             string         `json:"period"`
+	Start              string         `json:"start"`
+	End                string         `json:"end"`
+	Detections         int            `json:"detections"`
+	Leaks              int            `json:"leaks"`
+	PreviousDetections int            `json:"previous_detections"`
+	Types              []NamedCount   `json:"types"`
+	Apps               []NamedCount   `json:"apps"`
+	Days               []DayCount     `json:"days"`
+	Changes            []PolicyChange `json:"changes"`
+}
+
+// ValidateRequest mirrors the server's web.ValidateRequest type
+type ValidateRequest struct {
+	UID      string            `json:"uid,omitempty"`
+	Name     string            `json:"name,omitempty"`
+	Content  string            `json:"content,omitempty"`
+	Encoding string            `json:"encoding,omitempty"`
+	Files    []ValidateFile    `json:"files,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+}
+
+// ValidateResponse mirrors the server's web.ValidateResponse type
+type ValidateResponse struct {
+	UID        string            `json:"uid,omitempty"`
+	Allowed    bool              `json:"allowed"`
+	Reason     string            `json:"reason,omitempty"`
+	Findings   []ValidateFinding `json:"findings"`
+	Metadata   map[string]string `json:"metadata,omitempty"`
+	Suppressed []ValidateFinding `json:"suppressed,omitempty"`
+}
+
+// ValidationResult mirrors the server's web.ValidationResult type
+type ValidationResult struct {
+	Valid  bool         `json:"valid"`
+	Errors []FieldError `json:"errors"`
+}
+
+// AuditEntry mirrors the server's db.AuditEntry type
+type AuditEntry struct {
+	ID        int             `json:"id"`
+	Timestamp string          `json:"timestamp"`
+	Actor     string          `json:"actor"`
+	Action    string          `json:"action"`
+	OldValue  json.RawMessage `json:"old"`
+	NewValue  json.RawMessage `json:"new"`
+	Changes   []AuditChange   `json:"changes"`
+}
+
+// Day mirrors the server's usage.Day type
+type Day struct {
+	Date       string         `json:"date"`
+	Detections map[string]int `json:"detections"`
+}
+
+// DayCount mirrors the server's report.DayCount type
+type DayCount struct {
+	Date       string `json:"date"`
+	Detections int    `json:"detections"`
+	Leaks      int    `json:"leaks"`
+}
+
+// EditorFinding mirrors the server's web.EditorFinding type
+type EditorFinding struct {
+	Range       Range  `json:"range"`
+	Type        string `json:"type"`
+	Action      string `json:"action"`
+	Replacement string `json:"replacement,omitempty"`
+}
+
+// FieldError mirrors the server's config.FieldError type
+type FieldError struct {
+	Field   string `json:"field"`
+	Message string `json:"message"`
+}
+
+// ImportError mirrors the server's config.ImportError type
+type ImportError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// LogEntry mirrors the server's db.LogEntry type
+type LogEntry struct {
+	ID           int      `json:"id"`
+	Timestamp    string   `json:"timestamp"`
+	OriginalText string   `json:"original"`
+	FilteredText string   `json:"filtered"`
+	Detections   []string `json:"detections"`
+	Count        int      `json:"count"`
+	LastSeen     string   `json:"last_seen"`
+	Hashed       bool     `json:"hashed"`
+	Kind         string   `json:"kind"`
+	RiskLabel    string   `json:"risk_label"`
+	RiskScore    float64  `json:"risk_score"`
+	App          string   `json:"app"`
+}
+
+// LogMatch mirrors the server's db.LogMatch type
+type LogMatch struct {
+	Type          string `json:"type"`
+	Start         int    `json:"start"`
+	End           int    `json:"end"`
+	FilteredStart int    `json:"filtered_start"`
+	FilteredEnd   int    `json:"filtered_end"`
+}
+
+// NamedCount mirrors the server's report.NamedCount type
+type NamedCount struct {
+	Name       string `json:"name"`
+	Detections int    `json:"detections"`
+}
+
+// PolicyChange mirrors the server's report.PolicyChange type
+type PolicyChange struct {
+	Time   string   `json:"time"`
+	Actor  string   `json:"actor"`
+	Action string   `json:"action"`
+	Fields []string `json:"fields"`
+}
+
+// Range mirrors the server's editor.Range type
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// Replacement mirrors the server's web.Replacement type
+type Replacement struct {
+	Type        string `json:"type"`
+	Original    string `json:"original"`
+	Replacement string `json:"replacement"`
+	Action      string `json:"action"`
+}
+
+// ReplayEntry mirrors the server's bench.ReplayEntry type
+type ReplayEntry struct {
+	ID        int      `json:"id"`
+	Timestamp string   `json:"timestamp"`
+	Before    []string `json:"before"`
+	After     []string `json:"after"`
+	Filtered  string   `json:"filtered"`
+	Changed   bool     `json:"changed"`
+}
+
+// Schedule mirrors the server's db.Schedule type
+type Schedule struct {
+	Name    string   `json:"name"`
+	Days    []string `json:"days"`
+	Start   string   `json:"start"`
+	End     string   `json:"end"`
+	Profile string   `json:"profile"`
+	Enabled bool     `json:"enabled"`
+}
+
+// TextChange mirrors the server's editor.TextChange type
+type TextChange struct {
+	Range *Range `json:"range,omitempty"`
+	Text  string `json:"text"`
+}
+
+// TextEdit mirrors the server's editor.TextEdit type
+type TextEdit struct {
+	Range   Range  `json:"range"`
+	NewText string `json:"newText"`
+}
+
+// ValidateFile mirrors the server's web.ValidateFile type
+type ValidateFile struct {
+	Name     string `json:"name,omitempty"`
+	Content  string `json:"content"`
+	Encoding string `json:"encoding,omitempty"`
+}
+
+// ValidateFinding mirrors the server's web.ValidateFinding type
+type ValidateFinding struct {
+	File   string `json:"file,omitempty"`
+	Path   string `json:"path,omitempty"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	Type   string `json:"type"`
+	Action string `json:"action"`
+}
+
+// AuditChange mirrors the server's db.AuditChange type
+type AuditChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+// Position mirrors the server's editor.Position type
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// GetConfig calls GET /api/v1/config (requires role admin).
+//
+// Get the current configuration, including the breaker webhook, allowlist and confirmed values.
+func (c *Client) GetConfig(ctx context.Context) (*Config, error) {
+	var out Config
+	if err := c.do(ctx, "GET", "/api/v1/config", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// UpdateConfig calls POST /api/v1/config (requires role admin).
+//
+// Replace the configuration.
+func (c *Client) UpdateConfig(ctx context.Context, body Config) (*StatusResponse, error) {
+	var out StatusResponse
+	if err := c.do(ctx, "POST", "/api/v1/config", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ValidateConfig calls POST /api/v1/config/validate (requires role viewer).
+//
+// Validate a configuration without saving it.
+func (c *Client) ValidateConfig(ctx context.Context, body Config) (*ValidationResult, error) {
+	var out ValidationResult
+	if err := c.do(ctx, "POST", "/api/v1/config/validate", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListPatterns calls GET /api/v1/patterns (requires role admin).
+//
+// List string match patterns.
+func (c *Client) ListPatterns(ctx context.Context) ([]StringMatchPattern, error) {
+	var out []StringMatchPattern
+	if err := c.do(ctx, "GET", "/api/v1/patterns", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SavePattern calls POST /api/v1/patterns (requires role admin).
+//
+// Create (id 0) or update a string match pattern.
+func (c *Client) SavePattern(ctx context.Context, body StringMatchPattern) (*StringMatchPattern, error) {
+	var out StringMatchPattern
+	if err := c.do(ctx, "POST", "/api/v1/patterns", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeletePatternParams holds the query parameters for DeletePattern
+type DeletePatternParams struct {
+	ID int // Pattern ID
+}
+
+// DeletePattern calls DELETE /api/v1/patterns (requires role admin).
+//
+// Delete a string match pattern.
+func (c *Client) DeletePattern(ctx context.Context, params DeletePatternParams) (*StatusResponse, error) {
+	q := url.Values{}
+	if params.ID != 0 {
+		q.Set("id", strconv.Itoa(params.ID))
+	}
+	var out StatusResponse
+	if err := c.do(ctx, "DELETE", "/api/v1/patterns", q, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ImportPatterns calls POST /api/v1/patterns/import (requires role admin).
+//
+// Add the literal terms of a CSV or TSV dictionary as string match patterns, skipping duplicates, or preview them on a dry run.
+func (c *Client) ImportPatterns(ctx context.Context, body PatternImportRequest) (*ImportResult, error) {
+	var out ImportResult
+	if err := c.do(ctx, "POST", "/api/v1/patterns/import", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListDomainPolicies calls GET /api/v1/domain-policies (requires role viewer).
+//
+// List the policies for traffic by destination host in gateway and proxy modes.
+func (c *Client) ListDomainPolicies(ctx context.Context) ([]DomainPolicy, error) {
+	var out []DomainPolicy
+	if err := c.do(ctx, "GET", "/api/v1/domain-policies", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// SaveDomainPolicy calls POST /api/v1/domain-policies (requires role admin).
+//
+// Create (id 0) or update a domain policy.
+func (c *Client) SaveDomainPolicy(ctx context.Context, body DomainPolicy) (*DomainPolicy, error) {
+	var out DomainPolicy
+	if err := c.do(ctx, "POST", "/api/v1/domain-policies", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// DeleteDomainPolicyParams holds the query parameters for DeleteDomainPolicy
+type DeleteDomainPolicyParams struct {
+	ID int // Domain policy ID
+}
+
+// DeleteDomainPolicy calls DELETE /api/v1/domain-policies (requires role admin).
+//
+// Delete a domain policy.
+func (c *Client) DeleteDomainPolicy(ctx context.Context, params DeleteDomainPolicyParams) (*StatusResponse, error) {
+	q := url.Values{}
+	if params.ID != 0 {
+		q.Set("id", strconv.Itoa(params.ID))
+	}
+	var out StatusResponse
+	if err := c.do(ctx, "DELETE", "/api/v1/domain-policies", q, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Filter calls POST /api/v1/filter (requires role viewer).
+//
+// Filter text with the current configuration.
+func (c *Client) Filter(ctx context.Context, body FilterRequest) (*FilterResponse, error) {
+	var out FilterResponse
+	if err := c.do(ctx, "POST", "/api/v1/filter", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// Validate calls POST /api/v1/validate (requires role viewer).
+//
+// Allow or deny content against the policy, e.g. prompt templates in CI; also accepts a Kubernetes AdmissionReview.
+func (c *Client) Validate(ctx context.Context, body ValidateRequest) (*ValidateResponse, error) {
+	var out ValidateResponse
+	if err := c.do(ctx, "POST", "/api/v1/validate", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// EditorScan calls POST /api/v1/editor/scan (requires role viewer).
+//
+// Find sensitive data in a document open in an editor, rescanning only the lines around changes since its last scan.
+func (c *Client) EditorScan(ctx context.Context, body EditorScanRequest) (*EditorScanResponse, error) {
+	var out EditorScanResponse
+	if err := c.do(ctx, "POST", "/api/v1/editor/scan", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// EditorRedact calls POST /api/v1/editor/redact (requires role viewer).
+//
+// Redact a selection or document open in an editor, returning the redacted text and the edits.
+func (c *Client) EditorRedact(ctx context.Context, body EditorRedactRequest) (*EditorRedactResponse, error) {
+	var out EditorRedactResponse
+	if err := c.do(ctx, "POST", "/api/v1/editor/redact", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListLogsParams holds the query parameters for ListLogs
+type ListLogsParams struct {
+	Page     int // Page number starting at 1
+	PageSize int // Number of entries per page
+}
+
+// ListLogs calls GET /api/v1/logs (requires role viewer).
+//
+// List filter logs, newest first.
+func (c *Client) ListLogs(ctx context.Context, params ListLogsParams) (*LogsPage, error) {
+	q := url.Values{}
+	if params.Page != 0 {
+		q.Set("page", strconv.Itoa(params.Page))
+	}
+	if params.PageSize != 0 {
+		q.Set("pageSize", strconv.Itoa(params.PageSize))
+	}
+	var out LogsPage
+	if err := c.do(ctx, "GET", "/api/v1/logs", q, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetLog calls GET /api/v1/logs/{id} (requires role viewer).
+//
+// Get a filter log with the offsets of every replacement.
+func (c *Client) GetLog(ctx context.Context, id int) (*LogDetail, error) {
+	var out LogDetail
+	if err := c.do(ctx, "GET", "/api/v1/logs/"+strconv.Itoa(id), nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ReplayLogs calls POST /api/v1/logs/replay (requires role viewer).
+//
+// Re-run logged copies through the current, or a candidate, configuration.
+func (c *Client) ReplayLogs(ctx context.Context, body ReplayRequest) (*ReplayReport, error) {
+	var out ReplayReport
+	if err := c.do(ctx, "POST", "/api/v1/logs/replay", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ClearLogs calls POST /api/v1/logs/clear (requires role admin).
+//
+// Delete all filter logs.
+func (c *Client) ClearLogs(ctx context.Context) (*StatusResponse, error) {
+	var out StatusResponse
+	if err := c.do(ctx, "POST", "/api/v1/logs/clear", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListReviewParams holds the query parameters for ListReview
+type ListReviewParams struct {
+	Status   string // pending (default), confirmed or ignored
+	Page     int    // Page number starting at 1
+	PageSize int    // Number of entries per page
+}
+
+// ListReview calls GET /api/v1/review (requires role viewer).
+//
+// List uncertain matches by review status, newest first.
+func (c *Client) ListReview(ctx context.Context, params ListReviewParams) (*ReviewPage, error) {
+	q := url.Values{}
+	if params.Status != "" {
+		q.Set("status", params.Status)
+	}
+	if params.Page != 0 {
+		q.Set("page", strconv.Itoa(params.Page))
+	}
+	if params.PageSize != 0 {
+		q.Set("pageSize", strconv.Itoa(params.PageSize))
+	}
+	var out ReviewPage
+	if err := c.do(ctx, "GET", "/api/v1/review", q, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ConfirmReview calls POST /api/v1/review/{id}/confirm (requires role admin).
+//
+// Confirm an uncertain match as sensitive so its value is always acted upon.
+func (c *Client) ConfirmReview(ctx context.Context, id int) (*ReviewItem, error) {
+	var out ReviewItem
+	if err := c.do(ctx, "POST", "/api/v1/review/"+strconv.Itoa(id)+"/confirm", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// IgnoreReview calls POST /api/v1/review/{id}/ignore (requires role admin).
+//
+// Mark an uncertain match as not sensitive and allowlist its value.
+func (c *Client) IgnoreReview(ctx context.Context, id int) (*ReviewItem, error) {
+	var out ReviewItem
+	if err := c.do(ctx, "POST", "/api/v1/review/"+strconv.Itoa(id)+"/ignore", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListAuditParams holds the query parameters for ListAudit
+type ListAuditParams struct {
+	Page     int // Page number starting at 1
+	PageSize int // Number of entries per page
+}
+
+// ListAudit calls GET /api/v1/audit (requires role admin).
+//
+// List config audit entries, newest first.
+func (c *Client) ListAudit(ctx context.Context, params ListAuditParams) (*AuditPage, error) {
+	q := url.Values{}
+	if params.Page != 0 {
+		q.Set("page", strconv.Itoa(params.Page))
+	}
+	if params.PageSize != 0 {
+		q.Set("pageSize", strconv.Itoa(params.PageSize))
+	}
+	var out AuditPage
+	if err := c.do(ctx, "GET", "/api/v1/audit", q, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetMonitorStatus calls GET /api/v1/monitor (requires role viewer).
+//
+// Get clipboard monitor status.
+func (c *Client) GetMonitorStatus(ctx context.Context) (*MonitorStatus, error) {
+	var out MonitorStatus
+	if err := c.do(ctx, "GET", "/api/v1/monitor", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetMonitorHealth calls GET /api/v1/monitor/status (requires role viewer).
+//
+// Get clipboard monitor health.
+func (c *Client) GetMonitorHealth(ctx context.Context) (*MonitorHealth, error) {
+	var out MonitorHealth
+	if err := c.do(ctx, "GET", "/api/v1/monitor/status", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// PauseMonitor calls POST /api/v1/monitor/pause (requires role operator).
+//
+// Pause clipboard filtering.
+func (c *Client) PauseMonitor(ctx context.Context) (*MonitorStatus, error) {
+	var out MonitorStatus
+	if err := c.do(ctx, "POST", "/api/v1/monitor/pause", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ResumeMonitor calls POST /api/v1/monitor/resume (requires role operator).
+//
+// Resume clipboard filtering.
+func (c *Client) ResumeMonitor(ctx context.Context) (*MonitorStatus, error) {
+	var out MonitorStatus
+	if err := c.do(ctx, "POST", "/api/v1/monitor/resume", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ListHolds calls GET /api/v1/monitor/holds (requires role viewer).
+//
+// List redacted copies whose original may be restored.
+func (c *Client) ListHolds(ctx context.Context) ([]HeldCopy, error) {
+	var out []HeldCopy
+	if err := c.do(ctx, "GET", "/api/v1/monitor/holds", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// RestoreHold calls POST /api/v1/monitor/holds/{id}/restore (requires role operator).
+//
+// Put the original of a redacted copy back on the clipboard.
+func (c *Client) RestoreHold(ctx context.Context, id string) (*StatusResponse, error) {
+	var out StatusResponse
+	if err := c.do(ctx, "POST", "/api/v1/monitor/holds/"+url.PathEscape(id)+"/restore", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetKeyboardStatus calls GET /api/v1/keyboard (requires role viewer).
+//
+// Get keyboard protection status.
+func (c *Client) GetKeyboardStatus(ctx context.Context) (*KeyboardStatus, error) {
+	var out KeyboardStatus
+	if err := c.do(ctx, "GET", "/api/v1/keyboard", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetBreakerStatus calls GET /api/v1/breaker (requires role viewer).
+//
+// Get whether the circuit breaker tripped and the high severity detections counted in its window.
+func (c *Client) GetBreakerStatus(ctx context.Context) (*BreakerStatus, error) {
+	var out BreakerStatus
+	if err := c.do(ctx, "GET", "/api/v1/breaker", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// ResetBreaker calls POST /api/v1/breaker/reset (requires role admin).
+//
+// Close a tripped circuit breaker, restoring the configured detector actions.
+func (c *Client) ResetBreaker(ctx context.Context) (*BreakerStatus, error) {
+	var out BreakerStatus
+	if err := c.do(ctx, "POST", "/api/v1/breaker/reset", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetUsageReport calls GET /api/v1/usage (requires role viewer).
+//
+// Preview the anonymous usage report sent next: detection counts per type for finished days.
+func (c *Client) GetUsageReport(ctx context.Context) (*Report, error) {
+	var out Report
+	if err := c.do(ctx, "GET", "/api/v1/usage", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetStatsSummary calls GET /api/v1/stats/summary (requires role viewer).
+//
+// Get today's detection count, the last event time and the monitor state, cheap enough to poll.
+func (c *Client) GetStatsSummary(ctx context.Context) (*StatsSummary, error) {
+	var out StatsSummary
+	if err := c.do(ctx, "GET", "/api/v1/stats/summary", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetAppStats calls GET /api/v1/stats/apps (requires role viewer).
+//
+// Get the detections in prompts per source application, most first.
+func (c *Client) GetAppStats(ctx context.Context) ([]AppStats, error) {
+	var out []AppStats
+	if err := c.do(ctx, "GET", "/api/v1/stats/apps", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// GetLabels calls GET /api/v1/labels (requires role viewer).
+//
+// Get the display names of the detection types in the language of the request.
+func (c *Client) GetLabels(ctx context.Context) (*Labels, error) {
+	var out Labels
+	if err := c.do(ctx, "GET", "/api/v1/labels", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetReportParams holds the query parameters for GetReport
+type GetReportParams struct {
+	Period string // day or week (default week)
+	Until  string // Last day of the period as YYYY-MM-DD (default today)
+	Format string // json, markdown or html (default json)
+}
+
+// GetReport calls GET /api/v1/reports (requires role viewer).
+//
+// Summarize the detections, top types and applications, trend and policy changes of a day or week.
+func (c *Client) GetReport(ctx context.Context, params GetReportParams) (*Summary, error) {
+	q := url.Values{}
+	if params.Period != "" {
+		q.Set("period", params.Period)
+	}
+	if params.Until != "" {
+		q.Set("until", params.Until)
+	}
+	if params.Format != "" {
+		q.Set("format", params.Format)
+	}
+	var out Summary
+	if err := c.do(ctx, "GET", "/api/v1/reports", q, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetSetupStatus calls GET /api/v1/setup (requires role viewer).
+//
+// Get whether first-run setup is needed and the choices it offers.
+func (c *Client) GetSetupStatus(ctx context.Context) (*SetupStatus, error) {
+	var out SetupStatus
+	if err := c.do(ctx, "GET", "/api/v1/setup", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// CompleteSetup calls POST /api/v1/setup (requires role admin).
+//
+// Choose the detectors and replacement strategy, optionally create an admin token, and mark setup complete.
+func (c *Client) CompleteSetup(ctx context.Context, body SetupRequest) (*SetupResult, error) {
+	var out SetupResult
+	if err := c.do(ctx, "POST", "/api/v1/setup", nil, body, &out); err != nil {
+		return nil, err
+	}
+	return &out, nil
+}
+
+// GetOpenAPI calls GET /api/v1/openapi.json (requires role viewer).
+//
+// Get the OpenAPI document for this API.
+func (c *Client) GetOpenAPI(ctx context.Context) (map[string]interface{}, error) {
+	var out map[string]interface{}
+	if err := c.do(ctx, "GET", "/api/v1/openapi.json", nil, nil, &out); err != nil {
+		return nil, err
+	}
+	return out, nil
+}
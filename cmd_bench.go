@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"text/tabwriter"
+
+	"github.com/happytaoer/prompt-security/internal/bench"
+	"github.com/happytaoer/prompt-security/internal/config"
+	"github.com/happytaoer/prompt-security/internal/filter"
+	"github.com/spf13/cobra"
+)
+
+// newBenchCmd creates the `bench` command for measuring detection quality
+func newBenchCmd() *cobra.Command {
+	benchCmd := &cobra.Command{
+		Use:   "bench",
+		Short: "Measure detection quality",
+	}
+
+	accuracyCmd := &cobra.Command{
+		Use:   "accuracy [corpus.jsonl...]",
+		Short: "Report precision and recall per detector",
+		Long: `Run the detectors, as currently configured, against the bundled labeled corpus
+and any given corpora, and report precision and recall per detector.
+
+A corpus has one JSON sample per line listing the sensitive values it contains:
+
+  {"text": "mail a@example.com", "labels": [{"type": "email", "value": "a@example.com"}]}`,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			bundled, _ := cmd.Flags().GetBool("bundled")
+			profile, _ := cmd.Flags().GetString("profile")
+			asJSON, _ := cmd.Flags().GetBool("json")
+			if !config.ValidProfile(profile) {
+				return fmt.Errorf("unknown profile %q, expected %s, %s or %s", profile, config.ProfileStandard, config.ProfileStrict, config.ProfileOff)
+			}
+
+			var samples []bench.Sample
+			if bundled {
+				s, err := bench.Bundled()
+				if err != nil {
+					return err
+				}
+				samples = append(samples, s...)
+			}
+			for _, path := range args {
+				s, err := bench.Load(path)
+				if err != nil {
+					return err
+				}
+				samples = append(samples, s...)
+			}
+			if len(samples) == 0 {
+				return fmt.Errorf("no samples, pass a corpus or drop --bundled=false")
+			}
+
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+			scores := bench.Evaluate(filter.NewDetectorSet(config.ApplyProfile(cfg, profile)), samples)
+
+			if asJSON {
+				return printScoresJSON(scores, len(samples))
+			}
+			return printScores(scores, len(samples))
+		},
+	}
+	accuracyCmd.Flags().Bool("bundled", true, "Include the bundled corpus")
+	accuracyCmd.Flags().String("profile", config.ProfileStandard, "Detection profile to apply (standard, strict, off)")
+	accuracyCmd.Flags().Bool("json", false, "Print the report as JSON")
+
+	benchCmd.AddCommand(accuracyCmd)
+	return benchCmd
+}
+
+// printScores prints an accuracy report as a table
+func printScores(scores []bench.Score, samples int) error {
+	fmt.Printf("%d samples\n\n", samples)
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "DETECTOR\tTP\tFP\tFN\tPRECISION\tRECALL")
+	for _, s := range append(scores, bench.Total(scores)) {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t%d\t%s\t%s\n", s.Type, s.TruePositives, s.FalsePositives, s.FalseNegatives, percent(s.Precision()), percent(s.Recall()))
+	}
+	return tw.Flush()
+}
+
+// printScoresJSON prints an accuracy report as JSON, with null for
+// undefined precision or recall
+func printScoresJSON(scores []bench.Score, samples int) error {
+	type row struct {
+		bench.Score
+		Precision *float64 `json:"precision"`
+		Recall    *float64 `json:"recall"`
+	}
+	report := struct {
+		Samples   int   `json:"samples"`
+		Detectors []row `json:"detectors"`
+		Total     row   `json:"total"`
+	}{Samples: samples}
+
+	toRow := func(s bench.Score) row {
+		return row{Score: s, Precision: defined(s.Precision()), Recall: defined(s.Recall())}
+	}
+	for _, s := range scores {
+		report.Detectors = append(report.Detectors, toRow(s))
+	}
+	report.Total = toRow(bench.Total(scores))
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+// percent formats a ratio, "-" if undefined
+func percent(r float64) string {
+	if math.IsNaN(r) {
+		return "-"
+	}
+	return fmt.Sprintf("%.1f%%", r*100)
+}
+
+// defined returns a pointer to r, nil if undefined
+func defined(r float64) *float64 {
+	if math.IsNaN(r) {
+		return nil
+	}
+	return &r
+}
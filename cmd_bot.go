@@ -0,0 +1,98 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/happytaoer/prompt-security/internal/chatbot"
+	"github.com/spf13/cobra"
+)
+
+// newBotCmd creates the `bot` command watching team chat for sensitive data
+func newBotCmd() *cobra.Command {
+	botCmd := &cobra.Command{
+		Use:   "bot",
+		Short: "Watch Slack or Discord channels for sensitive data",
+		Long: `Connect to Slack or Discord as a bot and scan the messages posted in the
+watched channels with the current configuration. In warn mode the author is
+told to remove the message; in redact mode the message is removed and posted
+again redacted, or removed with a notice if a detector blocks it. Messages
+with sensitive data are logged like clipboard events.
+
+The bot keeps a websocket open, so it needs no public endpoint, and
+reconnects on its own. Tokens can also be given through the environment,
+e.g. PROMPT_SECURITY_SLACK_BOT_TOKEN.`,
+	}
+	botCmd.PersistentFlags().StringSlice("channel", nil, "ID of a channel to watch, repeatable (default every channel the bot is in)")
+	botCmd.PersistentFlags().String("mode", chatbot.ModeWarn, "What to do with messages containing sensitive data: warn or redact")
+
+	slackCmd := &cobra.Command{
+		Use:   "slack",
+		Short: "Watch Slack channels through Socket Mode",
+		Long: `Watch Slack channels through Socket Mode. The Slack app needs Socket Mode
+with an app-level token (connections:write), and a bot token with chat:write
+subscribed to the message.channels event (message.groups for private
+channels). Slack bots cannot remove other users' messages, so redact mode
+also needs the user token of a workspace admin with chat:write; without it
+the bot warns instead.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			appToken, _ := cmd.Flags().GetString("slack-app-token")
+			botToken, _ := cmd.Flags().GetString("slack-bot-token")
+			userToken, _ := cmd.Flags().GetString("slack-user-token")
+			if appToken == "" || botToken == "" {
+				return fmt.Errorf("--slack-app-token and --slack-bot-token are required")
+			}
+			return runBot(cmd, chatbot.NewSlack(appToken, botToken, userToken))
+		},
+	}
+	slackCmd.Flags().String("slack-app-token", "", "App-level token (xapp-) opening Socket Mode connections")
+	slackCmd.Flags().String("slack-bot-token", "", "Bot token (xoxb-) posting warnings")
+	slackCmd.Flags().String("slack-user-token", "", "Admin user token (xoxp-) removing messages in redact mode")
+
+	discordCmd := &cobra.Command{
+		Use:   "discord",
+		Short: "Watch Discord channels through the gateway",
+		Long: `Watch Discord channels through the gateway. Enable the Message Content
+intent of the bot in the developer portal; redact mode also needs the Manage
+Messages permission in the watched channels, without which the bot warns
+instead. Discord has no private replies, so warnings are posted in the
+channel.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			token, _ := cmd.Flags().GetString("discord-token")
+			if token == "" {
+				return fmt.Errorf("--discord-token is required")
+			}
+			return runBot(cmd, chatbot.NewDiscord(token))
+		},
+	}
+	discordCmd.Flags().String("discord-token", "", "Bot token")
+
+	botCmd.AddCommand(slackCmd, discordCmd)
+	return botCmd
+}
+
+// runBot watches the channels of platform until interrupted
+func runBot(cmd *cobra.Command, platform chatbot.Platform) error {
+	mode, _ := cmd.Flags().GetString("mode")
+	if mode != chatbot.ModeWarn && mode != chatbot.ModeRedact {
+		return fmt.Errorf("invalid --mode %q, expected %s or %s", mode, chatbot.ModeWarn, chatbot.ModeRedact)
+	}
+	channels, _ := cmd.Flags().GetStringSlice("channel")
+
+	_, engine, logs, err := newLogServer()
+	if err != nil {
+		return err
+	}
+	bot := chatbot.New(platform, engine, chatbot.Options{Mode: mode, Channels: channels, AddLog: logs.AddLog})
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	fmt.Printf("🤖 Watching %s messages (%s mode)\n", platform.Name(), mode)
+	bot.Run(ctx)
+	return nil
+}
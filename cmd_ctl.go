@@ -0,0 +1,196 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+	"github.com/happytaoer/prompt-security/internal/ctl"
+	"github.com/happytaoer/prompt-security/internal/db"
+	"github.com/happytaoer/prompt-security/internal/monitor"
+	"github.com/spf13/cobra"
+)
+
+// ctlSummaries describes the control commands
+var ctlSummaries = map[string]string{
+	ctl.CommandStatus: "Show whether monitoring is paused, the active profile, health and lockdown",
+	ctl.CommandPause:  "Pause clipboard monitoring",
+	ctl.CommandResume: "Resume clipboard monitoring",
+	ctl.CommandReload: "Reload the configuration from the database",
+	ctl.CommandStats:  "Show log counts, detections, outbound leaks, risk labels and source applications",
+}
+
+// newCtlCmd creates the `ctl` command for controlling the running daemon
+func newCtlCmd() *cobra.Command {
+	ctlCmd := &cobra.Command{
+		Use:   "ctl",
+		Short: "Control the running instance",
+		Long: `Control the running instance through its local control socket, without the
+HTTP API or its tokens. Only the user running the instance can connect.`,
+	}
+	ctlCmd.PersistentFlags().Bool("json", false, "Print the result as JSON")
+
+	for _, command := range ctl.Commands {
+		command := command
+		ctlCmd.AddCommand(&cobra.Command{
+			Use:   command,
+			Short: ctlSummaries[command],
+			Args:  cobra.NoArgs,
+			// Failures are about the running instance, not the usage
+			SilenceUsage: true,
+			RunE: func(cmd *cobra.Command, args []string) error {
+				asJSON, _ := cmd.Flags().GetBool("json")
+				dir, err := db.DataDir()
+				if err != nil {
+					return err
+				}
+				data, err := ctl.Call(ctl.SocketPath(dir), command)
+				if err != nil {
+					return err
+				}
+				return printCtlResult(command, data, asJSON)
+			},
+		})
+	}
+	return ctlCmd
+}
+
+// printCtlResult prints the result of a control command
+func printCtlResult(command string, data json.RawMessage, asJSON bool) error {
+	if asJSON {
+		if len(data) == 0 {
+			data = json.RawMessage("{}")
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	switch command {
+	case ctl.CommandStatus:
+		var status ctl.Status
+		if err := json.Unmarshal(data, &status); err != nil {
+			return err
+		}
+		profile := status.Profile
+		if status.Schedule != "" {
+			profile += " (schedule " + status.Schedule + ")"
+		}
+		fmt.Fprintf(tw, "Paused:\t%v\n", status.Paused)
+		fmt.Fprintf(tw, "Profile:\t%s\n", profile)
+		fmt.Fprintf(tw, "Health:\t%s\n", status.Health)
+		fmt.Fprintf(tw, "Lockdown:\t%v\n", status.Lockdown)
+		fmt.Fprintf(tw, "Web UI:\t%s\n", status.WebUI)
+	case ctl.CommandStats:
+		var stats ctl.Stats
+		if err := json.Unmarshal(data, &stats); err != nil {
+			return err
+		}
+		fmt.Fprintf(tw, "Logs:\t%d\n", stats.Logs)
+		fmt.Fprintf(tw, "Pending review:\t%d\n", stats.PendingReview)
+		printCounts(tw, "Detections", stats.Detections)
+		printCounts(tw, "Outbound leaks", stats.Leaks)
+		printCounts(tw, "Risk", stats.Risks)
+		printCounts(tw, "Application", stats.Apps)
+	default:
+		fmt.Fprintln(tw, "OK")
+	}
+	return tw.Flush()
+}
+
+// printCounts prints counts per type, sorted by type
+func printCounts(tw *tabwriter.Writer, label string, counts map[string]int) {
+	types := make([]string, 0, len(counts))
+	for typ := range counts {
+		types = append(types, typ)
+	}
+	sort.Strings(types)
+	for _, typ := range types {
+		fmt.Fprintf(tw, "%s (%s):\t%d\n", label, typ, counts[typ])
+	}
+}
+
+// ctlHandlers returns the handlers of the control commands for the running
+// instance
+func ctlHandlers(manager *config.Manager, clipboardMonitor *monitor.Monitor, addr string) map[string]ctl.Handler {
+	return map[string]ctl.Handler{
+		ctl.CommandStatus: func() (interface{}, error) {
+			profile, schedule := manager.ActiveProfile()
+			return ctl.Status{
+				Paused:   clipboardMonitor.Paused(),
+				Profile:  profile,
+				Schedule: schedule,
+				Health:   clipboardMonitor.Health().Status,
+				Lockdown: manager.Lockdown(),
+				WebUI:    "http://" + addr,
+			}, nil
+		},
+		ctl.CommandPause: func() (interface{}, error) {
+			was := clipboardMonitor.Paused()
+			clipboardMonitor.Pause()
+			return nil, auditCtl(db.AuditActionMonitorPause, map[string]bool{"paused": was}, map[string]bool{"paused": true})
+		},
+		ctl.CommandResume: func() (interface{}, error) {
+			was := clipboardMonitor.Paused()
+			clipboardMonitor.Resume()
+			return nil, auditCtl(db.AuditActionMonitorResume, map[string]bool{"paused": was}, map[string]bool{"paused": false})
+		},
+		ctl.CommandReload: func() (interface{}, error) {
+			if err := manager.Reload(); err != nil {
+				return nil, err
+			}
+			return nil, auditCtl(db.AuditActionConfigReload, nil, nil)
+		},
+		ctl.CommandStats: func() (interface{}, error) {
+			logs, err := db.GetLogCount()
+			if err != nil {
+				return nil, err
+			}
+			pending, err := db.GetReviewCount(db.ReviewPending)
+			if err != nil {
+				return nil, err
+			}
+			detections, err := db.GetDetectionCounts(db.LogKindPrompt)
+			if err != nil {
+				return nil, err
+			}
+			leaks, err := db.GetDetectionCounts(db.LogKindLeak)
+			if err != nil {
+				return nil, err
+			}
+			risks, err := db.GetRiskCounts()
+			if err != nil {
+				return nil, err
+			}
+			byApp, err := db.GetAppDetectionCounts()
+			if err != nil {
+				return nil, err
+			}
+			apps := make(map[string]int)
+			for app, types := range byApp {
+				if app == "" {
+					continue // Unknown
+				}
+				for _, n := range types {
+					apps[app] += n
+				}
+			}
+			return ctl.Stats{Logs: logs, PendingReview: pending, Detections: detections, Leaks: leaks, Risks: risks, Apps: apps}, nil
+		},
+	}
+}
+
+// ctlActor is the audit actor of commands sent over the control socket,
+// which only the user running the instance can reach
+const ctlActor = "ctl"
+
+// auditCtl records a command that changed the running instance
+func auditCtl(action string, oldValue, newValue interface{}) error {
+	if err := db.AddAudit(ctlActor, action, oldValue, newValue); err != nil {
+		return fmt.Errorf("failed to record audit entry: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/happytaoer/prompt-security/internal/breaker"
+	"github.com/happytaoer/prompt-security/internal/classifier"
+	"github.com/happytaoer/prompt-security/internal/config"
+	"github.com/happytaoer/prompt-security/internal/db"
+	"github.com/happytaoer/prompt-security/internal/filter"
+	"github.com/happytaoer/prompt-security/internal/gateway"
+	"github.com/happytaoer/prompt-security/internal/web"
+	"github.com/spf13/cobra"
+)
+
+// newGatewayCmd creates the `gateway` command serving model provider APIs
+// that redact requests
+func newGatewayCmd() *cobra.Command {
+	gatewayCmd := &cobra.Command{
+		Use:   "gateway",
+		Short: "Serve model provider APIs that redact prompts before forwarding them",
+		Long: `Serve the OpenAI, Anthropic and Gemini APIs, redacting sensitive data in
+prompts with the current configuration before forwarding them upstream. Point
+the base URL of an SDK at the gateway:
+
+  OpenAI     http://localhost:8282/v1
+  Anthropic  http://localhost:8282/anthropic
+  Gemini     http://localhost:8282/gemini
+
+Redacted messages are logged like clipboard events. Sensitive data in the
+responses, including tool call arguments, is logged as outbound leaks.
+
+To have --api-key or a stored key sent for it, a client must send an API token
+created with 'prompt-security token create' as its API key.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			listen, _ := cmd.Flags().GetString("listen")
+			openai := gateway.Upstream{}
+			openai.URL, _ = cmd.Flags().GetString("upstream")
+			openai.APIKey, _ = cmd.Flags().GetString("api-key")
+			openai.APIKeyHeader, _ = cmd.Flags().GetString("api-key-header")
+			anthropicURL, _ := cmd.Flags().GetString("anthropic-upstream")
+			geminiURL, _ := cmd.Flags().GetString("gemini-upstream")
+			opts := gateway.Options{
+				Upstreams: map[string]gateway.Upstream{
+					gateway.ProviderOpenAI:    openai,
+					gateway.ProviderAnthropic: {URL: anthropicURL},
+					gateway.ProviderGemini:    {URL: geminiURL},
+				},
+				Keys: db.GetUpstreamKey,
+			}
+			opts.Detokenize, _ = cmd.Flags().GetBool("detokenize")
+			if host, _, err := net.SplitHostPort(listen); err == nil && host != "" {
+				opts.Hosts = []string{host}
+			}
+			gw, _, err := newGateway(cmd, opts)
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("\n🔀 Gateway listening on http://%s\n", listen)
+			tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			for _, p := range gateway.Providers {
+				fmt.Fprintf(tw, "   %s\thttp://%s%s\t→ %s\n", p.Name, listen, p.Prefix, gw.UpstreamURL(p.Name))
+			}
+			tw.Flush()
+			fmt.Println()
+			return http.ListenAndServe(listen, gw.Handler())
+		},
+	}
+	gatewayCmd.Flags().String("listen", "localhost:8282", "Address the gateway listens on")
+	gatewayCmd.Flags().String("upstream", gateway.DefaultUpstream, "Base URL of the OpenAI-compatible upstream, e.g. http://localhost:11434/v1 for Ollama")
+	gatewayCmd.Flags().String("api-key", "", "API key for the OpenAI-compatible upstream, replacing stored and client keys")
+	gatewayCmd.Flags().String("api-key-header", "", "Header carrying the OpenAI-compatible key, e.g. api-key for Azure OpenAI")
+	gatewayCmd.Flags().String("anthropic-upstream", "", "Base URL of the Anthropic upstream (default https://api.anthropic.com)")
+	gatewayCmd.Flags().String("gemini-upstream", "", "Base URL of the Gemini upstream (default https://generativelanguage.googleapis.com)")
+	gatewayCmd.Flags().Bool("detokenize", false, "Replace values with unique tokens and restore them in responses")
+	addGatewayFlags(gatewayCmd)
+
+	gatewayCmd.AddCommand(newGatewayKeysCmd())
+	return gatewayCmd
+}
+
+// addGatewayFlags adds the flags shared by the commands that redact through
+// a gateway
+func addGatewayFlags(cmd *cobra.Command) {
+	cmd.Flags().Bool("scan-responses", true, "Log sensitive data in responses and tool calls as outbound leaks")
+	cmd.Flags().String("upstream-ca", "", "PEM file of additional CAs trusted for upstream connections, e.g. a corporate CA")
+	cmd.Flags().String("client-cert", "", "PEM client certificate for upstreams requiring mutual TLS")
+	cmd.Flags().String("client-key", "", "PEM key of --client-cert")
+}
+
+// newGateway creates a gateway redacting with the current configuration,
+// logging like the web server, with domain policies and the circuit
+// breaker in effect and upstream TLS configured by the flags of cmd
+func newGateway(cmd *cobra.Command, opts gateway.Options) (*gateway.Gateway, *config.Manager, error) {
+	tlsConfig, err := upstreamTLSConfig(cmd)
+	if err != nil {
+		return nil, nil, err
+	}
+	opts.TLSConfig = tlsConfig
+
+	configManager, engine, logs, err := newLogServer()
+	if err != nil {
+		return nil, nil, err
+	}
+	if scan, _ := cmd.Flags().GetBool("scan-responses"); scan {
+		opts.LogLeak = logs.AddLeak
+	}
+
+	// Allow and redact traffic by the policy of each upstream host
+	opts.Policy = gateway.NewDomainPolicies(configManager).Policy
+	opts.Authorize = authorizeGatewayClient
+	gw, err := gateway.New(opts, engine, logs.AddLog)
+	if err != nil {
+		return nil, nil, err
+	}
+	return gw, configManager, nil
+}
+
+// authorizeGatewayClient reports whether credential is an API token, which
+// entitles a gateway client to have the configured or stored keys sent
+func authorizeGatewayClient(credential string) (bool, error) {
+	_, ok, err := db.LookupAPIToken(credential)
+	return ok, err
+}
+
+// newLogServer creates an engine redacting with the current configuration
+// and a web server logging its events, without serving the web UI, so logs
+// follow the log mode, feed the review queue and count toward the circuit
+// breaker
+func newLogServer() (*config.Manager, *filter.Engine, *web.Server, error) {
+	configManager, err := config.NewManager()
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("failed to create config manager: %v", err)
+	}
+	engine := filter.NewManagedEngine(configManager)
+	go configManager.RunScheduler()
+	go configManager.Watch()
+
+	logs := web.NewServer(configManager, engine)
+	riskClassifier := classifier.New(configManager)
+	logs.SetClassifier(riskClassifier)
+	go riskClassifier.Run()
+	circuitBreaker := breaker.New(configManager)
+	logs.SetBreaker(circuitBreaker)
+	go circuitBreaker.Run()
+	return configManager, engine, logs, nil
+}
+
+// upstreamTLSConfig returns the TLS configuration for upstream connections
+// given by the flags of cmd, nil for the defaults
+func upstreamTLSConfig(cmd *cobra.Command) (*tls.Config, error) {
+	caFile, _ := cmd.Flags().GetString("upstream-ca")
+	certFile, _ := cmd.Flags().GetString("client-cert")
+	keyFile, _ := cmd.Flags().GetString("client-key")
+	if caFile == "" && certFile == "" && keyFile == "" {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read --upstream-ca: %v", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", caFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if certFile != "" || keyFile != "" {
+		if certFile == "" || keyFile == "" {
+			return nil, fmt.Errorf("--client-cert and --client-key must be given together")
+		}
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load the client certificate: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// newGatewayKeysCmd creates the `gateway keys` command for storing provider
+// API keys
+func newGatewayKeysCmd() *cobra.Command {
+	keysCmd := &cobra.Command{
+		Use:   "keys",
+		Short: "Manage the API keys the gateway sends to providers",
+		Long: `Store an API key per provider, sent by the gateway in place of the key from
+the client, so apps need no real key. Clients must send an API token created
+with 'prompt-security token create' as their key instead. Keys are stored in
+the database in plain text, readable only by the current user, and take effect
+immediately.`,
+	}
+	providers := strings.Join(gateway.ProviderNames(), ", ")
+
+	setCmd := &cobra.Command{
+		Use:   "set <provider>",
+		Short: "Store the key of a provider, read from standard input (" + providers + ")",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if _, ok := gateway.FindProvider(args[0]); !ok {
+				return fmt.Errorf("unknown provider %q, expected one of %s", args[0], providers)
+			}
+			fmt.Fprintf(os.Stderr, "Enter the %s API key: ", args[0])
+			line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+			key := strings.TrimSpace(line)
+			if key == "" {
+				return fmt.Errorf("no key given: %v", err)
+			}
+			if err := db.SetUpstreamKey(args[0], key); err != nil {
+				return err
+			}
+			fmt.Printf("Stored the %s key.\n", args[0])
+			return nil
+		},
+	}
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the stored keys",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			keys, err := db.ListUpstreamKeys()
+			if err != nil {
+				return err
+			}
+			tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "PROVIDER\tKEY\tUPDATED")
+			for _, k := range keys {
+				fmt.Fprintf(tw, "%s\t%s\t%s\n", k.Provider, k.Hint, k.UpdatedAt)
+			}
+			return tw.Flush()
+		},
+	}
+
+	deleteCmd := &cobra.Command{
+		Use:   "delete <provider>",
+		Short: "Delete the stored key of a provider",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := db.DeleteUpstreamKey(args[0]); err != nil {
+				return err
+			}
+			fmt.Printf("Deleted the %s key.\n", args[0])
+			return nil
+		},
+	}
+
+	keysCmd.AddCommand(setCmd, listCmd, deleteCmd)
+	return keysCmd
+}
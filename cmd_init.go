@@ -0,0 +1,166 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+	"github.com/happytaoer/prompt-security/internal/db"
+	"github.com/happytaoer/prompt-security/internal/web"
+	"github.com/spf13/cobra"
+)
+
+// newInitCmd creates the `init` command that walks through first-run setup
+func newInitCmd() *cobra.Command {
+	initCmd := &cobra.Command{
+		Use:   "init",
+		Short: "Choose detectors, replacements and access control on first run",
+		Long: `Walk through first-run setup: choose which detectors to enable, how matches
+are replaced, and optionally create an admin API token. Answers can be given
+with flags instead; --yes accepts the defaults for anything not given.`,
+		Args: cobra.NoArgs,
+		// Failures are about the answers, not the usage
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			force, _ := cmd.Flags().GetBool("force")
+			completedAt, _, err := config.SetupState()
+			if err != nil {
+				return err
+			}
+			if completedAt != nil && !force {
+				return fmt.Errorf("setup was already completed on %s, use --force to run it again", completedAt.Format("2006-01-02 15:04"))
+			}
+
+			yes, _ := cmd.Flags().GetBool("yes")
+			p := &prompter{in: bufio.NewReader(os.Stdin), out: cmd.OutOrStdout(), defaults: yes}
+
+			choices := config.SetupChoices{Strategy: config.DefaultStrategy}
+			if cmd.Flags().Changed("detectors") {
+				value, _ := cmd.Flags().GetString("detectors")
+				choices.Detectors = config.ParseDetectors(value)
+			} else {
+				fmt.Fprintln(p.out, "Which detectors should be enabled?")
+				for _, d := range config.Detectors {
+					if p.confirm("  "+d, contains(config.DefaultSetupDetectors, d)) {
+						choices.Detectors = append(choices.Detectors, d)
+					}
+				}
+			}
+			if cmd.Flags().Changed("strategy") {
+				choices.Strategy, _ = cmd.Flags().GetString("strategy")
+			} else {
+				fmt.Fprintln(p.out, "How should matches be replaced?")
+				for _, s := range config.Strategies {
+					fmt.Fprintf(p.out, "  %s\n", config.DescribeStrategy(s))
+				}
+				choices.Strategy = p.ask("Strategy", config.DefaultStrategy)
+			}
+			tokenName, _ := cmd.Flags().GetString("token")
+			if !cmd.Flags().Changed("token") && p.confirm("Require an API token for the web UI and API?", false) {
+				tokenName = p.ask("Token name", "admin")
+			}
+			if p.err != nil {
+				return p.err
+			}
+
+			manager, err := config.NewManager()
+			if err != nil {
+				return err
+			}
+			cfg, err := config.ApplySetup(manager.Get(), choices)
+			if err == nil {
+				err = config.Validate(cfg)
+			}
+			if err != nil {
+				return err
+			}
+
+			// The token is created first, as its name may be taken, and
+			// revoked if setup cannot be completed
+			var secret string
+			if tokenName = strings.TrimSpace(tokenName); tokenName != "" {
+				if secret, err = db.CreateAPIToken(tokenName, string(web.RoleAdmin)); err != nil {
+					return err
+				}
+			}
+			err = manager.Update(cfg, "cli")
+			if err == nil {
+				err = db.CompleteSetup()
+			}
+			if err != nil {
+				if secret != "" {
+					if deleteErr := db.DeleteAPIToken(tokenName); deleteErr != nil {
+						return fmt.Errorf("%v, and failed to revoke token %q: %v", err, tokenName, deleteErr)
+					}
+				}
+				return err
+			}
+
+			fmt.Fprintf(p.out, "\nSetup complete: detecting %s, replacing with %s.\n", strings.Join(choices.Detectors, ", "), config.DescribeStrategy(choices.Strategy))
+			if secret != "" {
+				fmt.Fprintf(p.out, "Created admin token %q. Store this secret now, it cannot be shown again:\n\n%s\n", tokenName, secret)
+			}
+			return nil
+		},
+	}
+	initCmd.Flags().String("detectors", "", "Comma separated detectors to enable ("+strings.Join(config.Detectors, ", ")+")")
+	initCmd.Flags().String("strategy", "", "Replacement strategy ("+strings.Join(config.Strategies, ", ")+")")
+	initCmd.Flags().String("token", "", "Create an admin API token with this name")
+	initCmd.Flags().BoolP("yes", "y", false, "Accept the defaults instead of asking")
+	initCmd.Flags().Bool("force", false, "Run setup again even if it was completed")
+	return initCmd
+}
+
+// prompter asks questions on the terminal. With defaults set, or once
+// input ends, every question takes its default answer.
+type prompter struct {
+	in       *bufio.Reader
+	out      io.Writer
+	defaults bool
+	err      error
+}
+
+// ask asks a question and returns the answer, or def if none is given
+func (p *prompter) ask(question, def string) string {
+	fmt.Fprintf(p.out, "%s [%s]: ", question, def)
+	if p.defaults {
+		fmt.Fprintln(p.out, def)
+		return def
+	}
+	line, err := p.in.ReadString('\n')
+	if err != nil {
+		if !errors.Is(err, io.EOF) {
+			p.err = err
+		}
+		p.defaults = true
+		fmt.Fprintln(p.out)
+	}
+	if answer := strings.TrimSpace(line); answer != "" {
+		return answer
+	}
+	return def
+}
+
+// confirm asks a yes or no question
+func (p *prompter) confirm(question string, def bool) bool {
+	answer := "n"
+	if def {
+		answer = "y"
+	}
+	answer = strings.ToLower(p.ask(question+" (y/n)", answer))
+	return answer == "y" || answer == "yes"
+}
+
+// contains reports whether values contains value
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/happytaoer/prompt-security/internal/bench"
+	"github.com/happytaoer/prompt-security/internal/config"
+	"github.com/happytaoer/prompt-security/internal/db"
+	"github.com/happytaoer/prompt-security/internal/filter"
+	"github.com/spf13/cobra"
+)
+
+// newLogsCmd creates the `logs` command for working with filter logs
+func newLogsCmd() *cobra.Command {
+	logsCmd := &cobra.Command{
+		Use:   "logs",
+		Short: "Work with filter logs",
+	}
+
+	replayCmd := &cobra.Command{
+		Use:   "replay",
+		Short: "Re-run logged copies through the current configuration",
+		Long: `Re-run the original text of the newest filter logs through the current
+configuration and report what would be detected now, to check new patterns
+against real clipboard history. Logs stored as hashes cannot be replayed.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			limit, _ := cmd.Flags().GetInt("limit")
+			profile, _ := cmd.Flags().GetString("profile")
+			changedOnly, _ := cmd.Flags().GetBool("changed")
+			asJSON, _ := cmd.Flags().GetBool("json")
+			if !config.ValidProfile(profile) {
+				return fmt.Errorf("unknown profile %q, expected %s, %s or %s", profile, config.ProfileStandard, config.ProfileStrict, config.ProfileOff)
+			}
+
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+			logs, err := db.GetLogs(limit)
+			if err != nil {
+				return err
+			}
+			report := bench.Replay(filter.NewDetectorSet(config.ApplyProfile(cfg, profile)), logs)
+
+			if changedOnly {
+				var changed []bench.ReplayEntry
+				for _, e := range report.Entries {
+					if e.Changed {
+						changed = append(changed, e)
+					}
+				}
+				report.Entries = changed
+			}
+
+			if asJSON {
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				return enc.Encode(report)
+			}
+			return printReplay(report)
+		},
+	}
+	replayCmd.Flags().Int("limit", 100, "Number of the newest logs to replay")
+	replayCmd.Flags().String("profile", config.ProfileStandard, "Detection profile to apply (standard, strict, off)")
+	replayCmd.Flags().Bool("changed", false, "Only list logs whose detections changed")
+	replayCmd.Flags().Bool("json", false, "Print the report as JSON")
+
+	logsCmd.AddCommand(replayCmd)
+	return logsCmd
+}
+
+// printReplay prints a replay report as a table followed by per type totals
+func printReplay(report bench.ReplayReport) error {
+	fmt.Printf("%d logs replayed, %d changed, %d hashed logs skipped\n\n", report.Scanned, report.Changed, report.Skipped)
+
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "ID\tTIMESTAMP\tBEFORE\tNOW\t")
+	for _, e := range report.Entries {
+		mark := ""
+		if e.Changed {
+			mark = "changed"
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", e.ID, e.Timestamp, typeList(e.Before), typeList(e.After), mark)
+	}
+	fmt.Fprintln(tw)
+
+	fmt.Fprintln(tw, "TYPE\tBEFORE\tNOW\t")
+	types := make(map[string]bool)
+	for t := range report.Before {
+		types[t] = true
+	}
+	for t := range report.After {
+		types[t] = true
+	}
+	sorted := make([]string, 0, len(types))
+	for t := range types {
+		sorted = append(sorted, t)
+	}
+	sort.Strings(sorted)
+	for _, t := range sorted {
+		fmt.Fprintf(tw, "%s\t%d\t%d\t\n", t, report.Before[t], report.After[t])
+	}
+	return tw.Flush()
+}
+
+// typeList formats detected types, "-" if none
+func typeList(types []string) string {
+	if len(types) == 0 {
+		return "-"
+	}
+	return strings.Join(types, ",")
+}
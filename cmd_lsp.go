@@ -0,0 +1,51 @@
+package main
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+	"github.com/happytaoer/prompt-security/internal/filter"
+	"github.com/happytaoer/prompt-security/internal/lsp"
+	"github.com/spf13/cobra"
+)
+
+// newLSPCmd creates the `lsp` command running a language server over
+// standard input and output
+func newLSPCmd() *cobra.Command {
+	lspCmd := &cobra.Command{
+		Use:   "lsp",
+		Short: "Run a language server warning about sensitive data in open files",
+		Long: `Run a language server over standard input and output that marks sensitive
+data in the files open in an editor with diagnostics, and offers code actions
+redacting it the way a copy would be redacted. Configure it in the editor as
+a server for all file types, e.g. for Neovim:
+
+  vim.lsp.start({ name = "prompt-security", cmd = { "prompt-security", "lsp" } })
+
+Configuration changes apply to open files without a restart. Nothing is
+logged.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Standard output carries the protocol, anything else goes to
+			// standard error
+			out := os.Stdout
+			os.Stdout = os.Stderr
+
+			configManager, err := config.NewManager()
+			if err != nil {
+				return fmt.Errorf("failed to create config manager: %v", err)
+			}
+			engine := filter.NewManagedEngine(configManager)
+			server := lsp.New(engine)
+			configManager.OnChange(func(config.Config) { server.Refresh() })
+			go configManager.RunScheduler()
+			go configManager.Watch()
+
+			return server.Serve(os.Stdin, out)
+		},
+	}
+	// Editors commonly pass --stdio; it is the only transport
+	lspCmd.Flags().Bool("stdio", true, "Communicate over standard input and output")
+	return lspCmd
+}
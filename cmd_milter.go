@@ -0,0 +1,59 @@
+package main
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"strings"
+
+	"github.com/happytaoer/prompt-security/internal/milter"
+	"github.com/spf13/cobra"
+)
+
+// newMilterCmd creates the `milter` command scanning outgoing mail
+func newMilterCmd() *cobra.Command {
+	milterCmd := &cobra.Command{
+		Use:   "milter",
+		Short: "Scan outgoing mail for sensitive data as a Postfix or Sendmail milter",
+		Long: `Serve the milter protocol so a mail server scans outgoing messages with the
+current configuration. Text bodies, the subject and text attachments are
+scanned: mail a blocking detector matches is rejected, values of replacing
+detectors are redacted in place and the message is tagged with an
+X-Prompt-Security header, and everything found is logged like copies.
+
+For Postfix, add to main.cf:
+
+  smtpd_milters = inet:localhost:8899
+  non_smtpd_milters = inet:localhost:8899
+  milter_default_action = accept
+
+Use --recipient to scan only mail to AI assistants and mail bridges.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			listen, _ := cmd.Flags().GetString("listen")
+			recipients, _ := cmd.Flags().GetStringSlice("recipient")
+
+			network, addr := "tcp", strings.TrimPrefix(listen, "inet:")
+			if path, ok := strings.CutPrefix(listen, "unix:"); ok {
+				network, addr = "unix", path
+				os.Remove(path)
+			}
+			listener, err := net.Listen(network, addr)
+			if err != nil {
+				return fmt.Errorf("failed to listen on %s: %v", listen, err)
+			}
+			defer listener.Close()
+
+			_, engine, logs, err := newLogServer()
+			if err != nil {
+				return err
+			}
+			server := milter.New(engine, milter.Options{Recipients: recipients, AddLog: logs.AddLog})
+			fmt.Printf("📧 Milter listening on %s\n", listen)
+			return server.Serve(listener)
+		},
+	}
+	milterCmd.Flags().String("listen", "localhost:8899", "Address the milter listens on, host:port or unix:/path")
+	milterCmd.Flags().StringSlice("recipient", nil, "Only scan mail to matching recipients, e.g. *@openai.com or *.example.ai, repeatable (default all mail)")
+	return milterCmd
+}
@@ -0,0 +1,169 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/happytaoer/prompt-security/internal/db"
+	"github.com/happytaoer/prompt-security/internal/nativehost"
+	"github.com/spf13/cobra"
+)
+
+// browserDirs are the per-user native messaging host directories of the
+// Chromium browsers on macOS and Linux, relative to the home directory
+var browserDirs = map[string]map[string]string{
+	"darwin": {
+		"chrome":   "Library/Application Support/Google/Chrome/NativeMessagingHosts",
+		"chromium": "Library/Application Support/Chromium/NativeMessagingHosts",
+		"edge":     "Library/Application Support/Microsoft Edge/NativeMessagingHosts",
+		"brave":    "Library/Application Support/BraveSoftware/Brave-Browser/NativeMessagingHosts",
+	},
+	"linux": {
+		"chrome":   ".config/google-chrome/NativeMessagingHosts",
+		"chromium": ".config/chromium/NativeMessagingHosts",
+		"edge":     ".config/microsoft-edge/NativeMessagingHosts",
+		"brave":    ".config/BraveSoftware/Brave-Browser/NativeMessagingHosts",
+	},
+}
+
+// browserKeys are the registry keys of the native messaging hosts of the
+// Chromium browsers on Windows
+var browserKeys = map[string]string{
+	"chrome":   `HKCU\Software\Google\Chrome\NativeMessagingHosts`,
+	"chromium": `HKCU\Software\Chromium\NativeMessagingHosts`,
+	"edge":     `HKCU\Software\Microsoft\Edge\NativeMessagingHosts`,
+	"brave":    `HKCU\Software\BraveSoftware\Brave-Browser\NativeMessagingHosts`,
+}
+
+// newNativeHostCmd creates the `native-host` command answering a browser
+// extension over native messaging
+func newNativeHostCmd() *cobra.Command {
+	hostCmd := &cobra.Command{
+		Use:   "native-host",
+		Short: "Answer a browser extension over native messaging, without opening a port",
+		Long: `Answer a browser extension over native messaging: the browser spawns the host
+and exchanges length prefixed JSON messages on its standard input and output,
+so no port is opened. Register the host with ` + "`native-host install`" + `; the
+browser then starts it when the extension connects to ` + nativehost.Name + `.
+
+Messages are {"id": ..., "type": ...} with the types ping, filter (with
+"text", and "log": true to log findings like a copy) and config. Results have
+the shapes of the web API.`,
+		// Browsers pass the extension origin and, on Windows, --parent-window
+		Args:               cobra.ArbitraryArgs,
+		FParseErrWhitelist: cobra.FParseErrWhitelist{UnknownFlags: true},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			// Standard output carries the messages, anything else goes to
+			// standard error
+			out := os.Stdout
+			os.Stdout = os.Stderr
+
+			configManager, engine, logs, err := newLogServer()
+			if err != nil {
+				return err
+			}
+			host := nativehost.New(engine, nativehost.Options{Version: version, Config: configManager.Effective, AddLog: logs.AddLog})
+			return host.Serve(os.Stdin, out)
+		},
+	}
+
+	installCmd := &cobra.Command{
+		Use:   "install",
+		Short: "Register the native messaging host with a browser for an extension",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ids, _ := cmd.Flags().GetStringSlice("extension-id")
+			browser, _ := cmd.Flags().GetString("browser")
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			if len(ids) == 0 {
+				return fmt.Errorf("--extension-id is required")
+			}
+			if _, ok := browserKeys[browser]; !ok {
+				return fmt.Errorf("unknown browser %q, expected chrome, chromium, edge or brave", browser)
+			}
+			return installNativeHost(runtime.GOOS, browser, ids, dryRun)
+		},
+	}
+	installCmd.Flags().StringSlice("extension-id", nil, "ID of the extension allowed to start the host, repeatable")
+	installCmd.Flags().String("browser", "chrome", "Browser to register with: chrome, chromium, edge or brave")
+	installCmd.Flags().Bool("dry-run", false, "Print what would be written without writing it")
+
+	hostCmd.AddCommand(installCmd)
+	return hostCmd
+}
+
+// installNativeHost writes a launcher running `native-host` and the host
+// manifest of browser allowing the extensions ids, and registers the
+// manifest on Windows
+func installNativeHost(goos, browser string, ids []string, dryRun bool) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("failed to locate the executable: %v", err)
+	}
+	dataDir, err := db.DataDir()
+	if err != nil {
+		return err
+	}
+
+	// Browsers start the host without arguments of our own, so the
+	// manifest points at a launcher adding the subcommand and data directory
+	launcher := filepath.Join(dataDir, "native-host.sh")
+	quote := func(s string) string { return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'" }
+	script := fmt.Sprintf("#!/bin/sh\nexec %s native-host --data-dir %s \"$@\"\n", quote(exe), quote(dataDir))
+	manifestPath := ""
+	if goos == "windows" {
+		launcher = filepath.Join(dataDir, "native-host.bat")
+		script = fmt.Sprintf("@echo off\r\n\"%s\" native-host --data-dir \"%s\" %%*\r\n", exe, dataDir)
+		manifestPath = filepath.Join(dataDir, nativehost.Name+".json")
+	} else {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return err
+		}
+		dir, ok := browserDirs[goos][browser]
+		if !ok {
+			return fmt.Errorf("native messaging hosts cannot be installed on %s", goos)
+		}
+		manifestPath = filepath.Join(home, dir, nativehost.Name+".json")
+	}
+
+	origins := make([]string, len(ids))
+	for i, id := range ids {
+		origins[i] = "chrome-extension://" + id + "/"
+	}
+	manifest, err := json.MarshalIndent(map[string]interface{}{
+		"name":            nativehost.Name,
+		"description":     "Prompt Security",
+		"path":            launcher,
+		"type":            "stdio",
+		"allowed_origins": origins,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Writing %s\n", launcher)
+	fmt.Printf("Writing %s:\n%s\n", manifestPath, manifest)
+	var commands [][]string
+	if goos == "windows" {
+		commands = append(commands, []string{"reg", "add", browserKeys[browser] + `\` + nativehost.Name, "/ve", "/t", "REG_SZ", "/d", manifestPath, "/f"})
+	}
+	if dryRun {
+		return runCommands(commands, true)
+	}
+
+	if err := os.WriteFile(launcher, []byte(script), 0755); err != nil {
+		return fmt.Errorf("failed to write the launcher: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(manifestPath), 0755); err != nil {
+		return fmt.Errorf("failed to create the manifest directory: %v", err)
+	}
+	if err := os.WriteFile(manifestPath, append(manifest, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write the manifest: %v", err)
+	}
+	return runCommands(commands, false)
+}
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+	"github.com/spf13/cobra"
+)
+
+// newPatternsCmd creates the `patterns` command for managing string match
+// patterns
+func newPatternsCmd() *cobra.Command {
+	patternsCmd := &cobra.Command{
+		Use:   "patterns",
+		Short: "Manage string match patterns",
+	}
+
+	importCmd := &cobra.Command{
+		Use:   "import <file>",
+		Short: "Add the literal terms of a CSV or TSV dictionary as string match patterns",
+		Long: `Add literal terms such as employee names, project codenames or customer IDs
+as string match patterns, one per row of a CSV or TSV file ("-" reads
+standard input). Terms are read from the first column, or from the term
+column if the first row is a header; name and replacement columns override
+--name and --replacement per row. Terms that already exist or repeat are
+skipped.
+
+  prompt-security patterns import --name employee_name --dry-run staff.csv`,
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			format, _ := cmd.Flags().GetString("format")
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			var opts config.DictionaryOptions
+			opts.Name, _ = cmd.Flags().GetString("name")
+			opts.Replacement, _ = cmd.Flags().GetString("replacement")
+			opts.CaseInsensitive, _ = cmd.Flags().GetBool("case-insensitive")
+			opts.WholeWord, _ = cmd.Flags().GetBool("whole-word")
+			opts.Fuzzy, _ = cmd.Flags().GetBool("fuzzy")
+			opts.Action, _ = cmd.Flags().GetString("action")
+			opts.Severity, _ = cmd.Flags().GetString("severity")
+
+			var content []byte
+			var err error
+			if args[0] == "-" {
+				content, err = io.ReadAll(os.Stdin)
+			} else {
+				content, err = os.ReadFile(args[0])
+				if format == "" && strings.EqualFold(filepath.Ext(args[0]), ".tsv") {
+					format = config.DictionaryTSV
+				}
+			}
+			if err != nil {
+				return fmt.Errorf("failed to read dictionary: %v", err)
+			}
+
+			manager, err := config.NewManager()
+			if err != nil {
+				return err
+			}
+			result, err := manager.ImportPatterns(string(content), format, opts, dryRun, "cli")
+			if err != nil {
+				return err
+			}
+
+			for _, e := range result.Errors {
+				fmt.Fprintf(os.Stderr, "line %d: %s\n", e.Line, e.Error)
+			}
+			verb := "Imported"
+			if dryRun {
+				verb = "Would import"
+				for _, p := range result.Imported {
+					fmt.Printf("  %s\t%s -> %s\n", p.Name, p.Pattern, p.Replacement)
+				}
+			}
+			fmt.Printf("%s %d terms, skipped %d duplicates and %d invalid rows\n", verb, len(result.Imported), len(result.Duplicates), len(result.Errors))
+			return nil
+		},
+	}
+	importCmd.Flags().String("name", "", "Detection type of the terms, e.g. employee_name")
+	importCmd.Flags().String("replacement", "", "Replacement of the terms (default the name in brackets, e.g. [EMPLOYEE_NAME])")
+	importCmd.Flags().Bool("case-insensitive", true, "Match the terms in any case")
+	importCmd.Flags().Bool("whole-word", true, "Only match the terms as whole words")
+	importCmd.Flags().Bool("fuzzy", false, "Also match the terms misspelt by one character, ignoring case and diacritics")
+	importCmd.Flags().String("action", "", "Action for the terms (replace, block, ask, log; default by severity)")
+	importCmd.Flags().String("severity", "", "Severity of the terms (low, medium, high, critical)")
+	importCmd.Flags().String("format", "", "Dictionary format (csv, tsv; default detected)")
+	importCmd.Flags().Bool("dry-run", false, "Only print the terms that would be imported")
+
+	patternsCmd.AddCommand(importCmd)
+	return patternsCmd
+}
@@ -0,0 +1,371 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+	"github.com/happytaoer/prompt-security/internal/db"
+	"github.com/happytaoer/prompt-security/internal/gateway"
+	"github.com/happytaoer/prompt-security/internal/proxy"
+	"github.com/spf13/cobra"
+)
+
+// newProxyCmd creates the `proxy` command, an intercepting HTTPS proxy in
+// front of the gateway
+func newProxyCmd() *cobra.Command {
+	proxyCmd := &cobra.Command{
+		Use:   "proxy",
+		Short: "Run an intercepting HTTPS proxy that redacts prompts to model providers",
+		Long: `Run an HTTP proxy for apps that cannot be pointed at the gateway. HTTPS
+traffic to the OpenAI, Anthropic and Gemini APIs is decrypted with certificates
+issued by a local CA and redacted like gateway requests; all other traffic is
+tunneled without being decrypted. Apps must trust the CA, see
+'prompt-security proxy ca install', and use the proxy, e.g. with
+
+  HTTPS_PROXY=http://localhost:8383
+
+Hosts in proxy_bypass or --bypass are never decrypted, and domain policies
+decide which hosts may be reached at all. To send only model provider traffic
+through the proxy, use the PAC file it serves at /proxy.pac, see
+'prompt-security proxy system enable'.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			listen, _ := cmd.Flags().GetString("listen")
+			bypass, _ := cmd.Flags().GetStringSlice("bypass")
+			route, _ := cmd.Flags().GetStringSlice("route")
+			ca, err := loadCA()
+			if err != nil {
+				return err
+			}
+
+			opts := gateway.Options{Keys: db.GetUpstreamKey}
+			opts.Detokenize, _ = cmd.Flags().GetBool("detokenize")
+			gw, configManager, err := newGateway(cmd, opts)
+			if err != nil {
+				return err
+			}
+
+			p := proxy.New(proxy.Options{
+				CA:        ca,
+				Intercept: gw.Intercept,
+				Bypass: func(host string) bool {
+					return config.MatchesHost(bypass, host) || config.MatchesHost(configManager.Get().ProxyBypass, host)
+				},
+				Allow: func(host string) bool {
+					policy, ok := config.MatchDomainPolicy(configManager.Effective().DomainPolicies, host)
+					return !ok || policy.Allow
+				},
+				PAC: func(proxyAddr string) []byte {
+					return proxy.PAC(proxyAddr, append(interceptedHosts(), route...), append(append([]string{}, bypass...), configManager.Get().ProxyBypass...))
+				},
+			})
+
+			fmt.Printf("\n🛡️  Proxy listening on http://%s\n", listen)
+			fmt.Printf("   Intercepting %s\n", strings.Join(interceptedHosts(), ", "))
+			fmt.Printf("   PAC file http://%s%s\n", listen, proxy.PACPath)
+			fmt.Printf("   CA certificate %s (SHA-256 %s)\n\n", ca.CertPath(), ca.Fingerprint())
+			return http.ListenAndServe(listen, p)
+		},
+	}
+	proxyCmd.Flags().String("listen", "localhost:8383", "Address the proxy listens on")
+	proxyCmd.Flags().StringSlice("bypass", nil, "Hosts or *. wildcards never decrypted, in addition to proxy_bypass")
+	proxyCmd.Flags().StringSlice("route", nil, "Additional hosts or *. wildcards the PAC file sends through the proxy, e.g. to enforce domain policies")
+	proxyCmd.Flags().Bool("detokenize", false, "Replace values with unique tokens and restore them in responses")
+	addGatewayFlags(proxyCmd)
+
+	proxyCmd.AddCommand(newProxyCACmd(), newProxyPACCmd(), newProxySystemCmd())
+	return proxyCmd
+}
+
+// interceptedHosts returns the hosts whose traffic the proxy decrypts, the
+// default upstream hosts of the providers
+func interceptedHosts() []string {
+	var hosts []string
+	for _, p := range gateway.Providers {
+		if u, err := url.Parse(p.DefaultUpstream); err == nil && u.Scheme == "https" {
+			hosts = append(hosts, u.Hostname())
+		}
+	}
+	return hosts
+}
+
+// loadCA loads the proxy's CA from the data directory, creating it on first
+// use
+func loadCA() (*proxy.CA, error) {
+	dir, err := db.DataDir()
+	if err != nil {
+		return nil, err
+	}
+	return proxy.LoadOrCreateCA(dir, interceptedHosts())
+}
+
+// newProxyCACmd creates the `proxy ca` command for managing the local CA
+func newProxyCACmd() *cobra.Command {
+	caCmd := &cobra.Command{
+		Use:   "ca",
+		Short: "Manage the local CA the proxy issues certificates with",
+		Long: `The CA is generated into the data directory on first use. Its key never
+leaves the machine; anyone holding it can impersonate any site to apps that
+trust the CA, so it is readable only by its owner.`,
+	}
+
+	pathCmd := &cobra.Command{
+		Use:   "path",
+		Short: "Print the path of the CA certificate",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ca, err := loadCA()
+			if err != nil {
+				return err
+			}
+			fmt.Println(ca.CertPath())
+			return nil
+		},
+	}
+
+	showCmd := &cobra.Command{
+		Use:   "show",
+		Short: "Show the CA certificate's path, fingerprint and expiry",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			ca, err := loadCA()
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Certificate: %s\n", ca.CertPath())
+			fmt.Printf("SHA-256:     %s\n", ca.Fingerprint())
+			fmt.Printf("Expires:     %s\n", ca.NotAfter().Format("2006-01-02"))
+			return nil
+		},
+	}
+
+	installCmd := &cobra.Command{
+		Use:   "install",
+		Short: "Trust the CA in the system certificate store",
+		Long: `Add the CA certificate to the trust store of the system: the login keychain on
+macOS, the user's root store on Windows, and the system store on Linux (with
+sudo). Some runtimes keep their own store; point them at the certificate with
+NODE_EXTRA_CA_CERTS, REQUESTS_CA_BUNDLE or SSL_CERT_FILE.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			ca, err := loadCA()
+			if err != nil {
+				return err
+			}
+			commands, err := trustCommands(runtime.GOOS, ca.CertPath())
+			if err != nil {
+				return err
+			}
+			if err := runCommands(commands, dryRun); err != nil {
+				return err
+			}
+			if !dryRun {
+				fmt.Printf("Trusted the CA with SHA-256 fingerprint %s.\n", ca.Fingerprint())
+			}
+			return nil
+		},
+	}
+	installCmd.Flags().Bool("dry-run", false, "Print the commands without running them")
+
+	caCmd.AddCommand(pathCmd, showCmd, installCmd)
+	return caCmd
+}
+
+// trustCommands returns the commands adding the certificate at certPath to
+// the trust store of goos
+func trustCommands(goos, certPath string) ([][]string, error) {
+	switch goos {
+	case "darwin":
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return nil, err
+		}
+		keychain := filepath.Join(home, "Library", "Keychains", "login.keychain-db")
+		return [][]string{{"security", "add-trusted-cert", "-r", "trustRoot", "-k", keychain, certPath}}, nil
+	case "windows":
+		return [][]string{{"certutil", "-user", "-addstore", "Root", certPath}}, nil
+	case "linux":
+		sudo := []string{}
+		if os.Geteuid() != 0 {
+			sudo = []string{"sudo"}
+		}
+		for _, store := range []struct{ dir, update string }{
+			{"/usr/local/share/ca-certificates", "update-ca-certificates"},
+			{"/etc/pki/ca-trust/source/anchors", "update-ca-trust"},
+		} {
+			if info, err := os.Stat(store.dir); err == nil && info.IsDir() {
+				return [][]string{
+					append(append([]string{}, sudo...), "cp", certPath, filepath.Join(store.dir, "prompt-security.crt")),
+					append(append([]string{}, sudo...), store.update),
+				}, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("no known certificate store on this system; trust %s manually", certPath)
+}
+
+// runCommands prints and runs commands in order, stopping at the first
+// failure, or only prints them for a dry run
+func runCommands(commands [][]string, dryRun bool) error {
+	for _, command := range commands {
+		fmt.Println("$ " + strings.Join(command, " "))
+		if dryRun {
+			continue
+		}
+		c := exec.Command(command[0], command[1:]...)
+		c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+		if err := c.Run(); err != nil {
+			return fmt.Errorf("%s failed: %v", command[0], err)
+		}
+	}
+	return nil
+}
+
+// newProxyPACCmd creates the `proxy pac` command writing a PAC file
+func newProxyPACCmd() *cobra.Command {
+	pacCmd := &cobra.Command{
+		Use:   "pac",
+		Short: "Write a PAC file sending only model provider traffic through the proxy",
+		Long: `Write a proxy auto-config file that sends traffic to the intercepted hosts,
+and hosts given with --route, through the proxy and everything else directly,
+so as little traffic as possible is proxied. Hosts in proxy_bypass or --bypass
+always go directly. A running proxy also serves this file at /proxy.pac.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			proxyAddr, _ := cmd.Flags().GetString("proxy")
+			route, _ := cmd.Flags().GetStringSlice("route")
+			bypass, _ := cmd.Flags().GetStringSlice("bypass")
+			output, _ := cmd.Flags().GetString("output")
+			cfg, err := config.Load()
+			if err != nil {
+				return fmt.Errorf("failed to load config: %v", err)
+			}
+
+			pac := proxy.PAC(proxyAddr, append(interceptedHosts(), route...), append(bypass, cfg.ProxyBypass...))
+			if output == "" {
+				_, err := os.Stdout.Write(pac)
+				return err
+			}
+			if err := os.WriteFile(output, pac, 0644); err != nil {
+				return fmt.Errorf("failed to write PAC file: %v", err)
+			}
+			fmt.Printf("Wrote %s\n", output)
+			return nil
+		},
+	}
+	pacCmd.Flags().String("proxy", "localhost:8383", "Address clients reach the proxy at")
+	pacCmd.Flags().StringSlice("route", nil, "Additional hosts or *. wildcards sent through the proxy")
+	pacCmd.Flags().StringSlice("bypass", nil, "Hosts or *. wildcards sent directly, in addition to proxy_bypass")
+	pacCmd.Flags().StringP("output", "o", "", "File to write instead of standard output")
+	return pacCmd
+}
+
+// newProxySystemCmd creates the `proxy system` command pointing the system
+// proxy settings at the PAC file
+func newProxySystemCmd() *cobra.Command {
+	systemCmd := &cobra.Command{
+		Use:   "system",
+		Short: "Configure the system to use the proxy's PAC file",
+		Long: `Set the automatic proxy configuration URL of the system to the PAC file of a
+running proxy, so apps following the system settings send only model provider
+traffic through it: all network services on macOS, the user's Internet
+settings on Windows and GNOME's settings on Linux.`,
+	}
+
+	enableCmd := &cobra.Command{
+		Use:   "enable",
+		Short: "Use the proxy's PAC file as the system's automatic proxy configuration",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			pacURL, _ := cmd.Flags().GetString("pac-url")
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			commands, err := systemProxyCommands(runtime.GOOS, pacURL)
+			if err != nil {
+				return err
+			}
+			return runCommands(commands, dryRun)
+		},
+	}
+	enableCmd.Flags().String("pac-url", "http://localhost:8383"+proxy.PACPath, "URL of the PAC file")
+	enableCmd.Flags().Bool("dry-run", false, "Print the commands without running them")
+
+	disableCmd := &cobra.Command{
+		Use:   "disable",
+		Short: "Turn the system's automatic proxy configuration off",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			dryRun, _ := cmd.Flags().GetBool("dry-run")
+			commands, err := systemProxyCommands(runtime.GOOS, "")
+			if err != nil {
+				return err
+			}
+			return runCommands(commands, dryRun)
+		},
+	}
+	disableCmd.Flags().Bool("dry-run", false, "Print the commands without running them")
+
+	systemCmd.AddCommand(enableCmd, disableCmd)
+	return systemCmd
+}
+
+// systemProxyCommands returns the commands setting the automatic proxy
+// configuration URL of goos to pacURL, or turning it off if pacURL is empty
+func systemProxyCommands(goos, pacURL string) ([][]string, error) {
+	switch goos {
+	case "darwin":
+		services, err := networkServices()
+		if err != nil {
+			return nil, err
+		}
+		var commands [][]string
+		for _, service := range services {
+			if pacURL == "" {
+				commands = append(commands, []string{"networksetup", "-setautoproxystate", service, "off"})
+			} else {
+				commands = append(commands, []string{"networksetup", "-setautoproxyurl", service, pacURL})
+			}
+		}
+		return commands, nil
+	case "windows":
+		key := `HKCU\Software\Microsoft\Windows\CurrentVersion\Internet Settings`
+		if pacURL == "" {
+			return [][]string{{"reg", "delete", key, "/v", "AutoConfigURL", "/f"}}, nil
+		}
+		return [][]string{{"reg", "add", key, "/v", "AutoConfigURL", "/t", "REG_SZ", "/d", pacURL, "/f"}}, nil
+	case "linux":
+		if _, err := exec.LookPath("gsettings"); err == nil {
+			if pacURL == "" {
+				return [][]string{{"gsettings", "set", "org.gnome.system.proxy", "mode", "none"}}, nil
+			}
+			return [][]string{
+				{"gsettings", "set", "org.gnome.system.proxy", "autoconfig-url", pacURL},
+				{"gsettings", "set", "org.gnome.system.proxy", "mode", "auto"},
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("no known proxy settings on this system; configure the automatic proxy configuration URL manually")
+}
+
+// networkServices returns the enabled network services of macOS
+func networkServices() ([]string, error) {
+	out, err := exec.Command("networksetup", "-listallnetworkservices").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list network services: %v", err)
+	}
+	var services []string
+	// The first line is a notice, disabled services start with *
+	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n")[1:] {
+		if line = strings.TrimSpace(line); line != "" && !strings.HasPrefix(line, "*") {
+			services = append(services, line)
+		}
+	}
+	return services, nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+	"github.com/happytaoer/prompt-security/internal/report"
+	"github.com/spf13/cobra"
+)
+
+// newReportCmd creates the `report` command summarizing a day or week of
+// detections
+func newReportCmd() *cobra.Command {
+	reportCmd := &cobra.Command{
+		Use:   "report",
+		Short: "Summarize the detections and policy changes of a day or week",
+		Long: `Print a summary of the detections logged over a day or a week: totals and
+the trend against the period before, the top types and source applications,
+detections by day and the configuration and policy changes made meanwhile.
+The detected text itself is never included.
+
+The period ends on --until, today by default, and is printed as Markdown,
+an HTML page for sharing, or JSON:
+
+  prompt-security report --period week --format html > week.html
+
+With --email the report is e-mailed to the configured report recipients
+instead, as the running instance does each Monday for the week before.`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			period, _ := cmd.Flags().GetString("period")
+			format, _ := cmd.Flags().GetString("format")
+			untilFlag, _ := cmd.Flags().GetString("until")
+			email, _ := cmd.Flags().GetBool("email")
+			if !report.ValidPeriod(period) {
+				return fmt.Errorf("unknown period %q, expected %s or %s", period, report.PeriodDay, report.PeriodWeek)
+			}
+			if !report.ValidFormat(format) {
+				return fmt.Errorf("unknown format %q, expected %s, %s or %s", format, report.FormatMarkdown, report.FormatHTML, report.FormatJSON)
+			}
+			until := time.Now()
+			if untilFlag != "" {
+				var err error
+				if until, err = time.ParseInLocation("2006-01-02", untilFlag, time.Local); err != nil {
+					return fmt.Errorf("invalid --until date %q, expected YYYY-MM-DD", untilFlag)
+				}
+			}
+
+			r, err := report.Generate(period, until)
+			if err != nil {
+				return err
+			}
+			if email {
+				cfg, err := config.Load()
+				if err != nil {
+					return err
+				}
+				if err := report.Send(cfg, r); err != nil {
+					return fmt.Errorf("failed to e-mail report: %v", err)
+				}
+				fmt.Fprintf(os.Stderr, "E-mailed the report to %s\n", strings.Join(cfg.ReportRecipients, ", "))
+				return nil
+			}
+			switch format {
+			case report.FormatJSON:
+				enc := json.NewEncoder(os.Stdout)
+				enc.SetIndent("", "  ")
+				return enc.Encode(r)
+			case report.FormatHTML:
+				page, err := r.HTML()
+				if err != nil {
+					return err
+				}
+				fmt.Print(page)
+			default:
+				fmt.Print(r.Markdown())
+			}
+			return nil
+		},
+	}
+	reportCmd.Flags().String("period", report.PeriodWeek, "Period to summarize (day, week)")
+	reportCmd.Flags().String("until", "", "Last day of the period as YYYY-MM-DD (default today)")
+	reportCmd.Flags().String("format", report.FormatMarkdown, "Output format (markdown, html, json)")
+	reportCmd.Flags().Bool("email", false, "E-mail the report to the configured recipients instead of printing it")
+	return reportCmd
+}
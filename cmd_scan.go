@@ -0,0 +1,388 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+	"github.com/happytaoer/prompt-security/internal/db"
+	"github.com/happytaoer/prompt-security/internal/filter"
+	"github.com/happytaoer/prompt-security/internal/scan"
+	"github.com/spf13/cobra"
+)
+
+// newScanCmd creates the `scan` command finding sensitive data in files
+func newScanCmd() *cobra.Command {
+	scanCmd := &cobra.Command{
+		Use:   "scan [path...]",
+		Short: "Find sensitive data in files, PDF and Word documents included",
+		Long: `Scan files and directories with the current configuration and list where
+sensitive data is, as path:line:column: type (action). The values themselves
+are never printed. Text is extracted from PDF and Word (.docx) documents;
+other binary files are skipped. Zip, tar, tar.gz and gzip archives are
+descended into, nested up to --archive-depth levels, and their files reported
+as archive.zip!path/in/archive; extraction stops after --max-archive-size MB
+so archive bombs cannot exhaust memory.
+
+Images, media and other binaries are recognized from their first bytes and
+skipped without being read. .git, node_modules and vendor directories are
+skipped, as are files matching --exclude or a .psignore, which lists
+patterns like a .gitignore and applies to its directory and below:
+
+  *.log
+  testdata/
+  /docs/examples/**/*.md
+  !vendor/
+
+With --redact a redacted copy is written next to each file with values to
+replace: name.redacted.ext for text files and Word documents, whose
+formatting is kept, and the redacted text as name.redacted.txt for PDF files.
+Files inside archives are reported but not redacted.
+
+To adopt scanning on an existing repository, accept what is there in a
+baseline and fail only on new findings afterwards:
+
+  prompt-security scan . --baseline findings.json --update-baseline
+  prompt-security scan . --baseline findings.json
+
+Findings are matched by file, detector and an HMAC of the value, so moving a
+value within its file keeps it accepted; the values are not stored. The HMAC
+key is --baseline-key, or else a key generated in the data directory.
+
+Intentional values like test fixtures can be acknowledged in the file itself:
+ps:ignore exempts its line and ps:ignore-next-line the next one, from every
+detector or only those named with rule=. Suppressed findings are listed in a
+section of their own:
+
+  // ps:ignore-next-line rule=email,phone
+  fixture := "jane@example.com"
+
+With --git-history the lines added by each commit of the git repositories at
+the paths are scanned instead of the working tree, newest first, and findings
+name the commit, its author and date, to find sensitive data committed and
+since deleted. --git-range limits the commits, e.g. to a branch:
+
+  prompt-security scan --git-history --git-range main..HEAD
+
+Files are scanned by --jobs workers at once, with files per second and
+findings so far shown on a terminal, and findings are summarized by detector
+and by file at the end. Exits with status 1 if anything is found, so scans
+can gate builds. Scans the current directory if no path is given.`,
+		Args:          cobra.ArbitraryArgs,
+		SilenceUsage:  true,
+		SilenceErrors: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			profile, _ := cmd.Flags().GetString("profile")
+			redact, _ := cmd.Flags().GetBool("redact")
+			asJSON, _ := cmd.Flags().GetBool("json")
+			maxSize, _ := cmd.Flags().GetInt64("max-size")
+			jobs, _ := cmd.Flags().GetInt("jobs")
+			showProgress, _ := cmd.Flags().GetBool("progress")
+			baselinePath, _ := cmd.Flags().GetString("baseline")
+			updateBaseline, _ := cmd.Flags().GetBool("update-baseline")
+			archiveDepth, _ := cmd.Flags().GetInt("archive-depth")
+			maxArchiveSize, _ := cmd.Flags().GetInt64("max-archive-size")
+			exclude, _ := cmd.Flags().GetStringSlice("exclude")
+			gitHistory, _ := cmd.Flags().GetBool("git-history")
+			gitRange, _ := cmd.Flags().GetString("git-range")
+			if !config.ValidProfile(profile) {
+				return fmt.Errorf("unknown profile %q, expected %s, %s or %s", profile, config.ProfileStandard, config.ProfileStrict, config.ProfileOff)
+			}
+			if maxSize <= 0 || maxArchiveSize <= 0 {
+				return fmt.Errorf("--max-size and --max-archive-size must be positive")
+			}
+			if archiveDepth < 0 {
+				return fmt.Errorf("--archive-depth must not be negative")
+			}
+			if gitRange != "" && !gitHistory {
+				return fmt.Errorf("--git-range requires --git-history")
+			}
+			if gitHistory && redact {
+				return fmt.Errorf("--redact cannot be used with --git-history")
+			}
+			if len(args) == 0 {
+				args = []string{"."}
+			}
+
+			if updateBaseline && baselinePath == "" {
+				return fmt.Errorf("--update-baseline requires --baseline")
+			}
+			var baseline *scan.Baseline
+			var hashKey []byte
+			if baselinePath != "" {
+				keyValue, _ := cmd.Flags().GetString("baseline-key")
+				var err error
+				if hashKey, err = baselineKey(keyValue, updateBaseline); err != nil {
+					return err
+				}
+				if !updateBaseline {
+					if baseline, err = scan.LoadBaseline(baselinePath, hashKey); err != nil {
+						return err
+					}
+				}
+			}
+
+			cfg, err := config.Load()
+			if err != nil {
+				return err
+			}
+			scanner := scan.New(filter.NewDetectorSet(config.ApplyProfile(cfg, profile)), scan.Options{
+				MaxSize:        maxSize << 20,
+				Redact:         redact,
+				ArchiveDepth:   archiveDepth,
+				MaxArchiveSize: maxArchiveSize << 20,
+				Exclude:        exclude,
+				Jobs:           jobs,
+				Baseline:       baseline,
+				HashKey:        hashKey,
+			})
+
+			findings, suppressed := []scan.Finding{}, []scan.Finding{}
+			var tally scan.Tally
+			progress := newScanProgress(showProgress && isTerminal(os.Stderr))
+			stop := progress.run()
+			started := time.Now()
+			report := func(r scan.Result) {
+				progress.mu.Lock()
+				defer progress.mu.Unlock()
+				progress.clear()
+				tally.Add(r)
+				progress.files, progress.findings = tally.Files, tally.Findings
+
+				if r.Err != nil {
+					fmt.Fprintf(os.Stderr, "%s: %v\n", r.File, r.Err)
+				}
+				if r.Skipped != "" && r.Skipped != scan.SkippedBinary {
+					fmt.Fprintf(os.Stderr, "%s: skipped, %s\n", r.File, r.Skipped)
+				}
+				findings = append(findings, r.Findings...)
+				suppressed = append(suppressed, r.Suppressed...)
+				if !asJSON {
+					for _, f := range r.Findings {
+						printFinding("", f)
+					}
+				}
+				if r.Redacted != "" {
+					fmt.Fprintf(os.Stderr, "Wrote %s\n", r.Redacted)
+				}
+			}
+			if gitHistory {
+				for _, dir := range args {
+					if err = scanner.History(dir, gitRange, report); err != nil {
+						break
+					}
+				}
+			} else {
+				err = scanner.Walk(args, report)
+			}
+			stop()
+			if err != nil {
+				return err
+			}
+
+			if asJSON {
+				data, err := json.MarshalIndent(map[string][]scan.Finding{"findings": findings, "suppressed": suppressed}, "", "  ")
+				if err != nil {
+					return err
+				}
+				fmt.Println(string(data))
+			} else {
+				if len(suppressed) > 0 {
+					fmt.Println("\nSuppressed by ps:ignore:")
+					for _, f := range suppressed {
+						printFinding("  ", f)
+					}
+				}
+				if err := printScanSummary(&tally, time.Since(started)); err != nil {
+					return err
+				}
+			}
+			if updateBaseline {
+				if err := scan.NewBaseline(findings, hashKey).Save(baselinePath); err != nil {
+					return err
+				}
+				fmt.Fprintf(os.Stderr, "Wrote a baseline of %d findings to %s\n", tally.Findings, baselinePath)
+				return nil
+			}
+			if tally.Failed > 0 {
+				return fmt.Errorf("failed to scan %d files", tally.Failed)
+			}
+			if tally.Findings > 0 {
+				return fmt.Errorf("%d findings in %d files", tally.Findings, tally.FilesWithFindings())
+			}
+			return nil
+		},
+	}
+	scanCmd.Flags().String("profile", config.ProfileStandard, "Detection profile to apply (standard, strict, off)")
+	scanCmd.Flags().Bool("redact", false, "Write a redacted copy of each file with values to replace")
+	scanCmd.Flags().Bool("json", false, "Print the findings as JSON")
+	scanCmd.Flags().String("baseline", "", "Suppress the findings accepted in this baseline file")
+	scanCmd.Flags().Bool("update-baseline", false, "Write all findings to the --baseline file instead of failing on them")
+	scanCmd.Flags().String("baseline-key", "", "Secret keying the hashes in the baseline (default a key generated in the data directory)")
+	scanCmd.Flags().IntP("jobs", "j", 0, "Files to scan at once (default the number of CPUs)")
+	scanCmd.Flags().Bool("progress", true, "Show progress when standard error is a terminal")
+	scanCmd.Flags().Int64("max-size", scan.DefaultMaxSize>>20, "Skip files larger than this many MB")
+	scanCmd.Flags().StringSlice("exclude", nil, "Skip files and directories matching a .psignore pattern, e.g. dist/ or *.min.js, repeatable")
+	scanCmd.Flags().Int("archive-depth", scan.DefaultArchiveDepth, "Levels of nested zip and tar.gz archives to descend into, 0 to skip archives")
+	scanCmd.Flags().Int64("max-archive-size", scan.DefaultMaxArchiveSize>>20, "Stop extracting an archive after this many MB")
+	scanCmd.Flags().Bool("git-history", false, "Scan the lines added by each commit of the git repositories instead of the files")
+	scanCmd.Flags().String("git-range", "", "Commits to scan with --git-history, e.g. main..HEAD (default HEAD and its ancestors)")
+	return scanCmd
+}
+
+// baselineKeyFile is the file in the data directory holding the default
+// baseline key
+const baselineKeyFile = "baseline.key"
+
+// baselineKey returns the key of the baseline hashes: value if set, else the
+// key in the data directory, generated when create is set and there is none
+func baselineKey(value string, create bool) ([]byte, error) {
+	if value != "" {
+		return []byte(value), nil
+	}
+	dir, err := db.DataDir()
+	if err != nil {
+		return nil, err
+	}
+	path := filepath.Join(dir, baselineKeyFile)
+	key, err := os.ReadFile(path)
+	if err == nil {
+		return key, nil
+	}
+	if !os.IsNotExist(err) || !create {
+		return nil, fmt.Errorf("failed to read the baseline key, set --baseline-key to the key the baseline was written with: %v", err)
+	}
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("failed to generate a baseline key: %v", err)
+	}
+	key = []byte(hex.EncodeToString(buf))
+	if err := os.WriteFile(path, key, 0600); err != nil {
+		return nil, fmt.Errorf("failed to write the baseline key: %v", err)
+	}
+	fmt.Fprintf(os.Stderr, "Generated a baseline key in %s\n", path)
+	return key, nil
+}
+
+// printFinding prints where a finding is, and the commit that added it
+// when scanning history
+func printFinding(indent string, f scan.Finding) {
+	if f.Commit == "" {
+		fmt.Printf("%s%s:%d:%d: %s (%s)\n", indent, f.File, f.Line, f.Column, f.Type, f.Action)
+		return
+	}
+	fmt.Printf("%s%s:%d:%d: %s (%s) in %.12s by %s on %.10s\n", indent, f.File, f.Line, f.Column, f.Type, f.Action, f.Commit, f.Author, f.Date)
+}
+
+// maxSummaryFiles bounds the files listed in the scan summary
+const maxSummaryFiles = 20
+
+// printScanSummary prints the findings of a scan by detector and by file
+func printScanSummary(tally *scan.Tally, elapsed time.Duration) error {
+	fmt.Printf("\nScanned %d files in %s (%.0f files/s), %d skipped\n", tally.Files, elapsed.Round(time.Millisecond), rate(tally.Files, elapsed), tally.Skipped)
+	if tally.Known > 0 {
+		fmt.Printf("%d known findings suppressed by the baseline\n", tally.Known)
+	}
+	if tally.Suppressed > 0 {
+		fmt.Printf("%d findings suppressed by ps:ignore annotations\n", tally.Suppressed)
+	}
+	if tally.Findings == 0 {
+		fmt.Println("No new sensitive data found")
+		return nil
+	}
+
+	fmt.Println()
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "DETECTOR\tFINDINGS\tFILES")
+	for _, tc := range tally.ByType() {
+		fmt.Fprintf(tw, "%s\t%d\t%d\n", tc.Type, tc.Findings, tc.Files)
+	}
+	fmt.Fprintln(tw)
+	fmt.Fprintln(tw, "FILE\tFINDINGS\tDETECTORS")
+	files := tally.ByFile()
+	for i, fc := range files {
+		if i == maxSummaryFiles {
+			fmt.Fprintf(tw, "... %d more files\t\t\n", len(files)-maxSummaryFiles)
+			break
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\n", fc.File, fc.Findings, strings.Join(fc.Types, ", "))
+	}
+	return tw.Flush()
+}
+
+// rate returns n per second
+func rate(n int, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(n) / elapsed.Seconds()
+}
+
+// scanProgress keeps a progress line at the bottom of a terminal. Output
+// written while holding mu must call clear first.
+type scanProgress struct {
+	mu       sync.Mutex
+	enabled  bool
+	started  time.Time
+	files    int
+	findings int
+	shown    bool
+}
+
+func newScanProgress(enabled bool) *scanProgress {
+	return &scanProgress{enabled: enabled, started: time.Now()}
+}
+
+// run redraws the progress line until the returned function is called
+func (p *scanProgress) run() func() {
+	if !p.enabled {
+		return func() {}
+	}
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(200 * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				p.mu.Lock()
+				fmt.Fprintf(os.Stderr, "\r\033[K%d files, %.0f files/s, %d findings", p.files, rate(p.files, time.Since(p.started)), p.findings)
+				p.shown = true
+				p.mu.Unlock()
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+		p.mu.Lock()
+		p.clear()
+		p.mu.Unlock()
+	}
+}
+
+// clear erases the progress line, if shown
+func (p *scanProgress) clear() {
+	if p.shown {
+		fmt.Fprint(os.Stderr, "\r\033[K")
+		p.shown = false
+	}
+}
+
+// isTerminal reports whether f is a terminal
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
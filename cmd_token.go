@@ -0,0 +1,78 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/happytaoer/prompt-security/internal/db"
+	"github.com/happytaoer/prompt-security/internal/web"
+	"github.com/spf13/cobra"
+)
+
+// newTokenCmd creates the `token` command for managing API access tokens
+func newTokenCmd() *cobra.Command {
+	tokenCmd := &cobra.Command{
+		Use:   "token",
+		Short: "Manage web API access tokens",
+		Long: `Manage bearer tokens for the web API. While no tokens exist the API is open
+to local callers; once a token is created every API request must present one.`,
+	}
+
+	createCmd := &cobra.Command{
+		Use:   "create <name>",
+		Short: "Create a token and print its secret",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			roleName, _ := cmd.Flags().GetString("role")
+			role, err := web.ParseRole(roleName)
+			if err != nil {
+				return err
+			}
+
+			secret, err := db.CreateAPIToken(args[0], string(role))
+			if err != nil {
+				return err
+			}
+
+			fmt.Printf("Created %s token %q. Store this secret now, it cannot be shown again:\n\n%s\n", role, args[0], secret)
+			return nil
+		},
+	}
+	createCmd.Flags().String("role", string(web.RoleViewer), "Role for the token (viewer, operator, admin)")
+
+	listCmd := &cobra.Command{
+		Use:   "list",
+		Short: "List tokens",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			tokens, err := db.ListAPITokens()
+			if err != nil {
+				return err
+			}
+
+			tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "NAME\tROLE\tCREATED\tLAST USED")
+			for _, t := range tokens {
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", t.Name, t.Role, t.CreatedAt, t.LastUsedAt)
+			}
+			return tw.Flush()
+		},
+	}
+
+	revokeCmd := &cobra.Command{
+		Use:   "revoke <name>",
+		Short: "Revoke a token",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := db.DeleteAPIToken(args[0]); err != nil {
+				return err
+			}
+			fmt.Printf("Revoked token %q\n", args[0])
+			return nil
+		},
+	}
+
+	tokenCmd.AddCommand(createCmd, listCmd, revokeCmd)
+	return tokenCmd
+}
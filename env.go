@@ -0,0 +1,41 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+)
+
+// envPrefix prefixes the environment variables that set flags
+const envPrefix = "PROMPT_SECURITY_"
+
+// envFlags are the flags that can be set through the environment, e.g.
+// --monitoring-interval as PROMPT_SECURITY_MONITORING_INTERVAL
+var envFlags = []string{"port", "bind", "monitoring-interval", "data-dir", "db", "no-persist", "demo", "upstream", "api-key", "baseline-key", "slack-app-token", "slack-bot-token", "slack-user-token", "discord-token"}
+
+// envName returns the environment variable that sets a flag
+func envName(flag string) string {
+	return envPrefix + strings.ToUpper(strings.ReplaceAll(flag, "-", "_"))
+}
+
+// applyEnv sets the flags of cmd that were not given on the command line from
+// their environment variables. Flags given on the command line win, and
+// either wins over the configuration in the database.
+func applyEnv(cmd *cobra.Command) error {
+	for _, name := range envFlags {
+		flag := cmd.Flags().Lookup(name)
+		if flag == nil || flag.Changed {
+			continue
+		}
+		value, ok := os.LookupEnv(envName(name))
+		if !ok {
+			continue
+		}
+		if err := cmd.Flags().Set(name, value); err != nil {
+			return fmt.Errorf("invalid %s: %v", envName(name), err)
+		}
+	}
+	return nil
+}
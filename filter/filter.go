@@ -0,0 +1,40 @@
+// Package filter is the library API of the detection engine, for Go
+// programs redacting sensitive data the way the daemon redacts copies.
+//
+// The types are those of the daemon itself. Build a Config with the
+// detectors to enable, create an Engine and call Filter; SetReplacementFunc
+// plugs in a replacement strategy of your own, such as vault tokenization or
+// format-preserving encryption, without forking the filter.
+package filter
+
+import (
+	"github.com/happytaoer/prompt-security/internal/config"
+	"github.com/happytaoer/prompt-security/internal/filter"
+)
+
+type (
+	// Config selects the detectors and their replacements and actions
+	Config = config.Config
+
+	// Engine filters text with the detectors of a Config and may be used
+	// from any goroutine
+	Engine = filter.Engine
+
+	// Match is a piece of sensitive data found in text
+	Match = filter.Match
+
+	// ReplacementFunc returns the text replacing a match, see
+	// Engine.SetReplacementFunc
+	ReplacementFunc = filter.ReplacementFunc
+
+	// ReplacementSummary lists what Filter replaced
+	ReplacementSummary = filter.ReplacementSummary
+
+	// ReplacementInfo is one replacement made by Filter
+	ReplacementInfo = filter.ReplacementInfo
+)
+
+// NewEngine creates an engine for cfg
+func NewEngine(cfg Config) *Engine {
+	return filter.NewEngine(cfg)
+}
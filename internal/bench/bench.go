@@ -0,0 +1,160 @@
+// Package bench measures how accurately the detectors find sensitive data in
+// labeled corpora.
+package bench
+
+import (
+	"bufio"
+	"bytes"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/happytaoer/prompt-security/internal/filter"
+)
+
+// BundledName names the bundled corpus in reports
+const BundledName = "bundled"
+
+//go:embed corpus.jsonl
+var bundledCorpus []byte
+
+// Sample is a labeled text of a corpus
+type Sample struct {
+	Text   string  `json:"text"`
+	Labels []Label `json:"labels"` // Sensitive data in Text, empty if none
+}
+
+// Label is a piece of sensitive data a sample contains
+type Label struct {
+	Type  string `json:"type"`  // Detector type, e.g. "email"
+	Value string `json:"value"` // Text the detector should match
+}
+
+// Score counts the outcomes of one detector type over a corpus
+type Score struct {
+	Type           string `json:"type"`
+	TruePositives  int    `json:"true_positives"`
+	FalsePositives int    `json:"false_positives"`
+	FalseNegatives int    `json:"false_negatives"`
+}
+
+// Precision returns the share of matches that were labeled, NaN if there
+// were no matches
+func (s Score) Precision() float64 {
+	return ratio(s.TruePositives, s.TruePositives+s.FalsePositives)
+}
+
+// Recall returns the share of labels that were matched, NaN if there were
+// no labels
+func (s Score) Recall() float64 {
+	return ratio(s.TruePositives, s.TruePositives+s.FalseNegatives)
+}
+
+func ratio(n, total int) float64 {
+	if total == 0 {
+		return math.NaN()
+	}
+	return float64(n) / float64(total)
+}
+
+// Bundled returns the samples of the corpus shipped with the binary
+func Bundled() ([]Sample, error) {
+	return Parse(bytes.NewReader(bundledCorpus), BundledName)
+}
+
+// Load reads a corpus file
+func Load(path string) ([]Sample, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f, path)
+}
+
+// Parse reads a corpus of one JSON sample per line. Blank lines and lines
+// starting with # are skipped; name identifies the corpus in errors.
+func Parse(r io.Reader, name string) ([]Sample, error) {
+	var samples []Sample
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+
+		var s Sample
+		if err := json.Unmarshal([]byte(text), &s); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", name, line, err)
+		}
+		for _, l := range s.Labels {
+			if !strings.Contains(s.Text, l.Value) {
+				return nil, fmt.Errorf("%s:%d: label %q does not occur in the text", name, line, l.Value)
+			}
+		}
+		samples = append(samples, s)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	return samples, nil
+}
+
+// Evaluate filters every sample with ds and scores the matches against the
+// labels by type and matched text. Scores are sorted by type.
+func Evaluate(ds *filter.DetectorSet, samples []Sample) []Score {
+	scores := make(map[string]*Score)
+	score := func(typ string) *Score {
+		s, ok := scores[typ]
+		if !ok {
+			s = &Score{Type: typ}
+			scores[typ] = s
+		}
+		return s
+	}
+
+	for _, sample := range samples {
+		expected := make(map[Label]int, len(sample.Labels))
+		for _, l := range sample.Labels {
+			expected[l]++
+		}
+
+		_, _, summary := ds.Filter(sample.Text)
+		for _, r := range summary.Replacements {
+			l := Label{Type: r.Type, Value: r.Original}
+			if expected[l] > 0 {
+				expected[l]--
+				score(r.Type).TruePositives++
+			} else {
+				score(r.Type).FalsePositives++
+			}
+		}
+		for l, missed := range expected {
+			score(l.Type).FalseNegatives += missed
+		}
+	}
+
+	result := make([]Score, 0, len(scores))
+	for _, s := range scores {
+		result = append(result, *s)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Type < result[j].Type })
+	return result
+}
+
+// Total sums scores over all types
+func Total(scores []Score) Score {
+	total := Score{Type: "total"}
+	for _, s := range scores {
+		total.TruePositives += s.TruePositives
+		total.FalsePositives += s.FalsePositives
+		total.FalseNegatives += s.FalseNegatives
+	}
+	return total
+}
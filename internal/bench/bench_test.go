@@ -0,0 +1,96 @@
+package bench
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+	"github.com/happytaoer/prompt-security/internal/filter"
+)
+
+// TestBundled tests that the bundled corpus parses and covers every
+// built-in detector
+func TestBundled(t *testing.T) {
+	samples, err := Bundled()
+	if err != nil {
+		t.Fatalf("Failed to parse the bundled corpus: %v", err)
+	}
+
+	types := make(map[string]bool)
+	for _, s := range samples {
+		for _, l := range s.Labels {
+			types[l.Type] = true
+		}
+	}
+	for _, typ := range []string{filter.SensitiveTypeEmail, filter.SensitiveTypePhone, filter.SensitiveTypeCreditCard, filter.SensitiveTypeSSN, filter.SensitiveTypeIPV4} {
+		if !types[typ] {
+			t.Errorf("Expected the bundled corpus to label %s", typ)
+		}
+	}
+}
+
+// TestParse tests corpus parsing errors
+func TestParse(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		err   string
+	}{
+		{"Comments and blank lines", "# corpus\n\n{\"text\": \"a@b.co\", \"labels\": [{\"type\": \"email\", \"value\": \"a@b.co\"}]}\n", ""},
+		{"Invalid JSON", "{\"text\": \"x\"}\n{oops}\n", "corpus:2:"},
+		{"Label not in text", "{\"text\": \"x\", \"labels\": [{\"type\": \"email\", \"value\": \"a@b.co\"}]}", "does not occur"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tt.input), "corpus")
+			if tt.err == "" && err != nil {
+				t.Errorf("Expected no error, got %v", err)
+			}
+			if tt.err != "" && (err == nil || !strings.Contains(err.Error(), tt.err)) {
+				t.Errorf("Expected an error containing %q, got %v", tt.err, err)
+			}
+		})
+	}
+}
+
+// TestEvaluate tests scoring matches against labels
+func TestEvaluate(t *testing.T) {
+	ds := filter.NewDetectorSet(config.Config{
+		DetectEmails:     true,
+		EmailReplacement: "[EMAIL]",
+		DetectIPV4:       true,
+		IPV4Replacement:  "[IP]",
+	})
+	samples := []Sample{
+		{Text: "a@example.com and a@example.com", Labels: []Label{{"email", "a@example.com"}, {"email", "a@example.com"}}},
+		{Text: "version 1.2.3.4", Labels: nil},
+		{Text: "host 10.0.0.1, phone 555-123-4567", Labels: []Label{{"ipv4", "10.0.0.1"}, {"phone", "555-123-4567"}}},
+	}
+
+	scores := Evaluate(ds, samples)
+	want := []Score{
+		{Type: "email", TruePositives: 2},
+		{Type: "ipv4", TruePositives: 1, FalsePositives: 1},
+		{Type: "phone", FalseNegatives: 1},
+	}
+	if len(scores) != len(want) {
+		t.Fatalf("Expected %d scores, got %+v", len(want), scores)
+	}
+	for i := range want {
+		if scores[i] != want[i] {
+			t.Errorf("Expected %+v, got %+v", want[i], scores[i])
+		}
+	}
+
+	if p := scores[1].Precision(); p != 0.5 {
+		t.Errorf("Expected ipv4 precision 0.5, got %v", p)
+	}
+	if p := scores[2].Precision(); !math.IsNaN(p) {
+		t.Errorf("Expected undefined phone precision, got %v", p)
+	}
+	if total := Total(scores); total.TruePositives != 3 || total.FalsePositives != 1 || total.FalseNegatives != 1 {
+		t.Errorf("Unexpected total %+v", total)
+	}
+}
@@ -0,0 +1,89 @@
+package bench
+
+import (
+	"sort"
+
+	"github.com/happytaoer/prompt-security/internal/db"
+	"github.com/happytaoer/prompt-security/internal/filter"
+)
+
+// ReplayEntry compares what was detected in a logged copy with what is
+// detected now
+type ReplayEntry struct {
+	ID        int      `json:"id"`
+	Timestamp string   `json:"timestamp"`
+	Before    []string `json:"before"`   // Types detected when logged
+	After     []string `json:"after"`    // Types detected now
+	Filtered  string   `json:"filtered"` // The copy as it would be filtered now
+	Changed   bool     `json:"changed"`  // Before and After differ
+}
+
+// ReplayReport is the result of re-running logged copies through detectors
+type ReplayReport struct {
+	Scanned int            `json:"scanned"` // Entries re-run
+	Skipped int            `json:"skipped"` // Hashed entries, whose text is not stored
+	Changed int            `json:"changed"` // Entries whose detections differ
+	Before  map[string]int `json:"before"`  // Detections by type when logged
+	After   map[string]int `json:"after"`   // Detections by type now
+	Entries []ReplayEntry  `json:"entries"`
+}
+
+// Replay filters the original text of every log entry with ds and reports
+// how the detections differ from those logged
+func Replay(ds *filter.DetectorSet, logs []db.LogEntry) ReplayReport {
+	report := ReplayReport{
+		Before:  make(map[string]int),
+		After:   make(map[string]int),
+		Entries: []ReplayEntry{},
+	}
+
+	for _, l := range logs {
+		if l.Hashed {
+			report.Skipped++
+			continue
+		}
+
+		filtered, _, summary := ds.Filter(l.OriginalText)
+		after := make([]string, len(summary.Replacements))
+		for i, r := range summary.Replacements {
+			after[i] = r.Type
+			report.After[r.Type]++
+		}
+		before := append([]string{}, l.Detections...)
+		for _, t := range before {
+			report.Before[t]++
+		}
+
+		entry := ReplayEntry{
+			ID:        l.ID,
+			Timestamp: l.Timestamp,
+			Before:    before,
+			After:     after,
+			Filtered:  filtered,
+			Changed:   !sameTypes(before, after),
+		}
+		if entry.Changed {
+			report.Changed++
+		}
+		report.Scanned++
+		report.Entries = append(report.Entries, entry)
+	}
+	return report
+}
+
+// sameTypes reports whether a and b hold the same types, in any order
+func sameTypes(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	a = append([]string{}, a...)
+	b = append([]string{}, b...)
+	sort.Strings(a)
+	sort.Strings(b)
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
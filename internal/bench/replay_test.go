@@ -0,0 +1,38 @@
+package bench
+
+import (
+	"testing"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+	"github.com/happytaoer/prompt-security/internal/db"
+	"github.com/happytaoer/prompt-security/internal/filter"
+)
+
+// TestReplay tests comparing logged detections with a new configuration
+func TestReplay(t *testing.T) {
+	ds := filter.NewDetectorSet(config.Config{
+		DetectEmails:     true,
+		EmailReplacement: "[EMAIL]",
+		DetectSSNs:       true,
+		SSNReplacement:   "[SSN]",
+	})
+	logs := []db.LogEntry{
+		{ID: 3, OriginalText: "a@example.com 123-45-6789", Detections: []string{"ssn", "email"}},
+		{ID: 2, OriginalText: "call 555-123-4567 or b@example.com", Detections: []string{"phone", "email"}},
+		{ID: 1, OriginalText: "hmac-sha256:00", Detections: []string{"email"}, Hashed: true},
+	}
+
+	report := Replay(ds, logs)
+	if report.Scanned != 2 || report.Skipped != 1 || report.Changed != 1 {
+		t.Fatalf("Expected 2 scanned, 1 skipped and 1 changed, got %+v", report)
+	}
+	if report.Entries[0].Changed || !report.Entries[1].Changed {
+		t.Errorf("Expected only the phone log to change, got %+v", report.Entries)
+	}
+	if got := report.Entries[1].Filtered; got != "call 555-123-4567 or [EMAIL]" {
+		t.Errorf("Unexpected filtered text %q", got)
+	}
+	if report.Before["phone"] != 1 || report.After["phone"] != 0 || report.Before["email"] != 2 || report.After["email"] != 2 {
+		t.Errorf("Unexpected totals, before %v, after %v", report.Before, report.After)
+	}
+}
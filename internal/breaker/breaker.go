@@ -0,0 +1,264 @@
+// Package breaker trips a circuit breaker when high severity detections pile
+// up, e.g. because a script copies a secrets file over and over. Once
+// tripped every detector blocks until the cooldown ends or the breaker is
+// reset, and the configured webhook is notified. The tripped state is kept
+// in the database, so it applies to every process sharing it.
+package breaker
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+	"github.com/happytaoer/prompt-security/internal/db"
+)
+
+const (
+	// Severity is the least severity of detections the breaker counts
+	Severity = config.SeverityHigh
+
+	// checkInterval is how often Run checks whether the cooldown is over
+	checkInterval = 15 * time.Second
+
+	// notifyTimeout bounds a single webhook request
+	notifyTimeout = 30 * time.Second
+)
+
+// Events posted to the webhook
+const (
+	EventTripped = "tripped"
+	EventReset   = "reset"
+)
+
+// Status is the state of the circuit breaker
+type Status struct {
+	Enabled   bool       `json:"enabled"`
+	Tripped   bool       `json:"tripped"`
+	TrippedAt *time.Time `json:"tripped_at,omitempty"`
+	Until     *time.Time `json:"until,omitempty"` // End of the cooldown, nil while tripped until reset
+	Recent    int        `json:"recent"`          // Counted detections within the window
+}
+
+// Event is the body posted to the webhook
+type Event struct {
+	Event         string     `json:"event"`
+	Time          time.Time  `json:"time"`
+	Detections    int        `json:"detections"` // Counted detections that tripped the breaker
+	Threshold     int        `json:"threshold"`
+	WindowMinutes int        `json:"window_minutes"`
+	Until         *time.Time `json:"until,omitempty"`
+}
+
+// Breaker counts high severity detections and locks the configuration down
+// while tripped
+type Breaker struct {
+	manager *config.Manager
+	mu      sync.Mutex
+	recent  []time.Time // Times of counted detections within the window, oldest first
+	client  *http.Client
+	logger  *slog.Logger
+	now     func() time.Time
+}
+
+// New creates a circuit breaker locking down manager's configuration
+func New(manager *config.Manager) *Breaker {
+	return &Breaker{
+		manager: manager,
+		client:  &http.Client{Timeout: notifyTimeout},
+		logger:  slog.New(slog.NewJSONHandler(os.Stdout, nil)),
+		now:     time.Now,
+	}
+}
+
+// Record counts the detections of the given types, one per entry, that are
+// at least high severity and trips the breaker once more than the
+// configured threshold were counted within the window
+func (b *Breaker) Record(types []string) {
+	cfg := b.manager.Get()
+	if cfg.BreakerThreshold <= 0 {
+		return
+	}
+	n := 0
+	for _, typ := range types {
+		if config.SeverityAtLeast(config.DetectionSeverity(cfg, typ), Severity) {
+			n++
+		}
+	}
+	if n == 0 {
+		return
+	}
+
+	b.mu.Lock()
+	now := b.now()
+	if b.manager.Lockdown() {
+		b.mu.Unlock()
+		return
+	}
+	for i := 0; i < n; i++ {
+		b.recent = append(b.recent, now)
+	}
+	b.prune(cfg, now)
+	if len(b.recent) <= cfg.BreakerThreshold {
+		b.mu.Unlock()
+		return
+	}
+
+	event := Event{
+		Event:         EventTripped,
+		Time:          now,
+		Detections:    len(b.recent),
+		Threshold:     cfg.BreakerThreshold,
+		WindowMinutes: cfg.BreakerWindowMinutes,
+	}
+	b.recent = nil
+	var until time.Time
+	if cfg.BreakerCooldownMinutes > 0 {
+		until = now.Add(time.Duration(cfg.BreakerCooldownMinutes) * time.Minute)
+		event.Until = &until
+	}
+	b.mu.Unlock()
+
+	// Lock down even if the state cannot be saved
+	tripped, err := db.TripBreaker(now, until)
+	if err != nil {
+		b.logger.Error("Failed to save circuit breaker state", "error", err)
+	} else if !tripped {
+		// Another process tripped it and notified the webhook
+		b.manager.SetLockdown(true)
+		return
+	}
+	b.logger.Warn("Circuit breaker tripped, blocking all detections",
+		"detections", event.Detections, "window_minutes", event.WindowMinutes, "until", event.Until)
+	b.manager.SetLockdown(true)
+	go b.notify(cfg.BreakerWebhook, event)
+}
+
+// Status returns the state of the breaker
+func (b *Breaker) Status() Status {
+	cfg := b.manager.Get()
+	state, err := db.GetBreakerState()
+	if err != nil {
+		b.logger.Error("Failed to read circuit breaker state", "error", err)
+		state.Tripped = b.manager.Lockdown()
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.prune(cfg, b.now())
+
+	status := Status{Enabled: cfg.BreakerThreshold > 0, Tripped: state.Tripped, Recent: len(b.recent)}
+	if !state.TrippedAt.IsZero() {
+		status.TrippedAt = &state.TrippedAt
+	}
+	if !state.Until.IsZero() {
+		status.Until = &state.Until
+	}
+	return status
+}
+
+// Reset closes a tripped breaker, restoring the configured actions in every
+// process
+func (b *Breaker) Reset() error {
+	b.mu.Lock()
+	b.recent = nil
+	now := b.now()
+	b.mu.Unlock()
+
+	tripped, err := db.ResetBreaker()
+	if err != nil {
+		return err
+	}
+	b.manager.SetLockdown(false)
+	if !tripped {
+		return nil
+	}
+
+	cfg := b.manager.Get()
+	b.logger.Info("Circuit breaker reset, restoring configured actions")
+	b.manager.SetLockdown(false)
+	go b.notify(cfg.BreakerWebhook, Event{
+		Event:         EventReset,
+		Time:          now,
+		Threshold:     cfg.BreakerThreshold,
+		WindowMinutes: cfg.BreakerWindowMinutes,
+	})
+	return nil
+}
+
+// Run resets the breaker once its cooldown is over or it is disabled
+// (blocking)
+func (b *Breaker) Run() {
+	for {
+		time.Sleep(checkInterval)
+		b.check(b.manager.Get(), b.now())
+	}
+}
+
+// check resets the breaker if its cooldown ended by t or cfg disables it
+func (b *Breaker) check(cfg config.Config, t time.Time) {
+	state, err := db.GetBreakerState()
+	if err != nil {
+		b.logger.Error("Failed to read circuit breaker state", "error", err)
+		return
+	}
+	expired := state.Tripped &&
+		(cfg.BreakerThreshold <= 0 || (!state.Until.IsZero() && !t.Before(state.Until)))
+	if !expired {
+		return
+	}
+	if err := b.Reset(); err != nil {
+		b.logger.Error("Failed to reset circuit breaker", "error", err)
+	}
+}
+
+// prune drops counted detections older than the window of cfg. The caller
+// must hold mu.
+func (b *Breaker) prune(cfg config.Config, now time.Time) {
+	cutoff := now.Add(-time.Duration(cfg.BreakerWindowMinutes) * time.Minute)
+	i := 0
+	for i < len(b.recent) && !b.recent[i].After(cutoff) {
+		i++
+	}
+	b.recent = b.recent[i:]
+}
+
+// notify posts event to webhook, if set
+func (b *Breaker) notify(webhook string, event Event) {
+	if webhook == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), notifyTimeout)
+	defer cancel()
+	if err := b.post(ctx, webhook, event); err != nil {
+		b.logger.Warn("Failed to notify circuit breaker webhook", "webhook", webhook, "event", event.Event, "error", err)
+	}
+}
+
+// post sends an event as JSON
+func (b *Breaker) post(ctx context.Context, webhook string, event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned %s", resp.Status)
+	}
+	return nil
+}
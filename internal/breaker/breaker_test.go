@@ -0,0 +1,135 @@
+package breaker
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+	"github.com/happytaoer/prompt-security/internal/db"
+)
+
+// newTestBreaker creates a breaker backed by a fresh in-memory database,
+// with a fake clock, whose webhook sends events to the returned channel
+func newTestBreaker(t *testing.T, cfg config.Config) (*Breaker, *time.Time, chan Event) {
+	db.SetPath(db.MemoryPath)
+	if err := config.Initialize(); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	t.Cleanup(func() { config.Close() })
+
+	events := make(chan Event, 4)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var event Event
+		json.NewDecoder(r.Body).Decode(&event)
+		events <- event
+	}))
+	t.Cleanup(server.Close)
+
+	cfg.BreakerWebhook = server.URL
+	now := time.Date(2026, 3, 2, 9, 0, 0, 0, time.UTC)
+	b := New(config.NewStaticManager(cfg))
+	b.client = server.Client()
+	b.now = func() time.Time { return now }
+	return b, &now, events
+}
+
+// receive returns the next event posted to the webhook
+func receive(t *testing.T, events chan Event) Event {
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("Expected the webhook to be notified")
+		return Event{}
+	}
+}
+
+// TestBreaker_Trip tests that only high severity detections within the
+// window count, and that tripping locks down detection and notifies the
+// webhook
+func TestBreaker_Trip(t *testing.T) {
+	b, now, events := newTestBreaker(t, config.Config{
+		DetectEmails:           true,
+		BreakerThreshold:       2,
+		BreakerWindowMinutes:   5,
+		BreakerCooldownMinutes: 30,
+	})
+
+	b.Record([]string{config.DetectorSSN, config.DetectorEmail})
+	*now = now.Add(10 * time.Minute)
+	b.Record([]string{config.DetectorSSN, config.DetectorEmail, config.DetectorCreditCard})
+	if status := b.Status(); status.Tripped || status.Recent != 2 {
+		t.Fatalf("Expected 2 recent detections without tripping, got %+v", status)
+	}
+
+	b.Record([]string{config.DetectorSSN})
+	status := b.Status()
+	if !status.Tripped || status.Until == nil || !status.Until.Equal(now.Add(30*time.Minute)) {
+		t.Fatalf("Expected the breaker to trip for 30 minutes, got %+v", status)
+	}
+	if b.manager.Effective().EmailAction != config.ActionBlock {
+		t.Error("Expected detection to be locked down")
+	}
+	if event := receive(t, events); event.Event != EventTripped || event.Detections != 3 || event.Threshold != 2 {
+		t.Errorf("Unexpected webhook event %+v", event)
+	}
+
+	b.check(b.manager.Get(), now.Add(29*time.Minute))
+	if !b.Status().Tripped {
+		t.Fatal("Expected the breaker to stay tripped during the cooldown")
+	}
+	b.check(b.manager.Get(), now.Add(30*time.Minute))
+	if b.Status().Tripped || b.manager.Lockdown() {
+		t.Error("Expected the breaker to reset after the cooldown")
+	}
+	if event := receive(t, events); event.Event != EventReset {
+		t.Errorf("Expected a reset event, got %+v", event)
+	}
+}
+
+// TestBreaker_Shared tests that a breaker tripped by another process is
+// not tripped again, and that resetting it here resets it there
+func TestBreaker_Shared(t *testing.T) {
+	b, now, events := newTestBreaker(t, config.Config{DetectEmails: true, BreakerThreshold: 1, BreakerWindowMinutes: 5})
+	other := New(config.NewStaticManager(b.manager.Get()))
+	other.now = b.now
+
+	other.Record([]string{config.DetectorSSN, config.DetectorSSN})
+	if !other.manager.Lockdown() || !b.Status().Tripped {
+		t.Fatal("Expected the breaker to be tripped in both processes")
+	}
+	if event := receive(t, events); event.Event != EventTripped {
+		t.Errorf("Expected a tripped event, got %+v", event)
+	}
+
+	*now = now.Add(time.Minute)
+	b.Record([]string{config.DetectorSSN, config.DetectorSSN})
+	if !b.manager.Lockdown() {
+		t.Error("Expected detection to be locked down")
+	}
+	if status := b.Status(); status.TrippedAt == nil || !status.TrippedAt.Equal(now.Add(-time.Minute)) {
+		t.Errorf("Expected the first trip to be kept, got %+v", status)
+	}
+
+	if err := b.Reset(); err != nil {
+		t.Fatalf("Failed to reset: %v", err)
+	}
+	if event := receive(t, events); event.Event != EventReset {
+		t.Errorf("Expected a reset event, got %+v", event)
+	}
+	if other.Status().Tripped {
+		t.Error("Expected the reset to reach the other process")
+	}
+}
+
+// TestBreaker_Disabled tests that nothing is counted without a threshold
+func TestBreaker_Disabled(t *testing.T) {
+	b, _, _ := newTestBreaker(t, config.Config{BreakerWindowMinutes: 5})
+	b.Record([]string{config.DetectorSSN, config.DetectorSSN})
+	if status := b.Status(); status.Enabled || status.Tripped || status.Recent != 0 {
+		t.Errorf("Expected a disabled breaker, got %+v", status)
+	}
+}
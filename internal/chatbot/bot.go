@@ -0,0 +1,165 @@
+// Package chatbot watches team chat channels for sensitive data, warning
+// about messages that contain it or replacing them with a redacted copy,
+// using the same detectors as the clipboard. Platforms are Slack, through
+// Socket Mode, and Discord, through its gateway; both keep a websocket open
+// and need no public endpoint.
+package chatbot
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+	"github.com/happytaoer/prompt-security/internal/filter"
+)
+
+// What the bot does with messages containing sensitive data
+const (
+	ModeWarn   = "warn"   // Tell the author, leaving the message as is
+	ModeRedact = "redact" // Replace the message with a redacted copy
+)
+
+const (
+	// minBackoff and maxBackoff bound the wait before reconnecting
+	minBackoff = time.Second
+	maxBackoff = time.Minute
+
+	// stableConnection is how long a connection must last for the backoff
+	// to start over
+	stableConnection = time.Minute
+)
+
+// ErrCannotRedact is returned by platforms lacking the permission to
+// remove other users' messages; the bot warns instead
+var ErrCannotRedact = errors.New("missing permission to remove messages")
+
+// Message is a chat message
+type Message struct {
+	ID      string // Platform ID of the message, the timestamp on Slack
+	Channel string
+	Thread  string // Thread the message is in, if any
+	User    string
+	Text    string
+}
+
+// Platform is a chat platform the bot connects to
+type Platform interface {
+	// Name names the platform in logs
+	Name() string
+
+	// Run connects and calls handle with every new or edited message from
+	// users until ctx is done or the connection fails
+	Run(ctx context.Context, handle func(Message)) error
+
+	// Warn tells the author of m about text, as privately as the
+	// platform allows
+	Warn(ctx context.Context, m Message, text string) error
+
+	// Replace removes m and posts text in its place
+	Replace(ctx context.Context, m Message, text string) error
+}
+
+// Options configure a Bot
+type Options struct {
+	Mode     string
+	Channels []string // IDs of the channels watched, empty for all the bot is in
+
+	// AddLog, if set, logs messages with sensitive data like copies
+	AddLog func(original, filtered string, replacements []filter.ReplacementInfo)
+}
+
+// Bot watches the channels of a platform
+type Bot struct {
+	platform Platform
+	engine   *filter.Engine
+	opts     Options
+	channels map[string]bool
+	logger   *slog.Logger
+}
+
+// New creates a bot scanning the messages of platform with engine
+func New(platform Platform, engine *filter.Engine, opts Options) *Bot {
+	channels := make(map[string]bool, len(opts.Channels))
+	for _, c := range opts.Channels {
+		channels[c] = true
+	}
+	return &Bot{
+		platform: platform,
+		engine:   engine,
+		opts:     opts,
+		channels: channels,
+		logger:   slog.New(slog.NewJSONHandler(os.Stdout, nil)),
+	}
+}
+
+// Run keeps the bot connected, reconnecting with backoff, until ctx is
+// done (blocking)
+func (b *Bot) Run(ctx context.Context) {
+	backoff := minBackoff
+	for {
+		started := time.Now()
+		err := b.platform.Run(ctx, func(m Message) { b.handle(ctx, m) })
+		if ctx.Err() != nil {
+			return
+		}
+		if time.Since(started) > stableConnection {
+			backoff = minBackoff
+		}
+		b.logger.Warn("Chat connection lost, reconnecting", "platform", b.platform.Name(), "error", err, "retry_in", backoff.String())
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+// handle scans a message and warns about or replaces it if it contains
+// sensitive data
+func (b *Bot) handle(ctx context.Context, m Message) {
+	if len(b.channels) > 0 && !b.channels[m.Channel] {
+		return
+	}
+	filtered, _, summary := b.engine.Filter(m.Text)
+	if len(summary.Replacements) == 0 {
+		return
+	}
+	if b.opts.AddLog != nil {
+		b.opts.AddLog(m.Text, filtered, summary.Replacements)
+	}
+	blocked := summary.Action() == config.ActionBlock
+	if filtered == m.Text && !blocked {
+		return // Only detectors that log or queue for review matched
+	}
+
+	types := strings.Join(summary.Types(), ", ")
+	b.logger.Warn("Chat message contains sensitive data", "platform", b.platform.Name(), "channel", m.Channel, "types", types)
+
+	if b.opts.Mode == ModeRedact {
+		text := fmt.Sprintf("[prompt-security] A message from %s was redacted: %s", mention(m.User), filtered)
+		if blocked {
+			text = fmt.Sprintf("[prompt-security] A message from %s was removed because it contained sensitive data: %s", mention(m.User), types)
+		}
+		err := b.platform.Replace(ctx, m, text)
+		if err == nil {
+			return
+		}
+		b.logger.Error("Failed to replace chat message, warning instead", "platform", b.platform.Name(), "error", err)
+	}
+
+	warning := fmt.Sprintf("[prompt-security] Your message contains sensitive data (%s). Please delete it and share a redacted version.", types)
+	if err := b.platform.Warn(ctx, m, warning); err != nil {
+		b.logger.Error("Failed to warn about chat message", "platform", b.platform.Name(), "error", err)
+	}
+}
+
+// mention returns how Slack and Discord alike mention a user
+func mention(user string) string {
+	return "<@" + user + ">"
+}
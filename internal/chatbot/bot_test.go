@@ -0,0 +1,222 @@
+package chatbot
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+	"github.com/happytaoer/prompt-security/internal/filter"
+	"golang.org/x/net/websocket"
+)
+
+// fakePlatform records the warnings and replacements of a bot
+type fakePlatform struct {
+	replaceErr error
+	warned     []string
+	replaced   []string
+}
+
+func (p *fakePlatform) Name() string                             { return "fake" }
+func (p *fakePlatform) Run(context.Context, func(Message)) error { return nil }
+func (p *fakePlatform) Warn(_ context.Context, _ Message, text string) error {
+	p.warned = append(p.warned, text)
+	return nil
+}
+func (p *fakePlatform) Replace(_ context.Context, _ Message, text string) error {
+	if p.replaceErr != nil {
+		return p.replaceErr
+	}
+	p.replaced = append(p.replaced, text)
+	return nil
+}
+
+func testEngine() *filter.Engine {
+	return filter.NewEngine(config.Config{
+		DetectEmails:     true,
+		EmailReplacement: "[EMAIL]",
+		DetectSSNs:       true,
+		SSNReplacement:   "XXX-XX-XXXX",
+		SSNAction:        config.ActionBlock,
+	})
+}
+
+// TestBot tests warning about and replacing messages in watched channels
+func TestBot(t *testing.T) {
+	platform := &fakePlatform{}
+	logged := 0
+	bot := New(platform, testEngine(), Options{Mode: ModeRedact, Channels: []string{"C1"}, AddLog: func(string, string, []filter.ReplacementInfo) { logged++ }})
+	ctx := context.Background()
+
+	bot.handle(ctx, Message{Channel: "C1", User: "U1", Text: "hello"})
+	bot.handle(ctx, Message{Channel: "C2", User: "U1", Text: "mail ann@example.com"})
+	if len(platform.replaced)+len(platform.warned)+logged != 0 {
+		t.Fatalf("Expected clean messages and unwatched channels to be ignored, got %+v", platform)
+	}
+
+	bot.handle(ctx, Message{Channel: "C1", User: "U1", Text: "mail ann@example.com"})
+	if len(platform.replaced) != 1 || !strings.Contains(platform.replaced[0], "<@U1> was redacted: mail [EMAIL]") || logged != 1 {
+		t.Errorf("Expected the message to be replaced by a redacted copy, got %+v", platform.replaced)
+	}
+	bot.handle(ctx, Message{Channel: "C1", User: "U1", Text: "ssn 123-45-6789"})
+	if len(platform.replaced) != 2 || strings.Contains(platform.replaced[1], "123") || !strings.Contains(platform.replaced[1], "ssn") {
+		t.Errorf("Expected blocked messages to be removed without a copy, got %q", platform.replaced[1])
+	}
+
+	platform.replaceErr = ErrCannotRedact
+	bot.handle(ctx, Message{Channel: "C1", User: "U1", Text: "mail ann@example.com"})
+	if len(platform.warned) != 1 || !strings.Contains(platform.warned[0], "email") {
+		t.Errorf("Expected a warning when the message cannot be replaced, got %+v", platform.warned)
+	}
+
+	bot.opts.Mode = ModeWarn
+	platform.replaceErr = nil
+	bot.handle(ctx, Message{Channel: "C1", User: "U1", Text: "mail ann@example.com"})
+	if len(platform.replaced) != 2 || len(platform.warned) != 2 {
+		t.Errorf("Expected only a warning in warn mode, got %+v", platform)
+	}
+}
+
+// fakeAPI serves REST calls and a websocket, recording the calls
+type fakeAPI struct {
+	*httptest.Server
+	mu    sync.Mutex
+	calls []string
+}
+
+// newFakeAPI serves rest at every path but /ws, where ws serves the socket
+func newFakeAPI(rest func(path string, body map[string]interface{}) interface{}, ws func(*websocket.Conn)) *fakeAPI {
+	api := &fakeAPI{}
+	mux := http.NewServeMux()
+	mux.Handle("/ws", websocket.Handler(ws))
+	mux.Handle("/ws/", websocket.Handler(ws))
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		raw, _ := io.ReadAll(r.Body)
+		var body map[string]interface{}
+		json.Unmarshal(raw, &body)
+		api.mu.Lock()
+		api.calls = append(api.calls, r.Method+" "+r.URL.Path+" "+string(raw))
+		api.mu.Unlock()
+		json.NewEncoder(w).Encode(rest(r.URL.Path, body))
+	})
+	api.Server = httptest.NewServer(mux)
+	return api
+}
+
+func (a *fakeAPI) wsURL() string {
+	return "ws" + strings.TrimPrefix(a.URL, "http") + "/ws"
+}
+
+func (a *fakeAPI) called() []string {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	return append([]string{}, a.calls...)
+}
+
+// TestSlack tests receiving messages through Socket Mode and the Web API
+// calls warning about and replacing them
+func TestSlack(t *testing.T) {
+	var api *fakeAPI
+	acked := make(chan string, 1)
+	api = newFakeAPI(func(path string, _ map[string]interface{}) interface{} {
+		if path == "/apps.connections.open" {
+			return map[string]interface{}{"ok": true, "url": api.wsURL()}
+		}
+		return map[string]interface{}{"ok": true}
+	}, func(ws *websocket.Conn) {
+		websocket.Message.Send(ws, `{"envelope_id":"e1","type":"events_api","payload":{"event":{"type":"message","channel":"C1","user":"U1","text":"hi","ts":"1.2"}}}`)
+		websocket.Message.Send(ws, `{"envelope_id":"e2","type":"events_api","payload":{"event":{"type":"message","subtype":"bot_message","channel":"C1","text":"bot"}}}`)
+		websocket.Message.Send(ws, `{"type":"events_api","payload":{"event":{"type":"message","subtype":"message_changed","channel":"C1",
+			"message":{"user":"U1","text":"hi ann@example.com","ts":"1.2"},"previous_message":{"user":"U1","text":"hi","ts":"1.2"}}}}`)
+		websocket.Message.Send(ws, `{"type":"events_api","payload":{"event":{"type":"message","subtype":"message_changed","channel":"C1",
+			"message":{"user":"U1","text":"same","ts":"1.3"},"previous_message":{"user":"U1","text":"same","ts":"1.3"}}}}`)
+		websocket.Message.Send(ws, `{"type":"events_api","payload":{"event":{"type":"message","subtype":"file_share","channel":"C1","user":"U2","text":"see file","ts":"1.4"}}}`)
+		var ack map[string]string
+		websocket.JSON.Receive(ws, &ack)
+		acked <- ack["envelope_id"]
+		websocket.Message.Send(ws, `{"type":"disconnect"}`)
+	})
+	defer api.Close()
+
+	slack := NewSlack("xapp", "xoxb", "")
+	slack.api = api.URL + "/"
+	var messages []Message
+	if err := slack.Run(context.Background(), func(m Message) { messages = append(messages, m) }); err == nil {
+		t.Error("Expected an error after Slack disconnected")
+	}
+	if <-acked != "e1" || len(messages) != 3 || messages[0] != (Message{ID: "1.2", Channel: "C1", User: "U1", Text: "hi"}) {
+		t.Fatalf("Expected the user messages to be acknowledged and handled, got %+v", messages)
+	}
+	if messages[1] != (Message{ID: "1.2", Channel: "C1", User: "U1", Text: "hi ann@example.com"}) || messages[2].Text != "see file" {
+		t.Errorf("Expected the edit and the file share to be handled, got %+v", messages[1:])
+	}
+
+	if err := slack.Replace(context.Background(), messages[0], "redacted"); err != ErrCannotRedact {
+		t.Errorf("Expected %v without a user token, got %v", ErrCannotRedact, err)
+	}
+	slack.UserToken = "xoxp"
+	if err := slack.Replace(context.Background(), messages[0], "redacted"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	calls := api.called()
+	if len(calls) != 3 || !strings.HasPrefix(calls[1], "POST /chat.delete ") || !strings.Contains(calls[1], `"ts":"1.2"`) || !strings.HasPrefix(calls[2], "POST /chat.postMessage ") || strings.Contains(calls[2], "thread_ts") {
+		t.Errorf("Expected the message to be deleted and reposted, got %q", calls)
+	}
+}
+
+// TestDiscord tests identifying on the gateway, receiving messages and the
+// REST calls replacing them
+func TestDiscord(t *testing.T) {
+	var api *fakeAPI
+	identified := make(chan map[string]interface{}, 1)
+	api = newFakeAPI(func(path string, _ map[string]interface{}) interface{} {
+		if path == "/gateway/bot" {
+			return map[string]string{"url": api.wsURL()}
+		}
+		return map[string]string{}
+	}, func(ws *websocket.Conn) {
+		websocket.Message.Send(ws, `{"op":10,"d":{"heartbeat_interval":60000}}`)
+		var identify struct {
+			Op   int                    `json:"op"`
+			Data map[string]interface{} `json:"d"`
+		}
+		websocket.JSON.Receive(ws, &identify)
+		identified <- identify.Data
+		websocket.Message.Send(ws, `{"op":0,"s":1,"t":"MESSAGE_CREATE","d":{"id":"M1","channel_id":"C1","content":"hi","author":{"id":"U1"}}}`)
+		websocket.Message.Send(ws, `{"op":0,"s":2,"t":"MESSAGE_CREATE","d":{"id":"M2","channel_id":"C1","content":"bot","author":{"id":"B1","bot":true}}}`)
+		websocket.Message.Send(ws, `{"op":0,"s":3,"t":"MESSAGE_UPDATE","d":{"id":"M1","channel_id":"C1","content":"hi ann@example.com","author":{"id":"U1"}}}`)
+		websocket.Message.Send(ws, `{"op":0,"s":4,"t":"MESSAGE_UPDATE","d":{"id":"M1","channel_id":"C1","embeds":[]}}`)
+		websocket.Message.Send(ws, `{"op":7,"d":null}`)
+	})
+	defer api.Close()
+
+	discord := NewDiscord("secret")
+	discord.api = api.URL
+	var messages []Message
+	if err := discord.Run(context.Background(), func(m Message) { messages = append(messages, m) }); err == nil {
+		t.Error("Expected an error after Discord asked to reconnect")
+	}
+	if identify := <-identified; identify["token"] != "secret" || identify["intents"] != float64(discordGuildMessages|discordMessageContent) {
+		t.Errorf("Expected to identify with the token and message intents, got %+v", identify)
+	}
+	if len(messages) != 2 || messages[0] != (Message{ID: "M1", Channel: "C1", User: "U1", Text: "hi"}) ||
+		messages[1] != (Message{ID: "M1", Channel: "C1", User: "U1", Text: "hi ann@example.com"}) {
+		t.Fatalf("Expected the user message and its edit to be handled, got %+v", messages)
+	}
+
+	if err := discord.Replace(context.Background(), messages[0], "redacted"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := discord.Warn(context.Background(), messages[0], "careful"); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	calls := api.called()
+	if len(calls) != 4 || calls[1] != "DELETE /channels/C1/messages/M1 " || !strings.HasPrefix(calls[2], "POST /channels/C1/messages ") || !strings.Contains(calls[3], `"message_reference":{"message_id":"M1"}`) {
+		t.Errorf("Expected the message to be deleted, reposted and replied to, got %q", calls)
+	}
+}
@@ -0,0 +1,226 @@
+package chatbot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"runtime"
+	"sync"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// DiscordAPI is the base URL of the Discord REST API
+const DiscordAPI = "https://discord.com/api/v10"
+
+// Gateway opcodes and intents used by the bot
+const (
+	discordDispatch       = 0
+	discordHeartbeat      = 1
+	discordIdentify       = 2
+	discordReconnect      = 7
+	discordInvalidSession = 9
+	discordHello          = 10
+
+	discordGuildMessages  = 1 << 9
+	discordMessageContent = 1 << 15
+)
+
+// Discord connects to the Discord gateway as a bot. The bot needs the
+// Message Content intent, and Manage Messages in the watched channels to
+// replace messages.
+type Discord struct {
+	Token string
+
+	api    string
+	client *http.Client
+}
+
+// NewDiscord creates a Discord platform with a bot token
+func NewDiscord(token string) *Discord {
+	return &Discord{Token: token, api: DiscordAPI, client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// Name names the platform in logs
+func (d *Discord) Name() string {
+	return "discord"
+}
+
+// discordPayload is a gateway message
+type discordPayload struct {
+	Op   int             `json:"op"`
+	Data json.RawMessage `json:"d"`
+	Seq  *int64          `json:"s,omitempty"`
+	Type string          `json:"t,omitempty"`
+}
+
+// Run connects to the gateway, identifies and handles the messages created
+// or edited until ctx is done or the gateway asks to reconnect
+func (d *Discord) Run(ctx context.Context, handle func(Message)) error {
+	var gateway struct {
+		URL string `json:"url"`
+	}
+	if err := d.call(ctx, http.MethodGet, "/gateway/bot", nil, &gateway); err != nil {
+		return err
+	}
+	ws, err := websocket.Dial(gateway.URL+"/?v=10&encoding=json", "", "https://localhost/")
+	if err != nil {
+		return fmt.Errorf("failed to connect to Discord: %v", err)
+	}
+	defer ws.Close()
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	context.AfterFunc(ctx, func() { ws.Close() })
+
+	var mu sync.Mutex // Guards seq and sending
+	var seq *int64
+	send := func(op int, data interface{}) error {
+		raw, err := json.Marshal(data)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		return websocket.JSON.Send(ws, discordPayload{Op: op, Data: raw})
+	}
+
+	for {
+		var payload discordPayload
+		if err := websocket.JSON.Receive(ws, &payload); err != nil {
+			return err
+		}
+		if payload.Seq != nil {
+			mu.Lock()
+			seq = payload.Seq
+			mu.Unlock()
+		}
+
+		switch payload.Op {
+		case discordHello:
+			var hello struct {
+				HeartbeatInterval int64 `json:"heartbeat_interval"`
+			}
+			json.Unmarshal(payload.Data, &hello)
+			go func() {
+				ticker := time.NewTicker(time.Duration(hello.HeartbeatInterval) * time.Millisecond)
+				defer ticker.Stop()
+				for {
+					select {
+					case <-ctx.Done():
+						return
+					case <-ticker.C:
+						mu.Lock()
+						last := seq
+						mu.Unlock()
+						if send(discordHeartbeat, last) != nil {
+							cancel()
+							return
+						}
+					}
+				}
+			}()
+			if err := send(discordIdentify, map[string]interface{}{
+				"token":      d.Token,
+				"intents":    discordGuildMessages | discordMessageContent,
+				"properties": map[string]string{"os": runtime.GOOS, "browser": "prompt-security", "device": "prompt-security"},
+			}); err != nil {
+				return err
+			}
+		case discordHeartbeat:
+			mu.Lock()
+			last := seq
+			mu.Unlock()
+			if err := send(discordHeartbeat, last); err != nil {
+				return err
+			}
+		case discordReconnect, discordInvalidSession:
+			return fmt.Errorf("Discord asked to reconnect (op %d)", payload.Op)
+		case discordDispatch:
+			// Edits are scanned like new messages
+			if payload.Type != "MESSAGE_CREATE" && payload.Type != "MESSAGE_UPDATE" {
+				continue
+			}
+			var m struct {
+				ID        string  `json:"id"`
+				ChannelID string  `json:"channel_id"`
+				Content   *string `json:"content"` // Missing from updates leaving it unchanged
+				Author    struct {
+					ID  string `json:"id"`
+					Bot bool   `json:"bot"`
+				} `json:"author"`
+			}
+			if err := json.Unmarshal(payload.Data, &m); err != nil || m.Content == nil || m.Author.ID == "" || m.Author.Bot {
+				continue
+			}
+			handle(Message{ID: m.ID, Channel: m.ChannelID, User: m.Author.ID, Text: *m.Content})
+		}
+	}
+}
+
+// Warn replies to m with text, since Discord bots cannot message a user
+// privately in a channel
+func (d *Discord) Warn(ctx context.Context, m Message, text string) error {
+	return d.post(ctx, m.Channel, mention(m.User)+" "+text, m.ID, m.User)
+}
+
+// Replace removes m and posts text in the channel
+func (d *Discord) Replace(ctx context.Context, m Message, text string) error {
+	if err := d.call(ctx, http.MethodDelete, "/channels/"+m.Channel+"/messages/"+m.ID, nil, nil); err != nil {
+		return err
+	}
+	return d.post(ctx, m.Channel, text, "", m.User)
+}
+
+// post posts text in a channel, as a reply to the message replyTo if set,
+// notifying only user
+func (d *Discord) post(ctx context.Context, channel, text, replyTo, user string) error {
+	body := map[string]interface{}{
+		"content":          text,
+		"allowed_mentions": map[string][]string{"users": {user}},
+	}
+	if replyTo != "" {
+		body["message_reference"] = map[string]string{"message_id": replyTo}
+	}
+	return d.call(ctx, http.MethodPost, "/channels/"+channel+"/messages", body, nil)
+}
+
+// call calls a REST endpoint and decodes its result into out
+func (d *Discord) call(ctx context.Context, method, path string, body, out interface{}) error {
+	var payload io.Reader
+	if body != nil {
+		raw, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		payload = bytes.NewReader(raw)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, d.api+path, payload)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bot "+d.Token)
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call Discord %s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusForbidden && method == http.MethodDelete {
+		return ErrCannotRedact
+	}
+	if resp.StatusCode >= 300 {
+		detail, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("Discord %s %s failed with status %d: %s", method, path, resp.StatusCode, detail)
+	}
+	if out != nil {
+		return json.NewDecoder(resp.Body).Decode(out)
+	}
+	return nil
+}
@@ -0,0 +1,182 @@
+package chatbot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+
+	"golang.org/x/net/websocket"
+)
+
+// SlackAPI is the base URL of the Slack Web API
+const SlackAPI = "https://slack.com/api/"
+
+// Slack connects to Slack through Socket Mode. The app needs an app-level
+// token with connections:write, and a bot token with chat:write subscribed
+// to the message events of the watched channels. Replacing messages also
+// needs a user token of a workspace admin with chat:write, since bots
+// cannot remove other users' messages.
+type Slack struct {
+	AppToken  string // xapp- token opening Socket Mode connections
+	BotToken  string // xoxb- token posting as the bot
+	UserToken string // xoxp- token removing messages, optional
+
+	api    string
+	client *http.Client
+}
+
+// NewSlack creates a Slack platform with the given tokens
+func NewSlack(appToken, botToken, userToken string) *Slack {
+	return &Slack{AppToken: appToken, BotToken: botToken, UserToken: userToken, api: SlackAPI, client: &http.Client{Timeout: 30 * time.Second}}
+}
+
+// Name names the platform in logs
+func (s *Slack) Name() string {
+	return "slack"
+}
+
+// slackEnvelope is a Socket Mode message
+type slackEnvelope struct {
+	EnvelopeID string `json:"envelope_id"`
+	Type       string `json:"type"`
+	Payload    struct {
+		Event struct {
+			slackMessage
+			Type            string        `json:"type"`
+			Subtype         string        `json:"subtype"`
+			Channel         string        `json:"channel"`
+			Message         *slackMessage `json:"message"`          // The edited message of message_changed
+			PreviousMessage *slackMessage `json:"previous_message"` // Its text before the edit
+		} `json:"event"`
+	} `json:"payload"`
+}
+
+// slackMessage is a message in a Slack event
+type slackMessage struct {
+	BotID    string `json:"bot_id"`
+	User     string `json:"user"`
+	Text     string `json:"text"`
+	TS       string `json:"ts"`
+	ThreadTS string `json:"thread_ts"`
+}
+
+// Run opens a Socket Mode connection and acknowledges and handles its
+// events until ctx is done or Slack asks to reconnect
+func (s *Slack) Run(ctx context.Context, handle func(Message)) error {
+	var open struct {
+		URL string `json:"url"`
+	}
+	if err := s.call(ctx, s.AppToken, "apps.connections.open", nil, &open); err != nil {
+		return err
+	}
+	ws, err := websocket.Dial(open.URL, "", "https://localhost/")
+	if err != nil {
+		return fmt.Errorf("failed to connect to Slack: %v", err)
+	}
+	defer ws.Close()
+	stop := context.AfterFunc(ctx, func() { ws.Close() })
+	defer stop()
+
+	for {
+		var envelope slackEnvelope
+		if err := websocket.JSON.Receive(ws, &envelope); err != nil {
+			return err
+		}
+		if envelope.EnvelopeID != "" {
+			if err := websocket.JSON.Send(ws, map[string]string{"envelope_id": envelope.EnvelopeID}); err != nil {
+				return err
+			}
+		}
+
+		switch envelope.Type {
+		case "disconnect":
+			return fmt.Errorf("Slack asked to reconnect")
+		case "events_api":
+			e := envelope.Payload.Event
+			if e.Type != "message" {
+				continue
+			}
+			m := e.slackMessage
+			// Other subtypes are joins, bot posts, deletions and the like
+			switch e.Subtype {
+			case "", "file_share", "thread_broadcast":
+			case "message_changed":
+				if e.Message == nil || (e.PreviousMessage != nil && e.PreviousMessage.Text == e.Message.Text) {
+					continue // Not an edit of the text, e.g. an unfurled link
+				}
+				m = *e.Message
+			default:
+				continue
+			}
+			if m.BotID != "" || m.User == "" {
+				continue
+			}
+			handle(Message{ID: m.TS, Channel: e.Channel, Thread: m.ThreadTS, User: m.User, Text: m.Text})
+		}
+	}
+}
+
+// Warn tells the author of m about text in a message only they see
+func (s *Slack) Warn(ctx context.Context, m Message, text string) error {
+	return s.call(ctx, s.BotToken, "chat.postEphemeral", map[string]string{
+		"channel": m.Channel, "user": m.User, "text": text, "thread_ts": m.Thread,
+	}, nil)
+}
+
+// Replace removes m with the user token and posts text as the bot
+func (s *Slack) Replace(ctx context.Context, m Message, text string) error {
+	if s.UserToken == "" {
+		return ErrCannotRedact
+	}
+	if err := s.call(ctx, s.UserToken, "chat.delete", map[string]string{"channel": m.Channel, "ts": m.ID}, nil); err != nil {
+		return err
+	}
+	return s.call(ctx, s.BotToken, "chat.postMessage", map[string]string{
+		"channel": m.Channel, "text": text, "thread_ts": m.Thread,
+	}, nil)
+}
+
+// call calls a Web API method with token and the arguments in body,
+// leaving out empty ones, and decodes its result into out
+func (s *Slack) call(ctx context.Context, token, method string, body map[string]string, out interface{}) error {
+	args := make(map[string]string, len(body))
+	for k, v := range body {
+		if v != "" {
+			args[k] = v
+		}
+	}
+	payload, err := json.Marshal(args)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.api+method, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to call Slack %s: %v", method, err)
+	}
+	defer resp.Body.Close()
+
+	raw := json.RawMessage{}
+	if err := json.NewDecoder(resp.Body).Decode(&raw); err != nil {
+		return fmt.Errorf("invalid Slack %s response (status %d): %v", method, resp.StatusCode, err)
+	}
+	var result struct {
+		OK    bool   `json:"ok"`
+		Error string `json:"error"`
+	}
+	if err := json.Unmarshal(raw, &result); err != nil || !result.OK {
+		return fmt.Errorf("Slack %s failed: %s", method, result.Error)
+	}
+	if out != nil {
+		return json.Unmarshal(raw, out)
+	}
+	return nil
+}
@@ -0,0 +1,230 @@
+// Package classifier gives logged events a risk label, so security teams
+// can follow jailbreak attempts and unsafe requests alongside sensitive
+// data. Events are labeled in the background by the built-in heuristics or
+// an external classifier, such as a local model behind an HTTP endpoint,
+// and the label is recorded on the log entry.
+package classifier
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"regexp"
+	"time"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+	"github.com/happytaoer/prompt-security/internal/db"
+	"github.com/happytaoer/prompt-security/internal/patterns"
+)
+
+// Risk labels given by the built-in heuristics. External classifiers may
+// return labels of their own.
+const (
+	LabelSafe       = "safe"
+	LabelSuspicious = "suspicious"
+	LabelJailbreak  = "jailbreak"
+	LabelUnsafe     = "unsafe"
+)
+
+const (
+	// queueSize bounds the events waiting to be classified; events beyond
+	// it are left unlabeled rather than slowing down logging
+	queueSize = 64
+
+	// classifyTimeout bounds classifying a single event
+	classifyTimeout = 10 * time.Second
+
+	// maxResponseBytes bounds the response read from an external classifier
+	maxResponseBytes = 1 << 20
+)
+
+// Result is the risk assessment of a text
+type Result struct {
+	Label string  `json:"label"`
+	Score float64 `json:"score"` // Risk from 0 to 1
+}
+
+// Classifier assigns a risk label to text
+type Classifier interface {
+	Classify(ctx context.Context, text string) (Result, error)
+}
+
+// Heuristic classifies text locally by prompt injection patterns and
+// jailbreak and unsafe request phrases
+type Heuristic struct{}
+
+var (
+	// jailbreakPattern matches phrases common in jailbreak prompts
+	jailbreakPattern = regexp.MustCompile(`(?i)\b(?:jail-?break(?:ing)?|do\s+anything\s+now|stay\s+in\s+character|no\s+(?:rules|restrictions|limits|filters)\b|without\s+(?:any\s+)?(?:restrictions|filters|censorship|limits)|uncensored|unfiltered|hypothetical(?:ly)?\s+(?:speaking|scenario)|for\s+educational\s+purposes\s+only|bypass\s+(?:the\s+|your\s+)?(?:safety|content\s+(?:policy|filters?)|filters?|guardrails?))`)
+
+	// unsafePattern matches requests for clearly harmful content
+	unsafePattern = regexp.MustCompile(`(?i)\b(?:(?:make|build|synthesi[sz]e|create|assemble)\s+(?:a\s+|an\s+|some\s+)?(?:pipe\s+)?(?:bomb|explosives?|nerve\s+agent|bioweapon|meth(?:amphetamine)?)|(?:write|create|build|code|develop)\s+(?:a\s+|an\s+|some\s+)?(?:working\s+)?(?:malware|ransomware|keylogger|rootkit|botnet|credential\s+stealer|phishing\s+(?:kit|page|site|email))|credit\s+card\s+dumps?|carding\s+(?:tutorial|method))`)
+)
+
+// Classify scores text by its prompt injection patterns and phrases.
+// Requests for harmful content are unsafe, and attempts to subvert the
+// model are jailbreaks, or suspicious when only a weak signal is present.
+func (Heuristic) Classify(ctx context.Context, text string) (Result, error) {
+	unsafe := 0.6 * float64(len(unsafePattern.FindAllStringIndex(text, -1)))
+	jailbreak := 0.6*float64(len(patterns.PromptInjectionPattern.FindAllStringIndex(text, -1))) +
+		0.6*float64(len(patterns.RoleOverridePattern.FindAllStringIndex(text, -1))) +
+		0.5*float64(len(patterns.DataExfiltrationPattern.FindAllStringIndex(text, -1))) +
+		0.25*float64(len(jailbreakPattern.FindAllStringIndex(text, -1)))
+	unsafe, jailbreak = min(unsafe, 1), min(jailbreak, 1)
+
+	switch {
+	case unsafe >= 0.5:
+		return Result{Label: LabelUnsafe, Score: unsafe}, nil
+	case jailbreak >= 0.5:
+		return Result{Label: LabelJailbreak, Score: jailbreak}, nil
+	case jailbreak > 0 || unsafe > 0:
+		return Result{Label: LabelSuspicious, Score: max(jailbreak, unsafe)}, nil
+	}
+	return Result{Label: LabelSafe}, nil
+}
+
+// HTTP classifies text with an external classifier. It posts
+// {"text": "..."} as JSON to URL and expects {"label": "...", "score": 0.9}
+// in return.
+type HTTP struct {
+	URL    string
+	Client *http.Client
+}
+
+// Classify posts text to the classifier
+func (h HTTP) Classify(ctx context.Context, text string) (Result, error) {
+	body, err := json.Marshal(map[string]string{"text": text})
+	if err != nil {
+		return Result{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, h.URL, bytes.NewReader(body))
+	if err != nil {
+		return Result{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	client := h.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return Result{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return Result{}, fmt.Errorf("classifier returned %s", resp.Status)
+	}
+
+	var result Result
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxResponseBytes)).Decode(&result); err != nil {
+		return Result{}, fmt.Errorf("invalid classifier response: %v", err)
+	}
+	if result.Label == "" {
+		return Result{}, fmt.Errorf("classifier returned no label")
+	}
+	result.Score = min(max(result.Score, 0), 1)
+	return result, nil
+}
+
+// event is an event waiting to be classified
+type event struct {
+	logID    int
+	original string
+	filtered string              // Empty if the event was blocked
+	log      func() (int, error) // Logs an event that was not logged yet
+}
+
+// Service labels logged events in the background with the classifier the
+// configuration selects
+type Service struct {
+	manager *config.Manager
+	queue   chan event
+	client  *http.Client
+	logger  *slog.Logger
+}
+
+// New creates a classification service
+func New(manager *config.Manager) *Service {
+	return &Service{
+		manager: manager,
+		queue:   make(chan event, queueSize),
+		client:  &http.Client{Timeout: classifyTimeout},
+		logger:  slog.New(slog.NewJSONHandler(os.Stdout, nil)),
+	}
+}
+
+// Submit queues a logged event for classification if a classifier is
+// enabled. The heuristics read the original text; an external classifier
+// only gets the filtered text, and nothing of blocked events, whose
+// filtered text is empty.
+func (s *Service) Submit(logID int, original, filtered string) {
+	if s.manager.Get().ClassifierMode == config.ClassifierOff {
+		return
+	}
+	select {
+	case s.queue <- event{logID: logID, original: original, filtered: filtered}:
+	default:
+		s.logger.Warn("Classifier queue is full, leaving event unlabeled", "log_id", logID)
+	}
+}
+
+// SubmitPrompt queues a prompt without detections, which is not logged,
+// for classification if a classifier is enabled. Unless it is labeled safe,
+// log is called to log it and the label is recorded on the new entry.
+func (s *Service) SubmitPrompt(text string, log func() (int, error)) {
+	if s.manager.Get().ClassifierMode == config.ClassifierOff {
+		return
+	}
+	select {
+	case s.queue <- event{original: text, filtered: text, log: log}:
+	default:
+		s.logger.Warn("Classifier queue is full, leaving prompt unlabeled")
+	}
+}
+
+// Run classifies queued events (blocking)
+func (s *Service) Run() {
+	for e := range s.queue {
+		classifier, text := s.classifier(s.manager.Get(), e)
+		if classifier == nil || text == "" {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), classifyTimeout)
+		result, err := classifier.Classify(ctx, text)
+		cancel()
+		if err != nil {
+			s.logger.Warn("Failed to classify event", "log_id", e.logID, "error", err)
+			continue
+		}
+		if e.log != nil {
+			if result.Label == LabelSafe {
+				continue
+			}
+			if e.logID, err = e.log(); err != nil {
+				s.logger.Error("Failed to log risky prompt", "label", result.Label, "error", err)
+				continue
+			}
+		}
+		if err := db.SetLogRisk(e.logID, result.Label, result.Score); err != nil {
+			s.logger.Error("Failed to record risk label", "log_id", e.logID, "error", err)
+		}
+	}
+}
+
+// classifier returns the classifier cfg selects and the text of e it
+// classifies, nil if classification is off
+func (s *Service) classifier(cfg config.Config, e event) (Classifier, string) {
+	switch cfg.ClassifierMode {
+	case config.ClassifierHeuristic:
+		return Heuristic{}, e.original
+	case config.ClassifierHTTP:
+		return HTTP{URL: cfg.ClassifierEndpoint, Client: s.client}, e.filtered
+	}
+	return nil, ""
+}
@@ -0,0 +1,122 @@
+package config
+
+import (
+	"sort"
+	"strings"
+)
+
+// CompiledConfig is an immutable snapshot of a Manager's configuration
+// together with the tables derived from it, built once per change instead
+// of on every use: value sets, the domain policy table and, with a
+// Compiler, the compiled detectors. Share it freely between goroutines; it
+// must not be modified.
+type CompiledConfig struct {
+	Config    Config // Saved configuration with overrides, as returned by Get
+	Effective Config // With the active profile and any lockdown applied, as returned by Effective
+	Profile   string // Active detection profile
+	Schedule  string // Schedule that selected Profile, empty if none
+	Lockdown  bool   // Whether every detector blocks
+	Version   uint64 // Increases with every snapshot of a manager
+
+	Allowlist       map[string]bool // Effective.Allowlist as a set
+	ReviewConfirmed map[string]bool // Effective.ReviewConfirmed as a set
+
+	domains  domainTable
+	compiled interface{}
+}
+
+// Compiler compiles what detection needs from a snapshot, such as regular
+// expressions and automata. It runs with the manager locked, once per
+// change, and must not call the manager.
+type Compiler func(c *CompiledConfig) interface{}
+
+// Compiled returns the result of the manager's Compiler, nil without one
+func (c *CompiledConfig) Compiled() interface{} {
+	return c.compiled
+}
+
+// DomainPolicy returns the policy for traffic to host, like
+// MatchDomainPolicy on the effective domain policies
+func (c *CompiledConfig) DomainPolicy(host string) (DomainPolicy, bool) {
+	return c.domains.match(host)
+}
+
+// compile builds the snapshot of a configuration
+func compile(saved, effective Config, profile, schedule string, lockdown bool, version uint64, compiler Compiler) *CompiledConfig {
+	c := &CompiledConfig{
+		Config:          saved,
+		Effective:       effective,
+		Profile:         profile,
+		Schedule:        schedule,
+		Lockdown:        lockdown,
+		Version:         version,
+		Allowlist:       valueSet(effective.Allowlist),
+		ReviewConfirmed: valueSet(effective.ReviewConfirmed),
+		domains:         newDomainTable(effective.DomainPolicies),
+	}
+	if compiler != nil {
+		c.compiled = compiler(c)
+	}
+	return c
+}
+
+// valueSet returns the set of values, nil if there are none
+func valueSet(values []string) map[string]bool {
+	if len(values) == 0 {
+		return nil
+	}
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
+
+// domainTable indexes domain policies for MatchDomainPolicy's lookup
+type domainTable struct {
+	hosts     map[string]DomainPolicy // Policies of single hosts
+	wildcards []DomainPolicy          // *. policies, longest first
+	all       *DomainPolicy           // The policy for every host
+}
+
+// newDomainTable indexes policies. Where policies share a host, the first
+// wins, as in MatchDomainPolicy.
+func newDomainTable(policies []DomainPolicy) domainTable {
+	var t domainTable
+	for i, p := range policies {
+		switch {
+		case p.Host == AnyHost:
+			if t.all == nil {
+				t.all = &policies[i]
+			}
+		case strings.HasPrefix(p.Host, "*."):
+			t.wildcards = append(t.wildcards, p)
+		default:
+			if t.hosts == nil {
+				t.hosts = make(map[string]DomainPolicy)
+			}
+			if _, ok := t.hosts[p.Host]; !ok {
+				t.hosts[p.Host] = p
+			}
+		}
+	}
+	sort.SliceStable(t.wildcards, func(i, j int) bool { return len(t.wildcards[i].Host) > len(t.wildcards[j].Host) })
+	return t
+}
+
+// match returns the most specific policy matching host
+func (t domainTable) match(host string) (DomainPolicy, bool) {
+	host = normalizeHost(host)
+	if p, ok := t.hosts[host]; ok {
+		return p, true
+	}
+	for _, p := range t.wildcards {
+		if strings.HasSuffix(host, p.Host[1:]) {
+			return p, true
+		}
+	}
+	if t.all != nil {
+		return *t.all, true
+	}
+	return DomainPolicy{}, false
+}
@@ -8,9 +8,18 @@ import (
 type StringMatchPattern = db.StringMatchPattern
 type Config = db.Config
 
+// Errors of loading and saving the configuration, see the db package
+var (
+	ErrConfigNotFound = db.ErrConfigNotFound
+	ErrStorage        = db.ErrStorage
+)
+
 // Initialize initializes the database
 func Initialize() error {
-	return db.Initialize()
+	if err := db.Initialize(); err != nil {
+		return err
+	}
+	return db.MigrateNormalizeUnicode(NormalizableDetectors)
 }
 
 // Close closes the database connection
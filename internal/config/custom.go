@@ -0,0 +1,87 @@
+package config
+
+import (
+	"fmt"
+	"regexp/syntax"
+)
+
+// MaxPatternComplexity is the largest compiled program, in instructions, a
+// custom regular expression may have. The built-in patterns need under 30.
+const MaxPatternComplexity = 1000
+
+// customPattern is a custom regular expression field of a configuration
+type customPattern struct {
+	field string  // JSON field name
+	value *string // Pattern in the configuration
+}
+
+// customPatterns returns the custom regular expression fields of cfg
+func customPatterns(cfg *Config) []customPattern {
+	return []customPattern{
+		{"custom_email_pattern", &cfg.CustomEmailPattern},
+		{"custom_phone_pattern", &cfg.CustomPhonePattern},
+		{"custom_credit_card_pattern", &cfg.CustomCreditCardPattern},
+		{"custom_ssn_pattern", &cfg.CustomSSNPattern},
+		{"custom_ipv4_pattern", &cfg.CustomIPV4Pattern},
+	}
+}
+
+// patternComplexity returns the number of instructions pattern compiles to
+func patternComplexity(pattern string) (int, error) {
+	re, err := syntax.Parse(pattern, syntax.Perl)
+	if err != nil {
+		return 0, err
+	}
+	prog, err := syntax.Compile(re.Simplify())
+	if err != nil {
+		return 0, err
+	}
+	return len(prog.Inst), nil
+}
+
+// WithoutDisabledPatterns returns cfg with every custom pattern listed in
+// DisabledPatterns cleared, so detection falls back to the built-in pattern
+func WithoutDisabledPatterns(cfg Config) Config {
+	if len(cfg.DisabledPatterns) == 0 {
+		return cfg
+	}
+	for _, p := range customPatterns(&cfg) {
+		if containsString(cfg.DisabledPatterns, p.field) {
+			*p.value = ""
+		}
+	}
+	return cfg
+}
+
+// carryDisabledPatterns returns the patterns of previous that stay disabled
+// in next: those whose custom pattern was not changed
+func carryDisabledPatterns(previous, next Config) []string {
+	disabled := []string{}
+	nextPatterns := customPatterns(&next)
+	for i, p := range customPatterns(&previous) {
+		if containsString(previous.DisabledPatterns, p.field) && *p.value == *nextPatterns[i].value {
+			disabled = append(disabled, p.field)
+		}
+	}
+	return disabled
+}
+
+// checkCustomPatternField returns an error unless field names a custom pattern
+func checkCustomPatternField(field string) error {
+	var cfg Config
+	for _, p := range customPatterns(&cfg) {
+		if p.field == field {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: unknown custom pattern %q", ErrInvalidPattern, field)
+}
+
+func containsString(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,41 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestCarryDisabledPatterns tests that changing a disabled pattern re-enables it
+func TestCarryDisabledPatterns(t *testing.T) {
+	previous := Config{
+		CustomEmailPattern: "slow",
+		CustomPhonePattern: "also slow",
+		DisabledPatterns:   []string{"custom_email_pattern", "custom_phone_pattern"},
+	}
+
+	next := previous
+	next.CustomPhonePattern = "fixed"
+	next.DisabledPatterns = nil // Clients cannot clear the flag directly
+
+	got := carryDisabledPatterns(previous, next)
+	if !reflect.DeepEqual(got, []string{"custom_email_pattern"}) {
+		t.Errorf("Expected only the unchanged pattern to stay disabled, got %v", got)
+	}
+}
+
+// TestWithoutDisabledPatterns tests clearing disabled custom patterns
+func TestWithoutDisabledPatterns(t *testing.T) {
+	cfg := Config{
+		CustomEmailPattern: "a",
+		CustomSSNPattern:   "b",
+		DisabledPatterns:   []string{"custom_ssn_pattern"},
+	}
+
+	got := WithoutDisabledPatterns(cfg)
+	if got.CustomEmailPattern != "a" || got.CustomSSNPattern != "" {
+		t.Errorf("Expected only the SSN pattern to be cleared, got %+v", got)
+	}
+	if cfg.CustomSSNPattern != "b" {
+		t.Error("Expected the original configuration to be left alone")
+	}
+}
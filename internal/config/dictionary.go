@@ -0,0 +1,167 @@
+package config
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Dictionary file formats
+const (
+	DictionaryCSV = "csv"
+	DictionaryTSV = "tsv"
+)
+
+// DictionaryOptions are the settings of the string match patterns imported
+// from a dictionary. Name and Replacement may be overridden per row by the
+// name and replacement columns; an empty Replacement defaults to the name
+// in upper case and brackets, e.g. [EMPLOYEE_NAME].
+type DictionaryOptions struct {
+	Name            string `json:"name"`
+	Replacement     string `json:"replacement"`
+	CaseInsensitive bool   `json:"case_insensitive"`
+	WholeWord       bool   `json:"whole_word"`
+	Fuzzy           bool   `json:"fuzzy"`
+	Action          string `json:"action"`
+	Severity        string `json:"severity"`
+}
+
+// ImportResult reports what a dictionary import added, or would add on a
+// dry run
+type ImportResult struct {
+	DryRun     bool                 `json:"dry_run"`
+	Imported   []StringMatchPattern `json:"imported"`
+	Duplicates []string             `json:"duplicates"` // Terms already present or repeated in the file
+	Errors     []ImportError        `json:"errors"`     // Rows that were skipped
+}
+
+// ImportError describes a dictionary row that could not be imported
+type ImportError struct {
+	Line  int    `json:"line"`
+	Error string `json:"error"`
+}
+
+// PlanImport reads literal terms, such as employee names or customer IDs,
+// from a CSV or TSV dictionary, one per row, and returns the patterns to
+// add to existing. format is csv, tsv or empty to tell by the first line.
+// A first row naming a term (or pattern) column is a header, and its name
+// and replacement columns, if any, are used too; otherwise the terms are
+// read from the first column. Terms already in existing or repeated are
+// reported as duplicates.
+func PlanImport(content, format string, opts DictionaryOptions, existing []StringMatchPattern) (ImportResult, error) {
+	result := ImportResult{Imported: []StringMatchPattern{}, Duplicates: []string{}, Errors: []ImportError{}}
+
+	r := csv.NewReader(strings.NewReader(strings.TrimPrefix(content, "\ufeff")))
+	switch format {
+	case DictionaryTSV:
+		r.Comma = '\t'
+	case DictionaryCSV:
+	case "":
+		firstLine, _, _ := strings.Cut(content, "\n")
+		if strings.Contains(firstLine, "\t") {
+			r.Comma = '\t'
+		}
+	default:
+		return result, fmt.Errorf("unknown dictionary format %q, expected %s or %s", format, DictionaryCSV, DictionaryTSV)
+	}
+	r.FieldsPerRecord = -1
+	r.LazyQuotes = true
+
+	columns := map[string]int{"term": 0}
+	seen := make(map[string]bool)
+	for _, p := range existing {
+		seen[dictionaryKey(p.Pattern, p.CaseInsensitive)] = true
+	}
+	for row := 0; ; row++ {
+		record, err := r.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return result, fmt.Errorf("failed to read dictionary: %w", err)
+		}
+		line, _ := r.FieldPos(0)
+
+		if row == 0 {
+			if header, ok := dictionaryHeader(record); ok {
+				columns = header
+				continue
+			}
+		}
+
+		term := dictionaryField(record, columns, "term")
+		if term == "" {
+			continue
+		}
+		p := StringMatchPattern{
+			Name:            opts.Name,
+			Pattern:         term,
+			Enabled:         true,
+			Replacement:     opts.Replacement,
+			CaseInsensitive: opts.CaseInsensitive,
+			WholeWord:       opts.WholeWord,
+			Fuzzy:           opts.Fuzzy,
+			Action:          opts.Action,
+			Severity:        opts.Severity,
+		}
+		if name := dictionaryField(record, columns, "name"); name != "" {
+			p.Name = name
+		}
+		if replacement := dictionaryField(record, columns, "replacement"); replacement != "" {
+			p.Replacement = replacement
+		}
+		if p.Replacement == "" && strings.TrimSpace(p.Name) != "" {
+			p.Replacement = "[" + strings.ToUpper(strings.TrimSpace(p.Name)) + "]"
+		}
+
+		if err := ValidatePattern(p); err != nil {
+			result.Errors = append(result.Errors, ImportError{Line: line, Error: err.Error()})
+			continue
+		}
+		key := dictionaryKey(p.Pattern, p.CaseInsensitive)
+		if seen[key] || (!p.CaseInsensitive && seen[dictionaryKey(p.Pattern, true)]) {
+			result.Duplicates = append(result.Duplicates, term)
+			continue
+		}
+		seen[key] = true
+		result.Imported = append(result.Imported, p)
+	}
+	return result, nil
+}
+
+// dictionaryHeader returns the columns named by record if it is a header
+// row, one naming a term or pattern column
+func dictionaryHeader(record []string) (map[string]int, bool) {
+	columns := make(map[string]int)
+	for i, cell := range record {
+		switch name := strings.ToLower(strings.TrimSpace(cell)); name {
+		case "term", "pattern":
+			columns["term"] = i
+		case "name", "replacement":
+			columns[name] = i
+		}
+	}
+	_, ok := columns["term"]
+	return columns, ok
+}
+
+// dictionaryField returns the trimmed cell of a column in record, or ""
+func dictionaryField(record []string, columns map[string]int, column string) string {
+	i, ok := columns[column]
+	if !ok || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+// dictionaryKey identifies a term for finding duplicates, folding the case
+// of case-insensitive terms. A case-sensitive term is also a duplicate of
+// a case-insensitive one that matches it.
+func dictionaryKey(term string, caseInsensitive bool) string {
+	if caseInsensitive {
+		return "i:" + strings.ToLower(term)
+	}
+	return "s:" + term
+}
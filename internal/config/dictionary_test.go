@@ -0,0 +1,69 @@
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestPlanImport tests that an imported list becomes patterns with the given
+// options, the first column of each line being the term, and that existing
+// and repeated terms are reported as duplicates
+func TestPlanImport(t *testing.T) {
+	existing := []StringMatchPattern{
+		{ID: 1, Name: "employee", Pattern: "Ann Smith", CaseInsensitive: true},
+		{ID: 2, Name: "project", Pattern: "Bluebird"},
+	}
+	opts := DictionaryOptions{Name: "employee", CaseInsensitive: true, WholeWord: true}
+
+	content := "Bob Jones\nann smith\n\n  Carol Diaz  ,ignored\nBOB JONES\n\"Dan, Jr.\"\n"
+	result, err := PlanImport(content, "", opts, existing)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var terms []string
+	for _, p := range result.Imported {
+		terms = append(terms, p.Pattern)
+		if p.Name != "employee" || p.Replacement != "[EMPLOYEE]" || !p.Enabled || !p.CaseInsensitive || !p.WholeWord {
+			t.Errorf("Expected the options to apply, got %+v", p)
+		}
+	}
+	if got := strings.Join(terms, "|"); got != "Bob Jones|Carol Diaz|Dan, Jr." {
+		t.Errorf("Expected three new terms, got %q", got)
+	}
+	if got := strings.Join(result.Duplicates, "|"); got != "ann smith|BOB JONES" {
+		t.Errorf("Expected existing and repeated terms as duplicates, got %q", got)
+	}
+}
+
+// TestPlanImport_Header tests that a header row names the columns, so the
+// name column overrides the name option
+func TestPlanImport_Header(t *testing.T) {
+	content := "Name\tTerm\tNotes\ncustomer_id\tC-1001\tgold\n\tC-1002\t\nproject\tbluebird\t\n"
+	opts := DictionaryOptions{Name: "customer_id", Replacement: "[ID]", Action: ActionBlock}
+	result, err := PlanImport(content, "", opts, []StringMatchPattern{{Pattern: "Bluebird"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Imported) != 3 || len(result.Duplicates) != 0 {
+		t.Fatalf("Expected three terms, a case-sensitive one distinct from Bluebird, got %+v", result)
+	}
+	if p := result.Imported[2]; p.Name != "project" || p.Pattern != "bluebird" || p.Replacement != "[ID]" || p.Action != ActionBlock {
+		t.Errorf("Expected the name column to override the option, got %+v", p)
+	}
+}
+
+// TestPlanImport_Errors tests that invalid rows are reported by line and
+// unknown formats refused
+func TestPlanImport_Errors(t *testing.T) {
+	result, err := PlanImport("term,name\nBluebird\nfoo,x\n", DictionaryCSV, DictionaryOptions{Severity: "extreme"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(result.Imported) != 0 || len(result.Errors) != 2 || result.Errors[0].Line != 2 || result.Errors[1].Line != 3 {
+		t.Errorf("Expected both rows to fail validation by line, got %+v", result)
+	}
+
+	if _, err := PlanImport("a", "xlsx", DictionaryOptions{}, nil); err == nil {
+		t.Error("Expected an unknown format to be rejected")
+	}
+}
@@ -0,0 +1,171 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	"github.com/happytaoer/prompt-security/internal/db"
+)
+
+// DomainPolicy controls traffic to a destination host in gateway and proxy
+// modes
+type DomainPolicy = db.DomainPolicy
+
+// ErrDomainPolicyNotFound is returned when a domain policy ID does not exist
+var ErrDomainPolicyNotFound = errors.New("domain policy not found")
+
+// Detector groups a domain policy can apply besides the built-in detectors
+const (
+	DetectorStringMatch      = "string_match"
+	DetectorPromptSafety     = "prompt_safety"
+	DetectorSpecialCategory  = "special_category"
+	DetectorLocation         = "location"
+	DetectorTerraformSecret  = "terraform_secret"
+	DetectorHTTPAuth         = "http_auth"
+	DetectorCookie           = "cookie"
+	DetectorCurl             = "curl"
+	DetectorHomePath         = "home_path"
+	DetectorShellPrompt      = "shell_prompt"
+	DetectorInfrastructureID = "infrastructure_id"
+	DetectorLicenseKey       = "license_key"
+	DetectorMAC              = "mac_address"
+	DetectorTOTP             = "totp_secret"
+	DetectorRecoveryCodes    = "recovery_codes"
+	DetectorPassword         = "password"
+)
+
+// DomainDetectors lists the detectors a domain policy can apply
+var DomainDetectors = append(append([]string{}, Detectors...), DetectorStringMatch, DetectorPromptSafety, DetectorSpecialCategory, DetectorLocation, DetectorTerraformSecret, DetectorHTTPAuth, DetectorCookie, DetectorCurl, DetectorHomePath, DetectorShellPrompt, DetectorInfrastructureID, DetectorLicenseKey, DetectorMAC, DetectorTOTP, DetectorRecoveryCodes, DetectorPassword)
+
+// NormalizableDetectors lists the detectors normalize_detectors can name:
+// those of domain policies but string match patterns, which each have a
+// normalize setting of their own
+var NormalizableDetectors = append(append([]string{}, Detectors...), DetectorPromptSafety, DetectorSpecialCategory, DetectorLocation, DetectorTerraformSecret, DetectorHTTPAuth, DetectorCookie, DetectorCurl, DetectorHomePath, DetectorShellPrompt, DetectorInfrastructureID, DetectorLicenseKey, DetectorMAC, DetectorTOTP, DetectorRecoveryCodes, DetectorPassword)
+
+// AnyHost is the domain policy host matching every host without a more
+// specific policy
+const AnyHost = "*"
+
+// hostPattern matches host names, optionally as a *. wildcard
+var hostPattern = regexp.MustCompile(`^(?:\*\.)?[a-z0-9](?:[a-z0-9-]*[a-z0-9])?(?:\.[a-z0-9](?:[a-z0-9-]*[a-z0-9])?)*$`)
+
+// ValidateDomainPolicy validates a single domain policy
+func ValidateDomainPolicy(p DomainPolicy) error {
+	v := &validator{}
+	validateDomainPolicy(v, "", p)
+	return v.err()
+}
+
+func validateDomainPolicy(v *validator, prefix string, p DomainPolicy) {
+	if p.Host != AnyHost && !hostPattern.MatchString(p.Host) {
+		v.add(prefix+"host", "must be a lowercase host name, a *. wildcard or %s", AnyHost)
+	}
+	seen := make(map[string]bool, len(p.Detectors))
+	for _, d := range p.Detectors {
+		if !containsString(DomainDetectors, d) {
+			v.add(prefix+"detectors", "unknown detector %q, expected one of %s", d, strings.Join(DomainDetectors, ", "))
+		} else if seen[d] {
+			v.add(prefix+"detectors", "lists %q more than once", d)
+		}
+		seen[d] = true
+	}
+}
+
+// MatchesHost reports whether one of patterns, host names or *. wildcards,
+// matches host
+func MatchesHost(patterns []string, host string) bool {
+	host = normalizeHost(host)
+	for _, p := range patterns {
+		if p == host || (strings.HasPrefix(p, "*.") && strings.HasSuffix(host, p[1:])) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchDomainPolicy returns the most specific policy matching host: one for
+// the host itself, else the one with the longest matching wildcard, else
+// the one for every host
+func MatchDomainPolicy(policies []DomainPolicy, host string) (DomainPolicy, bool) {
+	host = normalizeHost(host)
+	var best DomainPolicy
+	found, bestLen := false, -1
+	for _, p := range policies {
+		length := -1
+		switch {
+		case p.Host == host:
+			length = len(host) + 1 // Beats any wildcard
+		case strings.HasPrefix(p.Host, "*.") && strings.HasSuffix(host, p.Host[1:]):
+			length = len(p.Host)
+		case p.Host == AnyHost:
+			length = 0
+		}
+		if length > bestLen {
+			best, found, bestLen = p, true, length
+		}
+	}
+	return best, found
+}
+
+// normalizeHost lowercases host and strips any port and trailing dot
+func normalizeHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.TrimSuffix(strings.ToLower(host), ".")
+}
+
+// RestrictDetectors disables the detectors not in detectors, or returns cfg
+// unchanged if detectors is empty. String match patterns are copied, so cfg
+// is not modified.
+func RestrictDetectors(cfg Config, detectors []string) Config {
+	if len(detectors) == 0 {
+		return cfg
+	}
+	for _, d := range []struct {
+		name    string
+		enabled *bool
+	}{
+		{DetectorEmail, &cfg.DetectEmails},
+		{DetectorPhone, &cfg.DetectPhones},
+		{DetectorCreditCard, &cfg.DetectCreditCards},
+		{DetectorSSN, &cfg.DetectSSNs},
+		{DetectorIPV4, &cfg.DetectIPV4},
+		{DetectorSpecialCategory, &cfg.DetectSpecialCategories},
+		{DetectorLocation, &cfg.DetectLocations},
+		{DetectorTerraformSecret, &cfg.DetectTerraformSecrets},
+		{DetectorHTTPAuth, &cfg.DetectHTTPAuth},
+		{DetectorCookie, &cfg.DetectCookies},
+		{DetectorCurl, &cfg.SanitizeCurl},
+		{DetectorHomePath, &cfg.DetectHomePaths},
+		{DetectorShellPrompt, &cfg.DetectShellPrompts},
+		{DetectorInfrastructureID, &cfg.DetectInfrastructureIDs},
+		{DetectorLicenseKey, &cfg.DetectLicenseKeys},
+		{DetectorMAC, &cfg.DetectMACAddresses},
+		{DetectorTOTP, &cfg.DetectTOTPSecrets},
+		{DetectorRecoveryCodes, &cfg.DetectRecoveryCodes},
+		{DetectorPassword, &cfg.DetectPasswords},
+	} {
+		*d.enabled = *d.enabled && containsString(detectors, d.name)
+	}
+	if !containsString(detectors, DetectorStringMatch) {
+		cfg.StringMatchPatterns = nil
+	}
+	if !containsString(detectors, DetectorPromptSafety) {
+		cfg.PromptSafetyMode = PromptSafetyOff
+	}
+	return cfg
+}
+
+// checkDomainPolicyHost fails if another policy than p already has its host
+func checkDomainPolicyHost(policies []DomainPolicy, p DomainPolicy) error {
+	for _, existing := range policies {
+		if existing.Host == p.Host && existing.ID != p.ID {
+			return &ValidationError{Fields: []FieldError{{Field: "host", Message: fmt.Sprintf("policy %d already applies to %s", existing.ID, p.Host)}}}
+		}
+	}
+	return nil
+}
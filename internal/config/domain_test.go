@@ -0,0 +1,107 @@
+package config
+
+import "testing"
+
+// TestMatchDomainPolicy tests that the most specific policy matching a host
+// applies
+func TestMatchDomainPolicy(t *testing.T) {
+	policies := []DomainPolicy{
+		{ID: 1, Host: AnyHost},
+		{ID: 2, Host: "*.corp"},
+		{ID: 3, Host: "*.llm.corp"},
+		{ID: 4, Host: "api.openai.com"},
+	}
+	tests := map[string]int{
+		"api.openai.com":      4,
+		"API.OpenAI.com:443":  4,
+		"internal.llm.corp":   3,
+		"wiki.corp":           2,
+		"corp":                1,
+		"api.anthropic.com.":  1,
+		"evilapi.openai.com":  1,
+		"internal.llm.corp.":  3,
+		"notcorp.example.com": 1,
+	}
+	table := newDomainTable(policies)
+	for host, want := range tests {
+		if p, ok := MatchDomainPolicy(policies, host); !ok || p.ID != want {
+			t.Errorf("%s: expected policy %d, got %d (found %v)", host, want, p.ID, ok)
+		}
+		if p, ok := table.match(host); !ok || p.ID != want {
+			t.Errorf("%s: expected policy %d from the table, got %d (found %v)", host, want, p.ID, ok)
+		}
+	}
+
+	if _, ok := MatchDomainPolicy(policies[3:], "api.anthropic.com"); ok {
+		t.Error("Expected no policy without a policy for every host")
+	}
+	if _, ok := newDomainTable(policies[3:]).match("api.anthropic.com"); ok {
+		t.Error("Expected no policy from the table without a policy for every host")
+	}
+}
+
+// TestMatchesHost tests host and wildcard matching
+func TestMatchesHost(t *testing.T) {
+	patterns := []string{"*.apple.com", "bank.example.com"}
+	for host, want := range map[string]bool{
+		"icloud.apple.com":         true,
+		"Bank.Example.com:443":     true,
+		"apple.com":                false,
+		"www.bank.example.com":     false,
+		"notapple.com":             false,
+		"pineapple.com":            false,
+		"api.openai.com":           false,
+		"gateway.icloud.apple.com": true,
+	} {
+		if got := MatchesHost(patterns, host); got != want {
+			t.Errorf("%s: expected %v, got %v", host, want, got)
+		}
+	}
+}
+
+// TestRestrictDetectors tests that only the listed detectors stay enabled
+func TestRestrictDetectors(t *testing.T) {
+	cfg := Config{
+		DetectEmails:            true,
+		DetectSSNs:              true,
+		PromptSafetyMode:        PromptSafetyBlock,
+		StringMatchPatterns:     []StringMatchPattern{{Name: "company", Pattern: "Acme", Enabled: true}},
+		DetectSpecialCategories: true,
+	}
+
+	got := RestrictDetectors(cfg, []string{DetectorSSN, DetectorPhone, DetectorPromptSafety})
+	if got.DetectEmails || !got.DetectSSNs || got.DetectPhones || got.StringMatchPatterns != nil || got.PromptSafetyMode != PromptSafetyBlock || got.DetectSpecialCategories {
+		t.Errorf("Expected only SSNs and prompt safety to stay enabled, got %+v", got)
+	}
+	if got := RestrictDetectors(cfg, nil); !got.DetectEmails || len(got.StringMatchPatterns) != 1 {
+		t.Error("Expected no restriction without detectors")
+	}
+}
+
+// TestValidateDomainPolicy tests host and detector validation
+func TestValidateDomainPolicy(t *testing.T) {
+	valid := []DomainPolicy{
+		{Host: AnyHost},
+		{Host: "api.openai.com", Allow: true, Detectors: []string{DetectorEmail, DetectorStringMatch}},
+		{Host: "*.corp"},
+	}
+	for _, p := range valid {
+		if err := ValidateDomainPolicy(p); err != nil {
+			t.Errorf("%s: unexpected error %v", p.Host, err)
+		}
+	}
+
+	invalid := []DomainPolicy{
+		{Host: ""},
+		{Host: "https://api.openai.com"},
+		{Host: "API.openai.com"},
+		{Host: "api.*.com"},
+		{Host: "api.openai.com", Detectors: []string{"names"}},
+		{Host: "api.openai.com", Detectors: []string{DetectorSSN, DetectorSSN}},
+	}
+	for _, p := range invalid {
+		if err := ValidateDomainPolicy(p); err == nil {
+			t.Errorf("%s %q: expected a validation error", p.Host, p.Detectors)
+		}
+	}
+}
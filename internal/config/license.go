@@ -0,0 +1,46 @@
+package config
+
+import "strings"
+
+// License key formats describe the keys detected: X stands for a letter or
+// digit, A for a letter and 9 for a digit, separated into groups by -, . or
+// a space, e.g. XXXXX-XXXXX-XXXXX-XXXXX-XXXXX
+const (
+	// MinLicenseKeyChars is the fewest letters and digits a format may
+	// have, so that short codes such as dates are not detected as keys
+	MinLicenseKeyChars = 8
+
+	// MaxLicenseKeyFormatLength is the longest format allowed
+	MaxLicenseKeyFormatLength = 64
+)
+
+// DefaultLicenseKeyFormats are detected while no formats are configured
+var DefaultLicenseKeyFormats = []string{
+	"XXXXX-XXXXX-XXXXX-XXXXX-XXXXX",
+	"XXXXX-XXXXX-XXXXX-XXXXX",
+	"XXXX-XXXX-XXXX-XXXX-XXXX",
+	"XXXX-XXXX-XXXX-XXXX",
+}
+
+// ValidLicenseKeyFormat reports whether format is a usable license key
+// format: groups of X, A and 9 between single separators, with at least
+// MinLicenseKeyChars characters in all
+func ValidLicenseKeyFormat(format string) bool {
+	if format == "" || len(format) > MaxLicenseKeyFormatLength {
+		return false
+	}
+	chars := 0
+	for i, c := range format {
+		switch {
+		case strings.ContainsRune("XA9", c):
+			chars++
+		case strings.ContainsRune("-. ", c):
+			if i == 0 || i == len(format)-1 || strings.ContainsRune("-. ", rune(format[i-1])) {
+				return false
+			}
+		default:
+			return false
+		}
+	}
+	return chars >= MinLicenseKeyChars
+}
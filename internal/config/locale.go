@@ -0,0 +1,41 @@
+package config
+
+// Locales of the detector format packs
+const (
+	LocaleUS   = "us"   // United States and the North American numbering plan
+	LocaleUK   = "uk"   // United Kingdom
+	LocaleDE   = "de"   // Germany
+	LocaleFR   = "fr"   // France
+	LocaleIN   = "in"   // India
+	LocaleCN   = "cn"   // China
+	LocaleJP   = "jp"   // Japan
+	LocaleIntl = "intl" // Any phone number in international +CC format
+)
+
+// PhoneLocales lists the locales with a phone number pack
+var PhoneLocales = []string{LocaleUS, LocaleUK, LocaleDE, LocaleFR, LocaleIN, LocaleCN, LocaleJP, LocaleIntl}
+
+// SSNLocales lists the locales with a national ID pack for the SSN detector
+var SSNLocales = []string{LocaleUS, LocaleUK, LocaleFR, LocaleIN, LocaleCN}
+
+// WithPack returns locales with locale added if the pack is on. No locales
+// stand for the US formats, which are kept.
+func WithPack(locales []string, pack bool, locale string) []string {
+	if !pack || validLocale(locales, locale) {
+		return locales
+	}
+	if len(locales) == 0 {
+		locales = []string{LocaleUS}
+	}
+	return append(append([]string{}, locales...), locale)
+}
+
+// validLocale reports whether locale is one of known
+func validLocale(known []string, locale string) bool {
+	for _, l := range known {
+		if l == locale {
+			return true
+		}
+	}
+	return false
+}
@@ -1,82 +1,550 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/happytaoer/prompt-security/internal/db"
 )
 
-// Manager manages configuration with dynamic reload support
+// ErrPatternNotFound is returned when a string match pattern ID does not exist
+var ErrPatternNotFound = errors.New("string match pattern not found")
+
+// schedulerInterval is how often RunScheduler re-evaluates the schedules
+const schedulerInterval = 15 * time.Second
+
+// watchInterval is how often Watch checks the database for changes made by
+// other processes
+const watchInterval = 2 * time.Second
+
+// Manager manages configuration with dynamic reload support. It also tracks
+// the detection profile selected by the configured schedules; listeners
+// receive the effective configuration with that profile applied.
 type Manager struct {
-	config   Config
-	mu       sync.RWMutex
-	onChange []func(Config) // Callbacks to notify when config changes
+	config          Config
+	version         string        // db.ConfigVersion of config
+	override        func(*Config) // Settings taking precedence over config, may be nil
+	profile         string        // Active detection profile
+	schedule        string        // Schedule that selected profile, empty if none
+	lockdown        bool          // Whether every detector blocks, see SetLockdown
+	now             func() time.Time
+	mu              sync.RWMutex
+	compiler        Compiler                         // Compiles the snapshots, may be nil
+	snapshot        atomic.Pointer[CompiledConfig]   // Of the current state, see Snapshot
+	snapshots       uint64                           // Number of snapshots taken
+	onChange        []func(Config)                   // Callbacks to notify when the effective config changes
+	onProfileChange []func(profile, schedule string) // Callbacks to notify when the active profile changes
 }
 
 // NewManager creates a new configuration manager
 func NewManager() (*Manager, error) {
-	cfg, err := Load()
+	cfg, version, err := loadVersion()
+	if err != nil {
+		return nil, err
+	}
+	state, err := db.GetBreakerState()
 	if err != nil {
 		return nil, err
 	}
+	m := NewStaticManager(cfg)
+	m.version = version
+	m.SetLockdown(state.Tripped)
+	return m, nil
+}
 
-	return &Manager{
+// NewStaticManager creates a manager holding cfg without loading it from the
+// database, e.g. for tests. Its configuration must not be updated.
+func NewStaticManager(cfg Config) *Manager {
+	m := &Manager{
 		config:   cfg,
+		now:      time.Now,
 		onChange: make([]func(Config), 0),
-	}, nil
+	}
+	m.profile, m.schedule = resolveProfile(cfg, m.now())
+	m.compile()
+	return m
 }
 
-// Get returns a copy of the current configuration
+// Get returns a copy of the saved configuration, with overrides applied
 func (m *Manager) Get() Config {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.current()
+}
+
+// Snapshot returns an immutable snapshot of the configuration, see
+// CompiledConfig. It takes no lock nor copy, so prefer it to Get and
+// Effective on hot paths, such as per request or per tick.
+func (m *Manager) Snapshot() *CompiledConfig {
+	return m.snapshot.Load()
+}
+
+// SetCompiler sets how the compiled part of the snapshots is built, e.g.
+// the detectors, and recompiles the current snapshot
+func (m *Manager) SetCompiler(compiler Compiler) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.compiler = compiler
+	m.compile()
+}
+
+// compile takes and returns a snapshot of the current state. The caller
+// must hold mu for writing.
+func (m *Manager) compile() *CompiledConfig {
+	m.snapshots++
+	c := compile(m.current(), m.effective(m.profile), m.profile, m.schedule, m.lockdown, m.snapshots, m.compiler)
+	m.snapshot.Store(c)
+	return c
+}
+
+// Effective returns the saved configuration with overrides and the active
+// profile applied. This is what detection should use.
+func (m *Manager) Effective() Config {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.effective(m.profile)
+}
+
+// Override sets settings that take precedence over the saved configuration,
+// such as those given on the command line. They are applied to the result
+// of Get and Effective but never saved.
+func (m *Manager) Override(override func(*Config)) {
+	m.mu.Lock()
+	m.override = override
+	effective := m.compile().Effective
+	callbacks := m.onChange
+	m.mu.Unlock()
+
+	for _, callback := range callbacks {
+		callback(effective)
+	}
+}
+
+// saved returns a copy of the configuration as saved, without overrides
+func (m *Manager) saved() Config {
 	m.mu.RLock()
 	defer m.mu.RUnlock()
 	return m.config
 }
 
-// Update updates the configuration and notifies all listeners
-func (m *Manager) Update(cfg Config) error {
-	// Save to database first
-	if err := db.SaveConfig(cfg); err != nil {
-		return err
+// current returns the configuration with overrides applied. The caller must
+// hold mu.
+func (m *Manager) current() Config {
+	cfg := m.config
+	if m.override != nil {
+		m.override(&cfg)
+	}
+	return cfg
+}
+
+// effective returns the configuration with overrides, profile and any
+// lockdown applied. The caller must hold mu.
+func (m *Manager) effective(profile string) Config {
+	cfg := ApplyProfile(m.current(), profile)
+	if m.lockdown {
+		cfg = ApplyLockdown(cfg)
 	}
+	return cfg
+}
 
-	// Update in-memory config
+// SetLockdown makes every detector block, e.g. while the circuit breaker is
+// tripped, or restores the configured actions, and notifies all listeners
+// if that changed. Watch keeps it in line with the breaker state in the
+// database.
+func (m *Manager) SetLockdown(lockdown bool) {
 	m.mu.Lock()
-	m.config = cfg
+	if m.lockdown == lockdown {
+		m.mu.Unlock()
+		return
+	}
+	m.lockdown = lockdown
+	effective := m.compile().Effective
 	callbacks := m.onChange
 	m.mu.Unlock()
 
-	// Notify all listeners
 	for _, callback := range callbacks {
-		callback(cfg)
+		callback(effective)
 	}
+}
 
+// Lockdown reports whether every detector blocks
+func (m *Manager) Lockdown() bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.lockdown
+}
+
+// ActiveProfile returns the active detection profile and the name of the
+// schedule that selected it, which is empty outside every schedule
+func (m *Manager) ActiveProfile() (profile, schedule string) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.profile, m.schedule
+}
+
+// Update updates the configuration, records an audit entry attributed to
+// actor and notifies all listeners
+func (m *Manager) Update(cfg Config, actor string) error {
+	if err := Validate(cfg); err != nil {
+		return err
+	}
+
+	previous := m.saved()
+
+	// Disabled patterns are managed by the server and re-enabled by
+	// changing the pattern
+	cfg.DisabledPatterns = carryDisabledPatterns(previous, cfg)
+
+	// The saved configuration is read back so in-memory state matches what
+	// was actually persisted (string match patterns are managed separately)
+	saved, version, err := db.SaveConfigAudited(cfg, actor, previous)
+	if err != nil {
+		return err
+	}
+
+	m.apply(saved, version)
+	return nil
+}
+
+// DisablePattern switches off the custom pattern named by field (e.g.
+// "custom_email_pattern") so detection falls back to the built-in pattern,
+// records an audit entry attributed to actor and notifies all listeners
+func (m *Manager) DisablePattern(field, actor string) error {
+	if err := checkCustomPatternField(field); err != nil {
+		return err
+	}
+
+	previous := m.saved()
+	if containsString(previous.DisabledPatterns, field) {
+		return nil
+	}
+
+	cfg := previous
+	cfg.DisabledPatterns = append(append([]string{}, previous.DisabledPatterns...), field)
+	saved, version, err := db.SaveConfigAudited(cfg, actor, previous)
+	if err != nil {
+		return err
+	}
+
+	m.apply(saved, version)
 	return nil
 }
 
-// OnChange registers a callback to be called when configuration changes
+// SavePattern creates or updates a string match pattern, records an audit
+// entry attributed to actor and notifies all listeners
+func (m *Manager) SavePattern(p StringMatchPattern, actor string) (StringMatchPattern, error) {
+	if err := ValidatePattern(p); err != nil {
+		return StringMatchPattern{}, err
+	}
+
+	previous, exists := m.findPattern(p.ID)
+	if p.ID != 0 && !exists {
+		return StringMatchPattern{}, fmt.Errorf("%w: %d", ErrPatternNotFound, p.ID)
+	}
+
+	saved, err := db.SaveStringMatchPattern(p)
+	if err != nil {
+		return StringMatchPattern{}, err
+	}
+
+	if exists {
+		err = db.AddAudit(actor, db.AuditActionPatternUpdate, previous, saved)
+	} else {
+		err = db.AddAudit(actor, db.AuditActionPatternCreate, nil, saved)
+	}
+	if err != nil {
+		return saved, fmt.Errorf("failed to record audit entry: %w", err)
+	}
+
+	return saved, m.Reload()
+}
+
+// DeletePattern deletes a string match pattern, records an audit entry
+// attributed to actor and notifies all listeners
+func (m *Manager) DeletePattern(id int, actor string) error {
+	previous, exists := m.findPattern(id)
+	if !exists {
+		return fmt.Errorf("%w: %d", ErrPatternNotFound, id)
+	}
+
+	if err := db.DeleteStringMatchPattern(id); err != nil {
+		return err
+	}
+
+	if err := db.AddAudit(actor, db.AuditActionPatternDelete, previous, nil); err != nil {
+		return fmt.Errorf("failed to record audit entry: %w", err)
+	}
+
+	return m.Reload()
+}
+
+// ImportPatterns adds the terms of a CSV or TSV dictionary as string match
+// patterns, see PlanImport, records one audit entry attributed to actor and
+// notifies all listeners. With dryRun it only reports what would be added.
+func (m *Manager) ImportPatterns(content, format string, opts DictionaryOptions, dryRun bool, actor string) (ImportResult, error) {
+	m.mu.RLock()
+	existing := m.config.StringMatchPatterns
+	m.mu.RUnlock()
+
+	result, err := PlanImport(content, format, opts, existing)
+	if err != nil {
+		return result, err
+	}
+	result.DryRun = dryRun
+	if dryRun || len(result.Imported) == 0 {
+		return result, nil
+	}
+
+	if result.Imported, err = db.CreateStringMatchPatterns(result.Imported); err != nil {
+		return result, err
+	}
+	imported := struct {
+		StringMatchPatterns []StringMatchPattern `json:"string_match_patterns"`
+	}{result.Imported}
+	if err := db.AddAudit(actor, db.AuditActionPatternImport, nil, imported); err != nil {
+		return result, fmt.Errorf("failed to record audit entry: %w", err)
+	}
+
+	return result, m.Reload()
+}
+
+// SaveDomainPolicy creates or updates a domain policy, records an audit
+// entry attributed to actor and notifies all listeners
+func (m *Manager) SaveDomainPolicy(p DomainPolicy, actor string) (DomainPolicy, error) {
+	if err := ValidateDomainPolicy(p); err != nil {
+		return DomainPolicy{}, err
+	}
+
+	previous, exists := m.findDomainPolicy(p.ID)
+	if p.ID != 0 && !exists {
+		return DomainPolicy{}, fmt.Errorf("%w: %d", ErrDomainPolicyNotFound, p.ID)
+	}
+	if err := checkDomainPolicyHost(m.saved().DomainPolicies, p); err != nil {
+		return DomainPolicy{}, err
+	}
+
+	saved, err := db.SaveDomainPolicy(p)
+	if err != nil {
+		return DomainPolicy{}, err
+	}
+
+	if exists {
+		err = db.AddAudit(actor, db.AuditActionDomainPolicyUpdate, previous, saved)
+	} else {
+		err = db.AddAudit(actor, db.AuditActionDomainPolicyCreate, nil, saved)
+	}
+	if err != nil {
+		return saved, fmt.Errorf("failed to record audit entry: %w", err)
+	}
+
+	return saved, m.Reload()
+}
+
+// DeleteDomainPolicy deletes a domain policy, records an audit entry
+// attributed to actor and notifies all listeners
+func (m *Manager) DeleteDomainPolicy(id int, actor string) error {
+	previous, exists := m.findDomainPolicy(id)
+	if !exists {
+		return fmt.Errorf("%w: %d", ErrDomainPolicyNotFound, id)
+	}
+
+	if err := db.DeleteDomainPolicy(id); err != nil {
+		return err
+	}
+
+	if err := db.AddAudit(actor, db.AuditActionDomainPolicyDelete, previous, nil); err != nil {
+		return fmt.Errorf("failed to record audit entry: %w", err)
+	}
+
+	return m.Reload()
+}
+
+// findDomainPolicy looks up a domain policy in the current configuration
+func (m *Manager) findDomainPolicy(id int) (DomainPolicy, bool) {
+	if id == 0 {
+		return DomainPolicy{}, false
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, p := range m.config.DomainPolicies {
+		if p.ID == id {
+			return p, true
+		}
+	}
+	return DomainPolicy{}, false
+}
+
+// findPattern looks up a string match pattern in the current configuration
+func (m *Manager) findPattern(id int) (StringMatchPattern, bool) {
+	if id == 0 {
+		return StringMatchPattern{}, false
+	}
+
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, p := range m.config.StringMatchPatterns {
+		if p.ID == id {
+			return p, true
+		}
+	}
+	return StringMatchPattern{}, false
+}
+
+// OnChange registers a callback to be called with the effective
+// configuration whenever it changes
 func (m *Manager) OnChange(callback func(Config)) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 	m.onChange = append(m.onChange, callback)
 }
 
+// OnProfileChange registers a callback to be called when a schedule switches
+// the active detection profile
+func (m *Manager) OnProfileChange(callback func(profile, schedule string)) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.onProfileChange = append(m.onProfileChange, callback)
+}
+
+// RunScheduler periodically switches the active profile according to the
+// configured schedules (blocking)
+func (m *Manager) RunScheduler() {
+	for {
+		time.Sleep(schedulerInterval)
+		m.checkSchedule(m.now())
+	}
+}
+
+// checkSchedule activates the profile selected for t and notifies listeners
+// if it changed
+func (m *Manager) checkSchedule(t time.Time) {
+	m.mu.Lock()
+	profile, schedule := resolveProfile(m.config, t)
+	if profile == m.profile {
+		if schedule != m.schedule {
+			m.schedule = schedule
+			m.compile()
+		}
+		m.mu.Unlock()
+		return
+	}
+	m.profile, m.schedule = profile, schedule
+	effective := m.compile().Effective
+	callbacks, profileCallbacks := m.onChange, m.onProfileChange
+	m.mu.Unlock()
+
+	for _, callback := range callbacks {
+		callback(effective)
+	}
+	for _, callback := range profileCallbacks {
+		callback(profile, schedule)
+	}
+}
+
+// resolveProfile returns the profile selected by cfg's schedules at t
+func resolveProfile(cfg Config, t time.Time) (profile, schedule string) {
+	s, ok := ActiveSchedule(cfg.Schedules, t)
+	if !ok {
+		return ProfileStandard, ""
+	}
+	return s.Profile, s.Name
+}
+
 // Reload reloads configuration from database
 func (m *Manager) Reload() error {
-	cfg, err := Load()
+	cfg, version, err := loadVersion()
 	if err != nil {
 		return err
 	}
 
+	m.apply(cfg, version)
+	return nil
+}
+
+// Watch reloads the configuration whenever another process, such as the
+// ctl command or a second copy sharing the database, changes it, and
+// follows the lockdown of a circuit breaker tripped or reset there (blocking)
+func (m *Manager) Watch() {
+	logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+	var lastErr string
+	for {
+		time.Sleep(watchInterval)
+		err := m.reloadIfChanged()
+		if err == nil {
+			lastErr = ""
+			continue
+		}
+		// Log a persistent failure once
+		if err.Error() != lastErr {
+			logger.Error("Failed to reload changed configuration", "error", err)
+		}
+		lastErr = err.Error()
+	}
+}
+
+// reloadIfChanged reloads the configuration if its version in the database
+// differs from the one loaded, and locks detection down while the database
+// records the circuit breaker as tripped
+func (m *Manager) reloadIfChanged() error {
+	state, err := db.GetBreakerState()
+	if err != nil {
+		return err
+	}
+	m.SetLockdown(state.Tripped)
+
+	version, err := db.ConfigVersion()
+	if err != nil {
+		return err
+	}
+	m.mu.RLock()
+	current := m.version
+	m.mu.RUnlock()
+	if version == current {
+		return nil
+	}
+	return m.Reload()
+}
+
+// loadVersion loads the configuration with the version it was loaded at.
+// The version is read first so that a change in between is not missed.
+func loadVersion() (Config, string, error) {
+	version, err := db.ConfigVersion()
+	if err != nil {
+		return Config{}, "", err
+	}
+	cfg, err := Load()
+	if err != nil {
+		return Config{}, "", err
+	}
+	return cfg, version, nil
+}
+
+// apply swaps the in-memory configuration loaded at version, re-evaluates
+// the schedules and notifies all listeners
+func (m *Manager) apply(cfg Config, version string) {
 	m.mu.Lock()
 	m.config = cfg
-	callbacks := m.onChange
+	m.version = version
+	previous := m.profile
+	m.profile, m.schedule = resolveProfile(cfg, m.now())
+	profile, schedule := m.profile, m.schedule
+	effective := m.compile().Effective
+	callbacks, profileCallbacks := m.onChange, m.onProfileChange
 	m.mu.Unlock()
 
 	// Notify all listeners
 	for _, callback := range callbacks {
-		callback(cfg)
+		callback(effective)
+	}
+	if profile != previous {
+		for _, callback := range profileCallbacks {
+			callback(profile, schedule)
+		}
 	}
-
-	return nil
 }
@@ -0,0 +1,157 @@
+package config
+
+import (
+	"testing"
+	"time"
+
+	"github.com/happytaoer/prompt-security/internal/db"
+)
+
+// TestManager_Override tests that overrides apply to Get and Effective and
+// reach listeners, without changing the saved configuration
+func TestManager_Override(t *testing.T) {
+	m := NewStaticManager(Config{MonitoringInterval: 500, DetectEmails: true})
+
+	var configs []Config
+	m.OnChange(func(cfg Config) { configs = append(configs, cfg) })
+	m.Override(func(cfg *Config) { cfg.MonitoringInterval = 2000 })
+
+	if m.Get().MonitoringInterval != 2000 || m.Effective().MonitoringInterval != 2000 {
+		t.Errorf("Expected the override in Get and Effective, got %d and %d", m.Get().MonitoringInterval, m.Effective().MonitoringInterval)
+	}
+	if len(configs) != 1 || configs[0].MonitoringInterval != 2000 || !configs[0].DetectEmails {
+		t.Errorf("Expected listeners to receive the overridden configuration, got %+v", configs)
+	}
+	if m.saved().MonitoringInterval != 500 {
+		t.Errorf("Expected the saved configuration to be kept, got %d", m.saved().MonitoringInterval)
+	}
+}
+
+// TestManager_Lockdown tests that lockdown applies to Effective only and
+// notifies listeners when it changes
+func TestManager_Lockdown(t *testing.T) {
+	m := NewStaticManager(Config{DetectEmails: true, EmailAction: ActionLog})
+
+	var configs []Config
+	m.OnChange(func(cfg Config) { configs = append(configs, cfg) })
+	m.SetLockdown(true)
+	m.SetLockdown(true)
+
+	if !m.Lockdown() || m.Effective().EmailAction != ActionBlock || m.Get().EmailAction != ActionLog {
+		t.Errorf("Expected lockdown to block in Effective only, got %q and %q", m.Effective().EmailAction, m.Get().EmailAction)
+	}
+	m.SetLockdown(false)
+	if len(configs) != 2 || configs[0].EmailAction != ActionBlock || configs[1].EmailAction != ActionLog {
+		t.Errorf("Expected listeners to be notified of each change, got %+v", configs)
+	}
+}
+
+// TestManager_Snapshot tests that snapshots follow changes, keep their own
+// state and carry the compiler's result
+func TestManager_Snapshot(t *testing.T) {
+	m := NewStaticManager(Config{DetectEmails: true, EmailAction: ActionLog, Allowlist: []string{"ok@example.com"}})
+	first := m.Snapshot()
+	if first.Effective.EmailAction != ActionLog || !first.Allowlist["ok@example.com"] || first.Compiled() != nil {
+		t.Errorf("Expected the initial configuration without compiled part, got %+v", first)
+	}
+
+	m.SetCompiler(func(c *CompiledConfig) interface{} { return c.Effective.EmailAction })
+	m.SetLockdown(true)
+	locked := m.Snapshot()
+	if !locked.Lockdown || locked.Compiled() != ActionBlock || locked.Config.EmailAction != ActionLog || locked.Version <= first.Version {
+		t.Errorf("Expected a newer snapshot compiled in lockdown, got %+v", locked)
+	}
+	if first.Lockdown || first.Effective.EmailAction != ActionLog {
+		t.Error("Expected the earlier snapshot to be unchanged")
+	}
+}
+
+// TestManager_Update tests that an update is saved together with its audit
+// entry and applied
+func TestManager_Update(t *testing.T) {
+	db.SetPath(db.MemoryPath)
+	if err := Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	defer Close()
+	m, err := NewManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := m.Get()
+	cfg.BreakerWebhook = "https://hooks.example.com/breaker"
+	if err := m.Update(cfg, "alice"); err != nil {
+		t.Fatal(err)
+	}
+	if m.Get().BreakerWebhook != cfg.BreakerWebhook {
+		t.Errorf("Expected the update to be applied, got %q", m.Get().BreakerWebhook)
+	}
+	entries, err := db.GetAuditWithPagination(1, 10)
+	if err != nil || len(entries) != 1 || entries[0].Actor != "alice" || entries[0].Action != db.AuditActionConfigUpdate ||
+		len(entries[0].Changes) != 1 || entries[0].Changes[0].Field != "breaker_webhook" {
+		t.Errorf("Expected one audit entry for the webhook, got %+v (%v)", entries, err)
+	}
+	if saved, err := Load(); err != nil || saved.BreakerWebhook != cfg.BreakerWebhook {
+		t.Errorf("Expected the update to be saved, got %q (%v)", saved.BreakerWebhook, err)
+	}
+}
+
+// TestManager_WatchLockdown tests that the lockdown follows the circuit
+// breaker state another process records in the database
+func TestManager_WatchLockdown(t *testing.T) {
+	db.SetPath(db.MemoryPath)
+	if err := Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	defer Close()
+	m, err := NewManager()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.TripBreaker(time.Now(), time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.reloadIfChanged(); err != nil || !m.Lockdown() {
+		t.Fatalf("Expected the tripped breaker to lock detection down, got %v (%v)", m.Lockdown(), err)
+	}
+	if restarted, err := NewManager(); err != nil || !restarted.Lockdown() {
+		t.Errorf("Expected the lockdown to survive a restart, got %v (%v)", restarted != nil && restarted.Lockdown(), err)
+	}
+
+	if _, err := db.ResetBreaker(); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.reloadIfChanged(); err != nil || m.Lockdown() {
+		t.Errorf("Expected the reset to restore the configured actions, got %v (%v)", m.Lockdown(), err)
+	}
+}
+
+// TestInitialize_MigratesNormalizeUnicode tests that the former global
+// normalize_unicode setting turns into normalization for every detector and
+// string match pattern
+func TestInitialize_MigratesNormalizeUnicode(t *testing.T) {
+	db.SetPath(db.MemoryPath)
+	if err := Initialize(); err != nil {
+		t.Fatal(err)
+	}
+	defer Close()
+	if _, err := db.SaveStringMatchPattern(StringMatchPattern{Name: "project", Pattern: "falcon", Enabled: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.GetDB().Exec("UPDATE config SET normalize_unicode = ?", true).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := db.MigrateNormalizeUnicode(NormalizableDetectors); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cfg.NormalizeDetectors) != len(NormalizableDetectors) || len(cfg.StringMatchPatterns) != 1 || !cfg.StringMatchPatterns[0].Normalize {
+		t.Errorf("Expected every detector and pattern to normalize, got %v and %+v", cfg.NormalizeDetectors, cfg.StringMatchPatterns)
+	}
+}
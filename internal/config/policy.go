@@ -0,0 +1,153 @@
+package config
+
+// Detection severities, from least to most severe
+const (
+	SeverityLow      = "low"
+	SeverityMedium   = "medium"
+	SeverityHigh     = "high"
+	SeverityCritical = "critical"
+)
+
+// Severities lists the severities from least to most severe
+var Severities = []string{SeverityLow, SeverityMedium, SeverityHigh, SeverityCritical}
+
+// Default severities of detectors whose severity is not configured
+const (
+	DefaultEmailSeverity       = SeverityMedium
+	DefaultPhoneSeverity       = SeverityMedium
+	DefaultCreditCardSeverity  = SeverityHigh
+	DefaultSSNSeverity         = SeverityHigh
+	DefaultIPV4Severity        = SeverityLow
+	DefaultTOTPSeverity        = SeverityHigh
+	DefaultStringMatchSeverity = SeverityMedium
+)
+
+// ValidSeverity reports whether severity names a known severity
+func ValidSeverity(severity string) bool {
+	for _, s := range Severities {
+		if s == severity {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidAction reports whether action names a detector action
+func ValidAction(action string) bool {
+	switch action {
+	case ActionReplace, ActionBlock, ActionAsk, ActionLog:
+		return true
+	}
+	return false
+}
+
+// PolicyAction returns the action the policy of profile assigns to
+// severity, ActionReplace if it assigns none
+func PolicyAction(cfg Config, profile, severity string) string {
+	if action := cfg.Policies[profile][severity]; action != "" {
+		return action
+	}
+	return ActionReplace
+}
+
+// applyPolicy sets the actions left empty in cfg from the policy of profile,
+// by each detector's severity. String match patterns are copied, so the
+// saved configuration is not modified.
+func applyPolicy(cfg Config, profile string) Config {
+	detectors := []struct {
+		action   *string
+		severity string
+		fallback string
+	}{
+		{&cfg.EmailAction, cfg.EmailSeverity, DefaultEmailSeverity},
+		{&cfg.PhoneAction, cfg.PhoneSeverity, DefaultPhoneSeverity},
+		{&cfg.CreditCardAction, cfg.CreditCardSeverity, DefaultCreditCardSeverity},
+		{&cfg.SSNAction, cfg.SSNSeverity, DefaultSSNSeverity},
+		{&cfg.IPV4Action, cfg.IPV4Severity, DefaultIPV4Severity},
+		{&cfg.TOTPAction, cfg.TOTPSeverity, DefaultTOTPSeverity},
+	}
+	for _, d := range detectors {
+		if *d.action == "" {
+			*d.action = PolicyAction(cfg, profile, severityOr(d.severity, d.fallback))
+		}
+	}
+
+	patterns := make([]StringMatchPattern, len(cfg.StringMatchPatterns))
+	for i, p := range cfg.StringMatchPatterns {
+		if p.Action == "" {
+			p.Action = PolicyAction(cfg, profile, severityOr(p.Severity, DefaultStringMatchSeverity))
+		}
+		patterns[i] = p
+	}
+	cfg.StringMatchPatterns = patterns
+	return cfg
+}
+
+// severityOr returns severity, or fallback if it is empty
+func severityOr(severity, fallback string) string {
+	if severity == "" {
+		return fallback
+	}
+	return severity
+}
+
+// DetectionSeverity returns the severity of detections of typ, the name of a
+// built-in detector or string match pattern, or "" for other types
+func DetectionSeverity(cfg Config, typ string) string {
+	switch typ {
+	case DetectorEmail:
+		return severityOr(cfg.EmailSeverity, DefaultEmailSeverity)
+	case DetectorPhone:
+		return severityOr(cfg.PhoneSeverity, DefaultPhoneSeverity)
+	case DetectorCreditCard:
+		return severityOr(cfg.CreditCardSeverity, DefaultCreditCardSeverity)
+	case DetectorSSN:
+		return severityOr(cfg.SSNSeverity, DefaultSSNSeverity)
+	case DetectorIPV4:
+		return severityOr(cfg.IPV4Severity, DefaultIPV4Severity)
+	case DetectorTOTP:
+		return severityOr(cfg.TOTPSeverity, DefaultTOTPSeverity)
+	}
+	for _, p := range cfg.StringMatchPatterns {
+		if p.Name == typ {
+			return severityOr(p.Severity, DefaultStringMatchSeverity)
+		}
+	}
+	return ""
+}
+
+// SeverityAtLeast reports whether severity is at least as severe as
+// threshold. Unknown severities are below every threshold.
+func SeverityAtLeast(severity, threshold string) bool {
+	rank := func(s string) int {
+		for i, known := range Severities {
+			if known == s {
+				return i
+			}
+		}
+		return -1
+	}
+	return rank(severity) >= 0 && rank(severity) >= rank(threshold)
+}
+
+// ApplyLockdown makes every enabled detector block, along with prompt
+// safety and file scan warnings, as the circuit breaker does once tripped.
+// String match patterns are copied, so cfg is not modified.
+func ApplyLockdown(cfg Config) Config {
+	for _, action := range []*string{&cfg.EmailAction, &cfg.PhoneAction, &cfg.CreditCardAction, &cfg.SSNAction, &cfg.IPV4Action, &cfg.TOTPAction} {
+		*action = ActionBlock
+	}
+	patterns := make([]StringMatchPattern, len(cfg.StringMatchPatterns))
+	for i, p := range cfg.StringMatchPatterns {
+		p.Action = ActionBlock
+		patterns[i] = p
+	}
+	cfg.StringMatchPatterns = patterns
+	if cfg.PromptSafetyMode == PromptSafetyWarn {
+		cfg.PromptSafetyMode = PromptSafetyBlock
+	}
+	if cfg.FileScanMode == FileScanWarn {
+		cfg.FileScanMode = FileScanBlock
+	}
+	return cfg
+}
@@ -0,0 +1,122 @@
+package config
+
+import "testing"
+
+// TestApplyProfile_Policy tests that detector actions come from the policy
+// of the active profile unless set on the detector
+func TestApplyProfile_Policy(t *testing.T) {
+	cfg := Config{
+		DetectEmails:     true,
+		DetectSSNs:       true,
+		SSNSeverity:      SeverityCritical,
+		IPV4Action:       ActionReplace,
+		CreditCardAction: ActionAsk,
+		StringMatchPatterns: []StringMatchPattern{
+			{Name: "key", Pattern: "PRIVATE KEY", Severity: SeverityCritical},
+			{Name: "company", Pattern: "Acme"},
+		},
+		Policies: map[string]map[string]string{
+			ProfileStandard: {SeverityCritical: ActionBlock, SeverityLow: ActionLog},
+			ProfileStrict:   {SeverityMedium: ActionAsk, SeverityLow: ActionBlock},
+		},
+	}
+
+	tests := []struct {
+		profile                                string
+		email, phone, card, ssn, ipv4, key, co string
+	}{
+		{ProfileStandard, ActionReplace, ActionReplace, ActionAsk, ActionBlock, ActionReplace, ActionBlock, ActionReplace},
+		{ProfileStrict, ActionAsk, ActionAsk, ActionAsk, ActionReplace, ActionReplace, ActionReplace, ActionAsk},
+	}
+	for _, tt := range tests {
+		got := ApplyProfile(cfg, tt.profile)
+		actions := []string{got.EmailAction, got.PhoneAction, got.CreditCardAction, got.SSNAction, got.IPV4Action,
+			got.StringMatchPatterns[0].Action, got.StringMatchPatterns[1].Action}
+		want := []string{tt.email, tt.phone, tt.card, tt.ssn, tt.ipv4, tt.key, tt.co}
+		for i := range want {
+			if actions[i] != want[i] {
+				t.Errorf("%s: expected actions %q, got %q", tt.profile, want, actions)
+				break
+			}
+		}
+	}
+
+	if cfg.EmailAction != "" || cfg.StringMatchPatterns[0].Action != "" {
+		t.Error("Expected ApplyProfile not to modify the saved configuration")
+	}
+}
+
+// TestApplyProfile_TOTPSeverity tests that TOTP secrets are high severity
+// unless configured otherwise, so policies can block them
+func TestApplyProfile_TOTPSeverity(t *testing.T) {
+	cfg := Config{
+		DetectTOTPSecrets: true,
+		Policies:          map[string]map[string]string{ProfileStandard: {SeverityHigh: ActionBlock}},
+	}
+	if got := ApplyProfile(cfg, ProfileStandard); got.TOTPAction != ActionBlock {
+		t.Errorf("Expected TOTP secrets to be blocked, got %q", got.TOTPAction)
+	}
+	if got := DetectionSeverity(cfg, DetectorTOTP); got != SeverityHigh {
+		t.Errorf("Expected high severity, got %q", got)
+	}
+
+	cfg.TOTPSeverity = SeverityMedium
+	if got := ApplyProfile(cfg, ProfileStandard); got.TOTPAction != ActionReplace {
+		t.Errorf("Expected medium TOTP secrets to be replaced, got %q", got.TOTPAction)
+	}
+}
+
+// TestPolicyAction tests the fallback for unmapped severities
+func TestPolicyAction(t *testing.T) {
+	cfg := Config{Policies: map[string]map[string]string{ProfileStandard: {SeverityHigh: ActionBlock}}}
+	if got := PolicyAction(cfg, ProfileStandard, SeverityHigh); got != ActionBlock {
+		t.Errorf("Expected block, got %q", got)
+	}
+	if got := PolicyAction(cfg, ProfileStrict, SeverityHigh); got != ActionReplace {
+		t.Errorf("Expected replace for an unmapped profile, got %q", got)
+	}
+}
+
+// TestApplyLockdown tests that lockdown blocks every detection without
+// modifying the configuration given
+func TestApplyLockdown(t *testing.T) {
+	cfg := Config{
+		EmailAction:         ActionLog,
+		PromptSafetyMode:    PromptSafetyWarn,
+		FileScanMode:        FileScanOff,
+		StringMatchPatterns: []StringMatchPattern{{Name: "key", Pattern: "PRIVATE KEY", Action: ActionAsk}},
+	}
+	got := ApplyLockdown(cfg)
+	if got.EmailAction != ActionBlock || got.IPV4Action != ActionBlock || got.TOTPAction != ActionBlock || got.StringMatchPatterns[0].Action != ActionBlock {
+		t.Errorf("Expected every detector to block, got %+v", got)
+	}
+	if got.PromptSafetyMode != PromptSafetyBlock || got.FileScanMode != FileScanOff {
+		t.Errorf("Expected warnings to block and disabled scans to stay off, got %q and %q", got.PromptSafetyMode, got.FileScanMode)
+	}
+	if cfg.EmailAction != ActionLog || cfg.StringMatchPatterns[0].Action != ActionAsk {
+		t.Error("Expected ApplyLockdown not to modify the configuration given")
+	}
+}
+
+// TestDetectionSeverity tests severities of built-in detectors and string
+// match patterns
+func TestDetectionSeverity(t *testing.T) {
+	cfg := Config{
+		EmailSeverity:       SeverityCritical,
+		StringMatchPatterns: []StringMatchPattern{{Name: "company", Pattern: "Acme"}},
+	}
+	tests := map[string]string{
+		DetectorEmail:      SeverityCritical,
+		DetectorCreditCard: DefaultCreditCardSeverity,
+		"company":          DefaultStringMatchSeverity,
+		"prompt_injection": "",
+	}
+	for typ, want := range tests {
+		if got := DetectionSeverity(cfg, typ); got != want {
+			t.Errorf("%s: expected %q, got %q", typ, want, got)
+		}
+	}
+	if !SeverityAtLeast(SeverityCritical, SeverityHigh) || SeverityAtLeast(SeverityMedium, SeverityHigh) || SeverityAtLeast("", SeverityLow) {
+		t.Error("Expected severities to be ranked from low to critical")
+	}
+}
@@ -0,0 +1,152 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/happytaoer/prompt-security/internal/db"
+)
+
+// Schedule switches the detection profile during a time window
+type Schedule = db.Schedule
+
+// Detection profiles a schedule can activate
+const (
+	// ProfileStandard uses the configuration as saved
+	ProfileStandard = "standard"
+	// ProfileStrict enables every detector and string match pattern and
+	// normalizes Unicode before matching with each of them
+	ProfileStrict = "strict"
+	// ProfileOff disables all detection
+	ProfileOff = "off"
+)
+
+// StrictReplacement replaces matches of detectors that strict mode enables
+// without a configured replacement
+const StrictReplacement = "[REDACTED]"
+
+// weekdays maps schedule day names to weekdays
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// ValidProfile reports whether profile names a known detection profile
+func ValidProfile(profile string) bool {
+	switch profile {
+	case ProfileStandard, ProfileStrict, ProfileOff:
+		return true
+	}
+	return false
+}
+
+// ApplyProfile returns the configuration the detectors use when profile is
+// active, with detector actions resolved from the profile's policy
+func ApplyProfile(cfg Config, profile string) Config {
+	switch profile {
+	case ProfileStrict:
+		cfg.DetectEmails = true
+		cfg.DetectPhones = true
+		cfg.DetectCreditCards = true
+		cfg.DetectSSNs = true
+		cfg.DetectIPV4 = true
+		cfg.NormalizeDetectors = NormalizableDetectors
+		for _, r := range []*string{&cfg.EmailReplacement, &cfg.PhoneReplacement, &cfg.CreditCardReplacement, &cfg.SSNReplacement, &cfg.IPV4Replacement} {
+			if *r == "" {
+				*r = StrictReplacement
+			}
+		}
+		patterns := make([]StringMatchPattern, len(cfg.StringMatchPatterns))
+		for i, p := range cfg.StringMatchPatterns {
+			p.Enabled, p.Normalize = true, true
+			patterns[i] = p
+		}
+		cfg.StringMatchPatterns = patterns
+		return applyPolicy(cfg, profile)
+	case ProfileOff:
+		cfg.DetectEmails = false
+		cfg.DetectPhones = false
+		cfg.DetectCreditCards = false
+		cfg.DetectSSNs = false
+		cfg.DetectIPV4 = false
+		cfg.ChinaPack = false
+		cfg.DetectLocations = false
+		cfg.DetectTerraformSecrets = false
+		cfg.DetectHTTPAuth = false
+		cfg.DetectCookies = false
+		cfg.SanitizeCurl = false
+		cfg.DetectHomePaths = false
+		cfg.DetectShellPrompts = false
+		cfg.DetectInfrastructureIDs = false
+		cfg.DetectLicenseKeys = false
+		cfg.DetectMACAddresses = false
+		cfg.DetectTOTPSecrets = false
+		cfg.DetectRecoveryCodes = false
+		cfg.DetectPasswords = false
+		cfg.DetectSpecialCategories = false
+		cfg.StringMatchPatterns = nil
+		return cfg
+	}
+	return applyPolicy(cfg, ProfileStandard)
+}
+
+// ActiveSchedule returns the first enabled schedule covering t
+func ActiveSchedule(schedules []Schedule, t time.Time) (Schedule, bool) {
+	for _, s := range schedules {
+		if s.Enabled && scheduleCovers(s, t) {
+			return s, true
+		}
+	}
+	return Schedule{}, false
+}
+
+// scheduleCovers reports whether t falls within the schedule's window
+func scheduleCovers(s Schedule, t time.Time) bool {
+	start, err := parseClock(s.Start)
+	if err != nil {
+		return false
+	}
+	end, err := parseClock(s.End)
+	if err != nil {
+		return false
+	}
+
+	now := t.Hour()*60 + t.Minute()
+	if start < end {
+		return onDay(s, t.Weekday()) && now >= start && now < end
+	}
+
+	// Spans midnight: the early hours belong to the previous day's window
+	if now >= start {
+		return onDay(s, t.Weekday())
+	}
+	return now < end && onDay(s, (t.Weekday()+6)%7)
+}
+
+// onDay reports whether the schedule applies to day
+func onDay(s Schedule, day time.Weekday) bool {
+	if len(s.Days) == 0 {
+		return true
+	}
+	for _, d := range s.Days {
+		if weekdays[strings.ToLower(d)] == day {
+			return true
+		}
+	}
+	return false
+}
+
+// parseClock parses an HH:MM time into minutes after midnight
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
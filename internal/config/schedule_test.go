@@ -0,0 +1,123 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+// at returns a local time on the week of Monday 2024-01-01
+func at(day time.Weekday, clock string) time.Time {
+	t, _ := time.ParseInLocation("15:04", clock, time.Local)
+	return time.Date(2024, 1, 1+int(day+6)%7, t.Hour(), t.Minute(), 0, 0, time.Local)
+}
+
+// TestActiveSchedule tests schedule window matching
+func TestActiveSchedule(t *testing.T) {
+	schedules := []Schedule{
+		{Name: "disabled", Start: "00:00", End: "23:59", Profile: ProfileOff},
+		{Name: "work", Days: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "09:00", End: "17:00", Profile: ProfileStrict, Enabled: true},
+		{Name: "night", Days: []string{"fri"}, Start: "22:00", End: "06:00", Profile: ProfileOff, Enabled: true},
+	}
+
+	tests := []struct {
+		name     string
+		time     time.Time
+		expected string
+	}{
+		{"WorkStart", at(time.Monday, "09:00"), "work"},
+		{"WorkEndExclusive", at(time.Monday, "17:00"), ""},
+		{"Weekend", at(time.Saturday, "10:00"), ""},
+		{"OvernightStartDay", at(time.Friday, "23:30"), "night"},
+		{"OvernightNextMorning", at(time.Saturday, "05:59"), "night"},
+		{"OvernightOtherDay", at(time.Thursday, "23:30"), ""},
+		{"OvernightAfterEnd", at(time.Saturday, "06:00"), ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s, ok := ActiveSchedule(schedules, tt.time)
+			if tt.expected == "" {
+				if ok {
+					t.Errorf("Expected no schedule, got %q", s.Name)
+				}
+				return
+			}
+			if !ok || s.Name != tt.expected {
+				t.Errorf("Expected %q, got %q (active %v)", tt.expected, s.Name, ok)
+			}
+		})
+	}
+}
+
+// TestApplyProfile tests how profiles change the effective configuration
+func TestApplyProfile(t *testing.T) {
+	cfg := Config{
+		DetectEmails:     true,
+		EmailReplacement: "[EMAIL]",
+		StringMatchPatterns: []StringMatchPattern{
+			{Name: "company", Pattern: "Acme", Replacement: "[COMPANY]"},
+		},
+	}
+
+	if got := ApplyProfile(cfg, ProfileStandard); !got.DetectEmails || got.DetectPhones {
+		t.Errorf("Expected standard profile to keep the configuration, got %+v", got)
+	}
+
+	strict := ApplyProfile(cfg, ProfileStrict)
+	if !strict.DetectPhones || len(strict.NormalizeDetectors) != len(NormalizableDetectors) || !strict.StringMatchPatterns[0].Enabled || !strict.StringMatchPatterns[0].Normalize {
+		t.Errorf("Expected strict profile to enable everything, got %+v", strict)
+	}
+	if strict.EmailReplacement != "[EMAIL]" || strict.PhoneReplacement != StrictReplacement {
+		t.Errorf("Unexpected strict replacements %q, %q", strict.EmailReplacement, strict.PhoneReplacement)
+	}
+	if cfg.StringMatchPatterns[0].Enabled {
+		t.Error("Expected ApplyProfile not to modify the saved patterns")
+	}
+
+	if off := ApplyProfile(cfg, ProfileOff); off.DetectEmails || len(off.StringMatchPatterns) != 0 {
+		t.Errorf("Expected off profile to disable detection, got %+v", off)
+	}
+}
+
+// TestManager_CheckSchedule tests that the scheduler swaps profiles and notifies listeners
+func TestManager_CheckSchedule(t *testing.T) {
+	m := &Manager{
+		config: Config{
+			DetectEmails:     true,
+			EmailReplacement: "[EMAIL]",
+			Schedules: []Schedule{
+				{Name: "night", Start: "22:00", End: "06:00", Profile: ProfileOff, Enabled: true},
+			},
+		},
+		profile: ProfileStandard,
+	}
+
+	var configs []Config
+	var profiles []string
+	m.OnChange(func(cfg Config) { configs = append(configs, cfg) })
+	m.OnProfileChange(func(profile, schedule string) { profiles = append(profiles, profile+"/"+schedule) })
+
+	m.checkSchedule(at(time.Monday, "12:00"))
+	if len(configs) != 0 || len(profiles) != 0 {
+		t.Fatal("Expected no notification while the profile is unchanged")
+	}
+
+	m.checkSchedule(at(time.Monday, "23:00"))
+	if len(profiles) != 1 || profiles[0] != "off/night" {
+		t.Fatalf("Expected switch to off/night, got %v", profiles)
+	}
+	if len(configs) != 1 || configs[0].DetectEmails {
+		t.Errorf("Expected listeners to receive the off profile, got %+v", configs)
+	}
+	if !m.Get().DetectEmails || m.Effective().DetectEmails {
+		t.Error("Expected Get to return the saved and Effective the scheduled configuration")
+	}
+
+	m.checkSchedule(at(time.Tuesday, "07:00"))
+	if profile, schedule := m.ActiveProfile(); profile != ProfileStandard || schedule != "" {
+		t.Errorf("Expected standard profile after the window, got %q/%q", profile, schedule)
+	}
+	if len(configs) != 2 || !configs[1].DetectEmails {
+		t.Errorf("Expected listeners to receive the standard profile, got %+v", configs)
+	}
+}
@@ -0,0 +1,142 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/happytaoer/prompt-security/internal/db"
+)
+
+// Built-in detectors that first-run setup can enable
+const (
+	DetectorEmail      = "email"
+	DetectorPhone      = "phone"
+	DetectorCreditCard = "credit_card"
+	DetectorSSN        = "ssn"
+	DetectorIPV4       = "ipv4"
+)
+
+// Detectors lists the built-in detectors in the order they are offered
+var Detectors = []string{DetectorEmail, DetectorPhone, DetectorCreditCard, DetectorSSN, DetectorIPV4}
+
+// DefaultSetupDetectors are the detectors setup suggests. IPv4 addresses
+// are common in logs and configuration that is safe to share.
+var DefaultSetupDetectors = []string{DetectorEmail, DetectorPhone, DetectorCreditCard, DetectorSSN}
+
+// Replacement strategies offered by first-run setup
+const (
+	// StrategyFake replaces matches with realistic fake values, keeping the
+	// text readable and its format intact
+	StrategyFake = "fake"
+	// StrategyPlaceholder replaces matches with a placeholder naming the
+	// type, e.g. [EMAIL]
+	StrategyPlaceholder = "placeholder"
+	// StrategyRedact replaces every match with [REDACTED]
+	StrategyRedact = "redact"
+)
+
+// Strategies lists the replacement strategies in the order they are offered
+var Strategies = []string{StrategyFake, StrategyPlaceholder, StrategyRedact}
+
+// DefaultStrategy is the replacement strategy setup suggests
+const DefaultStrategy = StrategyFake
+
+// strategyReplacements maps each strategy to the replacement of each
+// detector
+var strategyReplacements = map[string]map[string]string{
+	StrategyFake: {
+		DetectorEmail:      "security@example.com",
+		DetectorPhone:      "+1-555-123-4567",
+		DetectorCreditCard: "XXXX-XXXX-XXXX-XXXX",
+		DetectorSSN:        "XXX-XX-XXXX",
+		DetectorIPV4:       "0.0.0.0",
+	},
+	StrategyPlaceholder: {
+		DetectorEmail:      "[EMAIL]",
+		DetectorPhone:      "[PHONE]",
+		DetectorCreditCard: "[CREDIT_CARD]",
+		DetectorSSN:        "[SSN]",
+		DetectorIPV4:       "[IP]",
+	},
+	StrategyRedact: {
+		DetectorEmail:      StrictReplacement,
+		DetectorPhone:      StrictReplacement,
+		DetectorCreditCard: StrictReplacement,
+		DetectorSSN:        StrictReplacement,
+		DetectorIPV4:       StrictReplacement,
+	},
+}
+
+// SetupChoices are the answers given during first-run setup
+type SetupChoices struct {
+	Detectors []string `json:"detectors"` // Detectors to enable, the others are disabled
+	Strategy  string   `json:"strategy"`  // Replacement strategy
+}
+
+// ApplySetup returns cfg with the detectors and replacements chosen during
+// setup. Unknown detectors or strategies are reported as a
+// *ValidationError.
+func ApplySetup(cfg Config, choices SetupChoices) (Config, error) {
+	v := &validator{}
+	enabled := make(map[string]bool, len(choices.Detectors))
+	for _, d := range choices.Detectors {
+		if _, ok := strategyReplacements[StrategyFake][d]; !ok {
+			v.add("detectors", "unknown detector %q, expected one of %s", d, strings.Join(Detectors, ", "))
+			continue
+		}
+		enabled[d] = true
+	}
+	replacements, ok := strategyReplacements[choices.Strategy]
+	if !ok {
+		v.add("strategy", "unknown strategy %q, expected one of %s", choices.Strategy, strings.Join(Strategies, ", "))
+	}
+	if err := v.err(); err != nil {
+		return cfg, err
+	}
+
+	cfg.DetectEmails = enabled[DetectorEmail]
+	cfg.DetectPhones = enabled[DetectorPhone]
+	cfg.DetectCreditCards = enabled[DetectorCreditCard]
+	cfg.DetectSSNs = enabled[DetectorSSN]
+	cfg.DetectIPV4 = enabled[DetectorIPV4]
+	cfg.EmailReplacement = replacements[DetectorEmail]
+	cfg.PhoneReplacement = replacements[DetectorPhone]
+	cfg.CreditCardReplacement = replacements[DetectorCreditCard]
+	cfg.SSNReplacement = replacements[DetectorSSN]
+	cfg.IPV4Replacement = replacements[DetectorIPV4]
+	return cfg, nil
+}
+
+// ParseDetectors splits a comma separated list of detectors, as given on
+// the command line
+func ParseDetectors(s string) []string {
+	var detectors []string
+	for _, d := range strings.Split(s, ",") {
+		if d = strings.TrimSpace(d); d != "" {
+			detectors = append(detectors, d)
+		}
+	}
+	return detectors
+}
+
+// DescribeStrategy returns an example of what a strategy replaces an email
+// address with
+func DescribeStrategy(strategy string) string {
+	return fmt.Sprintf("%s (email becomes %s)", strategy, strategyReplacements[strategy][DetectorEmail])
+}
+
+// SetupState returns when first-run setup was completed, nil if it was not,
+// and whether the configuration was ever changed. A changed configuration
+// means its defaults were reviewed, so setup is no longer needed.
+func SetupState() (*time.Time, bool, error) {
+	completedAt, err := db.GetSetupCompletedAt()
+	if err != nil {
+		return nil, false, err
+	}
+	changes, err := db.GetAuditCount()
+	if err != nil {
+		return nil, false, err
+	}
+	return completedAt, changes > 0, nil
+}
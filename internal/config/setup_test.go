@@ -0,0 +1,65 @@
+package config
+
+import (
+	"errors"
+	"testing"
+)
+
+// TestApplySetup tests applying the choices made during first-run setup
+func TestApplySetup(t *testing.T) {
+	cfg := validConfig()
+	cfg.DetectIPV4 = true
+
+	got, err := ApplySetup(cfg, SetupChoices{Detectors: []string{DetectorPhone, DetectorSSN}, Strategy: StrategyPlaceholder})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if got.DetectEmails || !got.DetectPhones || got.DetectCreditCards || !got.DetectSSNs || got.DetectIPV4 {
+		t.Errorf("Expected only phone and SSN detection, got %+v", got)
+	}
+	if got.PhoneReplacement != "[PHONE]" || got.EmailReplacement != "[EMAIL]" {
+		t.Errorf("Expected placeholder replacements, got %q and %q", got.PhoneReplacement, got.EmailReplacement)
+	}
+	if err := Validate(got); err != nil {
+		t.Errorf("Expected the result to validate, got %v", err)
+	}
+	if got.MonitoringInterval != cfg.MonitoringInterval || len(got.StringMatchPatterns) != 1 {
+		t.Error("Expected other settings to be kept")
+	}
+}
+
+// TestApplySetup_Invalid tests that unknown choices are reported per field
+func TestApplySetup_Invalid(t *testing.T) {
+	_, err := ApplySetup(validConfig(), SetupChoices{Detectors: []string{DetectorEmail, "iban"}, Strategy: "blur"})
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) {
+		t.Fatalf("Expected a *ValidationError, got %v", err)
+	}
+	if len(validationErr.Fields) != 2 || validationErr.Fields[0].Field != "detectors" || validationErr.Fields[1].Field != "strategy" {
+		t.Errorf("Expected detectors and strategy errors, got %+v", validationErr.Fields)
+	}
+}
+
+// TestSetupStrategies tests that every strategy replaces every detector
+func TestSetupStrategies(t *testing.T) {
+	for _, strategy := range Strategies {
+		got, err := ApplySetup(Config{}, SetupChoices{Detectors: Detectors, Strategy: strategy})
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", strategy, err)
+		}
+		for _, r := range []string{got.EmailReplacement, got.PhoneReplacement, got.CreditCardReplacement, got.SSNReplacement, got.IPV4Replacement} {
+			if r == "" {
+				t.Errorf("%s: expected a replacement for every detector, got %+v", strategy, got)
+				break
+			}
+		}
+	}
+}
+
+// TestParseDetectors tests parsing a comma separated detector list
+func TestParseDetectors(t *testing.T) {
+	got := ParseDetectors(" email, ,ssn,")
+	if len(got) != 2 || got[0] != DetectorEmail || got[1] != DetectorSSN {
+		t.Errorf("Expected [email ssn], got %q", got)
+	}
+}
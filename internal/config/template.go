@@ -0,0 +1,91 @@
+package config
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// templateRef matches a capture group reference in a replacement template,
+// ${name}, ${1}, $name or $1 as in regexp.Expand, or $$ for a literal $
+var templateRef = regexp.MustCompile(`\$(?:\{(\w+)\}|(\w+)|\$)`)
+
+// IsTemplate reports whether a replacement references capture groups or
+// escapes a $
+func IsTemplate(replacement string) bool {
+	return templateRef.MatchString(replacement)
+}
+
+// refName returns the group a template reference names, or $ for $$
+func refName(ref string) string {
+	if strings.HasPrefix(ref, "${") {
+		return ref[2 : len(ref)-1]
+	}
+	return ref[1:]
+}
+
+// ExpandTemplate expands the capture group references in a replacement
+// template with the submatches of pattern at loc, as returned by
+// FindStringSubmatchIndex. Like regexp.Expand, $name takes the longest name
+// possible, so $1x refers to a group named 1x, not to $1 followed by x.
+// Unknown and unmatched groups expand to nothing.
+func ExpandTemplate(pattern *regexp.Regexp, template, text string, loc []int) string {
+	return templateRef.ReplaceAllStringFunc(template, func(ref string) string {
+		if ref == "$$" {
+			return "$"
+		}
+		i := matchedGroup(pattern, refName(ref), loc)
+		if i < 0 {
+			return ""
+		}
+		return text[loc[2*i]:loc[2*i+1]]
+	})
+}
+
+// matchedGroup returns the index of the referenced capture group that took
+// part in the match at loc, or -1. A name may be shared by groups in
+// different alternatives, only one of which matches.
+func matchedGroup(pattern *regexp.Regexp, ref string, loc []int) int {
+	matched := func(i int) bool { return i >= 0 && 2*i+1 < len(loc) && loc[2*i] >= 0 }
+	if n, err := strconv.Atoi(ref); err == nil {
+		if !matched(n) {
+			return -1
+		}
+		return n
+	}
+	for i, name := range pattern.SubexpNames() {
+		if name == ref && matched(i) {
+			return i
+		}
+	}
+	return -1
+}
+
+// groupIndex returns the index of a capture group referenced by name or
+// number, or -1 if pattern has no such group
+func groupIndex(pattern *regexp.Regexp, ref string) int {
+	if n, err := strconv.Atoi(ref); err == nil {
+		if n > pattern.NumSubexp() {
+			return -1
+		}
+		return n
+	}
+	return pattern.SubexpIndex(ref)
+}
+
+// template checks that the groups a replacement template references exist
+// in a custom pattern. References to built-in patterns are not checked.
+func (v *validator) template(field, replacement, pattern string) {
+	if pattern == "" || !IsTemplate(replacement) {
+		return
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return // Reported on the pattern field
+	}
+	for _, ref := range templateRef.FindAllString(replacement, -1) {
+		if name := refName(ref); name != "$" && groupIndex(re, name) < 0 {
+			v.add(field, "references unknown capture group %q", name)
+		}
+	}
+}
@@ -0,0 +1,483 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/happytaoer/prompt-security/internal/i18n"
+)
+
+// Monitoring interval bounds in milliseconds
+const (
+	MinMonitoringInterval = 100
+	MaxMonitoringInterval = 60000
+)
+
+// Scan timeout bounds in milliseconds
+const (
+	MinScanTimeout = 100
+	MaxScanTimeout = 600000
+)
+
+// Ways to handle clipboard content larger than MaxClipboardBytes
+const (
+	LargeContentChunked = "chunked" // Scan in chunks within the scan timeout
+	LargeContentSkip    = "skip"    // Leave the content unfiltered
+)
+
+// What filter logs store
+const (
+	LogModeFull = "full" // Original and filtered text
+	LogModeHash = "hash" // Salted hashes of the text and the detected types only
+)
+
+// What to do when copied files contain sensitive data
+const (
+	FileScanOff   = "off"   // Do not scan copied files
+	FileScanWarn  = "warn"  // Log a warning
+	FileScanBlock = "block" // Replace the clipboard with a notice
+)
+
+// What to do when text contains prompt injection phrases
+const (
+	PromptSafetyOff   = "off"   // Do not look for prompt injection
+	PromptSafetyWarn  = "warn"  // Log matches, leaving the text as is
+	PromptSafetyBlock = "block" // Block the text
+)
+
+// How logged events are given a risk label
+const (
+	ClassifierOff       = "off"       // Do not classify
+	ClassifierHeuristic = "heuristic" // Built-in local heuristics
+	ClassifierHTTP      = "http"      // Post the filtered text to ClassifierEndpoint
+)
+
+// How report e-mails are sent to the SMTP server
+const (
+	SMTPStartTLS    = "starttls" // Upgrade the connection with STARTTLS, the default
+	SMTPImplicitTLS = "tls"      // Connect with TLS, usually to port 465
+	SMTPPlain       = "none"     // No encryption, for local relays
+)
+
+// DefaultSMTPPort is the SMTP port used when none is configured
+const DefaultSMTPPort = 587
+
+// Bounds of how long an ask action keeps the original, in seconds
+const (
+	MinAskTimeout = 1
+	MaxAskTimeout = 3600
+)
+
+// What a detector does with its matches
+const (
+	ActionReplace = "replace" // Replace matches; the default when empty
+	ActionBlock   = "block"   // Replace the whole copy with the block message
+	ActionAsk     = "ask"     // Replace matches, offering to restore the original
+	ActionLog     = "log"     // Only log matches, leaving the text as is
+
+	// ActionReview is set by the filter on matches below the review
+	// threshold, which are queued for review and left as is. It cannot be
+	// configured.
+	ActionReview = "review"
+)
+
+// FieldError describes a single invalid configuration field
+type FieldError struct {
+	Field   string `json:"field"`   // JSON name of the field, e.g. "custom_email_pattern"
+	Message string `json:"message"` // Human readable reason
+}
+
+// ErrInvalidPattern matches, with errors.Is, the errors of invalid string
+// match patterns and custom regular expressions
+var ErrInvalidPattern = errors.New("invalid pattern")
+
+// ValidationError is returned when a configuration fails validation
+type ValidationError struct {
+	Fields  []FieldError
+	pattern bool // Whether a pattern or custom regular expression is invalid
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = f.Field + ": " + f.Message
+	}
+	return "invalid configuration: " + strings.Join(msgs, "; ")
+}
+
+// Is reports whether target is ErrInvalidPattern and a string match pattern
+// or custom regular expression is among the invalid fields
+func (e *ValidationError) Is(target error) bool {
+	return target == ErrInvalidPattern && e.pattern
+}
+
+// validator accumulates field errors
+type validator struct {
+	fields  []FieldError
+	pattern bool // A pattern or custom regular expression failed
+}
+
+func (v *validator) add(field, format string, args ...interface{}) {
+	v.fields = append(v.fields, FieldError{Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// err returns a *ValidationError if any field failed, nil otherwise
+func (v *validator) err() error {
+	if len(v.fields) == 0 {
+		return nil
+	}
+	return &ValidationError{Fields: v.fields, pattern: v.pattern}
+}
+
+// regex checks that a non-empty custom pattern compiles and is not too
+// complex to scan quickly
+func (v *validator) regex(field, pattern string) {
+	if pattern == "" {
+		return
+	}
+	if _, err := regexp.Compile(pattern); err != nil {
+		v.add(field, "invalid regular expression: %v", err)
+		v.pattern = true
+		return
+	}
+	if n, err := patternComplexity(pattern); err == nil && n > MaxPatternComplexity {
+		v.add(field, "regular expression is too complex (%d instructions, at most %d allowed)", n, MaxPatternComplexity)
+		v.pattern = true
+	}
+}
+
+// replacement checks that an enabled detector has a replacement value
+func (v *validator) replacement(field string, enabled bool, value string) {
+	if enabled && value == "" {
+		v.add(field, "must not be empty while the detector is enabled")
+	}
+}
+
+// locales checks that every locale of a detector has a format pack
+func (v *validator) locales(field string, locales, known []string) {
+	for _, l := range locales {
+		if !validLocale(known, l) {
+			v.add(field, "unknown locale %q, expected %s", l, strings.Join(known, ", "))
+		}
+	}
+}
+
+// values checks that a list of literal values has no empty entries
+func (v *validator) values(field string, values []string) {
+	for _, value := range values {
+		if value == "" {
+			v.add(field, "must not contain empty values")
+			return
+		}
+	}
+}
+
+// priority checks that a conflict priority is not negative
+func (v *validator) priority(field string, value int) {
+	if value < 0 {
+		v.add(field, "must not be negative")
+	}
+}
+
+// action checks a detector action
+func (v *validator) action(field, value string) {
+	if value != "" && !ValidAction(value) {
+		v.add(field, "must be %q, %q, %q or %q", ActionReplace, ActionBlock, ActionAsk, ActionLog)
+	}
+}
+
+// severity checks a detector severity
+func (v *validator) severity(field, value string) {
+	if value != "" && !ValidSeverity(value) {
+		v.add(field, "must be %s", strings.Join(Severities, ", "))
+	}
+}
+
+// policies checks the severity to action policies of the profiles
+func (v *validator) policies(policies map[string]map[string]string) {
+	for _, profile := range sortedKeys(policies) {
+		if profile != ProfileStandard && profile != ProfileStrict {
+			v.add("policies", "unknown profile %q, expected %s or %s", profile, ProfileStandard, ProfileStrict)
+			continue
+		}
+		policy := policies[profile]
+		for _, severity := range sortedKeys(policy) {
+			action := policy[severity]
+			if !ValidSeverity(severity) {
+				v.add("policies."+profile, "unknown severity %q, expected %s", severity, strings.Join(Severities, ", "))
+			} else if !ValidAction(action) {
+				v.add("policies."+profile+"."+severity, "must be %q, %q, %q or %q", ActionReplace, ActionBlock, ActionAsk, ActionLog)
+			}
+		}
+	}
+}
+
+// sortedKeys returns the keys of m in order, so errors are reported in a
+// stable order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Validate checks a configuration before it is saved and returns a
+// *ValidationError listing every invalid field
+func Validate(cfg Config) error {
+	v := &validator{}
+
+	for _, p := range customPatterns(&cfg) {
+		v.regex(p.field, *p.value)
+	}
+
+	v.replacement("email_replacement", cfg.DetectEmails, cfg.EmailReplacement)
+	v.replacement("phone_replacement", cfg.DetectPhones, cfg.PhoneReplacement)
+	v.replacement("credit_card_replacement", cfg.DetectCreditCards, cfg.CreditCardReplacement)
+	v.replacement("ssn_replacement", cfg.DetectSSNs, cfg.SSNReplacement)
+	v.replacement("ipv4_replacement", cfg.DetectIPV4, cfg.IPV4Replacement)
+	v.replacement("location_replacement", cfg.DetectLocations, cfg.LocationReplacement)
+	v.replacement("terraform_replacement", cfg.DetectTerraformSecrets, cfg.TerraformReplacement)
+	v.replacement("http_auth_replacement", cfg.DetectHTTPAuth, cfg.HTTPAuthReplacement)
+	v.replacement("cookie_replacement", cfg.DetectCookies, cfg.CookieReplacement)
+	v.replacement("home_path_replacement", cfg.DetectHomePaths, cfg.HomePathReplacement)
+	v.replacement("shell_prompt_replacement", cfg.DetectShellPrompts, cfg.ShellPromptReplacement)
+	v.replacement("gcp_project_replacement", cfg.DetectInfrastructureIDs, cfg.GCPProjectReplacement)
+	v.replacement("azure_id_replacement", cfg.DetectInfrastructureIDs, cfg.AzureIDReplacement)
+	v.replacement("license_key_replacement", cfg.DetectLicenseKeys, cfg.LicenseKeyReplacement)
+	v.replacement("mac_replacement", cfg.DetectMACAddresses, cfg.MACReplacement)
+	v.replacement("totp_replacement", cfg.DetectTOTPSecrets, cfg.TOTPReplacement)
+	v.replacement("recovery_code_replacement", cfg.DetectRecoveryCodes, cfg.RecoveryCodeReplacement)
+	v.replacement("password_replacement", cfg.DetectPasswords, cfg.PasswordReplacement)
+
+	v.template("email_replacement", cfg.EmailReplacement, cfg.CustomEmailPattern)
+	v.template("phone_replacement", cfg.PhoneReplacement, cfg.CustomPhonePattern)
+	v.template("credit_card_replacement", cfg.CreditCardReplacement, cfg.CustomCreditCardPattern)
+	v.template("ssn_replacement", cfg.SSNReplacement, cfg.CustomSSNPattern)
+	v.template("ipv4_replacement", cfg.IPV4Replacement, cfg.CustomIPV4Pattern)
+
+	v.locales("phone_locales", cfg.PhoneLocales, PhoneLocales)
+	v.locales("ssn_locales", cfg.SSNLocales, SSNLocales)
+	for _, f := range cfg.LicenseKeyFormats {
+		if !ValidLicenseKeyFormat(f) {
+			v.add("license_key_formats", "invalid format %q, expected groups of X (letter or digit), A (letter) and 9 (digit) separated by -, . or a space, with at least %d of them", f, MinLicenseKeyChars)
+		}
+	}
+
+	v.priority("email_priority", cfg.EmailPriority)
+	v.priority("phone_priority", cfg.PhonePriority)
+	v.priority("credit_card_priority", cfg.CreditCardPriority)
+	v.priority("ssn_priority", cfg.SSNPriority)
+	v.priority("ipv4_priority", cfg.IPV4Priority)
+
+	v.action("email_action", cfg.EmailAction)
+	v.action("phone_action", cfg.PhoneAction)
+	v.action("credit_card_action", cfg.CreditCardAction)
+	v.action("ssn_action", cfg.SSNAction)
+	v.action("ipv4_action", cfg.IPV4Action)
+	v.action("totp_action", cfg.TOTPAction)
+
+	v.severity("email_severity", cfg.EmailSeverity)
+	v.severity("phone_severity", cfg.PhoneSeverity)
+	v.severity("credit_card_severity", cfg.CreditCardSeverity)
+	v.severity("ssn_severity", cfg.SSNSeverity)
+	v.severity("ipv4_severity", cfg.IPV4Severity)
+	v.severity("totp_severity", cfg.TOTPSeverity)
+	v.policies(cfg.Policies)
+
+	if cfg.ReviewThreshold < 0 || cfg.ReviewThreshold > 1 {
+		v.add("review_threshold", "must be between 0 and 1")
+	}
+	v.values("allowlist", cfg.Allowlist)
+	v.values("review_confirmed", cfg.ReviewConfirmed)
+
+	if cfg.AskTimeoutSeconds < MinAskTimeout || cfg.AskTimeoutSeconds > MaxAskTimeout {
+		v.add("ask_timeout_seconds", "must be between %d and %d", MinAskTimeout, MaxAskTimeout)
+	}
+
+	if cfg.MonitoringInterval < MinMonitoringInterval || cfg.MonitoringInterval > MaxMonitoringInterval {
+		v.add("monitoring_interval_ms", "must be between %d and %d", MinMonitoringInterval, MaxMonitoringInterval)
+	}
+
+	if cfg.MaxClipboardBytes < 0 {
+		v.add("max_clipboard_bytes", "must not be negative")
+	}
+	if cfg.LargeContentMode != LargeContentChunked && cfg.LargeContentMode != LargeContentSkip {
+		v.add("large_content_mode", "must be %q or %q", LargeContentChunked, LargeContentSkip)
+	}
+	if cfg.ScanTimeoutMs < MinScanTimeout || cfg.ScanTimeoutMs > MaxScanTimeout {
+		v.add("scan_timeout_ms", "must be between %d and %d", MinScanTimeout, MaxScanTimeout)
+	}
+
+	if cfg.LogMode != LogModeFull && cfg.LogMode != LogModeHash {
+		v.add("log_mode", "must be %q or %q", LogModeFull, LogModeHash)
+	}
+
+	if cfg.FileScanMode != FileScanOff && cfg.FileScanMode != FileScanWarn && cfg.FileScanMode != FileScanBlock {
+		v.add("file_scan_mode", "must be %q, %q or %q", FileScanOff, FileScanWarn, FileScanBlock)
+	}
+	if cfg.FileScanMaxBytes <= 0 {
+		v.add("file_scan_max_bytes", "must be positive")
+	}
+
+	if cfg.PromptSafetyMode != PromptSafetyOff && cfg.PromptSafetyMode != PromptSafetyWarn && cfg.PromptSafetyMode != PromptSafetyBlock {
+		v.add("prompt_safety_mode", "must be %q, %q or %q", PromptSafetyOff, PromptSafetyWarn, PromptSafetyBlock)
+	}
+
+	if cfg.ClassifierMode != ClassifierOff && cfg.ClassifierMode != ClassifierHeuristic && cfg.ClassifierMode != ClassifierHTTP {
+		v.add("classifier_mode", "must be %q, %q or %q", ClassifierOff, ClassifierHeuristic, ClassifierHTTP)
+	}
+	if cfg.ClassifierEndpoint != "" {
+		if u, err := url.Parse(cfg.ClassifierEndpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			v.add("classifier_endpoint", "must be an http or https URL")
+		}
+	} else if cfg.ClassifierMode == ClassifierHTTP {
+		v.add("classifier_endpoint", "must be set to use the http classifier")
+	}
+
+	if cfg.BreakerThreshold < 0 {
+		v.add("breaker_threshold", "must not be negative")
+	}
+	if cfg.BreakerThreshold > 0 && cfg.BreakerWindowMinutes <= 0 {
+		v.add("breaker_window_minutes", "must be positive to enable the circuit breaker")
+	}
+	if cfg.BreakerCooldownMinutes < 0 {
+		v.add("breaker_cooldown_minutes", "must not be negative")
+	}
+	if cfg.BreakerWebhook != "" {
+		if u, err := url.Parse(cfg.BreakerWebhook); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			v.add("breaker_webhook", "must be an http or https URL")
+		}
+	}
+
+	if cfg.KeyboardProtection && !cfg.KeyboardConsent {
+		v.add("keyboard_protection", "requires keyboard_consent, given once the user agreed to key presses being read")
+	}
+
+	for i, host := range cfg.ProxyBypass {
+		if !hostPattern.MatchString(host) {
+			v.add(fmt.Sprintf("proxy_bypass[%d]", i), "must be a lowercase host name or a *. wildcard")
+		}
+	}
+
+	if cfg.UsageEndpoint != "" {
+		if u, err := url.Parse(cfg.UsageEndpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			v.add("usage_endpoint", "must be an http or https URL")
+		}
+	} else if cfg.UsageReporting {
+		v.add("usage_endpoint", "must be set to enable usage reporting")
+	}
+
+	for i, addr := range cfg.ReportRecipients {
+		if _, err := mail.ParseAddress(addr); err != nil || strings.Contains(addr, ",") {
+			v.add(fmt.Sprintf("report_recipients[%d]", i), "must be an e-mail address")
+		}
+	}
+	if cfg.SMTPFrom != "" {
+		if _, err := mail.ParseAddress(cfg.SMTPFrom); err != nil {
+			v.add("smtp_from", "must be an e-mail address")
+		}
+	}
+	if len(cfg.ReportRecipients) > 0 {
+		if cfg.SMTPHost == "" {
+			v.add("smtp_host", "must be set to e-mail reports")
+		}
+		if cfg.SMTPFrom == "" {
+			v.add("smtp_from", "must be set to e-mail reports")
+		}
+	}
+	if cfg.SMTPPort < 0 || cfg.SMTPPort > 65535 {
+		v.add("smtp_port", "must be between 1 and 65535, or 0 for %d", DefaultSMTPPort)
+	}
+	if cfg.SMTPTLS != "" && cfg.SMTPTLS != SMTPStartTLS && cfg.SMTPTLS != SMTPImplicitTLS && cfg.SMTPTLS != SMTPPlain {
+		v.add("smtp_tls", "must be %q, %q or %q", SMTPStartTLS, SMTPImplicitTLS, SMTPPlain)
+	}
+
+	if cfg.Language != "" && !i18n.Supported(cfg.Language) {
+		v.add("language", "must be empty or one of %s", strings.Join(i18n.Languages, ", "))
+	}
+
+	seen := make(map[string]bool, len(cfg.NormalizeDetectors))
+	for _, d := range cfg.NormalizeDetectors {
+		if !containsString(NormalizableDetectors, d) {
+			v.add("normalize_detectors", "unknown detector %q, expected one of %s", d, strings.Join(NormalizableDetectors, ", "))
+		} else if seen[d] {
+			v.add("normalize_detectors", "lists %q more than once", d)
+		}
+		seen[d] = true
+	}
+
+	for i, p := range cfg.StringMatchPatterns {
+		validatePattern(v, fmt.Sprintf("string_match_patterns[%d].", i), p)
+	}
+
+	for i, p := range cfg.DomainPolicies {
+		validateDomainPolicy(v, fmt.Sprintf("domain_policies[%d].", i), p)
+	}
+
+	for i, s := range cfg.Schedules {
+		validateSchedule(v, fmt.Sprintf("schedules[%d].", i), s)
+	}
+
+	return v.err()
+}
+
+func validateSchedule(v *validator, prefix string, s Schedule) {
+	if strings.TrimSpace(s.Name) == "" {
+		v.add(prefix+"name", "must not be empty")
+	}
+	for _, d := range s.Days {
+		if _, ok := weekdays[strings.ToLower(d)]; !ok {
+			v.add(prefix+"days", "unknown day %q, expected one of sun, mon, tue, wed, thu, fri, sat", d)
+			break
+		}
+	}
+
+	start, startErr := parseClock(s.Start)
+	if startErr != nil {
+		v.add(prefix+"start", "%v", startErr)
+	}
+	end, endErr := parseClock(s.End)
+	if endErr != nil {
+		v.add(prefix+"end", "%v", endErr)
+	}
+	if startErr == nil && endErr == nil && start == end {
+		v.add(prefix+"end", "must differ from start")
+	}
+
+	if !ValidProfile(s.Profile) {
+		v.add(prefix+"profile", "must be one of %s, %s, %s", ProfileStandard, ProfileStrict, ProfileOff)
+	}
+}
+
+// ValidatePattern checks a string match pattern before it is saved
+func ValidatePattern(p StringMatchPattern) error {
+	v := &validator{}
+	validatePattern(v, "", p)
+	return v.err()
+}
+
+func validatePattern(v *validator, prefix string, p StringMatchPattern) {
+	valid := len(v.fields)
+	if strings.TrimSpace(p.Name) == "" {
+		v.add(prefix+"name", "must not be empty")
+	}
+	if p.Pattern == "" {
+		v.add(prefix+"pattern", "must not be empty")
+	}
+	if p.Replacement == "" {
+		v.add(prefix+"replacement", "must not be empty")
+	}
+	v.priority(prefix+"priority", p.Priority)
+	v.action(prefix+"action", p.Action)
+	v.severity(prefix+"severity", p.Severity)
+	if len(v.fields) > valid {
+		v.pattern = true
+	}
+}
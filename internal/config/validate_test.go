@@ -0,0 +1,344 @@
+package config
+
+import (
+	"errors"
+	"testing"
+)
+
+// validConfig returns a configuration that passes validation
+func validConfig() Config {
+	return Config{
+		DetectEmails:       true,
+		EmailReplacement:   "[EMAIL]",
+		MonitoringInterval: 500,
+		LargeContentMode:   LargeContentChunked,
+		ScanTimeoutMs:      5000,
+		LogMode:            LogModeFull,
+		FileScanMode:       FileScanOff,
+		FileScanMaxBytes:   1 << 20,
+		PromptSafetyMode:   PromptSafetyOff,
+		ClassifierMode:     ClassifierOff,
+		AskTimeoutSeconds:  30,
+		StringMatchPatterns: []StringMatchPattern{
+			{ID: 1, Name: "company", Pattern: "Acme", Enabled: true, Replacement: "[COMPANY]"},
+		},
+	}
+}
+
+// TestValidate tests field-level configuration validation
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name         string
+		modify       func(*Config)
+		expectFields []string
+	}{
+		{
+			name:   "Valid config",
+			modify: func(c *Config) {},
+		},
+		{
+			name:         "Invalid custom regex",
+			modify:       func(c *Config) { c.CustomEmailPattern = "[unclosed" },
+			expectFields: []string{"custom_email_pattern"},
+		},
+		{
+			name:         "Too complex custom regex",
+			modify:       func(c *Config) { c.CustomPhonePattern = `((a|b|c|d){1,50}x){1,20}` },
+			expectFields: []string{"custom_phone_pattern"},
+		},
+		{
+			name:         "Zero monitoring interval",
+			modify:       func(c *Config) { c.MonitoringInterval = 0 },
+			expectFields: []string{"monitoring_interval_ms"},
+		},
+		{
+			name:         "Monitoring interval too large",
+			modify:       func(c *Config) { c.MonitoringInterval = MaxMonitoringInterval + 1 },
+			expectFields: []string{"monitoring_interval_ms"},
+		},
+		{
+			name:         "Empty replacement for enabled detector",
+			modify:       func(c *Config) { c.EmailReplacement = "" },
+			expectFields: []string{"email_replacement"},
+		},
+		{
+			name: "Negative priorities",
+			modify: func(c *Config) {
+				c.SSNPriority = -1
+				c.StringMatchPatterns[0].Priority = -5
+			},
+			expectFields: []string{"ssn_priority", "string_match_patterns[0].priority"},
+		},
+		{
+			name: "Detector actions",
+			modify: func(c *Config) {
+				c.EmailAction = ActionBlock
+				c.PhoneAction = ActionReplace
+				c.SSNAction = "drop"
+				c.IPV4Action = ActionAsk
+				c.StringMatchPatterns[0].Action = "warn"
+				c.AskTimeoutSeconds = 0
+			},
+			expectFields: []string{"ssn_action", "ask_timeout_seconds", "string_match_patterns[0].action"},
+		},
+		{
+			name: "Severities and policies",
+			modify: func(c *Config) {
+				c.EmailSeverity = SeverityHigh
+				c.PhoneSeverity = "urgent"
+				c.StringMatchPatterns[0].Severity = "none"
+				c.Policies = map[string]map[string]string{
+					ProfileStandard: {SeverityLow: ActionLog, SeverityHigh: ActionBlock},
+					ProfileStrict:   {SeverityCritical: "", "severe": ActionBlock},
+					ProfileOff:      {SeverityLow: ActionLog},
+				}
+			},
+			expectFields: []string{"phone_severity", "policies", "policies.strict.critical", "policies.strict", "string_match_patterns[0].severity"},
+		},
+		{
+			name: "Review",
+			modify: func(c *Config) {
+				c.ReviewThreshold = 1.5
+				c.Allowlist = []string{"10.0.0.1", ""}
+				c.ReviewConfirmed = []string{"4111 1111 1111 1111"}
+			},
+			expectFields: []string{"review_threshold", "allowlist"},
+		},
+		{
+			name: "Usage reporting without endpoint",
+			modify: func(c *Config) {
+				c.UsageReporting = true
+			},
+			expectFields: []string{"usage_endpoint"},
+		},
+		{
+			name: "Usage endpoint not http",
+			modify: func(c *Config) {
+				c.UsageEndpoint = "ftp://stats.example.com/usage"
+			},
+			expectFields: []string{"usage_endpoint"},
+		},
+		{
+			name: "Report e-mail",
+			modify: func(c *Config) {
+				c.ReportRecipients = []string{"Security <security@example.com>", "not an address"}
+				c.SMTPPort = 70000
+				c.SMTPTLS = "ssl"
+			},
+			expectFields: []string{"report_recipients[1]", "smtp_host", "smtp_from", "smtp_port", "smtp_tls"},
+		},
+		{
+			name:         "Empty location replacement",
+			modify:       func(c *Config) { c.DetectLocations = true },
+			expectFields: []string{"location_replacement"},
+		},
+		{
+			name: "License key formats",
+			modify: func(c *Config) {
+				c.LicenseKeyFormats = []string{"XXXXX-XXXXX-99999", "XXXX", "XX--XXXXXX", "XXXX-XXXX-", "ZZZZ-ZZZZ"}
+			},
+			expectFields: []string{"license_key_formats", "license_key_formats", "license_key_formats", "license_key_formats"},
+		},
+		{
+			name:         "Keyboard protection without consent",
+			modify:       func(c *Config) { c.KeyboardProtection = true },
+			expectFields: []string{"keyboard_protection"},
+		},
+		{
+			name:   "Keyboard protection with consent",
+			modify: func(c *Config) { c.KeyboardProtection, c.KeyboardConsent = true, true },
+		},
+		{
+			name:         "Language",
+			modify:       func(c *Config) { c.Language = "fr" },
+			expectFields: []string{"language"},
+		},
+		{
+			name: "Locales",
+			modify: func(c *Config) {
+				c.PhoneLocales = []string{LocaleUK, "atlantis", LocaleIntl}
+				c.SSNLocales = []string{LocaleUS, LocaleIntl}
+			},
+			expectFields: []string{"phone_locales", "ssn_locales"},
+		},
+		{
+			name: "Replacement templates",
+			modify: func(c *Config) {
+				c.EmailReplacement = "[USER]@${domain}"
+				c.CustomPhonePattern = `(?P<area>\d{3})-\d{4}`
+				c.PhoneReplacement = "(${area}) ${1} ${exchange} ${2} $area $$ $3"
+			},
+			expectFields: []string{"phone_replacement", "phone_replacement", "phone_replacement"},
+		},
+		{
+			name: "Valid schedule",
+			modify: func(c *Config) {
+				c.Schedules = []Schedule{{Name: "night", Days: []string{"Mon"}, Start: "22:00", End: "06:00", Profile: ProfileOff}}
+			},
+		},
+		{
+			name: "Invalid schedule",
+			modify: func(c *Config) {
+				c.Schedules = []Schedule{{Name: "bad", Days: []string{"funday"}, Start: "25:00", End: "9am", Profile: "loud"}}
+			},
+			expectFields: []string{"schedules[0].days", "schedules[0].start", "schedules[0].end", "schedules[0].profile"},
+		},
+		{
+			name: "Empty schedule window",
+			modify: func(c *Config) {
+				c.Schedules = []Schedule{{Name: "never", Start: "09:00", End: "09:00", Profile: ProfileStrict}}
+			},
+			expectFields: []string{"schedules[0].end"},
+		},
+		{
+			name: "Invalid large content settings",
+			modify: func(c *Config) {
+				c.MaxClipboardBytes = -1
+				c.LargeContentMode = "truncate"
+				c.ScanTimeoutMs = 0
+			},
+			expectFields: []string{"max_clipboard_bytes", "large_content_mode", "scan_timeout_ms"},
+		},
+		{
+			name:         "Invalid log mode",
+			modify:       func(c *Config) { c.LogMode = "none" },
+			expectFields: []string{"log_mode"},
+		},
+		{
+			name: "Invalid file scan settings",
+			modify: func(c *Config) {
+				c.FileScanMode = "delete"
+				c.FileScanMaxBytes = 0
+			},
+			expectFields: []string{"file_scan_mode", "file_scan_max_bytes"},
+		},
+		{
+			name:         "Unknown or repeated normalized detector",
+			modify:       func(c *Config) { c.NormalizeDetectors = []string{"email", "email", DetectorStringMatch} },
+			expectFields: []string{"normalize_detectors", "normalize_detectors"},
+		},
+		{
+			name:         "Invalid prompt safety mode",
+			modify:       func(c *Config) { c.PromptSafetyMode = "ask" },
+			expectFields: []string{"prompt_safety_mode"},
+		},
+		{
+			name:         "HTTP classifier without endpoint",
+			modify:       func(c *Config) { c.ClassifierMode = ClassifierHTTP },
+			expectFields: []string{"classifier_endpoint"},
+		},
+		{
+			name: "Invalid classifier settings",
+			modify: func(c *Config) {
+				c.ClassifierMode = "model"
+				c.ClassifierEndpoint = "localhost:9000"
+			},
+			expectFields: []string{"classifier_mode", "classifier_endpoint"},
+		},
+		{
+			name: "Invalid circuit breaker settings",
+			modify: func(c *Config) {
+				c.BreakerThreshold = 5
+				c.BreakerCooldownMinutes = -1
+				c.BreakerWebhook = "ftp://example.com/hook"
+			},
+			expectFields: []string{"breaker_window_minutes", "breaker_cooldown_minutes", "breaker_webhook"},
+		},
+		{
+			name:         "Invalid proxy bypass host",
+			modify:       func(c *Config) { c.ProxyBypass = []string{"*.apple.com", "https://example.com"} },
+			expectFields: []string{"proxy_bypass[1]"},
+		},
+		{
+			name:   "Empty replacement for disabled detector",
+			modify: func(c *Config) { c.DetectEmails = false; c.EmailReplacement = "" },
+		},
+		{
+			name: "Invalid string match pattern",
+			modify: func(c *Config) {
+				c.StringMatchPatterns[0].Pattern = ""
+				c.StringMatchPatterns[0].Replacement = ""
+			},
+			expectFields: []string{"string_match_patterns[0].pattern", "string_match_patterns[0].replacement"},
+		},
+		{
+			name: "Multiple errors",
+			modify: func(c *Config) {
+				c.CustomSSNPattern = "(?P<bad"
+				c.MonitoringInterval = -1
+			},
+			expectFields: []string{"custom_ssn_pattern", "monitoring_interval_ms"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := validConfig()
+			tt.modify(&cfg)
+
+			err := Validate(cfg)
+			if len(tt.expectFields) == 0 {
+				if err != nil {
+					t.Errorf("Expected no error, got %v", err)
+				}
+				return
+			}
+
+			var validationErr *ValidationError
+			if !errors.As(err, &validationErr) {
+				t.Fatalf("Expected *ValidationError, got %v", err)
+			}
+
+			if len(validationErr.Fields) != len(tt.expectFields) {
+				t.Fatalf("Expected %d field errors, got %d: %v", len(tt.expectFields), len(validationErr.Fields), validationErr.Fields)
+			}
+			for i, field := range tt.expectFields {
+				if validationErr.Fields[i].Field != field {
+					t.Errorf("Expected field error %d on '%s', got '%s'", i, field, validationErr.Fields[i].Field)
+				}
+			}
+		})
+	}
+}
+
+// TestValidatePattern tests string match pattern validation
+func TestValidatePattern(t *testing.T) {
+	if err := ValidatePattern(StringMatchPattern{Name: "n", Pattern: "p", Replacement: "r"}); err != nil {
+		t.Errorf("Expected valid pattern, got %v", err)
+	}
+
+	err := ValidatePattern(StringMatchPattern{Name: " ", Pattern: "p", Replacement: "r"})
+	var validationErr *ValidationError
+	if !errors.As(err, &validationErr) || validationErr.Fields[0].Field != "name" {
+		t.Errorf("Expected name field error, got %v", err)
+	}
+	if !errors.Is(err, ErrInvalidPattern) {
+		t.Errorf("Expected an invalid pattern error, got %v", err)
+	}
+}
+
+// TestValidate_InvalidPattern tests that only invalid patterns and custom
+// regular expressions match ErrInvalidPattern
+func TestValidate_InvalidPattern(t *testing.T) {
+	cfg := validConfig()
+	cfg.CustomEmailPattern = "(unclosed"
+	if err := Validate(cfg); !errors.Is(err, ErrInvalidPattern) {
+		t.Errorf("Expected an invalid pattern error, got %v", err)
+	}
+
+	cfg = validConfig()
+	cfg.StringMatchPatterns[0].Replacement = ""
+	if err := Validate(cfg); !errors.Is(err, ErrInvalidPattern) {
+		t.Errorf("Expected an invalid pattern error, got %v", err)
+	}
+
+	cfg = validConfig()
+	cfg.MonitoringInterval = 1
+	if err := Validate(cfg); err == nil || errors.Is(err, ErrInvalidPattern) {
+		t.Errorf("Expected a validation error other than an invalid pattern, got %v", err)
+	}
+
+	if err := checkCustomPatternField("custom_nothing"); !errors.Is(err, ErrInvalidPattern) {
+		t.Errorf("Expected an invalid pattern error, got %v", err)
+	}
+}
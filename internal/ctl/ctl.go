@@ -0,0 +1,185 @@
+// Package ctl is the local control channel of the running daemon, used by
+// `prompt-security ctl` so scripts and desktop shortcuts can control it
+// without the HTTP API. It listens on a Unix domain socket in the data
+// directory, readable only by the user; Windows 10 and later support these
+// sockets too. Each connection carries one JSON request and one JSON
+// response, each on a line.
+package ctl
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// SocketFile is the name of the control socket in the data directory
+const SocketFile = "ctl.sock"
+
+// connTimeout bounds handling a single connection
+const connTimeout = 10 * time.Second
+
+// Commands
+const (
+	CommandStatus = "status" // Report the monitor state, returns Status
+	CommandPause  = "pause"  // Pause clipboard monitoring
+	CommandResume = "resume" // Resume clipboard monitoring
+	CommandReload = "reload" // Reload the configuration from the database
+	CommandStats  = "stats"  // Report log statistics, returns Stats
+)
+
+// Commands lists the commands in the order they are documented
+var Commands = []string{CommandStatus, CommandPause, CommandResume, CommandReload, CommandStats}
+
+// Status is the result of the status command
+type Status struct {
+	Paused   bool   `json:"paused"`
+	Profile  string `json:"profile"`            // Active detection profile
+	Schedule string `json:"schedule,omitempty"` // Schedule that selected the profile
+	Health   string `json:"health"`             // ok, backoff, stalled or stopped
+	Lockdown bool   `json:"lockdown"`           // Every detector blocks because the circuit breaker tripped
+	WebUI    string `json:"web_ui"`             // Address of the web UI
+}
+
+// Stats is the result of the stats command
+type Stats struct {
+	Logs          int            `json:"logs"`
+	PendingReview int            `json:"pending_review"`
+	Detections    map[string]int `json:"detections"` // Logged detections per type
+	Leaks         map[string]int `json:"leaks"`      // Logged outbound leaks per type
+	Risks         map[string]int `json:"risks"`      // Classified events per risk label
+	Apps          map[string]int `json:"apps"`       // Logged detections per source application
+}
+
+// Request is sent by the client
+type Request struct {
+	Command string `json:"command"`
+}
+
+// Response is sent by the daemon
+type Response struct {
+	OK    bool            `json:"ok"`
+	Data  json.RawMessage `json:"data,omitempty"`
+	Error string          `json:"error,omitempty"`
+}
+
+// Handler runs a command, returning a result to encode as JSON or nil
+type Handler func() (interface{}, error)
+
+// SocketPath returns the path of the control socket in dir
+func SocketPath(dir string) string {
+	return filepath.Join(dir, SocketFile)
+}
+
+// Server accepts control connections
+type Server struct {
+	listener net.Listener
+	handlers map[string]Handler
+	logger   *slog.Logger
+}
+
+// Listen creates the control socket at path, replacing one left behind by
+// an instance that did not exit cleanly. Only one instance runs at a time,
+// so the socket is never in use.
+func Listen(path string, handlers map[string]Handler) (*Server, error) {
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to remove stale control socket: %v", err)
+	}
+	listener, err := listen(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create control socket: %v", err)
+	}
+	if err := os.Chmod(path, 0600); err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("failed to restrict control socket: %v", err)
+	}
+
+	return &Server{
+		listener: listener,
+		handlers: handlers,
+		logger:   slog.New(slog.NewJSONHandler(os.Stdout, nil)),
+	}, nil
+}
+
+// Serve handles connections until the server is closed (blocking)
+func (s *Server) Serve() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return
+			}
+			s.logger.Error("Failed to accept control connection", "error", err)
+			continue
+		}
+		go s.handle(conn)
+	}
+}
+
+// Close stops accepting connections and removes the socket
+func (s *Server) Close() error {
+	return s.listener.Close()
+}
+
+// handle answers the request on conn
+func (s *Server) handle(conn net.Conn) {
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(connTimeout))
+
+	var req Request
+	if err := json.NewDecoder(bufio.NewReader(conn)).Decode(&req); err != nil {
+		json.NewEncoder(conn).Encode(Response{Error: "invalid request"})
+		return
+	}
+	json.NewEncoder(conn).Encode(s.run(req.Command))
+}
+
+// run runs a command and wraps its result
+func (s *Server) run(command string) Response {
+	handler, ok := s.handlers[command]
+	if !ok {
+		return Response{Error: fmt.Sprintf("unknown command %q", command)}
+	}
+	result, err := handler()
+	if err != nil {
+		return Response{Error: err.Error()}
+	}
+
+	resp := Response{OK: true}
+	if result != nil {
+		data, err := json.Marshal(result)
+		if err != nil {
+			return Response{Error: err.Error()}
+		}
+		resp.Data = data
+	}
+	return resp
+}
+
+// Call sends a command to the daemon listening at path and returns its
+// result, or the error it reported
+func Call(path, command string) (json.RawMessage, error) {
+	conn, err := net.DialTimeout("unix", path, connTimeout)
+	if err != nil {
+		return nil, fmt.Errorf("prompt-security is not running (%v)", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(connTimeout))
+
+	if err := json.NewEncoder(conn).Encode(Request{Command: command}); err != nil {
+		return nil, err
+	}
+	var resp Response
+	if err := json.NewDecoder(conn).Decode(&resp); err != nil {
+		return nil, fmt.Errorf("failed to read response: %v", err)
+	}
+	if !resp.OK {
+		return nil, errors.New(resp.Error)
+	}
+	return resp.Data, nil
+}
@@ -0,0 +1,57 @@
+package ctl
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"sync/atomic"
+	"testing"
+)
+
+// TestServer tests running commands over the control socket
+func TestServer(t *testing.T) {
+	path := SocketPath(t.TempDir())
+	os.WriteFile(path, nil, 0600) // Left behind by a crashed instance
+
+	var paused atomic.Bool
+	server, err := Listen(path, map[string]Handler{
+		CommandStatus: func() (interface{}, error) { return Status{Paused: paused.Load(), Profile: "standard"}, nil },
+		CommandPause:  func() (interface{}, error) { paused.Store(true); return nil, nil },
+		CommandReload: func() (interface{}, error) { return nil, errors.New("database is locked") },
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer server.Close()
+	go server.Serve()
+
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("Expected the socket to be private, got %v (%v)", info.Mode(), err)
+	}
+
+	if data, err := Call(path, CommandPause); err != nil || len(data) != 0 {
+		t.Fatalf("Expected pause to succeed without a result, got %s (%v)", data, err)
+	}
+	data, err := Call(path, CommandStatus)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	var status Status
+	if err := json.Unmarshal(data, &status); err != nil || !status.Paused || status.Profile != "standard" {
+		t.Errorf("Expected a paused standard status, got %s (%v)", data, err)
+	}
+
+	if _, err := Call(path, CommandReload); err == nil || err.Error() != "database is locked" {
+		t.Errorf("Expected the handler error, got %v", err)
+	}
+	if _, err := Call(path, "shutdown"); err == nil {
+		t.Error("Expected an error for an unknown command")
+	}
+}
+
+// TestCallNotRunning tests calling without a running instance
+func TestCallNotRunning(t *testing.T) {
+	if _, err := Call(SocketPath(t.TempDir()), CommandStatus); err == nil {
+		t.Error("Expected an error without a running instance")
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !windows
+
+package ctl
+
+import (
+	"net"
+	"syscall"
+)
+
+// listen creates the socket at path readable only by the user. The umask is
+// set while it is created, so it is never open to others, even briefly.
+func listen(path string) (net.Listener, error) {
+	mask := syscall.Umask(0077)
+	defer syscall.Umask(mask)
+	return net.Listen("unix", path)
+}
@@ -0,0 +1,9 @@
+package ctl
+
+import "net"
+
+// listen creates the socket at path. Windows has no umask; the socket
+// inherits the access rules of the data directory.
+func listen(path string) (net.Listener, error) {
+	return net.Listen("unix", path)
+}
@@ -0,0 +1,200 @@
+package db
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Audit action constants
+const (
+	AuditActionConfigUpdate  = "config.update"
+	AuditActionPatternCreate = "pattern.create"
+	AuditActionPatternUpdate = "pattern.update"
+	AuditActionPatternDelete = "pattern.delete"
+	AuditActionPatternImport = "pattern.import"
+
+	AuditActionDomainPolicyCreate = "domain_policy.create"
+	AuditActionDomainPolicyUpdate = "domain_policy.update"
+	AuditActionDomainPolicyDelete = "domain_policy.delete"
+
+	AuditActionMonitorPause  = "monitor.pause"
+	AuditActionMonitorResume = "monitor.resume"
+	AuditActionConfigReload  = "config.reload"
+)
+
+// ConfigAuditModel represents a config audit entry (GORM model)
+type ConfigAuditModel struct {
+	ID        uint      `gorm:"primaryKey;autoIncrement"`
+	Timestamp time.Time `gorm:"index:idx_config_audit_timestamp,sort:desc;default:CURRENT_TIMESTAMP"`
+	Actor     string    `gorm:"not null"`
+	Action    string    `gorm:"not null"`
+	OldValue  string    `gorm:"not null"` // JSON string
+	NewValue  string    `gorm:"not null"` // JSON string
+	Changes   string    `gorm:"not null"` // JSON string
+	CreatedAt time.Time
+}
+
+func (ConfigAuditModel) TableName() string {
+	return "config_audit"
+}
+
+// AuditChange describes a single changed field
+type AuditChange struct {
+	Field string      `json:"field"`
+	Old   interface{} `json:"old"`
+	New   interface{} `json:"new"`
+}
+
+// AuditEntry represents a config audit entry (API model)
+type AuditEntry struct {
+	ID        int             `json:"id"`
+	Timestamp string          `json:"timestamp"`
+	Actor     string          `json:"actor"`
+	Action    string          `json:"action"`
+	OldValue  json.RawMessage `json:"old"`
+	NewValue  json.RawMessage `json:"new"`
+	Changes   []AuditChange   `json:"changes"`
+}
+
+// AddAudit records a change made by actor. oldValue or newValue may be nil
+// for creations and deletions respectively.
+func AddAudit(actor, action string, oldValue, newValue interface{}) error {
+	return addAudit(db, actor, action, oldValue, newValue)
+}
+
+// addAudit records a change in tx
+func addAudit(tx *gorm.DB, actor, action string, oldValue, newValue interface{}) error {
+	oldJSON, err := json.Marshal(oldValue)
+	if err != nil {
+		return fmt.Errorf("failed to marshal old value: %v", err)
+	}
+	newJSON, err := json.Marshal(newValue)
+	if err != nil {
+		return fmt.Errorf("failed to marshal new value: %v", err)
+	}
+
+	changes, err := diffJSON(oldJSON, newJSON)
+	if err != nil {
+		return fmt.Errorf("failed to diff values: %v", err)
+	}
+	changesJSON, err := json.Marshal(changes)
+	if err != nil {
+		return fmt.Errorf("failed to marshal changes: %v", err)
+	}
+
+	auditModel := ConfigAuditModel{
+		Timestamp: time.Now(),
+		Actor:     actor,
+		Action:    action,
+		OldValue:  string(oldJSON),
+		NewValue:  string(newJSON),
+		Changes:   string(changesJSON),
+	}
+
+	if err := tx.Create(&auditModel).Error; err != nil {
+		return storageError("save audit entry", err)
+	}
+	return nil
+}
+
+// GetAuditWithPagination retrieves audit entries with pagination support
+func GetAuditWithPagination(page, pageSize int) ([]AuditEntry, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 10 // Default page size
+	}
+
+	offset := (page - 1) * pageSize
+
+	var models []ConfigAuditModel
+	if err := db.Order("timestamp DESC").Limit(pageSize).Offset(offset).Find(&models).Error; err != nil {
+		return nil, storageError("query audit entries", err)
+	}
+
+	return convertAuditModels(models)
+}
+
+// GetAuditBetween retrieves the audit entries recorded from start until
+// before end, oldest first
+func GetAuditBetween(start, end time.Time) ([]AuditEntry, error) {
+	var models []ConfigAuditModel
+	if err := db.Where("timestamp >= ? AND timestamp < ?", start, end).Order("timestamp ASC").Find(&models).Error; err != nil {
+		return nil, storageError("query audit entries", err)
+	}
+
+	return convertAuditModels(models)
+}
+
+// convertAuditModels converts GORM models to API models
+func convertAuditModels(models []ConfigAuditModel) ([]AuditEntry, error) {
+	entries := make([]AuditEntry, len(models))
+	for i, m := range models {
+		var changes []AuditChange
+		if err := json.Unmarshal([]byte(m.Changes), &changes); err != nil {
+			return nil, storageError("unmarshal audit changes", err)
+		}
+
+		entries[i] = AuditEntry{
+			ID:        int(m.ID),
+			Timestamp: m.Timestamp.Format(time.RFC3339),
+			Actor:     m.Actor,
+			Action:    m.Action,
+			OldValue:  json.RawMessage(m.OldValue),
+			NewValue:  json.RawMessage(m.NewValue),
+			Changes:   changes,
+		}
+	}
+
+	return entries, nil
+}
+
+// GetAuditCount returns the total number of audit entries
+func GetAuditCount() (int, error) {
+	var count int64
+	if err := db.Model(&ConfigAuditModel{}).Count(&count).Error; err != nil {
+		return 0, storageError("count audit entries", err)
+	}
+	return int(count), nil
+}
+
+// diffJSON compares two JSON objects field by field and returns the fields
+// whose values differ, sorted by field name. A JSON null on either side is
+// treated as an empty object.
+func diffJSON(oldJSON, newJSON []byte) ([]AuditChange, error) {
+	var oldFields, newFields map[string]interface{}
+	if err := json.Unmarshal(oldJSON, &oldFields); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(newJSON, &newFields); err != nil {
+		return nil, err
+	}
+
+	keys := make(map[string]struct{})
+	for k := range oldFields {
+		keys[k] = struct{}{}
+	}
+	for k := range newFields {
+		keys[k] = struct{}{}
+	}
+
+	changes := make([]AuditChange, 0)
+	for k := range keys {
+		oldVal, newVal := oldFields[k], newFields[k]
+		if !reflect.DeepEqual(oldVal, newVal) {
+			changes = append(changes, AuditChange{Field: k, Old: oldVal, New: newVal})
+		}
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Field < changes[j].Field
+	})
+
+	return changes, nil
+}
@@ -0,0 +1,71 @@
+package db
+
+import (
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// BreakerStateModel is the tripped circuit breaker (GORM model). The row
+// exists only while the breaker is tripped, so every process sharing the
+// database locks detection down, and the lockdown survives a restart.
+type BreakerStateModel struct {
+	ID        uint `gorm:"primaryKey"`
+	TrippedAt time.Time
+	Until     *time.Time // End of the cooldown, nil while tripped until reset
+}
+
+func (BreakerStateModel) TableName() string {
+	return "breaker_state"
+}
+
+// BreakerState is the state of the circuit breaker
+type BreakerState struct {
+	Tripped   bool
+	TrippedAt time.Time
+	Until     time.Time // Zero while tripped until reset
+}
+
+// GetBreakerState returns the state of the circuit breaker
+func GetBreakerState() (BreakerState, error) {
+	// Find rather than First, which logs the missing row of every poll
+	var models []BreakerStateModel
+	if err := db.Where("id = ?", 1).Limit(1).Find(&models).Error; err != nil {
+		return BreakerState{}, storageError("query breaker state", err)
+	}
+	if len(models) == 0 {
+		return BreakerState{}, nil
+	}
+	model := models[0]
+
+	state := BreakerState{Tripped: true, TrippedAt: model.TrippedAt}
+	if model.Until != nil {
+		state.Until = *model.Until
+	}
+	return state, nil
+}
+
+// TripBreaker records the circuit breaker as tripped at trippedAt until
+// until, zero to stay tripped until reset. It reports false if the breaker
+// was already tripped, e.g. by another process, which is left unchanged.
+func TripBreaker(trippedAt, until time.Time) (bool, error) {
+	model := BreakerStateModel{ID: 1, TrippedAt: trippedAt}
+	if !until.IsZero() {
+		model.Until = &until
+	}
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&model)
+	if result.Error != nil {
+		return false, storageError("trip breaker", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// ResetBreaker records the circuit breaker as closed. It reports false if
+// the breaker was not tripped, e.g. because another process reset it.
+func ResetBreaker() (bool, error) {
+	result := db.Where("id = ?", 1).Delete(&BreakerStateModel{})
+	if result.Error != nil {
+		return false, storageError("reset breaker", result.Error)
+	}
+	return result.RowsAffected > 0, nil
+}
@@ -1,10 +1,17 @@
 package db
 
 import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"time"
 
 	"github.com/glebarez/sqlite"
@@ -15,24 +22,114 @@ var db *gorm.DB
 
 // ConfigModel represents the configuration table (GORM model)
 type ConfigModel struct {
-	ID                      uint   `gorm:"primaryKey;check:id=1"`
-	DetectEmails            bool   `gorm:"default:true"`
-	DetectPhones            bool   `gorm:"default:true"`
-	DetectCreditCards       bool   `gorm:"default:true"`
-	DetectSSNs              bool   `gorm:"default:true"`
-	DetectIPV4              bool   `gorm:"default:true"`
-	CustomEmailPattern      string `gorm:"default:''"`
-	CustomPhonePattern      string `gorm:"default:''"`
-	CustomCreditCardPattern string `gorm:"default:''"`
-	CustomSSNPattern        string `gorm:"default:''"`
-	CustomIPV4Pattern       string `gorm:"default:''"`
-	EmailReplacement        string `gorm:"default:'security@example.com'"`
-	PhoneReplacement        string `gorm:"default:'+1-555-123-4567'"`
-	CreditCardReplacement   string `gorm:"default:'XXXX-XXXX-XXXX-XXXX'"`
-	SSNReplacement          string `gorm:"default:'XXX-XX-XXXX'"`
-	IPV4Replacement         string `gorm:"default:'0.0.0.0'"`
-	MonitoringIntervalMs    int    `gorm:"default:500"`
-	NotifyOnFilter          bool   `gorm:"default:true"`
+	ID                      uint       `gorm:"primaryKey;check:id=1"`
+	DetectEmails            bool       `gorm:"default:true"`
+	DetectPhones            bool       `gorm:"default:true"`
+	DetectCreditCards       bool       `gorm:"default:true"`
+	DetectSSNs              bool       `gorm:"default:true"`
+	DetectIPV4              bool       `gorm:"default:true"`
+	CustomEmailPattern      string     `gorm:"default:''"`
+	CustomPhonePattern      string     `gorm:"default:''"`
+	CustomCreditCardPattern string     `gorm:"default:''"`
+	CustomSSNPattern        string     `gorm:"default:''"`
+	CustomIPV4Pattern       string     `gorm:"default:''"`
+	EmailReplacement        string     `gorm:"default:'security@example.com'"`
+	PhoneReplacement        string     `gorm:"default:'+1-555-123-4567'"`
+	CreditCardReplacement   string     `gorm:"default:'XXXX-XXXX-XXXX-XXXX'"`
+	SSNReplacement          string     `gorm:"default:'XXX-XX-XXXX'"`
+	IPV4Replacement         string     `gorm:"default:'0.0.0.0'"`
+	PhoneLocales            string     `gorm:"default:'us'"` // Comma-separated phone pattern packs
+	SSNLocales              string     `gorm:"default:'us'"` // Comma-separated national ID pattern packs
+	ChinaPack               bool       `gorm:"default:false"`
+	DetectLocations         bool       `gorm:"default:false"`
+	LocationReplacement     string     `gorm:"default:'[LOCATION]'"`
+	DetectTerraformSecrets  bool       `gorm:"default:false"`
+	TerraformReplacement    string     `gorm:"default:'[SECRET]'"`
+	DetectHTTPAuth          bool       `gorm:"default:false"`
+	HTTPAuthReplacement     string     `gorm:"default:'[CREDENTIAL]'"`
+	DetectCookies           bool       `gorm:"default:false"`
+	CookieReplacement       string     `gorm:"default:'[COOKIE]'"`
+	SanitizeCurl            bool       `gorm:"default:false"`
+	DetectHomePaths         bool       `gorm:"default:false"`
+	HomePathReplacement     string     `gorm:"default:'[USER]'"`
+	DetectShellPrompts      bool       `gorm:"default:false"`
+	ShellPromptReplacement  string     `gorm:"default:'[USER]@[HOST]'"`
+	DetectInfrastructureIDs bool       `gorm:"default:false"`
+	GCPProjectReplacement   string     `gorm:"default:'[GCP_PROJECT]'"`
+	AzureIDReplacement      string     `gorm:"default:'[AZURE_ID]'"`
+	DetectLicenseKeys       bool       `gorm:"default:false"`
+	LicenseKeyReplacement   string     `gorm:"default:'[LICENSE_KEY]'"`
+	LicenseKeyFormats       string     `gorm:"default:''"` // Comma-separated license key formats
+	DetectMACAddresses      bool       `gorm:"default:false"`
+	MACReplacement          string     `gorm:"default:'[MAC]'"`
+	MACAllowLocal           bool       `gorm:"default:true"`
+	DetectTOTPSecrets       bool       `gorm:"default:false"`
+	TOTPReplacement         string     `gorm:"default:'[TOTP_SECRET]'"`
+	DetectRecoveryCodes     bool       `gorm:"default:false"`
+	RecoveryCodeReplacement string     `gorm:"default:'[RECOVERY_CODES]'"`
+	DetectPasswords         bool       `gorm:"default:false"`
+	PasswordReplacement     string     `gorm:"default:'[PASSWORD]'"`
+	EmailPriority           int        `gorm:"default:0"`
+	PhonePriority           int        `gorm:"default:0"`
+	CreditCardPriority      int        `gorm:"default:0"`
+	SSNPriority             int        `gorm:"default:0"`
+	IPV4Priority            int        `gorm:"default:0"`
+	EmailAction             string     `gorm:"default:''"`
+	PhoneAction             string     `gorm:"default:''"`
+	CreditCardAction        string     `gorm:"default:''"`
+	SSNAction               string     `gorm:"default:''"`
+	IPV4Action              string     `gorm:"default:''"`
+	TOTPAction              string     `gorm:"default:''"`
+	BlockMessage            string     `gorm:"default:'[prompt-security] Copy blocked, clipboard contains sensitive data: {types}'"`
+	AskTimeoutSeconds       int        `gorm:"default:30"`
+	EmailSeverity           string     `gorm:"default:''"`
+	PhoneSeverity           string     `gorm:"default:''"`
+	CreditCardSeverity      string     `gorm:"default:''"`
+	SSNSeverity             string     `gorm:"default:''"`
+	IPV4Severity            string     `gorm:"default:''"`
+	TOTPSeverity            string     `gorm:"default:''"`
+	Policies                string     `gorm:"default:'{}'"` // JSON profile -> severity -> action
+	ReviewThreshold         float64    `gorm:"default:0"`
+	Allowlist               string     `gorm:"default:'[]'"` // JSON list
+	ReviewConfirmed         string     `gorm:"default:'[]'"` // JSON list
+	MonitoringIntervalMs    int        `gorm:"default:500"`
+	MonitorClipboard        bool       `gorm:"default:true"`
+	MonitorPrimarySelection bool       `gorm:"default:false"`
+	NotifyOnFilter          bool       `gorm:"default:true"`
+	NormalizeUnicode        bool       `gorm:"default:false"` // Replaced by NormalizeDetectors, see MigrateNormalizeUnicode
+	NormalizeDetectors      string     `gorm:"default:''"`    // Comma-separated detector names
+	MaxClipboardBytes       int        `gorm:"default:1048576"`
+	LargeContentMode        string     `gorm:"default:'chunked'"`
+	ScanTimeoutMs           int        `gorm:"default:5000"`
+	IncrementalScan         bool       `gorm:"default:true"`
+	LogMode                 string     `gorm:"default:'full'"`
+	FileScanMode            string     `gorm:"default:'off'"`
+	FileScanMaxBytes        int        `gorm:"default:1048576"`
+	PromptSafetyMode        string     `gorm:"default:'off'"`
+	DetectSpecialCategories bool       `gorm:"default:false"`
+	ClassifierMode          string     `gorm:"default:'off'"`
+	ClassifierEndpoint      string     `gorm:"default:''"`
+	BreakerThreshold        int        `gorm:"default:0"`
+	BreakerWindowMinutes    int        `gorm:"default:10"`
+	BreakerCooldownMinutes  int        `gorm:"default:30"`
+	BreakerWebhook          string     `gorm:"default:''"`
+	ProxyBypass             string     `gorm:"default:''"` // Comma-separated hosts
+	KeyboardProtection      bool       `gorm:"default:false"`
+	KeyboardConsent         bool       `gorm:"default:false"`
+	KeyboardApps            string     `gorm:"default:'ChatGPT,Claude,Gemini,Copilot,Perplexity,Mistral,DeepSeek'"`
+	UsageReporting          bool       `gorm:"default:false"`
+	UsageEndpoint           string     `gorm:"default:''"`
+	ReportRecipients        string     `gorm:"default:''"` // Comma-separated addresses
+	SMTPHost                string     `gorm:"default:''"`
+	SMTPPort                int        `gorm:"default:587"`
+	SMTPFrom                string     `gorm:"default:''"`
+	SMTPUsername            string     `gorm:"default:''"`
+	SMTPTLS                 string     `gorm:"default:'starttls'"`
+	Language                string     `gorm:"default:''"`
+	ReportSentUntil         string     `gorm:"default:''"` // Last day of the last report e-mailed, managed separately
+	LogSalt                 string     `gorm:"default:''"` // Salt for hashed logs, never exposed
+	SetupCompletedAt        *time.Time // When first-run setup was completed, managed separately
+	DisabledPatterns        string     `gorm:"default:''"` // Comma-separated custom pattern fields
 	CreatedAt               time.Time
 	UpdatedAt               time.Time
 }
@@ -43,13 +140,20 @@ func (ConfigModel) TableName() string {
 
 // StringMatchPatternModel represents a string match pattern (GORM model)
 type StringMatchPatternModel struct {
-	ID          uint   `gorm:"primaryKey;autoIncrement"`
-	Name        string `gorm:"not null"`
-	Pattern     string `gorm:"not null"`
-	Enabled     bool   `gorm:"default:true"`
-	Replacement string `gorm:"not null"`
-	CreatedAt   time.Time
-	UpdatedAt   time.Time
+	ID              uint   `gorm:"primaryKey;autoIncrement"`
+	Name            string `gorm:"not null"`
+	Pattern         string `gorm:"not null"`
+	Enabled         bool   `gorm:"default:true"`
+	Replacement     string `gorm:"not null"`
+	CaseInsensitive bool   `gorm:"default:false"` // Ignore letter case when matching
+	WholeWord       bool   `gorm:"default:false"` // Skip occurrences inside larger words
+	Fuzzy           bool   `gorm:"default:false"` // Also match with one edit, ignoring case and diacritics
+	Normalize       bool   `gorm:"default:false"` // Match against Unicode-normalized text
+	Priority        int    `gorm:"default:0"`     // Conflict priority, 0 uses the default
+	Action          string `gorm:"default:''"`    // replace, block, ask or log; empty uses the policy
+	Severity        string `gorm:"default:''"`    // Severity for the policy, empty for medium
+	CreatedAt       time.Time
+	UpdatedAt       time.Time
 }
 
 func (StringMatchPatternModel) TableName() string {
@@ -62,7 +166,16 @@ type LogEntryModel struct {
 	Timestamp    time.Time `gorm:"index:idx_logs_timestamp,sort:desc;default:CURRENT_TIMESTAMP"`
 	OriginalText string    `gorm:"not null"`
 	FilteredText string    `gorm:"not null"`
-	Detections   string    `gorm:"not null"` // JSON string
+	Detections   string    `gorm:"not null"`         // JSON string
+	Matches      string    `gorm:"default:'[]'"`     // JSON LogMatch list
+	ContentHash  string    `gorm:"index;default:''"` // Identifies repeats of the same event
+	Count        int       `gorm:"default:1"`        // Number of consecutive occurrences
+	Hashed       bool      `gorm:"default:false"`    // Text columns hold salted hashes
+	Kind         string    `gorm:"index;default:'prompt'"`
+	RiskLabel    string    `gorm:"default:''"` // Set by the classifier, empty until classified
+	RiskScore    float64   `gorm:"default:0"`
+	App          string    `gorm:"index;default:''"` // Application the text came from, empty if unknown
+	LastSeen     *time.Time
 	CreatedAt    time.Time
 }
 
@@ -70,6 +183,28 @@ func (LogEntryModel) TableName() string {
 	return "logs"
 }
 
+// MemoryPath is the database path that keeps everything in memory and
+// nothing on disk
+const MemoryPath = ":memory:"
+
+// path is the database file set by SetPath, empty for the default
+var path string
+
+// dataDir is the data directory set by SetDataDir, empty for the default
+var dataDir string
+
+// SetPath sets the database file opened by Initialize, or MemoryPath. An
+// empty path selects config.db in the data directory, see SetDataDir.
+func SetPath(p string) {
+	path = p
+}
+
+// Persistent reports whether the database is kept on disk, rather than in
+// memory with nothing written to disk at all
+func Persistent() bool {
+	return path != MemoryPath
+}
+
 // Initialize initializes the database connection and creates tables if needed
 func Initialize() error {
 	dbPath, err := getDBPath()
@@ -77,16 +212,56 @@ func Initialize() error {
 		return err
 	}
 
+	if dbPath != MemoryPath {
+		// Create the database readable only by the current user, since it
+		// holds upstream keys
+		file, err := os.OpenFile(dbPath, os.O_RDWR|os.O_CREATE, 0600)
+		if err != nil {
+			return storageError("open database", err)
+		}
+		file.Close()
+		if err := os.Chmod(dbPath, 0600); err != nil {
+			return storageError("restrict database", err)
+		}
+	}
+
 	database, err := gorm.Open(sqlite.Open(dbPath), &gorm.Config{})
 	if err != nil {
-		return fmt.Errorf("failed to open database: %v", err)
+		return storageError("open database", err)
+	}
+
+	if dbPath == MemoryPath {
+		// Every connection to :memory: opens a new, empty database
+		sqlDB, err := database.DB()
+		if err != nil {
+			return storageError("open database", err)
+		}
+		sqlDB.SetMaxOpenConns(1)
+
+		// Keep temporary tables and indices of large queries off disk too
+		if err := database.Exec("PRAGMA temp_store = MEMORY").Error; err != nil {
+			return storageError("configure database", err)
+		}
 	}
 
 	db = database
 
+	if err := registerTelemetry(db); err != nil {
+		return fmt.Errorf("failed to register telemetry: %v", err)
+	}
+
+	// Keyboard protection enabled before consent was recorded was enabled
+	// through the consent dialog of the web UI
+	addConsent := db.Migrator().HasTable(&ConfigModel{}) && !db.Migrator().HasColumn(&ConfigModel{}, "KeyboardConsent")
+
 	// Auto migrate tables
-	if err := db.AutoMigrate(&ConfigModel{}, &StringMatchPatternModel{}, &LogEntryModel{}); err != nil {
-		return fmt.Errorf("failed to migrate tables: %v", err)
+	if err := db.AutoMigrate(&ConfigModel{}, &StringMatchPatternModel{}, &LogEntryModel{}, &ConfigAuditModel{}, &APITokenModel{}, &ScheduleModel{}, &ReviewItemModel{}, &UsageCountModel{}, &UpstreamKeyModel{}, &DomainPolicyModel{}, &BreakerStateModel{}); err != nil {
+		return storageError("migrate tables", err)
+	}
+	if addConsent {
+		if err := db.Model(&ConfigModel{}).Where("keyboard_protection = ?", true).Update("keyboard_consent", true).Error; err != nil {
+			return storageError("migrate keyboard consent", err)
+		}
 	}
 
 	// Insert default config if not exists
@@ -95,10 +270,32 @@ func Initialize() error {
 	if count == 0 {
 		defaultConfig := &ConfigModel{ID: 1}
 		if err := db.Create(defaultConfig).Error; err != nil {
-			return fmt.Errorf("failed to create default config: %v", err)
+			return storageError("create default config", err)
 		}
 	}
 
+	if err := initLogSalt(); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// MigrateNormalizeUnicode replaces the former global normalize_unicode
+// setting, if it is on, with normalization for detectors and for every
+// string match pattern
+func MigrateNormalizeUnicode(detectors []string) error {
+	err := db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&ConfigModel{}).Where("normalize_unicode = ?", true).
+			Updates(map[string]interface{}{"normalize_unicode": false, "normalize_detectors": strings.Join(detectors, ",")})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return tx.Model(&StringMatchPatternModel{}).Where("1 = 1").Update("normalize", true).Error
+	})
+	if err != nil {
+		return storageError("migrate unicode normalization", err)
+	}
 	return nil
 }
 
@@ -107,9 +304,11 @@ func Close() error {
 	if db != nil {
 		sqlDB, err := db.DB()
 		if err != nil {
-			return err
+			return storageError("close database", err)
+		}
+		if err := sqlDB.Close(); err != nil {
+			return storageError("close database", err)
 		}
-		return sqlDB.Close()
 	}
 	return nil
 }
@@ -119,18 +318,46 @@ func GetDB() *gorm.DB {
 	return db
 }
 
-// getDBPath returns the path to the SQLite database file
-func getDBPath() (string, error) {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return "", fmt.Errorf("failed to get home directory: %v", err)
+// SetDataDir sets the directory returned by DataDir. An empty dir selects
+// ~/.prompt-security.
+func SetDataDir(dir string) {
+	dataDir = dir
+}
+
+// DataDir returns the directory holding the database and other local
+// state, creating it if needed. It holds secrets such as upstream keys, so
+// it is created accessible only to the current user.
+func DataDir() (string, error) {
+	configDir := dataDir
+	if configDir == "" {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", fmt.Errorf("failed to get home directory: %v", err)
+		}
+		configDir = filepath.Join(homeDir, ".prompt-security")
 	}
 
-	configDir := filepath.Join(homeDir, ".prompt-security")
-	if err := os.MkdirAll(configDir, 0755); err != nil {
-		return "", fmt.Errorf("failed to create config directory: %v", err)
+	if err := os.MkdirAll(configDir, 0700); err != nil {
+		return "", storageError("create config directory", err)
 	}
+	if dataDir == "" {
+		// Restrict the default directory created by earlier versions too
+		if err := os.Chmod(configDir, 0700); err != nil {
+			return "", storageError("restrict config directory", err)
+		}
+	}
+	return configDir, nil
+}
 
+// getDBPath returns the path to the SQLite database file
+func getDBPath() (string, error) {
+	if path != "" {
+		return path, nil
+	}
+	configDir, err := DataDir()
+	if err != nil {
+		return "", err
+	}
 	return filepath.Join(configDir, "config.db"), nil
 }
 
@@ -141,6 +368,27 @@ type StringMatchPattern struct {
 	Pattern     string `json:"pattern"`
 	Enabled     bool   `json:"enabled"`
 	Replacement string `json:"replacement"`
+
+	CaseInsensitive bool `json:"case_insensitive"`
+	WholeWord       bool `json:"whole_word"`
+
+	// Fuzzy also matches the pattern's words misspelt by one inserted,
+	// missing, wrong or swapped character, ignoring case and diacritics
+	Fuzzy bool `json:"fuzzy"`
+
+	// Normalize matches against text with NFKC normalization, homoglyph
+	// folding and zero-width characters stripped, catching obfuscated terms
+	Normalize bool `json:"normalize"`
+
+	// Priority decides which match wins when matches overlap; lower values
+	// win and 0 uses the default
+	Priority int `json:"priority"`
+
+	// Action is "block" to block copies containing the pattern, "ask" to
+	// replace it while offering to restore the original, "log" to only log
+	// it, or "replace" to replace it. Empty uses the policy for Severity.
+	Action   string `json:"action"`
+	Severity string `json:"severity"`
 }
 
 // Config represents the application configuration (API model)
@@ -153,6 +401,14 @@ type Config struct {
 
 	StringMatchPatterns []StringMatchPattern `json:"string_match_patterns"`
 
+	// Schedules switch the detection profile by time window; the first
+	// enabled schedule covering the current time wins
+	Schedules []Schedule `json:"schedules"`
+
+	// DomainPolicies control traffic by destination host in gateway and
+	// proxy modes; managed separately and ignored on update
+	DomainPolicies []DomainPolicy `json:"domain_policies"`
+
 	CustomEmailPattern      string `json:"custom_email_pattern"`
 	CustomPhonePattern      string `json:"custom_phone_pattern"`
 	CustomCreditCardPattern string `json:"custom_credit_card_pattern"`
@@ -165,21 +421,324 @@ type Config struct {
 	SSNReplacement        string `json:"ssn_replacement"`
 	IPV4Replacement       string `json:"ipv4_replacement"`
 
+	// DetectLocations replaces latitude/longitude pairs and plus codes,
+	// such as positions copied from maps and telemetry, with
+	// LocationReplacement
+	DetectLocations     bool   `json:"detect_locations"`
+	LocationReplacement string `json:"location_replacement"`
+
+	// DetectTerraformSecrets replaces the values of secret attributes, such
+	// as access_key, client_secret or password, in Terraform HCL and state
+	// or plan JSON with TerraformReplacement, along with the values of
+	// outputs marked sensitive
+	DetectTerraformSecrets bool   `json:"detect_terraform_secrets"`
+	TerraformReplacement   string `json:"terraform_replacement"`
+
+	// DetectHTTPAuth replaces the credentials of Authorization headers,
+	// curl's -u user:password and URLs' user:password@ with
+	// HTTPAuthReplacement, keeping the header, scheme and user name
+	DetectHTTPAuth      bool   `json:"detect_http_auth"`
+	HTTPAuthReplacement string `json:"http_auth_replacement"`
+
+	// DetectCookies replaces the value of each cookie of Cookie and
+	// Set-Cookie headers, and of well-known session cookies such as
+	// JSESSIONID or __Secure-*, with CookieReplacement, keeping their names
+	DetectCookies     bool   `json:"detect_cookies"`
+	CookieReplacement string `json:"cookie_replacement"`
+
+	// SanitizeCurl parses copied curl commands and replaces the credentials
+	// in their headers, -u, cookies, data and URLs with placeholders such as
+	// REDACTED_TOKEN, keeping the commands runnable
+	SanitizeCurl bool `json:"sanitize_curl"`
+
+	// DetectHomePaths replaces the user name of home directory paths, such
+	// as /Users/jane or C:\Users\jane in stack traces and logs, and its other
+	// occurrences with HomePathReplacement
+	DetectHomePaths     bool   `json:"detect_home_paths"`
+	HomePathReplacement string `json:"home_path_replacement"`
+
+	// DetectShellPrompts replaces the user@host part of shell prompts in
+	// copied terminal output, such as jane@prod-db-01:~$, with
+	// ShellPromptReplacement
+	DetectShellPrompts     bool   `json:"detect_shell_prompts"`
+	ShellPromptReplacement string `json:"shell_prompt_replacement"`
+
+	// DetectInfrastructureIDs replaces cloud infrastructure identifiers
+	// named by their context: GCP project IDs with GCPProjectReplacement and
+	// Azure subscription and tenant GUIDs with AzureIDReplacement
+	DetectInfrastructureIDs bool   `json:"detect_infrastructure_ids"`
+	GCPProjectReplacement   string `json:"gcp_project_replacement"`
+	AzureIDReplacement      string `json:"azure_id_replacement"`
+
+	// DetectLicenseKeys replaces product license keys of LicenseKeyFormats,
+	// or of DefaultLicenseKeyFormats if empty, preceded by a word such as
+	// license or serial, with LicenseKeyReplacement
+	DetectLicenseKeys     bool     `json:"detect_license_keys"`
+	LicenseKeyReplacement string   `json:"license_key_replacement"`
+	LicenseKeyFormats     []string `json:"license_key_formats"`
+
+	// DetectMACAddresses replaces MAC addresses and EUI-64 identifiers, in
+	// colon, hyphen or Cisco dotted notation, with MACReplacement. While
+	// MACAllowLocal is set, locally administered addresses, which devices
+	// randomize for privacy, are left alone.
+	DetectMACAddresses bool   `json:"detect_mac_addresses"`
+	MACReplacement     string `json:"mac_replacement"`
+	MACAllowLocal      bool   `json:"mac_allow_local"`
+
+	// DetectTOTPSecrets replaces otpauth:// URIs and base32 TOTP seeds
+	// preceded by words such as "2FA secret" or "authenticator" with
+	// TOTPReplacement
+	DetectTOTPSecrets bool   `json:"detect_totp_secrets"`
+	TOTPReplacement   string `json:"totp_replacement"`
+
+	// DetectRecoveryCodes replaces each block of backup or recovery codes
+	// following a phrase such as "recovery codes" as a unit with
+	// RecoveryCodeReplacement
+	DetectRecoveryCodes     bool   `json:"detect_recovery_codes"`
+	RecoveryCodeReplacement string `json:"recovery_code_replacement"`
+
+	// DetectPasswords replaces the value following a password keyword in
+	// prose, as in "the password is hunter2" or "Passwort: hunter2", with
+	// PasswordReplacement
+	DetectPasswords     bool   `json:"detect_passwords"`
+	PasswordReplacement string `json:"password_replacement"`
+
+	// PhoneLocales and SSNLocales select the phone number and national ID
+	// formats detected, e.g. "us", "uk" or "intl"; each is ignored while
+	// the detector's custom pattern is set
+	PhoneLocales []string `json:"phone_locales"`
+	SSNLocales   []string `json:"ssn_locales"`
+
+	// ChinaPack turns on the China formats as a group, on top of the
+	// locales: mainland mobile numbers (+86, 0086), resident identity card
+	// numbers checked by their check digit, and Alipay and WeChat Pay
+	// merchant IDs
+	ChinaPack bool `json:"china_pack"`
+
+	// Conflict priorities for the built-in detectors; lower values win when
+	// matches overlap and 0 uses the default
+	EmailPriority      int `json:"email_priority"`
+	PhonePriority      int `json:"phone_priority"`
+	CreditCardPriority int `json:"credit_card_priority"`
+	SSNPriority        int `json:"ssn_priority"`
+	IPV4Priority       int `json:"ipv4_priority"`
+
+	// Actions of the built-in detectors: "replace" substitutes matches,
+	// "block" replaces the whole copy with BlockMessage, where {types} lists
+	// the detected types (an empty message clears the clipboard), "ask"
+	// substitutes matches but keeps the original for AskTimeoutSeconds so the
+	// user can restore it, and "log" only logs them. An empty action is taken
+	// from the policy of the active profile for the detector's severity.
+	EmailAction      string `json:"email_action"`
+	PhoneAction      string `json:"phone_action"`
+	CreditCardAction string `json:"credit_card_action"`
+	SSNAction        string `json:"ssn_action"`
+	IPV4Action       string `json:"ipv4_action"`
+	TOTPAction       string `json:"totp_action"`
+	BlockMessage     string `json:"block_message"`
+
+	AskTimeoutSeconds int `json:"ask_timeout_seconds"`
+
+	// Severities of the built-in detectors (low, medium, high or critical),
+	// empty for the detector's default
+	EmailSeverity      string `json:"email_severity"`
+	PhoneSeverity      string `json:"phone_severity"`
+	CreditCardSeverity string `json:"credit_card_severity"`
+	SSNSeverity        string `json:"ssn_severity"`
+	IPV4Severity       string `json:"ipv4_severity"`
+	TOTPSeverity       string `json:"totp_severity"`
+
+	// Policies map a profile ("standard" or "strict") and a severity to the
+	// action of detectors without their own; unmapped severities are replaced
+	Policies map[string]map[string]string `json:"policies"`
+
+	// Matches scored below ReviewThreshold (0 to 1, 0 disables review) are
+	// queued for review instead of being acted upon, unless their text is in
+	// ReviewConfirmed. Text in Allowlist is never treated as sensitive.
+	// Review decisions add to these lists.
+	ReviewThreshold float64  `json:"review_threshold"`
+	Allowlist       []string `json:"allowlist"`
+	ReviewConfirmed []string `json:"review_confirmed"`
+
 	MonitoringInterval int  `json:"monitoring_interval_ms"`
 	NotifyOnFilter     bool `json:"notify_on_filter"`
+
+	// MonitorClipboard and MonitorPrimarySelection choose what is watched:
+	// the standard clipboard and, on X11/Wayland, the primary selection
+	MonitorClipboard        bool `json:"monitor_clipboard"`
+	MonitorPrimarySelection bool `json:"monitor_primary_selection"`
+
+	// Clipboard content larger than MaxClipboardBytes (0 for no limit) is
+	// scanned in chunks within ScanTimeoutMs, or skipped, per LargeContentMode
+	MaxClipboardBytes int    `json:"max_clipboard_bytes"`
+	LargeContentMode  string `json:"large_content_mode"`
+	ScanTimeoutMs     int    `json:"scan_timeout_ms"`
+
+	// IncrementalScan only scans the added text when new clipboard content
+	// extends the last content that needed no filtering
+	IncrementalScan bool `json:"incremental_scan"`
+
+	// LogMode selects what filter logs store: "full" keeps the original and
+	// filtered text, "hash" only salted hashes of them and the detected types
+	LogMode string `json:"log_mode"`
+
+	// FileScanMode selects what happens when files copied to the clipboard
+	// contain sensitive data: "off", "warn" or "block". At most
+	// FileScanMaxBytes of each file are scanned.
+	FileScanMode     string `json:"file_scan_mode"`
+	FileScanMaxBytes int    `json:"file_scan_max_bytes"`
+
+	// PromptSafetyMode selects what happens to text containing known prompt
+	// injection phrases, role overrides or data exfiltration markers: "off",
+	// "warn" to log them or "block"
+	PromptSafetyMode string `json:"prompt_safety_mode"`
+
+	// DetectSpecialCategories logs mentions of GDPR special categories of
+	// personal data (health, religion, trade union membership) near a
+	// reference to a person, leaving the text as is
+	DetectSpecialCategories bool `json:"detect_special_categories"`
+
+	// ClassifierMode selects how logged events are given a risk label such
+	// as "jailbreak" or "unsafe": "off", "heuristic" for the built-in local
+	// heuristics, or "http" to post the filtered text to ClassifierEndpoint,
+	// e.g. a local model server
+	ClassifierMode     string `json:"classifier_mode"`
+	ClassifierEndpoint string `json:"classifier_endpoint"`
+
+	// BreakerThreshold trips the circuit breaker when more than this many
+	// high or critical severity detections are logged within
+	// BreakerWindowMinutes, e.g. by a script copying a secrets file over and
+	// over. Every detector then blocks for BreakerCooldownMinutes (until
+	// reset if 0) and BreakerWebhook, if set, is notified. 0 disables it.
+	BreakerThreshold       int    `json:"breaker_threshold"`
+	BreakerWindowMinutes   int    `json:"breaker_window_minutes"`
+	BreakerCooldownMinutes int    `json:"breaker_cooldown_minutes"`
+	BreakerWebhook         string `json:"breaker_webhook"`
+
+	// ProxyBypass lists hosts, or *. wildcards, whose HTTPS traffic the
+	// intercepting proxy tunnels without decrypting it, e.g. apps that pin
+	// certificates
+	ProxyBypass []string `json:"proxy_bypass"`
+
+	// KeyboardProtection warns when sensitive data is typed while a window
+	// whose title contains one of KeyboardApps has focus (none if the list
+	// is empty). It reads key presses from the OS, so it can only be enabled
+	// with KeyboardConsent, set once the user agreed to that.
+	KeyboardProtection bool     `json:"keyboard_protection"`
+	KeyboardConsent    bool     `json:"keyboard_consent"`
+	KeyboardApps       []string `json:"keyboard_apps"`
+
+	// UsageReporting sends anonymous daily detection counts per type and
+	// the version to UsageEndpoint. No content, hashes or identifiers are
+	// sent. Off unless enabled.
+	UsageReporting bool   `json:"usage_reporting"`
+	UsageEndpoint  string `json:"usage_endpoint"`
+
+	// ReportRecipients are e-mailed the weekly summary report once each
+	// week is over, through the SMTP server at SMTPHost:SMTPPort from
+	// SMTPFrom. SMTPTLS is "starttls", "tls" for implicit TLS or "none" for
+	// local relays. With SMTPUsername set the server is logged in to with
+	// the password in PROMPT_SECURITY_SMTP_PASSWORD, which is never stored.
+	ReportRecipients []string `json:"report_recipients"`
+	SMTPHost         string   `json:"smtp_host"`
+	SMTPPort         int      `json:"smtp_port"`
+	SMTPFrom         string   `json:"smtp_from"`
+	SMTPUsername     string   `json:"smtp_username"`
+	SMTPTLS          string   `json:"smtp_tls"`
+
+	// Language is the language of API messages and detection type names:
+	// "en", "zh", "ja" or "de". Empty picks one per request from its
+	// Accept-Language header, English if none is supported.
+	Language string `json:"language"`
+
+	// DisabledPatterns lists custom pattern fields (e.g. "custom_email_pattern")
+	// that were switched off for repeatedly exceeding their time budget.
+	// Detection uses the built-in pattern instead until the custom pattern is
+	// changed. Managed by the server; ignored on update.
+	DisabledPatterns []string `json:"disabled_patterns"`
+
+	// NormalizeDetectors lists the built-in detectors, by the names domain
+	// policies use, that match against text with NFKC normalization,
+	// homoglyph folding and zero-width characters stripped. String match
+	// patterns have a normalize setting of their own.
+	NormalizeDetectors []string `json:"normalize_detectors"`
+}
+
+// ConfigVersion returns a value that changes whenever the saved
+// configuration, its string match patterns, schedules or domain policies
+// change, by this process or another one
+func ConfigVersion() (string, error) {
+	return configVersion(db)
+}
+
+// configVersion returns the configuration version seen by tx
+func configVersion(tx *gorm.DB) (string, error) {
+	var parts [7]sql.NullString
+	row := tx.Raw(`SELECT
+		(SELECT updated_at FROM config WHERE id = 1),
+		(SELECT COUNT(*) FROM string_match_patterns),
+		(SELECT MAX(updated_at) FROM string_match_patterns),
+		(SELECT COUNT(*) FROM schedules),
+		(SELECT MAX(id) FROM schedules),
+		(SELECT COUNT(*) FROM domain_policies),
+		(SELECT MAX(updated_at) FROM domain_policies)`).Row()
+	if err := row.Scan(&parts[0], &parts[1], &parts[2], &parts[3], &parts[4], &parts[5], &parts[6]); err != nil {
+		return "", storageError("get config version", err)
+	}
+
+	version := make([]string, len(parts))
+	for i, p := range parts {
+		version[i] = p.String
+	}
+	return strings.Join(version, "|"), nil
 }
 
 // LoadConfig loads the configuration from the database
 func LoadConfig() (Config, error) {
+	return loadConfig(db)
+}
+
+// loadConfig loads the configuration seen by tx
+func loadConfig(tx *gorm.DB) (Config, error) {
 	var configModel ConfigModel
-	if err := db.First(&configModel, 1).Error; err != nil {
-		return Config{}, fmt.Errorf("failed to load config: %v", err)
+	err := tx.First(&configModel, 1).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return Config{}, ErrConfigNotFound
+	}
+	if err != nil {
+		return Config{}, storageError("load config", err)
 	}
 
 	// Load string match patterns
-	patterns, err := LoadStringMatchPatterns()
+	patterns, err := loadStringMatchPatterns(tx)
+	if err != nil {
+		return Config{}, storageError("load string match patterns", err)
+	}
+
+	schedules, err := loadSchedules(tx)
+	if err != nil {
+		return Config{}, storageError("load schedules", err)
+	}
+
+	domainPolicies, err := loadDomainPolicies(tx)
+	if err != nil {
+		return Config{}, err
+	}
+
+	policies := map[string]map[string]string{}
+	if configModel.Policies != "" {
+		if err := json.Unmarshal([]byte(configModel.Policies), &policies); err != nil {
+			return Config{}, storageError("unmarshal policies", err)
+		}
+	}
+
+	allowlist, err := unmarshalList(configModel.Allowlist)
+	if err != nil {
+		return Config{}, storageError("unmarshal allowlist", err)
+	}
+	confirmed, err := unmarshalList(configModel.ReviewConfirmed)
 	if err != nil {
-		return Config{}, fmt.Errorf("failed to load string match patterns: %v", err)
+		return Config{}, storageError("unmarshal confirmed review values", err)
 	}
 
 	cfg := Config{
@@ -198,16 +757,157 @@ func LoadConfig() (Config, error) {
 		CreditCardReplacement:   configModel.CreditCardReplacement,
 		SSNReplacement:          configModel.SSNReplacement,
 		IPV4Replacement:         configModel.IPV4Replacement,
+		PhoneLocales:            splitList(configModel.PhoneLocales),
+		SSNLocales:              splitList(configModel.SSNLocales),
+		ChinaPack:               configModel.ChinaPack,
+		DetectLocations:         configModel.DetectLocations,
+		LocationReplacement:     configModel.LocationReplacement,
+		DetectTerraformSecrets:  configModel.DetectTerraformSecrets,
+		TerraformReplacement:    configModel.TerraformReplacement,
+		DetectHTTPAuth:          configModel.DetectHTTPAuth,
+		HTTPAuthReplacement:     configModel.HTTPAuthReplacement,
+		DetectCookies:           configModel.DetectCookies,
+		CookieReplacement:       configModel.CookieReplacement,
+		SanitizeCurl:            configModel.SanitizeCurl,
+		DetectHomePaths:         configModel.DetectHomePaths,
+		HomePathReplacement:     configModel.HomePathReplacement,
+		DetectShellPrompts:      configModel.DetectShellPrompts,
+		ShellPromptReplacement:  configModel.ShellPromptReplacement,
+		DetectInfrastructureIDs: configModel.DetectInfrastructureIDs,
+		GCPProjectReplacement:   configModel.GCPProjectReplacement,
+		AzureIDReplacement:      configModel.AzureIDReplacement,
+		DetectLicenseKeys:       configModel.DetectLicenseKeys,
+		LicenseKeyReplacement:   configModel.LicenseKeyReplacement,
+		LicenseKeyFormats:       splitList(configModel.LicenseKeyFormats),
+		DetectMACAddresses:      configModel.DetectMACAddresses,
+		MACReplacement:          configModel.MACReplacement,
+		MACAllowLocal:           configModel.MACAllowLocal,
+		DetectTOTPSecrets:       configModel.DetectTOTPSecrets,
+		TOTPReplacement:         configModel.TOTPReplacement,
+		DetectRecoveryCodes:     configModel.DetectRecoveryCodes,
+		RecoveryCodeReplacement: configModel.RecoveryCodeReplacement,
+		DetectPasswords:         configModel.DetectPasswords,
+		PasswordReplacement:     configModel.PasswordReplacement,
+		EmailPriority:           configModel.EmailPriority,
+		PhonePriority:           configModel.PhonePriority,
+		CreditCardPriority:      configModel.CreditCardPriority,
+		SSNPriority:             configModel.SSNPriority,
+		IPV4Priority:            configModel.IPV4Priority,
+		EmailAction:             configModel.EmailAction,
+		PhoneAction:             configModel.PhoneAction,
+		CreditCardAction:        configModel.CreditCardAction,
+		SSNAction:               configModel.SSNAction,
+		IPV4Action:              configModel.IPV4Action,
+		TOTPAction:              configModel.TOTPAction,
+		BlockMessage:            configModel.BlockMessage,
+		AskTimeoutSeconds:       configModel.AskTimeoutSeconds,
+		EmailSeverity:           configModel.EmailSeverity,
+		PhoneSeverity:           configModel.PhoneSeverity,
+		CreditCardSeverity:      configModel.CreditCardSeverity,
+		SSNSeverity:             configModel.SSNSeverity,
+		IPV4Severity:            configModel.IPV4Severity,
+		TOTPSeverity:            configModel.TOTPSeverity,
+		Policies:                policies,
+		ReviewThreshold:         configModel.ReviewThreshold,
+		Allowlist:               allowlist,
+		ReviewConfirmed:         confirmed,
 		MonitoringInterval:      configModel.MonitoringIntervalMs,
+		MonitorClipboard:        configModel.MonitorClipboard,
+		MonitorPrimarySelection: configModel.MonitorPrimarySelection,
 		NotifyOnFilter:          configModel.NotifyOnFilter,
+		NormalizeDetectors:      splitList(configModel.NormalizeDetectors),
+		MaxClipboardBytes:       configModel.MaxClipboardBytes,
+		LargeContentMode:        configModel.LargeContentMode,
+		ScanTimeoutMs:           configModel.ScanTimeoutMs,
+		IncrementalScan:         configModel.IncrementalScan,
+		LogMode:                 configModel.LogMode,
+		FileScanMode:            configModel.FileScanMode,
+		PromptSafetyMode:        configModel.PromptSafetyMode,
+		DetectSpecialCategories: configModel.DetectSpecialCategories,
+		ClassifierMode:          configModel.ClassifierMode,
+		ClassifierEndpoint:      configModel.ClassifierEndpoint,
+		BreakerThreshold:        configModel.BreakerThreshold,
+		BreakerWindowMinutes:    configModel.BreakerWindowMinutes,
+		BreakerCooldownMinutes:  configModel.BreakerCooldownMinutes,
+		BreakerWebhook:          configModel.BreakerWebhook,
+		ProxyBypass:             splitList(configModel.ProxyBypass),
+		FileScanMaxBytes:        configModel.FileScanMaxBytes,
+		KeyboardProtection:      configModel.KeyboardProtection,
+		KeyboardConsent:         configModel.KeyboardConsent,
+		KeyboardApps:            splitList(configModel.KeyboardApps),
+		UsageReporting:          configModel.UsageReporting,
+		UsageEndpoint:           configModel.UsageEndpoint,
+		ReportRecipients:        splitList(configModel.ReportRecipients),
+		SMTPHost:                configModel.SMTPHost,
+		SMTPPort:                configModel.SMTPPort,
+		SMTPFrom:                configModel.SMTPFrom,
+		SMTPUsername:            configModel.SMTPUsername,
+		SMTPTLS:                 configModel.SMTPTLS,
+		Language:                configModel.Language,
+		DisabledPatterns:        splitList(configModel.DisabledPatterns),
 		StringMatchPatterns:     patterns,
+		Schedules:               schedules,
+		DomainPolicies:          domainPolicies,
 	}
 
 	return cfg, nil
 }
 
-// SaveConfig saves the configuration to the database
+// SaveConfig saves the configuration and its schedules to the database.
+// String match patterns are managed separately.
 func SaveConfig(cfg Config) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		return saveConfig(tx, cfg)
+	})
+}
+
+// SaveConfigAudited saves the configuration like SaveConfig and records its
+// change from previous, attributed to actor, in the same transaction, so
+// neither is kept without the other. It returns the configuration as saved
+// and its version, see ConfigVersion.
+func SaveConfigAudited(cfg Config, actor string, previous Config) (Config, string, error) {
+	var saved Config
+	var version string
+	err := db.Transaction(func(tx *gorm.DB) error {
+		if err := saveConfig(tx, cfg); err != nil {
+			return err
+		}
+		var err error
+		if version, err = configVersion(tx); err != nil {
+			return err
+		}
+		if saved, err = loadConfig(tx); err != nil {
+			return err
+		}
+		if err := addAudit(tx, actor, AuditActionConfigUpdate, previous, saved); err != nil {
+			return fmt.Errorf("failed to record audit entry: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return Config{}, "", err
+	}
+	return saved, version, nil
+}
+
+// saveConfig saves the configuration and its schedules in tx
+func saveConfig(tx *gorm.DB, cfg Config) error {
+	if cfg.Policies == nil {
+		cfg.Policies = map[string]map[string]string{}
+	}
+	policies, err := json.Marshal(cfg.Policies)
+	if err != nil {
+		return fmt.Errorf("failed to marshal policies: %v", err)
+	}
+	allowlist, err := marshalList(cfg.Allowlist)
+	if err != nil {
+		return fmt.Errorf("failed to marshal allowlist: %v", err)
+	}
+	confirmed, err := marshalList(cfg.ReviewConfirmed)
+	if err != nil {
+		return fmt.Errorf("failed to marshal confirmed review values: %v", err)
+	}
+
 	configModel := ConfigModel{
 		ID:                      1,
 		DetectEmails:            cfg.DetectEmails,
@@ -225,50 +925,234 @@ func SaveConfig(cfg Config) error {
 		CreditCardReplacement:   cfg.CreditCardReplacement,
 		SSNReplacement:          cfg.SSNReplacement,
 		IPV4Replacement:         cfg.IPV4Replacement,
+		PhoneLocales:            strings.Join(cfg.PhoneLocales, ","),
+		SSNLocales:              strings.Join(cfg.SSNLocales, ","),
+		ChinaPack:               cfg.ChinaPack,
+		DetectLocations:         cfg.DetectLocations,
+		LocationReplacement:     cfg.LocationReplacement,
+		DetectTerraformSecrets:  cfg.DetectTerraformSecrets,
+		TerraformReplacement:    cfg.TerraformReplacement,
+		DetectHTTPAuth:          cfg.DetectHTTPAuth,
+		HTTPAuthReplacement:     cfg.HTTPAuthReplacement,
+		DetectCookies:           cfg.DetectCookies,
+		CookieReplacement:       cfg.CookieReplacement,
+		SanitizeCurl:            cfg.SanitizeCurl,
+		DetectHomePaths:         cfg.DetectHomePaths,
+		HomePathReplacement:     cfg.HomePathReplacement,
+		DetectShellPrompts:      cfg.DetectShellPrompts,
+		ShellPromptReplacement:  cfg.ShellPromptReplacement,
+		DetectInfrastructureIDs: cfg.DetectInfrastructureIDs,
+		GCPProjectReplacement:   cfg.GCPProjectReplacement,
+		AzureIDReplacement:      cfg.AzureIDReplacement,
+		DetectLicenseKeys:       cfg.DetectLicenseKeys,
+		LicenseKeyReplacement:   cfg.LicenseKeyReplacement,
+		LicenseKeyFormats:       strings.Join(cfg.LicenseKeyFormats, ","),
+		DetectMACAddresses:      cfg.DetectMACAddresses,
+		MACReplacement:          cfg.MACReplacement,
+		MACAllowLocal:           cfg.MACAllowLocal,
+		DetectTOTPSecrets:       cfg.DetectTOTPSecrets,
+		TOTPReplacement:         cfg.TOTPReplacement,
+		DetectRecoveryCodes:     cfg.DetectRecoveryCodes,
+		RecoveryCodeReplacement: cfg.RecoveryCodeReplacement,
+		DetectPasswords:         cfg.DetectPasswords,
+		PasswordReplacement:     cfg.PasswordReplacement,
+		EmailPriority:           cfg.EmailPriority,
+		PhonePriority:           cfg.PhonePriority,
+		CreditCardPriority:      cfg.CreditCardPriority,
+		SSNPriority:             cfg.SSNPriority,
+		IPV4Priority:            cfg.IPV4Priority,
+		EmailAction:             cfg.EmailAction,
+		PhoneAction:             cfg.PhoneAction,
+		CreditCardAction:        cfg.CreditCardAction,
+		SSNAction:               cfg.SSNAction,
+		IPV4Action:              cfg.IPV4Action,
+		TOTPAction:              cfg.TOTPAction,
+		BlockMessage:            cfg.BlockMessage,
+		AskTimeoutSeconds:       cfg.AskTimeoutSeconds,
+		EmailSeverity:           cfg.EmailSeverity,
+		PhoneSeverity:           cfg.PhoneSeverity,
+		CreditCardSeverity:      cfg.CreditCardSeverity,
+		SSNSeverity:             cfg.SSNSeverity,
+		IPV4Severity:            cfg.IPV4Severity,
+		TOTPSeverity:            cfg.TOTPSeverity,
+		Policies:                string(policies),
+		ReviewThreshold:         cfg.ReviewThreshold,
+		Allowlist:               allowlist,
+		ReviewConfirmed:         confirmed,
 		MonitoringIntervalMs:    cfg.MonitoringInterval,
+		MonitorClipboard:        cfg.MonitorClipboard,
+		MonitorPrimarySelection: cfg.MonitorPrimarySelection,
 		NotifyOnFilter:          cfg.NotifyOnFilter,
+		NormalizeDetectors:      strings.Join(cfg.NormalizeDetectors, ","),
+		MaxClipboardBytes:       cfg.MaxClipboardBytes,
+		LargeContentMode:        cfg.LargeContentMode,
+		ScanTimeoutMs:           cfg.ScanTimeoutMs,
+		IncrementalScan:         cfg.IncrementalScan,
+		LogMode:                 cfg.LogMode,
+		FileScanMode:            cfg.FileScanMode,
+		PromptSafetyMode:        cfg.PromptSafetyMode,
+		DetectSpecialCategories: cfg.DetectSpecialCategories,
+		ClassifierMode:          cfg.ClassifierMode,
+		ClassifierEndpoint:      cfg.ClassifierEndpoint,
+		BreakerThreshold:        cfg.BreakerThreshold,
+		BreakerWindowMinutes:    cfg.BreakerWindowMinutes,
+		BreakerCooldownMinutes:  cfg.BreakerCooldownMinutes,
+		BreakerWebhook:          cfg.BreakerWebhook,
+		ProxyBypass:             strings.Join(cfg.ProxyBypass, ","),
+		FileScanMaxBytes:        cfg.FileScanMaxBytes,
+		KeyboardProtection:      cfg.KeyboardProtection,
+		KeyboardConsent:         cfg.KeyboardConsent,
+		KeyboardApps:            strings.Join(cfg.KeyboardApps, ","),
+		UsageReporting:          cfg.UsageReporting,
+		UsageEndpoint:           cfg.UsageEndpoint,
+		ReportRecipients:        strings.Join(cfg.ReportRecipients, ","),
+		SMTPHost:                cfg.SMTPHost,
+		SMTPPort:                cfg.SMTPPort,
+		SMTPFrom:                cfg.SMTPFrom,
+		SMTPUsername:            cfg.SMTPUsername,
+		SMTPTLS:                 cfg.SMTPTLS,
+		Language:                cfg.Language,
+		DisabledPatterns:        strings.Join(cfg.DisabledPatterns, ","),
 	}
 
-	return db.Save(&configModel).Error
+	// The log salt is generated once and never changed through the API,
+	// setup completion is recorded by CompleteSetup and sent reports by
+	// SetReportSentUntil
+	if err := tx.Omit("LogSalt", "SetupCompletedAt", "ReportSentUntil").Save(&configModel).Error; err != nil {
+		return storageError("save config", err)
+	}
+	return replaceSchedules(tx, cfg.Schedules)
+}
+
+// unmarshalList decodes a JSON list column into a non-nil slice
+func unmarshalList(s string) ([]string, error) {
+	list := []string{}
+	if s == "" {
+		return list, nil
+	}
+	if err := json.Unmarshal([]byte(s), &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// marshalList encodes a list for a JSON list column, nil as []
+func marshalList(list []string) (string, error) {
+	if list == nil {
+		list = []string{}
+	}
+	b, err := json.Marshal(list)
+	return string(b), err
+}
+
+// splitList splits a comma-separated column into a non-nil slice
+func splitList(s string) []string {
+	if s == "" {
+		return []string{}
+	}
+	return strings.Split(s, ",")
 }
 
 // LoadStringMatchPatterns loads all string match patterns from the database
 func LoadStringMatchPatterns() ([]StringMatchPattern, error) {
+	return loadStringMatchPatterns(db)
+}
+
+// loadStringMatchPatterns loads the string match patterns seen by tx
+func loadStringMatchPatterns(tx *gorm.DB) ([]StringMatchPattern, error) {
 	var models []StringMatchPatternModel
-	if err := db.Order("id").Find(&models).Error; err != nil {
-		return nil, fmt.Errorf("failed to query string match patterns: %v", err)
+	if err := tx.Order("id").Find(&models).Error; err != nil {
+		return nil, storageError("query string match patterns", err)
 	}
 
 	patterns := make([]StringMatchPattern, len(models))
 	for i, m := range models {
 		patterns[i] = StringMatchPattern{
-			ID:          int(m.ID),
-			Name:        m.Name,
-			Pattern:     m.Pattern,
-			Enabled:     m.Enabled,
-			Replacement: m.Replacement,
+			ID:              int(m.ID),
+			Name:            m.Name,
+			Pattern:         m.Pattern,
+			Enabled:         m.Enabled,
+			Replacement:     m.Replacement,
+			CaseInsensitive: m.CaseInsensitive,
+			WholeWord:       m.WholeWord,
+			Fuzzy:           m.Fuzzy,
+			Normalize:       m.Normalize,
+			Priority:        m.Priority,
+			Action:          m.Action,
+			Severity:        m.Severity,
 		}
 	}
 
 	return patterns, nil
 }
 
-// SaveStringMatchPattern saves or updates a string match pattern
-func SaveStringMatchPattern(p StringMatchPattern) error {
+// SaveStringMatchPattern saves or updates a string match pattern and returns
+// the stored pattern (with its assigned ID for new patterns)
+func SaveStringMatchPattern(p StringMatchPattern) (StringMatchPattern, error) {
 	model := StringMatchPatternModel{
-		ID:          uint(p.ID),
-		Name:        p.Name,
-		Pattern:     p.Pattern,
-		Enabled:     p.Enabled,
-		Replacement: p.Replacement,
+		ID:              uint(p.ID),
+		Name:            p.Name,
+		Pattern:         p.Pattern,
+		Enabled:         p.Enabled,
+		Replacement:     p.Replacement,
+		CaseInsensitive: p.CaseInsensitive,
+		WholeWord:       p.WholeWord,
+		Fuzzy:           p.Fuzzy,
+		Normalize:       p.Normalize,
+		Priority:        p.Priority,
+		Action:          p.Action,
+		Severity:        p.Severity,
+	}
+
+	if err := db.Save(&model).Error; err != nil {
+		return StringMatchPattern{}, storageError("save string match pattern", err)
+	}
+
+	p.ID = int(model.ID)
+	return p, nil
+}
+
+// CreateStringMatchPatterns creates string match patterns in one
+// transaction and returns them with their assigned IDs
+func CreateStringMatchPatterns(patterns []StringMatchPattern) ([]StringMatchPattern, error) {
+	models := make([]StringMatchPatternModel, len(patterns))
+	for i, p := range patterns {
+		models[i] = StringMatchPatternModel{
+			Name:            p.Name,
+			Pattern:         p.Pattern,
+			Enabled:         p.Enabled,
+			Replacement:     p.Replacement,
+			CaseInsensitive: p.CaseInsensitive,
+			WholeWord:       p.WholeWord,
+			Fuzzy:           p.Fuzzy,
+			Normalize:       p.Normalize,
+			Priority:        p.Priority,
+			Action:          p.Action,
+			Severity:        p.Severity,
+		}
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		return tx.CreateInBatches(&models, 500).Error
+	})
+	if err != nil {
+		return nil, storageError("create string match patterns", err)
 	}
 
-	return db.Save(&model).Error
+	created := make([]StringMatchPattern, len(patterns))
+	for i, p := range patterns {
+		p.ID = int(models[i].ID)
+		created[i] = p
+	}
+	return created, nil
 }
 
 // DeleteStringMatchPattern deletes a string match pattern by ID
 func DeleteStringMatchPattern(id int) error {
-	return db.Delete(&StringMatchPatternModel{}, id).Error
+	if err := db.Delete(&StringMatchPatternModel{}, id).Error; err != nil {
+		return storageError("delete string match pattern", err)
+	}
+	return nil
 }
 
 // MarshalConfig converts Config to JSON (for compatibility)
@@ -283,6 +1167,9 @@ func UnmarshalConfig(data []byte) (Config, error) {
 	return cfg, err
 }
 
+// ErrLogNotFound is returned when a log entry ID does not exist
+var ErrLogNotFound = errors.New("log entry not found")
+
 // LogEntry represents a filter log entry (API model)
 type LogEntry struct {
 	ID           int      `json:"id"`
@@ -290,23 +1177,185 @@ type LogEntry struct {
 	OriginalText string   `json:"original"`
 	FilteredText string   `json:"filtered"`
 	Detections   []string `json:"detections"`
+	Count        int      `json:"count"`      // Consecutive occurrences of this event
+	LastSeen     string   `json:"last_seen"`  // Time of the latest occurrence
+	Hashed       bool     `json:"hashed"`     // Original and filtered hold salted hashes
+	Kind         string   `json:"kind"`       // prompt or outbound_leak
+	RiskLabel    string   `json:"risk_label"` // Label given by the classifier, empty if unclassified
+	RiskScore    float64  `json:"risk_score"` // Risk from 0 to 1
+	App          string   `json:"app"`        // Application the text came from, empty if unknown
+}
+
+// LogMatch locates a single replacement in a log entry's texts by byte offsets
+type LogMatch struct {
+	Type          string `json:"type"`
+	Start         int    `json:"start"`          // Offset in the original text
+	End           int    `json:"end"`            // End offset in the original text
+	FilteredStart int    `json:"filtered_start"` // Offset of the replacement in the filtered text
+	FilteredEnd   int    `json:"filtered_end"`   // End offset of the replacement in the filtered text
+}
+
+// LogDetail is a log entry with the location of every replacement
+type LogDetail struct {
+	LogEntry
+	Matches []LogMatch `json:"matches"`
+}
+
+// Log kinds
+const (
+	// LogKindPrompt is sensitive data found in text on its way to a model
+	LogKindPrompt = "prompt"
+
+	// LogKindLeak is sensitive data found in a model's response, such as a
+	// secret echoed back or passed to a tool
+	LogKindLeak = "outbound_leak"
+)
+
+// AddLog adds a new log entry of a kind to the database and returns its ID.
+// app is the application the text came from, empty if unknown. An event
+// identical to the most recent entry is counted on that entry instead.
+func AddLog(kind, app, originalText, filteredText string, matches []LogMatch) (int, error) {
+	return addLog(kind, app, originalText, filteredText, matches, false)
 }
 
-// AddLog adds a new log entry to the database
-func AddLog(originalText, filteredText string, detections []string) error {
+// AddHashedLog is like AddLog but stores salted hashes of the original and
+// filtered text instead of the text itself
+func AddHashedLog(kind, app, originalText, filteredText string, matches []LogMatch) (int, error) {
+	return addLog(kind, app, hashLogText(originalText), hashLogText(filteredText), matches, true)
+}
+
+// addLog stores a log entry, or counts a repeat of the most recent one
+func addLog(kind, app, originalText, filteredText string, matches []LogMatch, hashed bool) (int, error) {
+	detections := make([]string, len(matches))
+	for i, m := range matches {
+		detections[i] = m.Type
+	}
 	detectionsJSON, err := json.Marshal(detections)
 	if err != nil {
-		return fmt.Errorf("failed to marshal detections: %v", err)
+		return 0, fmt.Errorf("failed to marshal detections: %v", err)
+	}
+	matchesJSON, err := json.Marshal(matches)
+	if err != nil {
+		return 0, fmt.Errorf("failed to marshal matches: %v", err)
 	}
 
-	logModel := LogEntryModel{
-		Timestamp:    time.Now(),
-		OriginalText: originalText,
-		FilteredText: filteredText,
-		Detections:   string(detectionsJSON),
+	now := time.Now()
+	hash := logHash(kind, app, originalText, filteredText, string(matchesJSON))
+
+	var id uint
+	err = db.Transaction(func(tx *gorm.DB) error {
+		var latest LogEntryModel
+		err := tx.Order("id DESC").Limit(1).Find(&latest).Error
+		if err != nil {
+			return storageError("query latest log", err)
+		}
+		if latest.ID != 0 && latest.ContentHash == hash {
+			id = latest.ID
+			err := tx.Model(&latest).Updates(map[string]interface{}{
+				"count":     gorm.Expr("count + 1"),
+				"last_seen": now,
+			}).Error
+			if err != nil {
+				return storageError("update log count", err)
+			}
+			return nil
+		}
+
+		logModel := LogEntryModel{
+			Timestamp:    now,
+			OriginalText: originalText,
+			FilteredText: filteredText,
+			Detections:   string(detectionsJSON),
+			Matches:      string(matchesJSON),
+			ContentHash:  hash,
+			Count:        1,
+			LastSeen:     &now,
+			Hashed:       hashed,
+			Kind:         kind,
+			App:          app,
+		}
+		if err := tx.Create(&logModel).Error; err != nil {
+			return storageError("add log", err)
+		}
+		id = logModel.ID
+		return nil
+	})
+	return int(id), err
+}
+
+// SetLogRisk records the risk label and score given to a log entry by the
+// classifier
+func SetLogRisk(id int, label string, score float64) error {
+	err := db.Model(&LogEntryModel{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"risk_label": label,
+		"risk_score": score,
+	}).Error
+	if err != nil {
+		return storageError("update log risk", err)
+	}
+	return nil
+}
+
+// GetRiskCounts returns the number of classified log events per risk label,
+// counting every occurrence of repeated log entries
+func GetRiskCounts() (map[string]int, error) {
+	var models []LogEntryModel
+	if err := db.Select("risk_label", "count").Where("risk_label != ''").Find(&models).Error; err != nil {
+		return nil, storageError("query logs", err)
+	}
+
+	counts := make(map[string]int)
+	for _, m := range models {
+		counts[m.RiskLabel] += max(m.Count, 1)
+	}
+	return counts, nil
+}
+
+// logSalt keys the hashes stored by AddHashedLog. It is generated once per
+// database so hashes cannot be compared across installations.
+var logSalt []byte
+
+// initLogSalt loads the log salt, generating it on first use
+func initLogSalt() error {
+	var configModel ConfigModel
+	if err := db.Select("log_salt").First(&configModel, 1).Error; err != nil {
+		return storageError("load log salt", err)
+	}
+
+	if configModel.LogSalt == "" {
+		salt := make([]byte, 32)
+		if _, err := rand.Read(salt); err != nil {
+			return fmt.Errorf("failed to generate log salt: %v", err)
+		}
+		configModel.LogSalt = hex.EncodeToString(salt)
+		if err := db.Model(&ConfigModel{}).Where("id = ?", 1).Update("log_salt", configModel.LogSalt).Error; err != nil {
+			return storageError("save log salt", err)
+		}
+	}
+
+	salt, err := hex.DecodeString(configModel.LogSalt)
+	if err != nil {
+		return storageError("decode log salt", err)
 	}
+	logSalt = salt
+	return nil
+}
 
-	return db.Create(&logModel).Error
+// hashLogText returns the salted hash of text as stored in hashed logs
+func hashLogText(text string) string {
+	mac := hmac.New(sha256.New, logSalt)
+	mac.Write([]byte(text))
+	return "hmac-sha256:" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// logHash identifies a log event by its content
+func logHash(parts ...string) string {
+	h := sha256.New()
+	for _, p := range parts {
+		h.Write([]byte(p))
+		h.Write([]byte{0})
+	}
+	return hex.EncodeToString(h.Sum(nil))
 }
 
 // GetLogs retrieves logs from the database with optional limit
@@ -317,7 +1366,7 @@ func GetLogs(limit int) ([]LogEntry, error) {
 
 	var models []LogEntryModel
 	if err := db.Order("timestamp DESC").Limit(limit).Find(&models).Error; err != nil {
-		return nil, fmt.Errorf("failed to query logs: %v", err)
+		return nil, storageError("query logs", err)
 	}
 
 	return convertLogModelsToEntries(models)
@@ -336,19 +1385,52 @@ func GetLogsWithPagination(page, pageSize int) ([]LogEntry, error) {
 
 	var models []LogEntryModel
 	if err := db.Order("timestamp DESC").Limit(pageSize).Offset(offset).Find(&models).Error; err != nil {
-		return nil, fmt.Errorf("failed to query logs: %v", err)
+		return nil, storageError("query logs", err)
 	}
 
 	return convertLogModelsToEntries(models)
 }
 
+// GetLog retrieves a single log entry with its matches. It returns
+// ErrLogNotFound if no entry has that ID.
+func GetLog(id int) (LogDetail, error) {
+	var models []LogEntryModel
+	if err := db.Where("id = ?", id).Limit(1).Find(&models).Error; err != nil {
+		return LogDetail{}, storageError("query log", err)
+	}
+	if len(models) == 0 {
+		return LogDetail{}, fmt.Errorf("%w: %d", ErrLogNotFound, id)
+	}
+
+	entries, err := convertLogModelsToEntries(models)
+	if err != nil {
+		return LogDetail{}, err
+	}
+
+	// Entries written before offsets were recorded have no matches
+	matches := []LogMatch{}
+	if models[0].Matches != "" {
+		if err := json.Unmarshal([]byte(models[0].Matches), &matches); err != nil {
+			return LogDetail{}, storageError("unmarshal matches", err)
+		}
+	}
+
+	return LogDetail{LogEntry: entries[0], Matches: matches}, nil
+}
+
 // convertLogModelsToEntries converts GORM models to API models
 func convertLogModelsToEntries(models []LogEntryModel) ([]LogEntry, error) {
 	logs := make([]LogEntry, len(models))
 	for i, m := range models {
 		var detections []string
 		if err := json.Unmarshal([]byte(m.Detections), &detections); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal detections: %v", err)
+			return nil, storageError("unmarshal detections", err)
+		}
+
+		// Entries written before deduplication have no last seen time
+		lastSeen := m.Timestamp
+		if m.LastSeen != nil {
+			lastSeen = *m.LastSeen
 		}
 
 		logs[i] = LogEntry{
@@ -357,6 +1439,13 @@ func convertLogModelsToEntries(models []LogEntryModel) ([]LogEntry, error) {
 			OriginalText: m.OriginalText,
 			FilteredText: m.FilteredText,
 			Detections:   detections,
+			Count:        m.Count,
+			LastSeen:     lastSeen.Format(time.RFC3339),
+			Hashed:       m.Hashed,
+			Kind:         m.Kind,
+			RiskLabel:    m.RiskLabel,
+			RiskScore:    m.RiskScore,
+			App:          m.App,
 		}
 	}
 
@@ -365,12 +1454,163 @@ func convertLogModelsToEntries(models []LogEntryModel) ([]LogEntry, error) {
 
 // ClearLogs removes all log entries from the database
 func ClearLogs() error {
-	return db.Where("1 = 1").Delete(&LogEntryModel{}).Error
+	if err := db.Where("1 = 1").Delete(&LogEntryModel{}).Error; err != nil {
+		return storageError("clear logs", err)
+	}
+	return nil
 }
 
 // GetLogCount returns the total number of log entries
 func GetLogCount() (int, error) {
 	var count int64
-	err := db.Model(&LogEntryModel{}).Count(&count).Error
-	return int(count), err
+	if err := db.Model(&LogEntryModel{}).Count(&count).Error; err != nil {
+		return 0, storageError("count logs", err)
+	}
+	return int(count), nil
+}
+
+// DetectionSummary counts the detections logged since a time
+type DetectionSummary struct {
+	Detections int        // Detections in prompts
+	Leaks      int        // Detections in responses
+	LastEvent  *time.Time // When the latest event of any kind was logged
+}
+
+// GetDetectionSummary returns the detections logged since a time and when
+// the latest event was logged. Repeated log entries count every occurrence
+// if the last one was since then.
+func GetDetectionSummary(since time.Time) (DetectionSummary, error) {
+	var models []LogEntryModel
+	if err := db.Select("detections", "count", "kind").Where("last_seen >= ?", since).Find(&models).Error; err != nil {
+		return DetectionSummary{}, storageError("query logs", err)
+	}
+
+	var summary DetectionSummary
+	for _, m := range models {
+		var detections []string
+		if err := json.Unmarshal([]byte(m.Detections), &detections); err != nil {
+			return DetectionSummary{}, storageError("unmarshal detections", err)
+		}
+		n := len(detections) * max(m.Count, 1)
+		if m.Kind == LogKindLeak {
+			summary.Leaks += n
+		} else {
+			summary.Detections += n
+		}
+	}
+
+	var latest LogEntryModel
+	if err := db.Select("last_seen").Order("id DESC").Limit(1).Find(&latest).Error; err != nil {
+		return DetectionSummary{}, storageError("query latest log", err)
+	}
+	summary.LastEvent = latest.LastSeen
+	return summary, nil
+}
+
+// LogMarker identifies the state of the logs: it changes whenever an entry
+// is added, repeated or deleted, by any process, so caches of statistics
+// can tell cheaply whether they are stale
+type LogMarker struct {
+	Entries     int64
+	LatestID    uint
+	LatestCount int
+	LatestSeen  time.Time
+}
+
+// GetLogMarker returns the current LogMarker
+func GetLogMarker() (LogMarker, error) {
+	var marker LogMarker
+	if err := db.Model(&LogEntryModel{}).Count(&marker.Entries).Error; err != nil {
+		return LogMarker{}, storageError("count logs", err)
+	}
+	var latest LogEntryModel
+	if err := db.Select("id", "count", "last_seen").Order("id DESC").Limit(1).Find(&latest).Error; err != nil {
+		return LogMarker{}, storageError("query latest log", err)
+	}
+	marker.LatestID, marker.LatestCount = latest.ID, latest.Count
+	if latest.LastSeen != nil {
+		marker.LatestSeen = *latest.LastSeen
+	}
+	return marker, nil
+}
+
+// GetDetectionCounts returns the number of detections logged in entries of
+// a kind per type, counting every occurrence of repeated log entries
+func GetDetectionCounts(kind string) (map[string]int, error) {
+	var models []LogEntryModel
+	if err := db.Select("detections", "count").Where("kind = ?", kind).Find(&models).Error; err != nil {
+		return nil, storageError("query logs", err)
+	}
+
+	counts := make(map[string]int)
+	for _, m := range models {
+		var detections []string
+		if err := json.Unmarshal([]byte(m.Detections), &detections); err != nil {
+			return nil, storageError("unmarshal detections", err)
+		}
+		for _, d := range detections {
+			counts[d] += max(m.Count, 1)
+		}
+	}
+	return counts, nil
+}
+
+// GetAppDetectionCounts returns the number of detections logged in prompts
+// per application and type, counting every occurrence of repeated log
+// entries. Detections from unknown applications are counted under "".
+func GetAppDetectionCounts() (map[string]map[string]int, error) {
+	var models []LogEntryModel
+	if err := db.Select("app", "detections", "count").Where("kind = ?", LogKindPrompt).Find(&models).Error; err != nil {
+		return nil, storageError("query logs", err)
+	}
+
+	counts := make(map[string]map[string]int)
+	for _, m := range models {
+		var detections []string
+		if err := json.Unmarshal([]byte(m.Detections), &detections); err != nil {
+			return nil, storageError("unmarshal detections", err)
+		}
+		if len(detections) == 0 {
+			continue
+		}
+		types, ok := counts[m.App]
+		if !ok {
+			types = make(map[string]int)
+			counts[m.App] = types
+		}
+		for _, d := range detections {
+			types[d] += max(m.Count, 1)
+		}
+	}
+	return counts, nil
+}
+
+// DetectionEvent is a logged event without its text
+type DetectionEvent struct {
+	Time       time.Time // When it was first logged
+	Kind       string
+	App        string
+	Detections []string
+	Count      int // Occurrences
+}
+
+// GetDetectionEvents returns the events first logged from start until
+// before end, oldest first
+func GetDetectionEvents(start, end time.Time) ([]DetectionEvent, error) {
+	var models []LogEntryModel
+	err := db.Select("timestamp", "kind", "app", "detections", "count").
+		Where("timestamp >= ? AND timestamp < ?", start, end).Order("timestamp ASC").Find(&models).Error
+	if err != nil {
+		return nil, storageError("query logs", err)
+	}
+
+	events := make([]DetectionEvent, len(models))
+	for i, m := range models {
+		var detections []string
+		if err := json.Unmarshal([]byte(m.Detections), &detections); err != nil {
+			return nil, storageError("unmarshal detections", err)
+		}
+		events[i] = DetectionEvent{Time: m.Timestamp, Kind: m.Kind, App: m.App, Detections: detections, Count: max(m.Count, 1)}
+	}
+	return events, nil
 }
@@ -0,0 +1,98 @@
+package db
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DomainPolicyModel is a policy for traffic to a destination host in
+// gateway and proxy modes (GORM model)
+type DomainPolicyModel struct {
+	ID        uint   `gorm:"primaryKey;autoIncrement"`
+	Host      string `gorm:"not null;uniqueIndex"`
+	Allow     bool   `gorm:"not null"`
+	Detectors string `gorm:"default:''"` // Comma-separated detectors, empty for all
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+func (DomainPolicyModel) TableName() string {
+	return "domain_policies"
+}
+
+// DomainPolicy controls traffic to a destination host in gateway and proxy
+// modes (API model)
+type DomainPolicy struct {
+	ID int `json:"id"`
+
+	// Host is a host name such as api.openai.com, a wildcard such as
+	// *.corp matching its subdomains, or * for every other host. The most
+	// specific policy matching a host applies.
+	Host string `json:"host"`
+
+	// Allow lets traffic to the host through; otherwise it is refused
+	Allow bool `json:"allow"`
+
+	// Detectors lists the detectors applied to traffic to the host, such
+	// as "email" or "string_match". Empty applies every enabled detector.
+	Detectors []string `json:"detectors"`
+}
+
+// LoadDomainPolicies loads all domain policies from the database
+func LoadDomainPolicies() ([]DomainPolicy, error) {
+	return loadDomainPolicies(db)
+}
+
+// loadDomainPolicies loads the domain policies seen by tx
+func loadDomainPolicies(tx *gorm.DB) ([]DomainPolicy, error) {
+	var models []DomainPolicyModel
+	if err := tx.Order("id").Find(&models).Error; err != nil {
+		return nil, storageError("query domain policies", err)
+	}
+
+	policies := make([]DomainPolicy, len(models))
+	for i, m := range models {
+		policies[i] = DomainPolicy{
+			ID:        int(m.ID),
+			Host:      m.Host,
+			Allow:     m.Allow,
+			Detectors: splitList(m.Detectors),
+		}
+	}
+	return policies, nil
+}
+
+// SaveDomainPolicy saves or updates a domain policy and returns the stored
+// policy (with its assigned ID for new policies)
+func SaveDomainPolicy(p DomainPolicy) (DomainPolicy, error) {
+	model := DomainPolicyModel{
+		ID:        uint(p.ID),
+		Host:      p.Host,
+		Allow:     p.Allow,
+		Detectors: strings.Join(p.Detectors, ","),
+	}
+	if p.ID != 0 {
+		var existing DomainPolicyModel
+		if err := db.First(&existing, p.ID).Error; err == nil {
+			model.CreatedAt = existing.CreatedAt
+		}
+	}
+
+	if err := db.Save(&model).Error; err != nil {
+		return DomainPolicy{}, storageError("save domain policy", err)
+	}
+
+	p.ID = int(model.ID)
+	p.Detectors = splitList(model.Detectors)
+	return p, nil
+}
+
+// DeleteDomainPolicy deletes a domain policy by ID
+func DeleteDomainPolicy(id int) error {
+	if err := db.Delete(&DomainPolicyModel{}, id).Error; err != nil {
+		return storageError("delete domain policy", err)
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package db
+
+import "errors"
+
+// ErrStorage matches, with errors.Is, every error of the database itself,
+// such as a locked, full or corrupt database file, as opposed to a missing
+// record or invalid input. See StorageError.
+var ErrStorage = errors.New("storage error")
+
+// ErrConfigNotFound is returned when the database holds no configuration,
+// e.g. because it was not initialized
+var ErrConfigNotFound = errors.New("configuration not found")
+
+// StorageError is a failed database operation
+type StorageError struct {
+	Op  string // What failed, e.g. "query logs"
+	Err error
+}
+
+func (e *StorageError) Error() string {
+	return "failed to " + e.Op + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error
+func (e *StorageError) Unwrap() error {
+	return e.Err
+}
+
+// Is reports whether target is ErrStorage
+func (e *StorageError) Is(target error) bool {
+	return target == ErrStorage
+}
+
+// storageError returns a *StorageError for the failure of op
+func storageError(op string, err error) error {
+	return &StorageError{Op: op, Err: err}
+}
@@ -0,0 +1,19 @@
+package db
+
+// GetReportSentUntil returns the last day, as YYYY-MM-DD, of the last
+// report e-mailed, or "" if none was
+func GetReportSentUntil() (string, error) {
+	var configModel ConfigModel
+	if err := db.Select("report_sent_until").First(&configModel, 1).Error; err != nil {
+		return "", storageError("load report state", err)
+	}
+	return configModel.ReportSentUntil, nil
+}
+
+// SetReportSentUntil records that the report ending on day was e-mailed
+func SetReportSentUntil(day string) error {
+	if err := db.Model(&ConfigModel{ID: 1}).Update("report_sent_until", day).Error; err != nil {
+		return storageError("save report state", err)
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Review item statuses
+const (
+	ReviewPending   = "pending"   // Awaiting a decision
+	ReviewConfirmed = "confirmed" // Confirmed as sensitive
+	ReviewIgnored   = "ignored"   // Not sensitive, allowlisted
+)
+
+// ErrReviewItemNotFound is returned when a review item ID does not exist
+var ErrReviewItemNotFound = errors.New("review item not found")
+
+// ReviewItemModel represents an uncertain match awaiting review (GORM model)
+type ReviewItemModel struct {
+	ID         uint    `gorm:"primaryKey;autoIncrement"`
+	Type       string  `gorm:"not null;index:idx_review_value"`
+	Value      string  `gorm:"not null;index:idx_review_value"`
+	Confidence float64 `gorm:"not null"`
+	Status     string  `gorm:"not null;index;default:'pending'"`
+	Count      int     `gorm:"default:1"` // Times the value was seen while pending
+	LastSeen   time.Time
+	DecidedBy  string `gorm:"default:''"`
+	DecidedAt  *time.Time
+	CreatedAt  time.Time
+}
+
+func (ReviewItemModel) TableName() string {
+	return "review_items"
+}
+
+// ReviewItem is an uncertain match awaiting review (API model)
+type ReviewItem struct {
+	ID         int     `json:"id"`
+	Type       string  `json:"type"`
+	Value      string  `json:"value"`
+	Confidence float64 `json:"confidence"`
+	Status     string  `json:"status"` // pending, confirmed or ignored
+	Count      int     `json:"count"`
+	CreatedAt  string  `json:"created_at"`
+	LastSeen   string  `json:"last_seen"`
+	DecidedBy  string  `json:"decided_by,omitempty"`
+	DecidedAt  string  `json:"decided_at,omitempty"`
+}
+
+// AddReviewItem queues an uncertain match for review. A value already
+// pending review is counted on its existing item instead.
+func AddReviewItem(typ, value string, confidence float64) error {
+	now := time.Now()
+	return db.Transaction(func(tx *gorm.DB) error {
+		var existing ReviewItemModel
+		err := tx.Where("type = ? AND value = ? AND status = ?", typ, value, ReviewPending).Limit(1).Find(&existing).Error
+		if err != nil {
+			return storageError("query review items", err)
+		}
+		if existing.ID != 0 {
+			err := tx.Model(&existing).Updates(map[string]interface{}{
+				"count":     gorm.Expr("count + 1"),
+				"last_seen": now,
+			}).Error
+			if err != nil {
+				return storageError("update review item", err)
+			}
+			return nil
+		}
+
+		err = tx.Create(&ReviewItemModel{
+			Type:       typ,
+			Value:      value,
+			Confidence: confidence,
+			Status:     ReviewPending,
+			Count:      1,
+			LastSeen:   now,
+		}).Error
+		if err != nil {
+			return storageError("add review item", err)
+		}
+		return nil
+	})
+}
+
+// ListReviewItems returns a page of review items with the given status,
+// newest first
+func ListReviewItems(status string, page, pageSize int) ([]ReviewItem, error) {
+	if page < 1 {
+		page = 1
+	}
+	if pageSize <= 0 {
+		pageSize = 10
+	}
+
+	var models []ReviewItemModel
+	err := db.Where("status = ?", status).Order("last_seen DESC").Limit(pageSize).Offset((page - 1) * pageSize).Find(&models).Error
+	if err != nil {
+		return nil, storageError("query review items", err)
+	}
+
+	items := make([]ReviewItem, len(models))
+	for i, m := range models {
+		items[i] = convertReviewModel(m)
+	}
+	return items, nil
+}
+
+// GetReviewCount returns the number of review items with the given status
+func GetReviewCount(status string) (int, error) {
+	var count int64
+	if err := db.Model(&ReviewItemModel{}).Where("status = ?", status).Count(&count).Error; err != nil {
+		return 0, storageError("count review items", err)
+	}
+	return int(count), nil
+}
+
+// GetReviewItem returns a review item. It returns ErrReviewItemNotFound if
+// no item has that ID.
+func GetReviewItem(id int) (ReviewItem, error) {
+	var models []ReviewItemModel
+	if err := db.Where("id = ?", id).Limit(1).Find(&models).Error; err != nil {
+		return ReviewItem{}, storageError("query review item", err)
+	}
+	if len(models) == 0 {
+		return ReviewItem{}, fmt.Errorf("%w: %d", ErrReviewItemNotFound, id)
+	}
+	return convertReviewModel(models[0]), nil
+}
+
+// DecideReviewItem records actor's decision, ReviewConfirmed or
+// ReviewIgnored, on a review item and on any other pending item with the
+// same type and value
+func DecideReviewItem(id int, status, actor string) (ReviewItem, error) {
+	item, err := GetReviewItem(id)
+	if err != nil {
+		return ReviewItem{}, err
+	}
+
+	now := time.Now()
+	err = db.Model(&ReviewItemModel{}).
+		Where("id = ? OR (type = ? AND value = ? AND status = ?)", id, item.Type, item.Value, ReviewPending).
+		Updates(map[string]interface{}{"status": status, "decided_by": actor, "decided_at": now}).Error
+	if err != nil {
+		return ReviewItem{}, storageError("update review item", err)
+	}
+	return GetReviewItem(id)
+}
+
+// convertReviewModel converts a GORM model to the API model
+func convertReviewModel(m ReviewItemModel) ReviewItem {
+	item := ReviewItem{
+		ID:         int(m.ID),
+		Type:       m.Type,
+		Value:      m.Value,
+		Confidence: m.Confidence,
+		Status:     m.Status,
+		Count:      m.Count,
+		CreatedAt:  m.CreatedAt.Format(time.RFC3339),
+		LastSeen:   m.LastSeen.Format(time.RFC3339),
+		DecidedBy:  m.DecidedBy,
+	}
+	if m.DecidedAt != nil {
+		item.DecidedAt = m.DecidedAt.Format(time.RFC3339)
+	}
+	return item
+}
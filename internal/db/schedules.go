@@ -0,0 +1,86 @@
+package db
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// ScheduleModel represents a time window that switches the detection profile (GORM model)
+type ScheduleModel struct {
+	ID        uint   `gorm:"primaryKey;autoIncrement"`
+	Name      string `gorm:"not null"`
+	Days      string `gorm:"default:''"` // Comma-separated weekdays, empty for every day
+	Start     string `gorm:"not null"`   // HH:MM
+	End       string `gorm:"not null"`   // HH:MM
+	Profile   string `gorm:"not null"`
+	Enabled   bool   `gorm:"not null"`
+	CreatedAt time.Time
+}
+
+func (ScheduleModel) TableName() string {
+	return "schedules"
+}
+
+// Schedule represents a time window that switches the detection profile (API model).
+// A window whose end is before its start spans midnight and belongs to the
+// day it starts on.
+type Schedule struct {
+	Name    string   `json:"name"`
+	Days    []string `json:"days"`  // Weekdays such as "mon", empty for every day
+	Start   string   `json:"start"` // Local time, HH:MM
+	End     string   `json:"end"`   // Local time, HH:MM
+	Profile string   `json:"profile"`
+	Enabled bool     `json:"enabled"`
+}
+
+// LoadSchedules loads all schedules from the database in evaluation order
+func LoadSchedules() ([]Schedule, error) {
+	return loadSchedules(db)
+}
+
+// loadSchedules loads the schedules seen by tx
+func loadSchedules(tx *gorm.DB) ([]Schedule, error) {
+	var models []ScheduleModel
+	if err := tx.Order("id").Find(&models).Error; err != nil {
+		return nil, storageError("query schedules", err)
+	}
+
+	schedules := make([]Schedule, len(models))
+	for i, m := range models {
+		schedules[i] = Schedule{
+			Name:    m.Name,
+			Days:    splitList(m.Days),
+			Start:   m.Start,
+			End:     m.End,
+			Profile: m.Profile,
+			Enabled: m.Enabled,
+		}
+	}
+
+	return schedules, nil
+}
+
+// replaceSchedules replaces all stored schedules within tx
+func replaceSchedules(tx *gorm.DB, schedules []Schedule) error {
+	if err := tx.Where("1 = 1").Delete(&ScheduleModel{}).Error; err != nil {
+		return storageError("clear schedules", err)
+	}
+
+	for _, s := range schedules {
+		model := ScheduleModel{
+			Name:    s.Name,
+			Days:    strings.Join(s.Days, ","),
+			Start:   s.Start,
+			End:     s.End,
+			Profile: s.Profile,
+			Enabled: s.Enabled,
+		}
+		if err := tx.Create(&model).Error; err != nil {
+			return storageError("save schedule", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,21 @@
+package db
+
+import "time"
+
+// GetSetupCompletedAt returns when first-run setup was completed, or nil if
+// it never was
+func GetSetupCompletedAt() (*time.Time, error) {
+	var configModel ConfigModel
+	if err := db.Select("setup_completed_at").First(&configModel, 1).Error; err != nil {
+		return nil, storageError("load setup state", err)
+	}
+	return configModel.SetupCompletedAt, nil
+}
+
+// CompleteSetup records that first-run setup was completed
+func CompleteSetup() error {
+	if err := db.Model(&ConfigModel{ID: 1}).Update("setup_completed_at", time.Now()).Error; err != nil {
+		return storageError("complete setup", err)
+	}
+	return nil
+}
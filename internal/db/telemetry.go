@@ -0,0 +1,54 @@
+package db
+
+import (
+	"github.com/happytaoer/prompt-security/internal/telemetry"
+	"gorm.io/gorm"
+)
+
+// telemetryEndKey stores the function ending a statement's trace
+const telemetryEndKey = "telemetry:end"
+
+// registerTelemetry traces every GORM statement
+func registerTelemetry(database *gorm.DB) error {
+	cb := database.Callback()
+	callbacks := []struct {
+		register func(name string, fn func(*gorm.DB)) error
+		name     string
+		fn       func(*gorm.DB)
+	}{
+		{cb.Create().Before("gorm:create").Register, "telemetry:before_create", startStatement("create")},
+		{cb.Create().After("gorm:create").Register, "telemetry:after_create", endStatement},
+		{cb.Query().Before("gorm:query").Register, "telemetry:before_query", startStatement("query")},
+		{cb.Query().After("gorm:query").Register, "telemetry:after_query", endStatement},
+		{cb.Update().Before("gorm:update").Register, "telemetry:before_update", startStatement("update")},
+		{cb.Update().After("gorm:update").Register, "telemetry:after_update", endStatement},
+		{cb.Delete().Before("gorm:delete").Register, "telemetry:before_delete", startStatement("delete")},
+		{cb.Delete().After("gorm:delete").Register, "telemetry:after_delete", endStatement},
+		{cb.Row().Before("gorm:row").Register, "telemetry:before_row", startStatement("row")},
+		{cb.Row().After("gorm:row").Register, "telemetry:after_row", endStatement},
+		{cb.Raw().Before("gorm:raw").Register, "telemetry:before_raw", startStatement("raw")},
+		{cb.Raw().After("gorm:raw").Register, "telemetry:after_raw", endStatement},
+	}
+
+	for _, c := range callbacks {
+		if err := c.register(c.name, c.fn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// startStatement returns a callback starting the trace of an operation
+func startStatement(operation string) func(*gorm.DB) {
+	return func(tx *gorm.DB) {
+		_, end := telemetry.StartDB(tx.Statement.Context, operation, tx.Statement.Table)
+		tx.InstanceSet(telemetryEndKey, end)
+	}
+}
+
+// endStatement ends the trace started by startStatement
+func endStatement(tx *gorm.DB) {
+	if end, ok := tx.InstanceGet(telemetryEndKey); ok {
+		end.(func(error))(tx.Error)
+	}
+}
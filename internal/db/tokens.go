@@ -0,0 +1,130 @@
+package db
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// APITokenModel represents an API access token (GORM model)
+type APITokenModel struct {
+	ID         uint   `gorm:"primaryKey;autoIncrement"`
+	Name       string `gorm:"not null;uniqueIndex"`
+	TokenHash  string `gorm:"not null;uniqueIndex"`
+	Role       string `gorm:"not null"`
+	LastUsedAt *time.Time
+	CreatedAt  time.Time
+}
+
+func (APITokenModel) TableName() string {
+	return "api_tokens"
+}
+
+// APIToken represents an API access token without its secret (API model)
+type APIToken struct {
+	ID         int    `json:"id"`
+	Name       string `json:"name"`
+	Role       string `json:"role"`
+	CreatedAt  string `json:"created_at"`
+	LastUsedAt string `json:"last_used_at,omitempty"`
+}
+
+// CreateAPIToken creates a new token for name with the given role and returns
+// the plaintext secret. Only a hash of the secret is stored.
+func CreateAPIToken(name, role string) (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("failed to generate token: %v", err)
+	}
+	secret := "ps_" + hex.EncodeToString(buf)
+
+	model := APITokenModel{
+		Name:      name,
+		TokenHash: hashToken(secret),
+		Role:      role,
+	}
+	if err := db.Create(&model).Error; err != nil {
+		return "", storageError("create token", err)
+	}
+
+	return secret, nil
+}
+
+// LookupAPIToken returns the token matching secret and records its use.
+// The boolean result is false when no token matches.
+func LookupAPIToken(secret string) (APIToken, bool, error) {
+	var model APITokenModel
+	err := db.Where("token_hash = ?", hashToken(secret)).First(&model).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return APIToken{}, false, nil
+	}
+	if err != nil {
+		return APIToken{}, false, storageError("query token", err)
+	}
+
+	now := time.Now()
+	db.Model(&model).Update("last_used_at", now)
+	model.LastUsedAt = &now
+
+	return convertTokenModel(model), true, nil
+}
+
+// ListAPITokens returns all tokens ordered by ID
+func ListAPITokens() ([]APIToken, error) {
+	var models []APITokenModel
+	if err := db.Order("id").Find(&models).Error; err != nil {
+		return nil, storageError("query tokens", err)
+	}
+
+	tokens := make([]APIToken, len(models))
+	for i, m := range models {
+		tokens[i] = convertTokenModel(m)
+	}
+	return tokens, nil
+}
+
+// DeleteAPIToken deletes the token with the given name
+func DeleteAPIToken(name string) error {
+	result := db.Where("name = ?", name).Delete(&APITokenModel{})
+	if result.Error != nil {
+		return storageError("delete token", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("token %q not found", name)
+	}
+	return nil
+}
+
+// CountAPITokens returns the number of configured tokens
+func CountAPITokens() (int, error) {
+	var count int64
+	if err := db.Model(&APITokenModel{}).Count(&count).Error; err != nil {
+		return 0, storageError("count tokens", err)
+	}
+	return int(count), nil
+}
+
+// hashToken returns the hex-encoded SHA-256 of a token secret
+func hashToken(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// convertTokenModel converts a GORM model to an API model
+func convertTokenModel(m APITokenModel) APIToken {
+	token := APIToken{
+		ID:        int(m.ID),
+		Name:      m.Name,
+		Role:      m.Role,
+		CreatedAt: m.CreatedAt.Format(time.RFC3339),
+	}
+	if m.LastUsedAt != nil {
+		token.LastUsedAt = m.LastUsedAt.Format(time.RFC3339)
+	}
+	return token
+}
@@ -0,0 +1,87 @@
+package db
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// UpstreamKeyModel is the API key the gateway sends to a model provider
+// (GORM model). Unlike API tokens the key must be sent as is, so it is
+// stored in plain text. It is never returned by the web API.
+type UpstreamKeyModel struct {
+	ID        uint   `gorm:"primaryKey;autoIncrement"`
+	Provider  string `gorm:"not null;uniqueIndex"`
+	Key       string `gorm:"not null"`
+	UpdatedAt time.Time
+}
+
+func (UpstreamKeyModel) TableName() string {
+	return "upstream_keys"
+}
+
+// UpstreamKey describes a stored provider key without the key (API model)
+type UpstreamKey struct {
+	Provider  string `json:"provider"`
+	Hint      string `json:"hint"` // Last characters of the key
+	UpdatedAt string `json:"updated_at"`
+}
+
+// SetUpstreamKey stores the API key of provider, replacing any previous key
+func SetUpstreamKey(provider, key string) error {
+	model := UpstreamKeyModel{Provider: provider, Key: key, UpdatedAt: time.Now()}
+	err := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "provider"}},
+		DoUpdates: clause.AssignmentColumns([]string{"key", "updated_at"}),
+	}).Create(&model).Error
+	if err != nil {
+		return storageError("store key", err)
+	}
+	return nil
+}
+
+// GetUpstreamKey returns the API key of provider, or "" if none is stored
+func GetUpstreamKey(provider string) (string, error) {
+	var model UpstreamKeyModel
+	err := db.Where("provider = ?", provider).First(&model).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", nil
+	}
+	if err != nil {
+		return "", storageError("query key", err)
+	}
+	return model.Key, nil
+}
+
+// ListUpstreamKeys returns the stored keys ordered by provider
+func ListUpstreamKeys() ([]UpstreamKey, error) {
+	var models []UpstreamKeyModel
+	if err := db.Order("provider").Find(&models).Error; err != nil {
+		return nil, storageError("query keys", err)
+	}
+
+	keys := make([]UpstreamKey, len(models))
+	for i, m := range models {
+		hint := m.Key
+		if len(hint) > 4 {
+			hint = "…" + hint[len(hint)-4:]
+		}
+		keys[i] = UpstreamKey{Provider: m.Provider, Hint: hint, UpdatedAt: m.UpdatedAt.Format(time.RFC3339)}
+	}
+	return keys, nil
+}
+
+// DeleteUpstreamKey deletes the stored key of provider
+func DeleteUpstreamKey(provider string) error {
+	result := db.Where("provider = ?", provider).Delete(&UpstreamKeyModel{})
+	if result.Error != nil {
+		return storageError("delete key", result.Error)
+	}
+	if result.RowsAffected == 0 {
+		return fmt.Errorf("no key stored for %q", provider)
+	}
+	return nil
+}
@@ -0,0 +1,87 @@
+package db
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// usageDayFormat is the layout of usage count days, in UTC
+const usageDayFormat = "2006-01-02"
+
+// UsageCountModel counts the detections of one type on one day (GORM model).
+// Only counts are kept, never the detected text.
+type UsageCountModel struct {
+	ID       uint   `gorm:"primaryKey;autoIncrement"`
+	Day      string `gorm:"not null;uniqueIndex:idx_usage_day_type"` // YYYY-MM-DD, UTC
+	Type     string `gorm:"not null;uniqueIndex:idx_usage_day_type"`
+	Count    int    `gorm:"not null;default:0"`
+	Reported bool   `gorm:"not null;default:false;index"`
+}
+
+func (UsageCountModel) TableName() string {
+	return "usage_counts"
+}
+
+// UsageCount is the number of detections of one type on one day (API model)
+type UsageCount struct {
+	Day   string `json:"day"`
+	Type  string `json:"type"`
+	Count int    `json:"count"`
+}
+
+// UsageDay returns the usage count day of t
+func UsageDay(t time.Time) string {
+	return t.UTC().Format(usageDayFormat)
+}
+
+// AddUsage counts detections of the given types at t, one per entry
+func AddUsage(t time.Time, types []string) error {
+	if len(types) == 0 {
+		return nil
+	}
+	day := UsageDay(t)
+	return db.Transaction(func(tx *gorm.DB) error {
+		for _, typ := range types {
+			var existing UsageCountModel
+			if err := tx.Where("day = ? AND type = ?", day, typ).Limit(1).Find(&existing).Error; err != nil {
+				return storageError("query usage counts", err)
+			}
+			if existing.ID != 0 {
+				if err := tx.Model(&existing).Update("count", gorm.Expr("count + 1")).Error; err != nil {
+					return storageError("update usage count", err)
+				}
+				continue
+			}
+			if err := tx.Create(&UsageCountModel{Day: day, Type: typ, Count: 1}).Error; err != nil {
+				return storageError("add usage count", err)
+			}
+		}
+		return nil
+	})
+}
+
+// GetUnreportedUsage returns the unreported counts of days before the given
+// day, oldest first
+func GetUnreportedUsage(before string) ([]UsageCount, error) {
+	var models []UsageCountModel
+	err := db.Where("reported = ? AND day < ?", false, before).Order("day ASC, type ASC").Find(&models).Error
+	if err != nil {
+		return nil, storageError("query usage counts", err)
+	}
+
+	counts := make([]UsageCount, len(models))
+	for i, m := range models {
+		counts[i] = UsageCount{Day: m.Day, Type: m.Type, Count: m.Count}
+	}
+	return counts, nil
+}
+
+// MarkUsageReported marks the counts of days before the given day as
+// reported
+func MarkUsageReported(before string) error {
+	if err := db.Model(&UsageCountModel{}).Where("reported = ? AND day < ?", false, before).Update("reported", true).Error; err != nil {
+		return storageError("mark usage counts reported", err)
+	}
+	return nil
+}
@@ -0,0 +1,50 @@
+package demo
+
+import (
+	"testing"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+	"github.com/happytaoer/prompt-security/internal/db"
+	"github.com/happytaoer/prompt-security/internal/filter"
+)
+
+// TestSeed tests that every sample is detected and the configuration and
+// patterns are saved
+func TestSeed(t *testing.T) {
+	db.SetPath(db.MemoryPath)
+	if err := config.Initialize(); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer config.Close()
+
+	manager, err := config.NewManager()
+	if err != nil {
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+	engine := filter.NewEngine(manager.Effective())
+	manager.OnChange(engine.Reload)
+
+	types := make(map[string]bool)
+	logged := 0
+	err = Seed(manager, engine, func(original, filtered string, replacements []filter.ReplacementInfo) {
+		logged++
+		for _, r := range replacements {
+			types[r.Type] = true
+		}
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if logged != len(Samples) {
+		t.Errorf("Expected every sample to be logged, got %d of %d", logged, len(Samples))
+	}
+	for _, typ := range []string{"email", "phone", "credit_card", "ssn", "ipv4", "Project codename", "Private key"} {
+		if !types[typ] {
+			t.Errorf("Expected a %s detection, got %v", typ, types)
+		}
+	}
+	if got := manager.Get(); !got.DetectIPV4 || got.ReviewThreshold != reviewThreshold || len(got.StringMatchPatterns) != len(Patterns) {
+		t.Errorf("Expected the demo configuration to be saved, got %+v", got)
+	}
+}
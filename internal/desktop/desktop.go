@@ -0,0 +1,16 @@
+// Package desktop tells which window has the focus, for the clipboard
+// monitor and the keyboard guard. It works on Linux under X11 with xdotool;
+// elsewhere, Wayland included, every call fails.
+package desktop
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrUnsupported is returned where the focused window cannot be told
+var ErrUnsupported = errors.New("the focused window cannot be told on this system")
+
+// timeout bounds each lookup, so a hung X server stalls neither the
+// clipboard loop nor the keyboard guard
+const timeout = 2 * time.Second
@@ -0,0 +1,53 @@
+package desktop
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// FocusedWindow returns the title of the focused X11 window
+func FocusedWindow() (string, error) {
+	return xdotool("getwindowname")
+}
+
+// FocusedApp returns the process name of the focused X11 window
+func FocusedApp() (string, error) {
+	out, err := xdotool("getwindowpid")
+	if err != nil {
+		return "", err
+	}
+	pid, err := strconv.Atoi(out)
+	if err != nil {
+		return "", fmt.Errorf("failed to parse window PID %q: %v", out, err)
+	}
+	comm, err := os.ReadFile(filepath.Join("/proc", strconv.Itoa(pid), "comm"))
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(comm)), nil
+}
+
+// xdotool runs xdotool's command on the active window and returns its
+// output
+func xdotool(command string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, "xdotool", "getactivewindow", command).Output()
+	if err != nil {
+		if errors.Is(err, exec.ErrNotFound) {
+			return "", errors.New("install xdotool (X11 only)")
+		}
+		if ctx.Err() != nil {
+			return "", fmt.Errorf("xdotool did not answer within %v", timeout)
+		}
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
@@ -0,0 +1,13 @@
+//go:build !linux
+
+package desktop
+
+// FocusedWindow reports ErrUnsupported
+func FocusedWindow() (string, error) {
+	return "", ErrUnsupported
+}
+
+// FocusedApp reports ErrUnsupported
+func FocusedApp() (string, error) {
+	return "", ErrUnsupported
+}
@@ -0,0 +1,59 @@
+package editor
+
+import "sync"
+
+// Cache holds the latest scanned version of recently used documents by
+// URI, dropping the least recently used beyond its size
+type Cache struct {
+	mu    sync.Mutex
+	size  int
+	docs  map[string]*cachedDocument
+	clock int
+}
+
+type cachedDocument struct {
+	doc  *Document
+	used int
+}
+
+// NewCache creates a cache of up to size documents
+func NewCache(size int) *Cache {
+	return &Cache{size: size, docs: make(map[string]*cachedDocument)}
+}
+
+// Get returns the cached document with uri, nil if there is none
+func (c *Cache) Get(uri string) *Document {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cached, ok := c.docs[uri]
+	if !ok {
+		return nil
+	}
+	c.clock++
+	cached.used = c.clock
+	return cached.doc
+}
+
+// Put caches doc as the latest version of the document with uri
+func (c *Cache) Put(uri string, doc *Document) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.clock++
+	if _, ok := c.docs[uri]; !ok && len(c.docs) >= c.size {
+		oldest := ""
+		for u, cached := range c.docs {
+			if oldest == "" || cached.used < c.docs[oldest].used {
+				oldest = u
+			}
+		}
+		delete(c.docs, oldest)
+	}
+	c.docs[uri] = &cachedDocument{doc: doc, used: c.clock}
+}
+
+// Delete drops the document with uri
+func (c *Cache) Delete(uri string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.docs, uri)
+}
@@ -0,0 +1,242 @@
+// Package editor scans documents open in an editor for sensitive data.
+// Positions are lines and UTF-16 offsets like in the language server
+// protocol. After an edit only the lines around it are scanned again,
+// unless the configuration changed since the last scan.
+package editor
+
+import (
+	"errors"
+	"sort"
+	"strings"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+	"github.com/happytaoer/prompt-security/internal/filter"
+)
+
+// contextLines is how many lines around an edit are scanned again with it,
+// for matches that depend on nearby text
+const contextLines = 2
+
+// ErrInvalidRange is returned for changes whose range ends before it starts
+var ErrInvalidRange = errors.New("range ends before it starts")
+
+// Position is a zero-based line and UTF-16 offset in the line
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// Before reports whether p comes before o
+func (p Position) Before(o Position) bool {
+	return p.Line < o.Line || (p.Line == o.Line && p.Character < o.Character)
+}
+
+// Range is the text from Start up to End
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// Overlaps reports whether two ranges share text, or touch if either is
+// empty, like a cursor
+func (r Range) Overlaps(o Range) bool {
+	return !r.End.Before(o.Start) && !o.End.Before(r.Start)
+}
+
+// TextChange replaces the text in Range, or the whole text if Range is nil
+type TextChange struct {
+	Range *Range `json:"range,omitempty"`
+	Text  string `json:"text"`
+}
+
+// TextEdit replaces the text in Range
+type TextEdit struct {
+	Range   Range  `json:"range"`
+	NewText string `json:"newText"`
+}
+
+// Finding is sensitive data in a document
+type Finding struct {
+	Range       Range  `json:"range"`
+	Type        string `json:"type"`
+	Action      string `json:"action"`                // replace, block, ask, log or review
+	Replacement string `json:"replacement,omitempty"` // Empty if the detector leaves matches in place
+}
+
+// finding is a Finding at byte offsets
+type finding struct {
+	start, end  int
+	typ         string
+	action      string
+	replacement string
+}
+
+// Document is a scanned version of a document. It is not modified once
+// created and may be shared.
+type Document struct {
+	Version   int
+	text      string
+	index     *lineIndex
+	findings  []finding
+	detectors *filter.DetectorSet // The detectors the findings are from
+}
+
+// Scan scans text in full with the engine's current detectors
+func Scan(engine *filter.Engine, version int, text string) *Document {
+	ds := engine.Detectors()
+	return &Document{Version: version, text: text, index: newLineIndex(text), findings: scan(ds, text, 0), detectors: ds}
+}
+
+// Text returns the text of the document
+func (d *Document) Text() string {
+	return d.text
+}
+
+// Findings returns the sensitive data in the document, in order
+func (d *Document) Findings() []Finding {
+	findings := make([]Finding, len(d.findings))
+	for i, f := range d.findings {
+		findings[i] = Finding{
+			Range:       Range{Start: d.index.position(f.start), End: d.index.position(f.end)},
+			Type:        f.typ,
+			Action:      f.action,
+			Replacement: f.replacement,
+		}
+	}
+	return findings
+}
+
+// Redact returns the text in selection, or the whole document if it is nil,
+// with its sensitive data redacted, and the edits redacting it in the
+// document. The selection is widened to whole findings.
+func (d *Document) Redact(selection *Range) (string, []TextEdit) {
+	from, to := 0, len(d.text)
+	if selection != nil {
+		from, to = d.index.offset(selection.Start), d.index.offset(selection.End)
+		if to < from {
+			from, to = to, from
+		}
+	}
+	for _, f := range d.findings {
+		if f.replacement != "" && f.end > from && f.start < to {
+			from, to = min(from, f.start), max(to, f.end)
+		}
+	}
+
+	var b strings.Builder
+	edits := []TextEdit{}
+	pos := from
+	for _, f := range d.findings {
+		if f.replacement == "" || f.start < from || f.end > to {
+			continue
+		}
+		b.WriteString(d.text[pos:f.start])
+		b.WriteString(f.replacement)
+		pos = f.end
+		edits = append(edits, TextEdit{
+			Range:   Range{Start: d.index.position(f.start), End: d.index.position(f.end)},
+			NewText: f.replacement,
+		})
+	}
+	b.WriteString(d.text[pos:to])
+	return b.String(), edits
+}
+
+// Apply returns the document at version after applying changes in order.
+// Only the lines around each change are scanned again, unless the engine's
+// detectors changed since the document was scanned.
+func (d *Document) Apply(engine *filter.Engine, version int, changes []TextChange) (*Document, error) {
+	if engine.Detectors() != d.detectors {
+		text := d.text
+		for _, c := range changes {
+			start, end, err := d.changeOffsets(c)
+			if err != nil {
+				return nil, err
+			}
+			text = text[:start] + c.Text + text[end:]
+			d = &Document{text: text, index: newLineIndex(text)}
+		}
+		return Scan(engine, version, text), nil
+	}
+
+	for _, c := range changes {
+		next, err := d.apply(c)
+		if err != nil {
+			return nil, err
+		}
+		d = next
+	}
+	return &Document{Version: version, text: d.text, index: d.index, findings: d.findings, detectors: d.detectors}, nil
+}
+
+// changeOffsets returns the byte offsets of the text a change replaces
+func (d *Document) changeOffsets(c TextChange) (int, int, error) {
+	if c.Range == nil {
+		return 0, len(d.text), nil
+	}
+	if c.Range.End.Before(c.Range.Start) {
+		return 0, 0, ErrInvalidRange
+	}
+	return d.index.offset(c.Range.Start), d.index.offset(c.Range.End), nil
+}
+
+// apply applies a change and scans the lines around it again
+func (d *Document) apply(c TextChange) (*Document, error) {
+	start, end, err := d.changeOffsets(c)
+	if err != nil {
+		return nil, err
+	}
+	text := d.text[:start] + c.Text + d.text[end:]
+	index := newLineIndex(text)
+	delta := len(c.Text) - (end - start)
+
+	// The window to scan again, in the new text: the changed lines with
+	// context, widened to cover the findings it cuts through
+	from := index.lineStart(index.line(start) - contextLines)
+	to := index.lineStart(index.line(start+len(c.Text)) + contextLines + 1)
+	for _, f := range d.findings {
+		if f.end > from && f.start < to-delta {
+			if f.start < from {
+				from = f.start
+			}
+			if f.end+delta > to {
+				to = f.end + delta
+			}
+		}
+	}
+
+	findings := make([]finding, 0, len(d.findings))
+	for _, f := range d.findings {
+		if f.end <= from {
+			findings = append(findings, f)
+		}
+	}
+	findings = append(findings, scan(d.detectors, text[from:to], from)...)
+	for _, f := range d.findings {
+		if f.start >= to-delta {
+			f.start += delta
+			f.end += delta
+			findings = append(findings, f)
+		}
+	}
+	return &Document{text: text, index: index, findings: findings, detectors: d.detectors}, nil
+}
+
+// scan returns the sensitive data in text, which starts at offset of the
+// document
+func scan(ds *filter.DetectorSet, text string, offset int) []finding {
+	_, _, summary := ds.Filter(text)
+	findings := make([]finding, 0, len(summary.Replacements))
+	for _, r := range summary.Replacements {
+		f := finding{start: r.Start + offset, end: r.End + offset, typ: r.Type, action: r.Action}
+		if f.action == "" {
+			f.action = config.ActionReplace
+		}
+		if r.Replacement != r.Original {
+			f.replacement = r.Replacement
+		}
+		findings = append(findings, f)
+	}
+	sort.SliceStable(findings, func(i, j int) bool { return findings[i].start < findings[j].start })
+	return findings
+}
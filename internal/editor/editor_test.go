@@ -0,0 +1,120 @@
+package editor
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+	"github.com/happytaoer/prompt-security/internal/filter"
+)
+
+func testEngine() *filter.Engine {
+	return filter.NewEngine(config.Config{
+		DetectEmails:     true,
+		EmailReplacement: "[EMAIL]",
+		DetectSSNs:       true,
+		SSNReplacement:   "XXX-XX-XXXX",
+		SSNAction:        config.ActionBlock,
+	})
+}
+
+// TestScan tests positions of findings and their replacements
+func TestScan(t *testing.T) {
+	doc := Scan(testEngine(), 1, "a\r\n🙂 ann@example.com\nssn 123-45-6789")
+	want := []Finding{
+		{Range: Range{Start: Position{Line: 1, Character: 3}, End: Position{Line: 1, Character: 18}}, Type: "email", Action: config.ActionReplace, Replacement: "[EMAIL]"},
+		{Range: Range{Start: Position{Line: 2, Character: 4}, End: Position{Line: 2, Character: 15}}, Type: "ssn", Action: config.ActionBlock, Replacement: "XXX-XX-XXXX"},
+	}
+	if got := doc.Findings(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Expected %+v, got %+v", want, got)
+	}
+}
+
+// TestApply tests that documents scanned incrementally after random edits
+// have the findings of a full scan
+func TestApply(t *testing.T) {
+	engine := testEngine()
+	pieces := []string{"ann@example.com", "123-45-6789", "bob@", "example.org", "\n", "\r\n", " ", "text", "🙂", "-", "1"}
+	random := rand.New(rand.NewSource(1))
+	randomText := func(n int) string {
+		var b strings.Builder
+		for i := 0; i < n; i++ {
+			b.WriteString(pieces[random.Intn(len(pieces))])
+		}
+		return b.String()
+	}
+	randomPosition := func(doc *Document) Position {
+		return doc.index.position(doc.index.offset(Position{Line: random.Intn(len(doc.index.starts)), Character: random.Intn(20)}))
+	}
+
+	doc := Scan(engine, 0, randomText(200))
+	for version := 1; version <= 500; version++ {
+		start, end := randomPosition(doc), randomPosition(doc)
+		if end.Before(start) {
+			start, end = end, start
+		}
+		change := TextChange{Range: &Range{Start: start, End: end}, Text: randomText(random.Intn(4))}
+
+		next, err := doc.Apply(engine, version, []TextChange{change})
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		full := Scan(engine, version, next.Text())
+		if !reflect.DeepEqual(next.Findings(), full.Findings()) {
+			t.Fatalf("Version %d: incremental findings %+v differ from a full scan %+v of %q", version, next.Findings(), full.Findings(), next.Text())
+		}
+		doc = next
+	}
+
+	if _, err := doc.Apply(engine, 0, []TextChange{{Range: &Range{Start: Position{Line: 1}}}}); err != ErrInvalidRange {
+		t.Errorf("Expected %v, got %v", ErrInvalidRange, err)
+	}
+	if next, _ := doc.Apply(engine, 0, []TextChange{{Text: "ann@example.com"}}); next.Text() != "ann@example.com" || len(next.Findings()) != 1 {
+		t.Errorf("Expected a change without a range to replace the whole text, got %q", next.Text())
+	}
+}
+
+// TestApply_Reload tests that documents are scanned in full after the
+// configuration changed
+func TestApply_Reload(t *testing.T) {
+	engine := testEngine()
+	doc := Scan(engine, 1, "ann@example.com\n\n\n\n\nbob@example.com")
+	engine.Reload(config.Config{DetectSSNs: true})
+
+	next, _ := doc.Apply(engine, 2, []TextChange{{Range: &Range{}, Text: "x"}})
+	if len(next.Findings()) != 0 {
+		t.Errorf("Expected no findings after emails were turned off, got %+v", next.Findings())
+	}
+}
+
+// TestRedact tests redacting a selection widened to whole findings
+func TestRedact(t *testing.T) {
+	doc := Scan(testEngine(), 1, "mail ann@example.com and bob@example.com")
+
+	text, edits := doc.Redact(&Range{Start: Position{Character: 10}, End: Position{Character: 24}})
+	if text != "[EMAIL] and" || len(edits) != 1 || edits[0].Range.Start.Character != 5 {
+		t.Errorf("Expected the selection to be widened to the first email, got %q %+v", text, edits)
+	}
+	if text, edits := doc.Redact(nil); text != "mail [EMAIL] and [EMAIL]" || len(edits) != 2 {
+		t.Errorf("Expected the whole document to be redacted, got %q %+v", text, edits)
+	}
+}
+
+// TestCache tests that the least recently used document is dropped
+func TestCache(t *testing.T) {
+	cache := NewCache(2)
+	a, b, c := &Document{}, &Document{}, &Document{}
+	cache.Put("a", a)
+	cache.Put("b", b)
+	cache.Get("a")
+	cache.Put("c", c)
+	if cache.Get("a") != a || cache.Get("b") != nil || cache.Get("c") != c {
+		t.Error("Expected b to be dropped")
+	}
+	cache.Delete("a")
+	if cache.Get("a") != nil {
+		t.Error("Expected a to be deleted")
+	}
+}
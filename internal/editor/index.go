@@ -0,0 +1,82 @@
+package editor
+
+import (
+	"sort"
+	"unicode/utf8"
+)
+
+// lineIndex converts between byte offsets in a text and positions
+type lineIndex struct {
+	text   string
+	starts []int // Byte offset of each line
+}
+
+func newLineIndex(text string) *lineIndex {
+	starts := []int{0}
+	for i := 0; i < len(text); i++ {
+		if text[i] == '\n' {
+			starts = append(starts, i+1)
+		}
+	}
+	return &lineIndex{text: text, starts: starts}
+}
+
+// line returns the line of a byte offset
+func (l *lineIndex) line(offset int) int {
+	return sort.Search(len(l.starts), func(i int) bool { return l.starts[i] > offset }) - 1
+}
+
+// lineStart returns the byte offset of a line, 0 before the first line and
+// the end of the text after the last
+func (l *lineIndex) lineStart(line int) int {
+	if line < 0 {
+		return 0
+	}
+	if line >= len(l.starts) {
+		return len(l.text)
+	}
+	return l.starts[line]
+}
+
+// position returns the position of a byte offset
+func (l *lineIndex) position(offset int) Position {
+	line := l.line(offset)
+	character := 0
+	for _, r := range l.text[l.starts[line]:offset] {
+		character += utf16Len(r)
+	}
+	return Position{Line: line, Character: character}
+}
+
+// offset returns the byte offset of a position. Like language servers, it
+// takes characters past the end of a line as the end of the line, and lines
+// past the end of the text as the end of the text.
+func (l *lineIndex) offset(p Position) int {
+	if p.Line < 0 {
+		return 0
+	}
+	if p.Line >= len(l.starts) {
+		return len(l.text)
+	}
+	offset, end := l.starts[p.Line], len(l.text)
+	if p.Line+1 < len(l.starts) {
+		end = l.starts[p.Line+1] - 1 // The newline
+		if end > l.starts[p.Line] && l.text[end-1] == '\r' {
+			end--
+		}
+	}
+	for character := 0; offset < end && character < p.Character; {
+		r, size := utf8.DecodeRuneInString(l.text[offset:])
+		character += utf16Len(r)
+		offset += size
+	}
+	return offset
+}
+
+// utf16Len returns the number of UTF-16 code units encoding r
+func utf16Len(r rune) int {
+	if r >= 0x10000 {
+		return 2 // A surrogate pair
+	}
+	return 1
+}
@@ -0,0 +1,289 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// maxPartSize bounds the decompressed size of a part of a document
+const maxPartSize = 64 << 20
+
+// docxParts orders the text parts of a document: the body first, then
+// headers, footers, notes and comments
+var docxParts = []string{"word/document.xml", "word/header", "word/footer", "word/footnotes.xml", "word/endnotes.xml", "word/comments.xml", "docProps/core.xml"}
+
+// isDOCX reports whether data is a zip holding a Word document
+func isDOCX(data []byte) bool {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	return err == nil && IsDOCX(zr)
+}
+
+// IsDOCX reports whether a zip holds a Word document
+func IsDOCX(zr *zip.Reader) bool {
+	for _, f := range zr.File {
+		if f.Name == "word/document.xml" {
+			return true
+		}
+	}
+	return false
+}
+
+// partRank orders a part among docxParts, or returns -1 if it holds no text
+func partRank(name string) int {
+	for i, part := range docxParts {
+		if name == part {
+			return i
+		}
+		// Headers and footers are numbered, e.g. word/header1.xml
+		if !strings.HasSuffix(part, ".xml") && strings.HasPrefix(name, part) && strings.HasSuffix(name, ".xml") {
+			return i
+		}
+	}
+	return -1
+}
+
+// textParts returns the parts of a document holding text, in reading order
+func textParts(zr *zip.Reader) []*zip.File {
+	var parts []*zip.File
+	for _, f := range zr.File {
+		if partRank(f.Name) >= 0 {
+			parts = append(parts, f)
+		}
+	}
+	sort.SliceStable(parts, func(i, j int) bool {
+		ri, rj := partRank(parts[i].Name), partRank(parts[j].Name)
+		if ri != rj {
+			return ri < rj
+		}
+		return parts[i].Name < parts[j].Name
+	})
+	return parts
+}
+
+// readPart reads a part of a document, bounding its size
+func readPart(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(io.LimitReader(rc, maxPartSize+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > maxPartSize {
+		return nil, fmt.Errorf("%s exceeds %d MB", f.Name, maxPartSize>>20)
+	}
+	return data, nil
+}
+
+// docxRun is text within a paragraph. Runs from text elements can be
+// rewritten; the others stand for tabs and breaks.
+type docxRun struct {
+	text       string
+	start, end int  // Offsets of the text in the part, for rewritable runs
+	rewritable bool // From the character data of a text element
+}
+
+// docxParagraph is the runs of a paragraph
+type docxParagraph struct {
+	runs []docxRun
+}
+
+func (p *docxParagraph) text() string {
+	var b strings.Builder
+	for _, r := range p.runs {
+		b.WriteString(r.text)
+	}
+	return b.String()
+}
+
+// paragraphs reads the paragraphs of a part. In the document properties
+// every element is a paragraph of its own.
+func paragraphs(name string, data []byte) ([]*docxParagraph, error) {
+	leaves := strings.HasPrefix(name, "docProps/")
+	d := xml.NewDecoder(bytes.NewReader(data))
+	var done []*docxParagraph
+	var open []*docxParagraph // Paragraphs can nest, in text boxes
+	inText := 0
+	for {
+		offset := int(d.InputOffset())
+		tok, err := d.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %v", name, err)
+		}
+		var p *docxParagraph
+		if len(open) > 0 {
+			p = open[len(open)-1]
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			switch {
+			case leaves || t.Name.Local == "p":
+				open = append(open, &docxParagraph{})
+			case t.Name.Local == "t" || t.Name.Local == "delText" || t.Name.Local == "instrText":
+				inText++
+			case p != nil && t.Name.Local == "tab":
+				p.runs = append(p.runs, docxRun{text: "\t"})
+			case p != nil && (t.Name.Local == "br" || t.Name.Local == "cr"):
+				p.runs = append(p.runs, docxRun{text: "\n"})
+			}
+		case xml.EndElement:
+			switch {
+			case (leaves || t.Name.Local == "p") && p != nil:
+				open = open[:len(open)-1]
+				done = append(done, p)
+			case t.Name.Local == "t" || t.Name.Local == "delText" || t.Name.Local == "instrText":
+				inText--
+			}
+		case xml.CharData:
+			if p != nil && (inText > 0 || leaves) {
+				p.runs = append(p.runs, docxRun{text: string(t), start: offset, end: int(d.InputOffset()), rewritable: true})
+			}
+		}
+	}
+	return done, nil
+}
+
+// docxText extracts the text of a Word document, one line per paragraph
+func docxText(data []byte) (string, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return "", fmt.Errorf("failed to open document: %v", err)
+	}
+	var lines []string
+	for _, f := range textParts(zr) {
+		part, err := readPart(f)
+		if err != nil {
+			return "", err
+		}
+		paras, err := paragraphs(f.Name, part)
+		if err != nil {
+			return "", err
+		}
+		for _, p := range paras {
+			if text := p.text(); strings.TrimSpace(text) != "" {
+				lines = append(lines, text)
+			}
+		}
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
+// redactDOCX rewrites the text of a Word document with the edits redact
+// returns for each paragraph, keeping its formatting. It reports whether
+// anything changed.
+func redactDOCX(data []byte, redact func(text string) []Edit) ([]byte, bool, error) {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to open document: %v", err)
+	}
+
+	rewritten := map[string][]byte{}
+	for _, f := range textParts(zr) {
+		part, err := readPart(f)
+		if err != nil {
+			return nil, false, err
+		}
+		paras, err := paragraphs(f.Name, part)
+		if err != nil {
+			return nil, false, err
+		}
+		var changes []docxRun
+		for _, p := range paras {
+			changes = append(changes, rewriteRuns(p, redact(p.text()))...)
+		}
+		if len(changes) == 0 {
+			continue
+		}
+		sort.Slice(changes, func(i, j int) bool { return changes[i].start < changes[j].start })
+		var out bytes.Buffer
+		last := 0
+		for _, c := range changes {
+			out.Write(part[last:c.start])
+			xml.EscapeText(&out, []byte(c.text))
+			last = c.end
+		}
+		out.Write(part[last:])
+		rewritten[f.Name] = out.Bytes()
+	}
+	if len(rewritten) == 0 {
+		return data, false, nil
+	}
+
+	// Copy the other entries as they are, without recompressing them
+	var out bytes.Buffer
+	zw := zip.NewWriter(&out)
+	for _, f := range zr.File {
+		content, ok := rewritten[f.Name]
+		if !ok {
+			if err := zw.Copy(f); err != nil {
+				return nil, false, fmt.Errorf("failed to copy %s: %v", f.Name, err)
+			}
+			continue
+		}
+		header := f.FileHeader
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: header.Name, Method: zip.Deflate, Modified: header.Modified, Comment: header.Comment})
+		if err != nil {
+			return nil, false, fmt.Errorf("failed to write %s: %v", f.Name, err)
+		}
+		if _, err := w.Write(content); err != nil {
+			return nil, false, fmt.Errorf("failed to write %s: %v", f.Name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, false, fmt.Errorf("failed to write document: %v", err)
+	}
+	return out.Bytes(), true, nil
+}
+
+// rewriteRuns applies edits to the text of a paragraph and returns the
+// rewritable runs whose text changed. A value split across runs, as Word
+// does around spelling marks, is replaced in the first run and removed
+// from the others.
+func rewriteRuns(p *docxParagraph, edits []Edit) []docxRun {
+	if len(edits) == 0 {
+		return nil
+	}
+	sort.Slice(edits, func(i, j int) bool { return edits[i].Start < edits[j].Start })
+	placed := make([]bool, len(edits))
+	var changed []docxRun
+	offset := 0
+	for _, r := range p.runs {
+		runStart, runEnd := offset, offset+len(r.text)
+		offset = runEnd
+		if !r.rewritable {
+			continue
+		}
+		var b strings.Builder
+		pos := runStart
+		for i, e := range edits {
+			if e.End <= runStart || e.Start >= runEnd || e.Start < pos && e.End <= pos {
+				continue
+			}
+			if e.Start > pos {
+				b.WriteString(r.text[pos-runStart : e.Start-runStart])
+				pos = e.Start
+			}
+			if !placed[i] {
+				b.WriteString(e.Text)
+				placed[i] = true
+			}
+			pos = min(max(e.End, pos), runEnd)
+		}
+		b.WriteString(r.text[pos-runStart:])
+		if text := b.String(); text != r.text {
+			r.text = text
+			changed = append(changed, r)
+		}
+	}
+	return changed
+}
@@ -0,0 +1,99 @@
+// Package extract reads the text of files for scanning: plain text, PDF and
+// Word documents. Word documents can also be rewritten with their sensitive
+// values redacted, keeping their formatting.
+package extract
+
+import (
+	"bytes"
+	"errors"
+	"sort"
+	"unicode/utf8"
+)
+
+// Kinds of files
+const (
+	KindText = "text"
+	KindPDF  = "pdf"
+	KindDOCX = "docx"
+)
+
+var (
+	// ErrUnsupported is returned for binary files of other kinds
+	ErrUnsupported = errors.New("unsupported file type")
+
+	// ErrEncrypted is returned for encrypted PDF files
+	ErrEncrypted = errors.New("encrypted PDF")
+)
+
+// sniffLen is how much of a file is checked for NUL bytes to tell text
+// from binary data
+const sniffLen = 8000
+
+// Edit replaces the text between the byte offsets Start and End
+type Edit struct {
+	Start, End int
+	Text       string
+}
+
+// Kind returns the kind of a file from its content, or "" if it is neither
+// text nor a supported document
+func Kind(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte("%PDF-")):
+		return KindPDF
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")) && isDOCX(data):
+		return KindDOCX
+	case bytes.IndexByte(data[:min(len(data), sniffLen)], 0) < 0 && utf8.Valid(data):
+		return KindText
+	}
+	return ""
+}
+
+// Text returns the text of a file
+func Text(data []byte) (string, error) {
+	switch Kind(data) {
+	case KindText:
+		return string(data), nil
+	case KindPDF:
+		return pdfText(data)
+	case KindDOCX:
+		return docxText(data)
+	}
+	return "", ErrUnsupported
+}
+
+// Redact rewrites a text file or Word document with the edits redact
+// returns for its text, and reports whether anything changed. The text of
+// a Word document is passed a paragraph at a time. PDF files cannot be
+// rewritten and return ErrUnsupported.
+func Redact(data []byte, redact func(text string) []Edit) ([]byte, bool, error) {
+	switch Kind(data) {
+	case KindText:
+		edits := redact(string(data))
+		if len(edits) == 0 {
+			return data, false, nil
+		}
+		return []byte(Apply(string(data), edits)), true, nil
+	case KindDOCX:
+		return redactDOCX(data, redact)
+	}
+	return nil, false, ErrUnsupported
+}
+
+// Apply applies non-overlapping edits to text
+func Apply(text string, edits []Edit) string {
+	sorted := append([]Edit(nil), edits...)
+	sort.SliceStable(sorted, func(i, j int) bool { return sorted[i].Start < sorted[j].Start })
+	var b bytes.Buffer
+	last := 0
+	for _, e := range sorted {
+		if e.Start < last {
+			continue
+		}
+		b.WriteString(text[last:e.Start])
+		b.WriteString(e.Text)
+		last = e.End
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
@@ -0,0 +1,284 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+var emailPattern = regexp.MustCompile(`[\w.]+@[\w.]+\.\w+`)
+
+// redactEmails returns edits replacing email addresses
+func redactEmails(text string) []Edit {
+	var edits []Edit
+	for _, loc := range emailPattern.FindAllStringIndex(text, -1) {
+		edits = append(edits, Edit{Start: loc[0], End: loc[1], Text: "[EMAIL]"})
+	}
+	return edits
+}
+
+// deflate compresses data with zlib, as PDF streams with /FlateDecode are
+func deflate(data string) string {
+	var b bytes.Buffer
+	w := zlib.NewWriter(&b)
+	w.Write([]byte(data))
+	w.Close()
+	return b.String()
+}
+
+// buildPDF writes a PDF with the given objects, numbered from 1
+func buildPDF(objects ...string) []byte {
+	var b bytes.Buffer
+	b.WriteString("%PDF-1.7\n")
+	for i, obj := range objects {
+		fmt.Fprintf(&b, "%d 0 obj\n%s\nendobj\n", i+1, obj)
+	}
+	b.WriteString("trailer\n<< /Root 1 0 R >>\n%%EOF\n")
+	return b.Bytes()
+}
+
+// stream returns a PDF stream object with the given dictionary entries
+func stream(dict, data string) string {
+	return fmt.Sprintf("<< %s /Length %d >>\nstream\n%s\nendstream", dict, len(data), data)
+}
+
+// testPDF returns a PDF whose text is drawn with plain and ToUnicode fonts,
+// in a TJ array and through a form XObject, next to an inline image
+func testPDF() []byte {
+	content := deflate(`BT /F1 12 Tf 72 720 Td (Contact: alice@example.com) Tj
+0 -14 Td /F2 12 Tf <00010002> Tj
+0 -14 Td /F1 12 Tf [(Total) -300 (\(due\))] TJ ET
+q 10 0 0 10 0 0 cm BI /W 1 /H 1 /BPC 8 /CS /G ID ` + "\x00BT (x) Tj" + ` EI Q
+/Fm1 Do`)
+	toUnicode := `begincmap
+1 begincodespacerange <0000> <FFFF> endcodespacerange
+1 beginbfchar <0001> <0048> endbfchar
+1 beginbfrange <0002> <0002> <0069> endbfrange
+endcmap`
+	return buildPDF(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 /Resources << /Font << /F1 5 0 R /F2 6 0 R >> /XObject << /Fm1 8 0 R >> >> >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 4 0 R >>",
+		stream("/Filter /FlateDecode", content),
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica >>",
+		"<< /Type /Font /Subtype /Type0 /BaseFont /Custom /ToUnicode 7 0 R >>",
+		stream("", toUnicode),
+		stream("/Type /XObject /Subtype /Form /Resources << /Font << /F1 5 0 R >> >>", "BT /F1 10 Tf (Footer \\050c\\051) Tj ET"),
+	)
+}
+
+// TestPDFText tests that the text of a PDF is extracted line by line, from
+// every font and form XObject of the page
+func TestPDFText(t *testing.T) {
+	text, err := Text(testPDF())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Contact: alice@example.com\nHi\nTotal (due)\nFooter (c)"
+	if text != want {
+		t.Errorf("Got %q, want %q", text, want)
+	}
+}
+
+// TestPDFObjectStream tests that pages and fonts stored in a compressed
+// object stream are found
+func TestPDFObjectStream(t *testing.T) {
+	objects := "3 0 4 50 "
+	page := "<< /Type /Page /Parent 2 0 R /Contents 5 0 R >>"
+	font := "<< /Type /Font /Subtype /Type1 >>"
+	data := objects + page + strings.Repeat(" ", 50-len(page)) + font
+	pdf := buildPDF(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 /Resources << /Font << /F1 4 0 R >> >> >>",
+		"null",
+		"null",
+		stream("", "BT /F1 12 Tf (123-45-6789) Tj ET"),
+		stream(fmt.Sprintf("/Type /ObjStm /N 2 /First %d /Filter /FlateDecode", len(objects)), deflate(data)),
+	)
+	// Objects defined directly take precedence, so drop the placeholders
+	pdf = bytes.Replace(pdf, []byte("3 0 obj\nnull\nendobj\n4 0 obj\nnull\nendobj\n"), nil, 1)
+
+	text, err := Text(pdf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "123-45-6789" {
+		t.Errorf("Got %q", text)
+	}
+}
+
+// TestPDFEncrypted tests that an encrypted PDF is reported with ErrEncrypted
+func TestPDFEncrypted(t *testing.T) {
+	pdf := buildPDF("<< /Type /Catalog >>", "<< /Filter /Standard /V 2 /O <00> /U <00> >>")
+	if _, err := Text(pdf); !errors.Is(err, ErrEncrypted) {
+		t.Errorf("Got %v, want ErrEncrypted", err)
+	}
+}
+
+// buildDOCX writes a Word document with the given parts
+func buildDOCX(parts map[string]string) []byte {
+	var b bytes.Buffer
+	zw := zip.NewWriter(&b)
+	for _, name := range []string{"[Content_Types].xml", "word/document.xml", "word/header1.xml", "word/media/image1.png"} {
+		content, ok := parts[name]
+		if !ok {
+			continue
+		}
+		w, _ := zw.Create(name)
+		w.Write([]byte(content))
+	}
+	zw.Close()
+	return b.Bytes()
+}
+
+const documentXML = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>` +
+	`<w:p><w:r><w:rPr><w:b/></w:rPr><w:t>Mail alice@exa</w:t></w:r><w:proofErr w:type="spellStart"/><w:r><w:t xml:space="preserve">mple.com now &amp; then</w:t></w:r></w:p>` +
+	`<w:p><w:r><w:t>Name</w:t><w:tab/><w:t>Bob</w:t></w:r></w:p>` +
+	`<w:p/>` +
+	`</w:body></w:document>`
+
+// testDOCX returns a Word document with an address split across runs, a
+// header and an image
+func testDOCX() []byte {
+	return buildDOCX(map[string]string{
+		"[Content_Types].xml":   `<Types/>`,
+		"word/document.xml":     documentXML,
+		"word/header1.xml":      `<w:hdr xmlns:w="w"><w:p><w:r><w:t>From bob@example.org</w:t></w:r></w:p></w:hdr>`,
+		"word/media/image1.png": "\x89PNG\x00\x00",
+	})
+}
+
+// TestDOCXText tests that a Word document is recognized and its body and
+// headers are extracted, keeping tabs
+func TestDOCXText(t *testing.T) {
+	data := testDOCX()
+	if kind := Kind(data); kind != KindDOCX {
+		t.Fatalf("Got kind %q", kind)
+	}
+	text, err := Text(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Mail alice@example.com now & then\nName\tBob\nFrom bob@example.org"
+	if text != want {
+		t.Errorf("Got %q, want %q", text, want)
+	}
+}
+
+// TestRedactDOCX tests that matches spanning runs are redacted while
+// formatting and other entries of the document are kept
+func TestRedactDOCX(t *testing.T) {
+	redacted, changed, err := Redact(testDOCX(), redactEmails)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changed {
+		t.Fatal("Expected the document to change")
+	}
+	text, err := Text(redacted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "Mail [EMAIL] now & then\nName\tBob\nFrom [EMAIL]"
+	if text != want {
+		t.Errorf("Got %q, want %q", text, want)
+	}
+
+	// Formatting and other entries are kept
+	zr, err := zip.NewReader(bytes.NewReader(redacted), int64(len(redacted)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{}
+	for _, f := range zr.File {
+		rc, _ := f.Open()
+		content, _ := io.ReadAll(rc)
+		rc.Close()
+		files[f.Name] = string(content)
+	}
+	if !strings.Contains(files["word/document.xml"], `<w:r><w:rPr><w:b/></w:rPr><w:t>Mail [EMAIL]</w:t></w:r><w:proofErr w:type="spellStart"/><w:r><w:t xml:space="preserve"> now &amp; then</w:t></w:r>`) {
+		t.Errorf("Unexpected document %s", files["word/document.xml"])
+	}
+	if files["word/media/image1.png"] != "\x89PNG\x00\x00" {
+		t.Errorf("Image changed: %q", files["word/media/image1.png"])
+	}
+	if len(zr.File) != 4 {
+		t.Errorf("Got %d entries, want 4", len(zr.File))
+	}
+}
+
+// TestRedactDOCXUnchanged tests that a Word document without matches is
+// returned as is
+func TestRedactDOCXUnchanged(t *testing.T) {
+	data := buildDOCX(map[string]string{"word/document.xml": `<w:document xmlns:w="w"><w:body><w:p><w:r><w:t>Nothing here</w:t></w:r></w:p></w:body></w:document>`})
+	redacted, changed, err := Redact(data, redactEmails)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changed || !bytes.Equal(redacted, data) {
+		t.Error("Expected the document to be left alone")
+	}
+}
+
+// TestRewriteRuns tests that edits are spread over the runs they cover,
+// leaving tabs in place
+func TestRewriteRuns(t *testing.T) {
+	tests := []struct {
+		runs  []string
+		edits []Edit
+		want  []string
+	}{
+		{[]string{"ab", "cd", "ef"}, []Edit{{Start: 1, End: 5, Text: "X"}}, []string{"aX", "", "f"}},
+		{[]string{"abc"}, []Edit{{Start: 0, End: 1, Text: "X"}, {Start: 2, End: 3, Text: "Y"}}, []string{"XbY"}},
+		{[]string{"\t", "abc"}, []Edit{{Start: 0, End: 2, Text: "X"}}, []string{"\t", "Xbc"}},
+	}
+	for _, tt := range tests {
+		p := &docxParagraph{}
+		for i, r := range tt.runs {
+			p.runs = append(p.runs, docxRun{text: r, start: i, rewritable: r != "\t"})
+		}
+		for _, r := range rewriteRuns(p, tt.edits) {
+			for i := range p.runs {
+				if p.runs[i].start == r.start && p.runs[i].rewritable {
+					p.runs[i] = r
+				}
+			}
+		}
+		var got []string
+		for _, r := range p.runs {
+			got = append(got, r.text)
+		}
+		if strings.Join(got, "|") != strings.Join(tt.want, "|") {
+			t.Errorf("Runs %q with %v: got %q, want %q", tt.runs, tt.edits, got, tt.want)
+		}
+	}
+}
+
+// TestKind tests that content is told apart by its leading bytes
+func TestKind(t *testing.T) {
+	tests := []struct {
+		data string
+		want string
+	}{
+		{"plain text\n", KindText},
+		{"", KindText},
+		{"\x89PNG\r\n\x1a\n\x00\x00", ""},
+		{"PK\x03\x04\x14\x00\x00\x00", ""},
+		{"%PDF-1.4", KindPDF},
+	}
+	for _, tt := range tests {
+		if got := Kind([]byte(tt.data)); got != tt.want {
+			t.Errorf("Kind(%q) = %q, want %q", tt.data, got, tt.want)
+		}
+	}
+	if _, err := Text([]byte("\x00\x01")); !errors.Is(err, ErrUnsupported) {
+		t.Errorf("Got %v, want ErrUnsupported", err)
+	}
+}
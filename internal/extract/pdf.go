@@ -0,0 +1,798 @@
+package extract
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// PDF values. Strings are raw bytes, decoded by the font showing them.
+type (
+	pdfName    string
+	pdfKeyword string // Operators, delimiters, true, false and null
+	pdfString  []byte
+	pdfArray   []interface{}
+	pdfDict    map[pdfName]interface{}
+	pdfRef     struct{ num, gen int }
+	pdfStream  struct {
+		dict pdfDict
+		data []byte // Encoded
+	}
+)
+
+const (
+	// maxResolveDepth bounds following references to references
+	maxResolveDepth = 16
+
+	// maxFormDepth bounds forms drawing forms
+	maxFormDepth = 5
+
+	// maxDecodedStream bounds the size of a decoded stream
+	maxDecodedStream = 64 << 20
+)
+
+// objectHeader finds the start of indirect objects
+var objectHeader = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+
+// pdfLexer reads PDF tokens and objects
+type pdfLexer struct {
+	data []byte
+	pos  int
+}
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func isPDFDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
+
+// skipSpace skips whitespace and comments
+func (l *pdfLexer) skipSpace() {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		if c == '%' {
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+		} else if !isPDFSpace(c) {
+			return
+		}
+		l.pos++
+	}
+}
+
+// token reads the next token: a number, name, string or keyword. ok is
+// false at the end of the data.
+func (l *pdfLexer) token() (tok interface{}, ok bool) {
+	l.skipSpace()
+	if l.pos >= len(l.data) {
+		return nil, false
+	}
+	c := l.data[l.pos]
+	switch {
+	case c == '/':
+		l.pos++
+		var name []byte
+		for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+			if l.data[l.pos] == '#' && l.pos+2 < len(l.data) {
+				if b, err := strconv.ParseUint(string(l.data[l.pos+1:l.pos+3]), 16, 8); err == nil {
+					name = append(name, byte(b))
+					l.pos += 3
+					continue
+				}
+			}
+			name = append(name, l.data[l.pos])
+			l.pos++
+		}
+		return pdfName(name), true
+	case c == '(':
+		return l.literalString(), true
+	case c == '<' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '<':
+		l.pos += 2
+		return pdfKeyword("<<"), true
+	case c == '>' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '>':
+		l.pos += 2
+		return pdfKeyword(">>"), true
+	case c == '<':
+		end := bytes.IndexByte(l.data[l.pos:], '>')
+		if end < 0 {
+			end = len(l.data) - l.pos
+		}
+		hex := bytes.Join(bytes.Fields(l.data[l.pos+1:l.pos+end]), nil)
+		l.pos += end + 1
+		return pdfString(decodeHex(hex)), true
+	case isPDFDelimiter(c):
+		l.pos++
+		return pdfKeyword(c), true
+	}
+
+	start := l.pos
+	for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !isPDFDelimiter(l.data[l.pos]) {
+		l.pos++
+	}
+	word := string(l.data[start:l.pos])
+	if n, err := strconv.ParseFloat(word, 64); err == nil && strings.IndexAny(word[:1], "+-.0123456789") == 0 {
+		return n, true
+	}
+	return pdfKeyword(word), true
+}
+
+// literalString reads a string in parentheses, with escapes
+func (l *pdfLexer) literalString() pdfString {
+	l.pos++ // (
+	var s []byte
+	depth := 1
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			if depth--; depth == 0 {
+				return s
+			}
+		case '\\':
+			if l.pos >= len(l.data) {
+				return s
+			}
+			e := l.data[l.pos]
+			l.pos++
+			switch e {
+			case 'n':
+				c = '\n'
+			case 'r':
+				c = '\r'
+			case 't':
+				c = '\t'
+			case 'b':
+				c = '\b'
+			case 'f':
+				c = '\f'
+			case '\r':
+				if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+				continue
+			case '\n':
+				continue
+			default:
+				if e >= '0' && e <= '7' {
+					n := int(e - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						n = n*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					c = byte(n)
+				} else {
+					c = e
+				}
+			}
+		}
+		s = append(s, c)
+	}
+	return s
+}
+
+// decodeHex decodes the digits of a hex string, padding an odd one
+func decodeHex(hex []byte) []byte {
+	if len(hex)%2 == 1 {
+		hex = append(hex, '0')
+	}
+	out := make([]byte, 0, len(hex)/2)
+	for i := 0; i+1 < len(hex); i += 2 {
+		b, err := strconv.ParseUint(string(hex[i:i+2]), 16, 8)
+		if err != nil {
+			break
+		}
+		out = append(out, byte(b))
+	}
+	return out
+}
+
+// object reads a value, with arrays, dictionaries and references
+func (l *pdfLexer) object() (interface{}, bool) {
+	tok, ok := l.token()
+	if !ok {
+		return nil, false
+	}
+	switch t := tok.(type) {
+	case float64:
+		// A reference is two integers and R
+		save := l.pos
+		if gen, ok := l.token(); ok {
+			if g, isNum := gen.(float64); isNum {
+				if r, ok := l.token(); ok && r == pdfKeyword("R") {
+					return pdfRef{int(t), int(g)}, true
+				}
+			}
+		}
+		l.pos = save
+		return t, true
+	case pdfKeyword:
+		switch t {
+		case "[":
+			var arr pdfArray
+			for {
+				l.skipSpace()
+				if l.pos < len(l.data) && l.data[l.pos] == ']' {
+					l.pos++
+					return arr, true
+				}
+				v, ok := l.object()
+				if !ok {
+					return arr, true
+				}
+				arr = append(arr, v)
+			}
+		case "<<":
+			dict := pdfDict{}
+			for {
+				key, ok := l.token()
+				if !ok || key == pdfKeyword(">>") {
+					return dict, true
+				}
+				name, isName := key.(pdfName)
+				if !isName {
+					continue
+				}
+				if dict[name], ok = l.object(); !ok {
+					return dict, true
+				}
+			}
+		}
+	}
+	return tok, true
+}
+
+// pdfDocument is the objects of a PDF file
+type pdfDocument struct {
+	objects map[int]interface{}
+}
+
+// parsePDF reads the objects of a PDF file, scanning for them rather than
+// trusting the cross-reference table, which is often broken
+func parsePDF(data []byte) (*pdfDocument, error) {
+	doc := &pdfDocument{objects: map[int]interface{}{}}
+	for pos := 0; pos < len(data); {
+		loc := objectHeader.FindSubmatchIndex(data[pos:])
+		if loc == nil {
+			break
+		}
+		num, _ := strconv.Atoi(string(data[pos+loc[2] : pos+loc[3]]))
+		l := &pdfLexer{data: data, pos: pos + loc[1]}
+		obj, ok := l.object()
+		if !ok {
+			break
+		}
+		if dict, isDict := obj.(pdfDict); isDict {
+			save := l.pos
+			if tok, _ := l.token(); tok == pdfKeyword("stream") {
+				obj = l.stream(dict, doc)
+			} else {
+				l.pos = save
+			}
+		}
+		doc.objects[num] = obj // Later definitions are updates
+		pos = l.pos
+	}
+	if len(doc.objects) == 0 {
+		return nil, fmt.Errorf("no PDF objects found")
+	}
+
+	// Object streams hold objects too, compressed
+	var streams []int
+	for num, obj := range doc.objects {
+		if s, ok := obj.(*pdfStream); ok && s.dict["Type"] == pdfName("ObjStm") {
+			streams = append(streams, num)
+		}
+	}
+	sort.Ints(streams)
+	for _, num := range streams {
+		doc.readObjectStream(doc.objects[num].(*pdfStream))
+	}
+	return doc, nil
+}
+
+// stream reads the data of a stream after its keyword
+func (l *pdfLexer) stream(dict pdfDict, doc *pdfDocument) *pdfStream {
+	if l.pos < len(l.data) && l.data[l.pos] == '\r' {
+		l.pos++
+	}
+	if l.pos < len(l.data) && l.data[l.pos] == '\n' {
+		l.pos++
+	}
+	start := l.pos
+	if n, ok := dict["Length"].(float64); ok && n >= 0 && start+int(n) <= len(l.data) {
+		end := start + int(n)
+		rest := bytes.TrimLeft(l.data[end:min(end+32, len(l.data))], "\r\n \t")
+		if bytes.HasPrefix(rest, []byte("endstream")) {
+			l.pos = end
+			l.token() // endstream
+			return &pdfStream{dict: dict, data: l.data[start:end]}
+		}
+	}
+	end := bytes.Index(l.data[start:], []byte("endstream"))
+	if end < 0 {
+		l.pos = len(l.data)
+		return &pdfStream{dict: dict, data: l.data[start:]}
+	}
+	l.pos = start + end + len("endstream")
+	return &pdfStream{dict: dict, data: bytes.TrimRight(l.data[start:start+end], "\r\n")}
+}
+
+// readObjectStream adds the objects of an object stream not defined
+// directly
+func (doc *pdfDocument) readObjectStream(s *pdfStream) {
+	data, err := doc.decode(s)
+	if err != nil {
+		return
+	}
+	n, _ := doc.resolve(s.dict["N"]).(float64)
+	first, _ := doc.resolve(s.dict["First"]).(float64)
+	if int(first) > len(data) {
+		return
+	}
+	header := &pdfLexer{data: data[:int(first)]}
+	for i := 0; i < int(n); i++ {
+		num, ok1 := header.token()
+		offset, ok2 := header.token()
+		objNum, isNum := num.(float64)
+		objOffset, isOffset := offset.(float64)
+		if !ok1 || !ok2 || !isNum || !isOffset {
+			return
+		}
+		if _, defined := doc.objects[int(objNum)]; defined {
+			continue
+		}
+		l := &pdfLexer{data: data, pos: int(first) + int(objOffset)}
+		if obj, ok := l.object(); ok {
+			doc.objects[int(objNum)] = obj
+		}
+	}
+}
+
+// resolve follows references
+func (doc *pdfDocument) resolve(v interface{}) interface{} {
+	for i := 0; i < maxResolveDepth; i++ {
+		ref, ok := v.(pdfRef)
+		if !ok {
+			return v
+		}
+		v = doc.objects[ref.num]
+	}
+	return nil
+}
+
+// dict resolves v to a dictionary, the dictionary of a stream included
+func (doc *pdfDocument) dict(v interface{}) pdfDict {
+	switch d := doc.resolve(v).(type) {
+	case pdfDict:
+		return d
+	case *pdfStream:
+		return d.dict
+	}
+	return nil
+}
+
+// decode returns the decoded data of a stream
+func (doc *pdfDocument) decode(s *pdfStream) ([]byte, error) {
+	var filters []interface{}
+	switch f := doc.resolve(s.dict["Filter"]).(type) {
+	case pdfName:
+		filters = []interface{}{f}
+	case pdfArray:
+		filters = f
+	}
+	data := s.data
+	for _, f := range filters {
+		switch doc.resolve(f) {
+		case pdfName("FlateDecode"), pdfName("Fl"):
+			r, err := zlib.NewReader(bytes.NewReader(data))
+			if err != nil {
+				return nil, err
+			}
+			// Truncated streams are common; keep what was inflated
+			decoded, err := io.ReadAll(io.LimitReader(r, maxDecodedStream))
+			if err != nil && len(decoded) == 0 {
+				return nil, err
+			}
+			data = decoded
+		default:
+			return nil, fmt.Errorf("unsupported PDF filter %v", f)
+		}
+	}
+	return data, nil
+}
+
+// pdfText extracts the text of the pages of a PDF file, in order
+func pdfText(data []byte) (string, error) {
+	doc, err := parsePDF(data)
+	if err != nil {
+		return "", err
+	}
+	for _, obj := range doc.objects {
+		if d, ok := obj.(pdfDict); ok && d["Filter"] == pdfName("Standard") && d["O"] != nil {
+			return "", ErrEncrypted
+		}
+	}
+
+	var pages []string
+	for _, page := range doc.pages() {
+		resources := doc.dict(page.resources)
+		var content bytes.Buffer
+		contents := doc.resolve(page.dict["Contents"])
+		if arr, ok := contents.(pdfArray); ok {
+			for _, c := range arr {
+				if s, ok := doc.resolve(c).(*pdfStream); ok {
+					if data, err := doc.decode(s); err == nil {
+						content.Write(data)
+						content.WriteByte('\n')
+					}
+				}
+			}
+		} else if s, ok := contents.(*pdfStream); ok {
+			if data, err := doc.decode(s); err == nil {
+				content.Write(data)
+			}
+		}
+		w := &textWriter{}
+		doc.showText(w, content.Bytes(), resources, 0)
+		pages = append(pages, strings.TrimSpace(w.String()))
+	}
+	return strings.Join(pages, "\n\n"), nil
+}
+
+// pdfPage is a page and the resources it inherits
+type pdfPage struct {
+	dict      pdfDict
+	resources interface{}
+}
+
+// pages returns the pages of the document in order, or every page object
+// if the page tree cannot be found
+func (doc *pdfDocument) pages() []pdfPage {
+	var pages []pdfPage
+	visited := map[interface{}]bool{}
+	var walk func(node interface{}, resources interface{})
+	walk = func(node interface{}, resources interface{}) {
+		if ref, ok := node.(pdfRef); ok {
+			if visited[ref] {
+				return
+			}
+			visited[ref] = true
+		}
+		d := doc.dict(node)
+		if d == nil {
+			return
+		}
+		if r, ok := d["Resources"]; ok {
+			resources = r
+		}
+		if kids, ok := doc.resolve(d["Kids"]).(pdfArray); ok {
+			for _, kid := range kids {
+				walk(kid, resources)
+			}
+			return
+		}
+		if d["Type"] == pdfName("Page") {
+			pages = append(pages, pdfPage{dict: d, resources: resources})
+		}
+	}
+
+	nums := make([]int, 0, len(doc.objects))
+	for num := range doc.objects {
+		nums = append(nums, num)
+	}
+	sort.Ints(nums)
+	for _, num := range nums {
+		if d := doc.dict(pdfRef{num: num}); d != nil && d["Type"] == pdfName("Catalog") {
+			walk(d["Pages"], nil)
+			if len(pages) > 0 {
+				return pages
+			}
+		}
+	}
+	for _, num := range nums {
+		if d := doc.dict(pdfRef{num: num}); d != nil && d["Type"] == pdfName("Page") {
+			pages = append(pages, pdfPage{dict: d, resources: d["Resources"]})
+		}
+	}
+	return pages
+}
+
+// textWriter collects shown text, separating lines and words
+type textWriter struct {
+	buf []byte
+}
+
+func (w *textWriter) WriteString(s string) {
+	w.buf = append(w.buf, s...)
+}
+
+func (w *textWriter) String() string {
+	return string(w.buf)
+}
+
+// separate ends the current word or line, unless it is already ended
+func (w *textWriter) separate(sep byte) {
+	if len(w.buf) == 0 {
+		return
+	}
+	switch last := w.buf[len(w.buf)-1]; {
+	case last == '\n':
+	case last == ' ' && sep == '\n':
+		w.buf[len(w.buf)-1] = '\n'
+	case last != ' ':
+		w.buf = append(w.buf, sep)
+	}
+}
+
+// showText interprets a content stream, writing the text it shows
+func (doc *pdfDocument) showText(w *textWriter, content []byte, resources pdfDict, depth int) {
+	fonts := map[pdfName]*pdfFont{}
+	var font *pdfFont
+	fontNamed := func(name pdfName) *pdfFont {
+		if f, ok := fonts[name]; ok {
+			return f
+		}
+		f := doc.font(doc.dict(doc.dict(resources["Font"])[name]))
+		fonts[name] = f
+		return f
+	}
+
+	l := &pdfLexer{data: content}
+	var operands []interface{}
+	lineY, haveLine := 0.0, false
+	for {
+		tok, ok := l.object()
+		if !ok {
+			return
+		}
+		op, isOp := tok.(pdfKeyword)
+		if !isOp {
+			operands = append(operands, tok)
+			continue
+		}
+
+		number := func(i int) float64 {
+			if i < len(operands) {
+				n, _ := operands[i].(float64)
+				return n
+			}
+			return 0
+		}
+		switch op {
+		case "Tf":
+			if len(operands) >= 1 {
+				if name, ok := operands[0].(pdfName); ok {
+					font = fontNamed(name)
+				}
+			}
+		case "Tj":
+			if len(operands) >= 1 {
+				w.WriteString(font.decode(operands[len(operands)-1]))
+			}
+		case "'", "\"":
+			w.separate('\n')
+			if len(operands) >= 1 {
+				w.WriteString(font.decode(operands[len(operands)-1]))
+			}
+		case "TJ":
+			if len(operands) >= 1 {
+				arr, _ := operands[len(operands)-1].(pdfArray)
+				for _, item := range arr {
+					if n, ok := item.(float64); ok {
+						// Large negative adjustments stand for spaces
+						if n < -200 {
+							w.separate(' ')
+						}
+						continue
+					}
+					w.WriteString(font.decode(item))
+				}
+			}
+		case "Td", "TD":
+			if number(1) != 0 {
+				w.separate('\n')
+			} else if number(0) != 0 {
+				w.separate(' ')
+			}
+		case "Tm":
+			y := number(5)
+			if haveLine && y == lineY {
+				w.separate(' ')
+			} else {
+				w.separate('\n')
+			}
+			lineY, haveLine = y, true
+		case "T*":
+			w.separate('\n')
+		case "ET":
+			w.separate(' ')
+		case "Do":
+			if len(operands) >= 1 && depth < maxFormDepth {
+				name, _ := operands[0].(pdfName)
+				form, ok := doc.resolve(doc.dict(resources["XObject"])[name]).(*pdfStream)
+				if ok && form.dict["Subtype"] == pdfName("Form") {
+					if data, err := doc.decode(form); err == nil {
+						formResources := doc.dict(form.dict["Resources"])
+						if formResources == nil {
+							formResources = resources
+						}
+						w.separate('\n')
+						doc.showText(w, data, formResources, depth+1)
+					}
+				}
+			}
+		case "ID":
+			// Skip the binary data of an inline image
+			if end := bytes.Index(l.data[l.pos:], []byte("EI")); end >= 0 {
+				l.pos += end + 2
+			} else {
+				l.pos = len(l.data)
+			}
+		}
+		operands = operands[:0]
+	}
+}
+
+// pdfFont decodes the strings shown with a font
+type pdfFont struct {
+	codeLen   int            // Bytes per character code
+	toUnicode map[int]string // Text of character codes, from the ToUnicode map
+}
+
+// font reads the encoding of a font dictionary
+func (doc *pdfDocument) font(d pdfDict) *pdfFont {
+	f := &pdfFont{codeLen: 1}
+	if d == nil {
+		return f
+	}
+	if d["Subtype"] == pdfName("Type0") {
+		f.codeLen = 2
+	}
+	if s, ok := doc.resolve(d["ToUnicode"]).(*pdfStream); ok {
+		if data, err := doc.decode(s); err == nil {
+			f.toUnicode, f.codeLen = parseCMap(data, f.codeLen)
+		}
+	}
+	return f
+}
+
+// decode returns the text of a shown string
+func (f *pdfFont) decode(v interface{}) string {
+	s, ok := v.(pdfString)
+	if !ok {
+		return ""
+	}
+	if f == nil {
+		f = &pdfFont{codeLen: 1}
+	}
+	var b strings.Builder
+	for i := 0; i+f.codeLen <= len(s); i += f.codeLen {
+		code := 0
+		for _, c := range s[i : i+f.codeLen] {
+			code = code<<8 | int(c)
+		}
+		if text, ok := f.toUnicode[code]; ok {
+			b.WriteString(text)
+		} else if f.codeLen == 1 {
+			b.WriteRune(winAnsi(byte(code)))
+		}
+	}
+	return b.String()
+}
+
+// winAnsiHigh are the characters of WinAnsiEncoding from 0x80 to 0x9f
+var winAnsiHigh = []rune("€\u0081‚ƒ„…†‡ˆ‰Š‹Œ\u008dŽ\u008f\u0090‘’“”•–—˜™š›œ\u009džŸ")
+
+// winAnsi decodes a byte of the standard encoding of simple fonts
+func winAnsi(b byte) rune {
+	if b >= 0x80 && b <= 0x9f {
+		return winAnsiHigh[b-0x80]
+	}
+	return rune(b)
+}
+
+// parseCMap reads the character codes of a ToUnicode map and their text,
+// and the length of codes in bytes
+func parseCMap(data []byte, codeLen int) (map[int]string, int) {
+	m := map[int]string{}
+	l := &pdfLexer{data: data}
+	var operands []interface{}
+	section := ""
+	for {
+		tok, ok := l.object()
+		if !ok {
+			return m, codeLen
+		}
+		kw, isKeyword := tok.(pdfKeyword)
+		if !isKeyword {
+			operands = append(operands, tok)
+			continue
+		}
+		switch kw {
+		case "begincodespacerange", "beginbfchar", "beginbfrange":
+			section = string(kw)
+			operands = operands[:0]
+		case "endcodespacerange":
+			if len(operands) >= 1 {
+				if s, ok := operands[0].(pdfString); ok && len(s) > 0 {
+					codeLen = len(s)
+				}
+			}
+			section = ""
+		case "endbfchar":
+			for i := 0; i+1 < len(operands); i += 2 {
+				src, ok1 := operands[i].(pdfString)
+				dst, ok2 := operands[i+1].(pdfString)
+				if ok1 && ok2 {
+					m[codeOf(src)] = utf16String(dst)
+				}
+			}
+			section = ""
+		case "endbfrange":
+			for i := 0; i+2 < len(operands); i += 3 {
+				lo, ok1 := operands[i].(pdfString)
+				hi, ok2 := operands[i+1].(pdfString)
+				if !ok1 || !ok2 {
+					continue
+				}
+				start, end := codeOf(lo), codeOf(hi)
+				if end-start > 0xffff {
+					continue
+				}
+				switch dst := operands[i+2].(type) {
+				case pdfString:
+					base := []rune(utf16String(dst))
+					if len(base) == 0 {
+						continue
+					}
+					for code := start; code <= end; code++ {
+						text := append([]rune{}, base...)
+						text[len(text)-1] += rune(code - start)
+						m[code] = string(text)
+					}
+				case pdfArray:
+					for j, item := range dst {
+						if s, ok := item.(pdfString); ok && start+j <= end {
+							m[start+j] = utf16String(s)
+						}
+					}
+				}
+			}
+			section = ""
+		}
+		if section == "" {
+			operands = operands[:0]
+		}
+	}
+}
+
+// codeOf returns a character code from its bytes
+func codeOf(s []byte) int {
+	code := 0
+	for _, c := range s {
+		code = code<<8 | int(c)
+	}
+	return code
+}
+
+// utf16String decodes UTF-16BE text
+func utf16String(s []byte) string {
+	units := make([]uint16, len(s)/2)
+	for i := range units {
+		units[i] = binary.BigEndian.Uint16(s[2*i:])
+	}
+	return string(utf16.Decode(units))
+}
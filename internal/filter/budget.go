@@ -0,0 +1,156 @@
+package filter
+
+import (
+	"regexp"
+	"sync"
+	"time"
+)
+
+// Custom pattern time budget parameters
+const (
+	// DefaultPatternBudget is how long a custom pattern may scan one text
+	DefaultPatternBudget = 100 * time.Millisecond
+	// MaxPatternOverruns is how many consecutive overruns disable a pattern
+	MaxPatternOverruns = 3
+	// budgetChunkSize is how much text a budgeted detector scans between
+	// deadline checks
+	budgetChunkSize = 16 * 1024
+)
+
+// BudgetedDetector is implemented by detectors whose scans must finish
+// within a time budget, such as those running user-supplied patterns
+type BudgetedDetector interface {
+	Detector
+	// BudgetKey identifies the pattern for overrun tracking; empty if the
+	// detector is not budgeted
+	BudgetKey() string
+	// DetectWithin returns the matches found before deadline and whether
+	// the whole text was scanned
+	DetectWithin(text string, deadline time.Time) ([]Match, bool)
+}
+
+// Budget enforces the time budget of budgeted detectors and tracks
+// consecutive overruns per pattern across configuration reloads
+type Budget struct {
+	limit       time.Duration
+	maxOverruns int
+
+	mu        sync.Mutex
+	overruns  map[string]int
+	onDisable func(key string)
+}
+
+// NewBudget creates a budget allowing limit per scan and disabling a pattern
+// after maxOverruns consecutive overruns
+func NewBudget(limit time.Duration, maxOverruns int) *Budget {
+	return &Budget{
+		limit:       limit,
+		maxOverruns: maxOverruns,
+		overruns:    make(map[string]int),
+	}
+}
+
+// OnDisable registers the callback invoked with a pattern's budget key when
+// it exceeds the budget maxOverruns times in a row. It runs in its own
+// goroutine.
+func (b *Budget) OnDisable(callback func(key string)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.onDisable = callback
+}
+
+// Limit returns the time allowed per scan
+func (b *Budget) Limit() time.Duration {
+	if b == nil {
+		return DefaultPatternBudget
+	}
+	return b.limit
+}
+
+// record notes whether a scan finished within budget
+func (b *Budget) record(key string, complete bool) {
+	if b == nil {
+		return
+	}
+
+	b.mu.Lock()
+	if complete {
+		delete(b.overruns, key)
+		b.mu.Unlock()
+		return
+	}
+	b.overruns[key]++
+	disable := b.overruns[key] >= b.maxOverruns
+	if disable {
+		delete(b.overruns, key)
+	}
+	callback := b.onDisable
+	b.mu.Unlock()
+
+	if disable && callback != nil {
+		// Disabling writes the configuration, which must not hold up or
+		// deadlock the scan that overran
+		go callback(key)
+	}
+}
+
+// WithBudget marks the detector as running a user-supplied pattern, so its
+// scans are time-limited and overruns are tracked under key. An empty key
+// leaves the detector unbudgeted.
+func (d *RegexDetector) WithBudget(key string) *RegexDetector {
+	d.budgetKey = key
+	return d
+}
+
+// BudgetKey returns the key overruns are tracked under
+func (d *RegexDetector) BudgetKey() string {
+	return d.budgetKey
+}
+
+// DetectWithin scans text in chunks, checking deadline between chunks, and
+// returns the matches found and whether the whole text was scanned
+func (d *RegexDetector) DetectWithin(text string, deadline time.Time) ([]Match, bool) {
+	var matches []Match
+	last := 0
+	for pos := 0; pos < len(text); {
+		end := runeBoundary(text, pos+budgetChunkSize, true)
+		windowStart := runeBoundary(text, pos-ChunkOverlap, false)
+		windowEnd := runeBoundary(text, end+ChunkOverlap, true)
+
+		window := text[windowStart:windowEnd]
+		for _, loc := range d.find(window) {
+			start, stop := loc[0]+windowStart, loc[1]+windowStart
+			if start == stop || start < pos || start < last || start >= end {
+				continue
+			}
+			matches = append(matches, Match{
+				Type:        d.name,
+				Start:       start,
+				End:         stop,
+				Text:        text[start:stop],
+				Replacement: d.replacementFor(window, loc),
+				Action:      d.action,
+				Confidence:  d.confidence(text[start:stop]),
+			})
+			last = stop
+		}
+
+		if last > end {
+			end = last
+		}
+		pos = end
+		if pos < len(text) && time.Now().After(deadline) {
+			return matches, false
+		}
+	}
+	return matches, true
+}
+
+// budgetKey returns field if compiled is the user's custom pattern rather
+// than the built-in fallback
+func budgetKey(field, custom string, compiled *regexp.Regexp) string {
+	if custom == "" || compiled.String() != custom {
+		return ""
+	}
+	return field
+}
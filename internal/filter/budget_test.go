@@ -0,0 +1,108 @@
+package filter
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+)
+
+// TestRegexDetector_DetectWithin tests budgeted scanning against a plain scan
+func TestRegexDetector_DetectWithin(t *testing.T) {
+	d := NewRegexDetector("word", regexp.MustCompile(`secret-\d+`), "[X]")
+	text := strings.Repeat("filler text secret-42 ", 5000)
+
+	want := d.Detect(text)
+	got, complete := d.DetectWithin(text, time.Now().Add(time.Minute))
+	if !complete {
+		t.Fatal("Expected the scan to complete within a generous deadline")
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %d matches, got %d", len(want), len(got))
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("Match %d: expected %+v, got %+v", i, want[i], got[i])
+		}
+	}
+
+	partial, complete := d.DetectWithin(text, time.Now().Add(-time.Second))
+	if complete || len(partial) == 0 || len(partial) >= len(want) {
+		t.Errorf("Expected a partial scan after the deadline, got %d of %d matches (complete %v)", len(partial), len(want), complete)
+	}
+}
+
+// TestBudget_DisablesAfterConsecutiveOverruns tests overrun tracking
+func TestBudget_DisablesAfterConsecutiveOverruns(t *testing.T) {
+	b := NewBudget(time.Millisecond, 3)
+	disabled := make(chan string, 10)
+	b.OnDisable(func(key string) { disabled <- key })
+
+	b.record("p", false)
+	b.record("p", false)
+	b.record("p", true) // A scan within budget resets the count
+	b.record("p", false)
+	b.record("p", false)
+	select {
+	case key := <-disabled:
+		t.Fatalf("Expected no disable before %d consecutive overruns, got %s", 3, key)
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	b.record("p", false)
+	select {
+	case key := <-disabled:
+		if key != "p" {
+			t.Errorf("Expected p to be disabled, got %s", key)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected p to be disabled")
+	}
+	if len(disabled) != 0 {
+		t.Errorf("Expected p to be disabled once, got %d more", len(disabled))
+	}
+}
+
+// TestEngine_PatternBudget tests that only custom patterns are budgeted and
+// that overruns are reported through the engine
+func TestEngine_PatternBudget(t *testing.T) {
+	cfg := config.Config{
+		DetectEmails:       true,
+		EmailReplacement:   "[EMAIL]",
+		CustomEmailPattern: `[a-z]+@corp\.example`,
+		DetectSSNs:         true,
+		SSNReplacement:     "[SSN]",
+	}
+
+	e := NewEngine(cfg)
+	e.budget.limit = -time.Second // Every budgeted scan overruns
+	e.Reload(cfg)
+
+	disabled := make(chan string, 10)
+	e.OnPatternDisabled(func(field string) { disabled <- field })
+
+	text := strings.Repeat("pad ", 10000) + "bob@corp.example 123-45-6789"
+	for i := 0; i < MaxPatternOverruns; i++ {
+		filtered, _, _ := e.Filter(text)
+		if !strings.HasSuffix(filtered, "[EMAIL] [SSN]") {
+			t.Fatal("Expected overrunning scans to be completed rather than cut short")
+		}
+	}
+	select {
+	case field := <-disabled:
+		if field != "custom_email_pattern" {
+			t.Errorf("Expected custom_email_pattern to be disabled, got %s", field)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected custom_email_pattern to be disabled")
+	}
+
+	// A disabled custom pattern falls back to the built-in one
+	cfg.DisabledPatterns = []string{"custom_email_pattern"}
+	e.Reload(cfg)
+	if filtered, _, _ := e.Filter("mail a@b.io"); filtered != "mail [EMAIL]" {
+		t.Errorf("Expected the built-in email pattern after disabling, got %q", filtered)
+	}
+}
@@ -0,0 +1,101 @@
+package filter
+
+import (
+	"context"
+	"strings"
+	"unicode/utf8"
+)
+
+// Chunked scanning parameters
+const (
+	// DefaultChunkSize is the number of bytes FilterChunked scans per step
+	DefaultChunkSize = 64 * 1024
+	// ChunkOverlap is the context scanned on both sides of a chunk so that
+	// matches crossing a chunk boundary are still found. Matches longer than
+	// this may be missed.
+	ChunkOverlap = 1024
+)
+
+// FilterChunked filters text like Filter but scans it in chunks of
+// chunkSize bytes, so the work per step stays bounded and ctx is honoured
+// between steps and detectors. Detectors needing the whole document, such
+// as Terraform state, only see one chunk, so FilterContext suits documents.
+// If ctx ends before the scan completes, the context error is returned and
+// the text should be treated as unfiltered.
+func (ds *DetectorSet) FilterChunked(ctx context.Context, text string, chunkSize int) (string, bool, ReplacementSummary, error) {
+	if !ds.Enabled() || text == "" {
+		return text, false, ReplacementSummary{}, nil
+	}
+	if chunkSize <= 0 {
+		chunkSize = DefaultChunkSize
+	}
+
+	s := getScratch()
+	defer putScratch(s)
+
+	var b strings.Builder
+	b.Grow(len(text))
+	summary := ReplacementSummary{}
+
+	last := 0 // End of the output written so far
+	for pos := 0; pos < len(text); {
+		if err := ctx.Err(); err != nil {
+			return text, false, ReplacementSummary{}, err
+		}
+
+		end := runeBoundary(text, pos+chunkSize, true)
+		windowStart := runeBoundary(text, pos-ChunkOverlap, false)
+		windowEnd := runeBoundary(text, end+ChunkOverlap, true)
+
+		// Keep matches starting in this chunk; earlier ones were handled by
+		// the previous chunk and later ones belong to the next
+		found, err := ds.matchesContext(ctx, s, text[windowStart:windowEnd], pos-windowStart)
+		if err != nil {
+			return text, false, ReplacementSummary{}, err
+		}
+		var accepted []Match
+		for _, m := range found {
+			m.Start += windowStart
+			m.End += windowStart
+			if m.Start < end {
+				accepted = append(accepted, m)
+			}
+		}
+
+		for _, m := range accepted {
+			b.WriteString(text[last:m.Start])
+			b.WriteString(m.Replacement)
+			last = m.End
+		}
+		summary.Replacements = append(summary.Replacements, summarize(accepted).Replacements...)
+
+		// A match may run past the chunk; resume after it
+		if last > end {
+			end = last
+		}
+		pos = end
+	}
+	b.WriteString(text[last:])
+
+	filtered := b.String()
+	return filtered, filtered != text, summary, nil
+}
+
+// runeBoundary clamps i to text and moves it to the start of a rune,
+// forward or backward
+func runeBoundary(text string, i int, forward bool) int {
+	if i <= 0 {
+		return 0
+	}
+	for i < len(text) && !utf8.RuneStart(text[i]) {
+		if forward {
+			i++
+		} else {
+			i--
+		}
+	}
+	if i > len(text) {
+		return len(text)
+	}
+	return i
+}
@@ -0,0 +1,134 @@
+package filter
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+	"github.com/happytaoer/prompt-security/internal/patterns"
+)
+
+// TestFilterChunked_MatchesFilter tests that chunked scanning gives the same
+// result as a single pass, including matches that straddle chunk boundaries
+func TestFilterChunked_MatchesFilter(t *testing.T) {
+	cfg := config.Config{
+		DetectEmails:     true,
+		EmailReplacement: "[EMAIL]",
+		DetectSSNs:       true,
+		SSNReplacement:   "[SSN]",
+		StringMatchPatterns: []config.StringMatchPattern{
+			{Name: "company", Pattern: "Acme", Enabled: true, Replacement: "[COMPANY]"},
+		},
+	}
+	ds := NewDetectorSet(cfg)
+
+	var b strings.Builder
+	for i := 0; i < 200; i++ {
+		b.WriteString("line ")
+		b.WriteString(strings.Repeat("é", i%7))
+		b.WriteString(" user")
+		b.WriteString(strings.Repeat("x", i%5))
+		b.WriteString("@example.com Acme 123-45-6789\n")
+	}
+	text := b.String()
+
+	want, wantChanged, wantSummary := ds.Filter(text)
+	for _, chunkSize := range []int{1, 7, 64, 1000, len(text) * 2} {
+		got, changed, summary, err := ds.FilterChunked(context.Background(), text, chunkSize)
+		if err != nil {
+			t.Fatalf("chunk %d: unexpected error %v", chunkSize, err)
+		}
+		if got != want || changed != wantChanged {
+			t.Errorf("chunk %d: output differs from single pass", chunkSize)
+		}
+		if len(summary.Replacements) != len(wantSummary.Replacements) {
+			t.Errorf("chunk %d: expected %d replacements, got %d", chunkSize, len(wantSummary.Replacements), len(summary.Replacements))
+		}
+	}
+}
+
+// TestFilterChunked_Cancelled tests that a cancelled scan leaves the text unfiltered
+func TestFilterChunked_Cancelled(t *testing.T) {
+	ds := NewDetectorSet(config.Config{DetectEmails: true, EmailReplacement: "[EMAIL]"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	text := "a@b.com"
+	got, changed, _, err := ds.FilterChunked(ctx, text, 1)
+	if err != context.Canceled {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+	if changed || got != text {
+		t.Errorf("Expected unfiltered text, got %q", got)
+	}
+}
+
+// cancellingDetector cancels a context when it runs
+type cancellingDetector struct {
+	cancel context.CancelFunc
+}
+
+func (d cancellingDetector) Name() string { return "cancelling" }
+
+func (d cancellingDetector) Detect(text string) []Match {
+	d.cancel()
+	return nil
+}
+
+// TestSensitiveDataContext tests that filtering with a context matches
+// SensitiveData and stops between detectors once the context ends
+func TestSensitiveDataContext(t *testing.T) {
+	cfg := config.Config{DetectEmails: true, EmailReplacement: "[EMAIL]"}
+	got, changed, summary, err := SensitiveDataContext(context.Background(), "mail a@b.com", cfg)
+	if err != nil || !changed || got != "mail [EMAIL]" || len(summary.Replacements) != 1 {
+		t.Errorf("Expected the email replaced, got %q, %v, %v", got, changed, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := NewRegistry()
+	r.Register("first", 1, func(cfg config.Config, compiled patterns.Set) []Detector {
+		return []Detector{cancellingDetector{cancel}}
+	})
+	r.Register("second", 2, func(cfg config.Config, compiled patterns.Set) []Detector {
+		return []Detector{NewStringDetector("second", "MARK", "[M]")}
+	})
+	ds := r.NewDetectorSet(config.Config{})
+	if got, _, _, err := ds.FilterContext(ctx, "a MARK b"); err != context.Canceled || got != "a MARK b" {
+		t.Errorf("Expected context.Canceled and unfiltered text, got %q, %v", got, err)
+	}
+}
+
+// documentDetector matches from BEGIN to END, which only a detector seeing
+// the whole document can find when they are far apart
+type documentDetector struct{}
+
+func (documentDetector) Name() string { return "document" }
+
+func (documentDetector) Detect(text string) []Match {
+	start, end := strings.Index(text, "BEGIN"), strings.LastIndex(text, "END")
+	if start < 0 || end < start {
+		return nil
+	}
+	return []Match{{Type: "document", Start: start, End: end + 3, Text: text[start : end+3], Replacement: "[DOC]"}}
+}
+
+// TestFilterContext_WholeDocument tests that FilterContext scans long texts
+// at once, unlike FilterChunked
+func TestFilterContext_WholeDocument(t *testing.T) {
+	r := NewRegistry()
+	r.Register("document", 1, func(cfg config.Config, compiled patterns.Set) []Detector {
+		return []Detector{documentDetector{}}
+	})
+	ds := r.NewDetectorSet(config.Config{})
+	text := "x BEGIN" + strings.Repeat(" ", 2*DefaultChunkSize) + "END y"
+
+	got, changed, summary, err := ds.FilterContext(context.Background(), text)
+	if err != nil || !changed || got != "x [DOC] y" || len(summary.Replacements) != 1 {
+		t.Errorf("Expected the whole document matched, got %d bytes, %v, %v", len(got), changed, err)
+	}
+	if got, _, _, _ := ds.FilterChunked(context.Background(), text, DefaultChunkSize); got != text {
+		t.Errorf("Expected chunks to miss the document, got %d bytes", len(got))
+	}
+}
@@ -0,0 +1,180 @@
+package filter
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Confidence of built-in matches that fail a plausibility check. Matches
+// that pass, and matches of detectors without a check, are certain.
+const (
+	confidenceUnformattedPhone = 0.5 // Digits only, could be any number
+	confidenceVersionLikeIP    = 0.4 // Single-digit octets, like a version
+	confidenceVersionLikeMAC   = 0.4 // Dotted digits only, like a version
+	confidenceInvalidCard      = 0.3 // Fails the Luhn checksum
+	confidenceInvalidSSN       = 0.3 // Area, group or serial never issued
+	confidenceNumericPlusCode  = 0.3 // Digits only, like a sum
+	confidenceNullIsland       = 0.3 // 0, 0, a default rather than a fix
+	confidenceLettersOnlySeed  = 0.4 // Base32 letters only, like a word
+	confidenceWordPassword     = 0.5 // A lowercase word after "is", like prose
+	confidencePlaceholder      = 0.2 // A variable or placeholder, like $TOKEN
+)
+
+// Scorer rates how likely a match is to be sensitive, from 0 to 1
+type Scorer func(text string) float64
+
+// WithConfidence sets how the detector's matches are scored
+func (d *RegexDetector) WithConfidence(score Scorer) *RegexDetector {
+	d.score = score
+	return d
+}
+
+// confidence returns the score of a match, 1 without a scorer
+func (d *RegexDetector) confidence(text string) float64 {
+	if d.score == nil {
+		return 1
+	}
+	return d.score(text)
+}
+
+// digits returns the ASCII digits of text
+func digits(text string) string {
+	var b strings.Builder
+	for i := 0; i < len(text); i++ {
+		if c := text[i]; c >= '0' && c <= '9' {
+			b.WriteByte(c)
+		}
+	}
+	return b.String()
+}
+
+// cardConfidence scores a credit card number by its Luhn checksum
+func cardConfidence(text string) float64 {
+	if luhn(digits(text)) {
+		return 1
+	}
+	return confidenceInvalidCard
+}
+
+// luhn reports whether a digit string passes the Luhn checksum
+func luhn(number string) bool {
+	if number == "" {
+		return false
+	}
+	sum := 0
+	double := false
+	for i := len(number) - 1; i >= 0; i-- {
+		d := int(number[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// ssnConfidence scores an SSN by whether its parts were ever issued, and a
+// Chinese resident identity card number by its check digit
+func ssnConfidence(text string) float64 {
+	if len(text) == 18 {
+		if chineseIDValid(text) {
+			return 1
+		}
+		return confidenceInvalidSSN
+	}
+	d := digits(text)
+	if len(d) != 9 {
+		return 1 // Not a US SSN, e.g. another locale's national ID
+	}
+	area, group, serial := d[:3], d[3:5], d[5:]
+	if area == "000" || area == "666" || area[0] == '9' || group == "00" || serial == "0000" {
+		return confidenceInvalidSSN
+	}
+	return 1
+}
+
+// chineseIDValid reports whether an 18 character resident identity card
+// number passes its ISO 7064 MOD 11-2 check digit
+func chineseIDValid(id string) bool {
+	sum := 0
+	for i := 0; i < 17; i++ {
+		c := id[i]
+		if c < '0' || c > '9' {
+			return false
+		}
+		weight := 1 << (17 - i) % 11
+		sum += int(c-'0') * weight
+	}
+	check := "10X98765432"[sum%11]
+	last := id[17]
+	if last == 'x' {
+		last = 'X'
+	}
+	return last == check
+}
+
+// ipv4Confidence scores an IPv4 address, doubting version-like ones
+func ipv4Confidence(text string) float64 {
+	for _, octet := range strings.Split(text, ".") {
+		if n, err := strconv.Atoi(octet); err != nil || n > 9 {
+			return 1
+		}
+	}
+	return confidenceVersionLikeIP
+}
+
+// locationConfidence scores a location, doubting plus codes without letters
+// and the origin
+func locationConfidence(text string) float64 {
+	if plus := strings.IndexByte(text, '+'); plus > 0 && strings.Trim(text[:plus]+text[plus+1:], "0123456789") == "" {
+		return confidenceNumericPlusCode
+	}
+	if d := digits(text); d != "" && strings.Trim(d, "0") == "" {
+		return confidenceNullIsland
+	}
+	return 1
+}
+
+// credentialConfidence scores a credential, doubting variables and
+// placeholders such as $TOKEN, ${API_KEY}, <token> or {{secret}}
+func credentialConfidence(text string) float64 {
+	if strings.HasPrefix(text, "$") || strings.HasPrefix(text, "<") || strings.HasPrefix(text, "{{") || strings.Trim(text, "xX*.") == "" {
+		return confidencePlaceholder
+	}
+	return 1
+}
+
+// passwordConfidence scores a password given in prose, doubting lowercase
+// words after a connector such as "is", which often describe the password
+// rather than give it; after a colon or an equals sign they are certain
+func passwordConfidence(text string, colon bool) float64 {
+	if c := credentialConfidence(text); c < 1 || colon {
+		return c
+	}
+	if strings.ToLower(text) == text && strings.IndexFunc(text, func(r rune) bool { return !unicode.IsLetter(r) }) < 0 {
+		return confidenceWordPassword
+	}
+	return 1
+}
+
+// cloudIDConfidence scores an infrastructure identifier, doubting the nil
+// GUID and documentation placeholders such as my-project or your-project-id
+func cloudIDConfidence(text string) float64 {
+	if strings.Trim(text, "0-") == "" || strings.HasPrefix(text, "my-project") || strings.HasPrefix(text, "your-") || strings.Contains(text, "example") {
+		return confidencePlaceholder
+	}
+	return 1
+}
+
+// phoneConfidence scores a phone number, doubting bare digit runs
+func phoneConfidence(text string) float64 {
+	if strings.ContainsAny(text, "+()-. /") {
+		return 1
+	}
+	return confidenceUnformattedPhone
+}
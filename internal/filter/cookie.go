@@ -0,0 +1,87 @@
+package filter
+
+import (
+	"strings"
+
+	"github.com/happytaoer/prompt-security/internal/patterns"
+)
+
+// SensitiveTypeCookie is the type of cookie values
+const SensitiveTypeCookie = "cookie"
+
+// CookieDetector detects the values of cookies, one match per cookie so that
+// their names are kept: every cookie of a Cookie header, the cookie of a
+// Set-Cookie header but not its attributes, and well-known session and CSRF
+// cookies anywhere else
+type CookieDetector struct {
+	replacement string
+}
+
+// NewCookieDetector creates a detector replacing cookie values with
+// replacement
+func NewCookieDetector(replacement string) *CookieDetector {
+	return &CookieDetector{replacement: replacement}
+}
+
+// Name returns the detector's sensitive data type
+func (d *CookieDetector) Name() string {
+	return SensitiveTypeCookie
+}
+
+// Detect returns the cookie values in text
+func (d *CookieDetector) Detect(text string) []Match {
+	var spans [][2]int
+	header := patterns.CookieHeaderPattern
+	set, cookies := 2*header.SubexpIndex("set"), 2*header.SubexpIndex("cookies")
+	for _, loc := range header.FindAllStringSubmatchIndex(text, -1) {
+		spans = append(spans, cookieValues(text, loc[cookies], loc[cookies+1], loc[set] >= 0)...)
+	}
+
+	session := patterns.SessionCookiePattern
+	for _, loc := range session.FindAllStringSubmatchIndex(text, -1) {
+		for g, name := range session.SubexpNames() {
+			if name == "value" && loc[2*g] >= 0 {
+				spans = append(spans, [2]int{loc[2*g], loc[2*g+1]})
+			}
+		}
+	}
+
+	// A session cookie inside a header is found twice
+	matches := make([]Match, 0, len(spans))
+	for _, s := range spans {
+		matches = append(matches, Match{
+			Type:        SensitiveTypeCookie,
+			Start:       s[0],
+			End:         s[1],
+			Text:        text[s[0]:s[1]],
+			Replacement: d.replacement,
+		})
+	}
+	return disjoint(matches)
+}
+
+// cookieValues returns the spans of the values of the name=value pairs of
+// text[start:end], separated by semicolons. Only the first pair of a
+// Set-Cookie header is a cookie, the others are attributes such as Path.
+func cookieValues(text string, start, end int, setCookie bool) [][2]int {
+	var spans [][2]int
+	for offset := start; offset < end; {
+		pair := text[offset:end]
+		if i := strings.IndexByte(pair, ';'); i >= 0 {
+			pair = pair[:i]
+		}
+		if eq := strings.IndexByte(pair, '='); eq > 0 {
+			value := pair[eq+1:]
+			trimmed := strings.TrimSpace(value)
+			if trimmed != "" {
+				s := offset + eq + 1 + strings.Index(value, trimmed)
+				spans = append(spans, [2]int{s, s + len(trimmed)})
+			}
+		}
+		if setCookie {
+			break
+		}
+		offset += len(pair) + 1
+	}
+	return spans
+}
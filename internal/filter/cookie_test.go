@@ -0,0 +1,37 @@
+package filter
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestCookieDetector tests that the values of Cookie and Set-Cookie headers,
+// cookie strings and session IDs in URLs are found, but not cookie
+// attributes or names merely starting like session cookies
+func TestCookieDetector(t *testing.T) {
+	d := NewCookieDetector("[COOKIE]")
+
+	tests := []struct {
+		text string
+		want string // Matched values
+	}{
+		{"Cookie: JSESSIONID=ABC123; theme=dark; empty=", "ABC123|dark"},
+		{"Set-Cookie: sid=s%3Axyz; Path=/; HttpOnly; Secure", "s%3Axyz"},
+		{`curl -H 'cookie: a=1;b= 2 ' https://example.com`, "1|2"},
+		{`{"Cookie": "csrftoken=tok"}`, "tok"},
+		{"GET /app;jsessionid=0F3A9B?x=1 HTTP/1.1", "0F3A9B"},
+		{"document.cookie = '__Host-session=v1; path=/'", "v1"},
+		{"PHPSESSID=abc&next=/home", "abc"},
+		{"The cookie jar: empty", ""},
+		{"sessionidentifier=abc", ""},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, m := range d.Detect(tt.text) {
+			got = append(got, m.Text)
+		}
+		if s := strings.Join(got, "|"); s != tt.want {
+			t.Errorf("Detect(%q) = %q, want %q", tt.text, s, tt.want)
+		}
+	}
+}
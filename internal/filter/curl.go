@@ -0,0 +1,360 @@
+package filter
+
+import (
+	"net/url"
+	"strings"
+
+	"github.com/happytaoer/prompt-security/internal/patterns"
+)
+
+// SensitiveTypeCurl is the type of credentials found in curl commands
+const SensitiveTypeCurl = "curl"
+
+// Placeholders of sanitized curl commands. They are plain words, so the
+// command stays runnable whether they end up quoted or not.
+const (
+	curlTokenPlaceholder    = "REDACTED_TOKEN"
+	curlPasswordPlaceholder = "REDACTED_PASSWORD"
+	curlCookiePlaceholder   = "REDACTED_COOKIE"
+)
+
+// curlFlags maps the curl options whose argument may carry credentials to
+// how the argument is sanitized
+var curlFlags = map[string]string{
+	"-H": "header", "--header": "header", "--proxy-header": "header",
+	"-u": "user", "--user": "user", "-U": "user", "--proxy-user": "user",
+	"-E": "user", "--cert": "user", // cert:password
+	"--oauth2-bearer": "token", "-b": "cookie", "--cookie": "cookie",
+	"-d": "data", "--data": "data", "--data-raw": "data", "--data-binary": "data", "--data-ascii": "data",
+	"--data-urlencode": "data", "--json": "data", "-F": "data", "--form": "data", "--form-string": "data",
+	"--url": "url", "-x": "url", "--proxy": "url",
+}
+
+// curlShortArgs are the curl short options taking an argument, which ends a
+// cluster of short options, as in -sSXPOST
+const curlShortArgs = "AbcCdDeEFHKmoPQrtTuUwxXyYz"
+
+// shortOption returns how the argument of a cluster of short options such as
+// -u, -sSu or -kHAuthorization:... is sanitized, or "" if it carries no
+// credentials, and the argument if it is attached to the cluster
+func shortOption(w shellWord) (kind string, arg shellWord, attached bool) {
+	for i := 1; i < len(w.value); i++ {
+		if strings.IndexByte(curlShortArgs, w.value[i]) < 0 {
+			continue
+		}
+		kind = curlFlags["-"+w.value[i:i+1]]
+		if i+1 < len(w.value) {
+			return kind, w.slice(i+1, len(w.value)), true
+		}
+		return kind, shellWord{}, false
+	}
+	return "", shellWord{}, false
+}
+
+// CurlDetector sanitizes copied curl commands. Rather than matching patterns
+// in the whole text, it splits each command into shell words and redacts the
+// credentials in the arguments of -H, -u, -b, --data and similar options and
+// in URLs, replacing them with placeholders that keep the command runnable.
+type CurlDetector struct{}
+
+// NewCurlDetector creates a curl command sanitizer
+func NewCurlDetector() *CurlDetector {
+	return &CurlDetector{}
+}
+
+// Name returns the detector's sensitive data type
+func (d *CurlDetector) Name() string {
+	return SensitiveTypeCurl
+}
+
+// Detect returns the credentials in the curl commands of text
+func (d *CurlDetector) Detect(text string) []Match {
+	var matches []Match
+	end := 0
+	for _, loc := range patterns.CurlCommandPattern.FindAllStringIndex(text, -1) {
+		if loc[1] < end {
+			continue // Inside the previous command, e.g. in its data
+		}
+		words, stop := shellWords(text, loc[1])
+		for _, m := range curlCredentials(text, words) {
+			if len(matches) == 0 || m.Start >= matches[len(matches)-1].End {
+				matches = append(matches, m)
+			}
+		}
+		end = stop
+	}
+	return matches
+}
+
+// curlCredentials returns the credentials in the arguments of a curl command
+func curlCredentials(text string, words []shellWord) []Match {
+	var matches []Match
+	add := func(w shellWord, start, end int, placeholder string) {
+		if start >= end {
+			return
+		}
+		s, e := w.raw[start], w.raw[end-1]+1
+		matches = append(matches, Match{
+			Type:        SensitiveTypeCurl,
+			Start:       s,
+			End:         e,
+			Text:        text[s:e],
+			Replacement: placeholder,
+			Confidence:  credentialConfidence(w.value[start:end]),
+		})
+	}
+
+	for i := 0; i < len(words); i++ {
+		w := words[i]
+		kind, arg := "url", w
+		if strings.HasPrefix(w.value, "-") {
+			attached := false
+			if strings.HasPrefix(w.value, "--") {
+				var ok bool
+				if kind, ok = curlFlags[w.value]; !ok {
+					continue
+				}
+			} else if kind, arg, attached = shortOption(w); kind == "" {
+				continue
+			}
+			if !attached {
+				if i+1 == len(words) {
+					break
+				}
+				i++
+				arg = words[i]
+			}
+		}
+
+		v := arg.value
+		switch kind {
+		case "header":
+			for _, s := range headerCredentials(v) {
+				add(arg, s.start, s.end, s.placeholder)
+			}
+		case "user":
+			if colon := strings.IndexByte(v, ':'); colon >= 0 {
+				add(arg, colon+1, len(v), curlPasswordPlaceholder)
+			}
+		case "token":
+			add(arg, 0, len(v), curlTokenPlaceholder)
+		case "cookie":
+			if strings.Contains(v, "=") { // Otherwise a cookie file
+				for _, s := range cookieValues(v, 0, len(v), false) {
+					add(arg, s[0], s[1], curlCookiePlaceholder)
+				}
+			}
+		case "data":
+			for _, s := range dataCredentials(v) {
+				add(arg, s[0], s[1], curlTokenPlaceholder)
+			}
+		case "url":
+			for _, s := range urlCredentials(v) {
+				add(arg, s.start, s.end, s.placeholder)
+			}
+		}
+	}
+	return disjoint(matches)
+}
+
+// credentialSpan is a credential within a curl argument
+type credentialSpan struct {
+	start, end  int
+	placeholder string
+}
+
+// headerCredentials returns the credentials of a Name: value header: the
+// credential of Authorization after its scheme, the values of Cookie, and
+// the whole value of other headers named like credentials, e.g. X-API-Key
+func headerCredentials(header string) []credentialSpan {
+	colon := strings.IndexByte(header, ':')
+	if colon <= 0 {
+		return nil
+	}
+	name := strings.TrimSpace(header[:colon])
+	start := colon + 1
+	for start < len(header) && (header[start] == ' ' || header[start] == '\t') {
+		start++
+	}
+	end := len(strings.TrimRight(header, " \t"))
+	if start >= end {
+		return nil
+	}
+
+	switch lower := strings.ToLower(name); {
+	case lower == "authorization" || lower == "proxy-authorization":
+		if sp := strings.IndexAny(header[start:end], " \t"); sp > 0 {
+			start += sp + 1 // Keep the scheme, e.g. Bearer
+		}
+		return []credentialSpan{{start, end, curlTokenPlaceholder}}
+	case lower == "cookie":
+		var spans []credentialSpan
+		for _, s := range cookieValues(header, start, end, false) {
+			spans = append(spans, credentialSpan{s[0], s[1], curlCookiePlaceholder})
+		}
+		return spans
+	case patterns.SecretHeaderPattern.MatchString(name):
+		return []credentialSpan{{start, end, curlTokenPlaceholder}}
+	}
+	return nil
+}
+
+// dataCredentials returns the values of the credential fields of request
+// data: a JSON body, name=value pairs separated by & or a form field
+func dataCredentials(data string) [][2]int {
+	if strings.HasPrefix(data, "@") {
+		return nil // Read from a file
+	}
+	if trimmed := strings.TrimSpace(data); strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+		var spans [][2]int
+		value := 2 * patterns.JSONSecretFieldPattern.SubexpIndex("value")
+		for _, loc := range patterns.JSONSecretFieldPattern.FindAllStringSubmatchIndex(data, -1) {
+			spans = append(spans, [2]int{loc[value], loc[value+1]})
+		}
+		return spans
+	}
+	return queryCredentials(data, 0)
+}
+
+// queryCredentials returns the values of the credential fields of the
+// name=value pairs of query, which starts at offset in its argument
+func queryCredentials(query string, offset int) [][2]int {
+	var spans [][2]int
+	for start := 0; start <= len(query); {
+		pair := query[start:]
+		if i := strings.IndexByte(pair, '&'); i >= 0 {
+			pair = pair[:i]
+		}
+		if eq := strings.IndexByte(pair, '='); eq > 0 && eq < len(pair)-1 {
+			name, err := url.QueryUnescape(pair[:eq])
+			if err != nil {
+				name = pair[:eq]
+			}
+			if patterns.SecretFieldPattern.MatchString(name) {
+				spans = append(spans, [2]int{offset + start + eq + 1, offset + start + len(pair)})
+			}
+		}
+		start += len(pair) + 1
+	}
+	return spans
+}
+
+// urlCredentials returns the password of a URL's user:password@ part and
+// the values of its credential query parameters
+func urlCredentials(u string) []credentialSpan {
+	var spans []credentialSpan
+	if scheme := strings.Index(u, "://"); scheme > 0 {
+		authority := u[scheme+3:]
+		if i := strings.IndexAny(authority, "/?#"); i >= 0 {
+			authority = authority[:i]
+		}
+		if at := strings.LastIndexByte(authority, '@'); at > 0 {
+			if colon := strings.IndexByte(authority[:at], ':'); colon >= 0 {
+				spans = append(spans, credentialSpan{scheme + 3 + colon + 1, scheme + 3 + at, curlPasswordPlaceholder})
+			}
+		}
+	}
+	if q := strings.IndexByte(u, '?'); q >= 0 {
+		query := u[q+1:]
+		if i := strings.IndexByte(query, '#'); i >= 0 {
+			query = query[:i]
+		}
+		for _, s := range queryCredentials(query, q+1) {
+			spans = append(spans, credentialSpan{s[0], s[1], curlTokenPlaceholder})
+		}
+	}
+	return spans
+}
+
+// shellWord is a word of a shell command, unquoted
+type shellWord struct {
+	value string
+	raw   []int // Offset in the command text of each byte of value
+}
+
+// slice returns the word made of value[start:end]
+func (w shellWord) slice(start, end int) shellWord {
+	return shellWord{value: w.value[start:end], raw: w.raw[start:end]}
+}
+
+// shellWords splits the command starting at offset of text into words,
+// following POSIX quoting and line continuations (\ in shells, ^ in cmd and
+// ` in PowerShell), and returns them with the offset the command ends at
+func shellWords(text string, offset int) ([]shellWord, int) {
+	var words []shellWord
+	i := offset
+	for {
+		// Whitespace and line continuations between words
+		for i < len(text) {
+			if text[i] == ' ' || text[i] == '\t' {
+				i++
+			} else if n := continuation(text, i); n > 0 {
+				i += n
+			} else {
+				break
+			}
+		}
+		if i >= len(text) || strings.IndexByte("\r\n;|&<>)#", text[i]) >= 0 {
+			return words, i
+		}
+
+		var w shellWord
+		var value strings.Builder
+		add := func(at int) {
+			value.WriteByte(text[at])
+			w.raw = append(w.raw, at)
+		}
+	word:
+		for i < len(text) {
+			switch c := text[i]; {
+			case c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == ';' || c == '|' || c == '&' || c == '<' || c == '>':
+				break word
+			case continuation(text, i) > 0:
+				break word
+			case c == '\'':
+				for i++; i < len(text) && text[i] != '\''; i++ {
+					add(i)
+				}
+				i++
+			case c == '$' && i+1 < len(text) && text[i+1] == '\'':
+				for i += 2; i < len(text) && text[i] != '\''; i++ {
+					if text[i] == '\\' && i+1 < len(text) {
+						i++
+					}
+					add(i)
+				}
+				i++
+			case c == '"':
+				for i++; i < len(text) && text[i] != '"'; i++ {
+					if text[i] == '\\' && i+1 < len(text) && strings.IndexByte("\"\\$`", text[i+1]) >= 0 {
+						i++
+					}
+					add(i)
+				}
+				i++
+			case c == '\\' && i+1 < len(text):
+				add(i + 1)
+				i += 2
+			default:
+				add(i)
+				i++
+			}
+		}
+		w.value = value.String()
+		words = append(words, w)
+	}
+}
+
+// continuation returns the length of the line continuation at text[i], or 0
+func continuation(text string, i int) int {
+	if c := text[i]; c != '\\' && c != '^' && c != '`' {
+		return 0
+	}
+	if strings.HasPrefix(text[i+1:], "\n") {
+		return 2
+	}
+	if strings.HasPrefix(text[i+1:], "\r\n") {
+		return 3
+	}
+	return 0
+}
@@ -0,0 +1,35 @@
+package filter
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestCurlDetector_Placeholders tests that shell variables in curl commands
+// are doubted as placeholders
+func TestCurlDetector_Placeholders(t *testing.T) {
+	matches := NewCurlDetector().Detect(`curl -H "Authorization: Bearer $TOKEN" -u ann:secret https://example.com`)
+	if len(matches) != 2 || matches[0].Confidence != confidencePlaceholder || matches[1].Confidence != 1 {
+		t.Errorf("Expected the variable to be doubted, got %+v", matches)
+	}
+}
+
+// TestShellWords tests that shell words are unquoted with an offset per byte
+// and end at the first control operator
+func TestShellWords(t *testing.T) {
+	text := `curl -H 'a b' "c\"d" e\ f $'g\'h' | grep x`
+	words, end := shellWords(text, 5)
+	var got []string
+	for _, w := range words {
+		got = append(got, w.value)
+		if len(w.raw) != len(w.value) {
+			t.Errorf("Expected an offset per byte of %q", w.value)
+		}
+	}
+	if s := strings.Join(got, "|"); s != `-H|a b|c"d|e f|g'h` {
+		t.Errorf("Expected the words unquoted, got %q", s)
+	}
+	if text[end] != '|' {
+		t.Errorf("Expected the command to end at the pipe, got %d", end)
+	}
+}
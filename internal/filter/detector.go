@@ -0,0 +1,773 @@
+package filter
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+	"github.com/happytaoer/prompt-security/internal/patterns"
+)
+
+// Match is a single piece of sensitive data found in a text
+type Match struct {
+	Type        string  // Type of sensitive data (detector name)
+	Start       int     // Byte offset of the match in the scanned text
+	End         int     // Byte offset just past the match
+	Text        string  // Matched text
+	Replacement string  // What the match should be replaced with
+	Priority    int     // Conflict priority, set by the DetectorSet
+	Action      string  // A config.Action* value; empty replaces
+	Confidence  float64 // How likely the match is sensitive, 0 to 1; 0 if unscored, which counts as certain
+}
+
+// Detector finds one kind of sensitive data. Detect must return matches in
+// ascending, non-overlapping order.
+type Detector interface {
+	Name() string
+	Detect(text string) []Match
+}
+
+// Prioritizer is implemented by detectors that carry their own priority.
+// A nonzero Priority overrides the priority of the registry entry that built
+// the detector.
+type Prioritizer interface {
+	Priority() int
+}
+
+// Normalizer is implemented by detectors that decide for themselves whether
+// they match against Unicode-normalized text, such as string match
+// patterns. Other detectors follow the configured normalize_detectors.
+type Normalizer interface {
+	Normalized() bool
+}
+
+// DetectorFactory builds the detectors for a registry entry from a
+// configuration and its compiled built-in patterns. It returns nil when the
+// entry is disabled in cfg.
+type DetectorFactory func(cfg config.Config, compiled patterns.Set) []Detector
+
+// registryEntry is a named, prioritized detector factory
+type registryEntry struct {
+	name     string
+	priority int
+	seq      int
+	factory  DetectorFactory
+}
+
+// Registry holds the detector factories used to build DetectorSets.
+// Detectors are ordered by ascending priority; entries with equal priority
+// keep registration order. When matches overlap, the detector with the lower
+// priority value wins.
+type Registry struct {
+	mu      sync.RWMutex
+	entries map[string]registryEntry
+	seq     int
+	builtin bool // Only the built-in entries are registered
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{entries: make(map[string]registryEntry)}
+}
+
+// Register adds a detector factory, replacing any entry with the same name
+func (r *Registry) Register(name string, priority int, factory DetectorFactory) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.seq++
+	r.entries[name] = registryEntry{name: name, priority: priority, seq: r.seq, factory: factory}
+	r.builtin = false
+}
+
+// mayDetect reports whether a DetectorSet built from cfg could contain a
+// detector. Without custom registrations this is answered from cfg alone,
+// so callers can skip compiling detectors that would never run.
+func (r *Registry) mayDetect(cfg *config.Config) bool {
+	r.mu.RLock()
+	builtin := r.builtin
+	r.mu.RUnlock()
+	return !builtin || anyDetectorEnabled(cfg)
+}
+
+// Unregister removes a detector factory
+func (r *Registry) Unregister(name string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.entries, name)
+}
+
+// SetPriority changes the priority of a registered entry. It reports false
+// if no entry has that name.
+func (r *Registry) SetPriority(name string, priority int) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	entry, ok := r.entries[name]
+	if !ok {
+		return false
+	}
+	entry.priority = priority
+	r.entries[name] = entry
+	return true
+}
+
+// Names returns the registered entry names in execution order
+func (r *Registry) Names() []string {
+	entries := r.sorted()
+	names := make([]string, len(entries))
+	for i, e := range entries {
+		names[i] = e.name
+	}
+	return names
+}
+
+// NewDetectorSet compiles cfg into a DetectorSet using this registry
+func (r *Registry) NewDetectorSet(cfg config.Config) *DetectorSet {
+	cfg = config.WithoutDisabledPatterns(cfg)
+	compiled := patterns.NewPatternCache().Compile(&cfg)
+
+	set := &DetectorSet{
+		allowlist: valueSet(cfg.Allowlist),
+		confirmed: valueSet(cfg.ReviewConfirmed),
+	}
+	// Review needs the matched text, which hashed logs do not keep
+	if cfg.LogMode != config.LogModeHash {
+		set.reviewThreshold = cfg.ReviewThreshold
+	}
+	normalized := valueSet(cfg.NormalizeDetectors)
+	for _, e := range r.sorted() {
+		for _, d := range e.factory(cfg, compiled) {
+			priority := e.priority
+			if p, ok := d.(Prioritizer); ok && p.Priority() != 0 {
+				priority = p.Priority()
+			}
+			normalize := normalized[normalizeName(e.name)]
+			if n, ok := d.(Normalizer); ok {
+				normalize = n.Normalized()
+			}
+			set.detectors = append(set.detectors, d)
+			set.priorities = append(set.priorities, priority)
+			set.normalize = append(set.normalize, normalize)
+		}
+	}
+
+	sort.Stable(byPriority{set})
+	return set
+}
+
+// normalizeName returns the name normalize_detectors gives the detectors of
+// a registry entry, e.g. infrastructure_id for the GCP project detector
+func normalizeName(entry string) string {
+	switch entry {
+	case SensitiveTypeGCPProject, SensitiveTypeAzureID:
+		return config.DetectorInfrastructureID
+	}
+	return entry
+}
+
+// valueSet returns the set of values, nil if there are none
+func valueSet(values []string) map[string]bool {
+	if len(values) == 0 {
+		return nil
+	}
+	set := make(map[string]bool, len(values))
+	for _, v := range values {
+		set[v] = true
+	}
+	return set
+}
+
+// sorted returns a snapshot of the entries in execution order
+func (r *Registry) sorted() []registryEntry {
+	r.mu.RLock()
+	entries := make([]registryEntry, 0, len(r.entries))
+	for _, e := range r.entries {
+		entries = append(entries, e)
+	}
+	r.mu.RUnlock()
+
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].priority != entries[j].priority {
+			return entries[i].priority < entries[j].priority
+		}
+		return entries[i].seq < entries[j].seq
+	})
+	return entries
+}
+
+// byPriority sorts a DetectorSet's detectors by ascending priority
+type byPriority struct{ *DetectorSet }
+
+func (s byPriority) Len() int           { return len(s.detectors) }
+func (s byPriority) Less(i, j int) bool { return s.priorities[i] < s.priorities[j] }
+func (s byPriority) Swap(i, j int) {
+	s.detectors[i], s.detectors[j] = s.detectors[j], s.detectors[i]
+	s.priorities[i], s.priorities[j] = s.priorities[j], s.priorities[i]
+	s.normalize[i], s.normalize[j] = s.normalize[j], s.normalize[i]
+}
+
+// Built-in detector priorities, used when a configuration leaves the
+// corresponding priority at zero
+const (
+	PriorityEmail            = 100
+	PriorityPhone            = 200
+	PriorityCreditCard       = 300
+	PrioritySSN              = 400
+	PriorityIPV4             = 500
+	PriorityMAC              = 520
+	PriorityLocation         = 550
+	PriorityHomePath         = 600
+	PriorityInfrastructureID = 650
+	PriorityStringMatch      = 1000
+
+	// Merchant IDs win overlaps with phones, whose US pattern matches ten
+	// of their digits
+	PriorityMerchantID = 150
+
+	// License keys, which need a word such as "license" before them, win
+	// overlaps with phones and cards matching their digit groups
+	PriorityLicenseKey = 150
+
+	// Credentials win overlaps with the data detected inside them
+	PriorityTerraformSecret = 90
+	PriorityHTTPAuth        = 80
+	PriorityCookie          = 80
+
+	// TOTP secrets win overlaps with the credentials of URIs
+	PriorityTOTP = 60
+
+	// Blocks of recovery codes win overlaps with the codes detected one by
+	// one, e.g. as phone numbers or license keys
+	PriorityRecoveryCodes = 60
+
+	// Passwords in prose win overlaps with the data their value may look
+	// like, such as emails, and lose them to the credentials above
+	PriorityPassword = 85
+
+	// Shell prompts win overlaps with emails, which user@host.domain
+	// looks like
+	PriorityShellPrompt = 95
+
+	// Parsed curl commands win overlaps with the patterns above
+	PriorityCurl = 70
+
+	// Prompt safety matches that block win every overlap, while warnings
+	// lose them so they never keep sensitive data from being replaced
+	PriorityPromptSafetyBlock = 50
+	PriorityPromptSafetyWarn  = 2000
+
+	// Special category findings are only logged, so they lose every overlap
+	PrioritySpecialCategory = 2000
+)
+
+// SensitiveTypePromptSafety is the registry name of the prompt safety entry
+const SensitiveTypePromptSafety = "prompt_safety"
+
+// SensitiveTypeStringMatch is the registry name of the string match patterns entry
+const SensitiveTypeStringMatch = "string_match"
+
+// DefaultRegistry contains the built-in detectors
+var DefaultRegistry = newDefaultRegistry()
+
+func newDefaultRegistry() *Registry {
+	r := NewRegistry()
+
+	r.Register(SensitiveTypeEmail, PriorityEmail, func(cfg config.Config, compiled patterns.Set) []Detector {
+		if !cfg.DetectEmails {
+			return nil
+		}
+		return []Detector{NewRegexDetector(SensitiveTypeEmail, compiled.Email, cfg.EmailReplacement).
+			WithPriority(cfg.EmailPriority).
+			WithAction(cfg.EmailAction).
+			WithBudget(budgetKey("custom_email_pattern", cfg.CustomEmailPattern, compiled.Email))}
+	})
+
+	r.Register(SensitiveTypePhone, PriorityPhone, func(cfg config.Config, compiled patterns.Set) []Detector {
+		if !cfg.DetectPhones {
+			return nil
+		}
+		return []Detector{NewRegexDetector(SensitiveTypePhone, compiled.Phone, cfg.PhoneReplacement).
+			WithPriority(cfg.PhonePriority).
+			WithAction(cfg.PhoneAction).
+			WithConfidence(phoneConfidence).
+			WithBudget(budgetKey("custom_phone_pattern", cfg.CustomPhonePattern, compiled.Phone))}
+	})
+
+	r.Register(SensitiveTypeCreditCard, PriorityCreditCard, func(cfg config.Config, compiled patterns.Set) []Detector {
+		if !cfg.DetectCreditCards {
+			return nil
+		}
+		return []Detector{NewRegexDetector(SensitiveTypeCreditCard, compiled.CreditCard, cfg.CreditCardReplacement).
+			WithPriority(cfg.CreditCardPriority).
+			WithAction(cfg.CreditCardAction).
+			WithConfidence(cardConfidence).
+			WithBudget(budgetKey("custom_credit_card_pattern", cfg.CustomCreditCardPattern, compiled.CreditCard))}
+	})
+
+	r.Register(SensitiveTypeSSN, PrioritySSN, func(cfg config.Config, compiled patterns.Set) []Detector {
+		if !cfg.DetectSSNs {
+			return nil
+		}
+		return []Detector{NewRegexDetector(SensitiveTypeSSN, compiled.SSN, cfg.SSNReplacement).
+			WithPriority(cfg.SSNPriority).
+			WithAction(cfg.SSNAction).
+			WithConfidence(ssnConfidence).
+			WithBudget(budgetKey("custom_ssn_pattern", cfg.CustomSSNPattern, compiled.SSN))}
+	})
+
+	r.Register(SensitiveTypeMerchantID, PriorityMerchantID, func(cfg config.Config, compiled patterns.Set) []Detector {
+		if !cfg.ChinaPack {
+			return nil
+		}
+		return []Detector{NewRegexDetector(SensitiveTypeMerchantID, patterns.MerchantIDPattern, "${label}[MERCHANT_ID]")}
+	})
+
+	r.Register(SensitiveTypeIPV4, PriorityIPV4, func(cfg config.Config, compiled patterns.Set) []Detector {
+		if !cfg.DetectIPV4 {
+			return nil
+		}
+		return []Detector{NewRegexDetector(SensitiveTypeIPV4, compiled.IPV4, cfg.IPV4Replacement).
+			WithPriority(cfg.IPV4Priority).
+			WithAction(cfg.IPV4Action).
+			WithConfidence(ipv4Confidence).
+			WithBudget(budgetKey("custom_ipv4_pattern", cfg.CustomIPV4Pattern, compiled.IPV4))}
+	})
+
+	r.Register(SensitiveTypeLocation, PriorityLocation, func(cfg config.Config, compiled patterns.Set) []Detector {
+		if !cfg.DetectLocations {
+			return nil
+		}
+		return []Detector{NewRegexDetector(SensitiveTypeLocation, patterns.LocationPattern, cfg.LocationReplacement).
+			WithConfidence(locationConfidence)}
+	})
+
+	r.Register(SensitiveTypeTerraformSecret, PriorityTerraformSecret, func(cfg config.Config, compiled patterns.Set) []Detector {
+		if !cfg.DetectTerraformSecrets {
+			return nil
+		}
+		return []Detector{NewTerraformDetector(cfg.TerraformReplacement)}
+	})
+
+	r.Register(SensitiveTypeHTTPAuth, PriorityHTTPAuth, func(cfg config.Config, compiled patterns.Set) []Detector {
+		if !cfg.DetectHTTPAuth {
+			return nil
+		}
+		return []Detector{NewRegexDetector(SensitiveTypeHTTPAuth, patterns.HTTPAuthPattern, cfg.HTTPAuthReplacement).
+			WithGroup("secret").
+			WithConfidence(credentialConfidence)}
+	})
+
+	r.Register(SensitiveTypeCookie, PriorityCookie, func(cfg config.Config, compiled patterns.Set) []Detector {
+		if !cfg.DetectCookies {
+			return nil
+		}
+		return []Detector{NewCookieDetector(cfg.CookieReplacement)}
+	})
+
+	r.Register(SensitiveTypeCurl, PriorityCurl, func(cfg config.Config, compiled patterns.Set) []Detector {
+		if !cfg.SanitizeCurl {
+			return nil
+		}
+		return []Detector{NewCurlDetector()}
+	})
+
+	r.Register(SensitiveTypeHomePath, PriorityHomePath, func(cfg config.Config, compiled patterns.Set) []Detector {
+		if !cfg.DetectHomePaths {
+			return nil
+		}
+		return []Detector{NewHomePathDetector(cfg.HomePathReplacement)}
+	})
+
+	r.Register(SensitiveTypeShellPrompt, PriorityShellPrompt, func(cfg config.Config, compiled patterns.Set) []Detector {
+		if !cfg.DetectShellPrompts {
+			return nil
+		}
+		return []Detector{NewRegexDetector(SensitiveTypeShellPrompt, patterns.ShellPromptPattern, cfg.ShellPromptReplacement).
+			WithGroup("login")}
+	})
+
+	r.Register(SensitiveTypeGCPProject, PriorityInfrastructureID, func(cfg config.Config, compiled patterns.Set) []Detector {
+		if !cfg.DetectInfrastructureIDs {
+			return nil
+		}
+		return []Detector{NewRegexDetector(SensitiveTypeGCPProject, patterns.GCPProjectPattern, cfg.GCPProjectReplacement).
+			WithGroup("project").
+			WithConfidence(cloudIDConfidence)}
+	})
+
+	r.Register(SensitiveTypeAzureID, PriorityInfrastructureID, func(cfg config.Config, compiled patterns.Set) []Detector {
+		if !cfg.DetectInfrastructureIDs {
+			return nil
+		}
+		return []Detector{NewRegexDetector(SensitiveTypeAzureID, patterns.AzureIDPattern, cfg.AzureIDReplacement).
+			WithGroup("guid").
+			WithConfidence(cloudIDConfidence)}
+	})
+
+	r.Register(SensitiveTypeLicenseKey, PriorityLicenseKey, func(cfg config.Config, compiled patterns.Set) []Detector {
+		if !cfg.DetectLicenseKeys || compiled.LicenseKey == nil {
+			return nil
+		}
+		return []Detector{NewLicenseKeyDetector(compiled.LicenseKey, cfg.LicenseKeyReplacement)}
+	})
+
+	r.Register(SensitiveTypeMAC, PriorityMAC, func(cfg config.Config, compiled patterns.Set) []Detector {
+		if !cfg.DetectMACAddresses {
+			return nil
+		}
+		return []Detector{NewMACDetector(cfg.MACReplacement, cfg.MACAllowLocal)}
+	})
+
+	r.Register(SensitiveTypeTOTP, PriorityTOTP, func(cfg config.Config, compiled patterns.Set) []Detector {
+		if !cfg.DetectTOTPSecrets {
+			return nil
+		}
+		return []Detector{NewTOTPDetector(cfg.TOTPReplacement, cfg.TOTPAction)}
+	})
+
+	r.Register(SensitiveTypeRecoveryCodes, PriorityRecoveryCodes, func(cfg config.Config, compiled patterns.Set) []Detector {
+		if !cfg.DetectRecoveryCodes {
+			return nil
+		}
+		return []Detector{NewRecoveryCodesDetector(cfg.RecoveryCodeReplacement)}
+	})
+
+	r.Register(SensitiveTypePassword, PriorityPassword, func(cfg config.Config, compiled patterns.Set) []Detector {
+		if !cfg.DetectPasswords {
+			return nil
+		}
+		return []Detector{NewPasswordProseDetector(cfg.PasswordReplacement)}
+	})
+
+	r.Register(SensitiveTypeStringMatch, PriorityStringMatch, func(cfg config.Config, compiled patterns.Set) []Detector {
+		type fuzzyGroup struct {
+			priority  int
+			normalize bool
+		}
+		var detectors []Detector
+		fuzzy := make(map[fuzzyGroup][]config.StringMatchPattern)
+		var groups []fuzzyGroup
+		for _, p := range cfg.StringMatchPatterns {
+			switch {
+			case !p.Enabled:
+			case p.Fuzzy && len(fuzzyWords(p.Pattern)) > 0:
+				g := fuzzyGroup{p.Priority, p.Normalize}
+				if _, ok := fuzzy[g]; !ok {
+					groups = append(groups, g)
+				}
+				fuzzy[g] = append(fuzzy[g], p)
+			default:
+				detectors = append(detectors, NewStringMatchDetector(p))
+			}
+		}
+		// One index per priority and normalization, as the set prioritizes
+		// and normalizes for whole detectors
+		for _, g := range groups {
+			detectors = append(detectors, NewFuzzyDetector(fuzzy[g], g.priority))
+		}
+		return detectors
+	})
+
+	r.Register(SensitiveTypePromptSafety, PriorityPromptSafetyWarn, func(cfg config.Config, compiled patterns.Set) []Detector {
+		action, priority := config.ActionLog, PriorityPromptSafetyWarn
+		switch cfg.PromptSafetyMode {
+		case config.PromptSafetyWarn:
+		case config.PromptSafetyBlock:
+			action, priority = config.ActionBlock, PriorityPromptSafetyBlock
+		default:
+			return nil
+		}
+		return []Detector{
+			NewRegexDetector(SensitiveTypePromptInjection, patterns.PromptInjectionPattern, "[PROMPT_INJECTION]").WithPriority(priority).WithAction(action),
+			NewRegexDetector(SensitiveTypeRoleOverride, patterns.RoleOverridePattern, "[ROLE_OVERRIDE]").WithPriority(priority).WithAction(action),
+			NewRegexDetector(SensitiveTypeDataExfiltration, patterns.DataExfiltrationPattern, "[DATA_EXFILTRATION]").WithPriority(priority).WithAction(action),
+		}
+	})
+
+	r.Register(SensitiveTypeSpecialCategory, PrioritySpecialCategory, func(cfg config.Config, compiled patterns.Set) []Detector {
+		if !cfg.DetectSpecialCategories {
+			return nil
+		}
+		return []Detector{defaultSpecialCategoryDetector}
+	})
+
+	r.builtin = true
+	return r
+}
+
+// anyDetectorEnabled reports whether cfg enables any built-in detector
+func anyDetectorEnabled(cfg *config.Config) bool {
+	if cfg.DetectEmails || cfg.DetectPhones || cfg.DetectCreditCards || cfg.DetectSSNs || cfg.DetectIPV4 || cfg.ChinaPack || cfg.DetectSpecialCategories || cfg.DetectLocations || cfg.DetectTerraformSecrets || cfg.DetectHTTPAuth || cfg.DetectCookies || cfg.SanitizeCurl || cfg.DetectHomePaths || cfg.DetectShellPrompts || cfg.DetectInfrastructureIDs || cfg.DetectLicenseKeys || cfg.DetectMACAddresses || cfg.DetectTOTPSecrets || cfg.DetectRecoveryCodes || cfg.DetectPasswords {
+		return true
+	}
+	if cfg.PromptSafetyMode == config.PromptSafetyWarn || cfg.PromptSafetyMode == config.PromptSafetyBlock {
+		return true
+	}
+	for _, p := range cfg.StringMatchPatterns {
+		if p.Enabled {
+			return true
+		}
+	}
+	return false
+}
+
+// RegexDetector detects matches of a regular expression
+type RegexDetector struct {
+	name        string
+	pattern     *regexp.Regexp
+	replacement string
+	priority    int
+	action      string
+	budgetKey   string
+	template    bool  // Replacement references capture groups
+	groups      []int // Submatches a match is narrowed to, the first taking part
+	score       Scorer
+}
+
+// NewRegexDetector creates a detector replacing every match of pattern. A
+// replacement may reference capture groups of the match, e.g. [USER]@${domain}.
+func NewRegexDetector(name string, pattern *regexp.Regexp, replacement string) *RegexDetector {
+	return &RegexDetector{name: name, pattern: pattern, replacement: replacement, template: config.IsTemplate(replacement)}
+}
+
+// WithPriority sets the detector's priority, zero keeps the registry default
+func (d *RegexDetector) WithPriority(priority int) *RegexDetector {
+	d.priority = priority
+	return d
+}
+
+// WithAction sets the config action of the detector's matches
+func (d *RegexDetector) WithAction(action string) *RegexDetector {
+	d.action = action
+	return d
+}
+
+// WithGroup narrows every match to the first capture group named group that
+// takes part in it, e.g. the credential of an Authorization header, leaving
+// the rest of the match as is. Matches without the group are skipped.
+func (d *RegexDetector) WithGroup(group string) *RegexDetector {
+	d.groups = make([]int, 0, 1)
+	for i, name := range d.pattern.SubexpNames() {
+		if name == group && i > 0 {
+			d.groups = append(d.groups, i)
+		}
+	}
+	return d
+}
+
+// Name returns the detector's sensitive data type
+func (d *RegexDetector) Name() string {
+	return d.name
+}
+
+// Priority returns the detector's configured priority
+func (d *RegexDetector) Priority() int {
+	return d.priority
+}
+
+// Detect returns all matches of the pattern in text
+func (d *RegexDetector) Detect(text string) []Match {
+	locs := d.find(text)
+	if len(locs) == 0 {
+		return nil
+	}
+
+	matches := make([]Match, 0, len(locs))
+	for _, loc := range locs {
+		start, end, ok := d.span(loc)
+		if !ok || start == end {
+			continue // Ignore empty matches from permissive custom patterns
+		}
+		matches = append(matches, Match{
+			Type:        d.name,
+			Start:       start,
+			End:         end,
+			Text:        text[start:end],
+			Replacement: d.replacementFor(text, loc),
+			Action:      d.action,
+			Confidence:  d.confidence(text[start:end]),
+		})
+	}
+	return matches
+}
+
+// span returns the part of the match at loc to replace: all of it, or the
+// first of the detector's groups taking part in it
+func (d *RegexDetector) span(loc []int) (int, int, bool) {
+	if d.groups == nil {
+		return loc[0], loc[1], true
+	}
+	for _, g := range d.groups {
+		if loc[2*g] >= 0 {
+			return loc[2*g], loc[2*g+1], true
+		}
+	}
+	return 0, 0, false
+}
+
+// find returns the locations of the pattern's matches in text, with the
+// submatches when the replacement or the groups need them
+func (d *RegexDetector) find(text string) [][]int {
+	if d.template || d.groups != nil {
+		return d.pattern.FindAllStringSubmatchIndex(text, -1)
+	}
+	return d.pattern.FindAllStringIndex(text, -1)
+}
+
+// replacementFor returns the replacement of the match of text at loc
+func (d *RegexDetector) replacementFor(text string, loc []int) string {
+	if !d.template {
+		return d.replacement
+	}
+	return config.ExpandTemplate(d.pattern, d.replacement, text, loc)
+}
+
+// StringDetector detects occurrences of a literal string
+type StringDetector struct {
+	name        string
+	pattern     string
+	replacement string
+	fold        *regexp.Regexp // Case-insensitive matcher, nil for exact matching
+	wholeWord   bool
+	normalize   bool
+	priority    int
+	action      string
+}
+
+// NewStringDetector creates a detector replacing every exact occurrence of pattern
+func NewStringDetector(name, pattern, replacement string) *StringDetector {
+	return &StringDetector{name: name, pattern: pattern, replacement: replacement}
+}
+
+// NewStringMatchDetector creates a detector for a configured string match
+// pattern, honouring its case, whole-word and normalize options
+func NewStringMatchDetector(p config.StringMatchPattern) *StringDetector {
+	d := NewStringDetector(p.Name, p.Pattern, p.Replacement)
+	if p.CaseInsensitive && p.Pattern != "" {
+		d.fold = regexp.MustCompile("(?i)" + regexp.QuoteMeta(p.Pattern))
+	}
+	d.wholeWord = p.WholeWord
+	d.normalize = p.Normalize
+	d.priority = p.Priority
+	d.action = p.Action
+	return d
+}
+
+// Name returns the detector's sensitive data type
+func (d *StringDetector) Name() string {
+	return d.name
+}
+
+// Priority returns the detector's configured priority
+func (d *StringDetector) Priority() int {
+	return d.priority
+}
+
+// Normalized reports whether the pattern matches against normalized text
+func (d *StringDetector) Normalized() bool {
+	return d.normalize
+}
+
+// Detect returns all non-overlapping occurrences of the pattern in text
+func (d *StringDetector) Detect(text string) []Match {
+	if d.pattern == "" {
+		return nil
+	}
+
+	var matches []Match
+	offset := 0
+	for offset < len(text) {
+		start, end := d.find(text[offset:])
+		if start < 0 {
+			return matches
+		}
+		start += offset
+		end += offset
+
+		if d.wholeWord && !isWholeWord(text, start, end) {
+			// Retry from the next character, a later overlapping
+			// occurrence may still stand on its own
+			_, size := utf8.DecodeRuneInString(text[start:])
+			offset = start + size
+			continue
+		}
+
+		matches = append(matches, Match{
+			Type:        d.name,
+			Start:       start,
+			End:         end,
+			Text:        text[start:end],
+			Replacement: d.replacement,
+			Action:      d.action,
+		})
+		offset = end
+	}
+	return matches
+}
+
+// find returns the offsets of the first occurrence of the pattern in text,
+// or -1, -1
+func (d *StringDetector) find(text string) (int, int) {
+	if d.fold != nil {
+		loc := d.fold.FindStringIndex(text)
+		if loc == nil {
+			return -1, -1
+		}
+		return loc[0], loc[1]
+	}
+
+	i := strings.Index(text, d.pattern)
+	if i < 0 {
+		return -1, -1
+	}
+	return i, i + len(d.pattern)
+}
+
+// isWholeWord reports whether text[start:end] is not part of a larger word.
+// A side is only checked when the match begins or ends with a word
+// character, so patterns like "@acme" still match after a letter.
+func isWholeWord(text string, start, end int) bool {
+	first, _ := utf8.DecodeRuneInString(text[start:end])
+	if isWordRune(first) && start > 0 {
+		if before, _ := utf8.DecodeLastRuneInString(text[:start]); isWordRune(before) {
+			return false
+		}
+	}
+
+	last, _ := utf8.DecodeLastRuneInString(text[start:end])
+	if isWordRune(last) && end < len(text) {
+		if after, _ := utf8.DecodeRuneInString(text[end:]); isWordRune(after) {
+			return false
+		}
+	}
+	return true
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// disjoint sorts matches by position and drops those overlapping an earlier
+// one, as Detect must. Of matches starting at the same offset the longest is
+// kept, so the result does not depend on the order they were found in.
+func disjoint(matches []Match) []Match {
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Start != matches[j].Start {
+			return matches[i].Start < matches[j].Start
+		}
+		return matches[i].End > matches[j].End
+	})
+	kept := matches[:0]
+	end := 0
+	for _, m := range matches {
+		if m.Start < end {
+			continue
+		}
+		kept = append(kept, m)
+		end = m.End
+	}
+	return kept
+}
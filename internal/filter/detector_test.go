@@ -0,0 +1,295 @@
+package filter
+
+import (
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+	"github.com/happytaoer/prompt-security/internal/patterns"
+)
+
+// TestRegistry_CustomDetector tests plugging a new detector into a registry
+func TestRegistry_CustomDetector(t *testing.T) {
+	registry := NewRegistry()
+	registry.Register("api_key", 50, func(cfg config.Config, compiled patterns.Set) []Detector {
+		return []Detector{NewRegexDetector("api_key", regexp.MustCompile(`sk-[A-Za-z0-9]{8,}`), "[API_KEY]")}
+	})
+
+	filtered, changed, summary := registry.NewDetectorSet(config.Config{}).Filter("token sk-abcdef123456 here")
+
+	if !changed || filtered != "token [API_KEY] here" {
+		t.Errorf("Expected API key to be replaced, got %q", filtered)
+	}
+	if len(summary.Replacements) != 1 || summary.Replacements[0].Type != "api_key" {
+		t.Errorf("Expected one api_key replacement, got %+v", summary.Replacements)
+	}
+}
+
+// TestRegistry_PriorityOrder tests that overlapping matches are resolved by priority
+func TestRegistry_PriorityOrder(t *testing.T) {
+	registry := NewRegistry()
+	word := func(name, pattern, replacement string) DetectorFactory {
+		return func(config.Config, patterns.Set) []Detector {
+			return []Detector{NewStringDetector(name, pattern, replacement)}
+		}
+	}
+
+	registry.Register("second", 20, word("second", "top secret", "[X]"))
+	registry.Register("first", 10, word("first", "secret", "[Y]"))
+
+	if got := registry.Names(); len(got) != 2 || got[0] != "first" || got[1] != "second" {
+		t.Fatalf("Expected order [first second], got %v", got)
+	}
+
+	filtered, _, _ := registry.NewDetectorSet(config.Config{}).Filter("a top secret")
+	if filtered != "a top [Y]" {
+		t.Errorf("Expected lower priority value to win, got %q", filtered)
+	}
+
+	// Swapping priorities changes the outcome
+	registry.SetPriority("second", 5)
+	filtered, _, _ = registry.NewDetectorSet(config.Config{}).Filter("a top secret")
+	if filtered != "a [X]" {
+		t.Errorf("Expected reordered replacement, got %q", filtered)
+	}
+
+	// Replacements are not scanned again
+	registry.Register("second", 20, word("second", "[Y]", "[Z]"))
+	filtered, _, _ = registry.NewDetectorSet(config.Config{}).Filter("a secret")
+	if filtered != "a [Y]" {
+		t.Errorf("Expected replacement text to be left alone, got %q", filtered)
+	}
+
+	if registry.SetPriority("missing", 1) {
+		t.Error("Expected SetPriority to report unknown entries")
+	}
+}
+
+// TestConflict_SSNInsideLongerNumber tests resolving an SSN match inside a longer account number
+func TestConflict_SSNInsideLongerNumber(t *testing.T) {
+	account := func(priority int) DetectorFactory {
+		return func(config.Config, patterns.Set) []Detector {
+			return []Detector{NewRegexDetector("account", regexp.MustCompile(`\d{3}-\d{2}-\d{4}-\d{2}`), "[ACCOUNT]").WithPriority(priority)}
+		}
+	}
+	cfg := config.Config{DetectSSNs: true, SSNReplacement: "[SSN]"}
+	input := "acct 123-45-6789-12 ssn 987-65-4321"
+
+	tests := []struct {
+		name     string
+		priority int
+		expected string
+	}{
+		{"AccountFirst", PrioritySSN - 1, "acct [ACCOUNT] ssn [SSN]"},
+		{"EqualPriorityLongestWins", PrioritySSN, "acct [ACCOUNT] ssn [SSN]"},
+		{"SSNFirst", PrioritySSN + 1, "acct [SSN]-12 ssn [SSN]"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			registry := newDefaultRegistry()
+			registry.Register("account", PriorityStringMatch, account(tt.priority))
+
+			filtered, _, _ := registry.NewDetectorSet(cfg).Filter(input)
+			if filtered != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, filtered)
+			}
+		})
+	}
+}
+
+// TestConflict_StringPatternOverlappingEmail tests a custom pattern overlapping the email regex
+func TestConflict_StringPatternOverlappingEmail(t *testing.T) {
+	cfg := config.Config{
+		DetectEmails:     true,
+		EmailReplacement: "[EMAIL]",
+		StringMatchPatterns: []config.StringMatchPattern{
+			{Name: "domain", Pattern: "example.com", Enabled: true, Replacement: "[DOMAIN]"},
+		},
+	}
+	input := "mail bob@example.com or visit example.com"
+
+	filtered, _, _ := NewDetectorSet(cfg).Filter(input)
+	if filtered != "mail [EMAIL] or visit [DOMAIN]" {
+		t.Errorf("Expected email to win by default, got %q", filtered)
+	}
+
+	cfg.StringMatchPatterns[0].Priority = PriorityEmail - 1
+	filtered, _, _ = NewDetectorSet(cfg).Filter(input)
+	if filtered != "mail bob@[DOMAIN] or visit [DOMAIN]" {
+		t.Errorf("Expected pattern priority to win, got %q", filtered)
+	}
+
+	cfg.StringMatchPatterns[0].Priority = 0
+	cfg.EmailPriority = PriorityStringMatch + 1
+	filtered, _, _ = NewDetectorSet(cfg).Filter(input)
+	if filtered != "mail bob@[DOMAIN] or visit [DOMAIN]" {
+		t.Errorf("Expected configured email priority to lose, got %q", filtered)
+	}
+}
+
+// TestConflict_StableOrdering tests that output and summary order do not
+// depend on registration order
+func TestConflict_StableOrdering(t *testing.T) {
+	detectors := map[string]string{
+		"a": "alpha beta",
+		"b": "beta gamma",
+		"c": "gamma",
+		"d": "alpha",
+	}
+	orders := [][]string{
+		{"a", "b", "c", "d"},
+		{"d", "c", "b", "a"},
+		{"b", "d", "a", "c"},
+	}
+	input := "gamma alpha beta gamma alpha"
+
+	var first string
+	var firstTypes []string
+	for _, order := range orders {
+		registry := NewRegistry()
+		for _, name := range order {
+			name, pattern := name, detectors[name]
+			registry.Register(name, 10, func(config.Config, patterns.Set) []Detector {
+				return []Detector{NewStringDetector(name, pattern, "["+name+"]")}
+			})
+		}
+
+		filtered, _, summary := registry.NewDetectorSet(config.Config{}).Filter(input)
+		types := make([]string, len(summary.Replacements))
+		for i, r := range summary.Replacements {
+			types[i] = r.Type
+		}
+
+		if first == "" {
+			first, firstTypes = filtered, types
+			continue
+		}
+		if filtered != first {
+			t.Errorf("Order %v: expected %q, got %q", order, first, filtered)
+		}
+		if strings.Join(types, ",") != strings.Join(firstTypes, ",") {
+			t.Errorf("Order %v: expected summary %v, got %v", order, firstTypes, types)
+		}
+	}
+
+	// At equal priority and length the earlier match wins, so "beta gamma"
+	// loses to "alpha beta"; the summary follows text order
+	if first != "[c] [a] [c] [d]" {
+		t.Errorf("Unexpected resolution %q", first)
+	}
+	if strings.Join(firstTypes, ",") != "c,a,c,d" {
+		t.Errorf("Expected summary in text order, got %v", firstTypes)
+	}
+}
+
+// TestRegistry_Unregister tests removing a built-in detector
+func TestRegistry_Unregister(t *testing.T) {
+	registry := newDefaultRegistry()
+	registry.Unregister(SensitiveTypeEmail)
+
+	cfg := config.Config{DetectEmails: true, EmailReplacement: "[EMAIL]"}
+	if _, changed, _ := registry.NewDetectorSet(cfg).Filter("user@example.com"); changed {
+		t.Error("Expected unregistered email detector not to run")
+	}
+}
+
+// TestRegexDetector_WithGroup tests narrowing matches to a named group
+func TestRegexDetector_WithGroup(t *testing.T) {
+	re := regexp.MustCompile(`key=(?P<value>\w+)|(?P<value>\d+)#|x(?P<other>y)`)
+	d := NewRegexDetector("kv", re, "[${value}]").WithGroup("value")
+
+	var got []string
+	for _, m := range d.Detect("key=abc 42# xy") {
+		got = append(got, m.Text+" "+m.Replacement)
+	}
+	if s := strings.Join(got, ", "); s != "abc [abc], 42 [42]" {
+		t.Errorf("Expected the value groups only, got %q", s)
+	}
+
+	if m := NewRegexDetector("kv", re, "X").WithGroup("missing").Detect("key=abc"); len(m) != 0 {
+		t.Errorf("Expected no matches without the group, got %+v", m)
+	}
+}
+
+// TestStringDetector_Detect tests literal match offsets
+func TestStringDetector_Detect(t *testing.T) {
+	matches := NewStringDetector("word", "ab", "X").Detect("ab-abab")
+
+	expected := [][2]int{{0, 2}, {3, 5}, {5, 7}}
+	if len(matches) != len(expected) {
+		t.Fatalf("Expected %d matches, got %d", len(expected), len(matches))
+	}
+	for i, m := range matches {
+		if m.Start != expected[i][0] || m.End != expected[i][1] {
+			t.Errorf("Match %d: expected [%d,%d), got [%d,%d)", i, expected[i][0], expected[i][1], m.Start, m.End)
+		}
+	}
+}
+
+// TestStringMatchDetector_Options tests the case-insensitive and whole-word options
+func TestStringMatchDetector_Options(t *testing.T) {
+	tests := []struct {
+		name     string
+		pattern  config.StringMatchPattern
+		input    string
+		expected string
+	}{
+		{
+			name:     "ExactByDefault",
+			pattern:  config.StringMatchPattern{Pattern: "Acme Corporation"},
+			input:    "acme corporation and Acme Corporation",
+			expected: "acme corporation and [ORG]",
+		},
+		{
+			name:     "CaseInsensitive",
+			pattern:  config.StringMatchPattern{Pattern: "Acme Corporation", CaseInsensitive: true},
+			input:    "acme corporation and ACME CORPORATION",
+			expected: "[ORG] and [ORG]",
+		},
+		{
+			name:     "SubstringByDefault",
+			pattern:  config.StringMatchPattern{Pattern: "cat"},
+			input:    "cat concatenate",
+			expected: "[ORG] con[ORG]enate",
+		},
+		{
+			name:     "WholeWord",
+			pattern:  config.StringMatchPattern{Pattern: "cat", WholeWord: true},
+			input:    "cat concatenate cat_x (cat).",
+			expected: "[ORG] concatenate cat_x ([ORG]).",
+		},
+		{
+			name:     "WholeWordRetriesOverlap",
+			pattern:  config.StringMatchPattern{Pattern: "aa", WholeWord: true},
+			input:    "aaa aa",
+			expected: "aaa [ORG]",
+		},
+		{
+			name:     "WholeWordNonWordEdge",
+			pattern:  config.StringMatchPattern{Pattern: "@acme", WholeWord: true},
+			input:    "mail@acme @acmes",
+			expected: "mail[ORG] @acmes",
+		},
+		{
+			name:     "BothUnicode",
+			pattern:  config.StringMatchPattern{Pattern: "Émile", CaseInsensitive: true, WholeWord: true},
+			input:    "ÉMILE émile émiles",
+			expected: "[ORG] [ORG] émiles",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := tt.pattern
+			p.Name, p.Enabled, p.Replacement = "org", true, "[ORG]"
+
+			cfg := config.Config{StringMatchPatterns: []config.StringMatchPattern{p}}
+			filtered, _, _ := NewDetectorSet(cfg).Filter(tt.input)
+			if filtered != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, filtered)
+			}
+		})
+	}
+}
@@ -0,0 +1,118 @@
+package filter
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+	"github.com/happytaoer/prompt-security/internal/telemetry"
+)
+
+// Engine holds the DetectorSet for the current configuration. Register
+// Reload as a config.Manager change listener to keep it up to date; Filter
+// may be called concurrently from any goroutine.
+type Engine struct {
+	current atomic.Pointer[DetectorSet]
+	budget  *Budget
+	mu      sync.Mutex      // Serializes swaps, so none loses replace
+	replace ReplacementFunc // Guarded by mu
+}
+
+// NewEngine creates an engine for the initial configuration
+func NewEngine(cfg config.Config) *Engine {
+	e := &Engine{budget: NewBudget(DefaultPatternBudget, MaxPatternOverruns)}
+	e.Reload(cfg)
+	return e
+}
+
+// Reload compiles cfg and atomically swaps it in
+func (e *Engine) Reload(cfg config.Config) {
+	ds := NewDetectorSet(cfg)
+	ds.budget = e.budget
+	e.store(ds)
+}
+
+// store swaps in ds with the engine's replacement function
+func (e *Engine) store(ds *DetectorSet) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	ds.replace = e.replace
+	e.current.Store(ds)
+}
+
+// NewManagedEngine creates an engine using the detectors compiled into the
+// snapshots of manager, see CompileDetectors, and following its changes.
+// Engines of the same manager share the compiled detectors.
+func NewManagedEngine(manager *config.Manager) *Engine {
+	if _, ok := manager.Snapshot().Compiled().(*DetectorSet); !ok {
+		manager.SetCompiler(CompileDetectors)
+	}
+	e := &Engine{budget: NewBudget(DefaultPatternBudget, MaxPatternOverruns)}
+	e.Use(manager.Snapshot())
+	manager.OnChange(func(config.Config) { e.Use(manager.Snapshot()) })
+	return e
+}
+
+// Use atomically swaps in the detectors compiled into c, or compiles them
+// if c has none
+func (e *Engine) Use(c *config.CompiledConfig) {
+	compiled, ok := c.Compiled().(*DetectorSet)
+	if !ok {
+		e.Reload(c.Effective)
+		return
+	}
+	ds := *compiled
+	ds.budget = e.budget
+	e.store(&ds)
+}
+
+// CompileDetectors is a config.Compiler building the DetectorSet of a
+// snapshot, for NewManagedEngine
+func CompileDetectors(c *config.CompiledConfig) interface{} {
+	ds := DefaultRegistry.NewDetectorSet(c.Effective)
+	ds.allowlist, ds.confirmed = c.Allowlist, c.ReviewConfirmed
+	return ds
+}
+
+// SetReplacementFunc makes the engine replace matches with fn, or with the
+// configured replacements if fn is nil, from now on and after reloads. It
+// may be called while the engine is in use.
+func (e *Engine) SetReplacementFunc(fn ReplacementFunc) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.replace = fn
+	e.current.Store(e.Detectors().WithReplacementFunc(fn))
+}
+
+// OnPatternDisabled registers the callback invoked with a custom pattern's
+// field name when it exceeds its time budget MaxPatternOverruns times in a
+// row. The callback runs in its own goroutine and is expected to disable
+// the pattern.
+func (e *Engine) OnPatternDisabled(callback func(field string)) {
+	e.budget.OnDisable(callback)
+}
+
+// Detectors returns the current DetectorSet
+func (e *Engine) Detectors() *DetectorSet {
+	return e.current.Load()
+}
+
+// Filter filters text with the current DetectorSet
+func (e *Engine) Filter(text string) (string, bool, ReplacementSummary) {
+	return e.Detectors().Filter(text)
+}
+
+// FilterContext is like Filter but traces the run as part of ctx and
+// honours its cancellation, see SensitiveDataContext
+func (e *Engine) FilterContext(ctx context.Context, text string) (string, bool, ReplacementSummary, error) {
+	ctx, done := telemetry.StartFilter(ctx, len(text))
+	filtered, changed, summary, err := e.Detectors().FilterContext(ctx, text)
+	done("full", len(summary.Replacements), err)
+	return filtered, changed, summary, err
+}
+
+// FilterChunked filters text in chunks with the current DetectorSet
+func (e *Engine) FilterChunked(ctx context.Context, text string, chunkSize int) (string, bool, ReplacementSummary, error) {
+	return e.Detectors().FilterChunked(ctx, text, chunkSize)
+}
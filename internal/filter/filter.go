@@ -1,28 +1,48 @@
 package filter
 
 import (
-	"regexp"
-	"strings"
+	"context"
+	"sort"
+	"time"
 
 	"github.com/happytaoer/prompt-security/internal/config"
-	"github.com/happytaoer/prompt-security/internal/patterns"
 )
 
 // Sensitive data type constants
 const (
-	SensitiveTypeEmail      = "email"
-	SensitiveTypePhone      = "phone"
-	SensitiveTypeCreditCard = "credit_card"
-	SensitiveTypeSSN        = "ssn"
-	SensitiveTypeIPV4       = "ipv4"
-	SensitiveTypeAPIKey     = "api_key"
+	SensitiveTypeEmail       = "email"
+	SensitiveTypePhone       = "phone"
+	SensitiveTypeCreditCard  = "credit_card"
+	SensitiveTypeSSN         = "ssn"
+	SensitiveTypeIPV4        = "ipv4"
+	SensitiveTypeAPIKey      = "api_key"
+	SensitiveTypeLocation    = "location"
+	SensitiveTypeHTTPAuth    = "http_auth"
+	SensitiveTypeShellPrompt = "shell_prompt"
+
+	// Infrastructure identifier types, detected together
+	SensitiveTypeGCPProject = "gcp_project"
+	SensitiveTypeAzureID    = "azure_id"
+
+	// SensitiveTypeMerchantID is detected by the China pack
+	SensitiveTypeMerchantID = "merchant_id"
+
+	// Prompt safety types, which flag attempts to subvert a model rather
+	// than sensitive data
+	SensitiveTypePromptInjection  = "prompt_injection"
+	SensitiveTypeRoleOverride     = "role_override"
+	SensitiveTypeDataExfiltration = "data_exfiltration"
 )
 
 // ReplacementInfo stores information about a single sensitive data replacement
 type ReplacementInfo struct {
-	Type        string // Type of sensitive data (email, phone, etc.)
-	Original    string // Original sensitive data
-	Replacement string // What it was replaced with
+	Type        string  // Type of sensitive data (email, phone, etc.)
+	Original    string  // Original sensitive data
+	Replacement string  // What it was replaced with
+	Start       int     // Byte offset of Original in the filtered input
+	End         int     // Byte offset just past Original
+	Action      string  // Action of the detector, empty for config.ActionReplace; log and review leave Original in place
+	Confidence  float64 // Score of the match, 0 if unscored
 }
 
 // ReplacementSummary contains all replacements made during filtering
@@ -30,68 +50,304 @@ type ReplacementSummary struct {
 	Replacements []ReplacementInfo
 }
 
-// SensitiveData filters sensitive data from text and returns the filtered text,
-// a boolean indicating whether any changes were made, and a summary of replacements
-func SensitiveData(text string, cfg config.Config) (string, bool, ReplacementSummary) {
-	original := text
-	summary := ReplacementSummary{}
-
-	// Helper function to find and replace sensitive data with regex
-	findAndReplaceRegex := func(pattern *regexp.Regexp, replacement string, dataType string) {
-		matches := pattern.FindAllString(text, -1)
-		for _, match := range matches {
-			summary.Replacements = append(summary.Replacements, ReplacementInfo{
-				Type:        dataType,
-				Original:    match,
-				Replacement: replacement,
-			})
+// actionRank orders actions by how strongly they intervene
+var actionRank = map[string]int{
+	config.ActionReview:  0,
+	config.ActionLog:     1,
+	config.ActionReplace: 2,
+	"":                   2,
+	config.ActionAsk:     3,
+	config.ActionBlock:   4,
+}
+
+// Action returns the strongest action the replacements call for: block,
+// then ask, then replace, then log, then review. Without replacements it is
+// config.ActionReplace.
+func (s ReplacementSummary) Action() string {
+	action := config.ActionReplace
+	if len(s.Replacements) > 0 {
+		action = config.ActionReview
+	}
+	for _, r := range s.Replacements {
+		if actionRank[r.Action] > actionRank[action] {
+			action = r.Action
 		}
-		text = pattern.ReplaceAllString(text, replacement)
-	}
-
-	// Helper function to find and replace sensitive data with string match
-	findAndReplaceString := func(pattern string, replacement string, dataType string) {
-		if strings.Contains(text, pattern) {
-			summary.Replacements = append(summary.Replacements, ReplacementInfo{
-				Type:        dataType,
-				Original:    pattern,
-				Replacement: replacement,
-			})
-			text = strings.ReplaceAll(text, pattern, replacement)
+	}
+	if action == "" {
+		return config.ActionReplace
+	}
+	return action
+}
+
+// Types returns the sorted, distinct types of the replacements
+func (s ReplacementSummary) Types() []string {
+	seen := make(map[string]bool)
+	var types []string
+	for _, r := range s.Replacements {
+		if !seen[r.Type] {
+			seen[r.Type] = true
+			types = append(types, r.Type)
 		}
 	}
+	sort.Strings(types)
+	return types
+}
+
+// DetectorSet is the immutable, compiled form of a configuration. Build one
+// per configuration change and share it freely between goroutines.
+type DetectorSet struct {
+	detectors  []Detector
+	priorities []int  // Priority of each detector, lower values win conflicts
+	normalize  []bool // Whether each detector matches against a Unicode-normalized view of the text
+	budget     *Budget
 
-	// Filter emails
-	if cfg.DetectEmails {
-		findAndReplaceRegex(patterns.GetEmailPattern(&cfg), cfg.EmailReplacement, SensitiveTypeEmail)
+	allowlist       map[string]bool // Matched text never treated as sensitive
+	confirmed       map[string]bool // Matched text exempt from review
+	reviewThreshold float64         // Matches scored below are queued for review
+	replace         ReplacementFunc // Overrides the replacements, nil to keep them
+}
+
+// ReplacementFunc returns the text replacing a match, letting embedders
+// implement their own strategies, such as vault tokenization or
+// format-preserving encryption. The match carries the configured
+// replacement, which the function may return to keep. It is called for
+// the matches that win their conflicts, never for those only logged or
+// queued for review, possibly concurrently and, when filtering in chunks,
+// more than once for a match seen by two windows.
+type ReplacementFunc func(m Match) string
+
+// WithReplacementFunc returns a copy of ds replacing matches with fn, or
+// with the configured replacements if fn is nil. ds itself is unchanged.
+func (ds *DetectorSet) WithReplacementFunc(fn ReplacementFunc) *DetectorSet {
+	set := *ds
+	set.replace = fn
+	return &set
+}
+
+// Enabled reports whether any detector is enabled. Filter returns its input
+// untouched, without allocating, when none is.
+func (ds *DetectorSet) Enabled() bool {
+	return len(ds.detectors) > 0
+}
+
+// NewDetectorSet compiles the detectors enabled in cfg using DefaultRegistry
+func NewDetectorSet(cfg config.Config) *DetectorSet {
+	return DefaultRegistry.NewDetectorSet(cfg)
+}
+
+// Detectors returns the detectors in execution order
+func (ds *DetectorSet) Detectors() []Detector {
+	return ds.detectors
+}
+
+// SensitiveData filters sensitive data from text and returns the filtered text,
+// a boolean indicating whether any changes were made, and a summary of replacements.
+// Callers filtering repeatedly with the same configuration should build a
+// DetectorSet once instead.
+func SensitiveData(text string, cfg config.Config) (string, bool, ReplacementSummary) {
+	if text == "" || !DefaultRegistry.mayDetect(&cfg) {
+		return text, false, ReplacementSummary{}
 	}
+	return NewDetectorSet(cfg).Filter(text)
+}
 
-	// Filter phone numbers
-	if cfg.DetectPhones {
-		findAndReplaceRegex(patterns.GetPhonePattern(&cfg), cfg.PhoneReplacement, SensitiveTypePhone)
+// SensitiveDataContext is like SensitiveData but honours ctx between
+// detectors. The whole text is scanned at once, so detectors needing the
+// whole document, such as Terraform state, see it; see FilterChunked to
+// bound the work per step instead. If ctx ends before the scan completes,
+// the context error is returned and the text should be treated as
+// unfiltered.
+func SensitiveDataContext(ctx context.Context, text string, cfg config.Config) (string, bool, ReplacementSummary, error) {
+	if err := ctx.Err(); err != nil {
+		return text, false, ReplacementSummary{}, err
+	}
+	if text == "" || !DefaultRegistry.mayDetect(&cfg) {
+		return text, false, ReplacementSummary{}, nil
 	}
+	return NewDetectorSet(cfg).FilterContext(ctx, text)
+}
 
-	// Filter credit card numbers
-	if cfg.DetectCreditCards {
-		findAndReplaceRegex(patterns.GetCreditCardPattern(&cfg), cfg.CreditCardReplacement, SensitiveTypeCreditCard)
+// FilterContext is like Filter but honours ctx, see SensitiveDataContext
+func (ds *DetectorSet) FilterContext(ctx context.Context, text string) (string, bool, ReplacementSummary, error) {
+	if !ds.Enabled() || text == "" {
+		return text, false, ReplacementSummary{}, nil
 	}
 
-	// Filter SSNs
-	if cfg.DetectSSNs {
-		findAndReplaceRegex(patterns.GetSSNPattern(&cfg), cfg.SSNReplacement, SensitiveTypeSSN)
+	s := getScratch()
+	defer putScratch(s)
+
+	matches, err := ds.matchesContext(ctx, s, text, 0)
+	if err != nil {
+		return text, false, ReplacementSummary{}, err
 	}
+	if len(matches) == 0 {
+		return text, false, ReplacementSummary{}, nil
+	}
+
+	filtered := s.apply(text, matches)
+	return filtered, filtered != text, summarize(matches), nil
+}
 
-	// Filter IPv4 addresses
-	if cfg.DetectIPV4 {
-		findAndReplaceRegex(patterns.GetIPV4Pattern(&cfg), cfg.IPV4Replacement, SensitiveTypeIPV4)
+// Filter filters sensitive data from text and returns the filtered text,
+// a boolean indicating whether any changes were made, and a summary of replacements.
+// Every detector scans the original text. Overlapping matches are resolved by
+// resolveConflicts and the summary lists replacements in text order.
+func (ds *DetectorSet) Filter(text string) (string, bool, ReplacementSummary) {
+	if !ds.Enabled() || text == "" {
+		return text, false, ReplacementSummary{}
 	}
 
-	// Filter string match patterns
-	for _, stringPattern := range cfg.StringMatchPatterns {
-		if stringPattern.Enabled {
-			findAndReplaceString(stringPattern.Pattern, stringPattern.Replacement, stringPattern.Name)
+	s := getScratch()
+	defer putScratch(s)
+
+	matches := ds.matches(s, text, 0)
+	if len(matches) == 0 {
+		return text, false, ReplacementSummary{}
+	}
+
+	filtered := s.apply(text, matches)
+	return filtered, filtered != text, summarize(matches)
+}
+
+// matches runs every detector on text and returns the resolved,
+// non-overlapping matches starting at or after from, sorted by position.
+// The result is backed by s and only valid until s is reused.
+func (ds *DetectorSet) matches(s *scratch, text string, from int) []Match {
+	matches, _ := ds.matchesContext(context.Background(), s, text, from)
+	return matches
+}
+
+// matchesContext is like matches but stops with the context error if ctx
+// ends, checking it before each detector
+func (ds *DetectorSet) matchesContext(ctx context.Context, s *scratch, text string, from int) ([]Match, error) {
+	// The normalized view is built once, for the first detector needing it
+	var n normalizedText
+	prepared, normalized := false, false
+
+	candidates := s.candidates[:0]
+	for i, d := range ds.detectors {
+		if err := ctx.Err(); err != nil {
+			s.candidates = candidates
+			return nil, err
+		}
+		if ds.normalize[i] && !prepared {
+			n = normalize(text)
+			prepared, normalized = true, n.changed(text)
+		}
+		var found []Match
+		if ds.normalize[i] && normalized {
+			found = n.mapMatches(text, ds.run(d, n.text))
+		} else {
+			found = ds.run(d, text)
+		}
+
+		for _, m := range found {
+			if m.Start < from {
+				continue
+			}
+			if ds.allowlist[m.Text] {
+				continue
+			}
+			m.Priority = ds.priorities[i]
+			if ds.needsReview(m) {
+				m.Action = config.ActionReview
+			}
+			if m.Action == config.ActionLog || m.Action == config.ActionReview {
+				m.Replacement = m.Text // Reported but left in place
+			}
+			candidates = append(candidates, candidate{Match: m, detector: i})
+		}
+	}
+	s.candidates = candidates
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+
+	s.accepted = resolveConflicts(candidates, s.accepted[:0])
+	if ds.replace != nil {
+		for i, m := range s.accepted {
+			if m.Action != config.ActionLog && m.Action != config.ActionReview {
+				s.accepted[i].Replacement = ds.replace(m)
+			}
+		}
+	}
+	return s.accepted, nil
+}
+
+// needsReview reports whether a match is too uncertain to act upon
+func (ds *DetectorSet) needsReview(m Match) bool {
+	return m.Confidence > 0 && m.Confidence < ds.reviewThreshold && !ds.confirmed[m.Text]
+}
+
+// summarize lists matches as replacements
+func summarize(matches []Match) ReplacementSummary {
+	summary := ReplacementSummary{Replacements: make([]ReplacementInfo, 0, len(matches))}
+	for _, m := range matches {
+		summary.Replacements = append(summary.Replacements, ReplacementInfo{
+			Type:        m.Type,
+			Original:    m.Text,
+			Replacement: m.Replacement,
+			Start:       m.Start,
+			End:         m.End,
+			Action:      m.Action,
+			Confidence:  m.Confidence,
+		})
+	}
+	return summary
+}
+
+// candidate is a match together with the index of the detector that found it
+type candidate struct {
+	Match
+	detector int
+}
+
+// resolveConflicts picks a non-overlapping subset of candidates, appends it
+// to accepted and returns it sorted by position. When matches overlap, the
+// one with the lowest priority value wins, then the longest, then the
+// earliest, then the one from the detector that comes first. The result
+// therefore does not depend on the order detectors reported their matches in.
+func resolveConflicts(candidates []candidate, accepted []Match) []Match {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.Priority != b.Priority {
+			return a.Priority < b.Priority
 		}
+		if la, lb := a.End-a.Start, b.End-b.Start; la != lb {
+			return la > lb
+		}
+		if a.Start != b.Start {
+			return a.Start < b.Start
+		}
+		return a.detector < b.detector
+	})
+
+	// accepted is kept sorted by Start so overlaps can be found by binary search
+	for _, c := range candidates {
+		i := sort.Search(len(accepted), func(i int) bool { return accepted[i].End > c.Start })
+		if i < len(accepted) && accepted[i].Start < c.End {
+			continue // Overlaps a match that takes precedence
+		}
+		accepted = append(accepted, Match{})
+		copy(accepted[i+1:], accepted[i:])
+		accepted[i] = c.Match
+	}
+	return accepted
+}
+
+// run runs d on text, within the time budget if d is budgeted. A scan
+// overrunning the budget is recorded and then completed without a deadline,
+// so text past the deadline is never passed on unscanned.
+func (ds *DetectorSet) run(d Detector, text string) []Match {
+	b, ok := d.(BudgetedDetector)
+	if !ok || b.BudgetKey() == "" {
+		return d.Detect(text)
 	}
 
-	return text, text != original, summary
+	matches, complete := b.DetectWithin(text, time.Now().Add(ds.budget.Limit()))
+	ds.budget.record(b.BudgetKey(), complete)
+	if !complete {
+		return d.Detect(text)
+	}
+	return matches
 }
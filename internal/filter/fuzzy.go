@@ -0,0 +1,273 @@
+package filter
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+	"golang.org/x/text/unicode/norm"
+)
+
+const (
+	// minFuzzyRunes is the length below which fuzzy terms only match
+	// exactly, ignoring case and diacritics: one edit turns short words
+	// into too many others
+	minFuzzyRunes = 5
+
+	// fuzzyConfidence scores matches one edit away from their term, so that
+	// a review threshold above it queues them
+	fuzzyConfidence = 0.8
+)
+
+// fuzzyTerm is a string match pattern indexed by a FuzzyDetector
+type fuzzyTerm struct {
+	key         string // Folded words of the pattern joined by spaces
+	words       int
+	name        string
+	replacement string
+	action      string
+}
+
+// FuzzyDetector detects the words of string match patterns, ignoring case
+// and diacritics and allowing one inserted, missing, wrong or swapped
+// character. Like SymSpell it indexes the terms by every single-character
+// deletion, so a window of text is looked up by its own deletions instead
+// of being compared with every term.
+type FuzzyDetector struct {
+	priority  int
+	normalize bool
+	terms     []fuzzyTerm
+	exact     map[string][]int // Term key -> terms, for every term
+	deletes   map[string][]int // Term key and its deletions -> terms, for terms of minFuzzyRunes or more
+	lengths   map[int][2]int   // Word count -> shortest and longest term key in runes
+	windows   []int            // Word counts of the terms, most first
+}
+
+// NewFuzzyDetector creates a detector for fuzzy string match patterns.
+// Patterns without any word characters are skipped. The detector matches
+// against normalized text if any pattern asks for it.
+func NewFuzzyDetector(patterns []config.StringMatchPattern, priority int) *FuzzyDetector {
+	d := &FuzzyDetector{
+		priority: priority,
+		exact:    make(map[string][]int),
+		deletes:  make(map[string][]int),
+		lengths:  make(map[int][2]int),
+	}
+	for _, p := range patterns {
+		words := fuzzyWords(p.Pattern)
+		if len(words) == 0 {
+			continue
+		}
+		d.normalize = d.normalize || p.Normalize
+		keys := make([]string, len(words))
+		for i, w := range words {
+			keys[i] = w.folded
+		}
+		t := fuzzyTerm{key: strings.Join(keys, " "), words: len(words), name: p.Name, replacement: p.Replacement, action: p.Action}
+		i := len(d.terms)
+		d.terms = append(d.terms, t)
+
+		d.exact[t.key] = append(d.exact[t.key], i)
+		n := utf8.RuneCountInString(t.key)
+		if n >= minFuzzyRunes {
+			for _, del := range withDeletions(t.key) {
+				d.deletes[del] = appendTerm(d.deletes[del], i)
+			}
+		}
+		bounds, ok := d.lengths[t.words]
+		if !ok {
+			bounds = [2]int{n, n}
+			d.windows = append(d.windows, t.words)
+		}
+		d.lengths[t.words] = [2]int{min(bounds[0], n), max(bounds[1], n)}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(d.windows)))
+	return d
+}
+
+// Name returns the detector's sensitive data type. Matches carry the name
+// of the pattern they matched.
+func (d *FuzzyDetector) Name() string {
+	return SensitiveTypeStringMatch
+}
+
+// Priority returns the priority shared by the detector's patterns
+func (d *FuzzyDetector) Priority() int {
+	return d.priority
+}
+
+// Normalized reports whether the patterns match against normalized text
+func (d *FuzzyDetector) Normalized() bool {
+	return d.normalize
+}
+
+// Detect returns the windows of words in text matching a term, preferring
+// the longest window at each word and exact matches over fuzzy ones
+func (d *FuzzyDetector) Detect(text string) []Match {
+	if len(d.terms) == 0 {
+		return nil
+	}
+
+	words := fuzzyWords(text)
+	var matches []Match
+	var key strings.Builder
+	for i := 0; i < len(words); {
+		matched := 0
+		for _, k := range d.windows {
+			if i+k > len(words) {
+				continue
+			}
+			key.Reset()
+			for j, w := range words[i : i+k] {
+				if j > 0 {
+					key.WriteByte(' ')
+				}
+				key.WriteString(w.folded)
+			}
+			term, distance, ok := d.lookup(key.String(), k)
+			if !ok {
+				continue
+			}
+
+			start, end := words[i].start, words[i+k-1].end
+			m := Match{
+				Type:        d.terms[term].name,
+				Start:       start,
+				End:         end,
+				Text:        text[start:end],
+				Replacement: d.terms[term].replacement,
+				Action:      d.terms[term].action,
+			}
+			if distance > 0 {
+				m.Confidence = fuzzyConfidence
+			}
+			matches = append(matches, m)
+			matched = k
+			break
+		}
+		if matched == 0 {
+			matched = 1
+		}
+		i += matched
+	}
+	return matches
+}
+
+// lookup returns the first term of words words matching key exactly, or
+// else one edit away
+func (d *FuzzyDetector) lookup(key string, words int) (int, int, bool) {
+	if terms, ok := d.exact[key]; ok {
+		return terms[0], 0, true
+	}
+
+	n := utf8.RuneCountInString(key)
+	bounds := d.lengths[words]
+	if n < minFuzzyRunes-1 || n < bounds[0]-1 || n > bounds[1]+1 {
+		return 0, 0, false
+	}
+	best := -1
+	for _, del := range withDeletions(key) {
+		for _, t := range d.deletes[del] {
+			if (best < 0 || t < best) && d.terms[t].words == words && withinOneEdit(key, d.terms[t].key) {
+				best = t
+			}
+		}
+	}
+	return best, 1, best >= 0
+}
+
+// appendTerm appends term to terms unless it is already the last one, as
+// deletions of repeated characters coincide
+func appendTerm(terms []int, term int) []int {
+	if len(terms) > 0 && terms[len(terms)-1] == term {
+		return terms
+	}
+	return append(terms, term)
+}
+
+// withDeletions returns s and every string made by deleting one of its
+// characters
+func withDeletions(s string) []string {
+	result := []string{s}
+	for i, r := range s {
+		result = append(result, s[:i]+s[i+utf8.RuneLen(r):])
+	}
+	return result
+}
+
+// withinOneEdit reports whether a and b differ by at most one inserted,
+// deleted, substituted or transposed character
+func withinOneEdit(a, b string) bool {
+	ra, rb := []rune(a), []rune(b)
+	if len(ra) < len(rb) {
+		ra, rb = rb, ra
+	}
+	if len(ra)-len(rb) > 1 {
+		return false
+	}
+
+	i := 0
+	for i < len(rb) && ra[i] == rb[i] {
+		i++
+	}
+	if i == len(rb) {
+		return true // Equal, or a extends b by one character
+	}
+	if len(ra) > len(rb) {
+		return string(ra[i+1:]) == string(rb[i:]) // Deletion
+	}
+	if string(ra[i+1:]) == string(rb[i+1:]) {
+		return true // Substitution
+	}
+	return i+1 < len(ra) && ra[i] == rb[i+1] && ra[i+1] == rb[i] && string(ra[i+2:]) == string(rb[i+2:]) // Transposition
+}
+
+// fuzzyWord is a word of a text, folded for comparison
+type fuzzyWord struct {
+	start, end int // Byte offsets in the text
+	folded     string
+}
+
+// fuzzyWords splits text into words of letters, digits and underscores,
+// folded to lower case without diacritics
+func fuzzyWords(text string) []fuzzyWord {
+	var words []fuzzyWord
+	var folded strings.Builder
+	start := -1
+	for i, r := range text {
+		if isWordRune(r) {
+			if start < 0 {
+				start = i
+				folded.Reset()
+			}
+			folded.WriteRune(foldFuzzyRune(r))
+			continue
+		}
+		if start >= 0 {
+			words = append(words, fuzzyWord{start: start, end: i, folded: folded.String()})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		words = append(words, fuzzyWord{start: start, end: len(text), folded: folded.String()})
+	}
+	return words
+}
+
+// foldFuzzyRune lowers r and strips its diacritics, e.g. É to e
+func foldFuzzyRune(r rune) rune {
+	r = unicode.ToLower(r)
+	if r < utf8.RuneSelf {
+		return r
+	}
+	decomposed := norm.NFD.String(string(r))
+	base, size := utf8.DecodeRuneInString(decomposed)
+	for _, mark := range decomposed[size:] {
+		if !unicode.Is(unicode.Mn, mark) {
+			return r
+		}
+	}
+	return base
+}
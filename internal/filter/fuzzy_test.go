@@ -0,0 +1,89 @@
+package filter
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+)
+
+// TestFuzzyDetector tests that fuzzy patterns match terms one edit away,
+// ignoring case and diacritics, with a lower confidence than exact matches,
+// and that matches carry the settings of their pattern
+func TestFuzzyDetector(t *testing.T) {
+	d := NewFuzzyDetector([]config.StringMatchPattern{
+		{Name: "project", Pattern: "Bluebird", Replacement: "[PROJECT]", Fuzzy: true},
+		{Name: "customer", Pattern: "Zoë Müller", Replacement: "[CUSTOMER]", Action: config.ActionBlock, Fuzzy: true},
+		{Name: "project", Pattern: "Orca", Replacement: "[PROJECT]", Fuzzy: true},
+		{Name: "project", Pattern: "!!!", Replacement: "[PROJECT]", Fuzzy: true},
+	}, 0)
+
+	tests := []struct {
+		text string
+		want string // Matched texts and confidences
+	}{
+		{"ship bluebird today", "[bluebird/0]"},
+		{"ship Bluebrid today", "[Bluebrid/0.8]"}, // Swapped
+		{"ship Bleubird, Bluebirds", "[Bleubird/0.8 Bluebirds/0.8]"},
+		{"ship Blubird today", "[Blubird/0.8]"},   // Missing
+		{"ship Bluebyrd today", "[Bluebyrd/0.8]"}, // Wrong
+		{"ship Blueberry today", "[]"},            // Two edits
+		{"call ZOE MULLER now", "[ZOE MULLER/0]"}, // Case and diacritics
+		{"call Zoe Muler now", "[Zoe Muler/0.8]"},
+		{"call Zoe now", "[]"},
+		{"the orca and the orcs", "[orca/0]"}, // Short terms match exactly
+		{"!!! Bluebird!!!", "[Bluebird/0]"},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, m := range d.Detect(tt.text) {
+			got = append(got, fmt.Sprintf("%s/%g", m.Text, m.Confidence))
+		}
+		if s := fmt.Sprint(got); s != tt.want {
+			t.Errorf("Detect(%q) = %s, want %s", tt.text, s, tt.want)
+		}
+	}
+
+	m := d.Detect("from zoe muller")[0]
+	if m.Type != "customer" || m.Replacement != "[CUSTOMER]" || m.Action != config.ActionBlock || m.Start != 5 || m.End != 15 {
+		t.Errorf("Expected the match to carry its pattern's settings, got %+v", m)
+	}
+}
+
+// TestWithinOneEdit tests the edit distance check, counting a swap of
+// neighbours as one edit
+func TestWithinOneEdit(t *testing.T) {
+	tests := []struct {
+		a, b string
+		want bool
+	}{
+		{"abc", "abc", true},
+		{"abc", "abcd", true},
+		{"abc", "xabc", true},
+		{"abc", "axc", true},
+		{"abc", "bac", true},
+		{"abc", "cba", false},
+		{"abc", "a", false},
+		{"müller", "muller", true},
+	}
+	for _, tt := range tests {
+		if got := withinOneEdit(tt.a, tt.b); got != tt.want {
+			t.Errorf("withinOneEdit(%q, %q) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+// BenchmarkFuzzyDetector measures fuzzy matching against a large employee
+// list
+func BenchmarkFuzzyDetector(b *testing.B) {
+	var patterns []config.StringMatchPattern
+	for i := 0; i < 5000; i++ {
+		patterns = append(patterns, config.StringMatchPattern{Name: "employee", Pattern: fmt.Sprintf("Person%04d Surname", i), Replacement: "[EMPLOYEE]", Fuzzy: true})
+	}
+	d := NewFuzzyDetector(patterns, 0)
+	text := "Please forward the notes to Persn1234 Surname and the rest of the team by Friday. "
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		d.Detect(text)
+	}
+}
@@ -0,0 +1,115 @@
+package filter
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/happytaoer/prompt-security/internal/patterns"
+)
+
+// SensitiveTypeHomePath is the type of user names found in home directory
+// paths
+const SensitiveTypeHomePath = "home_path"
+
+// homePathMinUser is the shortest user name also replaced outside paths, so
+// that short names such as "al" do not replace parts of ordinary words
+const homePathMinUser = 3
+
+// genericHomeUsers are home directories that do not belong to a person
+var genericHomeUsers = map[string]bool{
+	"shared": true, "public": true, "default": true, "default user": true, "all users": true,
+	"user": true, "username": true, "me": true, "you": true,
+}
+
+// HomePathDetector anonymizes home directory paths in pasted stack traces
+// and logs. It replaces the user name of each path, keeping the rest of the
+// path, and then every other whole-word occurrence of the names found, as
+// logs often print them elsewhere too (user=jane).
+type HomePathDetector struct {
+	replacement string
+}
+
+// NewHomePathDetector creates a detector replacing user names with
+// replacement
+func NewHomePathDetector(replacement string) *HomePathDetector {
+	return &HomePathDetector{replacement: replacement}
+}
+
+// Name returns the detector's sensitive data type
+func (d *HomePathDetector) Name() string {
+	return SensitiveTypeHomePath
+}
+
+// Detect returns the user names of the home directory paths in text and
+// their other occurrences
+func (d *HomePathDetector) Detect(text string) []Match {
+	var spans [][2]int
+	var users []string
+	seen := make(map[string]bool)
+	pattern := patterns.HomePathPattern
+	for _, loc := range pattern.FindAllStringSubmatchIndex(text, -1) {
+		for g, name := range pattern.SubexpNames() {
+			if name != "user" || loc[2*g] < 0 {
+				continue
+			}
+			user := text[loc[2*g]:loc[2*g+1]]
+			if !d.personal(user) {
+				continue
+			}
+			spans = append(spans, [2]int{loc[2*g], loc[2*g+1]})
+			if key := strings.ToLower(user); !seen[key] && utf8.RuneCountInString(user) >= homePathMinUser {
+				seen[key] = true
+				users = append(users, user)
+			}
+		}
+	}
+	spans = append(spans, userOccurrences(text, users)...)
+
+	// The user name of a path is found twice
+	matches := make([]Match, 0, len(spans))
+	for _, s := range spans {
+		matches = append(matches, Match{
+			Type:        SensitiveTypeHomePath,
+			Start:       s[0],
+			End:         s[1],
+			Text:        text[s[0]:s[1]],
+			Replacement: d.replacement,
+		})
+	}
+	return disjoint(matches)
+}
+
+// personal reports whether user names a person rather than a shared
+// directory, a variable such as $USER or %USERNAME%, or a replaced name
+func (d *HomePathDetector) personal(user string) bool {
+	if genericHomeUsers[strings.ToLower(user)] || user == d.replacement {
+		return false
+	}
+	return !strings.ContainsAny(user[:1], "$%<{~")
+}
+
+// userOccurrences returns the spans of the whole-word occurrences of users
+// in text, ignoring case
+func userOccurrences(text string, users []string) [][2]int {
+	if len(users) == 0 {
+		return nil
+	}
+	sort.Slice(users, func(i, j int) bool { return len(users[i]) > len(users[j]) })
+	quoted := make([]string, len(users))
+	for i, u := range users {
+		quoted[i] = regexp.QuoteMeta(u)
+	}
+	re := regexp.MustCompile(`(?i)` + strings.Join(quoted, "|"))
+
+	var spans [][2]int
+	for _, loc := range re.FindAllStringIndex(text, -1) {
+		before, _ := utf8.DecodeLastRuneInString(text[:loc[0]])
+		after, _ := utf8.DecodeRuneInString(text[loc[1]:])
+		if (loc[0] == 0 || !isWordRune(before)) && (loc[1] == len(text) || !isWordRune(after)) {
+			spans = append(spans, [2]int{loc[0], loc[1]})
+		}
+	}
+	return spans
+}
@@ -0,0 +1,163 @@
+package filter
+
+import (
+	"html"
+	"strings"
+
+	xhtml "golang.org/x/net/html"
+)
+
+// textAttributes are the attributes whose values carry text worth filtering;
+// data-* attributes are filtered too. Others, like class or style, are kept.
+var textAttributes = map[string]bool{
+	"href":        true,
+	"src":         true,
+	"title":       true,
+	"alt":         true,
+	"value":       true,
+	"placeholder": true,
+	"content":     true,
+	"cite":        true,
+	"action":      true,
+	"label":       true,
+	"aria-label":  true,
+}
+
+// rawTextElements hold unescaped text, such as scripts
+var rawTextElements = map[string]bool{
+	"script":    true,
+	"style":     true,
+	"xmp":       true,
+	"iframe":    true,
+	"noembed":   true,
+	"noframes":  true,
+	"noscript":  true,
+	"plaintext": true,
+}
+
+// FilterHTML filters an HTML document or fragment: its text nodes, comments
+// and the attributes carrying text, including href (so mailto links are
+// redacted). Markup is copied as is and replacements are escaped, so the
+// result stays valid HTML.
+//
+// Replacement offsets refer to the input. Where text had to be re-encoded,
+// because it contained character references or sat in an attribute, the whole
+// text node or tag is reported as one replacement, typed after its first match.
+func (ds *DetectorSet) FilterHTML(src string) (string, bool, ReplacementSummary) {
+	if !ds.Enabled() || src == "" {
+		return src, false, ReplacementSummary{}
+	}
+
+	var out strings.Builder
+	var summary ReplacementSummary
+	offset := 0
+	rawText := false
+
+	z := xhtml.NewTokenizer(strings.NewReader(src))
+	for {
+		tt := z.Next()
+		if tt == xhtml.ErrorToken {
+			// Only io.EOF is possible when reading from a string
+			break
+		}
+		raw := string(z.Raw())
+		start := offset
+		offset += len(raw)
+
+		replaced, found, exact := raw, []ReplacementInfo(nil), false
+		switch tt {
+		case xhtml.TextToken:
+			replaced, found, exact = ds.filterHTMLText(raw, start, rawText)
+		case xhtml.CommentToken:
+			tok := z.Token()
+			if filtered, changed, s := ds.Filter(tok.Data); len(s.Replacements) > 0 {
+				found = s.Replacements
+				if changed {
+					tok.Data = filtered
+					replaced = tok.String()
+				}
+			}
+		case xhtml.StartTagToken, xhtml.SelfClosingTagToken:
+			tok := z.Token()
+			if tt == xhtml.StartTagToken {
+				rawText = rawTextElements[tok.Data]
+			}
+			rewrite := false
+			for i, attr := range tok.Attr {
+				if !textAttributes[attr.Key] && !strings.HasPrefix(attr.Key, "data-") {
+					continue
+				}
+				if filtered, changed, s := ds.Filter(attr.Val); len(s.Replacements) > 0 {
+					found = append(found, s.Replacements...)
+					if changed {
+						tok.Attr[i].Val = filtered
+						rewrite = true
+					}
+				}
+			}
+			if rewrite {
+				replaced = tok.String()
+			}
+		default:
+			rawText = false
+		}
+
+		if len(found) > 0 && !exact {
+			// Re-encoded: report the token as a whole
+			action := ReplacementSummary{Replacements: found}.Action()
+			found = []ReplacementInfo{{Type: found[0].Type, Original: raw, Replacement: replaced, Start: start, End: offset, Action: action}}
+		}
+		summary.Replacements = append(summary.Replacements, found...)
+		out.WriteString(replaced)
+	}
+
+	if len(summary.Replacements) == 0 {
+		return src, false, ReplacementSummary{}
+	}
+	filtered := out.String()
+	return filtered, filtered != src, summary
+}
+
+// filterHTMLText filters a text token found at offset start. Text without
+// character references is filtered in place and reported exactly; other
+// text is decoded, filtered and re-encoded.
+func (ds *DetectorSet) filterHTMLText(raw string, start int, rawText bool) (string, []ReplacementInfo, bool) {
+	if !rawText && strings.Contains(raw, "&") {
+		filtered, changed, summary := ds.Filter(html.UnescapeString(raw))
+		if !changed {
+			// Only matches left in place, if any
+			return raw, summary.Replacements, false
+		}
+		return html.EscapeString(filtered), summary.Replacements, false
+	}
+
+	filtered, changed, summary := ds.Filter(raw)
+	if len(summary.Replacements) == 0 {
+		return raw, nil, true
+	}
+	if changed && !rawText {
+		filtered = escapeReplacements(raw, summary.Replacements)
+	}
+	for i := range summary.Replacements {
+		summary.Replacements[i].Start += start
+		summary.Replacements[i].End += start
+	}
+	return filtered, summary.Replacements, true
+}
+
+// escapeReplacements applies replacements to text, HTML-escaping each
+// replacement
+func escapeReplacements(text string, replacements []ReplacementInfo) string {
+	var b strings.Builder
+	last := 0
+	for i, r := range replacements {
+		escaped := html.EscapeString(r.Replacement)
+		b.WriteString(text[last:r.Start])
+		b.WriteString(escaped)
+		last = r.End
+
+		replacements[i].Replacement = escaped
+	}
+	b.WriteString(text[last:])
+	return b.String()
+}
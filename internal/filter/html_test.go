@@ -0,0 +1,105 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+)
+
+// TestFilterHTML tests that text nodes and text attributes are redacted
+// while the markup is kept
+func TestFilterHTML(t *testing.T) {
+	ds := NewDetectorSet(config.Config{
+		DetectEmails:     true,
+		EmailReplacement: "<EMAIL>",
+		DetectSSNs:       true,
+		SSNReplacement:   "[SSN]",
+	})
+
+	tests := []struct {
+		name    string
+		input   string
+		want    string
+		changed bool
+	}{
+		{
+			name:    "text node",
+			input:   `<p class="note">Mail a@example.com now</p>`,
+			want:    `<p class="note">Mail &lt;EMAIL&gt; now</p>`,
+			changed: true,
+		},
+		{
+			name:    "mailto link",
+			input:   `<a href="mailto:bob@example.com" title="Bob">Bob</a>`,
+			want:    `<a href="mailto:&lt;EMAIL&gt;" title="Bob">Bob</a>`,
+			changed: true,
+		},
+		{
+			name:    "character references",
+			input:   `<td>SSN&nbsp;123-45-6789 &amp; more</td>`,
+			want:    "<td>SSN\u00a0[SSN] &amp; more</td>",
+			changed: true,
+		},
+		{
+			name:    "comment",
+			input:   `<!-- owner: carol@example.com --><b>hi</b>`,
+			want:    `<!-- owner: <EMAIL> --><b>hi</b>`,
+			changed: true,
+		},
+		{
+			name:    "other attributes kept",
+			input:   `<div style="margin: 0" class="x@y.com">plain</div>`,
+			want:    `<div style="margin: 0" class="x@y.com">plain</div>`,
+			changed: false,
+		},
+		{
+			name:    "fragment markup kept verbatim",
+			input:   "<meta charset='utf-8'><ul><li>one<li>two</ul>",
+			want:    "<meta charset='utf-8'><ul><li>one<li>two</ul>",
+			changed: false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, changed, _ := ds.FilterHTML(tt.input)
+			if got != tt.want || changed != tt.changed {
+				t.Errorf("Expected %q (changed=%v), got %q (changed=%v)", tt.want, tt.changed, got, changed)
+			}
+		})
+	}
+}
+
+// TestFilterHTML_Offsets tests that replacements locate what was replaced in
+// the input, exactly for plain text and as the whole tag for attributes
+func TestFilterHTML_Offsets(t *testing.T) {
+	ds := NewDetectorSet(config.Config{DetectEmails: true, EmailReplacement: "[EMAIL]"})
+
+	input := `<a href="mailto:bob@example.com">Write to dave@example.com</a>`
+	filtered, _, summary := ds.FilterHTML(input)
+	if len(summary.Replacements) != 2 {
+		t.Fatalf("Expected 2 replacements, got %+v", summary.Replacements)
+	}
+
+	// Applying the replacements to the input must give the filtered output
+	var rebuilt string
+	last := 0
+	for _, r := range summary.Replacements {
+		if input[r.Start:r.End] != r.Original {
+			t.Errorf("Expected %q at %d:%d, got %q", r.Original, r.Start, r.End, input[r.Start:r.End])
+		}
+		rebuilt += input[last:r.Start] + r.Replacement
+		last = r.End
+	}
+	rebuilt += input[last:]
+	if rebuilt != filtered {
+		t.Errorf("Expected replacements to rebuild %q, got %q", filtered, rebuilt)
+	}
+
+	if tag := summary.Replacements[0]; tag.Original != `<a href="mailto:bob@example.com">` || tag.Type != SensitiveTypeEmail {
+		t.Errorf("Expected the tag to be reported whole, got %+v", tag)
+	}
+	if text := summary.Replacements[1]; text.Original != "dave@example.com" {
+		t.Errorf("Expected the text match to be exact, got %+v", text)
+	}
+}
@@ -0,0 +1,59 @@
+package filter
+
+import "strings"
+
+// FilterIncremental filters text given that previous, which text contains,
+// was already filtered by this DetectorSet without any change. Only the text
+// added before and after previous is scanned, together with ChunkOverlap
+// bytes of context so matches crossing into previous are still found.
+//
+// It reports ok=false, without filtering, when text does not contain
+// previous; callers should then fall back to Filter.
+func (ds *DetectorSet) FilterIncremental(previous, text string) (filtered string, changed bool, summary ReplacementSummary, ok bool) {
+	if previous == "" {
+		return text, false, ReplacementSummary{}, false
+	}
+	at := strings.Index(text, previous)
+	if at < 0 {
+		return text, false, ReplacementSummary{}, false
+	}
+	if !ds.Enabled() || len(text) == len(previous) {
+		return text, false, ReplacementSummary{}, true
+	}
+	if len(previous) <= 2*ChunkOverlap {
+		// Too short for the contexts of both added regions to stay apart
+		filtered, changed, summary = ds.Filter(text)
+		return filtered, changed, summary, true
+	}
+
+	s := getScratch()
+	defer putScratch(s)
+
+	// The added regions; either is empty when nothing was added on that side
+	added := [2][2]int{{0, at}, {at + len(previous), len(text)}}
+
+	var accepted []Match
+	for _, r := range added {
+		start, end := r[0], r[1]
+		if start == end {
+			continue
+		}
+		windowStart := runeBoundary(text, start-ChunkOverlap, false)
+		windowEnd := runeBoundary(text, end+ChunkOverlap, true)
+
+		// Matches lying entirely inside previous were ruled out by its scan
+		for _, m := range ds.matches(s, text[windowStart:windowEnd], 0) {
+			m.Start += windowStart
+			m.End += windowStart
+			if m.End > start && m.Start < end {
+				accepted = append(accepted, m)
+			}
+		}
+	}
+	if len(accepted) == 0 {
+		return text, false, ReplacementSummary{}, true
+	}
+
+	filtered = s.apply(text, accepted)
+	return filtered, filtered != text, summarize(accepted), true
+}
@@ -0,0 +1,70 @@
+package filter
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+)
+
+// TestFilterIncremental_MatchesFilter tests that scanning only the added text
+// gives the same result as a full scan, including matches that cross into
+// the previous content
+func TestFilterIncremental_MatchesFilter(t *testing.T) {
+	ds := NewDetectorSet(config.Config{
+		DetectEmails:     true,
+		EmailReplacement: "[EMAIL]",
+		DetectSSNs:       true,
+		SSNReplacement:   "[SSN]",
+	})
+
+	previous := "user" + strings.Repeat("plain text without secrets ", 200) + "ends with bob"
+	if _, changed, _ := ds.Filter(previous); changed {
+		t.Fatal("Expected previous content to be clean")
+	}
+
+	tests := []struct {
+		name string
+		text string
+	}{
+		{"appended", previous + "\nssn 123-45-6789"},
+		{"appended across boundary", previous + "@example.com and more"},
+		{"prepended across boundary", "mail alice." + previous},
+		{"both sides", "123-45-6789 " + previous + "@corp.example"},
+		{"nothing sensitive added", previous + " and some more words"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			want, wantChanged, wantSummary := ds.Filter(tt.text)
+			got, changed, summary, ok := ds.FilterIncremental(previous, tt.text)
+			if !ok {
+				t.Fatal("Expected incremental scan to apply")
+			}
+			if got != want || changed != wantChanged {
+				t.Errorf("Expected %q, got %q", want, got)
+			}
+			if len(summary.Replacements) != len(wantSummary.Replacements) {
+				t.Errorf("Expected %d replacements, got %d", len(wantSummary.Replacements), len(summary.Replacements))
+			}
+		})
+	}
+}
+
+// TestFilterIncremental_Fallback tests when the incremental scan does not apply
+func TestFilterIncremental_Fallback(t *testing.T) {
+	ds := NewDetectorSet(config.Config{DetectEmails: true, EmailReplacement: "[EMAIL]"})
+
+	if _, _, _, ok := ds.FilterIncremental("hello world", "goodbye a@b.com"); ok {
+		t.Error("Expected no incremental scan when text does not contain previous")
+	}
+	if _, _, _, ok := ds.FilterIncremental("", "a@b.com"); ok {
+		t.Error("Expected no incremental scan without previous content")
+	}
+
+	// Short previous content is rescanned in full
+	got, changed, _, ok := ds.FilterIncremental("contact", "contact a@b.com")
+	if !ok || !changed || got != "contact [EMAIL]" {
+		t.Errorf("Expected full rescan of short content, got %q, %v, %v", got, changed, ok)
+	}
+}
@@ -0,0 +1,81 @@
+package filter
+
+import (
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/happytaoer/prompt-security/internal/patterns"
+)
+
+// SensitiveTypeLicenseKey is the type of product license keys
+const SensitiveTypeLicenseKey = "license_key"
+
+// licenseContextWindow is how many bytes before a key are searched for a
+// word introducing it, such as "license" or "serial"
+const licenseContextWindow = 100
+
+// LicenseKeyDetector detects product license keys of the configured formats
+// when a word such as "license", "serial" or "product key" precedes them,
+// as grouped codes alone are too common in logs and tickets
+type LicenseKeyDetector struct {
+	pattern     *regexp.Regexp
+	replacement string
+}
+
+// NewLicenseKeyDetector creates a detector replacing the keys matched by
+// pattern with replacement
+func NewLicenseKeyDetector(pattern *regexp.Regexp, replacement string) *LicenseKeyDetector {
+	return &LicenseKeyDetector{pattern: pattern, replacement: replacement}
+}
+
+// Name returns the detector's sensitive data type
+func (d *LicenseKeyDetector) Name() string {
+	return SensitiveTypeLicenseKey
+}
+
+// Detect returns the license keys in text
+func (d *LicenseKeyDetector) Detect(text string) []Match {
+	var matches []Match
+	for _, loc := range d.pattern.FindAllStringIndex(text, -1) {
+		if !licenseKeyBounded(text, loc[0], loc[1]) {
+			continue
+		}
+		if !patterns.LicenseContextPattern.MatchString(text[max(0, loc[0]-licenseContextWindow):loc[0]]) {
+			continue
+		}
+		matches = append(matches, Match{
+			Type:        SensitiveTypeLicenseKey,
+			Start:       loc[0],
+			End:         loc[1],
+			Text:        text[loc[0]:loc[1]],
+			Replacement: d.replacement,
+			Confidence:  licenseKeyConfidence(text[loc[0]:loc[1]]),
+		})
+	}
+	return matches
+}
+
+// licenseKeyBounded reports whether text[start:end] is a whole key rather
+// than part of a longer code or word
+func licenseKeyBounded(text string, start, end int) bool {
+	before, _ := utf8.DecodeLastRuneInString(text[:start])
+	after, _ := utf8.DecodeRuneInString(text[end:])
+	return (start == 0 || !isWordRune(before) && before != '-') &&
+		(end == len(text) || !isWordRune(after) && after != '-')
+}
+
+// licenseKeyConfidence scores a license key, doubting placeholders such as
+// XXXXX-XXXXX-XXXXX-XXXXX and keys of a single repeated character
+func licenseKeyConfidence(text string) float64 {
+	chars := strings.ToUpper(strings.Map(func(r rune) rune {
+		if isWordRune(r) {
+			return r
+		}
+		return -1
+	}, text))
+	if strings.Trim(chars, chars[:1]) == "" {
+		return confidencePlaceholder
+	}
+	return 1
+}
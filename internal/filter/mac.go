@@ -0,0 +1,104 @@
+package filter
+
+import (
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/happytaoer/prompt-security/internal/patterns"
+)
+
+// SensitiveTypeMAC is the type of MAC addresses and EUI-64 identifiers
+const SensitiveTypeMAC = "mac_address"
+
+// MACDetector detects MAC addresses and EUI-64 identifiers in colon,
+// hyphen and Cisco dotted notation. It may allow locally administered
+// addresses, which operating systems randomize per network for privacy, so
+// they identify no device.
+type MACDetector struct {
+	replacement string
+	allowLocal  bool
+}
+
+// NewMACDetector creates a detector replacing hardware addresses with
+// replacement, skipping locally administered ones if allowLocal is set
+func NewMACDetector(replacement string, allowLocal bool) *MACDetector {
+	return &MACDetector{replacement: replacement, allowLocal: allowLocal}
+}
+
+// Name returns the detector's sensitive data type
+func (d *MACDetector) Name() string {
+	return SensitiveTypeMAC
+}
+
+// Detect returns the hardware addresses in text
+func (d *MACDetector) Detect(text string) []Match {
+	var matches []Match
+	for _, loc := range patterns.MACPattern.FindAllStringIndex(text, -1) {
+		address := text[loc[0]:loc[1]]
+		if !macBounded(text, loc[0], loc[1]) || d.allowLocal && macLocallyAdministered(address) {
+			continue
+		}
+		matches = append(matches, Match{
+			Type:        SensitiveTypeMAC,
+			Start:       loc[0],
+			End:         loc[1],
+			Text:        address,
+			Replacement: d.replacement,
+			Confidence:  macConfidence(address),
+		})
+	}
+	return matches
+}
+
+// macBounded reports whether text[start:end] is a whole address rather than
+// part of a longer hexadecimal sequence, such as an IPv6 address
+func macBounded(text string, start, end int) bool {
+	before, _ := utf8.DecodeLastRuneInString(text[:start])
+	if start > 0 && (isWordRune(before) || before == '.') {
+		return false
+	}
+	if end == len(text) {
+		return true
+	}
+	after, _ := utf8.DecodeRuneInString(text[end:])
+	if isWordRune(after) {
+		return false
+	}
+	// A separator followed by more hexadecimal digits continues the sequence
+	if strings.ContainsRune(":-.", after) && end+1 < len(text) && isHexDigit(text[end+1]) {
+		return false
+	}
+	return true
+}
+
+// isHexDigit reports whether c is a hexadecimal digit
+func isHexDigit(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+// macFirstOctet returns the first octet of a hardware address
+func macFirstOctet(address string) byte {
+	octet, _ := strconv.ParseUint(address[:2], 16, 8)
+	return byte(octet)
+}
+
+// macLocallyAdministered reports whether the address has the locally
+// administered bit set, as randomized addresses do
+func macLocallyAdministered(address string) bool {
+	return macFirstOctet(address)&0x02 != 0
+}
+
+// macConfidence scores a hardware address, doubting the all-zero and
+// broadcast addresses and dotted addresses of digits only, which look like
+// version numbers
+func macConfidence(address string) float64 {
+	hex := strings.ToLower(strings.NewReplacer(":", "", "-", "", ".", "").Replace(address))
+	if strings.Trim(hex, "0") == "" || strings.Trim(hex, "f") == "" {
+		return confidencePlaceholder
+	}
+	if strings.Contains(address, ".") && strings.Trim(hex, "0123456789") == "" {
+		return confidenceVersionLikeMAC
+	}
+	return 1
+}
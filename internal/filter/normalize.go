@@ -0,0 +1,157 @@
+package filter
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// zeroWidth lists invisible characters commonly used to split words so
+// that they evade pattern matching
+var zeroWidth = map[rune]bool{
+	'\u00AD': true, // Soft hyphen
+	'\u180E': true, // Mongolian vowel separator
+	'\u200B': true, // Zero width space
+	'\u200C': true, // Zero width non-joiner
+	'\u200D': true, // Zero width joiner
+	'\u2060': true, // Word joiner
+	'\u2062': true, // Invisible times
+	'\u2063': true, // Invisible separator
+	'\u2064': true, // Invisible plus
+	'\uFEFF': true, // Zero width no-break space
+}
+
+// confusables folds look-alike characters that NFKC leaves alone onto the
+// ASCII characters they imitate
+var confusables = map[rune]string{
+	'·': ".", // Middle dot
+	'․': ".", // One dot leader
+	'‧': ".", // Hyphenation point
+	'。': ".", // Ideographic full stop
+	'‐': "-", // Hyphen
+	'‑': "-", // Non-breaking hyphen
+	'‒': "-", // Figure dash
+	'–': "-", // En dash
+	'−': "-", // Minus sign
+	'а': "a", // Cyrillic small a
+	'е': "e", // Cyrillic small ie
+	'о': "o", // Cyrillic small o
+	'р': "p", // Cyrillic small er
+	'с': "c", // Cyrillic small es
+	'х': "x", // Cyrillic small ha
+	'у': "y", // Cyrillic small u
+	'і': "i", // Cyrillic small byelorussian-ukrainian i
+	'ј': "j", // Cyrillic small je
+	'ѕ': "s", // Cyrillic small dze
+	'А': "A", // Cyrillic capital a
+	'В': "B", // Cyrillic capital ve
+	'Е': "E", // Cyrillic capital ie
+	'К': "K", // Cyrillic capital ka
+	'М': "M", // Cyrillic capital em
+	'Н': "H", // Cyrillic capital en
+	'О': "O", // Cyrillic capital o
+	'Р': "P", // Cyrillic capital er
+	'С': "C", // Cyrillic capital es
+	'Т': "T", // Cyrillic capital te
+	'Х': "X", // Cyrillic capital ha
+	'ο': "o", // Greek small omicron
+	'Ο': "O", // Greek capital omicron
+	'Α': "A", // Greek capital alpha
+	'Β': "B", // Greek capital beta
+	'Ε': "E", // Greek capital epsilon
+	'Η': "H", // Greek capital eta
+	'Ι': "I", // Greek capital iota
+	'Κ': "K", // Greek capital kappa
+	'Μ': "M", // Greek capital mu
+	'Ν': "N", // Greek capital nu
+	'Ρ': "P", // Greek capital rho
+	'Τ': "T", // Greek capital tau
+	'Χ': "X", // Greek capital chi
+}
+
+// normalizedText is a normalized view of a text that remembers which
+// original bytes produced each normalized byte
+type normalizedText struct {
+	text  string
+	start []int // Original start offset of the rune that produced each byte
+	end   []int // Original end offset of the rune that produced each byte
+}
+
+// normalize applies NFKC per character, folds confusables and strips
+// zero-width characters. Normalizing per character keeps the offset mapping
+// exact at the cost of not composing across characters, which matching
+// sensitive data does not need.
+func normalize(text string) normalizedText {
+	var b strings.Builder
+	b.Grow(len(text))
+	n := normalizedText{
+		start: make([]int, 0, len(text)),
+		end:   make([]int, 0, len(text)),
+	}
+
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		next := i + size
+
+		var out string
+		switch {
+		case zeroWidth[r]:
+			out = ""
+		case r < utf8.RuneSelf:
+			out = text[i:next]
+		default:
+			out = foldRune(r, text[i:next])
+		}
+
+		b.WriteString(out)
+		for j := 0; j < len(out); j++ {
+			n.start = append(n.start, i)
+			n.end = append(n.end, next)
+		}
+		i = next
+	}
+
+	n.text = b.String()
+	return n
+}
+
+// foldRune returns the normalized form of the non-ASCII rune r encoded as s
+func foldRune(r rune, s string) string {
+	if folded, ok := confusables[r]; ok {
+		return folded
+	}
+	out := norm.NFKC.String(s)
+	if nr, size := utf8.DecodeRuneInString(out); size == len(out) {
+		if folded, ok := confusables[nr]; ok {
+			return folded
+		}
+	}
+	return out
+}
+
+// changed reports whether normalization altered the text
+func (n normalizedText) changed(original string) bool {
+	return n.text != original
+}
+
+// mapMatches converts matches found in the normalized text to matches over
+// the original text. Matches that would overlap after mapping are dropped.
+func (n normalizedText) mapMatches(original string, matches []Match) []Match {
+	mapped := make([]Match, 0, len(matches))
+	last := 0
+	for _, m := range matches {
+		if m.End <= m.Start {
+			continue
+		}
+		start, end := n.start[m.Start], n.end[m.End-1]
+		if start < last {
+			continue
+		}
+		m.Start, m.End = start, end
+		m.Text = original[start:end]
+		mapped = append(mapped, m)
+		last = end
+	}
+	return mapped
+}
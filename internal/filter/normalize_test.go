@@ -0,0 +1,105 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+)
+
+// TestNormalize_Obfuscation tests that obfuscated data is detected when
+// normalization is enabled for its detector and left alone when it is not
+func TestNormalize_Obfuscation(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    string
+		expected string
+	}{
+		{"FullwidthAtAndMiddleDot", "mail user＠example·com now", "mail [EMAIL] now"},
+		{"ZeroWidthSplit", "mail us\u200ber@exa\u200dmple.com", "mail [EMAIL]"},
+		{"CyrillicHomoglyph", "mail usеr@еxample.com", "mail [EMAIL]"},
+		{"FullwidthDigits", "SSN １２３-４５-６７８９.", "SSN [SSN]."},
+	}
+
+	cfg := config.Config{
+		DetectEmails:     true,
+		EmailReplacement: "[EMAIL]",
+		DetectSSNs:       true,
+		SSNReplacement:   "[SSN]",
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg.NormalizeDetectors = nil
+			if _, changed, _ := NewDetectorSet(cfg).Filter(tt.input); changed {
+				t.Errorf("Expected no match without normalization for %q", tt.input)
+			}
+
+			cfg.NormalizeDetectors = []string{config.DetectorEmail, config.DetectorSSN}
+			filtered, changed, summary := NewDetectorSet(cfg).Filter(tt.input)
+			if !changed || filtered != tt.expected {
+				t.Errorf("Expected %q, got %q", tt.expected, filtered)
+			}
+			if len(summary.Replacements) != 1 {
+				t.Fatalf("Expected 1 replacement, got %d", len(summary.Replacements))
+			}
+		})
+	}
+}
+
+// TestNormalize_PreservesUnmatchedText tests that text outside matches keeps
+// its original characters
+func TestNormalize_PreservesUnmatchedText(t *testing.T) {
+	cfg := config.Config{
+		DetectEmails:       true,
+		EmailReplacement:   "[EMAIL]",
+		NormalizeDetectors: []string{config.DetectorEmail},
+	}
+
+	input := "ｆｕｌｌｗｉｄｔｈ· text a@b.io"
+	filtered, _, summary := NewDetectorSet(cfg).Filter(input)
+
+	if filtered != "ｆｕｌｌｗｉｄｔｈ· text [EMAIL]" {
+		t.Errorf("Expected unmatched text to be preserved, got %q", filtered)
+	}
+	if summary.Replacements[0].Original != "a@b.io" {
+		t.Errorf("Expected original match text, got %q", summary.Replacements[0].Original)
+	}
+}
+
+// TestNormalize_OriginalMatchText tests that reported matches carry the
+// original, obfuscated text
+func TestNormalize_OriginalMatchText(t *testing.T) {
+	cfg := config.Config{
+		StringMatchPatterns: []config.StringMatchPattern{
+			{Name: "project", Pattern: "falcon", Enabled: true, Replacement: "[PROJECT]", Normalize: true},
+		},
+	}
+
+	_, _, summary := NewDetectorSet(cfg).Filter("the fal\u200bcon launch")
+	if len(summary.Replacements) != 1 || summary.Replacements[0].Original != "fal\u200bcon" {
+		t.Errorf("Expected obfuscated original text, got %+v", summary.Replacements)
+	}
+}
+
+// TestNormalize_PerDetector tests that only the detectors and patterns
+// normalization is enabled for match obfuscated text
+func TestNormalize_PerDetector(t *testing.T) {
+	cfg := config.Config{
+		DetectEmails:       true,
+		EmailReplacement:   "[EMAIL]",
+		DetectSSNs:         true,
+		SSNReplacement:     "[SSN]",
+		NormalizeDetectors: []string{config.DetectorSSN},
+		StringMatchPatterns: []config.StringMatchPattern{
+			{Name: "falcon", Pattern: "falcon", Enabled: true, Replacement: "[F]", Normalize: true},
+			{Name: "eagle", Pattern: "eagle", Enabled: true, Replacement: "[E]"},
+			{Name: "hawk", Pattern: "hawk", Enabled: true, Replacement: "[H]", Fuzzy: true, Normalize: true},
+		},
+	}
+
+	input := "ann＠example·com １２３-４５-６７８９ fal\u200bcon ea\u200bgle hа\u200bwk"
+	filtered, _, _ := NewDetectorSet(cfg).Filter(input)
+	if want := "ann＠example·com [SSN] [F] ea\u200bgle [H]"; filtered != want {
+		t.Errorf("Expected %q, got %q", want, filtered)
+	}
+}
@@ -0,0 +1,68 @@
+package filter
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"github.com/happytaoer/prompt-security/internal/patterns"
+)
+
+// SensitiveTypePassword is the type of passwords given in prose
+const SensitiveTypePassword = "password"
+
+// passwordMinLength is the fewest characters of a password value, so that
+// stray letters after a keyword are not replaced
+const passwordMinLength = 3
+
+// passwordStopwords are the lowercase patterns.PasswordStopwords
+var passwordStopwords = func() map[string]bool {
+	words := make(map[string]bool, len(patterns.PasswordStopwords))
+	for _, w := range patterns.PasswordStopwords {
+		words[w] = true
+	}
+	return words
+}()
+
+// PasswordProseDetector detects passwords given in prose, such as "the
+// password is hunter2" or "Passwort: hunter2", replacing the token after
+// the keyword. Words describing the password rather than giving it, as in
+// "the password is incorrect", are left alone, and other lowercase words
+// after a connector are doubted.
+type PasswordProseDetector struct {
+	replacement string
+}
+
+// NewPasswordProseDetector creates a detector replacing password values
+// with replacement
+func NewPasswordProseDetector(replacement string) *PasswordProseDetector {
+	return &PasswordProseDetector{replacement: replacement}
+}
+
+// Name returns the detector's sensitive data type
+func (d *PasswordProseDetector) Name() string {
+	return SensitiveTypePassword
+}
+
+// Detect returns the password values in text
+func (d *PasswordProseDetector) Detect(text string) []Match {
+	var matches []Match
+	value := 2 * patterns.PasswordProsePattern.SubexpIndex("value")
+	for _, loc := range patterns.PasswordProsePattern.FindAllStringSubmatchIndex(text, -1) {
+		start, end := loc[value], loc[value+1]
+		// Sentence punctuation after the value, as in "is hunter2."
+		end = start + len(strings.TrimRight(text[start:end], ".,;:!?)]}"))
+		v := text[start:end]
+		if utf8.RuneCountInString(v) < passwordMinLength || passwordStopwords[strings.ToLower(v)] {
+			continue
+		}
+		matches = append(matches, Match{
+			Type:        SensitiveTypePassword,
+			Start:       start,
+			End:         end,
+			Text:        v,
+			Replacement: d.replacement,
+			Confidence:  passwordConfidence(v, strings.ContainsAny(text[loc[0]:start], ":=：")),
+		})
+	}
+	return matches
+}
@@ -0,0 +1,25 @@
+package filter
+
+import "testing"
+
+// TestPasswordProseDetector_Confidence tests that a lowercase word given as
+// the password in a sentence is doubted, as is a placeholder
+func TestPasswordProseDetector_Confidence(t *testing.T) {
+	tests := []struct {
+		input string
+		want  float64
+	}{
+		{"the password is sunflower", confidenceWordPassword},
+		{"password: sunflower", 1},
+		{"the password is Sunflower", 1},
+		{"password: $DB_PASSWORD", confidencePlaceholder},
+	}
+
+	d := NewPasswordProseDetector("[PASSWORD]")
+	for _, tt := range tests {
+		matches := d.Detect(tt.input)
+		if len(matches) != 1 || matches[0].Confidence != tt.want {
+			t.Errorf("Expected %q scored %v, got %+v", tt.input, tt.want, matches)
+		}
+	}
+}
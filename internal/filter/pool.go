@@ -0,0 +1,96 @@
+package filter
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+)
+
+// Result is the outcome of filtering one document
+type Result struct {
+	Filtered string
+	Changed  bool
+	Summary  ReplacementSummary
+}
+
+// Pool filters documents concurrently on a fixed set of workers. Each worker
+// owns a DetectorSet compiled separately, so workers never share compiled
+// patterns. A Pool is safe for concurrent use; Close it when done.
+type Pool struct {
+	sets []atomic.Pointer[DetectorSet] // One per worker
+	jobs chan poolJob
+	wg   sync.WaitGroup
+}
+
+// poolJob is one document submitted to the pool
+type poolJob struct {
+	text   string
+	result *Result
+	done   *sync.WaitGroup
+}
+
+// NewPool starts a pool of workers filtering with cfg. A workers value of
+// zero or less uses GOMAXPROCS workers.
+func NewPool(cfg config.Config, workers int) *Pool {
+	if workers <= 0 {
+		workers = runtime.GOMAXPROCS(0)
+	}
+
+	p := &Pool{
+		sets: make([]atomic.Pointer[DetectorSet], workers),
+		jobs: make(chan poolJob, workers),
+	}
+	p.Reload(cfg)
+
+	p.wg.Add(workers)
+	for i := 0; i < workers; i++ {
+		go p.work(i)
+	}
+	return p
+}
+
+// Workers returns the number of workers
+func (p *Pool) Workers() int {
+	return len(p.sets)
+}
+
+// Reload compiles cfg for every worker. Documents already being filtered
+// finish with the previous configuration.
+func (p *Pool) Reload(cfg config.Config) {
+	for i := range p.sets {
+		p.sets[i].Store(NewDetectorSet(cfg))
+	}
+}
+
+// FilterAll filters docs and returns the results in the same order
+func (p *Pool) FilterAll(docs []string) []Result {
+	results := make([]Result, len(docs))
+
+	var done sync.WaitGroup
+	done.Add(len(docs))
+	for i, doc := range docs {
+		p.jobs <- poolJob{text: doc, result: &results[i], done: &done}
+	}
+	done.Wait()
+
+	return results
+}
+
+// Close stops the workers after pending documents are filtered. The pool
+// must not be used afterwards.
+func (p *Pool) Close() {
+	close(p.jobs)
+	p.wg.Wait()
+}
+
+// work filters submitted documents with worker i's DetectorSet
+func (p *Pool) work(i int) {
+	defer p.wg.Done()
+	for job := range p.jobs {
+		filtered, changed, summary := p.sets[i].Load().Filter(job.text)
+		*job.result = Result{Filtered: filtered, Changed: changed, Summary: summary}
+		job.done.Done()
+	}
+}
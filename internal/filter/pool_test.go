@@ -0,0 +1,113 @@
+package filter
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+)
+
+// poolConfig enables every built-in detector
+func poolConfig() config.Config {
+	return config.Config{
+		DetectEmails:          true,
+		DetectPhones:          true,
+		DetectCreditCards:     true,
+		DetectSSNs:            true,
+		DetectIPV4:            true,
+		EmailReplacement:      "[EMAIL]",
+		PhoneReplacement:      "[PHONE]",
+		CreditCardReplacement: "[CARD]",
+		SSNReplacement:        "[SSN]",
+		IPV4Replacement:       "[IP]",
+		StringMatchPatterns: []config.StringMatchPattern{
+			{Name: "company", Pattern: "Acme Corp", Enabled: true, Replacement: "[COMPANY]"},
+		},
+	}
+}
+
+// poolDocuments returns n documents of a few kilobytes each
+func poolDocuments(n int) []string {
+	docs := make([]string, n)
+	for i := range docs {
+		var doc string
+		for j := 0; j < 40; j++ {
+			doc += fmt.Sprintf("Ticket %d-%d from user%d@example.com at Acme Corp, call 123-456-%04d, host 10.0.%d.%d. ", i, j, j, i%10000, i%256, j)
+		}
+		docs[i] = doc
+	}
+	return docs
+}
+
+// TestPool_FilterAll tests that the pool matches sequential filtering and keeps order
+func TestPool_FilterAll(t *testing.T) {
+	cfg := poolConfig()
+	docs := poolDocuments(50)
+
+	pool := NewPool(cfg, 4)
+	defer pool.Close()
+
+	results := pool.FilterAll(docs)
+	if len(results) != len(docs) {
+		t.Fatalf("Expected %d results, got %d", len(docs), len(results))
+	}
+
+	ds := NewDetectorSet(cfg)
+	for i, doc := range docs {
+		filtered, changed, summary := ds.Filter(doc)
+		if results[i].Filtered != filtered || results[i].Changed != changed {
+			t.Fatalf("Document %d: pool result differs from sequential filtering", i)
+		}
+		if len(results[i].Summary.Replacements) != len(summary.Replacements) {
+			t.Fatalf("Document %d: expected %d replacements, got %d", i, len(summary.Replacements), len(results[i].Summary.Replacements))
+		}
+	}
+}
+
+// TestPool_Reload tests that reloading changes the configuration of every worker
+func TestPool_Reload(t *testing.T) {
+	pool := NewPool(poolConfig(), 0)
+	defer pool.Close()
+
+	pool.Reload(config.Config{})
+	for i, r := range pool.FilterAll(poolDocuments(pool.Workers() * 2)) {
+		if r.Changed {
+			t.Fatalf("Document %d: expected no filtering after reload", i)
+		}
+	}
+}
+
+// BenchmarkFilter_Sequential filters a batch of documents on one goroutine
+func BenchmarkFilter_Sequential(b *testing.B) {
+	ds := NewDetectorSet(poolConfig())
+	docs := poolDocuments(64)
+	b.SetBytes(int64(totalSize(docs)))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for _, doc := range docs {
+			ds.Filter(doc)
+		}
+	}
+}
+
+// BenchmarkFilter_Pool filters the same batch on a GOMAXPROCS worker pool
+func BenchmarkFilter_Pool(b *testing.B) {
+	pool := NewPool(poolConfig(), 0)
+	defer pool.Close()
+	docs := poolDocuments(64)
+	b.SetBytes(int64(totalSize(docs)))
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		pool.FilterAll(docs)
+	}
+}
+
+func totalSize(docs []string) int {
+	n := 0
+	for _, doc := range docs {
+		n += len(doc)
+	}
+	return n
+}
@@ -0,0 +1,145 @@
+package filter
+
+import (
+	"strings"
+
+	"github.com/happytaoer/prompt-security/internal/patterns"
+)
+
+// SensitiveTypeRecoveryCodes is the type of blocks of recovery codes
+const SensitiveTypeRecoveryCodes = "recovery_codes"
+
+// Limits of a block of recovery codes
+const (
+	recoveryMinCodes  = 2 // Fewer codes are not a list
+	recoveryMinChars  = 8 // Letters and digits of a code
+	recoveryMaxChars  = 12
+	recoveryLeadLines = 2 // Lines of prose allowed between the phrase and the codes
+)
+
+// RecoveryCodesDetector detects blocks of backup or recovery codes after a
+// phrase such as "recovery codes" and replaces each block as a unit, so
+// that neither the codes nor their number are left piecemeal
+type RecoveryCodesDetector struct {
+	replacement string
+}
+
+// NewRecoveryCodesDetector creates a detector replacing blocks of codes
+// with replacement
+func NewRecoveryCodesDetector(replacement string) *RecoveryCodesDetector {
+	return &RecoveryCodesDetector{replacement: replacement}
+}
+
+// Name returns the detector's sensitive data type
+func (d *RecoveryCodesDetector) Name() string {
+	return SensitiveTypeRecoveryCodes
+}
+
+// Detect returns the blocks of codes in text, from the first code of each
+// to its last
+func (d *RecoveryCodesDetector) Detect(text string) []Match {
+	var matches []Match
+	end := 0
+	for _, loc := range patterns.RecoveryHeaderPattern.FindAllStringIndex(text, -1) {
+		if loc[0] < end {
+			continue // A phrase inside the previous block
+		}
+		start, stop, ok := recoveryBlock(text, loc[1])
+		if !ok {
+			continue
+		}
+		matches = append(matches, Match{
+			Type:        SensitiveTypeRecoveryCodes,
+			Start:       start,
+			End:         stop,
+			Text:        text[start:stop],
+			Replacement: d.replacement,
+		})
+		end = stop
+	}
+	return matches
+}
+
+// recoveryBlock finds the codes following offset: on the rest of its line
+// and the lines below, after at most recoveryLeadLines of prose, up to the
+// first line that is not codes or a second blank line. It returns the span
+// from the first code to the last, if there are at least recoveryMinCodes.
+func recoveryBlock(text string, offset int) (int, int, bool) {
+	start, end, codes := -1, -1, 0
+	lead, blank := 0, 0
+lines:
+	for pos := offset; pos < len(text); {
+		line := text[pos:]
+		if i := strings.IndexByte(line, '\n'); i >= 0 {
+			line = line[:i]
+		}
+		next := pos + len(line) + 1
+
+		body, bodyStart := line, pos
+		if pos == offset {
+			body = strings.TrimLeft(line, ": \t") // After the phrase
+			bodyStart += len(line) - len(body)
+		}
+		body = strings.TrimRight(body, " \t\r")
+
+		switch spans := recoveryLineCodes(body); {
+		case body == "":
+			if pos != offset {
+				blank++
+			}
+			if blank > 1 && codes > 0 {
+				break lines
+			}
+		case spans != nil:
+			if start < 0 {
+				start = bodyStart + spans[0][0]
+			}
+			end = bodyStart + spans[len(spans)-1][1]
+			codes += len(spans)
+			blank = 0
+		case codes > 0:
+			break lines // Prose after the block
+		case pos != offset:
+			if lead++; lead > recoveryLeadLines {
+				break lines
+			}
+		}
+		pos = next
+	}
+	return start, end, codes >= recoveryMinCodes
+}
+
+// recoveryLineCodes returns the spans of the codes of a line made only of
+// codes, after an optional bullet or number, or nil if it is not. A period
+// may end the line.
+func recoveryLineCodes(line string) [][2]int {
+	prefix := patterns.RecoveryLinePrefixPattern.FindStringIndex(line)
+	var spans [][2]int
+	last := prefix[1]
+	for _, loc := range patterns.RecoveryCodePattern.FindAllStringIndex(line[prefix[1]:], -1) {
+		s, e := prefix[1]+loc[0], prefix[1]+loc[1]
+		if !recoveryGap(line[last:s]) || !recoveryCode(line[s:e]) {
+			return nil
+		}
+		spans = append(spans, [2]int{s, e})
+		last = e
+	}
+	if len(spans) == 0 || strings.Trim(line[last:], " \t,;.") != "" {
+		return nil
+	}
+	return spans
+}
+
+// recoveryGap reports whether gap only separates two codes of a line, with
+// commas, whitespace or the number of the next code, as in columns
+func recoveryGap(gap string) bool {
+	gap = strings.TrimLeft(gap, " \t,;")
+	return gap == "" || len(patterns.RecoveryLinePrefixPattern.FindString(gap)) == len(gap)
+}
+
+// recoveryCode reports whether code has the length of a recovery code and
+// a digit, which sets it apart from pairs of words
+func recoveryCode(code string) bool {
+	chars := len(code) - strings.Count(code, "-") - strings.Count(code, " ")
+	return chars >= recoveryMinChars && chars <= recoveryMaxChars && strings.ContainsAny(code, "0123456789")
+}
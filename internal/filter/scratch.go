@@ -0,0 +1,48 @@
+package filter
+
+import "sync"
+
+// maxPooledBuffer is the largest output buffer kept for reuse, so one huge
+// clipboard does not pin its memory
+const maxPooledBuffer = 64 * 1024
+
+// scratch holds the working memory of one Filter call
+type scratch struct {
+	candidates []candidate
+	accepted   []Match
+	buf        []byte
+}
+
+var scratchPool = sync.Pool{
+	New: func() interface{} { return new(scratch) },
+}
+
+func getScratch() *scratch {
+	return scratchPool.Get().(*scratch)
+}
+
+func putScratch(s *scratch) {
+	if cap(s.buf) > maxPooledBuffer {
+		s.buf = nil
+	}
+	// Drop references to the scanned text
+	clear(s.candidates[:cap(s.candidates)])
+	clear(s.accepted[:cap(s.accepted)])
+	scratchPool.Put(s)
+}
+
+// apply replaces each match in text with its replacement. Matches must be
+// sorted and non-overlapping.
+func (s *scratch) apply(text string, matches []Match) string {
+	buf := s.buf[:0]
+	last := 0
+	for _, m := range matches {
+		buf = append(buf, text[last:m.Start]...)
+		buf = append(buf, m.Replacement...)
+		last = m.End
+	}
+	buf = append(buf, text[last:]...)
+	s.buf = buf
+
+	return string(buf)
+}
@@ -0,0 +1,125 @@
+package filter
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+	"github.com/happytaoer/prompt-security/internal/patterns"
+)
+
+// SensitiveTypeSpecialCategory is the registry name of the GDPR special
+// category entry. Matches carry the category, e.g. health_data.
+const SensitiveTypeSpecialCategory = "special_category"
+
+// specialCategoryWindow is how many words either side of a keyword are
+// searched for a reference to a person
+const specialCategoryWindow = 6
+
+// specialKeyword is a keyword of a special category, split into folded words
+type specialKeyword struct {
+	words    []string
+	category string
+}
+
+// SpecialCategoryDetector detects keywords of the GDPR special categories of
+// personal data within a few words of a reference to a person. Its matches
+// are findings to log rather than data to replace.
+type SpecialCategoryDetector struct {
+	keywords map[string][]specialKeyword // First word -> keywords, longest first
+	context  map[string]bool
+}
+
+// NewSpecialCategoryDetector creates a detector for the keywords of
+// categories, mapping category names to keywords, near one of the context
+// words
+func NewSpecialCategoryDetector(categories map[string][]string, context []string) *SpecialCategoryDetector {
+	d := &SpecialCategoryDetector{
+		keywords: make(map[string][]specialKeyword),
+		context:  make(map[string]bool, len(context)),
+	}
+	for category, keywords := range categories {
+		for _, k := range keywords {
+			var words []string
+			for _, w := range fuzzyWords(k) {
+				words = append(words, w.folded)
+			}
+			if len(words) == 0 {
+				continue
+			}
+			d.keywords[words[0]] = append(d.keywords[words[0]], specialKeyword{words: words, category: category})
+		}
+	}
+	for _, list := range d.keywords {
+		sort.SliceStable(list, func(i, j int) bool { return len(list[i].words) > len(list[j].words) })
+	}
+	for _, w := range context {
+		for _, fw := range fuzzyWords(w) {
+			d.context[fw.folded] = true
+		}
+	}
+	return d
+}
+
+// Name returns the detector's registry name. Matches carry their category.
+func (d *SpecialCategoryDetector) Name() string {
+	return SensitiveTypeSpecialCategory
+}
+
+// Detect returns the keywords in text with a context word within
+// specialCategoryWindow words, preferring the longest keyword at each word
+func (d *SpecialCategoryDetector) Detect(text string) []Match {
+	words := fuzzyWords(text)
+	var matches []Match
+	for i := 0; i < len(words); i++ {
+		k, ok := d.keywordAt(words, i)
+		if !ok {
+			continue
+		}
+		end := i + len(k.words)
+		if d.nearContext(words, i, end) {
+			start, stop := words[i].start, words[end-1].end
+			matches = append(matches, Match{
+				Type:        k.category,
+				Start:       start,
+				End:         stop,
+				Text:        text[start:stop],
+				Replacement: "[" + strings.ToUpper(k.category) + "]",
+				Action:      config.ActionLog,
+			})
+		}
+		i = end - 1
+	}
+	return matches
+}
+
+// keywordAt returns the longest keyword starting at words[i]
+func (d *SpecialCategoryDetector) keywordAt(words []fuzzyWord, i int) (specialKeyword, bool) {
+next:
+	for _, k := range d.keywords[words[i].folded] {
+		if i+len(k.words) > len(words) {
+			continue
+		}
+		for j, w := range k.words[1:] {
+			if words[i+1+j].folded != w {
+				continue next
+			}
+		}
+		return k, true
+	}
+	return specialKeyword{}, false
+}
+
+// nearContext reports whether a context word is within specialCategoryWindow
+// words of words[start:end], not counting the keyword's own words
+func (d *SpecialCategoryDetector) nearContext(words []fuzzyWord, start, end int) bool {
+	for i := max(0, start-specialCategoryWindow); i < min(len(words), end+specialCategoryWindow); i++ {
+		if (i < start || i >= end) && d.context[words[i].folded] {
+			return true
+		}
+	}
+	return false
+}
+
+// defaultSpecialCategoryDetector is built once from the curated lists
+var defaultSpecialCategoryDetector = NewSpecialCategoryDetector(patterns.SpecialCategoryKeywords, patterns.SpecialCategoryContext)
@@ -0,0 +1,50 @@
+package filter
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+	"github.com/happytaoer/prompt-security/internal/patterns"
+)
+
+// TestSpecialCategoryDetector tests that special category keywords are
+// logged when they appear near words referring to a person, and that every
+// keyword has words to match
+func TestSpecialCategoryDetector(t *testing.T) {
+	d := defaultSpecialCategoryDetector
+
+	tests := []struct {
+		text string
+		want string // Categories and matched texts
+	}{
+		{"Our colleague is on chemotherapy until May", "[health_data/chemotherapy]"},
+		{"She was DIAGNOSED with Multiple Sclerosis", "[health_data/DIAGNOSED health_data/Multiple Sclerosis]"},
+		{"He converted to Islam last year", "[religious_belief/converted to religious_belief/Islam]"},
+		{"The employee joined a trade union, so he pays union dues", "[union_membership/trade union union_membership/union dues]"},
+		{"Turn left at the church on Main Street", "[]"}, // No person
+		{"The union member list is public", "[]"},        // The keyword's own words do not count
+		{"my report on surgery robots: a long survey of the many models", "[health_data/surgery]"},
+		{"surgery robots: a long survey of the many models and what my team thinks", "[]"}, // Too far
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, m := range d.Detect(tt.text) {
+			got = append(got, fmt.Sprintf("%s/%s", m.Type, m.Text))
+			if m.Action != config.ActionLog {
+				t.Errorf("Expected findings to be logged, got action %q", m.Action)
+			}
+		}
+		if s := fmt.Sprint(got); s != tt.want {
+			t.Errorf("Detect(%q) = %s, want %s", tt.text, s, tt.want)
+		}
+	}
+
+	for category, keywords := range patterns.SpecialCategoryKeywords {
+		for _, k := range keywords {
+			if len(fuzzyWords(k)) == 0 {
+				t.Errorf("Expected %s keyword %q to have words", category, k)
+			}
+		}
+	}
+}
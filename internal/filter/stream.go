@@ -0,0 +1,186 @@
+package filter
+
+import (
+	"bytes"
+	"io"
+	"unicode/utf8"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+)
+
+// StreamFilter filters streams of text, such as proxied bodies or large
+// files, without holding them in memory. Text is scanned in chunks of
+// DefaultChunkSize bytes like FilterChunked, each with ChunkOverlap bytes of
+// context on both sides, so a match crossing a chunk boundary is found as
+// long as it is not longer than ChunkOverlap. Only a chunk and its context
+// are buffered between writes, besides the text of large writes.
+type StreamFilter struct {
+	ds *DetectorSet
+}
+
+// NewStreamFilter creates a stream filter for the detectors enabled in cfg
+func NewStreamFilter(cfg config.Config) *StreamFilter {
+	return NewDetectorSet(cfg).Stream()
+}
+
+// Stream returns a stream filter using ds
+func (ds *DetectorSet) Stream() *StreamFilter {
+	return &StreamFilter{ds: ds}
+}
+
+// Writer returns a writer filtering what is written to it into w. Close
+// must be called to filter and write the buffered end of the stream; it
+// does not close w.
+func (f *StreamFilter) Writer(w io.Writer) *StreamWriter {
+	return &StreamWriter{stream: stream{ds: f.ds, out: w}}
+}
+
+// Reader returns a reader of the filtered content of r
+func (f *StreamFilter) Reader(r io.Reader) *StreamReader {
+	sr := &StreamReader{in: r}
+	sr.stream = stream{ds: f.ds, out: &sr.out}
+	return sr
+}
+
+// StreamWriter filters the text written to it, see StreamFilter
+type StreamWriter struct {
+	stream
+}
+
+// Write buffers p and writes out the filtered chunks it completes
+func (w *StreamWriter) Write(p []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	w.buf = append(w.buf, p...)
+	for w.err == nil && len(w.buf)-w.pos >= DefaultChunkSize+ChunkOverlap {
+		w.step()
+	}
+	if w.err != nil {
+		return 0, w.err
+	}
+	return len(p), nil
+}
+
+// Close filters and writes out the rest of the stream
+func (w *StreamWriter) Close() error {
+	for w.err == nil && w.pos < len(w.buf) {
+		w.step()
+	}
+	return w.err
+}
+
+// Summary returns the replacements made so far, with offsets in the
+// unfiltered stream
+func (w *StreamWriter) Summary() ReplacementSummary {
+	return w.summary
+}
+
+// StreamReader reads the filtered content of another reader, see
+// StreamFilter
+type StreamReader struct {
+	stream
+	in    io.Reader
+	chunk []byte       // Read buffer of in
+	out   bytes.Buffer // Filtered text not read yet
+	eof   bool
+}
+
+// Read reads filtered text, reading and filtering chunks of the underlying
+// reader as needed
+func (r *StreamReader) Read(p []byte) (int, error) {
+	if r.chunk == nil {
+		r.chunk = make([]byte, DefaultChunkSize)
+	}
+	for r.out.Len() == 0 && r.err == nil {
+		if r.eof {
+			if r.pos == len(r.buf) {
+				return 0, io.EOF
+			}
+			r.step()
+			continue
+		}
+		n, err := r.in.Read(r.chunk)
+		r.buf = append(r.buf, r.chunk[:n]...)
+		if err == io.EOF {
+			r.eof = true
+		} else if err != nil {
+			r.err = err
+		}
+		for r.err == nil && len(r.buf)-r.pos >= DefaultChunkSize+ChunkOverlap {
+			r.step()
+		}
+	}
+	if r.out.Len() > 0 {
+		return r.out.Read(p)
+	}
+	return 0, r.err
+}
+
+// Summary returns the replacements made so far, with offsets in the
+// unfiltered stream
+func (r *StreamReader) Summary() ReplacementSummary {
+	return r.summary
+}
+
+// stream is the state shared by stream readers and writers
+type stream struct {
+	ds      *DetectorSet
+	out     io.Writer
+	buf     []byte // Context already written, then text not yet filtered
+	pos     int    // Start of the text not yet filtered in buf
+	offset  int    // Offset of buf in the stream
+	summary ReplacementSummary
+	err     error
+}
+
+// step filters and writes out the next chunk of buf and drops what is no
+// longer needed as context. Unless the stream has ended, buf must hold
+// ChunkOverlap bytes past the chunk.
+func (st *stream) step() {
+	cut := min(len(st.buf), st.pos+DefaultChunkSize+2*ChunkOverlap)
+	for cut < len(st.buf) && !utf8.RuneStart(st.buf[cut]) {
+		cut++
+	}
+	text := string(st.buf[:cut])
+	end := runeBoundary(text, st.pos+DefaultChunkSize, true)
+
+	var out bytes.Buffer
+	last := st.pos
+	if st.ds.Enabled() {
+		s := getScratch()
+		// Keep matches starting in this chunk, like FilterChunked
+		var accepted []Match
+		for _, m := range st.ds.matches(s, text, st.pos) {
+			if m.Start < end {
+				accepted = append(accepted, m)
+			}
+		}
+		for _, m := range accepted {
+			out.WriteString(text[last:m.Start])
+			out.WriteString(m.Replacement)
+			last = m.End
+		}
+		for _, r := range summarize(accepted).Replacements {
+			r.Start += st.offset
+			r.End += st.offset
+			st.summary.Replacements = append(st.summary.Replacements, r)
+		}
+		putScratch(s)
+	}
+
+	// A match may run past the chunk; resume after it
+	if last > end {
+		end = last
+	}
+	out.WriteString(text[last:end])
+	if _, err := st.out.Write(out.Bytes()); err != nil {
+		st.err = err
+		return
+	}
+
+	keep := runeBoundary(text, end-ChunkOverlap, false)
+	st.buf = append(st.buf[:0], st.buf[keep:]...)
+	st.offset += keep
+	st.pos = end - keep
+}
@@ -0,0 +1,104 @@
+package filter
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+)
+
+// streamText returns text several chunks long with matches on every line,
+// some of which straddle chunk boundaries
+func streamText() string {
+	var b strings.Builder
+	for i := 0; b.Len() < 3*DefaultChunkSize; i++ {
+		b.WriteString("line ")
+		b.WriteString(strings.Repeat("é", i%7))
+		b.WriteString(" user")
+		b.WriteString(strings.Repeat("x", i%5))
+		b.WriteString("@example.com Acme 123-45-6789\n")
+	}
+	return b.String()
+}
+
+// TestStreamFilter_MatchesFilter tests that streamed filtering gives the
+// same result as a single pass, whatever the sizes of writes and reads
+func TestStreamFilter_MatchesFilter(t *testing.T) {
+	cfg := config.Config{
+		DetectEmails:     true,
+		EmailReplacement: "[EMAIL]",
+		DetectSSNs:       true,
+		SSNReplacement:   "[SSN]",
+		StringMatchPatterns: []config.StringMatchPattern{
+			{Name: "company", Pattern: "Acme", Enabled: true, Replacement: "[COMPANY]"},
+		},
+	}
+	text := streamText()
+	want, _, wantSummary := SensitiveData(text, cfg)
+	f := NewStreamFilter(cfg)
+
+	for _, size := range []int{1000, 4093, DefaultChunkSize + 1, len(text)} {
+		var out bytes.Buffer
+		w := f.Writer(&out)
+		for start := 0; start < len(text); start += size {
+			if _, err := w.Write([]byte(text[start:min(start+size, len(text))])); err != nil {
+				t.Fatalf("write %d: unexpected error %v", size, err)
+			}
+		}
+		if err := w.Close(); err != nil {
+			t.Fatalf("write %d: unexpected error %v", size, err)
+		}
+		if out.String() != want {
+			t.Errorf("write %d: streamed output differs from Filter", size)
+		}
+		if len(w.Summary().Replacements) != len(wantSummary.Replacements) {
+			t.Errorf("write %d: expected %d replacements, got %d", size, len(wantSummary.Replacements), len(w.Summary().Replacements))
+		}
+		for i, r := range w.Summary().Replacements {
+			if r != wantSummary.Replacements[i] {
+				t.Errorf("write %d: replacement %d is %+v, want %+v", size, i, r, wantSummary.Replacements[i])
+				break
+			}
+		}
+	}
+
+	for name, r := range map[string]io.Reader{
+		"whole": strings.NewReader(text),
+		"half":  iotest.HalfReader(strings.NewReader(text)),
+	} {
+		got, err := io.ReadAll(f.Reader(r))
+		if err != nil {
+			t.Fatalf("%s: unexpected error %v", name, err)
+		}
+		if string(got) != want {
+			t.Errorf("%s: streamed output differs from Filter", name)
+		}
+	}
+}
+
+// TestStreamFilter_Errors tests that errors of the underlying reader and
+// writer are returned
+func TestStreamFilter_Errors(t *testing.T) {
+	f := NewStreamFilter(config.Config{DetectEmails: true, EmailReplacement: "[EMAIL]"})
+	if _, err := io.ReadAll(f.Reader(iotest.TimeoutReader(strings.NewReader(streamText())))); err != iotest.ErrTimeout {
+		t.Errorf("Expected the reader's error, got %v", err)
+	}
+
+	w := f.Writer(failingWriter{})
+	if _, err := w.Write([]byte("mail ann@example.com")); err != nil {
+		t.Errorf("Expected a short write to be buffered, got %v", err)
+	}
+	if err := w.Close(); err != io.ErrShortWrite {
+		t.Errorf("Expected the writer's error, got %v", err)
+	}
+}
+
+// failingWriter fails every write
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, io.ErrShortWrite
+}
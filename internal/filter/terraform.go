@@ -0,0 +1,178 @@
+package filter
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+
+	"github.com/happytaoer/prompt-security/internal/patterns"
+)
+
+// SensitiveTypeTerraformSecret is the type of secrets found in Terraform
+// configuration, state and plans
+const SensitiveTypeTerraformSecret = "terraform_secret"
+
+// TerraformDetector detects secret attribute values in Terraform HCL and in
+// state or plan JSON. A whole JSON document is walked token by token, which
+// also finds the values of outputs marked sensitive; anything else, such as
+// HCL or a snippet of state, is matched attribute by attribute.
+type TerraformDetector struct {
+	replacement string
+}
+
+// NewTerraformDetector creates a detector replacing secret values with
+// replacement
+func NewTerraformDetector(replacement string) *TerraformDetector {
+	return &TerraformDetector{replacement: replacement}
+}
+
+// Name returns the detector's sensitive data type
+func (d *TerraformDetector) Name() string {
+	return SensitiveTypeTerraformSecret
+}
+
+// Detect returns the secret string values in text, without their quotes
+func (d *TerraformDetector) Detect(text string) []Match {
+	var spans [][2]int
+	if trimmed := strings.TrimSpace(text); strings.HasPrefix(trimmed, "{") && json.Valid([]byte(trimmed)) {
+		spans = terraformJSONSecrets(text)
+	} else {
+		spans = terraformAttributeSecrets(text)
+	}
+
+	var matches []Match
+	for _, s := range spans {
+		value := text[s[0]:s[1]]
+		if !terraformSecretValue(value) {
+			continue
+		}
+		matches = append(matches, Match{
+			Type:        SensitiveTypeTerraformSecret,
+			Start:       s[0],
+			End:         s[1],
+			Text:        value,
+			Replacement: d.replacement,
+		})
+	}
+	return disjoint(matches)
+}
+
+// terraformSecretValue reports whether value may be a secret rather than
+// empty, a pure interpolation or Terraform's own redaction
+func terraformSecretValue(value string) bool {
+	switch {
+	case strings.TrimSpace(value) == "":
+		return false
+	case strings.HasPrefix(value, "${") && strings.HasSuffix(value, "}") && strings.Count(value, "${") == 1:
+		return false
+	case value == "(sensitive value)" || value == "(sensitive)":
+		return false
+	}
+	return true
+}
+
+// jsonFrame is an object or array being walked by terraformJSONSecrets
+type jsonFrame struct {
+	object    bool
+	key       string // Current key of an object, or the key holding an array
+	expectKey bool
+	sensitive bool   // An object with "sensitive": true, such as an output
+	value     [2]int // Span of the object's "value" string, if any
+}
+
+// terraformJSONSecrets walks a JSON document and returns the spans of the
+// contents of strings under secret keys, and of the "value" strings of
+// objects marked "sensitive": true
+func terraformJSONSecrets(text string) [][2]int {
+	var spans [][2]int
+	var stack []*jsonFrame
+	dec := json.NewDecoder(strings.NewReader(text))
+	for {
+		before := dec.InputOffset()
+		tok, err := dec.Token()
+		if err == io.EOF {
+			return spans
+		}
+		if err != nil {
+			return nil
+		}
+
+		var top *jsonFrame
+		if len(stack) > 0 {
+			top = stack[len(stack)-1]
+		}
+		isKey := top != nil && top.object && top.expectKey
+
+		switch t := tok.(type) {
+		case json.Delim:
+			switch t {
+			case '{', '[':
+				frame := &jsonFrame{object: t == '{', expectKey: t == '{'}
+				if top != nil {
+					frame.key = top.key
+					if top.object {
+						top.expectKey = true
+					}
+				}
+				stack = append(stack, frame)
+			case '}', ']':
+				stack = stack[:len(stack)-1]
+				if top.sensitive && top.value[1] > 0 {
+					spans = append(spans, top.value)
+				}
+			}
+			continue
+		case string:
+			if isKey {
+				top.key = t
+				top.expectKey = false
+				continue
+			}
+			end := int(dec.InputOffset())
+			start := int(before) + strings.IndexByte(text[before:end], '"') + 1
+			span := [2]int{start, end - 1}
+			if top != nil && patterns.TerraformSecretKeyPattern.MatchString(top.key) {
+				spans = append(spans, span)
+			} else if top != nil && top.object && top.key == "value" {
+				top.value = span
+			}
+		case bool:
+			if top != nil && top.object && top.key == "sensitive" && t {
+				top.sensitive = true
+			}
+		}
+		if top != nil && top.object {
+			top.expectKey = true
+		}
+	}
+}
+
+// terraformAttributeSecrets returns the spans of the values of secret
+// attributes assigned quoted strings or heredocs
+func terraformAttributeSecrets(text string) [][2]int {
+	var spans [][2]int
+	value := patterns.TerraformSecretPattern.SubexpIndex("value")
+	for _, loc := range patterns.TerraformSecretPattern.FindAllStringSubmatchIndex(text, -1) {
+		spans = append(spans, [2]int{loc[2*value], loc[2*value+1]})
+	}
+
+	marker := patterns.TerraformHeredocPattern.SubexpIndex("marker")
+	for _, loc := range patterns.TerraformHeredocPattern.FindAllStringSubmatchIndex(text, -1) {
+		delimiter := text[loc[2*marker]:loc[2*marker+1]]
+		start := loc[1] + 1 // Past the newline
+		for offset := start; offset < len(text); {
+			line := text[offset:]
+			if i := strings.IndexByte(line, '\n'); i >= 0 {
+				line = line[:i+1]
+			}
+			if strings.TrimSpace(line) == delimiter {
+				if end := strings.TrimRight(text[start:offset], "\r\n"); end != "" {
+					spans = append(spans, [2]int{start, start + len(end)})
+				}
+				break
+			}
+			offset += len(line)
+		}
+	}
+	return spans
+}
@@ -0,0 +1,68 @@
+package filter
+
+import (
+	"strings"
+
+	"github.com/happytaoer/prompt-security/internal/patterns"
+)
+
+// SensitiveTypeTOTP is the type of TOTP secrets and otpauth URIs
+const SensitiveTypeTOTP = "totp_secret"
+
+// totpContextWindow is how many bytes before a seed are searched for a word
+// introducing it, such as "2FA" or "authenticator"
+const totpContextWindow = 100
+
+// TOTPDetector detects otpauth:// URIs anywhere and base32 TOTP seeds
+// preceded by a word such as "2FA secret" or "authenticator", as base32
+// runs alone may be any identifier
+type TOTPDetector struct {
+	replacement string
+	action      string
+}
+
+// NewTOTPDetector creates a detector replacing secrets with replacement and
+// giving its matches action
+func NewTOTPDetector(replacement, action string) *TOTPDetector {
+	return &TOTPDetector{replacement: replacement, action: action}
+}
+
+// Name returns the detector's sensitive data type
+func (d *TOTPDetector) Name() string {
+	return SensitiveTypeTOTP
+}
+
+// Detect returns the otpauth URIs and seeds in text
+func (d *TOTPDetector) Detect(text string) []Match {
+	spans := patterns.OTPAuthURIPattern.FindAllStringIndex(text, -1)
+	for _, loc := range patterns.TOTPSeedPattern.FindAllStringIndex(text, -1) {
+		if patterns.TOTPContextPattern.MatchString(text[max(0, loc[0]-totpContextWindow):loc[0]]) {
+			spans = append(spans, loc)
+		}
+	}
+
+	// The secret of a URI is found twice
+	matches := make([]Match, 0, len(spans))
+	for _, s := range spans {
+		matches = append(matches, Match{
+			Type:        SensitiveTypeTOTP,
+			Start:       s[0],
+			End:         s[1],
+			Text:        text[s[0]:s[1]],
+			Replacement: d.replacement,
+			Action:      d.action,
+			Confidence:  totpConfidence(text[s[0]:s[1]]),
+		})
+	}
+	return disjoint(matches)
+}
+
+// totpConfidence scores a seed, doubting runs without the base32 digits 2
+// to 7, which random seeds almost always have but words and identifiers
+// written in capitals do not
+func totpConfidence(text string) float64 {
+	if strings.HasPrefix(strings.ToLower(text), "otpauth") || strings.ContainsAny(text, "234567") {
+		return 1
+	}
+	return confidenceLettersOnlySeed
+}
@@ -0,0 +1,19 @@
+package filter
+
+import (
+	"testing"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+)
+
+// TestTOTPDetector_Action tests that seeds take the configured action and
+// that seeds without digits are doubted
+func TestTOTPDetector_Action(t *testing.T) {
+	matches := NewTOTPDetector("[TOTP_SECRET]", config.ActionBlock).Detect("MFA seed: JBSWY3DPEHPK3PXP or ABCDEFGHIJKLMNOPQ")
+	if len(matches) != 2 || matches[0].Action != config.ActionBlock {
+		t.Fatalf("Expected two blocking matches, got %+v", matches)
+	}
+	if matches[0].Confidence != 1 || matches[1].Confidence != confidenceLettersOnlySeed {
+		t.Errorf("Expected seeds without digits to be doubted, got %v and %v", matches[0].Confidence, matches[1].Confidence)
+	}
+}
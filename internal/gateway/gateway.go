@@ -0,0 +1,551 @@
+// Package gateway is an HTTP endpoint that redacts prompts before
+// forwarding them to the real API of a model provider: OpenAI and
+// compatible APIs, Anthropic and Gemini, each in its own request shape. Any
+// app using a provider's SDK is protected by pointing its base URL at the
+// gateway. With detokenization, each sensitive value is replaced with a
+// unique token such as [EMAIL_1], and tokens the model repeats in its answer
+// are given their values back, so answers stay useful without the values
+// leaving the machine. Streamed answers are passed on as they arrive.
+package gateway
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/happytaoer/prompt-security/internal/filter"
+)
+
+// DefaultUpstream is the API OpenAI requests are forwarded to by default
+const DefaultUpstream = "https://api.openai.com/v1"
+
+const (
+	// maxRequestBytes bounds the size of a request body
+	maxRequestBytes = 32 << 20
+
+	// maxResponseScanBytes bounds the size of a response scanned for leaks
+	// without detokenization, which otherwise needs no buffering
+	maxResponseScanBytes = 32 << 20
+
+	// maxResponseBytes bounds the size of a response the gateway holds in
+	// memory: read whole to restore tokens, or streamed, whose events and
+	// generated text are kept to restore tokens and scan for leaks
+	maxResponseBytes = 32 << 20
+
+	// upstreamTimeout bounds waiting for the upstream to start responding
+	upstreamTimeout = 5 * time.Minute
+)
+
+// hopHeaders are connection-level headers that are not forwarded
+var hopHeaders = []string{
+	"Connection", "Keep-Alive", "Proxy-Authenticate", "Proxy-Authorization",
+	"Te", "Trailer", "Transfer-Encoding", "Upgrade",
+}
+
+// keyHeaders are the headers clients send API keys in, removed when the
+// gateway sends its own key
+var keyHeaders = []string{"Authorization", "Api-Key", "X-Api-Key", "X-Goog-Api-Key"}
+
+// Upstream configures where the requests of a provider are forwarded
+type Upstream struct {
+	// URL is the base URL of the API, e.g. https://api.openai.com/v1 or
+	// http://localhost:11434/v1 for Ollama. Its query, such as Azure's
+	// api-version, is added to every request. Empty uses the provider's
+	// default.
+	URL string
+
+	// APIKey, if set, authenticates requests instead of a stored key or
+	// the key sent by the client
+	APIKey string
+
+	// APIKeyHeader overrides the header carrying the key, e.g. api-key for
+	// Azure OpenAI. Authorization sends it as a bearer token.
+	APIKeyHeader string
+}
+
+// Options configure a Gateway
+type Options struct {
+	// Upstreams configures the upstream of each provider by name
+	Upstreams map[string]Upstream
+
+	// Keys, if set, returns the stored API key of a provider, or "" to use
+	// the key sent by the client. It is called for every request, so
+	// stored keys can change while the gateway runs.
+	Keys func(provider string) (string, error)
+
+	// Authorize, if set, reports whether the credential a client sent as
+	// its API key, e.g. a prompt-security API token, entitles it to have
+	// the configured or stored key sent on its behalf. Without it such
+	// requests are refused, so no other process or web page reaching the
+	// gateway can spend the keys.
+	Authorize func(credential string) (bool, error)
+
+	// Hosts are the host names clients may address the gateway by besides
+	// loopback names such as localhost and 127.0.0.1. Requests naming
+	// other hosts are refused, so web pages cannot reach the gateway
+	// through DNS rebinding.
+	Hosts []string
+
+	// Detokenize replaces sensitive values with unique tokens and puts the
+	// values back where the response repeats a token
+	Detokenize bool
+
+	// LogLeak, if set, scans the text and tool calls generated in responses
+	// and records those containing sensitive data, such as a secret the
+	// model echoes back. Responses are passed on unchanged.
+	LogLeak LogFunc
+
+	// Policy, if set, returns the policy for traffic to the upstream host
+	// of a request, e.g. DomainPolicies.Policy. It is called for every
+	// request, so policies can change while the gateway runs.
+	Policy func(host string) Policy
+
+	// TLSConfig, if set, configures connections to upstreams, e.g. to trust
+	// a private CA or authenticate with a client certificate
+	TLSConfig *tls.Config
+}
+
+// LogFunc records a redacted message
+type LogFunc func(original, redacted string, replacements []filter.ReplacementInfo)
+
+// Gateway redacts requests to model providers and forwards them upstream
+type Gateway struct {
+	opts      Options
+	upstreams map[string]*url.URL
+	engine    *filter.Engine
+	addLog    LogFunc
+	client    *http.Client
+	logger    *slog.Logger
+}
+
+// New creates a gateway that redacts with engine and records the newest
+// message of each request with addLog, which may be nil; replacements are
+// empty if nothing was found, so the message can still be classified
+func New(opts Options, engine *filter.Engine, addLog LogFunc) (*Gateway, error) {
+	upstreams := make(map[string]*url.URL, len(Providers))
+	for name := range opts.Upstreams {
+		if _, ok := FindProvider(name); !ok {
+			return nil, fmt.Errorf("unknown provider %q", name)
+		}
+	}
+	for _, p := range Providers {
+		raw := opts.Upstreams[p.Name].URL
+		if raw == "" {
+			raw = p.DefaultUpstream
+		}
+		u, err := url.Parse(raw)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, fmt.Errorf("invalid %s upstream URL %q", p.Name, raw)
+		}
+		upstreams[p.Name] = u
+	}
+	if addLog == nil {
+		addLog = func(string, string, []filter.ReplacementInfo) {}
+	}
+
+	return &Gateway{
+		opts:      opts,
+		upstreams: upstreams,
+		engine:    engine,
+		addLog:    addLog,
+		client: &http.Client{Transport: &http.Transport{
+			Proxy:                 http.ProxyFromEnvironment,
+			TLSClientConfig:       opts.TLSConfig,
+			ResponseHeaderTimeout: upstreamTimeout,
+		}},
+		logger: slog.New(slog.NewJSONHandler(os.Stdout, nil)),
+	}, nil
+}
+
+// UpstreamURL returns the URL requests of a provider are forwarded to
+func (g *Gateway) UpstreamURL(provider string) string {
+	if u, ok := g.upstreams[provider]; ok {
+		return u.String()
+	}
+	return ""
+}
+
+// Handler returns the HTTP handler of the gateway, serving each provider
+// below its prefix to requests addressed to a loopback name or one of
+// Options.Hosts
+func (g *Gateway) Handler() http.Handler {
+	mux := http.NewServeMux()
+	for _, p := range Providers {
+		mux.HandleFunc(p.Prefix+"/", g.handleProvider(p))
+	}
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("%s is not supported by the prompt-security gateway", r.URL.Path))
+	})
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !g.allowedHost(r.Host) {
+			g.logger.Warn("Refused gateway request to an unknown host", "host", r.Host)
+			writeError(w, http.StatusForbidden, "invalid_host", fmt.Sprintf("The gateway is not served as %s", r.Host))
+			return
+		}
+		mux.ServeHTTP(w, r)
+	})
+}
+
+// allowedHost reports whether the Host header of a request names the
+// gateway: a loopback name or one of Options.Hosts
+func (g *Gateway) allowedHost(hostport string) bool {
+	host := hostport
+	if h, _, err := net.SplitHostPort(hostport); err == nil {
+		host = h
+	}
+	host = strings.TrimSuffix(strings.Trim(host, "[]"), ".")
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return true
+	}
+	for _, h := range g.opts.Hosts {
+		if strings.EqualFold(h, host) {
+			return true
+		}
+	}
+	return false
+}
+
+// Intercept returns a handler for requests sent straight to host, the
+// upstream host of a provider, as an intercepting proxy receives them.
+// Their paths are relative to the upstream rather than the gateway.
+func (g *Gateway) Intercept(host string) (http.Handler, bool) {
+	for _, p := range Providers {
+		u := g.upstreams[p.Name]
+		if u.Scheme != "https" || !strings.EqualFold(u.Hostname(), host) {
+			continue
+		}
+		handler, base := g.handleProvider(p), strings.TrimSuffix(u.Path, "/")
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if !strings.HasPrefix(r.URL.Path, base+"/") {
+				writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("%s is not supported by the prompt-security gateway", r.URL.Path))
+				return
+			}
+			r = r.Clone(r.Context())
+			r.URL.Path, r.URL.RawPath = p.Prefix+strings.TrimPrefix(r.URL.Path, base), ""
+			handler(w, r)
+		}), true
+	}
+	return nil, false
+}
+
+// handleProvider redacts requests that carry prompts, passes through those
+// that carry none and refuses the others, and all of them if the policy for
+// the upstream host does not allow traffic
+func (g *Gateway) handleProvider(p Provider) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		path := strings.TrimPrefix(r.URL.Path, p.Prefix)
+		redact, pass := p.endpoint(r.Method, path)
+		if !redact && !pass {
+			writeError(w, http.StatusNotFound, "not_found", fmt.Sprintf("%s %s is not supported by the prompt-security gateway", r.Method, r.URL.Path))
+			return
+		}
+
+		policy := g.policy(p)
+		if !policy.Allow {
+			host := g.upstreams[p.Name].Hostname()
+			g.logger.Warn("Refused gateway request to a disallowed host", "provider", p.Name, "host", host)
+			writeError(w, http.StatusForbidden, "destination_blocked", fmt.Sprintf("Requests to %s are not allowed by policy", host))
+			return
+		}
+
+		key, err := g.key(p)
+		if err != nil {
+			g.logger.Error("Failed to look up the upstream key", "provider", p.Name, "error", err)
+			writeError(w, http.StatusServiceUnavailable, "server_error", "Failed to look up the upstream API key")
+			return
+		}
+		if key != "" && !g.authorized(r) {
+			g.logger.Warn("Refused gateway request without a valid token", "provider", p.Name)
+			writeError(w, http.StatusUnauthorized, "invalid_api_key", "Send a prompt-security API token as the API key to have the gateway's key used")
+			return
+		}
+
+		if pass {
+			g.passThrough(w, r, p, key)
+		} else {
+			g.redactAndForward(w, r, p, key, policy.Engine)
+		}
+	}
+}
+
+// key returns the configured or stored key of a provider, or "" to send
+// the client's key
+func (g *Gateway) key(p Provider) (string, error) {
+	if key := g.opts.Upstreams[p.Name].APIKey; key != "" {
+		return key, nil
+	}
+	if g.opts.Keys == nil {
+		return "", nil
+	}
+	return g.opts.Keys(p.Name)
+}
+
+// authorized reports whether the client of r sent a credential accepted by
+// Options.Authorize
+func (g *Gateway) authorized(r *http.Request) bool {
+	credential := clientKey(r)
+	if credential == "" || g.opts.Authorize == nil {
+		return false
+	}
+	ok, err := g.opts.Authorize(credential)
+	if err != nil {
+		g.logger.Error("Failed to check the gateway token", "error", err)
+		return false
+	}
+	return ok
+}
+
+// clientKey returns the API key the client of r sent, in one of keyHeaders
+// or Gemini's key parameter
+func clientKey(r *http.Request) string {
+	for _, h := range keyHeaders {
+		value := strings.TrimSpace(r.Header.Get(h))
+		if len(value) > 7 && strings.EqualFold(value[:7], "Bearer ") {
+			value = strings.TrimSpace(value[7:])
+		}
+		if value != "" {
+			return value
+		}
+	}
+	return r.URL.Query().Get("key")
+}
+
+// policy returns the policy for the upstream host of a provider, with the
+// gateway's engine unless the policy restricts detection
+func (g *Gateway) policy(p Provider) Policy {
+	policy := Policy{Allow: true}
+	if g.opts.Policy != nil {
+		policy = g.opts.Policy(g.upstreams[p.Name].Host)
+	}
+	if policy.Engine == nil {
+		policy.Engine = g.engine
+	}
+	return policy
+}
+
+// redactAndForward redacts the prompt fields of a request body with engine
+// and forwards it with key
+func (g *Gateway) redactAndForward(w http.ResponseWriter, r *http.Request, p Provider, key string, engine *filter.Engine) {
+	data, err := io.ReadAll(io.LimitReader(r.Body, maxRequestBytes+1))
+	if err != nil || len(data) > maxRequestBytes {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "Request body is unreadable or too large")
+		return
+	}
+
+	var body interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&body); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "Request body must be JSON")
+		return
+	}
+	if _, ok := body.(map[string]interface{}); !ok {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "Request body must be a JSON object")
+		return
+	}
+
+	red := &redactor{ctx: r.Context(), engine: engine}
+	if g.opts.Detokenize {
+		red.tokens = newTokens()
+	}
+	err = red.redactFields(body, p.Fields, func(original, redacted string, summary filter.ReplacementSummary) {
+		g.addLog(original, redacted, summary.Replacements)
+	})
+	var blocked *blockedError
+	if errors.As(err, &blocked) {
+		g.logger.Warn("Blocked gateway request", "provider", p.Name, "types", blocked.Types)
+		writeError(w, http.StatusForbidden, "prompt_blocked", blocked.Error())
+		return
+	}
+	if err != nil && r.Context().Err() != nil {
+		g.logger.Warn("Gateway request ended while redacting", "provider", p.Name, "error", err)
+		writeError(w, http.StatusServiceUnavailable, "server_error", "The request ended before it was redacted")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", err.Error())
+		return
+	}
+	if data, err = json.Marshal(body); err != nil {
+		writeError(w, http.StatusInternalServerError, "server_error", "Failed to encode the redacted request")
+		return
+	}
+
+	resp, err := g.forward(r, p, key, data)
+	if err != nil {
+		g.logger.Error("Failed to reach upstream", "provider", p.Name, "error", err)
+		writeError(w, http.StatusBadGateway, "upstream_error", "Failed to reach the upstream API")
+		return
+	}
+	defer resp.Body.Close()
+
+	// Scan the response as the model generated it, before tokens are
+	// restored, so values the client sent are not reported back
+	var leaks *leakScanner
+	defer func() {
+		if leaks != nil && resp.StatusCode < 300 {
+			leaks.scan(engine, g.opts.LogLeak)
+		}
+	}()
+
+	if strings.HasPrefix(resp.Header.Get("Content-Type"), "text/event-stream") {
+		var d *detokenizer
+		if red.tokens != nil {
+			d = newDetokenizer(red.tokens, p.StreamFields)
+		}
+		var onEvent func(string)
+		if g.opts.LogLeak != nil {
+			leaks = newLeakScanner(append(append([]string{}, p.StreamFields...), p.StreamToolFields...))
+			onEvent = leaks.event
+		}
+		writeHeader(w, resp)
+		err := streamEvents(newFlushWriter(w), newCappedReader(resp.Body, maxResponseBytes), d, onEvent)
+		if errors.Is(err, errResponseTooLarge) {
+			// Too late for an error status; break the connection so the
+			// client does not take the stream for complete
+			g.logger.Warn("Aborted a stream exceeding the size limit", "provider", p.Name)
+			panic(http.ErrAbortHandler)
+		}
+		if err != nil {
+			g.logger.Warn("Stream interrupted", "provider", p.Name, "error", err)
+		}
+		return
+	}
+	if g.opts.LogLeak != nil {
+		leaks = newLeakScanner(p.ResponseFields)
+	}
+	if red.tokens == nil {
+		if leaks == nil {
+			copyResponse(w, resp, resp.Body)
+			return
+		}
+		buffer := &cappedBuffer{max: maxResponseScanBytes}
+		copyResponse(w, resp, io.TeeReader(resp.Body, buffer))
+		if !buffer.over {
+			leaks.document(buffer.Bytes())
+		}
+		return
+	}
+	data, err = io.ReadAll(newCappedReader(resp.Body, maxResponseBytes))
+	if errors.Is(err, errResponseTooLarge) {
+		g.logger.Warn("Refused a response exceeding the size limit", "provider", p.Name)
+		writeError(w, http.StatusBadGateway, "upstream_error", "The upstream response is too large")
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadGateway, "upstream_error", "Failed to read the upstream response")
+		return
+	}
+	if leaks != nil {
+		leaks.document(data)
+	}
+	copyResponse(w, resp, bytes.NewReader(red.tokens.restoreJSON(data)))
+}
+
+// passThrough forwards a request that carries no prompts as is, with key
+func (g *Gateway) passThrough(w http.ResponseWriter, r *http.Request, p Provider, key string) {
+	resp, err := g.forward(r, p, key, nil)
+	if err != nil {
+		g.logger.Error("Failed to reach upstream", "provider", p.Name, "error", err)
+		writeError(w, http.StatusBadGateway, "upstream_error", "Failed to reach the upstream API")
+		return
+	}
+	defer resp.Body.Close()
+	copyResponse(w, resp, resp.Body)
+}
+
+// forward sends r with body to the same path under the provider's upstream
+// URL, authenticated with key instead of the client's key unless it is ""
+func (g *Gateway) forward(r *http.Request, p Provider, key string, body []byte) (*http.Response, error) {
+	upstream := g.opts.Upstreams[p.Name]
+
+	target := *g.upstreams[p.Name]
+	target.Path = strings.TrimSuffix(target.Path, "/") + strings.TrimPrefix(r.URL.Path, p.Prefix)
+	query := target.Query()
+	for name, values := range r.URL.Query() {
+		if name == "key" && key != "" {
+			continue // Gemini's key parameter, replaced by the gateway's key
+		}
+		for _, v := range values {
+			query.Add(name, v)
+		}
+	}
+	target.RawQuery = query.Encode()
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	out, err := http.NewRequestWithContext(r.Context(), r.Method, target.String(), reader)
+	if err != nil {
+		return nil, err
+	}
+	out.Header = r.Header.Clone()
+	for _, h := range hopHeaders {
+		out.Header.Del(h)
+	}
+	// Let the transport negotiate compression so responses can be read
+	out.Header.Del("Accept-Encoding")
+	out.Header.Del("Content-Length")
+
+	if key != "" {
+		for _, h := range keyHeaders {
+			out.Header.Del(h)
+		}
+		header := upstream.APIKeyHeader
+		if header == "" {
+			header = p.KeyHeader
+		}
+		if strings.EqualFold(header, "Authorization") {
+			out.Header.Set("Authorization", "Bearer "+key)
+		} else {
+			out.Header.Set(header, key)
+		}
+	}
+	return g.client.Do(out)
+}
+
+// copyResponse writes the status and headers of resp with body, flushing
+// as it is read so streamed responses are not held back
+func copyResponse(w http.ResponseWriter, resp *http.Response, body io.Reader) {
+	writeHeader(w, resp)
+	io.Copy(newFlushWriter(w), body)
+}
+
+// writeHeader writes the status and headers of resp
+func writeHeader(w http.ResponseWriter, resp *http.Response) {
+	for name, values := range resp.Header {
+		w.Header()[name] = values
+	}
+	for _, h := range hopHeaders {
+		w.Header().Del(h)
+	}
+	w.Header().Del("Content-Length")
+	w.WriteHeader(resp.StatusCode)
+}
+
+// writeError writes an error in the shape OpenAI SDKs expect
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"error": map[string]string{
+			"message": message,
+			"type":    code,
+			"code":    code,
+		},
+	})
+}
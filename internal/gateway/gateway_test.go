@@ -0,0 +1,377 @@
+package gateway
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+	"github.com/happytaoer/prompt-security/internal/filter"
+)
+
+// testEngine detects emails and a quoted codename, and blocks SSNs
+func testEngine() *filter.Engine {
+	return filter.NewEngine(config.Config{
+		DetectEmails:     true,
+		EmailReplacement: "[EMAIL]",
+		DetectSSNs:       true,
+		SSNReplacement:   "XXX-XX-XXXX",
+		SSNAction:        config.ActionBlock,
+		StringMatchPatterns: []config.StringMatchPattern{
+			{Name: "codename", Pattern: `"Falcon"`, Enabled: true, Replacement: "[CODENAME]"},
+		},
+	})
+}
+
+// upstream records the last request and answers with reply
+type upstream struct {
+	server *httptest.Server
+	path   string
+	query  string
+	auth   string
+	header http.Header
+	body   map[string]json.RawMessage
+}
+
+func newUpstream(t *testing.T, reply string) *upstream {
+	u := &upstream{}
+	u.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		u.path, u.query, u.header = r.URL.Path, r.URL.RawQuery, r.Header
+		u.auth = r.Header.Get("Authorization")
+		u.body = nil
+		json.NewDecoder(r.Body).Decode(&u.body)
+		w.Header().Set("Content-Type", "application/json")
+		io.WriteString(w, reply)
+	}))
+	t.Cleanup(u.server.Close)
+	return u
+}
+
+// messages returns the contents of the forwarded messages
+func (u *upstream) messages(t *testing.T) []string {
+	var messages []struct {
+		Content json.RawMessage `json:"content"`
+	}
+	if err := json.Unmarshal(u.body["messages"], &messages); err != nil {
+		t.Fatalf("Failed to decode forwarded messages: %v", err)
+	}
+	contents := make([]string, len(messages))
+	for i, m := range messages {
+		contents[i] = string(m.Content)
+	}
+	return contents
+}
+
+// openAI returns options forwarding OpenAI requests to url with key
+func openAI(url, key string) Options {
+	return Options{Upstreams: map[string]Upstream{ProviderOpenAI: {URL: url, APIKey: key}}}
+}
+
+// clientToken authorizes clients sending client-key
+func clientToken(credential string) (bool, error) {
+	return credential == "client-key", nil
+}
+
+func post(t *testing.T, g *Gateway, body string) *httptest.ResponseRecorder {
+	return postTo(t, g, "/v1/chat/completions?trace=1", body)
+}
+
+func postTo(t *testing.T, g *Gateway, path, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	r := httptest.NewRequest(http.MethodPost, "http://localhost:8282"+path, strings.NewReader(body))
+	r.Header.Set("Authorization", "Bearer client-key")
+	g.Handler().ServeHTTP(w, r)
+	return w
+}
+
+// TestChatCompletions tests that messages are redacted and logged, and the
+// request forwarded with its other fields
+func TestChatCompletions(t *testing.T) {
+	up := newUpstream(t, `{"choices":[{"message":{"content":"ok"}}]}`)
+	var logged []string
+	g, err := New(openAI(up.server.URL+"/v1?api-version=1", ""), testEngine(), func(original, redacted string, _ []filter.ReplacementInfo) {
+		logged = append(logged, redacted)
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	w := post(t, g, `{"model":"gpt-4o","messages":[
+		{"role":"user","content":"I am ann@example.com"},
+		{"role":"user","content":[{"type":"text","text":"mail bob@example.com"},{"type":"image_url","image_url":{"url":"x"}}]}]}`)
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"ok"`) {
+		t.Fatalf("Expected the upstream response, got %d: %s", w.Code, w.Body.String())
+	}
+
+	got := up.messages(t)
+	if got[0] != `"I am [EMAIL]"` || !strings.Contains(got[1], `"mail [EMAIL]"`) || !strings.Contains(got[1], `"image_url"`) {
+		t.Errorf("Expected redacted messages, got %q", got)
+	}
+	if string(up.body["model"]) != `"gpt-4o"` {
+		t.Errorf("Expected other fields to be forwarded, got %s", up.body["model"])
+	}
+	if up.path != "/v1/chat/completions" || !strings.Contains(up.query, "api-version=1") || !strings.Contains(up.query, "trace=1") {
+		t.Errorf("Unexpected upstream path %q and query %q", up.path, up.query)
+	}
+	if up.auth != "Bearer client-key" {
+		t.Errorf("Expected the client's key to be forwarded, got %q", up.auth)
+	}
+	if len(logged) != 1 || logged[0] != "mail [EMAIL]" {
+		t.Errorf("Expected only the last message to be logged, got %q", logged)
+	}
+}
+
+// TestChatCompletions_Detokenize tests that tokens in the response get
+// their values back
+func TestChatCompletions_Detokenize(t *testing.T) {
+	up := newUpstream(t, `{"choices":[{"message":{"content":"Write to [EMAIL_2] about [CODENAME_1], not [EMAIL_1]"}}]}`)
+	opts := openAI(up.server.URL+"/v1", "server-key")
+	opts.Detokenize = true
+	opts.Authorize = clientToken
+	g, _ := New(opts, testEngine(), nil)
+
+	w := post(t, g, `{"messages":[{"role":"user","content":"a@example.com, b@example.com and a@example.com on \"Falcon\""}]}`)
+	if got := up.messages(t)[0]; got != `"[EMAIL_1], [EMAIL_2] and [EMAIL_1] on [CODENAME_1]"` {
+		t.Errorf("Expected unique tokens, got %s", got)
+	}
+	var resp struct {
+		Choices []struct {
+			Message struct{ Content string } `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("Expected valid JSON, got %s", w.Body.String())
+	}
+	if got := resp.Choices[0].Message.Content; got != `Write to b@example.com about "Falcon", not a@example.com` {
+		t.Errorf("Expected detokenized content, got %q", got)
+	}
+	if up.auth != "Bearer server-key" {
+		t.Errorf("Expected the configured key, got %q", up.auth)
+	}
+}
+
+// TestChatCompletions_Blocked tests that blocked data is never forwarded
+func TestChatCompletions_Blocked(t *testing.T) {
+	up := newUpstream(t, `{}`)
+	g, _ := New(openAI(up.server.URL+"/v1", ""), testEngine(), nil)
+
+	w := post(t, g, `{"messages":[{"role":"user","content":"SSN 123-45-6789"}]}`)
+	if w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), "prompt_blocked") {
+		t.Errorf("Expected a blocked error, got %d: %s", w.Code, w.Body.String())
+	}
+	if up.body != nil {
+		t.Error("Expected nothing to be forwarded")
+	}
+}
+
+// TestUnsupportedEndpoint tests that endpoints that are not redacted are
+// refused
+func TestUnsupportedEndpoint(t *testing.T) {
+	g, _ := New(Options{}, testEngine(), nil)
+	w := httptest.NewRecorder()
+	g.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodPost, "http://localhost:8282/v1/embeddings", strings.NewReader(`{}`)))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+// TestIntercept tests that requests intercepted on the way to an HTTPS
+// upstream are redacted and sent to it under their original path
+func TestIntercept(t *testing.T) {
+	var path string
+	var body map[string]json.RawMessage
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path, body = r.URL.Path, nil
+		json.NewDecoder(r.Body).Decode(&body)
+		io.WriteString(w, `{"choices":[]}`)
+	}))
+	defer server.Close()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(server.Certificate())
+	opts := openAI(server.URL+"/v1", "")
+	opts.TLSConfig = &tls.Config{RootCAs: roots}
+	g, err := New(opts, testEngine(), nil)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	if _, ok := g.Intercept("example.com"); ok {
+		t.Error("Expected hosts other than upstreams not to be intercepted")
+	}
+	handler, ok := g.Intercept("127.0.0.1")
+	if !ok {
+		t.Fatal("Expected the upstream host to be intercepted")
+	}
+
+	w := httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(`{"messages":[{"role":"user","content":"I am ann@example.com"}]}`)))
+	if w.Code != http.StatusOK || path != "/v1/chat/completions" || !strings.Contains(string(body["messages"]), "[EMAIL]") {
+		t.Errorf("Expected a redacted request to /v1/chat/completions, got %d to %q: %s", w.Code, path, body["messages"])
+	}
+
+	w = httptest.NewRecorder()
+	handler.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v2/chat/completions", strings.NewReader(`{}`)))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected paths outside the upstream to be refused, got %d", w.Code)
+	}
+}
+
+// TestAnthropicMessages tests redacting the system prompt and content
+// blocks of the Anthropic Messages API, authenticated with a stored key
+func TestAnthropicMessages(t *testing.T) {
+	up := newUpstream(t, `{"content":[{"type":"text","text":"ok"}]}`)
+	opts := Options{
+		Upstreams: map[string]Upstream{ProviderAnthropic: {URL: up.server.URL}},
+		Keys: func(provider string) (string, error) {
+			if provider == ProviderAnthropic {
+				return "stored-key", nil
+			}
+			return "", nil
+		},
+		Authorize: clientToken,
+	}
+	g, _ := New(opts, testEngine(), nil)
+
+	w := postTo(t, g, "/anthropic/v1/messages", `{"model":"claude","max_tokens":10,"system":"I am ann@example.com","messages":[
+		{"role":"user","content":[{"type":"text","text":"mail bob@example.com"}]},
+		{"role":"user","content":[{"type":"tool_result","tool_use_id":"t1","content":"from cat@example.com"}]}]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected the upstream response, got %d: %s", w.Code, w.Body.String())
+	}
+	if up.path != "/v1/messages" || up.header.Get("X-Api-Key") != "stored-key" || up.auth != "" {
+		t.Errorf("Unexpected path %q or keys %q and %q", up.path, up.header.Get("X-Api-Key"), up.auth)
+	}
+	if string(up.body["system"]) != `"I am [EMAIL]"` || string(up.body["max_tokens"]) != "10" {
+		t.Errorf("Expected a redacted system prompt and other fields kept, got %s", up.body["system"])
+	}
+	got := strings.Join(up.messages(t), " ")
+	if strings.Contains(got, "@example.com") || !strings.Contains(got, `"tool_use_id":"t1"`) {
+		t.Errorf("Expected redacted content blocks, got %s", got)
+	}
+}
+
+// TestGeminiGenerateContent tests redacting the parts of Gemini contents
+// and replacing the key given as a query parameter
+func TestGeminiGenerateContent(t *testing.T) {
+	up := newUpstream(t, `{"candidates":[]}`)
+	opts := Options{Upstreams: map[string]Upstream{ProviderGemini: {URL: up.server.URL, APIKey: "server-key"}}, Authorize: clientToken}
+	g, _ := New(opts, testEngine(), nil)
+
+	w := postTo(t, g, "/gemini/v1beta/models/gemini-pro:generateContent?key=client-key",
+		`{"systemInstruction":{"parts":[{"text":"ann@example.com"}]},"contents":[{"role":"user","parts":[{"text":"mail bob@example.com"},{"inlineData":{"mimeType":"image/png","data":"AAAA"}}]}]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("Expected the upstream response, got %d: %s", w.Code, w.Body.String())
+	}
+	if up.path != "/v1beta/models/gemini-pro:generateContent" || strings.Contains(up.query, "client-key") || up.header.Get("X-Goog-Api-Key") != "server-key" {
+		t.Errorf("Unexpected path %q, query %q or key %q", up.path, up.query, up.header.Get("X-Goog-Api-Key"))
+	}
+	contents, _ := json.Marshal(up.body)
+	if strings.Contains(string(contents), "@example.com") || !strings.Contains(string(contents), "inlineData") {
+		t.Errorf("Expected redacted parts, got %s", contents)
+	}
+
+	w = postTo(t, g, "/gemini/v1beta/models/gemini-pro:tuneModel", `{}`)
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected unknown methods to be refused, got %d", w.Code)
+	}
+}
+
+// TestKeyAuthorization tests that the gateway's key is only sent for
+// clients sending an authorized credential
+func TestKeyAuthorization(t *testing.T) {
+	up := newUpstream(t, `{"data":[]}`)
+	opts := openAI(up.server.URL+"/v1", "server-key")
+	g, _ := New(opts, testEngine(), nil)
+
+	w := httptest.NewRecorder()
+	g.Handler().ServeHTTP(w, httptest.NewRequest(http.MethodGet, "http://localhost:8282/v1/models", nil))
+	if w.Code != http.StatusUnauthorized || up.auth != "" {
+		t.Errorf("Expected a request without a token to be refused, got %d with %q upstream", w.Code, up.auth)
+	}
+
+	w = postTo(t, g, "/v1/chat/completions", `{"messages":[]}`)
+	if w.Code != http.StatusUnauthorized || up.auth != "" {
+		t.Errorf("Expected a request without Authorize to be refused, got %d with %q upstream", w.Code, up.auth)
+	}
+
+	opts.Authorize = clientToken
+	g, _ = New(opts, testEngine(), nil)
+	for _, header := range []string{"Authorization", "X-Api-Key"} {
+		w = httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "http://localhost:8282/v1/models", nil)
+		r.Header.Set(header, "wrong-key")
+		g.Handler().ServeHTTP(w, r)
+		if w.Code != http.StatusUnauthorized {
+			t.Errorf("Expected a wrong token in %s to be refused, got %d", header, w.Code)
+		}
+	}
+
+	w = postTo(t, g, "/v1/chat/completions", `{"messages":[]}`)
+	if w.Code != http.StatusOK || up.auth != "Bearer server-key" {
+		t.Errorf("Expected the gateway's key to be sent for an authorized client, got %d with %q", w.Code, up.auth)
+	}
+}
+
+// TestHandler_Host tests that requests naming hosts other than loopback
+// names and the configured hosts are refused, against DNS rebinding
+func TestHandler_Host(t *testing.T) {
+	up := newUpstream(t, `{"data":[]}`)
+	opts := openAI(up.server.URL+"/v1", "")
+	opts.Hosts = []string{"gateway.lan"}
+	g, _ := New(opts, testEngine(), nil)
+
+	for host, want := range map[string]int{
+		"localhost:8282":      http.StatusOK,
+		"LOCALHOST.":          http.StatusOK,
+		"127.0.0.1:8282":      http.StatusOK,
+		"[::1]:8282":          http.StatusOK,
+		"gateway.lan:8282":    http.StatusOK,
+		"attacker.example":    http.StatusForbidden,
+		"attacker.example:80": http.StatusForbidden,
+		"10.0.0.1:8282":       http.StatusForbidden,
+	} {
+		w := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/v1/models", nil)
+		r.Host = host
+		g.Handler().ServeHTTP(w, r)
+		if w.Code != want {
+			t.Errorf("Expected status %d for host %q, got %d", want, host, w.Code)
+		}
+	}
+}
+
+// TestToolFields tests that the tool calls and tool results clients send
+// back with the conversation are redacted for each provider
+func TestToolFields(t *testing.T) {
+	for _, c := range []struct {
+		provider, path, body, want string
+	}{
+		{ProviderOpenAI, "/v1/chat/completions",
+			`{"messages":[{"role":"assistant","tool_calls":[{"id":"c1","type":"function","function":{"name":"send","arguments":"{\"to\":\"ann@example.com\"}"}}]}]}`,
+			`"arguments":"{\"to\":\"[EMAIL]\"}"`},
+		{ProviderAnthropic, "/anthropic/v1/messages",
+			`{"messages":[{"role":"assistant","content":[{"type":"tool_use","id":"t1","name":"send","input":{"to":"ann@example.com","cc":["bob@example.com"]}}]}]}`,
+			`"input":{"cc":["[EMAIL]"],"to":"[EMAIL]"}`},
+		{ProviderGemini, "/gemini/v1beta/models/gemini-pro:generateContent",
+			`{"contents":[{"role":"model","parts":[{"functionCall":{"name":"lookup","args":{"email":"ann@example.com"}}}]},
+				{"role":"user","parts":[{"functionResponse":{"name":"lookup","response":{"owner":{"email":"bob@example.com"}}}}]}]}`,
+			`"response":{"owner":{"email":"[EMAIL]"}}`},
+	} {
+		up := newUpstream(t, `{}`)
+		g, _ := New(Options{Upstreams: map[string]Upstream{c.provider: {URL: up.server.URL}}}, testEngine(), nil)
+		w := postTo(t, g, c.path, c.body)
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: expected the upstream response, got %d: %s", c.provider, w.Code, w.Body.String())
+		}
+		forwarded, _ := json.Marshal(up.body)
+		if !strings.Contains(string(forwarded), c.want) || strings.Contains(string(forwarded), "@example.com") {
+			t.Errorf("%s: expected %s in the forwarded request, got %s", c.provider, c.want, forwarded)
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package gateway
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/happytaoer/prompt-security/internal/filter"
+)
+
+// leakScanner collects the text a model generates in a response, including
+// the arguments of tool calls, to scan it for sensitive data once the
+// response is complete. Streamed text is joined per field first, since a
+// value may be split across events.
+type leakScanner struct {
+	fields [][]string
+	keys   []string // Keys of the collected texts, in order of appearance
+	texts  map[string]*strings.Builder
+}
+
+func newLeakScanner(fields []string) *leakScanner {
+	s := &leakScanner{texts: make(map[string]*strings.Builder)}
+	for _, field := range fields {
+		s.fields = append(s.fields, strings.Split(field, "."))
+	}
+	return s
+}
+
+// event collects the text in the data of a stream event
+func (s *leakScanner) event(data string) {
+	s.document([]byte(data))
+}
+
+// document collects the text in a response body, which may also be an
+// array of responses as Gemini streams without alt=sse
+func (s *leakScanner) document(data []byte) {
+	var body interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if decoder.Decode(&body) != nil {
+		return
+	}
+	bodies := []interface{}{body}
+	if list, ok := body.([]interface{}); ok {
+		bodies = list
+	}
+	for _, body := range bodies {
+		for _, path := range s.fields {
+			eachValue(body, path, "", s.add)
+		}
+	}
+}
+
+// add appends a value to the text collected at key. Values other than
+// strings, such as tool call arguments given as objects, are added as JSON.
+func (s *leakScanner) add(key string, value interface{}) {
+	text, ok := value.(string)
+	if !ok {
+		encoded, err := json.Marshal(value)
+		if err != nil {
+			return
+		}
+		text = string(encoded)
+	}
+	b, ok := s.texts[key]
+	if !ok {
+		b = &strings.Builder{}
+		s.texts[key] = b
+		s.keys = append(s.keys, key)
+	}
+	b.WriteString(text)
+}
+
+// scan filters the collected texts with engine and calls logLeak for each
+// that contains sensitive data
+func (s *leakScanner) scan(engine *filter.Engine, logLeak LogFunc) {
+	for _, key := range s.keys {
+		text := s.texts[key].String()
+		filtered, _, summary := engine.Filter(text)
+		if len(summary.Replacements) > 0 {
+			logLeak(text, filtered, summary.Replacements)
+		}
+	}
+}
+
+// eachValue calls fn with each value at path below node, and a key naming
+// its position, e.g. choices.0.message.content
+func eachValue(node interface{}, path []string, key string, fn func(key string, value interface{})) {
+	if len(path) == 0 {
+		if node != nil {
+			fn(key, node)
+		}
+		return
+	}
+	switch n := node.(type) {
+	case map[string]interface{}:
+		if child, ok := n[path[0]]; ok {
+			eachValue(child, path[1:], key+"."+path[0], fn)
+		}
+	case []interface{}:
+		if path[0] == "*" {
+			for i, child := range n {
+				eachValue(child, path[1:], key+"."+strconv.Itoa(i), fn)
+			}
+		}
+	}
+}
+
+// cappedBuffer keeps what is written to it up to max bytes, and reports
+// whether more was written
+type cappedBuffer struct {
+	bytes.Buffer
+	max  int
+	over bool
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if b.over || b.Len()+len(p) > b.max {
+		b.over = true
+		return len(p), nil
+	}
+	return b.Buffer.Write(p)
+}
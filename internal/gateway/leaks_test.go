@@ -0,0 +1,64 @@
+package gateway
+
+import (
+	"testing"
+
+	"github.com/happytaoer/prompt-security/internal/filter"
+)
+
+// leakLog records the texts logged as leaks
+func leakLog(leaks *[]string) LogFunc {
+	return func(original, redacted string, _ []filter.ReplacementInfo) {
+		*leaks = append(*leaks, original)
+	}
+}
+
+// TestResponseLeaks tests that sensitive data in generated text and tool
+// call arguments is logged, but not values restored from tokens
+func TestResponseLeaks(t *testing.T) {
+	up := newUpstream(t, `{"choices":[{"message":{"content":"Mail [EMAIL_1] or cc@example.com",
+		"tool_calls":[{"function":{"name":"send","arguments":"{\"to\":\"dd@example.com\"}"}}]}}]}`)
+	var leaks []string
+	opts := openAI(up.server.URL+"/v1", "")
+	opts.Detokenize = true
+	opts.LogLeak = leakLog(&leaks)
+	g, _ := New(opts, testEngine(), nil)
+
+	post(t, g, `{"messages":[{"role":"user","content":"I am a@example.com"}]}`)
+	if len(leaks) != 2 || leaks[0] != "Mail [EMAIL_1] or cc@example.com" || leaks[1] != `{"to":"dd@example.com"}` {
+		t.Errorf("Expected the content and the tool call to be logged, got %q", leaks)
+	}
+}
+
+// TestResponseLeaks_Stream tests that deltas are joined before scanning, so
+// values split across events are found
+func TestResponseLeaks_Stream(t *testing.T) {
+	up := newStreamUpstream(t, []string{
+		`data: {"choices":[{"delta":{"content":"Hi "}}]}`,
+		`data: {"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"{\"to\":\"ee@exa"}}]}}]}`,
+		`data: {"choices":[{"delta":{"tool_calls":[{"index":0,"function":{"arguments":"mple.com\"}"}}]}}]}`,
+		"data: [DONE]",
+	}, nil)
+	var leaks []string
+	opts := openAI(up.URL+"/v1", "")
+	opts.LogLeak = leakLog(&leaks)
+	g, _ := New(opts, testEngine(), nil)
+
+	post(t, g, `{"stream":true,"messages":[]}`)
+	if len(leaks) != 1 || leaks[0] != `{"to":"ee@example.com"}` {
+		t.Errorf("Expected the joined tool call arguments to be logged, got %q", leaks)
+	}
+}
+
+// TestResponseLeaks_Anthropic tests scanning tool inputs given as objects
+func TestResponseLeaks_Anthropic(t *testing.T) {
+	up := newUpstream(t, `{"content":[{"type":"text","text":"ok"},{"type":"tool_use","name":"send","input":{"to":"ff@example.com"}}]}`)
+	var leaks []string
+	opts := Options{Upstreams: map[string]Upstream{ProviderAnthropic: {URL: up.server.URL}}, LogLeak: leakLog(&leaks)}
+	g, _ := New(opts, testEngine(), nil)
+
+	postTo(t, g, "/anthropic/v1/messages", `{"messages":[]}`)
+	if len(leaks) != 1 || leaks[0] != `{"to":"ff@example.com"}` {
+		t.Errorf("Expected the tool input to be logged, got %q", leaks)
+	}
+}
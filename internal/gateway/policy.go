@@ -0,0 +1,67 @@
+package gateway
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+	"github.com/happytaoer/prompt-security/internal/filter"
+)
+
+// Policy decides how traffic to a destination host is handled
+type Policy struct {
+	// Allow lets traffic to the host through; otherwise it is refused
+	Allow bool
+
+	// Engine redacts traffic to the host, nil for the gateway's engine
+	Engine *filter.Engine
+}
+
+// DomainPolicies resolves the domain policies of a configuration, with
+// detection restricted to each policy's detectors. Hosts without a
+// matching policy are allowed and use the gateway's engine.
+type DomainPolicies struct {
+	manager *config.Manager
+	mu      sync.Mutex
+	engines map[string]*filter.Engine // By the policy's detectors, comma-separated
+}
+
+// NewDomainPolicies creates a resolver for the domain policies of manager's
+// configuration
+func NewDomainPolicies(manager *config.Manager) *DomainPolicies {
+	d := &DomainPolicies{manager: manager, engines: make(map[string]*filter.Engine)}
+	manager.OnChange(d.reload)
+	return d
+}
+
+// Policy returns the policy for traffic to host
+func (d *DomainPolicies) Policy(host string) Policy {
+	p, ok := d.manager.Snapshot().DomainPolicy(host)
+	if !ok {
+		return Policy{Allow: true}
+	}
+	if !p.Allow || len(p.Detectors) == 0 {
+		return Policy{Allow: p.Allow}
+	}
+
+	key := strings.Join(p.Detectors, ",")
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	engine, ok := d.engines[key]
+	if !ok {
+		// Read the configuration again under mu, so a change is either
+		// seen here or reloaded into the new engine
+		engine = filter.NewEngine(config.RestrictDetectors(d.manager.Effective(), p.Detectors))
+		d.engines[key] = engine
+	}
+	return Policy{Allow: true, Engine: engine}
+}
+
+// reload recompiles the engines of every set of detectors in use
+func (d *DomainPolicies) reload(cfg config.Config) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for key, engine := range d.engines {
+		engine.Reload(config.RestrictDetectors(cfg, strings.Split(key, ",")))
+	}
+}
@@ -0,0 +1,58 @@
+package gateway
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+)
+
+// policyConfig is the configuration of testEngine with domain policies
+func policyConfig(policies ...config.DomainPolicy) config.Config {
+	return config.Config{
+		DetectEmails:     true,
+		EmailReplacement: "[EMAIL]",
+		StringMatchPatterns: []config.StringMatchPattern{
+			{Name: "codename", Pattern: "Falcon", Enabled: true, Replacement: "[CODENAME]"},
+		},
+		DomainPolicies: policies,
+	}
+}
+
+// TestDomainPolicy_Detectors tests that a policy restricts detection to its
+// detectors for its host only
+func TestDomainPolicy_Detectors(t *testing.T) {
+	up := newUpstream(t, `{}`)
+	cfg := policyConfig(
+		config.DomainPolicy{Host: "127.0.0.1", Allow: true, Detectors: []string{config.DetectorStringMatch}},
+		config.DomainPolicy{Host: config.AnyHost, Allow: true},
+	)
+	opts := openAI(up.server.URL+"/v1", "")
+	opts.Policy = NewDomainPolicies(config.NewStaticManager(cfg)).Policy
+	g, _ := New(opts, testEngine(), nil)
+
+	post(t, g, `{"messages":[{"role":"user","content":"a@example.com on Falcon"}]}`)
+	if got := up.messages(t)[0]; got != `"a@example.com on [CODENAME]"` {
+		t.Errorf("Expected only the string match pattern to apply, got %s", got)
+	}
+}
+
+// TestDomainPolicy_Disallowed tests that traffic to a disallowed host is
+// refused without reaching it
+func TestDomainPolicy_Disallowed(t *testing.T) {
+	up := newUpstream(t, `{}`)
+	opts := openAI(up.server.URL+"/v1", "")
+	opts.Policy = NewDomainPolicies(config.NewStaticManager(policyConfig(
+		config.DomainPolicy{Host: config.AnyHost, Allow: false},
+	))).Policy
+	g, _ := New(opts, testEngine(), nil)
+
+	w := post(t, g, `{"messages":[{"role":"user","content":"hello"}]}`)
+	if w.Code != http.StatusForbidden || !strings.Contains(w.Body.String(), "destination_blocked") {
+		t.Errorf("Expected the request to be refused, got %d: %s", w.Code, w.Body.String())
+	}
+	if up.body != nil {
+		t.Error("Expected nothing to be forwarded")
+	}
+}
@@ -0,0 +1,169 @@
+package gateway
+
+import (
+	"net/http"
+	"strings"
+)
+
+// Providers
+const (
+	ProviderOpenAI    = "openai"
+	ProviderAnthropic = "anthropic"
+	ProviderGemini    = "gemini"
+)
+
+// Provider describes the API of a model provider: where clients reach it on
+// the gateway, how it authenticates and which request fields carry prompts
+type Provider struct {
+	Name string
+
+	// Prefix is the path prefix clients use on the gateway. It is removed
+	// before the path is appended to the upstream URL.
+	Prefix string
+
+	// DefaultUpstream is the base URL requests are forwarded to by default
+	DefaultUpstream string
+
+	// KeyHeader carries the API key, as a bearer token for Authorization
+	KeyHeader string
+
+	// Fields are the paths of the prompt text in request bodies, see
+	// redactFields
+	Fields []string
+
+	// StreamFields are the paths of the text deltas in the events of
+	// streamed responses, where tokens are restored while detokenizing
+	StreamFields []string
+
+	// ResponseFields are the paths of the generated text and tool call
+	// arguments in responses, scanned for leaked sensitive data
+	ResponseFields []string
+
+	// StreamToolFields are the paths of the tool call argument deltas in
+	// stream events, scanned with StreamFields
+	StreamToolFields []string
+
+	// endpoint reports whether a request to path, relative to Prefix,
+	// carries prompts to redact, or is passed through as it carries none.
+	// Other requests are refused, since they could carry unredacted text.
+	endpoint func(method, path string) (redact, pass bool)
+}
+
+// Providers lists the supported providers
+var Providers = []Provider{
+	{
+		Name:            ProviderOpenAI,
+		Prefix:          "/v1",
+		DefaultUpstream: DefaultUpstream,
+		KeyHeader:       "Authorization",
+		Fields: []string{
+			"messages.*.content",
+			"messages.*.content.*.text",
+			"messages.*.tool_calls.*.function.arguments", // Earlier tool calls, a JSON string
+		},
+		StreamFields: []string{"choices.*.delta.content"},
+		ResponseFields: []string{
+			"choices.*.message.content",
+			"choices.*.message.tool_calls.*.function.arguments",
+		},
+		StreamToolFields: []string{"choices.*.delta.tool_calls.*.function.arguments"},
+		endpoint: func(method, path string) (bool, bool) {
+			if method == http.MethodPost && path == "/chat/completions" {
+				return true, false
+			}
+			return false, isModelListing(method, path, "/models")
+		},
+	},
+	{
+		Name:            ProviderAnthropic,
+		Prefix:          "/anthropic",
+		DefaultUpstream: "https://api.anthropic.com",
+		KeyHeader:       "X-Api-Key",
+		Fields: []string{
+			"system",
+			"system.*.text",
+			"messages.*.content",
+			"messages.*.content.*.text",
+			"messages.*.content.*.content",        // Tool result as a string
+			"messages.*.content.*.content.*.text", // Tool result as blocks
+			"messages.*.content.*.input.**",       // Earlier tool calls
+		},
+		StreamFields:     []string{"delta.text"},
+		ResponseFields:   []string{"content.*.text", "content.*.input"},
+		StreamToolFields: []string{"delta.partial_json"},
+		endpoint: func(method, path string) (bool, bool) {
+			if method == http.MethodPost && (path == "/v1/messages" || path == "/v1/messages/count_tokens") {
+				return true, false
+			}
+			return false, isModelListing(method, path, "/v1/models")
+		},
+	},
+	{
+		Name:            ProviderGemini,
+		Prefix:          "/gemini",
+		DefaultUpstream: "https://generativelanguage.googleapis.com",
+		KeyHeader:       "X-Goog-Api-Key",
+		Fields: []string{
+			"systemInstruction.parts.*.text",
+			"contents.*.parts.*.text",
+			"contents.*.parts.*.functionCall.args.**",
+			"contents.*.parts.*.functionResponse.response.**", // Tool results
+			"content.parts.*.text",                            // embedContent
+			"requests.*.content.parts.*.text",                 // batchEmbedContents
+			"generateContentRequest.contents.*.parts.*.text",
+		},
+		StreamFields: []string{"candidates.*.content.parts.*.text"}, // With alt=sse
+		ResponseFields: []string{
+			"candidates.*.content.parts.*.text",
+			"candidates.*.content.parts.*.functionCall.args",
+		},
+		StreamToolFields: []string{"candidates.*.content.parts.*.functionCall.args"},
+		endpoint: func(method, path string) (bool, bool) {
+			for _, version := range []string{"/v1beta", "/v1"} {
+				rest, ok := strings.CutPrefix(path, version+"/models")
+				if !ok {
+					continue
+				}
+				if method == http.MethodPost {
+					_, action, _ := strings.Cut(rest, ":")
+					switch action {
+					case "generateContent", "streamGenerateContent", "countTokens", "embedContent", "batchEmbedContents":
+						return true, false
+					}
+					return false, false
+				}
+				return false, isModelListing(method, rest, "")
+			}
+			return false, false
+		},
+	},
+}
+
+// isModelListing reports whether a request lists models or gets one below
+// the models path
+func isModelListing(method, path, models string) bool {
+	if method != http.MethodGet {
+		return false
+	}
+	rest, ok := strings.CutPrefix(path, models)
+	return ok && (rest == "" || (strings.HasPrefix(rest, "/") && !strings.Contains(rest[1:], "/")))
+}
+
+// FindProvider returns the provider named name
+func FindProvider(name string) (Provider, bool) {
+	for _, p := range Providers {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return Provider{}, false
+}
+
+// ProviderNames returns the names of the supported providers
+func ProviderNames() []string {
+	names := make([]string, len(Providers))
+	for i, p := range Providers {
+		names[i] = p.Name
+	}
+	return names
+}
@@ -0,0 +1,247 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+	"github.com/happytaoer/prompt-security/internal/filter"
+)
+
+// tokens maps sensitive values to the unique tokens that replace them while
+// detokenizing, so responses mentioning a token can be given the value back
+type tokens struct {
+	byOriginal map[string]string
+	originals  map[string]string // Original of each token
+	counts     map[string]int    // Tokens issued per type
+}
+
+func newTokens() *tokens {
+	return &tokens{
+		byOriginal: make(map[string]string),
+		originals:  make(map[string]string),
+		counts:     make(map[string]int),
+	}
+}
+
+// token returns the token for a value of type typ, e.g. [EMAIL_1]. The same
+// value always gets the same token.
+func (t *tokens) token(typ, original string) string {
+	if tok, ok := t.byOriginal[original]; ok {
+		return tok
+	}
+	name := strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return unicode.ToUpper(r)
+		}
+		return '_'
+	}, typ)
+	t.counts[name]++
+	tok := fmt.Sprintf("[%s_%d]", name, t.counts[name])
+	t.byOriginal[original] = tok
+	t.originals[tok] = original
+	return tok
+}
+
+// restoreJSON replaces the tokens in a JSON document with their originals,
+// escaped to stay valid inside JSON strings. Tokens contain no characters
+// JSON escapes, so they appear in the document as issued.
+func (t *tokens) restoreJSON(data []byte) []byte {
+	if len(t.originals) == 0 {
+		return data
+	}
+	pairs := make([]string, 0, 2*len(t.originals))
+	for tok, original := range t.originals {
+		escaped, _ := json.Marshal(original)
+		pairs = append(pairs, tok, string(escaped[1:len(escaped)-1]))
+	}
+	return []byte(strings.NewReplacer(pairs...).Replace(string(data)))
+}
+
+// restore replaces the tokens in text with their originals
+func (t *tokens) restore(text string) string {
+	if len(t.originals) == 0 {
+		return text
+	}
+	pairs := make([]string, 0, 2*len(t.originals))
+	for tok, original := range t.originals {
+		pairs = append(pairs, tok, original)
+	}
+	return strings.NewReplacer(pairs...).Replace(text)
+}
+
+// partialSuffix returns the length of the longest end of text that is the
+// start of a token but not yet a whole one
+func (t *tokens) partialSuffix(text string) int {
+	for i := strings.IndexByte(text, '['); i >= 0; {
+		suffix := text[i:]
+		for tok := range t.originals {
+			if len(suffix) < len(tok) && strings.HasPrefix(tok, suffix) {
+				return len(suffix)
+			}
+		}
+		next := strings.IndexByte(text[i+1:], '[')
+		if next < 0 {
+			break
+		}
+		i += next + 1
+	}
+	return 0
+}
+
+// blockedError is returned when a message contains data whose detector
+// blocks it
+type blockedError struct {
+	Types []string
+}
+
+func (e *blockedError) Error() string {
+	return "request blocked, it contains sensitive data: " + strings.Join(e.Types, ", ")
+}
+
+// redactor redacts the text of one request
+type redactor struct {
+	ctx    context.Context // Of the request, ending the scan if it ends
+	engine *filter.Engine
+	tokens *tokens // Unique tokens replace matches if set
+}
+
+// redact filters text. With tokens, replaced matches get unique tokens
+// instead of their configured replacement.
+func (r *redactor) redact(text string) (string, filter.ReplacementSummary, error) {
+	filtered, changed, summary, err := r.engine.FilterContext(r.ctx, text)
+	if err != nil {
+		return "", summary, err
+	}
+	if !changed && len(summary.Replacements) == 0 {
+		return text, summary, nil
+	}
+	if summary.Action() == config.ActionBlock {
+		return "", summary, &blockedError{Types: summary.Types()}
+	}
+	if r.tokens == nil {
+		return filtered, summary, nil
+	}
+
+	var b strings.Builder
+	last := 0
+	replacements := make([]filter.ReplacementInfo, len(summary.Replacements))
+	for i, rep := range summary.Replacements {
+		if rep.Action != config.ActionLog && rep.Action != config.ActionReview {
+			rep.Replacement = r.tokens.token(rep.Type, rep.Original)
+			b.WriteString(text[last:rep.Start])
+			b.WriteString(rep.Replacement)
+			last = rep.End
+		}
+		replacements[i] = rep
+	}
+	summary.Replacements = replacements
+	b.WriteString(text[last:])
+	return b.String(), summary, nil
+}
+
+// redactFields redacts the strings at each field path in body, a decoded
+// JSON document. Paths are dot separated keys, with * for every element of
+// an array, e.g. messages.*.content, and a final ** for every string below,
+// e.g. tool call arguments given as an object. Paths that do not fit the
+// document are skipped, so one path can name a field that is a string in
+// some requests and missing in others. logged is called for the fields of
+// the newest element of the first array on the path, e.g. the newest
+// message, even without detections so the prompt can be classified; earlier
+// messages and fields outside arrays, such as system prompts, are resent by
+// clients with every request and are redacted without logging.
+func (r *redactor) redactFields(body interface{}, fields []string, logged func(original, redacted string, summary filter.ReplacementSummary)) error {
+	for _, field := range fields {
+		_, err := r.walk(body, strings.Split(field, "."), false, false, func(text string, newest bool) (string, error) {
+			redacted, summary, err := r.redact(text)
+			if err == nil && newest {
+				logged(text, redacted, summary)
+			}
+			return redacted, err
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// walk calls visit with each string at path below node and stores the
+// result in its place. inArray and newest tell whether node is below the
+// first array on the path, and in its newest element.
+func (r *redactor) walk(node interface{}, path []string, inArray, newest bool, visit func(text string, newest bool) (string, error)) (interface{}, error) {
+	if len(path) == 0 {
+		if text, ok := node.(string); ok {
+			return visit(text, newest)
+		}
+		return node, nil
+	}
+	if path[0] == "**" {
+		return r.walkAll(node, func(text string) (string, error) { return visit(text, newest) })
+	}
+
+	switch n := node.(type) {
+	case map[string]interface{}:
+		child, ok := n[path[0]]
+		if !ok {
+			return node, nil
+		}
+		v, err := r.walk(child, path[1:], inArray, newest, visit)
+		if err != nil {
+			return nil, err
+		}
+		n[path[0]] = v
+	case []interface{}:
+		if path[0] != "*" {
+			return node, nil
+		}
+		for i, child := range n {
+			childNewest := newest
+			if !inArray {
+				childNewest = i == len(n)-1
+			}
+			v, err := r.walk(child, path[1:], true, childNewest, visit)
+			if err != nil {
+				return nil, err
+			}
+			n[i] = v
+		}
+	}
+	return node, nil
+}
+
+// walkAll calls visit with every string below node, at any depth and in a
+// stable order, and stores the result in its place. Object keys are left as
+// they are.
+func (r *redactor) walkAll(node interface{}, visit func(text string) (string, error)) (interface{}, error) {
+	switch n := node.(type) {
+	case string:
+		return visit(n)
+	case map[string]interface{}:
+		keys := make([]string, 0, len(n))
+		for key := range n {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			v, err := r.walkAll(n[key], visit)
+			if err != nil {
+				return nil, err
+			}
+			n[key] = v
+		}
+	case []interface{}:
+		for i, child := range n {
+			v, err := r.walkAll(child, visit)
+			if err != nil {
+				return nil, err
+			}
+			n[i] = v
+		}
+	}
+	return node, nil
+}
@@ -0,0 +1,262 @@
+package gateway
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// flushWriter flushes every write, so streamed responses reach the client
+// as they arrive instead of when a buffer fills
+type flushWriter struct {
+	w       io.Writer
+	flusher http.Flusher
+}
+
+func newFlushWriter(w http.ResponseWriter) *flushWriter {
+	flusher, _ := w.(http.Flusher)
+	return &flushWriter{w: w, flusher: flusher}
+}
+
+func (f *flushWriter) Write(p []byte) (int, error) {
+	n, err := f.w.Write(p)
+	if f.flusher != nil {
+		f.flusher.Flush()
+	}
+	return n, err
+}
+
+// errResponseTooLarge is returned by a cappedReader once its limit is
+// exceeded
+var errResponseTooLarge = errors.New("upstream response exceeds the size limit")
+
+// cappedReader reads up to max bytes of r and fails with
+// errResponseTooLarge if r holds more
+type cappedReader struct {
+	r   io.Reader
+	max int64
+}
+
+func newCappedReader(r io.Reader, max int64) *cappedReader {
+	return &cappedReader{r: io.LimitReader(r, max+1), max: max}
+}
+
+func (c *cappedReader) Read(p []byte) (int, error) {
+	if c.max < 0 {
+		return 0, errResponseTooLarge
+	}
+	n, err := c.r.Read(p)
+	if c.max -= int64(n); c.max < 0 {
+		return n + int(c.max), errResponseTooLarge
+	}
+	return n, err
+}
+
+// sseEvent is a server-sent event, kept as its lines
+type sseEvent struct {
+	lines []string // Field lines other than data, e.g. event: and id:
+	name  string   // Value of the event field
+	data  []string // Values of the data fields
+}
+
+// detokenizer restores tokens in the text deltas of an event stream. A
+// token may be split across events, so text that could be the start of a
+// token is held back until the next delta shows whether it is one.
+type detokenizer struct {
+	tokens  *tokens
+	fields  [][]string        // Paths of the text deltas in event data
+	pending map[string]string // Held back text per delta field
+	last    *sseEvent         // Latest event with a text delta, to carry held text
+}
+
+func newDetokenizer(t *tokens, fields []string) *detokenizer {
+	d := &detokenizer{tokens: t, pending: make(map[string]string)}
+	for _, field := range fields {
+		d.fields = append(d.fields, strings.Split(field, "."))
+	}
+	return d
+}
+
+// streamEvents copies the server-sent events of body to w, flushing each
+// event. With d set, the tokens in text deltas are restored. onEvent, if
+// set, is given the data of each event as received.
+func streamEvents(w io.Writer, body io.Reader, d *detokenizer, onEvent func(data string)) error {
+	reader := bufio.NewReader(body)
+	event := &sseEvent{}
+	write := func() error {
+		if len(event.lines) == 0 && len(event.data) == 0 {
+			return nil
+		}
+		if onEvent != nil && len(event.data) > 0 {
+			onEvent(strings.Join(event.data, "\n"))
+		}
+		err := d.write(w, event)
+		event = &sseEvent{}
+		return err
+	}
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" || err == nil {
+			line = strings.TrimRight(line, "\r\n")
+			if line != "" {
+				event.add(line)
+			} else if werr := write(); werr != nil {
+				return werr
+			}
+		}
+		if err != nil {
+			if werr := write(); werr != nil {
+				return werr
+			}
+			if ferr := d.flush(w); ferr != nil {
+				return ferr
+			}
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+// add adds a field line to the event
+func (e *sseEvent) add(line string) {
+	if value, ok := strings.CutPrefix(line, "data:"); ok {
+		e.data = append(e.data, strings.TrimPrefix(value, " "))
+		return
+	}
+	if value, ok := strings.CutPrefix(line, "event:"); ok {
+		e.name = strings.TrimPrefix(value, " ")
+	}
+	e.lines = append(e.lines, line)
+}
+
+// encode returns the event in wire format with data as its data
+func (e *sseEvent) encode(data string) []byte {
+	var b bytes.Buffer
+	for _, line := range e.lines {
+		b.WriteString(line)
+		b.WriteByte('\n')
+	}
+	if len(e.data) > 0 || data != "" {
+		for _, line := range strings.Split(data, "\n") {
+			b.WriteString("data: ")
+			b.WriteString(line)
+			b.WriteByte('\n')
+		}
+	}
+	b.WriteByte('\n')
+	return b.Bytes()
+}
+
+// write writes an event, detokenized if d is set. Held back text is
+// written before events without text deltas, such as the end of a message.
+func (d *detokenizer) write(w io.Writer, e *sseEvent) error {
+	data := strings.Join(e.data, "\n")
+	if d == nil {
+		_, err := w.Write(e.encode(data))
+		return err
+	}
+
+	var body interface{}
+	decoder := json.NewDecoder(strings.NewReader(data))
+	decoder.UseNumber()
+	found := false
+	if decoder.Decode(&body) == nil {
+		for _, path := range d.fields {
+			body = mapStrings(body, path, "", func(key, text string) string {
+				found = true
+				return d.restore(key, text)
+			})
+		}
+	}
+	if !found {
+		if err := d.flush(w); err != nil {
+			return err
+		}
+		_, err := w.Write(e.encode(data))
+		return err
+	}
+
+	encoded, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	d.last = &sseEvent{lines: e.lines, name: e.name, data: []string{string(encoded)}}
+	_, err = w.Write(e.encode(string(encoded)))
+	return err
+}
+
+// restore returns the text of a delta with its tokens restored, holding
+// back a trailing part that may be the start of a token
+func (d *detokenizer) restore(key, text string) string {
+	text = d.pending[key] + text
+	hold := d.tokens.partialSuffix(text)
+	d.pending[key] = text[len(text)-hold:]
+	return d.tokens.restore(text[:len(text)-hold])
+}
+
+// flush writes the held back text as copies of the latest event with a
+// text delta
+func (d *detokenizer) flush(w io.Writer) error {
+	if d == nil || d.last == nil {
+		return nil
+	}
+	for key, text := range d.pending {
+		if text == "" {
+			continue
+		}
+		var body interface{}
+		decoder := json.NewDecoder(strings.NewReader(d.last.data[0]))
+		decoder.UseNumber()
+		if err := decoder.Decode(&body); err != nil {
+			return err
+		}
+		for _, path := range d.fields {
+			body = mapStrings(body, path, "", func(k, _ string) string {
+				if k == key {
+					return d.tokens.restore(text)
+				}
+				return ""
+			})
+		}
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(d.last.encode(string(encoded))); err != nil {
+			return err
+		}
+		delete(d.pending, key)
+	}
+	return nil
+}
+
+// mapStrings replaces each string at path below node with fn's result. fn
+// is given a key naming the string's position, e.g. choices.0.delta.content.
+func mapStrings(node interface{}, path []string, key string, fn func(key, text string) string) interface{} {
+	if len(path) == 0 {
+		if text, ok := node.(string); ok {
+			return fn(key, text)
+		}
+		return node
+	}
+	switch n := node.(type) {
+	case map[string]interface{}:
+		if child, ok := n[path[0]]; ok {
+			n[path[0]] = mapStrings(child, path[1:], key+"."+path[0], fn)
+		}
+	case []interface{}:
+		if path[0] == "*" {
+			for i, child := range n {
+				n[i] = mapStrings(child, path[1:], key+"."+strconv.Itoa(i), fn)
+			}
+		}
+	}
+	return node
+}
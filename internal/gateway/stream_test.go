@@ -0,0 +1,156 @@
+package gateway
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// newStreamUpstream answers with the server-sent events, flushing each and
+// waiting for next between them when it is set
+func newStreamUpstream(t *testing.T, events []string, next chan struct{}) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i, event := range events {
+			if i > 0 && next != nil {
+				<-next
+			}
+			io.WriteString(w, event+"\n\n")
+			w.(http.Flusher).Flush()
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// openAIChunk returns an OpenAI stream event with a content delta
+func openAIChunk(content string) string {
+	data, _ := json.Marshal(map[string]interface{}{
+		"choices": []interface{}{map[string]interface{}{"index": 0, "delta": map[string]string{"content": content}}},
+	})
+	return "data: " + string(data)
+}
+
+// deltas returns the concatenated text of the events in a stream
+func deltas(t *testing.T, stream, field string) string {
+	var text strings.Builder
+	for _, line := range strings.Split(stream, "\n") {
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok || data == "[DONE]" {
+			continue
+		}
+		var body interface{}
+		if err := json.Unmarshal([]byte(data), &body); err != nil {
+			t.Fatalf("Expected JSON events, got %q", data)
+		}
+		mapStrings(body, strings.Split(field, "."), "", func(_, s string) string {
+			text.WriteString(s)
+			return s
+		})
+	}
+	return text.String()
+}
+
+// TestStream_Flushed tests that events reach the client as the upstream
+// sends them
+func TestStream_Flushed(t *testing.T) {
+	next := make(chan struct{})
+	up := newStreamUpstream(t, []string{openAIChunk("Hel"), openAIChunk("lo"), "data: [DONE]"}, next)
+	g, _ := New(openAI(up.URL+"/v1", ""), testEngine(), nil)
+	server := httptest.NewServer(g.Handler())
+	defer server.Close()
+
+	resp, err := http.Post(server.URL+"/v1/chat/completions", "application/json", strings.NewReader(`{"stream":true,"messages":[]}`))
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Expected an event stream, got %q", ct)
+	}
+
+	// The first event must arrive while the upstream waits to send the next
+	reader := bufio.NewReader(resp.Body)
+	line, err := reader.ReadString('\n')
+	if err != nil || !strings.Contains(line, `"Hel"`) {
+		t.Fatalf("Expected the first event before the rest, got %q: %v", line, err)
+	}
+	close(next)
+	rest, _ := io.ReadAll(reader)
+	if !strings.Contains(string(rest), `"lo"`) || !strings.Contains(string(rest), "data: [DONE]") {
+		t.Errorf("Expected the remaining events, got %q", rest)
+	}
+}
+
+// TestStream_Detokenize tests that tokens split across events get their
+// values back, including one left open when the message ends
+func TestStream_Detokenize(t *testing.T) {
+	up := newStreamUpstream(t, []string{
+		openAIChunk("Mail [EM"),
+		openAIChunk("AIL_1] and [EMAIL_"),
+		openAIChunk("2], [x] ["),
+		openAIChunk("EMAIL_1"),
+		`data: {"choices":[{"index":0,"delta":{},"finish_reason":"stop"}]}`,
+		"data: [DONE]",
+	}, nil)
+	opts := openAI(up.URL+"/v1", "")
+	opts.Detokenize = true
+	g, _ := New(opts, testEngine(), nil)
+
+	w := post(t, g, `{"stream":true,"messages":[{"role":"user","content":"a@example.com b@example.com"}]}`)
+	got := deltas(t, w.Body.String(), "choices.*.delta.content")
+	if want := "Mail a@example.com and b@example.com, [x] [EMAIL_1"; got != want {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+	if strings.Contains(w.Body.String(), "[EMAIL_2]") {
+		t.Errorf("Expected no tokens left, got %s", w.Body.String())
+	}
+	if !strings.HasSuffix(w.Body.String(), `"finish_reason":"stop"}]}`+"\n\ndata: [DONE]\n\n") {
+		t.Errorf("Expected the end of the stream to be kept, got %s", w.Body.String())
+	}
+}
+
+// TestStream_Anthropic tests detokenizing the named events of an Anthropic
+// stream
+func TestStream_Anthropic(t *testing.T) {
+	up := newStreamUpstream(t, []string{
+		"event: message_start\ndata: {\"type\":\"message_start\"}",
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"Hi [EMA\"}}",
+		"event: content_block_delta\ndata: {\"type\":\"content_block_delta\",\"index\":0,\"delta\":{\"type\":\"text_delta\",\"text\":\"IL_1]!\"}}",
+		"event: content_block_stop\ndata: {\"type\":\"content_block_stop\",\"index\":0}",
+	}, nil)
+	opts := Options{Upstreams: map[string]Upstream{ProviderAnthropic: {URL: up.URL}}, Detokenize: true}
+	g, _ := New(opts, testEngine(), nil)
+
+	w := postTo(t, g, "/anthropic/v1/messages", `{"stream":true,"messages":[{"role":"user","content":"I am a@example.com"}]}`)
+	if got := deltas(t, w.Body.String(), "delta.text"); got != "Hi a@example.com!" {
+		t.Errorf("Expected detokenized text, got %q", got)
+	}
+	if strings.Count(w.Body.String(), "event: content_block_delta\n") != 2 || !strings.Contains(w.Body.String(), "event: content_block_stop\n") {
+		t.Errorf("Expected event names to be kept, got %s", w.Body.String())
+	}
+}
+
+// TestCappedReader tests that reading stops with errResponseTooLarge past
+// the limit, also while streaming events, and not at it
+func TestCappedReader(t *testing.T) {
+	data, err := io.ReadAll(newCappedReader(strings.NewReader("0123456789"), 10))
+	if err != nil || string(data) != "0123456789" {
+		t.Errorf("Expected a response at the limit to be read, got %q, %v", data, err)
+	}
+	data, err = io.ReadAll(newCappedReader(strings.NewReader("0123456789"), 9))
+	if err != errResponseTooLarge || len(data) > 9 {
+		t.Errorf("Expected errResponseTooLarge after at most 9 bytes, got %q, %v", data, err)
+	}
+
+	stream := openAIChunk(strings.Repeat("x", 100)) + "\n\n"
+	var out strings.Builder
+	if err := streamEvents(&out, newCappedReader(strings.NewReader(stream), 50), nil, nil); err != errResponseTooLarge {
+		t.Errorf("Expected a stream over the limit to fail, got %v", err)
+	}
+}
@@ -0,0 +1,91 @@
+// Package i18n translates the messages of the web API and the display
+// names of detection types. Messages are looked up by their English text,
+// so one without a translation is returned unchanged.
+package i18n
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Supported languages
+const (
+	English  = "en"
+	Chinese  = "zh" // Simplified Chinese
+	Japanese = "ja"
+	German   = "de"
+)
+
+// Languages lists the supported languages, English first
+var Languages = []string{English, Chinese, Japanese, German}
+
+// Supported reports whether lang is a supported language
+func Supported(lang string) bool {
+	for _, l := range Languages {
+		if l == lang {
+			return true
+		}
+	}
+	return false
+}
+
+// Negotiate returns the supported language an Accept-Language header
+// prefers, or English if it names none. Only the primary subtag of each
+// language range is compared, so "de-CH" selects German.
+func Negotiate(acceptLanguage string) string {
+	type weighted struct {
+		lang string
+		q    float64
+	}
+	var ranges []weighted
+	for _, part := range strings.Split(acceptLanguage, ",") {
+		tag, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		q := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			var err error
+			if q, err = strconv.ParseFloat(v, 64); err != nil {
+				continue
+			}
+		}
+		primary, _, _ := strings.Cut(strings.ToLower(strings.TrimSpace(tag)), "-")
+		if q > 0 && Supported(primary) {
+			ranges = append(ranges, weighted{primary, q})
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool { return ranges[i].q > ranges[j].q })
+	if len(ranges) == 0 {
+		return English
+	}
+	return ranges[0].lang
+}
+
+// Message returns msg, an English API message, translated into lang
+func Message(lang, msg string) string {
+	if t, ok := messages[lang][msg]; ok {
+		return t
+	}
+	return msg
+}
+
+// Label returns the display name of a detection type in lang. Types
+// without a name, such as those of custom string match patterns, are
+// returned unchanged.
+func Label(lang, typ string) string {
+	if name, ok := labels[lang][typ]; ok {
+		return name
+	}
+	if name, ok := labels[English][typ]; ok {
+		return name
+	}
+	return typ
+}
+
+// Labels returns the display names of the built-in detection types in lang
+func Labels(lang string) map[string]string {
+	names := make(map[string]string, len(labels[English]))
+	for typ := range labels[English] {
+		names[typ] = Label(lang, typ)
+	}
+	return names
+}
@@ -0,0 +1,84 @@
+package i18n
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestNegotiate tests that the supported language of highest quality in an
+// Accept-Language header is chosen, English by default
+func TestNegotiate(t *testing.T) {
+	tests := []struct {
+		header string
+		want   string
+	}{
+		{"", English},
+		{"fr-FR, it", English},
+		{"de-CH", German},
+		{"zh-CN,zh;q=0.9,en;q=0.8", Chinese},
+		{"en-US;q=0.5, ja;q=0.9", Japanese},
+		{"ja;q=0, de;q=0.1", German},
+		{"ja;q=abc, de", German},
+		{"fr;q=1, ZH-tw;q=0.7, en;q=0.7", Chinese},
+	}
+	for _, tt := range tests {
+		if got := Negotiate(tt.header); got != tt.want {
+			t.Errorf("Negotiate(%q) = %q, want %q", tt.header, got, tt.want)
+		}
+	}
+}
+
+// TestMessage tests that messages are translated, and returned as is in
+// English or when not in the catalog
+func TestMessage(t *testing.T) {
+	if got := Message(German, "Invalid JSON body"); got != "Ungültiger JSON-Anfragetext" {
+		t.Errorf("German message = %q", got)
+	}
+	if got := Message(English, "Invalid JSON body"); got != "Invalid JSON body" {
+		t.Errorf("English message = %q", got)
+	}
+	if got := Message(Japanese, "Not in the catalog"); got != "Not in the catalog" {
+		t.Errorf("untranslated message = %q", got)
+	}
+}
+
+// TestCatalogsAreComplete tests that every language translates every
+// message, with the same formatting verbs, and labels every detection type
+func TestCatalogsAreComplete(t *testing.T) {
+	for _, lang := range Languages[1:] {
+		for msg := range messages[Chinese] {
+			translated, ok := messages[lang][msg]
+			if !ok {
+				t.Errorf("%s lacks message %q", lang, msg)
+			}
+			if ok && strings.Count(translated, "%") != strings.Count(msg, "%") {
+				t.Errorf("%s translation of %q has different verbs", lang, msg)
+			}
+		}
+		if len(messages[lang]) != len(messages[Chinese]) {
+			t.Errorf("%s has %d messages, zh %d", lang, len(messages[lang]), len(messages[Chinese]))
+		}
+		for typ := range labels[English] {
+			if _, ok := labels[lang][typ]; !ok {
+				t.Errorf("%s lacks a label for %s", lang, typ)
+			}
+		}
+	}
+}
+
+// TestLabel tests that detection types are labelled in the language, in
+// English for other languages and as is for custom types
+func TestLabel(t *testing.T) {
+	if got := Label(Japanese, "credit_card"); got != "クレジットカード番号" {
+		t.Errorf("Japanese label = %q", got)
+	}
+	if got := Label("fr", "email"); got != "Email address" {
+		t.Errorf("label in an unsupported language = %q, want English", got)
+	}
+	if got := Label(German, "employee_id"); got != "employee_id" {
+		t.Errorf("custom type label = %q, want it unchanged", got)
+	}
+	if got := Labels(Chinese); got["ipv4"] != "IPv4 地址" || len(got) != len(labels[English]) {
+		t.Errorf("Labels(zh) = %v", got)
+	}
+}
@@ -0,0 +1,134 @@
+package i18n
+
+// labels are the display names of the built-in detection types by
+// language. English names every type; the other languages fall back to it.
+var labels = map[string]map[string]string{
+	English: {
+		"email":             "Email address",
+		"phone":             "Phone number",
+		"credit_card":       "Credit card number",
+		"ssn":               "National ID number",
+		"ipv4":              "IPv4 address",
+		"location":          "Location",
+		"terraform_secret":  "Terraform secret",
+		"http_auth":         "HTTP credential",
+		"cookie":            "Cookie",
+		"curl":              "curl credential",
+		"home_path":         "Home directory user",
+		"shell_prompt":      "Shell prompt",
+		"gcp_project":       "GCP project ID",
+		"azure_id":          "Azure subscription or tenant ID",
+		"license_key":       "License key",
+		"mac_address":       "MAC address",
+		"totp_secret":       "TOTP secret",
+		"recovery_codes":    "Recovery codes",
+		"password":          "Passwords",
+		"api_key":           "API key",
+		"prompt_injection":  "Prompt injection",
+		"role_override":     "Role override",
+		"data_exfiltration": "Data exfiltration",
+		"prompt_safety":     "Prompt safety",
+		"string_match":      "String match",
+		"merchant_id":       "Payment merchant ID",
+		"special_category":  "Special category data",
+		"health_data":       "Health data",
+		"religious_belief":  "Religious belief",
+		"union_membership":  "Trade union membership",
+	},
+	Chinese: {
+		"email":             "电子邮件地址",
+		"phone":             "电话号码",
+		"credit_card":       "信用卡号",
+		"ssn":               "身份证号码",
+		"ipv4":              "IPv4 地址",
+		"location":          "地理位置",
+		"terraform_secret":  "Terraform 密钥",
+		"http_auth":         "HTTP 凭据",
+		"cookie":            "Cookie",
+		"curl":              "curl 凭据",
+		"home_path":         "主目录用户名",
+		"shell_prompt":      "Shell 提示符",
+		"gcp_project":       "GCP 项目 ID",
+		"azure_id":          "Azure 订阅/租户 ID",
+		"license_key":       "许可证密钥",
+		"mac_address":       "MAC 地址",
+		"totp_secret":       "TOTP 密钥",
+		"recovery_codes":    "恢复码",
+		"password":          "密码",
+		"api_key":           "API 密钥",
+		"prompt_injection":  "提示注入",
+		"role_override":     "角色覆盖",
+		"data_exfiltration": "数据外泄",
+		"prompt_safety":     "提示安全",
+		"string_match":      "字符串匹配",
+		"merchant_id":       "商户号",
+		"special_category":  "特殊类别数据",
+		"health_data":       "健康数据",
+		"religious_belief":  "宗教信仰",
+		"union_membership":  "工会会员身份",
+	},
+	Japanese: {
+		"email":             "メールアドレス",
+		"phone":             "電話番号",
+		"credit_card":       "クレジットカード番号",
+		"ssn":               "国民識別番号",
+		"ipv4":              "IPv4 アドレス",
+		"location":          "位置情報",
+		"terraform_secret":  "Terraform のシークレット",
+		"http_auth":         "HTTP 認証情報",
+		"cookie":            "クッキー",
+		"curl":              "curl の認証情報",
+		"home_path":         "ホームディレクトリのユーザー名",
+		"shell_prompt":      "シェルプロンプト",
+		"gcp_project":       "GCP プロジェクト ID",
+		"azure_id":          "Azure サブスクリプション/テナント ID",
+		"license_key":       "ライセンスキー",
+		"mac_address":       "MAC アドレス",
+		"totp_secret":       "TOTP シークレット",
+		"recovery_codes":    "リカバリーコード",
+		"password":          "パスワード",
+		"api_key":           "API キー",
+		"prompt_injection":  "プロンプトインジェクション",
+		"role_override":     "ロールの上書き",
+		"data_exfiltration": "データ持ち出し",
+		"prompt_safety":     "プロンプトの安全性",
+		"string_match":      "文字列一致",
+		"merchant_id":       "加盟店 ID",
+		"special_category":  "特別な種類のデータ",
+		"health_data":       "健康データ",
+		"religious_belief":  "宗教的信条",
+		"union_membership":  "労働組合への加入",
+	},
+	German: {
+		"email":             "E-Mail-Adresse",
+		"phone":             "Telefonnummer",
+		"credit_card":       "Kreditkartennummer",
+		"ssn":               "Personenkennziffer",
+		"ipv4":              "IPv4-Adresse",
+		"location":          "Standort",
+		"terraform_secret":  "Terraform-Geheimnis",
+		"http_auth":         "HTTP-Zugangsdaten",
+		"cookie":            "Cookie",
+		"curl":              "curl-Zugangsdaten",
+		"home_path":         "Benutzername im Home-Verzeichnis",
+		"shell_prompt":      "Shell-Prompt",
+		"gcp_project":       "GCP-Projekt-ID",
+		"azure_id":          "Azure-Abonnement- oder Mandanten-ID",
+		"license_key":       "Lizenzschlüssel",
+		"mac_address":       "MAC-Adresse",
+		"totp_secret":       "TOTP-Geheimnis",
+		"recovery_codes":    "Wiederherstellungscodes",
+		"password":          "Passwörter",
+		"api_key":           "API-Schlüssel",
+		"prompt_injection":  "Prompt-Injection",
+		"role_override":     "Rollenüberschreibung",
+		"data_exfiltration": "Datenabfluss",
+		"prompt_safety":     "Prompt-Sicherheit",
+		"string_match":      "Zeichenkettentreffer",
+		"merchant_id":       "Händler-ID",
+		"special_category":  "Besondere Datenkategorie",
+		"health_data":       "Gesundheitsdaten",
+		"religious_belief":  "Religiöse Überzeugung",
+		"union_membership":  "Gewerkschaftszugehörigkeit",
+	},
+}
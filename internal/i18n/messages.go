@@ -0,0 +1,186 @@
+package i18n
+
+// messages are the translations of API messages by language, keyed by
+// their English text. Keys with verbs are formats filled in by the caller.
+var messages = map[string]map[string]string{
+	Chinese: {
+		"AdmissionReview without a request":                              "AdmissionReview 缺少 request",
+		"Authentication unavailable":                                     "身份验证不可用",
+		"Circuit breaker not running":                                    "熔断器未运行",
+		"Content is not UTF-8 text":                                      "内容不是 UTF-8 文本",
+		"Failed to build OpenAPI document":                               "生成 OpenAPI 文档失败",
+		"Failed to clear logs":                                           "清除日志失败",
+		"Failed to complete setup":                                       "完成初始设置失败",
+		"Failed to create admin token, the name may be taken":            "创建管理员令牌失败，名称可能已被占用",
+		"Failed to delete domain policy":                                 "删除域名策略失败",
+		"Failed to delete string match pattern":                          "删除字符串匹配规则失败",
+		"Failed to generate report":                                      "生成报告失败",
+		"Failed to import string match patterns":                         "导入字符串匹配规则失败",
+		"Failed to read body":                                            "读取请求正文失败",
+		"Failed to render report":                                        "渲染报告失败",
+		"Failed to restore clipboard content":                            "恢复剪贴板内容失败",
+		"Failed to retrieve audit entries":                               "获取审计记录失败",
+		"Failed to retrieve log":                                         "获取日志失败",
+		"Failed to retrieve logs":                                        "获取日志失败",
+		"Failed to retrieve review item":                                 "获取审核项失败",
+		"Failed to retrieve review items":                                "获取审核项失败",
+		"Failed to retrieve setup state":                                 "获取初始设置状态失败",
+		"Failed to retrieve statistics":                                  "获取统计信息失败",
+		"Failed to retrieve summary":                                     "获取摘要失败",
+		"Failed to retrieve usage report":                                "获取使用情况报告失败",
+		"Failed to save configuration":                                   "保存配置失败",
+		"Failed to save domain policy":                                   "保存域名策略失败",
+		"Failed to save review decision":                                 "保存审核决定失败",
+		"Failed to save string match pattern":                            "保存字符串匹配规则失败",
+		"Failed to update config":                                        "更新配置失败",
+		"Failed to update review item":                                   "更新审核项失败",
+		"Internal server error":                                          "服务器内部错误",
+		"Invalid JSON body":                                              "无效的 JSON 请求正文",
+		"Invalid base64 content":                                         "无效的 base64 内容",
+		"Invalid bearer token":                                           "无效的 Bearer 令牌",
+		"Invalid changes":                                                "无效的变更",
+		"Invalid dictionary":                                             "无效的词典",
+		"Invalid dictionary format":                                      "无效的词典格式",
+		"Invalid domain policy id":                                       "无效的域名策略 ID",
+		"Invalid log ID":                                                 "无效的日志 ID",
+		"Invalid pattern id":                                             "无效的规则 ID",
+		"Invalid report format":                                          "无效的报告格式",
+		"Invalid report period":                                          "无效的报告周期",
+		"Invalid review item ID":                                         "无效的审核项 ID",
+		"Invalid review status":                                          "无效的审核状态",
+		"Invalid until date, expected YYYY-MM-DD":                        "无效的 until 日期，应为 YYYY-MM-DD",
+		"Method not allowed":                                             "不允许的请求方法",
+		"Missing bearer token":                                           "缺少 Bearer 令牌",
+		"Monitor not running":                                            "监控未运行",
+		"Role %s is not allowed to perform this action":                  "角色 %s 无权执行此操作",
+		"Setup was already completed":                                    "初始设置已完成",
+		"The document has not been scanned; send its text":               "该文档尚未扫描，请发送其文本",
+		"The document is not cached at base_version; send its full text": "该文档在 base_version 处没有缓存，请发送完整文本",
+		"The server is read-only":                                        "服务器为只读模式",
+		"Unknown API endpoint":                                           "未知的 API 端点",
+		"Unknown encoding, expected base64":                              "未知的编码，应为 base64",
+		"Validation failed":                                              "验证失败",
+		"limit must be between 0 and %d":                                 "limit 必须介于 0 和 %d 之间",
+		"uri is required":                                                "必须提供 uri",
+	},
+	Japanese: {
+		"AdmissionReview without a request":                              "AdmissionReview に request がありません",
+		"Authentication unavailable":                                     "認証を利用できません",
+		"Circuit breaker not running":                                    "サーキットブレーカーが動作していません",
+		"Content is not UTF-8 text":                                      "内容が UTF-8 テキストではありません",
+		"Failed to build OpenAPI document":                               "OpenAPI ドキュメントの生成に失敗しました",
+		"Failed to clear logs":                                           "ログの消去に失敗しました",
+		"Failed to complete setup":                                       "初期設定の完了に失敗しました",
+		"Failed to create admin token, the name may be taken":            "管理者トークンの作成に失敗しました。名前が使用済みの可能性があります",
+		"Failed to delete domain policy":                                 "ドメインポリシーの削除に失敗しました",
+		"Failed to delete string match pattern":                          "文字列一致パターンの削除に失敗しました",
+		"Failed to generate report":                                      "レポートの生成に失敗しました",
+		"Failed to import string match patterns":                         "文字列一致パターンのインポートに失敗しました",
+		"Failed to read body":                                            "リクエスト本文の読み取りに失敗しました",
+		"Failed to render report":                                        "レポートの描画に失敗しました",
+		"Failed to restore clipboard content":                            "クリップボードの内容の復元に失敗しました",
+		"Failed to retrieve audit entries":                               "監査記録の取得に失敗しました",
+		"Failed to retrieve log":                                         "ログの取得に失敗しました",
+		"Failed to retrieve logs":                                        "ログの取得に失敗しました",
+		"Failed to retrieve review item":                                 "レビュー項目の取得に失敗しました",
+		"Failed to retrieve review items":                                "レビュー項目の取得に失敗しました",
+		"Failed to retrieve setup state":                                 "初期設定の状態の取得に失敗しました",
+		"Failed to retrieve statistics":                                  "統計の取得に失敗しました",
+		"Failed to retrieve summary":                                     "概要の取得に失敗しました",
+		"Failed to retrieve usage report":                                "利用状況レポートの取得に失敗しました",
+		"Failed to save configuration":                                   "設定の保存に失敗しました",
+		"Failed to save domain policy":                                   "ドメインポリシーの保存に失敗しました",
+		"Failed to save review decision":                                 "レビューの判定の保存に失敗しました",
+		"Failed to save string match pattern":                            "文字列一致パターンの保存に失敗しました",
+		"Failed to update config":                                        "設定の更新に失敗しました",
+		"Failed to update review item":                                   "レビュー項目の更新に失敗しました",
+		"Internal server error":                                          "サーバー内部エラー",
+		"Invalid JSON body":                                              "JSON 本文が無効です",
+		"Invalid base64 content":                                         "base64 の内容が無効です",
+		"Invalid bearer token":                                           "Bearer トークンが無効です",
+		"Invalid changes":                                                "変更内容が無効です",
+		"Invalid dictionary":                                             "辞書が無効です",
+		"Invalid dictionary format":                                      "辞書の形式が無効です",
+		"Invalid domain policy id":                                       "ドメインポリシー ID が無効です",
+		"Invalid log ID":                                                 "ログ ID が無効です",
+		"Invalid pattern id":                                             "パターン ID が無効です",
+		"Invalid report format":                                          "レポート形式が無効です",
+		"Invalid report period":                                          "レポート期間が無効です",
+		"Invalid review item ID":                                         "レビュー項目 ID が無効です",
+		"Invalid review status":                                          "レビューの状態が無効です",
+		"Invalid until date, expected YYYY-MM-DD":                        "until の日付が無効です。YYYY-MM-DD で指定してください",
+		"Method not allowed":                                             "許可されていないメソッドです",
+		"Missing bearer token":                                           "Bearer トークンがありません",
+		"Monitor not running":                                            "監視が動作していません",
+		"Role %s is not allowed to perform this action":                  "ロール %s にはこの操作の権限がありません",
+		"Setup was already completed":                                    "初期設定はすでに完了しています",
+		"The document has not been scanned; send its text":               "このドキュメントは未スキャンです。テキストを送信してください",
+		"The document is not cached at base_version; send its full text": "このドキュメントは base_version でキャッシュされていません。全文を送信してください",
+		"The server is read-only":                                        "サーバーは読み取り専用です",
+		"Unknown API endpoint":                                           "不明な API エンドポイントです",
+		"Unknown encoding, expected base64":                              "不明なエンコーディングです。base64 を指定してください",
+		"Validation failed":                                              "検証に失敗しました",
+		"limit must be between 0 and %d":                                 "limit は 0 から %d の間で指定してください",
+		"uri is required":                                                "uri は必須です",
+	},
+	German: {
+		"AdmissionReview without a request":                              "AdmissionReview ohne request",
+		"Authentication unavailable":                                     "Authentifizierung nicht verfügbar",
+		"Circuit breaker not running":                                    "Schutzschalter läuft nicht",
+		"Content is not UTF-8 text":                                      "Inhalt ist kein UTF-8-Text",
+		"Failed to build OpenAPI document":                               "OpenAPI-Dokument konnte nicht erstellt werden",
+		"Failed to clear logs":                                           "Protokolle konnten nicht gelöscht werden",
+		"Failed to complete setup":                                       "Einrichtung konnte nicht abgeschlossen werden",
+		"Failed to create admin token, the name may be taken":            "Admin-Token konnte nicht erstellt werden, der Name ist möglicherweise vergeben",
+		"Failed to delete domain policy":                                 "Domain-Richtlinie konnte nicht gelöscht werden",
+		"Failed to delete string match pattern":                          "Zeichenkettenmuster konnte nicht gelöscht werden",
+		"Failed to generate report":                                      "Bericht konnte nicht erstellt werden",
+		"Failed to import string match patterns":                         "Zeichenkettenmuster konnten nicht importiert werden",
+		"Failed to read body":                                            "Anfragetext konnte nicht gelesen werden",
+		"Failed to render report":                                        "Bericht konnte nicht dargestellt werden",
+		"Failed to restore clipboard content":                            "Inhalt der Zwischenablage konnte nicht wiederhergestellt werden",
+		"Failed to retrieve audit entries":                               "Audit-Einträge konnten nicht abgerufen werden",
+		"Failed to retrieve log":                                         "Protokoll konnte nicht abgerufen werden",
+		"Failed to retrieve logs":                                        "Protokolle konnten nicht abgerufen werden",
+		"Failed to retrieve review item":                                 "Prüfeintrag konnte nicht abgerufen werden",
+		"Failed to retrieve review items":                                "Prüfeinträge konnten nicht abgerufen werden",
+		"Failed to retrieve setup state":                                 "Einrichtungsstatus konnte nicht abgerufen werden",
+		"Failed to retrieve statistics":                                  "Statistiken konnten nicht abgerufen werden",
+		"Failed to retrieve summary":                                     "Zusammenfassung konnte nicht abgerufen werden",
+		"Failed to retrieve usage report":                                "Nutzungsbericht konnte nicht abgerufen werden",
+		"Failed to save configuration":                                   "Konfiguration konnte nicht gespeichert werden",
+		"Failed to save domain policy":                                   "Domain-Richtlinie konnte nicht gespeichert werden",
+		"Failed to save review decision":                                 "Prüfentscheidung konnte nicht gespeichert werden",
+		"Failed to save string match pattern":                            "Zeichenkettenmuster konnte nicht gespeichert werden",
+		"Failed to update config":                                        "Konfiguration konnte nicht aktualisiert werden",
+		"Failed to update review item":                                   "Prüfeintrag konnte nicht aktualisiert werden",
+		"Internal server error":                                          "Interner Serverfehler",
+		"Invalid JSON body":                                              "Ungültiger JSON-Anfragetext",
+		"Invalid base64 content":                                         "Ungültiger base64-Inhalt",
+		"Invalid bearer token":                                           "Ungültiges Bearer-Token",
+		"Invalid changes":                                                "Ungültige Änderungen",
+		"Invalid dictionary":                                             "Ungültiges Wörterbuch",
+		"Invalid dictionary format":                                      "Ungültiges Wörterbuchformat",
+		"Invalid domain policy id":                                       "Ungültige Domain-Richtlinien-ID",
+		"Invalid log ID":                                                 "Ungültige Protokoll-ID",
+		"Invalid pattern id":                                             "Ungültige Muster-ID",
+		"Invalid report format":                                          "Ungültiges Berichtsformat",
+		"Invalid report period":                                          "Ungültiger Berichtszeitraum",
+		"Invalid review item ID":                                         "Ungültige Prüfeintrags-ID",
+		"Invalid review status":                                          "Ungültiger Prüfstatus",
+		"Invalid until date, expected YYYY-MM-DD":                        "Ungültiges until-Datum, erwartet wird JJJJ-MM-TT",
+		"Method not allowed":                                             "Methode nicht erlaubt",
+		"Missing bearer token":                                           "Bearer-Token fehlt",
+		"Monitor not running":                                            "Überwachung läuft nicht",
+		"Role %s is not allowed to perform this action":                  "Rolle %s darf diese Aktion nicht ausführen",
+		"Setup was already completed":                                    "Die Einrichtung wurde bereits abgeschlossen",
+		"The document has not been scanned; send its text":               "Das Dokument wurde nicht gescannt; senden Sie seinen Text",
+		"The document is not cached at base_version; send its full text": "Das Dokument ist zu base_version nicht zwischengespeichert; senden Sie den vollständigen Text",
+		"The server is read-only":                                        "Der Server ist schreibgeschützt",
+		"Unknown API endpoint":                                           "Unbekannter API-Endpunkt",
+		"Unknown encoding, expected base64":                              "Unbekannte Kodierung, erwartet wird base64",
+		"Validation failed":                                              "Validierung fehlgeschlagen",
+		"limit must be between 0 and %d":                                 "limit muss zwischen 0 und %d liegen",
+		"uri is required":                                                "uri ist erforderlich",
+	},
+}
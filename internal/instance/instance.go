@@ -0,0 +1,193 @@
+// Package instance keeps a single copy of the daemon running. Two copies
+// would fight over the clipboard, each rewriting what the other wrote. The
+// running copy holds a lock file naming its process and web UI address;
+// another copy finds it there and can open its UI instead, or take over.
+package instance
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"syscall"
+	"time"
+)
+
+// LockFile is the name of the lock file in the data directory
+const LockFile = "instance.lock"
+
+// uiTitle identifies the web UI of an instance
+const uiTitle = "<title>Prompt Security"
+
+const (
+	// startupGrace is how long a new lock counts as held while its
+	// instance starts its web UI
+	startupGrace = 5 * time.Second
+
+	// probeTimeout bounds checking whether an instance's web UI responds
+	probeTimeout = time.Second
+
+	// takeoverTimeout is how long a takeover waits for the running
+	// instance to exit
+	takeoverTimeout = 10 * time.Second
+)
+
+// Info identifies a running instance
+type Info struct {
+	PID  int    `json:"pid"`
+	Addr string `json:"addr"` // Web UI address, host:port
+}
+
+// URL returns the address of the instance's web UI
+func (i Info) URL() string {
+	return "http://" + i.Addr
+}
+
+// RunningError is returned when another instance holds the lock
+type RunningError struct {
+	Info Info
+}
+
+func (e *RunningError) Error() string {
+	return fmt.Sprintf("another instance is already running (pid %d) with its web UI at %s", e.Info.PID, e.Info.URL())
+}
+
+// Lock is the lock held by the running instance
+type Lock struct {
+	path string
+	pid  int
+}
+
+// Acquire takes the lock in dir for the instance described by info. If
+// another live instance holds it, Acquire returns a *RunningError, or with
+// takeover stops that instance and takes the lock. Locks left behind by
+// instances that exited without releasing them are replaced.
+func Acquire(dir string, info Info, takeover bool) (*Lock, error) {
+	path := filepath.Join(dir, LockFile)
+	data, err := json.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+
+	for attempt := 0; attempt < 3; attempt++ {
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			_, err = f.Write(data)
+			if closeErr := f.Close(); err == nil {
+				err = closeErr
+			}
+			if err != nil {
+				os.Remove(path)
+				return nil, fmt.Errorf("failed to write lock file: %v", err)
+			}
+			return &Lock{path: path, pid: info.PID}, nil
+		}
+		if !errors.Is(err, os.ErrExist) {
+			return nil, fmt.Errorf("failed to create lock file: %v", err)
+		}
+
+		other, held := holder(path)
+		if held {
+			if !takeover {
+				return nil, &RunningError{Info: other}
+			}
+			if err := stop(other); err != nil {
+				return nil, fmt.Errorf("failed to take over from pid %d: %v", other.PID, err)
+			}
+		}
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return nil, fmt.Errorf("failed to remove stale lock file: %v", err)
+		}
+	}
+	return nil, fmt.Errorf("failed to acquire lock file %s", path)
+}
+
+// Release removes the lock file if it is still this instance's
+func (l *Lock) Release() error {
+	var info Info
+	data, err := os.ReadFile(l.path)
+	if err != nil {
+		return nil // Already removed
+	}
+	if json.Unmarshal(data, &info) == nil && info.PID != l.pid {
+		return nil // Taken over
+	}
+	return os.Remove(l.path)
+}
+
+// holder reads the lock file and reports whether its instance is alive: its
+// web UI responds, or the lock is too new for the UI to have started
+func holder(path string) (Info, bool) {
+	var info Info
+	stat, err := os.Stat(path)
+	if err != nil {
+		return info, false
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || json.Unmarshal(data, &info) != nil {
+		// Being written, or corrupt once the grace period is over
+		return info, time.Since(stat.ModTime()) < startupGrace
+	}
+	return info, responds(info) || time.Since(stat.ModTime()) < startupGrace
+}
+
+// responds reports whether an instance's web UI answers. The page is
+// checked so that another program since bound to the port does not count.
+func responds(info Info) bool {
+	if info.Addr == "" {
+		return false
+	}
+	client := http.Client{Timeout: probeTimeout}
+	resp, err := client.Get(info.URL() + "/")
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+	page, err := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	return err == nil && bytes.Contains(page, []byte(uiTitle))
+}
+
+// stop asks a running instance to exit and waits until its web UI is gone
+func stop(info Info) error {
+	p, err := os.FindProcess(info.PID)
+	if err != nil {
+		return err
+	}
+	if runtime.GOOS == "windows" {
+		err = p.Kill() // Windows has no termination signal
+	} else {
+		err = p.Signal(syscall.SIGTERM)
+	}
+	if err != nil && !errors.Is(err, os.ErrProcessDone) {
+		return err
+	}
+
+	deadline := time.Now().Add(takeoverTimeout)
+	for responds(info) {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("instance did not exit within %v", takeoverTimeout)
+		}
+		time.Sleep(100 * time.Millisecond)
+	}
+	return nil
+}
+
+// OpenBrowser opens url in the default browser
+func OpenBrowser(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	case "darwin":
+		cmd = exec.Command("open", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	return cmd.Start()
+}
@@ -0,0 +1,70 @@
+package instance
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestAcquire tests that a live instance keeps the lock and a stale lock is
+// replaced
+func TestAcquire(t *testing.T) {
+	ui := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html><head>" + uiTitle + " - Configuration & Logs</title>"))
+	}))
+	defer ui.Close()
+	running := Info{PID: 1, Addr: strings.TrimPrefix(ui.URL, "http://")}
+
+	dir := t.TempDir()
+	lock, err := Acquire(dir, running, false)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	_, err = Acquire(dir, Info{PID: 2, Addr: "localhost:1"}, false)
+	var runningErr *RunningError
+	if !errors.As(err, &runningErr) || runningErr.Info != running {
+		t.Fatalf("Expected the running instance %+v, got %v", running, err)
+	}
+
+	// Released locks and locks of instances that are gone are taken
+	if err := lock.Release(); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := Acquire(dir, Info{PID: 2, Addr: "localhost:1"}, false); err != nil {
+		t.Fatalf("Expected a released lock to be taken, got %v", err)
+	}
+	old := time.Now().Add(-2 * startupGrace)
+	os.Chtimes(filepath.Join(dir, LockFile), old, old)
+	lock, err = Acquire(dir, Info{PID: 3, Addr: "localhost:1"}, false)
+	if err != nil {
+		t.Fatalf("Expected a stale lock to be replaced, got %v", err)
+	}
+
+	// A lock taken over by another instance is not released
+	os.WriteFile(filepath.Join(dir, LockFile), []byte(`{"pid": 4, "addr": "localhost:1"}`), 0644)
+	lock.Release()
+	if _, err := os.Stat(filepath.Join(dir, LockFile)); err != nil {
+		t.Errorf("Expected another instance's lock to be kept, got %v", err)
+	}
+}
+
+// TestResponds tests that only the web UI of an instance counts as running
+func TestResponds(t *testing.T) {
+	other := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("<html><head><title>Something else</title>"))
+	}))
+	defer other.Close()
+
+	if responds(Info{Addr: strings.TrimPrefix(other.URL, "http://")}) {
+		t.Error("Expected another program on the port not to count")
+	}
+	if responds(Info{}) {
+		t.Error("Expected an instance without an address not to count")
+	}
+}
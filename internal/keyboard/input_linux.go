@@ -0,0 +1,174 @@
+package keyboard
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+)
+
+// inputEvent is struct input_event from linux/input.h
+type inputEvent struct {
+	Time  syscall.Timeval
+	Type  uint16
+	Code  uint16
+	Value int32
+}
+
+// evKey is the event type of key presses; values are 0 for release, 1 for
+// press and 2 for autorepeat
+const evKey = 1
+
+// evRep is the event type bit set by devices with key autorepeat, which
+// tells keyboards from other devices with keys
+const evRep = 0x14
+
+// evdevSource reads key presses from the keyboards in /dev/input. Reading
+// them requires root or membership in the input group.
+type evdevSource struct {
+	files  []*os.File
+	events chan inputEvent
+	errs   chan error
+	closed chan struct{}
+	once   sync.Once
+	mods   modifiers
+}
+
+// openKeyboard opens every keyboard device
+func openKeyboard() (keySource, error) {
+	paths, err := keyboardDevices()
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, errors.New("no keyboard found in /proc/bus/input/devices")
+	}
+
+	s := &evdevSource{
+		events: make(chan inputEvent, 64),
+		errs:   make(chan error, len(paths)),
+		closed: make(chan struct{}),
+	}
+	for _, path := range paths {
+		f, err := os.Open(path)
+		if err != nil {
+			s.Close()
+			if errors.Is(err, os.ErrPermission) {
+				return nil, fmt.Errorf("cannot read %s: add your user to the input group and log in again", path)
+			}
+			return nil, err
+		}
+		s.files = append(s.files, f)
+	}
+	for _, f := range s.files {
+		go s.read(f)
+	}
+	return s, nil
+}
+
+// read forwards the key events of one device
+func (s *evdevSource) read(f *os.File) {
+	r := bufio.NewReader(f)
+	for {
+		var ev inputEvent
+		if err := binary.Read(r, binary.NativeEndian, &ev); err != nil {
+			s.errs <- err
+			return
+		}
+		if ev.Type != evKey {
+			continue
+		}
+		select {
+		case s.events <- ev:
+		case <-s.closed:
+			return
+		}
+	}
+}
+
+func (s *evdevSource) Next() (Key, error) {
+	for {
+		select {
+		case ev := <-s.events:
+			if key, ok := s.mods.translate(ev.Code, ev.Value); ok {
+				return key, nil
+			}
+		case err := <-s.errs:
+			select {
+			case <-s.closed:
+				return Key{}, io.EOF
+			default:
+				return Key{}, err
+			}
+		case <-s.closed:
+			return Key{}, io.EOF
+		}
+	}
+}
+
+func (s *evdevSource) Close() error {
+	s.once.Do(func() {
+		close(s.closed)
+		for _, f := range s.files {
+			f.Close()
+		}
+	})
+	return nil
+}
+
+// keyboardDevices lists the event devices of keyboards
+func keyboardDevices() ([]string, error) {
+	f, err := os.Open("/proc/bus/input/devices")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return parseDevices(f)
+}
+
+// parseDevices reads /proc/bus/input/devices, where each device is a block
+// of lines such as "H: Handlers=sysrq kbd event3 leds" and "B: EV=120013"
+func parseDevices(r io.Reader) ([]string, error) {
+	var paths []string
+	var event string
+	var kbd, rep bool
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "H: Handlers="):
+			for _, h := range strings.Fields(strings.TrimPrefix(line, "H: Handlers=")) {
+				if h == "kbd" {
+					kbd = true
+				} else if strings.HasPrefix(h, "event") {
+					event = h
+				}
+			}
+		case strings.HasPrefix(line, "B: EV="):
+			bits, err := strconv.ParseUint(strings.TrimPrefix(line, "B: EV="), 16, 64)
+			rep = err == nil && bits&(1<<evRep) != 0
+		case line == "":
+			if kbd && rep && event != "" {
+				paths = append(paths, "/dev/input/"+event)
+			}
+			event, kbd, rep = "", false, false
+		}
+	}
+	if kbd && rep && event != "" {
+		paths = append(paths, "/dev/input/"+event)
+	}
+	return paths, scanner.Err()
+}
+
+// notify shows a desktop notification
+func notify(message string) error {
+	return exec.Command("notify-send", "--urgency=critical", "Prompt Security", message).Run()
+}
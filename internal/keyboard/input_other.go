@@ -0,0 +1,13 @@
+//go:build !linux
+
+package keyboard
+
+// openKeyboard reports ErrUnsupported: key presses are only read on Linux
+func openKeyboard() (keySource, error) {
+	return nil, ErrUnsupported
+}
+
+// notify reports ErrUnsupported; warnings are only logged
+func notify(message string) error {
+	return ErrUnsupported
+}
@@ -0,0 +1,253 @@
+// Package keyboard warns when sensitive data is typed into AI apps. Where
+// the operating system allows it, key presses are read while a configured
+// app has focus, buffered, and checked with the detectors; a match raises a
+// desktop notification. Typed text is only held in memory and never logged.
+package keyboard
+
+import (
+	"errors"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+	"github.com/happytaoer/prompt-security/internal/desktop"
+	"github.com/happytaoer/prompt-security/internal/filter"
+)
+
+// ErrUnsupported is returned where key presses cannot be read
+var ErrUnsupported = errors.New("keyboard protection is not supported on this system")
+
+const (
+	// pollInterval is how often Run applies configuration changes
+	pollInterval = time.Second
+
+	// focusInterval is how long the focused window title is cached
+	focusInterval = 500 * time.Millisecond
+
+	// maxBuffer is the number of typed characters kept for detection
+	maxBuffer = 1024
+)
+
+// Non-character keys
+const (
+	KeyRune      = iota // A typed character
+	KeyBackspace        // Deletes the last character
+	KeyEnter            // Sends the prompt in most chat apps
+)
+
+// Key is a key press
+type Key struct {
+	Code int  // KeyRune or a special key
+	Rune rune // Character typed for KeyRune
+}
+
+// keySource delivers key presses from the operating system
+type keySource interface {
+	Next() (Key, error) // Blocks until a key is pressed
+	Close() error
+}
+
+// Status reports the state of keyboard protection
+type Status struct {
+	Enabled bool   // Enabled in the configuration
+	Active  bool   // Key presses are being read
+	Error   string // Why protection is not working, e.g. missing permissions
+}
+
+// Guard reads key presses and warns about typed sensitive data
+type Guard struct {
+	manager *config.Manager
+	engine  *filter.Engine
+	logger  *slog.Logger
+
+	// Platform hooks, replaced in tests
+	open   func() (keySource, error)
+	focus  func() (string, error) // Title of the focused window
+	notify func(message string) error
+
+	mu     sync.Mutex
+	status Status
+}
+
+// New creates a keyboard guard using the detectors of engine
+func New(manager *config.Manager, engine *filter.Engine) *Guard {
+	return &Guard{
+		manager: manager,
+		engine:  engine,
+		logger:  slog.New(slog.NewJSONHandler(os.Stdout, nil)),
+		open:    openKeyboard,
+		focus:   desktop.FocusedWindow,
+		notify:  notify,
+	}
+}
+
+// Status returns the current state of keyboard protection
+func (g *Guard) Status() Status {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	return g.status
+}
+
+// update changes the status, logging new errors
+func (g *Guard) update(fn func(*Status)) {
+	g.mu.Lock()
+	previous := g.status.Error
+	fn(&g.status)
+	current := g.status.Error
+	g.mu.Unlock()
+
+	if current != "" && current != previous {
+		g.logger.Warn("Keyboard protection unavailable", "error", current)
+	}
+}
+
+// capture is a running key capture
+type capture struct {
+	source keySource
+	done   chan struct{}
+}
+
+// Run starts and stops key capture as the configuration changes (blocking)
+func (g *Guard) Run() {
+	var c *capture
+	for {
+		// Consent is checked here too, for configurations not validated
+		cfg := g.manager.Get()
+		enabled := cfg.KeyboardProtection && cfg.KeyboardConsent
+		g.update(func(s *Status) { s.Enabled = enabled })
+
+		if c != nil {
+			select {
+			case <-c.done:
+				c = nil // Failed; retried below
+			default:
+			}
+		}
+
+		switch {
+		case enabled && c == nil:
+			c = g.start()
+		case !enabled && c != nil:
+			c.source.Close()
+			<-c.done
+			c = nil
+			g.update(func(s *Status) { *s = Status{} })
+		}
+		time.Sleep(pollInterval)
+	}
+}
+
+// start opens the keyboard and captures key presses in the background
+func (g *Guard) start() *capture {
+	source, err := g.open()
+	if err != nil {
+		g.update(func(s *Status) { s.Active, s.Error = false, err.Error() })
+		return nil
+	}
+
+	g.update(func(s *Status) { s.Active, s.Error = true, "" })
+	g.logger.Info("Keyboard protection started")
+
+	c := &capture{source: source, done: make(chan struct{})}
+	go func() {
+		defer close(c.done)
+		err := g.watch(source)
+		g.update(func(s *Status) {
+			s.Active = false
+			if err != nil && s.Enabled {
+				s.Error = err.Error()
+			}
+		})
+	}()
+	return c
+}
+
+// watch checks the text typed into configured apps until source fails
+func (g *Guard) watch(source keySource) error {
+	var (
+		buf       []rune
+		warned    int // Matches in buf already notified
+		title     string
+		checked   time.Time
+		focusErr  error
+		lastTitle string
+	)
+	for {
+		key, err := source.Next()
+		if err != nil {
+			return err
+		}
+
+		cfg := g.manager.Get()
+		if time.Since(checked) > focusInterval {
+			title, focusErr = g.focus()
+			checked = time.Now()
+			g.update(func(s *Status) {
+				s.Error = ""
+				if focusErr != nil && len(cfg.KeyboardApps) > 0 {
+					s.Error = "cannot tell the focused window: " + focusErr.Error()
+				}
+			})
+		}
+
+		// Text typed into another window starts a new prompt
+		if title != lastTitle || !matchesApp(title, cfg.KeyboardApps) {
+			buf, warned, lastTitle = buf[:0], 0, title
+			if !matchesApp(title, cfg.KeyboardApps) {
+				continue
+			}
+		}
+
+		switch key.Code {
+		case KeyEnter:
+			buf, warned = buf[:0], 0
+			continue
+		case KeyBackspace:
+			if len(buf) > 0 {
+				buf = buf[:len(buf)-1]
+			}
+			continue
+		}
+		buf = append(buf, key.Rune)
+		if len(buf) > maxBuffer {
+			buf = append(buf[:0], buf[len(buf)-maxBuffer:]...)
+		}
+
+		types, count := g.detect(string(buf))
+		if count > warned {
+			g.warn(title, types)
+		}
+		warned = count
+	}
+}
+
+// detect returns the sorted types of sensitive data in text and the number
+// of matches
+func (g *Guard) detect(text string) ([]string, int) {
+	_, _, summary := g.engine.Detectors().Filter(text)
+	return summary.Types(), len(summary.Replacements)
+}
+
+// warn notifies the user about sensitive data typed into the app
+func (g *Guard) warn(app string, types []string) {
+	g.logger.Warn("Sensitive data typed into an AI app", "app", app, "types", types)
+	message := "Sensitive data typed (" + strings.Join(types, ", ") + "). Remove it before sending."
+	if err := g.notify(message); err != nil && !errors.Is(err, ErrUnsupported) {
+		g.logger.Error("Failed to show notification", "error", err)
+	}
+}
+
+// matchesApp reports whether the window title belongs to one of apps,
+// matched case-insensitively; an empty list matches no window
+func matchesApp(title string, apps []string) bool {
+	title = strings.ToLower(title)
+	for _, app := range apps {
+		if app = strings.TrimSpace(app); app != "" && strings.Contains(title, strings.ToLower(app)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,110 @@
+package keyboard
+
+import (
+	"io"
+	"log/slog"
+	"testing"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+	"github.com/happytaoer/prompt-security/internal/filter"
+)
+
+// fakeSource replays key presses, then reports io.EOF
+type fakeSource struct {
+	keys []Key
+}
+
+func (s *fakeSource) Next() (Key, error) {
+	if len(s.keys) == 0 {
+		return Key{}, io.EOF
+	}
+	key := s.keys[0]
+	s.keys = s.keys[1:]
+	return key, nil
+}
+
+func (s *fakeSource) Close() error { return nil }
+
+// typed returns the key presses typing text, with "\n" as Enter and "\b" as
+// Backspace
+func typed(text string) []Key {
+	var keys []Key
+	for _, r := range text {
+		switch r {
+		case '\n':
+			keys = append(keys, Key{Code: KeyEnter})
+		case '\b':
+			keys = append(keys, Key{Code: KeyBackspace})
+		default:
+			keys = append(keys, Key{Code: KeyRune, Rune: r})
+		}
+	}
+	return keys
+}
+
+// newTestGuard returns a guard with the given focused window that records
+// its notifications
+func newTestGuard(window string) (*Guard, *[]string) {
+	cfg := config.Config{
+		DetectEmails:     true,
+		EmailReplacement: "[EMAIL]",
+		KeyboardApps:     []string{"ChatGPT", "Claude"},
+	}
+	manager := config.NewStaticManager(cfg)
+	var notes []string
+	g := New(manager, filter.NewEngine(manager.Effective()))
+	g.logger = slog.New(slog.NewJSONHandler(io.Discard, nil))
+	g.focus = func() (string, error) { return window, nil }
+	g.notify = func(message string) error {
+		notes = append(notes, message)
+		return nil
+	}
+	return g, &notes
+}
+
+// TestGuard_WarnsOncePerMatch tests that typing sensitive data into an AI app
+// notifies once per match, and again after Enter starts a new prompt
+func TestGuard_WarnsOncePerMatch(t *testing.T) {
+	g, notes := newTestGuard("New chat - ChatGPT - Firefox")
+
+	source := &fakeSource{keys: typed("mail al\bice@example.com please\nand bob@example.org\n")}
+	if err := g.watch(source); err != io.EOF {
+		t.Fatalf("Expected io.EOF, got %v", err)
+	}
+
+	if len(*notes) != 2 {
+		t.Fatalf("Expected 2 notifications, got %q", *notes)
+	}
+	if want := "Sensitive data typed (email). Remove it before sending."; (*notes)[0] != want {
+		t.Errorf("Expected %q, got %q", want, (*notes)[0])
+	}
+}
+
+// TestGuard_OtherApps tests that text typed into other windows is ignored
+func TestGuard_OtherApps(t *testing.T) {
+	g, notes := newTestGuard("Terminal")
+
+	g.watch(&fakeSource{keys: typed("alice@example.com")})
+	if len(*notes) != 0 {
+		t.Errorf("Expected no notification, got %q", *notes)
+	}
+}
+
+// TestMatchesApp tests window title matching
+func TestMatchesApp(t *testing.T) {
+	tests := []struct {
+		title string
+		apps  []string
+		want  bool
+	}{
+		{"Claude - Google Chrome", []string{"ChatGPT", "claude"}, true},
+		{"Inbox - Mail", []string{"ChatGPT", "Claude"}, false},
+		{"anything", nil, false},
+		{"", []string{" "}, false},
+	}
+	for _, tt := range tests {
+		if got := matchesApp(tt.title, tt.apps); got != tt.want {
+			t.Errorf("matchesApp(%q, %q) = %v, expected %v", tt.title, tt.apps, got, tt.want)
+		}
+	}
+}
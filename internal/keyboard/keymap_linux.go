@@ -0,0 +1,101 @@
+package keyboard
+
+// Linux key codes from linux/input-event-codes.h
+const (
+	codeBackspace  = 14
+	codeEnter      = 28
+	codeLeftCtrl   = 29
+	codeLeftShift  = 42
+	codeRightShift = 54
+	codeLeftAlt    = 56
+	codeCapsLock   = 58
+	codeKPEnter    = 96
+	codeRightCtrl  = 97
+	codeRightAlt   = 100
+	codeLeftMeta   = 125
+	codeRightMeta  = 126
+)
+
+// usLayout maps key codes to the characters of a US keyboard, unshifted and
+// shifted. Other layouts are not translated.
+var usLayout = map[uint16][2]rune{
+	2: {'1', '!'}, 3: {'2', '@'}, 4: {'3', '#'}, 5: {'4', '$'}, 6: {'5', '%'},
+	7: {'6', '^'}, 8: {'7', '&'}, 9: {'8', '*'}, 10: {'9', '('}, 11: {'0', ')'},
+	12: {'-', '_'}, 13: {'=', '+'}, 15: {'\t', '\t'},
+	16: {'q', 'Q'}, 17: {'w', 'W'}, 18: {'e', 'E'}, 19: {'r', 'R'}, 20: {'t', 'T'},
+	21: {'y', 'Y'}, 22: {'u', 'U'}, 23: {'i', 'I'}, 24: {'o', 'O'}, 25: {'p', 'P'},
+	26: {'[', '{'}, 27: {']', '}'},
+	30: {'a', 'A'}, 31: {'s', 'S'}, 32: {'d', 'D'}, 33: {'f', 'F'}, 34: {'g', 'G'},
+	35: {'h', 'H'}, 36: {'j', 'J'}, 37: {'k', 'K'}, 38: {'l', 'L'},
+	39: {';', ':'}, 40: {'\'', '"'}, 41: {'`', '~'}, 43: {'\\', '|'},
+	44: {'z', 'Z'}, 45: {'x', 'X'}, 46: {'c', 'C'}, 47: {'v', 'V'}, 48: {'b', 'B'},
+	49: {'n', 'N'}, 50: {'m', 'M'}, 51: {',', '<'}, 52: {'.', '>'}, 53: {'/', '?'},
+	57: {' ', ' '},
+}
+
+// modifiers tracks the modifier keys held down across key events
+type modifiers struct {
+	shift, ctrl, alt, meta int // Keys held; left and right count separately
+	caps                   bool
+}
+
+// translate turns a key event into a Key, reporting false for releases,
+// modifiers and shortcuts
+func (m *modifiers) translate(code uint16, value int32) (Key, bool) {
+	pressed := value != 0
+	delta := -1
+	if value == 1 {
+		delta = 1
+	}
+
+	switch code {
+	case codeLeftShift, codeRightShift:
+		if value != 2 {
+			m.shift = max(m.shift+delta, 0)
+		}
+		return Key{}, false
+	case codeLeftCtrl, codeRightCtrl:
+		if value != 2 {
+			m.ctrl = max(m.ctrl+delta, 0)
+		}
+		return Key{}, false
+	case codeLeftAlt, codeRightAlt:
+		if value != 2 {
+			m.alt = max(m.alt+delta, 0)
+		}
+		return Key{}, false
+	case codeLeftMeta, codeRightMeta:
+		if value != 2 {
+			m.meta = max(m.meta+delta, 0)
+		}
+		return Key{}, false
+	case codeCapsLock:
+		if value == 1 {
+			m.caps = !m.caps
+		}
+		return Key{}, false
+	}
+
+	if !pressed || m.ctrl > 0 || m.alt > 0 || m.meta > 0 {
+		return Key{}, false
+	}
+	switch code {
+	case codeBackspace:
+		return Key{Code: KeyBackspace}, true
+	case codeEnter, codeKPEnter:
+		return Key{Code: KeyEnter}, true
+	}
+
+	chars, ok := usLayout[code]
+	if !ok {
+		return Key{}, false
+	}
+	shifted := m.shift > 0
+	if m.caps && chars[0] >= 'a' && chars[0] <= 'z' {
+		shifted = !shifted
+	}
+	if shifted {
+		return Key{Code: KeyRune, Rune: chars[1]}, true
+	}
+	return Key{Code: KeyRune, Rune: chars[0]}, true
+}
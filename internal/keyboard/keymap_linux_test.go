@@ -0,0 +1,71 @@
+package keyboard
+
+import (
+	"strings"
+	"testing"
+)
+
+// TestModifiers_Translate tests turning key events into typed characters
+func TestModifiers_Translate(t *testing.T) {
+	events := []struct {
+		code  uint16
+		value int32
+	}{
+		{30, 1}, {30, 0}, // a
+		{codeLeftShift, 1}, {3, 1}, {3, 0}, {codeLeftShift, 0}, // @
+		{codeCapsLock, 1}, {codeCapsLock, 0}, {48, 1}, {48, 2}, // B, autorepeated
+		{codeLeftCtrl, 1}, {47, 1}, {codeLeftCtrl, 0}, // Ctrl+V is not typed
+		{codeBackspace, 1}, {codeEnter, 1},
+	}
+
+	var m modifiers
+	var got []Key
+	for _, ev := range events {
+		if key, ok := m.translate(ev.code, ev.value); ok {
+			got = append(got, key)
+		}
+	}
+
+	want := []Key{
+		{Code: KeyRune, Rune: 'a'},
+		{Code: KeyRune, Rune: '@'},
+		{Code: KeyRune, Rune: 'B'},
+		{Code: KeyRune, Rune: 'B'},
+		{Code: KeyBackspace},
+		{Code: KeyEnter},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("Expected %v, got %v", want, got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("Key %d: expected %v, got %v", i, want[i], got[i])
+		}
+	}
+}
+
+// TestParseDevices tests finding keyboards in /proc/bus/input/devices
+func TestParseDevices(t *testing.T) {
+	devices := `I: Bus=0011 Vendor=0001 Product=0001 Version=ab41
+N: Name="AT Translated Set 2 keyboard"
+H: Handlers=sysrq kbd event3 leds
+B: EV=120013
+
+I: Bus=0003 Vendor=046d Product=c52b Version=0111
+N: Name="Logitech USB Receiver Mouse"
+H: Handlers=mouse0 event5
+B: EV=17
+
+I: Bus=0019 Vendor=0000 Product=0001 Version=0000
+N: Name="Power Button"
+H: Handlers=kbd event1
+B: EV=3
+`
+	got, err := parseDevices(strings.NewReader(devices))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0] != "/dev/input/event3" {
+		t.Errorf("Expected only the keyboard, got %q", got)
+	}
+}
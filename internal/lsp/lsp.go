@@ -0,0 +1,328 @@
+// Package lsp is a minimal language server publishing diagnostics for
+// sensitive data in the documents open in an editor, with code actions
+// redacting it, so leaks are seen before anything is copied. Documents are
+// synced incrementally and nothing they contain is logged.
+package lsp
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sync"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+	"github.com/happytaoer/prompt-security/internal/editor"
+	"github.com/happytaoer/prompt-security/internal/filter"
+)
+
+// Source names the server in diagnostics
+const Source = "prompt-security"
+
+// Code action kinds
+const (
+	KindQuickFix = "quickfix"
+	KindFixAll   = "source.fixAll.prompt-security"
+)
+
+// Server is a language server scanning open documents with an engine
+type Server struct {
+	engine *filter.Engine
+
+	mu        sync.Mutex
+	out       io.Writer
+	documents map[string]*editor.Document
+	shutdown  bool
+}
+
+// New creates a server scanning with engine
+func New(engine *filter.Engine) *Server {
+	return &Server{engine: engine, documents: make(map[string]*editor.Document)}
+}
+
+// Serve answers the messages read from r on w until the client sends exit
+// or r ends
+func (s *Server) Serve(r io.Reader, w io.Writer) error {
+	s.mu.Lock()
+	s.out = w
+	s.mu.Unlock()
+
+	in := bufio.NewReader(r)
+	for {
+		body, err := readMessage(in)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		var req request
+		if err := json.Unmarshal(body, &req); err != nil {
+			return fmt.Errorf("invalid message: %v", err)
+		}
+		if req.Method == "exit" {
+			return nil
+		}
+
+		result, rpcErr := s.handle(req)
+		if len(req.ID) == 0 {
+			continue // Notifications are not answered
+		}
+		resp := response{JSONRPC: "2.0", ID: req.ID, Error: rpcErr}
+		if rpcErr == nil {
+			if resp.Result, err = json.Marshal(result); err != nil {
+				return err
+			}
+		}
+		if err := s.write(resp); err != nil {
+			return err
+		}
+	}
+}
+
+// handle runs the method of a request and returns its result
+func (s *Server) handle(req request) (interface{}, *responseError) {
+	s.mu.Lock()
+	shutdown := s.shutdown
+	s.mu.Unlock()
+	if shutdown && req.Method != "shutdown" {
+		return nil, &responseError{Code: codeInvalidRequest, Message: "the server is shut down"}
+	}
+
+	switch req.Method {
+	case "initialize":
+		return map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"textDocumentSync":   2, // Incremental changes
+				"codeActionProvider": map[string]interface{}{"codeActionKinds": []string{KindQuickFix, KindFixAll}},
+			},
+			"serverInfo": map[string]string{"name": Source},
+		}, nil
+	case "shutdown":
+		s.mu.Lock()
+		s.shutdown = true
+		s.mu.Unlock()
+		return nil, nil
+	case "textDocument/didOpen":
+		var params struct {
+			TextDocument struct {
+				URI     string `json:"uri"`
+				Version int    `json:"version"`
+				Text    string `json:"text"`
+			} `json:"textDocument"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		s.open(params.TextDocument.URI, editor.Scan(s.engine, params.TextDocument.Version, params.TextDocument.Text))
+		return nil, nil
+	case "textDocument/didChange":
+		var params struct {
+			TextDocument struct {
+				URI     string `json:"uri"`
+				Version int    `json:"version"`
+			} `json:"textDocument"`
+			ContentChanges []editor.TextChange `json:"contentChanges"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		s.change(params.TextDocument.URI, params.TextDocument.Version, params.ContentChanges)
+		return nil, nil
+	case "textDocument/didClose":
+		var params struct {
+			TextDocument struct {
+				URI string `json:"uri"`
+			} `json:"textDocument"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		s.close(params.TextDocument.URI)
+		return nil, nil
+	case "textDocument/codeAction":
+		var params struct {
+			TextDocument struct {
+				URI string `json:"uri"`
+			} `json:"textDocument"`
+			Range editor.Range `json:"range"`
+		}
+		if err := json.Unmarshal(req.Params, &params); err != nil {
+			return nil, invalidParams(err)
+		}
+		return s.codeActions(params.TextDocument.URI, params.Range), nil
+	}
+	if len(req.ID) > 0 {
+		return nil, &responseError{Code: codeMethodNotFound, Message: "method not supported: " + req.Method}
+	}
+	return nil, nil
+}
+
+func invalidParams(err error) *responseError {
+	return &responseError{Code: codeInvalidParams, Message: err.Error()}
+}
+
+// open stores a document and publishes its diagnostics
+func (s *Server) open(uri string, doc *editor.Document) {
+	s.mu.Lock()
+	s.documents[uri] = doc
+	s.mu.Unlock()
+	s.publish(uri, doc)
+}
+
+// change applies changes to a document and publishes its diagnostics
+func (s *Server) change(uri string, version int, changes []editor.TextChange) {
+	s.mu.Lock()
+	doc := s.documents[uri]
+	s.mu.Unlock()
+	if doc == nil {
+		return
+	}
+	next, err := doc.Apply(s.engine, version, changes)
+	if err != nil {
+		// The document is out of sync with the editor, so its findings
+		// would point at the wrong text. Drop it until it is opened again.
+		s.mu.Lock()
+		if s.documents[uri] == doc {
+			delete(s.documents, uri)
+		}
+		s.mu.Unlock()
+		s.publish(uri, nil)
+		s.logMessage(messageWarning, fmt.Sprintf("Stopped scanning %s, failed to apply a change: %v. Reopen the file to scan it again.", uri, err))
+		return
+	}
+	s.open(uri, next)
+}
+
+// close forgets a document and clears its diagnostics
+func (s *Server) close(uri string) {
+	s.mu.Lock()
+	delete(s.documents, uri)
+	s.mu.Unlock()
+	s.publish(uri, nil)
+}
+
+// Refresh scans the open documents again, e.g. after the configuration
+// changed, and publishes their diagnostics
+func (s *Server) Refresh() {
+	s.mu.Lock()
+	docs := make(map[string]*editor.Document, len(s.documents))
+	for uri, doc := range s.documents {
+		docs[uri] = doc
+	}
+	s.mu.Unlock()
+
+	for uri, doc := range docs {
+		scanned := editor.Scan(s.engine, doc.Version, doc.Text())
+		s.mu.Lock()
+		current := s.documents[uri] == doc // Not changed or closed meanwhile
+		if current {
+			s.documents[uri] = scanned
+		}
+		s.mu.Unlock()
+		if current {
+			s.publish(uri, scanned)
+		}
+	}
+}
+
+// diagnostic describes a finding as a diagnostic
+func diagnostic(f editor.Finding) Diagnostic {
+	d := Diagnostic{Range: f.Range, Severity: severity(f.Action), Code: f.Type, Source: Source}
+	switch f.Action {
+	case config.ActionBlock:
+		d.Message = fmt.Sprintf("Sensitive data (%s): copies containing it are blocked", f.Type)
+	case config.ActionLog, config.ActionReview:
+		d.Message = fmt.Sprintf("Possible sensitive data (%s)", f.Type)
+	default:
+		d.Message = fmt.Sprintf("Sensitive data (%s): copies would be redacted to %s", f.Type, f.Replacement)
+	}
+	return d
+}
+
+// severity returns the diagnostic severity for a detector's action
+func severity(action string) int {
+	switch action {
+	case config.ActionBlock:
+		return SeverityError
+	case config.ActionLog, config.ActionReview:
+		return SeverityInformation
+	}
+	return SeverityWarning
+}
+
+// publish sends the diagnostics of a document, none if doc is nil
+func (s *Server) publish(uri string, doc *editor.Document) {
+	diagnostics := []Diagnostic{}
+	if doc != nil {
+		for _, f := range doc.Findings() {
+			diagnostics = append(diagnostics, diagnostic(f))
+		}
+	}
+	s.write(notification{
+		JSONRPC: "2.0",
+		Method:  "textDocument/publishDiagnostics",
+		Params:  map[string]interface{}{"uri": uri, "diagnostics": diagnostics},
+	})
+}
+
+// logMessage shows a message of a type in the client's log
+func (s *Server) logMessage(typ int, text string) {
+	s.write(notification{
+		JSONRPC: "2.0",
+		Method:  "window/logMessage",
+		Params:  map[string]interface{}{"type": typ, "message": text},
+	})
+}
+
+// codeActions offers to redact the sensitive data in rng, and all of it in
+// the document if there is more
+func (s *Server) codeActions(uri string, rng editor.Range) []CodeAction {
+	s.mu.Lock()
+	doc := s.documents[uri]
+	s.mu.Unlock()
+	actions := []CodeAction{}
+	if doc == nil {
+		return actions
+	}
+
+	var all []editor.TextEdit
+	var allDiagnostics []Diagnostic
+	for _, f := range doc.Findings() {
+		if f.Replacement == "" {
+			continue
+		}
+		edit := editor.TextEdit{Range: f.Range, NewText: f.Replacement}
+		all = append(all, edit)
+		allDiagnostics = append(allDiagnostics, diagnostic(f))
+		if f.Range.Overlaps(rng) {
+			actions = append(actions, CodeAction{
+				Title:       fmt.Sprintf("Redact %s as %s", f.Type, f.Replacement),
+				Kind:        KindQuickFix,
+				Diagnostics: []Diagnostic{diagnostic(f)},
+				IsPreferred: true,
+				Edit:        WorkspaceEdit{Changes: map[string][]editor.TextEdit{uri: {edit}}},
+			})
+		}
+	}
+	if len(all) > 1 {
+		actions = append(actions, CodeAction{
+			Title:       fmt.Sprintf("Redact all sensitive data in the file (%d)", len(all)),
+			Kind:        KindFixAll,
+			Diagnostics: allDiagnostics,
+			Edit:        WorkspaceEdit{Changes: map[string][]editor.TextEdit{uri: all}},
+		})
+	}
+	return actions
+}
+
+// write sends a message to the client
+func (s *Server) write(v interface{}) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.out == nil {
+		return nil
+	}
+	return writeMessage(s.out, v)
+}
@@ -0,0 +1,148 @@
+package lsp
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+	"github.com/happytaoer/prompt-security/internal/editor"
+	"github.com/happytaoer/prompt-security/internal/filter"
+)
+
+// message is a message sent by the server
+type message struct {
+	ID     json.RawMessage `json:"id"`
+	Method string          `json:"method"`
+	Params struct {
+		URI         string       `json:"uri"`
+		Diagnostics []Diagnostic `json:"diagnostics"`
+		Type        int          `json:"type"`
+		Message     string       `json:"message"`
+	} `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *responseError  `json:"error"`
+}
+
+// serve runs a session of the given requests and returns the messages the
+// server sent
+func serve(t *testing.T, s *Server, requests ...string) []message {
+	var in, out bytes.Buffer
+	for _, r := range requests {
+		if err := writeMessage(&in, json.RawMessage(r)); err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+	}
+	if err := s.Serve(&in, &out); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	var messages []message
+	reader := bufio.NewReader(&out)
+	for reader.Buffered() > 0 || out.Len() > 0 {
+		body, err := readMessage(reader)
+		if err != nil {
+			t.Fatalf("Invalid message from the server: %v", err)
+		}
+		var m message
+		json.Unmarshal(body, &m)
+		messages = append(messages, m)
+	}
+	return messages
+}
+
+func testServer() *Server {
+	return New(filter.NewEngine(config.Config{
+		DetectEmails:     true,
+		EmailReplacement: "[EMAIL]",
+		DetectSSNs:       true,
+		SSNReplacement:   "XXX-XX-XXXX",
+		SSNAction:        config.ActionBlock,
+	}))
+}
+
+// TestServer tests diagnostics and code actions over a session
+func TestServer(t *testing.T) {
+	text := `mail ann@example.com\n🙂 or bob@example.com\nssn 123-45-6789`
+	messages := serve(t, testServer(),
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{}}`,
+		`{"jsonrpc":"2.0","method":"initialized","params":{}}`,
+		`{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":"file:///a.txt","text":"`+text+`"}}}`,
+		`{"jsonrpc":"2.0","id":2,"method":"textDocument/codeAction","params":{"textDocument":{"uri":"file:///a.txt"},"range":{"start":{"line":1,"character":8},"end":{"line":1,"character":8}},"context":{"diagnostics":[]}}}`,
+		`{"jsonrpc":"2.0","id":3,"method":"textDocument/hover","params":{}}`,
+		`{"jsonrpc":"2.0","method":"textDocument/didClose","params":{"textDocument":{"uri":"file:///a.txt"}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"shutdown"}`,
+		`{"jsonrpc":"2.0","method":"exit"}`,
+	)
+	if len(messages) != 6 {
+		t.Fatalf("Expected 6 messages, got %d: %+v", len(messages), messages)
+	}
+
+	if string(messages[0].ID) != "1" || !strings.Contains(string(messages[0].Result), `"codeActionProvider"`) {
+		t.Errorf("Expected the initialize result, got %+v", messages[0])
+	}
+
+	diagnostics := messages[1].Params.Diagnostics
+	if messages[1].Method != "textDocument/publishDiagnostics" || len(diagnostics) != 3 {
+		t.Fatalf("Expected 3 diagnostics, got %+v", messages[1])
+	}
+	// The emoji counts as two UTF-16 code units
+	want := editor.Range{Start: editor.Position{Line: 1, Character: 6}, End: editor.Position{Line: 1, Character: 21}}
+	if diagnostics[1].Range != want || diagnostics[1].Code != "email" || diagnostics[1].Severity != SeverityWarning {
+		t.Errorf("Expected a warning for the second email at %+v, got %+v", want, diagnostics[1])
+	}
+	if diagnostics[2].Severity != SeverityError {
+		t.Errorf("Expected an error for blocked data, got %+v", diagnostics[2])
+	}
+
+	var actions []CodeAction
+	json.Unmarshal(messages[2].Result, &actions)
+	if len(actions) != 2 || actions[0].Kind != KindQuickFix || actions[1].Kind != KindFixAll {
+		t.Fatalf("Expected a fix and a fix-all action, got %+v", actions)
+	}
+	if edits := actions[0].Edit.Changes["file:///a.txt"]; len(edits) != 1 || edits[0].Range != want || edits[0].NewText != "[EMAIL]" {
+		t.Errorf("Expected the email under the cursor to be redacted, got %+v", edits)
+	}
+	if edits := actions[1].Edit.Changes["file:///a.txt"]; len(edits) != 3 {
+		t.Errorf("Expected all data to be redacted, got %+v", edits)
+	}
+
+	if messages[3].Error == nil || messages[3].Error.Code != codeMethodNotFound {
+		t.Errorf("Expected unsupported methods to be refused, got %+v", messages[3])
+	}
+	if messages[4].Method != "textDocument/publishDiagnostics" || len(messages[4].Params.Diagnostics) != 0 {
+		t.Errorf("Expected diagnostics to be cleared on close, got %+v", messages[4])
+	}
+	if string(messages[5].ID) != "4" || string(messages[5].Result) != "null" {
+		t.Errorf("Expected the shutdown result, got %+v", messages[5])
+	}
+}
+
+// TestServer_Change tests that changed documents are scanned again
+func TestServer_Change(t *testing.T) {
+	messages := serve(t, testServer(),
+		`{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":"file:///a.txt","text":"hello"}}}`,
+		`{"jsonrpc":"2.0","method":"textDocument/didChange","params":{"textDocument":{"uri":"file:///a.txt","version":2},"contentChanges":[{"range":{"start":{"line":0,"character":5},"end":{"line":0,"character":5}},"text":" ann@example.com"}]}}`,
+	)
+	if len(messages) != 2 || len(messages[0].Params.Diagnostics) != 0 || len(messages[1].Params.Diagnostics) != 1 {
+		t.Errorf("Expected a diagnostic after the change only, got %+v", messages)
+	}
+}
+
+// TestServer_ChangeOutOfSync tests that a document a change does not fit is
+// dropped with its diagnostics and a warning in the client's log
+func TestServer_ChangeOutOfSync(t *testing.T) {
+	messages := serve(t, testServer(),
+		`{"jsonrpc":"2.0","method":"textDocument/didOpen","params":{"textDocument":{"uri":"file:///a.txt","text":"ann@example.com"}}}`,
+		`{"jsonrpc":"2.0","method":"textDocument/didChange","params":{"textDocument":{"uri":"file:///a.txt","version":2},"contentChanges":[{"range":{"start":{"line":0,"character":4},"end":{"line":0,"character":1}},"text":"x"}]}}`,
+		`{"jsonrpc":"2.0","method":"textDocument/didChange","params":{"textDocument":{"uri":"file:///a.txt","version":3},"contentChanges":[{"text":"bob@example.com"}]}}`,
+	)
+	if len(messages) != 3 || len(messages[0].Params.Diagnostics) != 1 || len(messages[1].Params.Diagnostics) != 0 {
+		t.Fatalf("Expected the diagnostics to be cleared and later changes ignored, got %+v", messages)
+	}
+	if messages[2].Method != "window/logMessage" || messages[2].Params.Type != messageWarning || !strings.Contains(messages[2].Params.Message, "file:///a.txt") {
+		t.Errorf("Expected a warning naming the document, got %+v", messages[2])
+	}
+}
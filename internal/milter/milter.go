@@ -0,0 +1,280 @@
+// Package milter scans outgoing mail for sensitive data as a milter, the
+// filter protocol of Sendmail and Postfix. Text bodies and text attachments
+// are scanned with the same detectors as the clipboard: mail a blocking
+// detector matches is rejected, values of replacing detectors are redacted
+// in place and the message is tagged, and everything found is logged.
+package milter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"mime"
+	"net"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/happytaoer/prompt-security/internal/filter"
+)
+
+const (
+	// idleTimeout bounds waiting for the next command of the MTA
+	idleTimeout = 10 * time.Minute
+
+	// maxBody bounds the size of a message body scanned; larger ones are
+	// rejected, since they cannot be scanned
+	maxBody = 32 << 20
+
+	// tagHeader names the header added to redacted messages
+	tagHeader = "X-Prompt-Security"
+)
+
+// LogFunc logs text containing sensitive data
+type LogFunc func(original, filtered string, replacements []filter.ReplacementInfo)
+
+// Options configure a Server
+type Options struct {
+	// Recipients are patterns of the recipients whose mail is scanned,
+	// addresses like *@openai.com or domains like *.openai.com, which also
+	// matches openai.com itself; empty scans all mail
+	Recipients []string
+
+	// AddLog, if set, logs what was found like copies
+	AddLog LogFunc
+}
+
+// Server is a milter scanning messages with engine
+type Server struct {
+	engine *filter.Engine
+	opts   Options
+	logger *slog.Logger
+}
+
+// New creates a milter scanning messages with engine
+func New(engine *filter.Engine, opts Options) *Server {
+	if opts.AddLog == nil {
+		opts.AddLog = func(string, string, []filter.ReplacementInfo) {}
+	}
+	for i, r := range opts.Recipients {
+		opts.Recipients[i] = strings.ToLower(r)
+	}
+	return &Server{engine: engine, opts: opts, logger: slog.New(slog.NewJSONHandler(os.Stdout, nil))}
+}
+
+// Serve accepts MTA connections on l until it is closed (blocking)
+func (s *Server) Serve(l net.Listener) error {
+	for {
+		conn, err := l.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go func() {
+			defer conn.Close()
+			if err := s.ServeConn(conn); err != nil && !errors.Is(err, io.EOF) {
+				s.logger.Warn("Milter connection failed", "error", err)
+			}
+		}()
+	}
+}
+
+// session is the state of an MTA connection
+type session struct {
+	actions    uint32 // Actions the MTA allows
+	recipients []string
+	headers    [][2]string
+	body       bytes.Buffer
+	tooLarge   bool
+}
+
+// reset forgets the current message
+func (ss *session) reset() {
+	ss.recipients = nil
+	ss.headers = nil
+	ss.body.Reset()
+	ss.tooLarge = false
+}
+
+// ServeConn speaks the milter protocol on conn until the MTA quits
+func (s *Server) ServeConn(conn net.Conn) error {
+	ss := &session{}
+	for {
+		conn.SetReadDeadline(time.Now().Add(idleTimeout))
+		cmd, data, err := readPacket(conn)
+		if err != nil {
+			return err
+		}
+
+		switch cmd {
+		case cmdOptNeg:
+			if len(data) < 12 {
+				return fmt.Errorf("invalid option negotiation")
+			}
+			version := binary.BigEndian.Uint32(data)
+			if version < minVersion {
+				return fmt.Errorf("unsupported milter protocol version %d", version)
+			}
+			ss.actions = binary.BigEndian.Uint32(data[4:]) & (actAddHeaders | actChgBody | actChgHeaders)
+			if ss.actions&actChgHeaders == 0 {
+				// Redacted subjects could not be written back and would
+				// pass unredacted
+				return fmt.Errorf("the MTA does not allow the milter to change headers")
+			}
+			protocol := binary.BigEndian.Uint32(data[8:]) & (protoNoConnect | protoNoHelo | protoNoUnknown | protoNoData)
+			reply := make([]byte, 12)
+			binary.BigEndian.PutUint32(reply, min(version, maxVersion))
+			binary.BigEndian.PutUint32(reply[4:], ss.actions)
+			binary.BigEndian.PutUint32(reply[8:], protocol)
+			err = writePacket(conn, respOptNeg, reply)
+		case cmdMacro:
+			// Macros need no reply
+		case cmdAbort:
+			ss.reset()
+		case cmdQuit:
+			return nil
+		case cmdQuitNC:
+			ss.reset()
+		case cmdMail:
+			ss.reset()
+			err = writePacket(conn, respContinue, nil)
+		case cmdRcpt:
+			if args := cstrings(data); len(args) > 0 {
+				ss.recipients = append(ss.recipients, strings.ToLower(strings.Trim(args[0], "<> ")))
+			}
+			err = writePacket(conn, respContinue, nil)
+		case cmdHeader:
+			if args := cstrings(data); len(args) == 2 {
+				ss.headers = append(ss.headers, [2]string{args[0], args[1]})
+			}
+			err = writePacket(conn, respContinue, nil)
+		case cmdBody:
+			if ss.body.Len()+len(data) > maxBody {
+				ss.tooLarge = true
+			} else {
+				ss.body.Write(data)
+			}
+			err = writePacket(conn, respContinue, nil)
+		case cmdEOB:
+			ss.body.Write(data)
+			err = s.endOfMessage(conn, ss)
+			ss.reset()
+		case cmdConnect, cmdHelo, cmdEOH, cmdData, cmdUnknown:
+			err = writePacket(conn, respContinue, nil)
+		default:
+			return fmt.Errorf("unknown milter command %q", cmd)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// scanned reports whether mail to the recipients of ss is scanned
+func (s *Server) scanned(ss *session) bool {
+	if len(s.opts.Recipients) == 0 {
+		return true
+	}
+	for _, r := range ss.recipients {
+		_, domain, _ := strings.Cut(r, "@")
+		for _, pattern := range s.opts.Recipients {
+			target := r
+			if !strings.Contains(pattern, "@") {
+				target = domain
+			}
+			if ok, _ := path.Match(pattern, target); ok {
+				return true
+			}
+			if apex, ok := strings.CutPrefix(pattern, "*."); ok && target == apex {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// endOfMessage scans the message of ss and tells the MTA to reject it,
+// replace its body and subject, or accept it
+func (s *Server) endOfMessage(conn net.Conn, ss *session) error {
+	if !s.scanned(ss) {
+		return writePacket(conn, respContinue, nil)
+	}
+	if ss.tooLarge {
+		s.logger.Warn("Rejected message too large to scan", "recipients", ss.recipients)
+		return writePacket(conn, respReplyCode, cstringData("552 5.3.4 Message too large to scan for sensitive data"))
+	}
+
+	sc := &scanner{engine: s.engine, addLog: s.opts.AddLog}
+	header := parseHeader([]byte(formatHeaders(ss.headers)))
+	body := sc.redactEntity(header, ss.body.Bytes(), 0)
+	subject, subjectIndex := s.redactSubject(sc, ss.headers)
+
+	if sc.blocked || (body != nil && ss.actions&actChgBody == 0) {
+		types := strings.Join(sc.types, ", ")
+		s.logger.Warn("Rejected message with sensitive data", "recipients", ss.recipients, "types", types)
+		return writePacket(conn, respReplyCode, cstringData("550 5.7.1 Message contains sensitive data: "+types))
+	}
+	if len(sc.types) == 0 {
+		return writePacket(conn, respContinue, nil)
+	}
+
+	if body != nil {
+		for len(body) > 0 {
+			n := min(len(body), chunkSize)
+			if err := writePacket(conn, respReplBody, body[:n]); err != nil {
+				return err
+			}
+			body = body[n:]
+		}
+	}
+	if subjectIndex > 0 {
+		data := make([]byte, 4)
+		binary.BigEndian.PutUint32(data, uint32(subjectIndex))
+		if err := writePacket(conn, respChgHeader, append(data, cstringData("Subject", subject)...)); err != nil {
+			return err
+		}
+	}
+	if ss.actions&actAddHeaders != 0 {
+		if err := writePacket(conn, respAddHeader, cstringData(tagHeader, "found "+strings.Join(sc.types, ", "))); err != nil {
+			return err
+		}
+	}
+	s.logger.Warn("Message contains sensitive data", "recipients", ss.recipients, "types", strings.Join(sc.types, ", "), "redacted", body != nil || subjectIndex > 0)
+	return writePacket(conn, respContinue, nil)
+}
+
+// redactSubject scans the first Subject header and returns it redacted and
+// its index among Subject headers, counted from 1, or 0 if unchanged
+func (s *Server) redactSubject(sc *scanner, headers [][2]string) (string, int) {
+	for _, h := range headers {
+		if !strings.EqualFold(h[0], "Subject") {
+			continue
+		}
+		decoder := &mime.WordDecoder{}
+		text, err := decoder.DecodeHeader(strings.TrimSpace(h[1]))
+		if err != nil {
+			return "", 0
+		}
+		filtered, changed := sc.scanText(text)
+		if !changed {
+			return "", 0
+		}
+		return mime.QEncoding.Encode("utf-8", filtered), 1
+	}
+	return "", 0
+}
+
+// formatHeaders formats headers as a raw header block
+func formatHeaders(headers [][2]string) string {
+	var b strings.Builder
+	for _, h := range headers {
+		b.WriteString(h[0] + ": " + h[1] + "\r\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,187 @@
+package milter
+
+import (
+	"encoding/binary"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+	"github.com/happytaoer/prompt-security/internal/filter"
+)
+
+func testEngine() *filter.Engine {
+	return filter.NewEngine(config.Config{
+		DetectEmails:     true,
+		EmailReplacement: "[EMAIL]",
+		DetectSSNs:       true,
+		SSNReplacement:   "XXX-XX-XXXX",
+		SSNAction:        config.ActionBlock,
+	})
+}
+
+// mta drives a milter session like Postfix
+type mta struct {
+	t    *testing.T
+	conn net.Conn
+}
+
+func newMTA(t *testing.T, s *Server) *mta {
+	client, server := net.Pipe()
+	go s.ServeConn(server)
+	m := &mta{t: t, conn: client}
+	t.Cleanup(func() { client.Close() })
+
+	negotiation := make([]byte, 12)
+	binary.BigEndian.PutUint32(negotiation, 6)
+	binary.BigEndian.PutUint32(negotiation[4:], 0x1ff)
+	binary.BigEndian.PutUint32(negotiation[8:], 0x1fffff)
+	if replies := m.send(cmdOptNeg, negotiation); len(replies) != 1 || replies[0][0] != respOptNeg || binary.BigEndian.Uint32([]byte(replies[0][5:])) != actAddHeaders|actChgBody|actChgHeaders {
+		t.Fatalf("Unexpected negotiation %q", replies)
+	}
+	return m
+}
+
+// send sends a command and returns the replies up to a final one
+func (m *mta) send(cmd byte, data []byte) []string {
+	if err := writePacket(m.conn, cmd, data); err != nil {
+		m.t.Fatal(err)
+	}
+	var replies []string
+	for {
+		code, data, err := readPacket(m.conn)
+		if err != nil {
+			m.t.Fatal(err)
+		}
+		replies = append(replies, string(code)+string(data))
+		if code != respReplBody && code != respAddHeader && code != respChgHeader {
+			return replies
+		}
+	}
+}
+
+// message sends a message and returns the replies to its end
+func (m *mta) message(rcpt string, headers [][2]string, body string) []string {
+	m.send(cmdMail, cstringData("<me@example.com>"))
+	m.send(cmdRcpt, cstringData("<"+rcpt+">"))
+	for _, h := range headers {
+		m.send(cmdHeader, cstringData(h[0], h[1]))
+	}
+	m.send(cmdEOH, nil)
+	for len(body) > 0 {
+		n := min(len(body), 10)
+		m.send(cmdBody, []byte(body[:n]))
+		body = body[n:]
+	}
+	return m.send(cmdEOB, nil)
+}
+
+// TestServer tests accepting, redacting and rejecting messages
+func TestServer(t *testing.T) {
+	logged := 0
+	m := newMTA(t, New(testEngine(), Options{Recipients: []string{"*@openai.com", "*.anthropic.com"}, AddLog: func(string, string, []filter.ReplacementInfo) { logged++ }}))
+	headers := [][2]string{{"Subject", "From ann@example.com"}, {"Content-Type", "text/plain"}}
+
+	if replies := m.message("bot@openai.com", [][2]string{{"Subject", "hi"}}, "hello\r\n"); len(replies) != 1 || replies[0] != "c" {
+		t.Errorf("Expected clean mail to be accepted, got %q", replies)
+	}
+	if replies := m.message("bob@example.com", headers, "mail ann@example.com\r\n"); len(replies) != 1 || replies[0] != "c" || logged != 0 {
+		t.Errorf("Expected mail to other recipients to be accepted unscanned, got %q", replies)
+	}
+
+	replies := m.message("bot@mail.anthropic.com", headers, "mail ann@example.com\r\n")
+	want := []string{"bmail [EMAIL]\r\n", "m\x00\x00\x00\x01Subject\x00From [EMAIL]\x00", "hX-Prompt-Security\x00found email\x00", "c"}
+	if strings.Join(replies, "|") != strings.Join(want, "|") || logged != 2 {
+		t.Errorf("Expected the body and subject to be redacted, got %q", replies)
+	}
+
+	if replies := m.message("bot@anthropic.com", headers, "mail ann@example.com\r\n"); len(replies) != 4 {
+		t.Errorf("Expected *.anthropic.com to match the domain itself, got %q", replies)
+	}
+
+	replies = m.message("bot@openai.com", headers, "ssn 123-45-6789\r\n")
+	if len(replies) != 1 || !strings.HasPrefix(replies[0], "y550 5.7.1 ") || !strings.Contains(replies[0], "ssn") {
+		t.Errorf("Expected blocked mail to be rejected, got %q", replies)
+	}
+
+	m.send(cmdMail, cstringData("<me@example.com>"))
+	m.send(cmdRcpt, cstringData("<bot@openai.com>"))
+	m.send(cmdBody, []byte("ssn 123-45-6789"))
+	writePacket(m.conn, cmdAbort, nil)
+	if replies := m.message("bot@openai.com", nil, "hello"); len(replies) != 1 || replies[0] != "c" {
+		t.Errorf("Expected an aborted message to be forgotten, got %q", replies)
+	}
+}
+
+// TestServer_Refusals tests that messages too large to scan are rejected
+// and that MTAs not allowing header changes are refused
+func TestServer_Refusals(t *testing.T) {
+	m := newMTA(t, New(testEngine(), Options{}))
+	m.send(cmdMail, cstringData("<me@example.com>"))
+	m.send(cmdRcpt, cstringData("<bot@openai.com>"))
+	chunk := make([]byte, maxPacket-1)
+	for i := 0; i <= maxBody/len(chunk); i++ {
+		m.send(cmdBody, chunk)
+	}
+	if replies := m.send(cmdEOB, nil); len(replies) != 1 || !strings.HasPrefix(replies[0], "y552 5.3.4 ") {
+		t.Errorf("Expected a message over the limit to be rejected, got %q", replies)
+	}
+
+	client, server := net.Pipe()
+	defer client.Close()
+	done := make(chan error, 1)
+	go func() { done <- New(testEngine(), Options{}).ServeConn(server) }()
+	negotiation := make([]byte, 12)
+	binary.BigEndian.PutUint32(negotiation, 6)
+	binary.BigEndian.PutUint32(negotiation[4:], actAddHeaders|actChgBody)
+	go writePacket(client, cmdOptNeg, negotiation)
+	if err := <-done; err == nil || !strings.Contains(err.Error(), "change headers") {
+		t.Errorf("Expected an MTA not allowing header changes to be refused, got %v", err)
+	}
+}
+
+// TestRedactEntity tests redacting encoded parts of multipart messages
+func TestRedactEntity(t *testing.T) {
+	body := strings.Join([]string{
+		"preamble",
+		"--b1",
+		"Content-Type: text/plain; charset=utf-8",
+		"Content-Transfer-Encoding: quoted-printable",
+		"",
+		"caf=C3=A9 ann@example.com",
+		"--b1",
+		"Content-Type: multipart/alternative; boundary=b2",
+		"",
+		"--b2",
+		"Content-Type: text/html",
+		"",
+		"<p>nothing</p>",
+		"--b2--",
+		"--b1",
+		"Content-Type: application/octet-stream; name=notes.txt",
+		"Content-Transfer-Encoding: base64",
+		"",
+		"bWFpbCBib2JAZXhhbXBsZS5jb20=",
+		"--b1",
+		"Content-Type: image/png",
+		"Content-Transfer-Encoding: base64",
+		"",
+		"bWFpbCBib2JAZXhhbXBsZS5jb20=",
+		"--b1--",
+		"",
+	}, "\r\n")
+	want := strings.Replace(strings.Replace(body, "caf=C3=A9 ann@example.com", "caf=C3=A9 [EMAIL]", 1), "bWFpbCBib2JAZXhhbXBsZS5jb20=", "bWFpbCBbRU1BSUxd", 1)
+
+	s := &scanner{engine: testEngine(), addLog: func(string, string, []filter.ReplacementInfo) {}}
+	header := parseHeader([]byte("Content-Type: multipart/mixed; boundary=\"b1\"\r\n"))
+	if got := string(s.redactEntity(header, []byte(body), 0)); got != want {
+		t.Errorf("Expected\n%q\ngot\n%q", want, got)
+	}
+	if s.blocked || len(s.types) != 1 {
+		t.Errorf("Expected one type found without blocking, got %+v", s.types)
+	}
+
+	if got := s.redactEntity(parseHeader([]byte("Content-Type: text/plain; charset=iso-8859-1\r\n")), []byte("ann@example.com"), 0); got != nil {
+		t.Errorf("Expected other charsets to be left alone, got %q", got)
+	}
+}
@@ -0,0 +1,281 @@
+package milter
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"io"
+	"mime"
+	"mime/quotedprintable"
+	"net/textproto"
+	"path"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+	"github.com/happytaoer/prompt-security/internal/filter"
+)
+
+// maxDepth bounds the nesting of multipart and attached messages scanned
+const maxDepth = 10
+
+// textTypes are the media types besides text/* that are scanned
+var textTypes = map[string]bool{
+	"application/json":                  true,
+	"application/xml":                   true,
+	"application/x-yaml":                true,
+	"application/yaml":                  true,
+	"application/x-sh":                  true,
+	"application/sql":                   true,
+	"application/x-pem-file":            true,
+	"application/x-www-form-urlencoded": true,
+}
+
+// textExtensions are the extensions of generic attachments that are
+// scanned as text
+var textExtensions = map[string]bool{
+	".txt": true, ".csv": true, ".tsv": true, ".log": true, ".md": true, ".json": true,
+	".xml": true, ".yaml": true, ".yml": true, ".env": true, ".ini": true, ".conf": true,
+	".cfg": true, ".toml": true, ".sql": true, ".pem": true, ".key": true, ".sh": true,
+}
+
+// scanner redacts the text parts of a message
+type scanner struct {
+	engine  *filter.Engine
+	addLog  LogFunc
+	types   []string
+	blocked bool
+}
+
+// scanText filters text, recording what was found, and returns the
+// filtered text and whether it changed
+func (s *scanner) scanText(text string) (string, bool) {
+	filtered, _, summary := s.engine.Filter(text)
+	if len(summary.Replacements) == 0 {
+		return text, false
+	}
+	s.addLog(text, filtered, summary.Replacements)
+	for _, t := range summary.Types() {
+		if !contains(s.types, t) {
+			s.types = append(s.types, t)
+		}
+	}
+	if summary.Action() == config.ActionBlock {
+		s.blocked = true
+	}
+	return filtered, filtered != text
+}
+
+// redactEntity scans the body of an entity with header and returns the body
+// redacted, or nil if nothing changed. Parts are rewritten in place, so
+// everything else in the message is kept byte for byte.
+func (s *scanner) redactEntity(header textproto.MIMEHeader, body []byte, depth int) []byte {
+	if depth > maxDepth {
+		return nil
+	}
+	mediaType, params, err := mime.ParseMediaType(header.Get("Content-Type"))
+	if err != nil {
+		mediaType, params = "text/plain", nil
+	}
+
+	switch {
+	case strings.HasPrefix(mediaType, "multipart/"):
+		return s.redactMultipart(body, params["boundary"], depth)
+	case mediaType == "message/rfc822":
+		inner, innerBody, sep, ok := splitEntity(body)
+		if !ok {
+			return nil
+		}
+		redacted := s.redactEntity(parseHeader(inner), innerBody, depth+1)
+		if redacted == nil {
+			return nil
+		}
+		return concat(inner, sep, redacted)
+	case !isText(mediaType, params, header):
+		return nil
+	}
+
+	charset := strings.ToLower(params["charset"])
+	if charset != "" && charset != "utf-8" && charset != "us-ascii" {
+		return nil // Redacting would garble other charsets
+	}
+	encoding := strings.ToLower(strings.TrimSpace(header.Get("Content-Transfer-Encoding")))
+	text, ok := decode(body, encoding)
+	if !ok || !utf8.Valid(text) {
+		return nil
+	}
+	filtered, changed := s.scanText(string(text))
+	if !changed {
+		return nil
+	}
+	return encode([]byte(filtered), encoding, body)
+}
+
+// redactMultipart redacts the parts of a multipart body
+func (s *scanner) redactMultipart(body []byte, boundary string, depth int) []byte {
+	if boundary == "" {
+		return nil
+	}
+	delimiter := []byte("--" + boundary)
+
+	var out bytes.Buffer
+	changed := false
+	last := 0       // Offset of body copied to out
+	partStart := -1 // Offset of the current part's content
+	for offset := 0; offset < len(body); {
+		end := bytes.IndexByte(body[offset:], '\n')
+		if end < 0 {
+			end = len(body)
+		} else {
+			end += offset + 1
+		}
+		line := body[offset:end]
+		if rest, ok := bytes.CutPrefix(line, delimiter); ok {
+			rest = bytes.TrimSpace(rest)
+			if len(rest) == 0 || bytes.Equal(rest, []byte("--")) {
+				if partStart >= 0 {
+					// The line break before a delimiter belongs to it
+					contentEnd := offset
+					if contentEnd > partStart && body[contentEnd-1] == '\n' {
+						contentEnd--
+						if contentEnd > partStart && body[contentEnd-1] == '\r' {
+							contentEnd--
+						}
+					}
+					if redacted := s.redactPart(body[partStart:contentEnd], depth); redacted != nil {
+						out.Write(body[last:partStart])
+						out.Write(redacted)
+						last = contentEnd
+						changed = true
+					}
+				}
+				partStart = end
+				if len(rest) > 0 {
+					break // Close delimiter
+				}
+			}
+		}
+		offset = end
+	}
+	if !changed {
+		return nil
+	}
+	out.Write(body[last:])
+	return out.Bytes()
+}
+
+// redactPart redacts a part of a multipart body, headers included
+func (s *scanner) redactPart(part []byte, depth int) []byte {
+	header, body, sep, ok := splitEntity(part)
+	if !ok {
+		return nil
+	}
+	redacted := s.redactEntity(parseHeader(header), body, depth+1)
+	if redacted == nil {
+		return nil
+	}
+	return concat(header, sep, redacted)
+}
+
+// splitEntity splits an entity into its header, the blank line after it
+// and its body
+func splitEntity(entity []byte) (header, body, sep []byte, ok bool) {
+	for _, sep := range [][]byte{[]byte("\r\n"), []byte("\n")} {
+		if bytes.HasPrefix(entity, sep) {
+			return nil, entity[len(sep):], sep, true
+		}
+	}
+	crlf := bytes.Index(entity, []byte("\r\n\r\n"))
+	lf := bytes.Index(entity, []byte("\n\n"))
+	switch {
+	case crlf >= 0 && (lf < 0 || crlf < lf):
+		return entity[:crlf+2], entity[crlf+4:], []byte("\r\n"), true
+	case lf >= 0:
+		return entity[:lf+1], entity[lf+2:], []byte("\n"), true
+	}
+	return nil, nil, nil, false
+}
+
+// parseHeader parses a raw header block, leniently
+func parseHeader(raw []byte) textproto.MIMEHeader {
+	reader := textproto.NewReader(bufio.NewReader(io.MultiReader(bytes.NewReader(raw), strings.NewReader("\r\n"))))
+	header, _ := reader.ReadMIMEHeader()
+	if header == nil {
+		header = textproto.MIMEHeader{}
+	}
+	return header
+}
+
+// isText reports whether an entity is text that can be scanned
+func isText(mediaType string, params map[string]string, header textproto.MIMEHeader) bool {
+	if strings.HasPrefix(mediaType, "text/") || textTypes[mediaType] || strings.HasSuffix(mediaType, "+json") || strings.HasSuffix(mediaType, "+xml") {
+		return true
+	}
+	if mediaType != "application/octet-stream" {
+		return false
+	}
+	name := params["name"]
+	if _, dispParams, err := mime.ParseMediaType(header.Get("Content-Disposition")); err == nil && dispParams["filename"] != "" {
+		name = dispParams["filename"]
+	}
+	return textExtensions[strings.ToLower(path.Ext(name))]
+}
+
+// decode decodes a body with its transfer encoding
+func decode(body []byte, encoding string) ([]byte, bool) {
+	switch encoding {
+	case "base64":
+		decoded, err := base64.StdEncoding.DecodeString(string(bytes.Join(bytes.Fields(body), nil)))
+		return decoded, err == nil
+	case "quoted-printable":
+		decoded, err := io.ReadAll(quotedprintable.NewReader(bytes.NewReader(body)))
+		return decoded, err == nil
+	case "", "7bit", "8bit", "binary":
+		return body, true
+	}
+	return nil, false
+}
+
+// encode encodes a redacted body with the transfer encoding of original,
+// keeping its trailing line break
+func encode(text []byte, encoding string, original []byte) []byte {
+	newline := "\r\n"
+	if !bytes.Contains(original, []byte("\r\n")) && bytes.Contains(original, []byte("\n")) {
+		newline = "\n"
+	}
+	var b bytes.Buffer
+	switch encoding {
+	case "base64":
+		encoded := base64.StdEncoding.EncodeToString(text)
+		for len(encoded) > 76 {
+			b.WriteString(encoded[:76] + newline)
+			encoded = encoded[76:]
+		}
+		b.WriteString(encoded)
+		if bytes.HasSuffix(original, []byte("\n")) {
+			b.WriteString(newline)
+		}
+	case "quoted-printable":
+		w := quotedprintable.NewWriter(&b)
+		w.Write(text)
+		w.Close()
+	default:
+		return text
+	}
+	return b.Bytes()
+}
+
+// concat joins byte slices into a new one
+func concat(parts ...[]byte) []byte {
+	return bytes.Join(parts, nil)
+}
+
+// contains reports whether list contains s
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
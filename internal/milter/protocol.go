@@ -0,0 +1,109 @@
+package milter
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// Commands sent by the MTA
+const (
+	cmdAbort   = 'A'
+	cmdBody    = 'B'
+	cmdConnect = 'C'
+	cmdMacro   = 'D'
+	cmdEOB     = 'E'
+	cmdHelo    = 'H'
+	cmdQuitNC  = 'K'
+	cmdHeader  = 'L'
+	cmdMail    = 'M'
+	cmdEOH     = 'N'
+	cmdOptNeg  = 'O'
+	cmdQuit    = 'Q'
+	cmdRcpt    = 'R'
+	cmdData    = 'T'
+	cmdUnknown = 'U'
+)
+
+// Responses sent to the MTA
+const (
+	respAddHeader = 'h'
+	respChgHeader = 'm'
+	respReplBody  = 'b'
+	respContinue  = 'c'
+	respOptNeg    = 'O'
+	respReplyCode = 'y'
+)
+
+// Actions the milter may take, negotiated with the MTA
+const (
+	actAddHeaders = 0x01
+	actChgBody    = 0x02
+	actChgHeaders = 0x10
+)
+
+// Events the milter asks the MTA not to send
+const (
+	protoNoConnect = 0x01
+	protoNoHelo    = 0x02
+	protoNoUnknown = 0x100
+	protoNoData    = 0x200
+)
+
+const (
+	// minVersion and maxVersion bound the protocol versions spoken
+	minVersion = 2
+	maxVersion = 6
+
+	// maxPacket bounds the size of a packet from the MTA
+	maxPacket = 1 << 20
+
+	// chunkSize bounds the body sent in one replace body packet
+	chunkSize = 65535
+)
+
+// readPacket reads a command and its data
+func readPacket(r io.Reader) (byte, []byte, error) {
+	var size uint32
+	if err := binary.Read(r, binary.BigEndian, &size); err != nil {
+		return 0, nil, err
+	}
+	if size == 0 || size > maxPacket {
+		return 0, nil, fmt.Errorf("invalid packet size %d", size)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return 0, nil, err
+	}
+	return data[0], data[1:], nil
+}
+
+// writePacket writes a response and its data
+func writePacket(w io.Writer, code byte, data []byte) error {
+	packet := make([]byte, 5, 5+len(data))
+	binary.BigEndian.PutUint32(packet, uint32(len(data)+1))
+	packet[4] = code
+	_, err := w.Write(append(packet, data...))
+	return err
+}
+
+// cstrings splits data into its NUL terminated strings
+func cstrings(data []byte) []string {
+	fields := bytes.Split(bytes.TrimSuffix(data, []byte{0}), []byte{0})
+	out := make([]string, len(fields))
+	for i, f := range fields {
+		out[i] = string(f)
+	}
+	return out
+}
+
+// cstringData joins strings into NUL terminated data
+func cstringData(strs ...string) []byte {
+	var b bytes.Buffer
+	for _, s := range strs {
+		b.WriteString(s)
+		b.WriteByte(0)
+	}
+	return b.Bytes()
+}
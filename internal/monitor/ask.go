@@ -0,0 +1,135 @@
+package monitor
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+)
+
+// ErrHoldNotFound is returned when restoring an unknown or expired hold
+var ErrHoldNotFound = errors.New("held copy not found or expired")
+
+// Hold is clipboard content redacted by an ask detector. Its original is
+// kept in memory until restored, replaced by a newer copy, or expired.
+type Hold struct {
+	ID       string
+	Source   string    // "clipboard" or "primary"
+	Types    []string  // Detected types, sorted
+	Filtered string    // Redacted text put on the clipboard
+	Expires  time.Time // When the original is discarded
+}
+
+// hold is a Hold with its original content
+type hold struct {
+	Hold
+	src      *source
+	original content
+	stop     func() bool // Stops the expiry
+}
+
+// hold keeps the original of content just redacted on src for the ask
+// timeout, replacing any earlier hold on src
+func (m *Monitor) hold(src *source, original content, filtered string, types []string, cfg *config.Config) {
+	timeout := time.Duration(cfg.AskTimeoutSeconds) * time.Second
+
+	m.mu.Lock()
+	for id, h := range m.holds {
+		if h.src == src {
+			h.stop()
+			delete(m.holds, id)
+		}
+	}
+	m.holdSeq++
+	id := strconv.Itoa(m.holdSeq)
+	h := &hold{
+		Hold:     Hold{ID: id, Source: src.name, Types: uniqueSorted(types), Filtered: filtered, Expires: m.clock.Now().Add(timeout)},
+		src:      src,
+		original: original,
+	}
+	h.stop = m.clock.AfterFunc(timeout, func() { m.expire(id) })
+	m.holds[id] = h
+	m.mu.Unlock()
+
+	m.logger.Warn("Sensitive data redacted, the original can be restored",
+		"source", src.name, "hold", id, "types", h.Types, "timeout", timeout.String())
+}
+
+// expire discards a hold that was not restored in time
+func (m *Monitor) expire(id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.holds, id)
+}
+
+// dropHolds discards the holds on src once its text is no longer the
+// redacted text, so a newer copy is never overwritten by a restore
+func (m *Monitor) dropHolds(src *source, text string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for id, h := range m.holds {
+		if h.src == src && h.Filtered != text {
+			h.stop()
+			delete(m.holds, id)
+		}
+	}
+}
+
+// takeRestored reports whether text was put back by Restore, which is left
+// unfiltered once
+func (m *Monitor) takeRestored(src *source, text string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if src.restored == "" || src.restored != text {
+		return false
+	}
+	src.restored = ""
+	return true
+}
+
+// Holds returns the copies whose original may still be restored, oldest
+// first
+func (m *Monitor) Holds() []Hold {
+	m.mu.Lock()
+	holds := make([]Hold, 0, len(m.holds))
+	for _, h := range m.holds {
+		holds = append(holds, h.Hold)
+	}
+	m.mu.Unlock()
+
+	sort.Slice(holds, func(i, j int) bool { return holds[i].Expires.Before(holds[j].Expires) })
+	return holds
+}
+
+// Restore puts the original of a held copy back on its clipboard, approving
+// it to be pasted unfiltered
+func (m *Monitor) Restore(id string) error {
+	m.mu.Lock()
+	h, ok := m.holds[id]
+	if ok {
+		h.stop()
+		delete(m.holds, id)
+		h.src.restored = h.original.text
+	}
+	m.mu.Unlock()
+	if !ok {
+		return ErrHoldNotFound
+	}
+
+	m.logger.Info("Original clipboard content restored", "source", h.Source, "hold", id, "types", h.Types)
+	return m.put(h.src, h.original.text, h.original.html)
+}
+
+// uniqueSorted sorts types and removes duplicates
+func uniqueSorted(types []string) []string {
+	sort.Strings(types)
+	unique := types[:0]
+	for i, t := range types {
+		if i == 0 || t != types[i-1] {
+			unique = append(unique, t)
+		}
+	}
+	return unique
+}
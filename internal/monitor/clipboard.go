@@ -0,0 +1,205 @@
+package monitor
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/atotto/clipboard"
+)
+
+// ErrSelectionUnsupported is returned when the primary selection cannot be
+// accessed on this system
+var ErrSelectionUnsupported = errors.New("primary selection not supported: install xclip, xsel or wl-clipboard")
+
+// Clipboard is the clipboard backend watched by the monitor
+type Clipboard interface {
+	ReadAll() (string, error)
+	WriteAll(text string) error
+}
+
+// systemClipboard is the operating system clipboard
+type systemClipboard struct{}
+
+func (systemClipboard) ReadAll() (string, error) {
+	return clipboard.ReadAll()
+}
+
+func (systemClipboard) WriteAll(text string) error {
+	return clipboard.WriteAll(text)
+}
+
+// FormatReader is implemented by clipboards that can report the formats on
+// offer besides text, as MIME types or X11 targets
+type FormatReader interface {
+	Formats() ([]string, error)
+	ReadFormat(format string) (string, error)
+}
+
+// HTMLWriter is implemented by clipboards that can write text together with
+// an HTML representation. On other clipboards writing the filtered text
+// drops the HTML.
+type HTMLWriter interface {
+	WriteHTML(text, html string) error
+}
+
+// FormatHTML is the format of HTML content
+const FormatHTML = "text/html"
+
+// textFormats are the formats holding plain text
+var textFormats = map[string]bool{
+	"text/plain":               true,
+	"text/plain;charset=utf-8": true,
+	"UTF8_STRING":              true,
+	"STRING":                   true,
+	"TEXT":                     true,
+	"COMPOUND_TEXT":            true,
+}
+
+// hasFormat reports whether formats contains format
+func hasFormat(formats []string, format string) bool {
+	for _, f := range formats {
+		if f == format {
+			return true
+		}
+	}
+	return false
+}
+
+// hasText reports whether formats include plain text
+func hasText(formats []string) bool {
+	for _, f := range formats {
+		if textFormats[f] {
+			return true
+		}
+	}
+	return false
+}
+
+// MockContent is the content of a MockClipboard
+type MockContent struct {
+	Text    string
+	HTML    string   // HTML representation, if any
+	Files   string   // text/uri-list of copied files, if any
+	Formats []string // Other formats, e.g. "image/png"
+}
+
+// MockClipboard is an in-memory Clipboard for tests. Content sent with Copy
+// or CopyContent becomes the clipboard content on the next read, and the
+// text of every write is delivered on Written.
+type MockClipboard struct {
+	mu      sync.Mutex
+	content MockContent
+	err     error
+	copies  chan MockContent
+	written chan string
+}
+
+// NewMockClipboard creates an empty mock clipboard
+func NewMockClipboard() *MockClipboard {
+	return &MockClipboard{
+		copies:  make(chan MockContent, 16),
+		written: make(chan string, 16),
+	}
+}
+
+// Copy simulates the user copying text
+func (c *MockClipboard) Copy(text string) {
+	c.copies <- MockContent{Text: text}
+}
+
+// CopyContent simulates the user copying content in several formats
+func (c *MockClipboard) CopyContent(content MockContent) {
+	c.copies <- content
+}
+
+// Written delivers the text written to the clipboard, in order
+func (c *MockClipboard) Written() <-chan string {
+	return c.written
+}
+
+// SetError makes reads fail with err until it is cleared with nil
+func (c *MockClipboard) SetError(err error) {
+	c.mu.Lock()
+	c.err = err
+	c.mu.Unlock()
+}
+
+// Content returns the current clipboard text
+func (c *MockClipboard) Content() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.content.Text
+}
+
+// HTML returns the current HTML representation
+func (c *MockClipboard) HTML() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.content.HTML
+}
+
+// receive takes the latest copied content; c.mu must be held
+func (c *MockClipboard) receive() {
+	select {
+	case content := <-c.copies:
+		c.content = content
+	default:
+	}
+}
+
+func (c *MockClipboard) ReadAll() (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return "", c.err
+	}
+	c.receive()
+	return c.content.Text, nil
+}
+
+func (c *MockClipboard) WriteAll(text string) error {
+	return c.WriteHTML(text, "")
+}
+
+// Formats lists the formats of the current content
+func (c *MockClipboard) Formats() ([]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err != nil {
+		return nil, c.err
+	}
+	c.receive()
+
+	formats := append([]string{}, c.content.Formats...)
+	if c.content.Text != "" {
+		formats = append(formats, "text/plain")
+	}
+	if c.content.HTML != "" {
+		formats = append(formats, FormatHTML)
+	}
+	if c.content.Files != "" {
+		formats = append(formats, FormatURIList)
+	}
+	return formats, nil
+}
+
+// ReadFormat returns the HTML representation or the file list
+func (c *MockClipboard) ReadFormat(format string) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	switch format {
+	case FormatHTML:
+		return c.content.HTML, nil
+	case FormatURIList:
+		return c.content.Files, nil
+	}
+	return "", errors.New("mock clipboard only reads HTML and file lists")
+}
+
+func (c *MockClipboard) WriteHTML(text, html string) error {
+	c.mu.Lock()
+	c.content = MockContent{Text: text, HTML: html}
+	c.mu.Unlock()
+	c.written <- text
+	return nil
+}
@@ -0,0 +1,14 @@
+//go:build !(dragonfly || freebsd || linux || netbsd || openbsd || solaris)
+
+package monitor
+
+// NewPrimarySelection returns ErrSelectionUnsupported: only X11 and Wayland
+// have a primary selection
+func NewPrimarySelection() (Clipboard, error) {
+	return nil, ErrSelectionUnsupported
+}
+
+// newSystemClipboard returns the clipboard, which is only read as text
+func newSystemClipboard() Clipboard {
+	return systemClipboard{}
+}
@@ -0,0 +1,175 @@
+//go:build dragonfly || freebsd || linux || netbsd || openbsd || solaris
+
+package monitor
+
+import (
+	"errors"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// errNoFormats is returned by tools that cannot list the clipboard formats
+var errNoFormats = errors.New("clipboard formats cannot be listed with xsel")
+
+// Selection tools, in order of preference after wl-clipboard on Wayland
+const (
+	toolWayland = "wl-clipboard"
+	toolXclip   = "xclip"
+	toolXsel    = "xsel"
+)
+
+// commandClipboard is an X11/Wayland selection ("clipboard" or "primary")
+// accessed with the same command line tools the clipboard library uses.
+// Besides text it can list and read the other formats on offer and write
+// HTML, but like those tools only one format at a time.
+type commandClipboard struct {
+	tool      string
+	selection string
+}
+
+// NewPrimarySelection returns the primary selection as a Clipboard, or
+// ErrSelectionUnsupported when no selection tool is installed
+func NewPrimarySelection() (Clipboard, error) {
+	c, ok := newCommandClipboard("primary")
+	if !ok {
+		return nil, ErrSelectionUnsupported
+	}
+	return c, nil
+}
+
+// newSystemClipboard returns the clipboard, using the selection tools
+// directly when installed so formats other than text can be inspected
+func newSystemClipboard() Clipboard {
+	if c, ok := newCommandClipboard("clipboard"); ok {
+		return c
+	}
+	return systemClipboard{}
+}
+
+// newCommandClipboard finds a tool to access selection
+func newCommandClipboard(selection string) (*commandClipboard, bool) {
+	if os.Getenv("WAYLAND_DISPLAY") != "" && installed("wl-paste") && installed("wl-copy") {
+		return &commandClipboard{tool: toolWayland, selection: selection}, true
+	}
+	for _, tool := range []string{toolXclip, toolXsel} {
+		if installed(tool) {
+			return &commandClipboard{tool: tool, selection: selection}, true
+		}
+	}
+	return nil, false
+}
+
+// installed reports whether the command is on the PATH
+func installed(command string) bool {
+	_, err := exec.LookPath(command)
+	return err == nil
+}
+
+// pasteCommand returns the command printing the selection in format, or
+// as text when format is empty
+func (c *commandClipboard) pasteCommand(format string) *exec.Cmd {
+	switch c.tool {
+	case toolWayland:
+		args := []string{"--no-newline"}
+		if c.selection == "primary" {
+			args = append(args, "--primary")
+		}
+		if format != "" {
+			args = append(args, "--type", format)
+		}
+		return exec.Command("wl-paste", args...)
+	case toolXclip:
+		args := []string{"-out", "-selection", c.selection}
+		if format != "" {
+			args = append(args, "-target", format)
+		}
+		return exec.Command("xclip", args...)
+	default:
+		return exec.Command("xsel", "--output", "--"+c.selection)
+	}
+}
+
+func (c *commandClipboard) ReadAll() (string, error) {
+	out, err := c.pasteCommand("").Output()
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
+
+func (c *commandClipboard) WriteAll(text string) error {
+	return c.copy(text, "")
+}
+
+// WriteHTML writes html as text/html, so pasting into rich text editors
+// keeps the filtered formatting. The tools offer one format, so text is
+// only written when the HTML cannot be, with xsel.
+func (c *commandClipboard) WriteHTML(text, html string) error {
+	if c.tool == toolXsel {
+		return c.copy(text, "")
+	}
+	return c.copy(html, FormatHTML)
+}
+
+// copy writes data to the selection in format, or as text when format is
+// empty
+func (c *commandClipboard) copy(data, format string) error {
+	var cmd *exec.Cmd
+	switch c.tool {
+	case toolWayland:
+		args := []string{}
+		if c.selection == "primary" {
+			args = append(args, "--primary")
+		}
+		if format != "" {
+			args = append(args, "--type", format)
+		}
+		cmd = exec.Command("wl-copy", args...)
+	case toolXclip:
+		args := []string{"-in", "-selection", c.selection}
+		if format != "" {
+			args = append(args, "-target", format)
+		}
+		cmd = exec.Command("xclip", args...)
+	default:
+		cmd = exec.Command("xsel", "--input", "--"+c.selection)
+	}
+	cmd.Stdin = strings.NewReader(data)
+	return cmd.Run()
+}
+
+// Formats lists the MIME types and X11 targets on offer
+func (c *commandClipboard) Formats() ([]string, error) {
+	var cmd *exec.Cmd
+	switch c.tool {
+	case toolWayland:
+		if c.selection == "primary" {
+			cmd = exec.Command("wl-paste", "--list-types", "--primary")
+		} else {
+			cmd = exec.Command("wl-paste", "--list-types")
+		}
+	case toolXclip:
+		cmd = exec.Command("xclip", "-out", "-selection", c.selection, "-target", "TARGETS")
+	default:
+		return nil, errNoFormats
+	}
+
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(out)), nil
+}
+
+// ReadFormat returns the content in format
+func (c *commandClipboard) ReadFormat(format string) (string, error) {
+	if c.tool == toolXsel {
+		return "", errNoFormats
+	}
+	out, err := c.pasteCommand(format).Output()
+	if err != nil {
+		return "", err
+	}
+	return string(out), nil
+}
@@ -0,0 +1,31 @@
+package monitor
+
+import "time"
+
+// Clock tells the time and waits for the polling loop, the ask holds and the
+// configuration debounce, so that tests can drive them without sleeping
+type Clock interface {
+	Now() time.Time
+
+	// After returns a channel receiving the time once d has elapsed and a
+	// function stopping the wait early
+	After(d time.Duration) (<-chan time.Time, func() bool)
+
+	// AfterFunc calls f once d has elapsed and returns a function stopping
+	// the call early, reporting whether it was still pending
+	AfterFunc(d time.Duration, f func()) func() bool
+}
+
+// systemClock is the Clock of the system
+type systemClock struct{}
+
+func (systemClock) Now() time.Time { return time.Now() }
+
+func (systemClock) After(d time.Duration) (<-chan time.Time, func() bool) {
+	timer := time.NewTimer(d)
+	return timer.C, timer.Stop
+}
+
+func (systemClock) AfterFunc(d time.Duration, f func()) func() bool {
+	return time.AfterFunc(d, f).Stop
+}
@@ -0,0 +1,117 @@
+package monitor
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+	"github.com/happytaoer/prompt-security/internal/filter"
+)
+
+// FormatURIList is the format of copied files
+const FormatURIList = "text/uri-list"
+
+// binarySniffLen is how much of a file is checked for NUL bytes to tell
+// binary files, which are not scanned, from text
+const binarySniffLen = 8000
+
+// fileFinding is a copied file holding sensitive data
+type fileFinding struct {
+	path  string
+	types []string // Detected types, sorted
+}
+
+// parseURIList returns the local paths in a text/uri-list, skipping comments
+// and URIs other than file://
+func parseURIList(list string) []string {
+	var paths []string
+	for _, line := range strings.Split(list, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		u, err := url.Parse(line)
+		if err != nil || u.Scheme != "file" || u.Path == "" {
+			continue
+		}
+		paths = append(paths, filepath.FromSlash(u.Path))
+	}
+	return paths
+}
+
+// checkFiles scans the files in a copied uri list and warns about, or
+// blocks, those holding sensitive data. It reports whether the clipboard
+// was replaced by a notice.
+func (m *Monitor) checkFiles(src *source, uriList string, cfg *config.Config) bool {
+	findings := scanFiles(m.detectors(), parseURIList(uriList), cfg.FileScanMaxBytes)
+	if len(findings) == 0 {
+		return false
+	}
+
+	for _, f := range findings {
+		m.logger.Warn("Copied file contains sensitive data",
+			"source", src.name, "path", f.path, "types", f.types, "mode", cfg.FileScanMode)
+	}
+	if cfg.FileScanMode != config.FileScanBlock {
+		return false
+	}
+
+	notice := blockNotice(findings)
+	if m.logCallback != nil {
+		m.logCallback(src.app, uriList, notice, nil)
+	}
+	if err := src.clipboard.WriteAll(notice); err != nil {
+		m.logger.Error("Error writing to clipboard", "source", src.name, "error", err)
+	}
+	return true
+}
+
+// scanFiles scans up to maxBytes of each regular text file in paths
+func scanFiles(ds *filter.DetectorSet, paths []string, maxBytes int) []fileFinding {
+	var findings []fileFinding
+	for _, path := range paths {
+		data, err := readHead(path, maxBytes)
+		if err != nil || bytes.IndexByte(data[:min(len(data), binarySniffLen)], 0) >= 0 {
+			continue
+		}
+
+		_, _, summary := ds.Filter(string(data))
+		if len(summary.Replacements) == 0 {
+			continue
+		}
+		findings = append(findings, fileFinding{path: path, types: summary.Types()})
+	}
+	return findings
+}
+
+// readHead reads up to maxBytes of a regular file
+func readHead(path string, maxBytes int) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if !info.Mode().IsRegular() {
+		return nil, fmt.Errorf("%s is not a regular file", path)
+	}
+	return io.ReadAll(io.LimitReader(f, int64(maxBytes)))
+}
+
+// blockNotice is the clipboard text replacing blocked files
+func blockNotice(findings []fileFinding) string {
+	parts := make([]string, len(findings))
+	for i, f := range findings {
+		parts[i] = fmt.Sprintf("%s (%s)", filepath.Base(f.path), strings.Join(f.types, ", "))
+	}
+	return "[prompt-security] Copy blocked, files contain sensitive data: " + strings.Join(parts, "; ")
+}
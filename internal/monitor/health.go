@@ -0,0 +1,141 @@
+package monitor
+
+import (
+	"fmt"
+	"runtime/debug"
+	"time"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+)
+
+// Supervision delays. Clipboard read errors and restarts after a panic are
+// retried with a delay doubling from minBackoff up to maxBackoff.
+const (
+	minBackoff = time.Second
+	maxBackoff = 30 * time.Second
+
+	// stableRun is how long the loop must run after a restart before a new
+	// panic restarts it without delay again
+	stableRun = time.Minute
+
+	// heartbeatGrace is added to the expected loop period before a missing
+	// heartbeat marks the monitor as stalled
+	heartbeatGrace = 5 * time.Second
+)
+
+// Monitor health states
+const (
+	StatusOK      = "ok"      // Polling the clipboard normally
+	StatusBackoff = "backoff" // Retrying after clipboard errors or a crash
+	StatusStalled = "stalled" // No heartbeat within the expected period
+	StatusStopped = "stopped" // Run has not been started
+)
+
+// Health reports the supervision state of the monitor loop
+type Health struct {
+	Status            string
+	Running           bool
+	LastHeartbeat     time.Time     // Last iteration of the polling loop
+	Restarts          int           // Restarts after a panic
+	LastPanic         string        // Value of the last recovered panic
+	ConsecutiveErrors int           // Clipboard reads failed in a row
+	LastError         string        // Last clipboard read error
+	Backoff           time.Duration // Delay before the next retry, zero when polling normally
+}
+
+// Health returns the current supervision state
+func (m *Monitor) Health() Health {
+	m.mu.Lock()
+	h := m.health
+	m.mu.Unlock()
+
+	interval := time.Duration(m.snapshot().Config.MonitoringInterval) * time.Millisecond
+	if interval < config.MinMonitoringInterval*time.Millisecond {
+		interval = config.MinMonitoringInterval * time.Millisecond
+	}
+
+	switch {
+	case !h.Running:
+		h.Status = StatusStopped
+	case m.clock.Now().Sub(h.LastHeartbeat) > interval+h.Backoff+heartbeatGrace:
+		h.Status = StatusStalled
+	case h.Backoff > 0:
+		h.Status = StatusBackoff
+	default:
+		h.Status = StatusOK
+	}
+	return h
+}
+
+// setRunning records whether Run is active
+func (m *Monitor) setRunning(running bool) {
+	m.mu.Lock()
+	m.health.Running = running
+	m.mu.Unlock()
+}
+
+// heartbeat records an iteration of the polling loop
+func (m *Monitor) heartbeat() {
+	m.mu.Lock()
+	m.health.LastHeartbeat = m.clock.Now()
+	m.mu.Unlock()
+}
+
+// readFailed records a clipboard read error and returns how long to wait
+// before reading again
+func (m *Monitor) readFailed(err error) time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.health.ConsecutiveErrors++
+	m.health.LastError = err.Error()
+	m.health.Backoff = nextBackoff(m.health.Backoff)
+	m.health.LastHeartbeat = m.clock.Now()
+	return m.health.Backoff
+}
+
+// readSucceeded clears the clipboard error backoff
+func (m *Monitor) readSucceeded() {
+	m.mu.Lock()
+	m.health.ConsecutiveErrors = 0
+	m.health.Backoff = 0
+	m.mu.Unlock()
+}
+
+// crashed records a recovered panic and returns how long to wait before
+// restarting the loop; delay is the previous restart delay, or zero after a
+// stable run
+func (m *Monitor) crashed(value interface{}, delay time.Duration) time.Duration {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.health.Restarts++
+	m.health.LastPanic = fmt.Sprint(value)
+	m.health.Backoff = nextBackoff(delay)
+	m.health.LastHeartbeat = m.clock.Now()
+	return m.health.Backoff
+}
+
+// guard runs the polling loop, returning the value and stack of the panic
+// that stopped it, or nil when the monitor was stopped
+func (m *Monitor) guard() (recovered interface{}, stack []byte) {
+	defer func() {
+		if recovered = recover(); recovered != nil {
+			stack = debug.Stack()
+		}
+	}()
+	m.poll()
+	return nil, nil
+}
+
+// nextBackoff doubles delay within [minBackoff, maxBackoff]
+func nextBackoff(delay time.Duration) time.Duration {
+	delay *= 2
+	if delay < minBackoff {
+		return minBackoff
+	}
+	if delay > maxBackoff {
+		return maxBackoff
+	}
+	return delay
+}
@@ -1,80 +1,594 @@
 package monitor
 
 import (
+	"context"
+	"fmt"
 	"log/slog"
 	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 
-	"github.com/atotto/clipboard"
 	"github.com/happytaoer/prompt-security/internal/config"
+	"github.com/happytaoer/prompt-security/internal/desktop"
 	"github.com/happytaoer/prompt-security/internal/filter"
+	"github.com/happytaoer/prompt-security/internal/telemetry"
 )
 
-// LogCallback is a function type for logging filtered data
-type LogCallback func(originalText, filteredText string, replacements []filter.ReplacementInfo)
+// configDebounce is how long the monitor waits for a burst of configuration
+// changes, such as several saves in a row, to end before applying them
+const configDebounce = 500 * time.Millisecond
+
+// LogCallback is a function type for logging filtered data. app is the
+// application the data was copied from, empty if unknown.
+type LogCallback func(app, originalText, filteredText string, replacements []filter.ReplacementInfo)
+
+// applied is a snapshot of the configuration in use with the detectors of
+// the engine at the time, swapped in together so that a scan never pairs
+// settings with detectors from another configuration
+type applied struct {
+	config    *config.CompiledConfig
+	detectors *filter.DetectorSet
+}
+
+// Monitor watches the clipboard and filters sensitive data
+type Monitor struct {
+	manager     *config.Manager
+	current     atomic.Pointer[applied] // Configuration and detectors in use, see configChanged
+	engine      *filter.Engine
+	logCallback LogCallback
+	clipboard   source
+	selection   source // Primary selection, watched when enabled
+	paused      atomic.Bool
+	off         atomic.Bool // Set while the active profile disables detection
+	logger      *slog.Logger
+
+	// foreground returns the focused application, replaced in tests
+	foreground func() (string, error)
+
+	// clock paces the polling loop, times its heartbeats and expires holds
+	clock Clock
+
+	// debounce delays applying configuration changes, replaced in tests
+	debounce time.Duration
+
+	mu      sync.Mutex
+	health  Health
+	holds   map[string]*hold // Originals the user may restore, by ID
+	holdSeq int
+	pending func() bool // Stops applying the latest configuration when a burst ends
+
+	stop     chan struct{} // Closed by Stop
+	stopOnce sync.Once
+}
+
+// source is a clipboard watched by the monitor and its scan state
+type source struct {
+	name      string // "clipboard" or "primary", reported in logs
+	clipboard Clipboard
+
+	// last is the last content seen; it survives restarts so content that
+	// crashed the loop is not scanned again
+	last content
+
+	// app is the application focused when last was copied, "" if unknown
+	app string
+
+	// clean is the last content fully scanned by cleanSet without needing
+	// any filtering, the base for incremental scans
+	clean    string
+	cleanSet *filter.DetectorSet
+
+	failing bool // Set while reads fail, so the error is logged once
+
+	// restored is original text put back by Restore, left unfiltered when
+	// read next; guarded by Monitor.mu
+	restored string
+}
+
+// content is what was read from a clipboard
+type content struct {
+	text  string
+	html  string // HTML representation, if any
+	files string // text/uri-list of copied files, if any
+}
+
+// unavailable is a Clipboard that cannot be read
+type unavailable struct{ err error }
+
+func (u unavailable) ReadAll() (string, error) { return "", u.err }
+func (u unavailable) WriteAll(string) error    { return u.err }
+
+// New creates a clipboard monitor. The config manager supplies monitoring
+// settings, which the monitor follows, and the engine supplies the compiled
+// detectors, which are expected to be kept up to date by the caller, as are
+// profile changes (SetProfile). Both are applied together, see configChanged.
+func New(manager *config.Manager, engine *filter.Engine, logCallback LogCallback) *Monitor {
+	m := &Monitor{
+		manager:     manager,
+		engine:      engine,
+		logCallback: logCallback,
+		clipboard:   source{name: "clipboard", clipboard: newSystemClipboard()},
+		selection:   source{name: "primary"},
+		logger:      slog.New(slog.NewJSONHandler(os.Stdout, nil)),
+		foreground:  desktop.FocusedApp,
+		clock:       systemClock{},
+		debounce:    configDebounce,
+		holds:       make(map[string]*hold),
+		stop:        make(chan struct{}),
+	}
+	if selection, err := NewPrimarySelection(); err == nil {
+		m.selection.clipboard = selection
+	} else {
+		m.selection.clipboard = unavailable{err}
+	}
+	profile, _ := manager.ActiveProfile()
+	m.off.Store(profile == config.ProfileOff)
+	m.apply()
+	manager.OnChange(m.configChanged)
+	return m
+}
+
+// configChanged is notified of configuration changes. The latest snapshot
+// and the detectors of the engine are swapped in once no change followed for
+// the debounce delay, so a burst of saves is applied once rather than
+// mid-burst, and the detectors change along with the settings.
+func (m *Monitor) configChanged(config.Config) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.stopped() {
+		return
+	}
+	if m.pending != nil {
+		m.pending()
+	}
+	m.pending = m.clock.AfterFunc(m.debounce, m.apply)
+}
+
+// apply swaps in the latest configuration with the detectors of the engine,
+// which follows the manager without delay
+func (m *Monitor) apply() {
+	ds := m.engine.Detectors()
+	m.current.Store(&applied{config: m.manager.Snapshot(), detectors: ds})
+}
+
+// snapshot returns the configuration in use
+func (m *Monitor) snapshot() *config.CompiledConfig {
+	return m.current.Load().config
+}
+
+// detectors returns the detectors in use
+func (m *Monitor) detectors() *filter.DetectorSet {
+	return m.current.Load().detectors
+}
+
+// SetClipboard replaces the system clipboard, e.g. with a MockClipboard.
+// It must be called before Run.
+func (m *Monitor) SetClipboard(c Clipboard) {
+	m.clipboard.clipboard = c
+}
+
+// SetSelection replaces the primary selection. It must be called before Run.
+func (m *Monitor) SetSelection(c Clipboard) {
+	m.selection.clipboard = c
+}
+
+// SetClock replaces the system clock pacing the polling loop, holds and
+// configuration changes, e.g. in tests. It must be called before Run and
+// before the configuration changes.
+func (m *Monitor) SetClock(c Clock) {
+	m.clock = c
+}
+
+// Stop makes Run return after the current iteration and drops pending
+// configuration changes
+func (m *Monitor) Stop() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.stopOnce.Do(func() { close(m.stop) })
+	if m.pending != nil {
+		m.pending()
+	}
+}
+
+// stopped reports whether Stop was called
+func (m *Monitor) stopped() bool {
+	select {
+	case <-m.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// SetProfile is notified when a schedule switches the detection profile
+func (m *Monitor) SetProfile(profile, schedule string) {
+	m.off.Store(profile == config.ProfileOff)
+	m.logger.Info("Detection profile changed", "profile", profile, "schedule", schedule)
+}
+
+// Pause stops filtering until Resume is called. Content copied while paused
+// is left untouched.
+func (m *Monitor) Pause() {
+	m.paused.Store(true)
+}
+
+// Resume resumes filtering after Pause
+func (m *Monitor) Resume() {
+	m.paused.Store(false)
+}
+
+// Paused reports whether the monitor is paused
+func (m *Monitor) Paused() bool {
+	return m.paused.Load()
+}
 
 // ClipboardWithManager starts monitoring with a config manager for dynamic reload
 func ClipboardWithManager(manager *config.Manager, logCallback LogCallback) {
-	// Setup JSON logger
-	jsonHandler := slog.NewJSONHandler(os.Stdout, nil)
-	logger := slog.New(jsonHandler)
+	engine := filter.NewManagedEngine(manager)
+	m := New(manager, engine, logCallback)
+	manager.OnProfileChange(m.SetProfile)
+	m.Run()
+}
+
+// Run starts monitoring the clipboard, blocking until Stop is called. The
+// polling loop is supervised: a panic is logged and the loop restarted after
+// a backoff.
+func (m *Monitor) Run() {
+	logger := m.logger
 
 	logger.Info("Starting clipboard monitoring with dynamic config reload...")
 	logger.Info("Press Ctrl+C to stop")
 
-	var lastContent string
+	m.setRunning(true)
+	defer m.setRunning(false)
+
+	var delay time.Duration
 	for {
-		// Get current config from manager
-		cfg := manager.Get()
-
-		content, err := clipboard.ReadAll()
-		if err != nil {
-			logger.Error("Error reading clipboard", "error", err)
-			time.Sleep(1 * time.Second)
-			continue
+		started := m.clock.Now()
+		recovered, stack := m.guard()
+		if recovered == nil {
+			return
+		}
+		if m.clock.Now().Sub(started) >= stableRun {
+			delay = 0
 		}
+		delay = m.crashed(recovered, delay)
+		logger.Error("Clipboard monitor crashed, restarting",
+			"panic", fmt.Sprint(recovered), "stack", string(stack), "retry_in", delay.String())
+		if !m.sleep(delay) {
+			return
+		}
+	}
+}
+
+// poll reads and filters the clipboard and, when enabled, the primary
+// selection until stopped
+func (m *Monitor) poll() {
+	logger := m.logger
+
+	ctx := context.Background()
+	for {
+		m.heartbeat()
 
-		// Only process if content has changed
-		if content != lastContent && content != "" {
-			lastContent = content
+		// Get current config, swapped in atomically on changes
+		cfg := &m.snapshot().Config
 
-			// Filter sensitive data with current config
-			filtered, changed, replacementSummary := filter.SensitiveData(content, cfg)
+		if cfg.MonitorClipboard {
+			if err := m.watch(ctx, &m.clipboard, cfg); err != nil {
+				delay := m.readFailed(err)
+				logger.Error("Error reading clipboard", "error", err, "retry_in", delay.String())
+				if !m.sleep(delay) {
+					return
+				}
+				continue
+			}
+		}
+		m.readSucceeded()
 
-			// If content was filtered, update clipboard
-			if changed {
-				updateClipboardWithNotification(content, filtered, cfg, replacementSummary, logCallback)
+		// The selection changes with every mouse selection and may be
+		// empty, so its errors are only logged when they start
+		if cfg.MonitorPrimarySelection {
+			err := m.watch(ctx, &m.selection, cfg)
+			if err != nil && !m.selection.failing {
+				logger.Warn("Error reading primary selection", "error", err)
 			}
+			m.selection.failing = err != nil
 		}
 
 		// Sleep to avoid high CPU usage (use current config's interval)
-		time.Sleep(time.Duration(cfg.MonitoringInterval) * time.Millisecond)
+		interval := cfg.MonitoringInterval
+		if interval < config.MinMonitoringInterval {
+			interval = config.MinMonitoringInterval
+		}
+		if !m.sleep(time.Duration(interval) * time.Millisecond) {
+			return
+		}
+	}
+}
+
+// watch reads src and filters its content if it changed. The HTML
+// representation, when the clipboard offers one, is filtered too; it is
+// written back if the clipboard supports it and dropped otherwise. A match
+// of a blocking detector replaces the whole content with the block message.
+// Copied files are scanned according to the file scan mode.
+func (m *Monitor) watch(ctx context.Context, src *source, cfg *config.Config) error {
+	_, done := telemetry.StartClipboardRead(ctx)
+	c, err := read(src.clipboard, cfg.FileScanMode != config.FileScanOff, src.last)
+	done(len(c.text)+len(c.html)+len(c.files), err)
+	if err != nil {
+		return err
+	}
+
+	// Only process if content has changed
+	if c == src.last || (c.text == "" && c.files == "") {
+		return nil
+	}
+	src.last = c
+	m.dropHolds(src, c.text)
+	if m.takeRestored(src, c.text) {
+		return nil
+	}
+
+	// Filter sensitive data with current config unless paused or switched
+	// off by a schedule
+	if m.Paused() || m.off.Load() {
+		return nil
+	}
+
+	// Tell the source application now, before a long scan gives the user
+	// time to switch windows
+	src.app = m.sourceApp()
+
+	if c.files != "" && m.checkFiles(src, c.files, cfg) {
+		return nil
+	}
+	if c.text == "" {
+		return nil
+	}
+
+	filtered, changed, replacementSummary := m.scan(ctx, src, c.text, cfg)
+	filteredHTML, htmlChanged := c.html, false
+	var htmlSummary filter.ReplacementSummary
+	if c.html != "" {
+		var ok bool
+		filteredHTML, htmlChanged, htmlSummary, ok = m.scanHTML(ctx, c.html, cfg)
+		if !ok {
+			// Never write back HTML that was not scanned
+			filteredHTML = ""
+		}
+	}
+
+	textAction, htmlAction := replacementSummary.Action(), htmlSummary.Action()
+	if textAction == config.ActionBlock || htmlAction == config.ActionBlock {
+		m.block(src, c, cfg, replacementSummary, htmlSummary)
+		return nil
+	}
+
+	// Log the HTML when only it held sensitive data, e.g. in a link. Matches
+	// of log-only detectors are logged without changing the content.
+	detected := len(replacementSummary.Replacements) > 0
+	if len(htmlSummary.Replacements) > 0 && !detected {
+		m.notify(src, c.html, filteredHTML, cfg, htmlSummary)
+	}
+	if detected {
+		m.notify(src, c.text, filtered, cfg, replacementSummary)
+	}
+
+	// If content was filtered, update clipboard
+	if changed || htmlChanged {
+		m.write(src, filtered, filteredHTML)
+	}
+	if textAction == config.ActionAsk || htmlAction == config.ActionAsk {
+		types := append(replacementSummary.Types(), htmlSummary.Types()...)
+		m.hold(src, c, filtered, types, cfg)
+	}
+	return nil
+}
+
+// read returns the content of c: its text, its HTML representation and,
+// when files is set, the list of copied files. Content only offered in
+// other formats, like an image, reads as empty. Listing the formats and
+// reading each takes a process with the selection tools, so last is
+// returned as is when its text was read again.
+func read(c Clipboard, files bool, last content) (content, error) {
+	text, textErr := c.ReadAll()
+	reader, ok := c.(FormatReader)
+	if !ok {
+		return content{text: text}, textErr
+	}
+	if textErr == nil && text != "" && text == last.text {
+		return last, nil
+	}
+
+	// Read as text when the formats cannot be listed
+	formats, err := reader.Formats()
+	if err != nil {
+		return content{text: text}, textErr
+	}
+
+	// Unreadable HTML or file lists are treated as absent; they are
+	// dropped with any rewrite of the text anyway
+	var result content
+	if files && hasFormat(formats, FormatURIList) {
+		result.files, _ = reader.ReadFormat(FormatURIList)
+	}
+	if !hasText(formats) {
+		return result, nil
+	}
+
+	if textErr != nil {
+		return content{}, textErr
+	}
+	result.text = text
+	if hasFormat(formats, FormatHTML) {
+		result.html, _ = reader.ReadFormat(FormatHTML)
+	}
+	return result, nil
+}
+
+// sleep waits for d, reporting false if the monitor was stopped meanwhile
+func (m *Monitor) sleep(d time.Duration) bool {
+	elapsed, stop := m.clock.After(d)
+	defer stop()
+	select {
+	case <-elapsed:
+		return true
+	case <-m.stop:
+		return false
+	}
+}
+
+// scan filters content, handling content above the configured size limit
+// according to the large content mode. Content that is skipped or cannot be
+// scanned in time is reported as unchanged.
+func (m *Monitor) scan(ctx context.Context, src *source, content string, cfg *config.Config) (string, bool, filter.ReplacementSummary) {
+	ctx, done := telemetry.StartFilter(ctx, len(content))
+	ds := m.detectors()
+	filtered, changed, summary, mode, err := m.scanWith(ctx, src, ds, content, cfg)
+	done(mode, len(summary.Replacements), err)
+
+	src.clean, src.cleanSet = "", nil
+	if err == nil && mode != scanSkipped && !changed {
+		src.clean, src.cleanSet = content, ds
+	}
+	return filtered, changed, summary
+}
+
+// scanHTML filters the HTML representation of clipboard content, reporting
+// ok=false when it exceeds the size limit and was left unscanned
+func (m *Monitor) scanHTML(ctx context.Context, html string, cfg *config.Config) (string, bool, filter.ReplacementSummary, bool) {
+	if !withinLimit(len(html), cfg) {
+		m.logger.Warn("HTML clipboard content exceeds size limit, left unfiltered",
+			"size", len(html), "max_clipboard_bytes", cfg.MaxClipboardBytes)
+		return html, false, filter.ReplacementSummary{}, false
 	}
+
+	_, done := telemetry.StartFilter(ctx, len(html))
+	filtered, changed, summary := m.detectors().FilterHTML(html)
+	done(scanMarkup, len(summary.Replacements), nil)
+	return filtered, changed, summary, true
 }
 
-// updateClipboardWithNotification updates the clipboard with filtered content and shows notifications based on configuration
-func updateClipboardWithNotification(originalText, filteredText string, cfg config.Config, summary filter.ReplacementSummary, logCallback LogCallback) {
-	// Setup JSON logger
-	jsonHandler := slog.NewJSONHandler(os.Stdout, nil)
-	logger := slog.New(jsonHandler)
+// Scan modes reported to telemetry
+const (
+	scanFull        = "full"
+	scanIncremental = "incremental"
+	scanChunked     = "chunked"
+	scanSkipped     = "skipped"
+	scanMarkup      = "html"
+)
+
+// scanWith filters content read from src with ds and reports how it was
+// scanned. A non-nil error means the scan did not complete.
+func (m *Monitor) scanWith(ctx context.Context, src *source, ds *filter.DetectorSet, content string, cfg *config.Config) (string, bool, filter.ReplacementSummary, string, error) {
+	// Content growing from the last clean content only needs the added text
+	// scanned, as long as the configuration has not changed since
+	if cfg.IncrementalScan && src.cleanSet == ds && withinLimit(len(content)-len(src.clean), cfg) {
+		if filtered, changed, summary, ok := ds.FilterIncremental(src.clean, content); ok {
+			return filtered, changed, summary, scanIncremental, nil
+		}
+	}
 
+	if withinLimit(len(content), cfg) {
+		filtered, changed, summary := ds.Filter(content)
+		return filtered, changed, summary, scanFull, nil
+	}
+
+	if cfg.LargeContentMode == config.LargeContentSkip {
+		m.logger.Warn("Clipboard content exceeds size limit, left unfiltered",
+			"source", src.name, "size", len(content), "max_clipboard_bytes", cfg.MaxClipboardBytes)
+		return content, false, filter.ReplacementSummary{}, scanSkipped, nil
+	}
+
+	timeout := time.Duration(cfg.ScanTimeoutMs) * time.Millisecond
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	filtered, changed, summary, err := ds.FilterChunked(ctx, content, filter.DefaultChunkSize)
+	if err != nil {
+		m.logger.Warn("Clipboard scan timed out, content left unfiltered",
+			"source", src.name, "size", len(content), "timeout_ms", cfg.ScanTimeoutMs)
+		return content, false, filter.ReplacementSummary{}, scanChunked, err
+	}
+
+	m.logger.Info("Scanned large clipboard content in chunks",
+		"source", src.name, "size", len(content), "duration_ms", time.Since(start).Milliseconds())
+	return filtered, changed, summary, scanChunked, nil
+}
+
+// withinLimit reports whether size bytes may be scanned in one pass
+func withinLimit(size int, cfg *config.Config) bool {
+	return cfg.MaxClipboardBytes <= 0 || size <= cfg.MaxClipboardBytes
+}
+
+// notify logs filtered content and shows notifications based on configuration
+func (m *Monitor) notify(src *source, originalText, filteredText string, cfg *config.Config, summary filter.ReplacementSummary) {
 	if cfg.NotifyOnFilter {
 		// Log with structured data including replacements
 		if len(summary.Replacements) > 0 {
-			logger.Info("Sensitive data detected and filtered",
-				"replacements", summary.Replacements)
+			m.logger.Info("Sensitive data detected and filtered",
+				"source", src.name, "replacements", summary.Replacements)
 		} else {
-			logger.Info("Sensitive data detected and filtered")
+			m.logger.Info("Sensitive data detected and filtered", "source", src.name)
 		}
 	}
 
 	// Call the log callback if provided
-	if logCallback != nil {
-		logCallback(originalText, filteredText, summary.Replacements)
+	if m.logCallback != nil {
+		m.logCallback(src.app, originalText, filteredText, summary.Replacements)
 	}
+}
 
-	err := clipboard.WriteAll(filteredText)
+// sourceApp returns the focused application, which content just copied
+// most likely came from, or "" if it cannot be told, e.g. on Wayland
+func (m *Monitor) sourceApp() string {
+	app, err := m.foreground()
 	if err != nil {
-		logger.Error("Error writing to clipboard", "error", err)
+		return ""
+	}
+	return app
+}
+
+// block replaces the content of src with the block message because a
+// blocking detector matched its text or, failing that, its HTML
+func (m *Monitor) block(src *source, c content, cfg *config.Config, text, html filter.ReplacementSummary) {
+	original, summary := c.text, text
+	if text.Action() != config.ActionBlock {
+		original, summary = c.html, html
+	}
+	message := blockMessage(cfg.BlockMessage, summary.Types())
+
+	m.logger.Warn("Copy blocked, clipboard contains sensitive data",
+		"source", src.name, "types", summary.Types())
+	if m.logCallback != nil {
+		m.logCallback(src.app, original, message, summary.Replacements)
+	}
+	m.write(src, message, "")
+}
+
+// blockMessage fills the {types} placeholder of a block message
+func blockMessage(message string, types []string) string {
+	return strings.ReplaceAll(message, "{types}", strings.Join(types, ", "))
+}
+
+// write updates src with filtered content, logging failures
+func (m *Monitor) write(src *source, text, html string) {
+	if err := m.put(src, text, html); err != nil {
+		m.logger.Error("Error writing to clipboard", "source", src.name, "error", err)
+	}
+}
+
+// put writes text to src, with its HTML representation where the clipboard
+// supports it
+func (m *Monitor) put(src *source, text, html string) error {
+	if writer, ok := src.clipboard.(HTMLWriter); ok && html != "" {
+		return writer.WriteHTML(text, html)
+	}
+	if html != "" {
+		m.logger.Info("HTML clipboard content replaced by filtered text", "source", src.name)
 	}
+	return src.clipboard.WriteAll(text)
 }
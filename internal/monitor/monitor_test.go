@@ -0,0 +1,666 @@
+package monitor
+
+import (
+	"errors"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+	"github.com/happytaoer/prompt-security/internal/filter"
+)
+
+// logged is a log callback invocation
+type logged struct {
+	app                string
+	original, filtered string
+	replacements       []filter.ReplacementInfo
+}
+
+// testMonitor is a running monitor over mock clipboards
+type testMonitor struct {
+	*Monitor
+	clipboard *MockClipboard
+	selection *MockClipboard
+	logs      chan logged
+
+	// With a fake clock, the clock and the wait the loop is in
+	clock   *fakeClock
+	waiting time.Duration
+}
+
+// startMonitor runs a monitor over mock clipboards until the test ends
+func startMonitor(t *testing.T, cfg config.Config) *testMonitor {
+	t.Helper()
+	cfg.MonitoringInterval = config.MinMonitoringInterval
+	return runMonitor(t, cfg, nil)
+}
+
+// startFakeMonitor runs a monitor over mock clipboards and a fake clock
+// until the test ends. The loop only moves on with step, so tests see each
+// iteration complete without sleeping.
+func startFakeMonitor(t *testing.T, cfg config.Config) *testMonitor {
+	t.Helper()
+	tm := runMonitor(t, cfg, newFakeClock())
+	tm.waiting = receive(t, tm.clock.waits)
+	return tm
+}
+
+// runMonitor runs a monitor over mock clipboards and clock, the system
+// clock if nil, until the test ends
+func runMonitor(t *testing.T, cfg config.Config, clock *fakeClock) *testMonitor {
+	t.Helper()
+	tm := &testMonitor{
+		clipboard: NewMockClipboard(),
+		selection: NewMockClipboard(),
+		logs:      make(chan logged, 16),
+		clock:     clock,
+	}
+	manager := config.NewStaticManager(cfg)
+	m := New(manager, filter.NewEngine(manager.Effective()), func(app, original, filtered string, replacements []filter.ReplacementInfo) {
+		tm.logs <- logged{app, original, filtered, replacements}
+	})
+	m.foreground = func() (string, error) { return "editor", nil }
+	m.SetClipboard(tm.clipboard)
+	m.SetSelection(tm.selection)
+	if clock != nil {
+		m.SetClock(clock)
+	}
+	tm.Monitor = m
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		m.Run()
+	}()
+	t.Cleanup(func() {
+		m.Stop()
+		wg.Wait()
+	})
+	return tm
+}
+
+// step ends the wait of the loop on the fake clock and returns once the
+// next iteration is done, with the delay the loop then waits for
+func (tm *testMonitor) step(t *testing.T) time.Duration {
+	t.Helper()
+	tm.clock.Advance(tm.waiting)
+	tm.waiting = receive(t, tm.clock.waits)
+	return tm.waiting
+}
+
+// fakeClock is a Clock that only moves when advanced
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+	waits  chan time.Duration // Delay of each wait started, in order
+}
+
+// fakeTimer is a wait on a fakeClock, ending by a send on c or a call of f
+type fakeTimer struct {
+	at time.Time
+	c  chan time.Time
+	f  func()
+}
+
+// newFakeClock creates a fake clock
+func newFakeClock() *fakeClock {
+	return &fakeClock{now: time.Date(2024, 1, 1, 9, 0, 0, 0, time.UTC), waits: make(chan time.Duration, 16)}
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) After(d time.Duration) (<-chan time.Time, func() bool) {
+	c.mu.Lock()
+	timer := &fakeTimer{at: c.now.Add(d), c: make(chan time.Time, 1)}
+	c.timers = append(c.timers, timer)
+	c.mu.Unlock()
+
+	c.waits <- d
+	return timer.c, func() bool { return c.remove(timer) }
+}
+
+// AfterFunc schedules f without reporting a wait on waits, which only
+// follows the polling loop
+func (c *fakeClock) AfterFunc(d time.Duration, f func()) func() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	timer := &fakeTimer{at: c.now.Add(d), f: f}
+	c.timers = append(c.timers, timer)
+	return func() bool { return c.remove(timer) }
+}
+
+// Advance moves the clock on by d, ending the waits due by then. Functions
+// due are called before it returns, outside the lock of the clock.
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	pending := c.timers[:0]
+	var due []func()
+	for _, timer := range c.timers {
+		switch {
+		case timer.at.After(c.now):
+			pending = append(pending, timer)
+		case timer.f != nil:
+			due = append(due, timer.f)
+		default:
+			timer.c <- c.now
+		}
+	}
+	c.timers = pending
+	c.mu.Unlock()
+
+	for _, f := range due {
+		f()
+	}
+}
+
+// remove stops timer, reporting whether it was pending
+func (c *fakeClock) remove(timer *fakeTimer) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, t := range c.timers {
+		if t == timer {
+			c.timers = append(c.timers[:i], c.timers[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// none fails if ch holds a value, without waiting: after step the
+// iteration is complete, so anything it produced is already there
+func none[T any](t *testing.T, ch <-chan T, what string) {
+	t.Helper()
+	select {
+	case v := <-ch:
+		t.Errorf("Expected no %s, got %v", what, v)
+	default:
+	}
+}
+
+// receive waits for a value from ch
+func receive[T any](t *testing.T, ch <-chan T) T {
+	t.Helper()
+	select {
+	case v := <-ch:
+		return v
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timed out waiting for the monitor")
+		panic("unreachable")
+	}
+}
+
+// TestMonitor_FiltersCopiedText tests that copied sensitive data is logged
+// and written back filtered, while clean text is left alone
+func TestMonitor_FiltersCopiedText(t *testing.T) {
+	tm := startMonitor(t, config.Config{MonitorClipboard: true, DetectEmails: true, EmailReplacement: "[EMAIL]"})
+	clip := tm.clipboard
+
+	clip.Copy("nothing to see here")
+	clip.Copy("mail alice@example.com")
+
+	if got := receive(t, clip.Written()); got != "mail [EMAIL]" {
+		t.Errorf("Expected filtered text written back, got %q", got)
+	}
+	entry := receive(t, tm.logs)
+	if entry.app != "editor" || entry.original != "mail alice@example.com" || entry.filtered != "mail [EMAIL]" {
+		t.Errorf("Unexpected log entry %+v", entry)
+	}
+	if len(entry.replacements) != 1 || entry.replacements[0].Type != "email" {
+		t.Errorf("Expected one email replacement, got %+v", entry.replacements)
+	}
+	if clip.Content() != "mail [EMAIL]" {
+		t.Errorf("Expected clipboard to hold filtered text, got %q", clip.Content())
+	}
+}
+
+// TestMonitor_HTML tests that the HTML representation is redacted
+// consistently with the text and kept on the clipboard
+func TestMonitor_HTML(t *testing.T) {
+	tm := startMonitor(t, config.Config{MonitorClipboard: true, DetectEmails: true, EmailReplacement: "[EMAIL]"})
+
+	tm.clipboard.CopyContent(MockContent{
+		Text: "mail alice@example.com",
+		HTML: `<p>mail <a href="mailto:alice@example.com">alice@example.com</a></p>`,
+	})
+	if got := receive(t, tm.clipboard.Written()); got != "mail [EMAIL]" {
+		t.Errorf("Expected filtered text written back, got %q", got)
+	}
+	if got, want := tm.clipboard.HTML(), `<p>mail <a href="mailto:[EMAIL]">[EMAIL]</a></p>`; got != want {
+		t.Errorf("Expected HTML %q, got %q", want, got)
+	}
+
+	// Sensitive data only present in the markup is redacted as well
+	tm.clipboard.CopyContent(MockContent{
+		Text: "Contact Bob",
+		HTML: `<a href="mailto:bob@example.com">Contact Bob</a>`,
+	})
+	if got := receive(t, tm.clipboard.Written()); got != "Contact Bob" {
+		t.Errorf("Expected unchanged text written back, got %q", got)
+	}
+	if got := tm.clipboard.HTML(); got != `<a href="mailto:[EMAIL]">Contact Bob</a>` {
+		t.Errorf("Expected redacted link, got %q", got)
+	}
+	receive(t, tm.logs)
+	if entry := receive(t, tm.logs); entry.filtered != `<a href="mailto:[EMAIL]">Contact Bob</a>` {
+		t.Errorf("Expected the HTML to be logged, got %+v", entry)
+	}
+}
+
+// TestMonitor_BlockAction tests that a blocking detector replaces the whole
+// copy with the block message, or clears it when the message is empty
+func TestMonitor_BlockAction(t *testing.T) {
+	cfg := config.Config{
+		MonitorClipboard: true,
+		DetectEmails:     true,
+		EmailReplacement: "[EMAIL]",
+		DetectSSNs:       true,
+		SSNReplacement:   "[SSN]",
+		SSNAction:        config.ActionBlock,
+		BlockMessage:     "Blocked: {types}",
+	}
+	tm := startMonitor(t, cfg)
+
+	tm.clipboard.CopyContent(MockContent{
+		Text: "ssn 123-45-6789 for a@b.com",
+		HTML: "<b>ssn 123-45-6789</b> for a@b.com",
+	})
+	if got := receive(t, tm.clipboard.Written()); got != "Blocked: email, ssn" {
+		t.Errorf("Expected the block message, got %q", got)
+	}
+	if html := tm.clipboard.HTML(); html != "" {
+		t.Errorf("Expected the HTML to be dropped, got %q", html)
+	}
+	entry := receive(t, tm.logs)
+	if entry.original != "ssn 123-45-6789 for a@b.com" || entry.filtered != "Blocked: email, ssn" || len(entry.replacements) != 2 {
+		t.Errorf("Unexpected log entry %+v", entry)
+	}
+
+	cfg.BlockMessage = ""
+	tm = startMonitor(t, cfg)
+	tm.clipboard.Copy("123-45-6789")
+	if got := receive(t, tm.clipboard.Written()); got != "" {
+		t.Errorf("Expected the clipboard to be cleared, got %q", got)
+	}
+}
+
+// TestMonitor_AskAction tests that an ask detector redacts the copy and
+// keeps the original until it is restored, replaced or expired
+func TestMonitor_AskAction(t *testing.T) {
+	cfg := config.Config{
+		MonitorClipboard:   true,
+		DetectEmails:       true,
+		EmailReplacement:   "[EMAIL]",
+		EmailAction:        config.ActionAsk,
+		AskTimeoutSeconds:  30,
+		MonitoringInterval: 10000,
+	}
+	tm := startFakeMonitor(t, cfg)
+
+	tm.clipboard.Copy("mail alice@example.com")
+	tm.step(t)
+	if got := receive(t, tm.clipboard.Written()); got != "mail [EMAIL]" {
+		t.Fatalf("Expected the copy to be redacted, got %q", got)
+	}
+	holds := tm.Holds()
+	if len(holds) != 1 || holds[0].Filtered != "mail [EMAIL]" || len(holds[0].Types) != 1 || holds[0].Types[0] != "email" {
+		t.Fatalf("Expected one hold, got %+v", holds)
+	}
+	if want := tm.clock.Now().Add(30 * time.Second); !holds[0].Expires.Equal(want) {
+		t.Errorf("Expected the hold to expire at %v, got %v", want, holds[0].Expires)
+	}
+
+	if err := tm.Restore(holds[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	if got := receive(t, tm.clipboard.Written()); got != "mail alice@example.com" {
+		t.Errorf("Expected the original to be restored, got %q", got)
+	}
+	tm.step(t)
+	none(t, tm.clipboard.Written(), "write of the restored original")
+	if err := tm.Restore(holds[0].ID); !errors.Is(err, ErrHoldNotFound) {
+		t.Errorf("Expected a hold to be restored once, got %v", err)
+	}
+
+	// A newer copy discards the hold
+	tm.clipboard.Copy("bob@example.com")
+	tm.step(t)
+	receive(t, tm.clipboard.Written())
+	tm.clipboard.Copy("something else")
+	tm.step(t)
+	if holds := tm.Holds(); len(holds) != 0 {
+		t.Errorf("Expected the hold to be dropped by a newer copy, got %+v", holds)
+	}
+
+	// Originals are discarded after the timeout, and kept until then
+	tm.clipboard.Copy("carol@example.com")
+	tm.step(t)
+	receive(t, tm.clipboard.Written())
+	expires := tm.Holds()[0].Expires
+	for tm.clock.Now().Add(tm.waiting).Before(expires) {
+		tm.step(t)
+	}
+	if holds := tm.Holds(); len(holds) != 1 {
+		t.Fatalf("Expected the hold to be kept until it expires, got %+v", holds)
+	}
+	tm.step(t)
+	if holds := tm.Holds(); len(holds) != 0 {
+		t.Errorf("Expected the hold to expire, got %+v", holds)
+	}
+}
+
+// TestMonitor_LogAction tests that matches of log-only detectors are logged
+// without rewriting the clipboard
+func TestMonitor_LogAction(t *testing.T) {
+	tm := startFakeMonitor(t, config.Config{
+		MonitorClipboard: true,
+		DetectIPV4:       true,
+		IPV4Replacement:  "[IP]",
+		IPV4Severity:     config.SeverityLow,
+		Policies:         map[string]map[string]string{config.ProfileStandard: {config.SeverityLow: config.ActionLog}},
+	})
+
+	tm.clipboard.Copy("ssh 10.0.0.1")
+	tm.step(t)
+	entry := receive(t, tm.logs)
+	if entry.original != "ssh 10.0.0.1" || entry.filtered != "ssh 10.0.0.1" || len(entry.replacements) != 1 {
+		t.Errorf("Unexpected log entry %+v", entry)
+	}
+	none(t, tm.clipboard.Written(), "write")
+}
+
+// TestMonitor_NonTextFormats tests that content without text, like an image,
+// is neither rewritten nor treated as a read error
+func TestMonitor_NonTextFormats(t *testing.T) {
+	tm := startFakeMonitor(t, config.Config{MonitorClipboard: true, DetectEmails: true, EmailReplacement: "[EMAIL]"})
+
+	tm.clipboard.CopyContent(MockContent{Formats: []string{"image/png"}})
+	tm.step(t)
+	none(t, tm.clipboard.Written(), "write of the image")
+	if h := tm.Health(); h.ConsecutiveErrors != 0 {
+		t.Errorf("Expected no read errors, got %+v", h)
+	}
+
+	tm.clipboard.Copy("erin@example.com")
+	tm.step(t)
+	if got := receive(t, tm.clipboard.Written()); got != "[EMAIL]" {
+		t.Errorf("Expected the next text to be filtered, got %q", got)
+	}
+}
+
+// probeCounter counts the format probes of a clipboard
+type probeCounter struct {
+	*MockClipboard
+	probes int
+}
+
+func (c *probeCounter) Formats() ([]string, error) {
+	c.probes++
+	return c.MockClipboard.Formats()
+}
+
+// TestRead tests that the formats are only probed again when the text
+// changed
+func TestRead(t *testing.T) {
+	clip := &probeCounter{MockClipboard: NewMockClipboard()}
+	clip.CopyContent(MockContent{Text: "hello", HTML: "<b>hello</b>"})
+	first, err := read(clip, false, content{})
+	if err != nil || first.text != "hello" || first.html != "<b>hello</b>" || clip.probes != 1 {
+		t.Fatalf("Unexpected first read %+v, %v after %d probes", first, err, clip.probes)
+	}
+	if c, err := read(clip, false, first); err != nil || c != first || clip.probes != 1 {
+		t.Errorf("Expected the unchanged content without a probe, got %+v, %v after %d probes", c, err, clip.probes)
+	}
+
+	clip.CopyContent(MockContent{Formats: []string{"image/png"}})
+	if c, err := read(clip, false, first); err != nil || c != (content{}) || clip.probes != 2 {
+		t.Errorf("Expected an image to read as empty, got %+v, %v after %d probes", c, err, clip.probes)
+	}
+}
+
+// TestMonitor_FileScan tests that copied files holding sensitive data are
+// blocked with a notice, or only warned about
+func TestMonitor_FileScan(t *testing.T) {
+	dir := t.TempDir()
+	secret := filepath.Join(dir, ".env")
+	if err := os.WriteFile(secret, []byte("ADMIN_EMAIL=admin@example.com\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	plain := filepath.Join(dir, "notes.txt")
+	if err := os.WriteFile(plain, []byte("nothing here\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	files := (&url.URL{Scheme: "file", Path: plain}).String() + "\r\n" + (&url.URL{Scheme: "file", Path: secret}).String() + "\r\n"
+
+	cfg := config.Config{
+		MonitorClipboard: true,
+		DetectEmails:     true,
+		EmailReplacement: "[EMAIL]",
+		FileScanMode:     config.FileScanBlock,
+		FileScanMaxBytes: 1024,
+	}
+	tm := startMonitor(t, cfg)
+	tm.clipboard.CopyContent(MockContent{Files: files})
+
+	want := "[prompt-security] Copy blocked, files contain sensitive data: .env (email)"
+	if got := receive(t, tm.clipboard.Written()); got != want {
+		t.Errorf("Expected notice %q, got %q", want, got)
+	}
+	if entry := receive(t, tm.logs); entry.original != files || entry.filtered != want {
+		t.Errorf("Unexpected log entry %+v", entry)
+	}
+
+	cfg.FileScanMode = config.FileScanWarn
+	tm = startMonitor(t, cfg)
+	tm.clipboard.CopyContent(MockContent{Text: "copied", Files: files})
+	tm.clipboard.Copy("then frank@example.com")
+	if got := receive(t, tm.clipboard.Written()); got != "then [EMAIL]" {
+		t.Errorf("Expected files to be left on the clipboard in warn mode, got %q", got)
+	}
+}
+
+// TestParseURIList tests reading local paths from a text/uri-list
+func TestParseURIList(t *testing.T) {
+	list := "# comment\r\nfile:///home/me/My%20Notes.txt\r\nhttps://example.com/a\r\n\r\nfile:///tmp/.env"
+	got := parseURIList(list)
+	want := []string{filepath.FromSlash("/home/me/My Notes.txt"), filepath.FromSlash("/tmp/.env")}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("Expected %q, got %q", want, got)
+	}
+}
+
+// TestMonitor_ReadErrorBackoff tests that clipboard errors are reported in the
+// health and cleared once reads succeed again
+func TestMonitor_ReadErrorBackoff(t *testing.T) {
+	clipErr := errors.New("clipboard unavailable")
+	tm := startFakeMonitor(t, config.Config{MonitorClipboard: true, DetectEmails: true, EmailReplacement: "[EMAIL]"})
+	m, clip := tm.Monitor, tm.clipboard
+	clip.SetError(clipErr)
+
+	tm.step(t)
+	h := m.Health()
+	if h.Status != StatusBackoff || h.ConsecutiveErrors != 1 || h.LastError != clipErr.Error() || h.Backoff != minBackoff {
+		t.Errorf("Unexpected health after a read error: %+v", h)
+	}
+
+	clip.SetError(nil)
+	clip.Copy("bob@example.com")
+	tm.step(t)
+	if got := receive(t, clip.Written()); got != "[EMAIL]" {
+		t.Errorf("Expected filtering to resume, got %q", got)
+	}
+	if h := m.Health(); h.Status != StatusOK || h.ConsecutiveErrors != 0 {
+		t.Errorf("Expected healthy monitor after recovery, got %+v", h)
+	}
+}
+
+// TestMonitor_PrimarySelection tests that the primary selection is filtered
+// independently of the clipboard, each with its own toggle
+func TestMonitor_PrimarySelection(t *testing.T) {
+	tm := startFakeMonitor(t, config.Config{MonitorPrimarySelection: true, DetectEmails: true, EmailReplacement: "[EMAIL]"})
+
+	tm.clipboard.Copy("clipboard carol@example.com")
+	tm.selection.Copy("selected dave@example.com")
+	tm.step(t)
+
+	if got := receive(t, tm.selection.Written()); got != "selected [EMAIL]" {
+		t.Errorf("Expected filtered selection written back, got %q", got)
+	}
+	none(t, tm.clipboard.Written(), "write to the disabled clipboard")
+}
+
+// TestMonitor_ConfigDebounce tests that a burst of configuration changes is
+// applied once it ends, with the latest configuration and its detectors, and
+// that changes are dropped once the monitor is stopped
+func TestMonitor_ConfigDebounce(t *testing.T) {
+	manager := config.NewStaticManager(config.Config{MonitoringInterval: 500})
+	engine := filter.NewManagedEngine(manager)
+	m := New(manager, engine, nil)
+	clock := newFakeClock()
+	m.SetClock(clock)
+	initial := m.detectors()
+
+	for _, interval := range []int{600, 700, 800} {
+		manager.Override(func(cfg *config.Config) {
+			cfg.MonitoringInterval = interval
+			cfg.DetectEmails = true
+		})
+		clock.Advance(m.debounce - time.Millisecond)
+	}
+	if got := m.snapshot().Config.MonitoringInterval; got != 500 {
+		t.Errorf("Expected the configuration to be kept mid-burst, got interval %d", got)
+	}
+	if m.detectors() != initial || engine.Detectors() == initial {
+		t.Error("Expected the detectors to be kept mid-burst while the engine follows the manager")
+	}
+
+	clock.Advance(time.Millisecond)
+	if got := m.snapshot().Config.MonitoringInterval; got != 800 {
+		t.Fatalf("Expected the latest configuration after the burst, got interval %d", got)
+	}
+	if m.detectors() != engine.Detectors() {
+		t.Error("Expected the detectors of the engine to be applied with the configuration")
+	}
+
+	manager.Override(func(cfg *config.Config) { cfg.MonitoringInterval = 900 })
+	m.Stop()
+	clock.Advance(m.debounce)
+	if got := m.snapshot().Config.MonitoringInterval; got != 800 {
+		t.Errorf("Expected the pending change to be dropped by Stop, got interval %d", got)
+	}
+}
+
+// TestMonitor_Loop tests that each iteration of the polling loop filters
+// new content once, logs it and waits for the configured interval
+func TestMonitor_Loop(t *testing.T) {
+	tm := startFakeMonitor(t, config.Config{MonitorClipboard: true, DetectEmails: true, EmailReplacement: "[EMAIL]", MonitoringInterval: 1000})
+	if tm.waiting != time.Second {
+		t.Errorf("Expected the loop to wait for the interval, got %v", tm.waiting)
+	}
+
+	tm.clipboard.Copy("mail alice@example.com")
+	tm.step(t)
+	if got := receive(t, tm.clipboard.Written()); got != "mail [EMAIL]" {
+		t.Errorf("Expected filtered text written back, got %q", got)
+	}
+	if entry := receive(t, tm.logs); entry.original != "mail alice@example.com" || entry.filtered != "mail [EMAIL]" {
+		t.Errorf("Unexpected log entry %+v", entry)
+	}
+
+	// The filtered text read back is not filtered or logged again
+	tm.step(t)
+	tm.clipboard.Copy("nothing to see here")
+	tm.step(t)
+	none(t, tm.clipboard.Written(), "write")
+	none(t, tm.logs, "log entry")
+	if h := tm.Health(); h.Status != StatusOK || !h.LastHeartbeat.Equal(tm.clock.Now()) {
+		t.Errorf("Expected a heartbeat at the fake time, got %+v", h)
+	}
+}
+
+// TestMonitor_PauseResume tests that content copied while paused is left
+// alone, also after resuming, and that later copies are filtered again
+func TestMonitor_PauseResume(t *testing.T) {
+	tm := startFakeMonitor(t, config.Config{MonitorClipboard: true, DetectEmails: true, EmailReplacement: "[EMAIL]"})
+
+	tm.Pause()
+	tm.clipboard.Copy("paused bob@example.com")
+	tm.step(t)
+	none(t, tm.clipboard.Written(), "write while paused")
+	none(t, tm.logs, "log entry while paused")
+
+	tm.Resume()
+	tm.step(t)
+	none(t, tm.clipboard.Written(), "write of content copied while paused")
+
+	tm.clipboard.Copy("resumed carol@example.com")
+	tm.step(t)
+	if got := receive(t, tm.clipboard.Written()); got != "resumed [EMAIL]" {
+		t.Errorf("Expected filtering after resuming, got %q", got)
+	}
+}
+
+// TestMonitor_IntervalChanges tests that the loop follows changes of the
+// monitoring interval, which is never below the minimum
+func TestMonitor_IntervalChanges(t *testing.T) {
+	tm := startFakeMonitor(t, config.Config{MonitorClipboard: true, MonitoringInterval: 1000})
+
+	for _, tt := range []struct {
+		interval int
+		want     time.Duration
+	}{
+		{2500, 2500 * time.Millisecond},
+		{1, config.MinMonitoringInterval * time.Millisecond},
+	} {
+		tm.manager.Override(func(cfg *config.Config) { cfg.MonitoringInterval = tt.interval })
+		tm.clock.Advance(tm.debounce)
+		if got := tm.snapshot().Config.MonitoringInterval; got != tt.interval {
+			t.Fatalf("Expected interval %d to be applied, got %d", tt.interval, got)
+		}
+		if got := tm.step(t); got != tt.want {
+			t.Errorf("Interval %d: expected a wait of %v, got %v", tt.interval, tt.want, got)
+		}
+	}
+}
+
+// TestMonitor_ReadErrorDelays tests that failing reads are retried with a
+// doubling delay, and the interval is waited for again once they succeed
+func TestMonitor_ReadErrorDelays(t *testing.T) {
+	tm := startFakeMonitor(t, config.Config{MonitorClipboard: true, MonitoringInterval: 1000})
+
+	tm.clipboard.SetError(errors.New("clipboard unavailable"))
+	for _, want := range []time.Duration{minBackoff, 2 * minBackoff, 4 * minBackoff} {
+		if got := tm.step(t); got != want {
+			t.Errorf("Expected a retry after %v, got %v", want, got)
+		}
+	}
+	if h := tm.Health(); h.Status != StatusBackoff || h.ConsecutiveErrors != 3 {
+		t.Errorf("Unexpected health while reads fail: %+v", h)
+	}
+
+	tm.clipboard.SetError(nil)
+	if got := tm.step(t); got != time.Second {
+		t.Errorf("Expected the interval once reads succeed, got %v", got)
+	}
+}
+
+// TestNextBackoff tests the doubling retry delay
+func TestNextBackoff(t *testing.T) {
+	delay := time.Duration(0)
+	for _, want := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second, maxBackoff, maxBackoff} {
+		delay = nextBackoff(delay)
+		if delay != want {
+			t.Errorf("Expected %v, got %v", want, delay)
+		}
+	}
+}
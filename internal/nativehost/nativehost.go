@@ -0,0 +1,166 @@
+// Package nativehost answers a browser extension over native messaging:
+// JSON messages on standard input and output, each prefixed with its length
+// in native byte order. The browser spawns the host itself, so extensions
+// can filter text and read the configuration without any port being opened.
+package nativehost
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+	"github.com/happytaoer/prompt-security/internal/filter"
+	"github.com/happytaoer/prompt-security/internal/web"
+)
+
+// Name is the name the host is registered under with browsers
+const Name = "com.prompt_security.host"
+
+const (
+	// maxRequest is the largest message browsers send
+	maxRequest = 64 << 20
+
+	// maxResponse is the largest message browsers accept
+	maxResponse = 1 << 20
+)
+
+// Message types
+const (
+	TypePing   = "ping"   // Answers the version, to check the host is installed
+	TypeFilter = "filter" // Filters text like POST /api/v1/filter
+	TypeConfig = "config" // Answers the configuration like GET /api/v1/config
+)
+
+// Request is a message from the extension
+type Request struct {
+	ID   json.RawMessage `json:"id,omitempty"` // Returned in the response
+	Type string          `json:"type"`
+	Text string          `json:"text,omitempty"` // Text to filter
+	Log  bool            `json:"log,omitempty"`  // Log sensitive data found like a copy
+}
+
+// Response is a message to the extension
+type Response struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Type   string          `json:"type"`
+	Result interface{}     `json:"result,omitempty"`
+	Error  string          `json:"error,omitempty"`
+}
+
+// PingResult is the result of a ping
+type PingResult struct {
+	Version string `json:"version"`
+}
+
+// Options configure a Host
+type Options struct {
+	Version string
+
+	// Config returns the configuration in effect
+	Config func() config.Config
+
+	// AddLog, if set, logs filtered text when requested
+	AddLog func(original, filtered string, replacements []filter.ReplacementInfo)
+}
+
+// Host answers extension messages
+type Host struct {
+	engine *filter.Engine
+	opts   Options
+}
+
+// New creates a host filtering with engine
+func New(engine *filter.Engine, opts Options) *Host {
+	return &Host{engine: engine, opts: opts}
+}
+
+// Serve answers the messages read from r on w until r is closed (blocking)
+func (h *Host) Serve(r io.Reader, w io.Writer) error {
+	for {
+		data, err := readMessage(r)
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		var req Request
+		var response Response
+		if err := json.Unmarshal(data, &req); err != nil {
+			response = Response{Type: "error", Error: fmt.Sprintf("invalid message: %v", err)}
+		} else {
+			response = h.handle(req)
+		}
+		if err := h.write(w, response); err != nil {
+			return err
+		}
+	}
+}
+
+// handle answers a request
+func (h *Host) handle(req Request) Response {
+	response := Response{ID: req.ID, Type: req.Type}
+	switch req.Type {
+	case TypePing:
+		response.Result = PingResult{Version: h.opts.Version}
+	case TypeFilter:
+		filtered, changed, summary := h.engine.Filter(req.Text)
+		if req.Log && len(summary.Replacements) > 0 && h.opts.AddLog != nil {
+			h.opts.AddLog(req.Text, filtered, summary.Replacements)
+		}
+		response.Result = web.NewFilterResponse(filtered, changed, summary)
+	case TypeConfig:
+		if h.opts.Config == nil {
+			response.Error = "configuration unavailable"
+		} else {
+			response.Result = h.opts.Config()
+		}
+	default:
+		response.Error = fmt.Sprintf("unknown message type %q", req.Type)
+	}
+	return response
+}
+
+// write writes a response, replacing it with an error if it is larger than
+// browsers accept
+func (h *Host) write(w io.Writer, response Response) error {
+	data, err := json.Marshal(response)
+	if err != nil {
+		return err
+	}
+	if len(data) > maxResponse {
+		response.Result = nil
+		response.Error = "response exceeds the 1 MB native messaging limit"
+		if data, err = json.Marshal(response); err != nil {
+			return err
+		}
+	}
+	return writeMessage(w, data)
+}
+
+// readMessage reads a length prefixed message
+func readMessage(r io.Reader) ([]byte, error) {
+	var size uint32
+	if err := binary.Read(r, binary.NativeEndian, &size); err != nil {
+		return nil, err
+	}
+	if size > maxRequest {
+		return nil, fmt.Errorf("message of %d bytes exceeds the limit", size)
+	}
+	data := make([]byte, size)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+// writeMessage writes a length prefixed message
+func writeMessage(w io.Writer, data []byte) error {
+	message := binary.NativeEndian.AppendUint32(make([]byte, 0, 4+len(data)), uint32(len(data)))
+	_, err := w.Write(append(message, data...))
+	return err
+}
@@ -0,0 +1,78 @@
+package nativehost
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+	"github.com/happytaoer/prompt-security/internal/filter"
+	"github.com/happytaoer/prompt-security/internal/web"
+)
+
+// TestHost tests answering framed messages in order
+func TestHost(t *testing.T) {
+	cfg := config.Config{DetectEmails: true, EmailReplacement: "[EMAIL]"}
+	logged := 0
+	host := New(filter.NewEngine(cfg), Options{
+		Version: "1.2.3",
+		Config:  func() config.Config { return cfg },
+		AddLog:  func(string, string, []filter.ReplacementInfo) { logged++ },
+	})
+
+	var in bytes.Buffer
+	for _, m := range []string{
+		`{"id":1,"type":"ping"}`,
+		`{"id":"a","type":"filter","text":"mail ann@example.com","log":true}`,
+		`{"type":"filter","text":"mail bob@example.com"}`,
+		`{"type":"config"}`,
+		`{"type":"nope"}`,
+		`not json`,
+		`{"type":"filter","text":"` + strings.Repeat("a@b.co ", 200000) + `"}`,
+	} {
+		writeMessage(&in, []byte(m))
+	}
+	var out bytes.Buffer
+	if err := host.Serve(&in, &out); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+
+	type response struct {
+		ID     json.RawMessage `json:"id"`
+		Result json.RawMessage `json:"result"`
+		Error  string          `json:"error"`
+	}
+	var responses []response
+	for out.Len() > 0 {
+		data, err := readMessage(&out)
+		if err != nil {
+			t.Fatalf("Invalid response: %v", err)
+		}
+		var r response
+		json.Unmarshal(data, &r)
+		responses = append(responses, r)
+	}
+	if len(responses) != 7 {
+		t.Fatalf("Expected 7 responses, got %d", len(responses))
+	}
+
+	if string(responses[0].ID) != "1" || string(responses[0].Result) != `{"version":"1.2.3"}` {
+		t.Errorf("Unexpected ping response %+v", responses[0])
+	}
+	var filtered web.FilterResponse
+	json.Unmarshal(responses[1].Result, &filtered)
+	if string(responses[1].ID) != `"a"` || filtered.Filtered != "mail [EMAIL]" || !filtered.Changed || len(filtered.Replacements) != 1 || logged != 1 {
+		t.Errorf("Unexpected filter response %+v", filtered)
+	}
+	var got config.Config
+	json.Unmarshal(responses[3].Result, &got)
+	if !got.DetectEmails || responses[3].Error != "" {
+		t.Errorf("Unexpected config response %+v", responses[3])
+	}
+	for i, want := range map[int]string{4: "unknown message type", 5: "invalid message", 6: "1 MB"} {
+		if !strings.Contains(responses[i].Error, want) {
+			t.Errorf("Expected response %d to fail with %q, got %+v", i, want, responses[i])
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package patterns
+
+import "regexp"
+
+// Cloud patterns match infrastructure identifiers only in contexts naming
+// them, as project IDs and GUIDs alone look like ordinary slugs and IDs
+const (
+	// gcpProjectID is a GCP project ID: 6 to 30 lowercase letters, digits
+	// and hyphens, starting with a letter and not ending with a hyphen
+	gcpProjectID = `(?P<project>[a-z][a-z0-9-]{4,28}[a-z0-9])`
+
+	// GCPProjectPatternStr matches GCP project IDs after keys such as
+	// project_id or GOOGLE_CLOUD_PROJECT, the --project flag and gcloud
+	// config set project, in projects/ID/ resource names and ?project=
+	// console parameters, and in service account emails, App Engine hosts
+	// and container registry paths
+	GCPProjectPatternStr = `(?i:\b(?:project[_-]?id|gcp[_-]?project(?:[_-]?id)?|google[_-]cloud[_-]project|cloudsdk[_-]core[_-]project|gcloud[_-]project))["']?[ \t]*[:=][ \t]*["']?` + gcpProjectID + `(?:[^\w-]|$)` +
+		`|(?:--project(?:[ \t]+|=)|\bgcloud[ \t]+config[ \t]+set[ \t]+(?:core/)?project[ \t]+)["']?` + gcpProjectID + `(?:[^\w-]|$)` +
+		`|(?:^|[\s"'(/])projects/` + gcpProjectID + `/[A-Za-z]` +
+		`|[?&]project=` + gcpProjectID + `(?:[^\w-]|$)` +
+		`|@` + gcpProjectID + `\.iam\.gserviceaccount\.com\b` +
+		`|(?:^|[^\w.-])` + gcpProjectID + `\.appspot\.com\b` +
+		`|\b(?:(?:[a-z]+\.)?gcr\.io|[a-z0-9-]+-docker\.pkg\.dev)/` + gcpProjectID + `/`
+
+	// azureGUID is a GUID in its canonical form
+	azureGUID = `(?P<guid>[0-9A-Fa-f]{8}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{4}-[0-9A-Fa-f]{12})\b`
+
+	// AzureIDPatternStr matches Azure subscription and tenant GUIDs in
+	// resource IDs (/subscriptions/GUID), sign-in URLs
+	// (login.microsoftonline.com/GUID), and after keys and flags such as
+	// subscriptionId, AZURE_TENANT_ID, --subscription or -TenantId
+	AzureIDPatternStr = `(?i:/subscriptions/)` + azureGUID +
+		`|(?i:\b(?:login\.microsoftonline\.(?:com|us)|login\.partner\.microsoftonline\.cn|sts\.windows\.net)/)` + azureGUID +
+		`|(?i:(?:\b|-)(?:azure[_-]?|arm[_-]?)?(?:subscription|tenant)(?:[ _-]?id)?)["']?(?:[ \t]*[:=][ \t]*|[ \t]+)["']?` + azureGUID
+)
+
+var (
+	// GCPProjectPattern is the compiled GCPProjectPatternStr
+	GCPProjectPattern = regexp.MustCompile(GCPProjectPatternStr)
+
+	// AzureIDPattern is the compiled AzureIDPatternStr
+	AzureIDPattern = regexp.MustCompile(AzureIDPatternStr)
+)
@@ -0,0 +1,73 @@
+package patterns
+
+import (
+	"regexp"
+	"testing"
+)
+
+// groupMatch returns the first participating group named name of the
+// first match of re in text
+func groupMatch(re *regexp.Regexp, name, text string) string {
+	loc := re.FindStringSubmatchIndex(text)
+	if loc == nil {
+		return ""
+	}
+	for i, n := range re.SubexpNames() {
+		if n == name && loc[2*i] >= 0 {
+			return text[loc[2*i]:loc[2*i+1]]
+		}
+	}
+	return ""
+}
+
+// TestGCPProjectPattern tests the GCP project ID pattern
+func TestGCPProjectPattern(t *testing.T) {
+	tests := []struct {
+		text    string
+		project string
+	}{
+		{`"project_id": "acme-prod-4821",`, "acme-prod-4821"},
+		{"GOOGLE_CLOUD_PROJECT=acme-prod-4821", "acme-prod-4821"},
+		{"gcloud compute instances list --project acme-prod-4821", "acme-prod-4821"},
+		{"gcloud config set project acme-prod-4821", "acme-prod-4821"},
+		{"projects/acme-prod-4821/locations/us-central1/keyRings/k", "acme-prod-4821"},
+		{"https://console.cloud.google.com/logs?project=acme-prod-4821&x=1", "acme-prod-4821"},
+		{"deployer@acme-prod-4821.iam.gserviceaccount.com", "acme-prod-4821"},
+		{"https://acme-prod-4821.appspot.com/", "acme-prod-4821"},
+		{"gcr.io/acme-prod-4821/api:latest", "acme-prod-4821"},
+		{"us-docker.pkg.dev/acme-prod-4821/images/api", "acme-prod-4821"},
+		{"project: website", ""},
+		{"projects/5/columns", ""},
+		{"project_id = ab", ""},
+		{"project_id = acme-prod-", ""},
+	}
+	for _, tt := range tests {
+		if got := groupMatch(GCPProjectPattern, "project", tt.text); got != tt.project {
+			t.Errorf("Expected %q in %q, got %q", tt.project, tt.text, got)
+		}
+	}
+}
+
+// TestAzureIDPattern tests the Azure subscription and tenant GUID pattern
+func TestAzureIDPattern(t *testing.T) {
+	const guid = "3f2504e0-4f89-11d3-9a0c-0305e82c3301"
+	tests := []struct {
+		text string
+		guid string
+	}{
+		{"/subscriptions/" + guid + "/resourceGroups/rg", guid},
+		{"https://login.microsoftonline.com/" + guid + "/oauth2/v2.0/token", guid},
+		{`"tenantId": "` + guid + `"`, guid},
+		{"AZURE_SUBSCRIPTION_ID=" + guid, guid},
+		{"az account set --subscription " + guid, guid},
+		{"Connect-AzAccount -TenantId " + guid, guid},
+		{"Subscription ID: " + guid, guid},
+		{`"requestId": "` + guid + `"`, ""},
+		{guid, ""},
+	}
+	for _, tt := range tests {
+		if got := groupMatch(AzureIDPattern, "guid", tt.text); got != tt.guid {
+			t.Errorf("Expected %q in %q, got %q", tt.guid, tt.text, got)
+		}
+	}
+}
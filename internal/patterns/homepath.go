@@ -0,0 +1,13 @@
+package patterns
+
+import "regexp"
+
+// HomePathPatternStr matches absolute home directory paths, as printed in
+// stack traces and logs, capturing the user name: /Users/jane on macOS,
+// /home/jane on Linux and C:\Users\jane on Windows, also with forward or
+// doubled (JSON-escaped) backslashes and in file:// URLs
+const HomePathPatternStr = `(?:^|[^\w.-])(?:/Users|/home|/var/home|/export/home)/(?P<user>[^/\s:"'<>|*?\\]+)` +
+	`|(?i:\b[a-z]:(?:\\{1,2}|/)(?:users|documents and settings)(?:\\{1,2}|/))(?P<user>[^\\/\s:"'<>|*?]+)`
+
+// HomePathPattern is the compiled HomePathPatternStr
+var HomePathPattern = regexp.MustCompile(HomePathPatternStr)
@@ -0,0 +1,39 @@
+package patterns
+
+import "testing"
+
+// TestHomePathPattern tests the home directory path pattern
+func TestHomePathPattern(t *testing.T) {
+	tests := []struct {
+		text string
+		user string
+	}{
+		{"at Object.<anonymous> (/Users/jane/project/index.js:3:9)", "jane"},
+		{`File "/home/jane.doe/app/main.py", line 12`, "jane.doe"},
+		{"file:///Users/jane/Desktop/report.pdf", "jane"},
+		{`C:\Users\jane\AppData\Local\Temp\x.log`, "jane"},
+		{`{"path": "C:\\Users\\jane\\source\\repos"}`, "jane"},
+		{"c:/users/Jane/Documents", "Jane"},
+		{`D:\Documents and Settings\jane\Desktop`, "jane"},
+		{"/var/home/jane", "jane"},
+		{"https://example.com/home/jane/", ""},
+		{"/home/", ""},
+		{"/usr/local/bin", ""},
+	}
+
+	names := HomePathPattern.SubexpNames()
+	for _, tt := range tests {
+		got := ""
+		if loc := HomePathPattern.FindStringSubmatchIndex(tt.text); loc != nil {
+			for i := 1; i < len(names); i++ {
+				if names[i] == "user" && loc[2*i] >= 0 {
+					got = tt.text[loc[2*i]:loc[2*i+1]]
+					break
+				}
+			}
+		}
+		if got != tt.user {
+			t.Errorf("Expected %q in %q, got %q", tt.user, tt.text, got)
+		}
+	}
+}
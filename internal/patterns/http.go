@@ -0,0 +1,68 @@
+package patterns
+
+import "regexp"
+
+// HTTP patterns match credentials in copied requests, HTTP dumps and curl
+// commands. Credentials are captured in named groups so that only they are
+// replaced, keeping header and cookie names, schemes and user names.
+const (
+	// HTTPAuthPatternStr matches the credentials of Authorization and
+	// Proxy-Authorization headers (after the scheme, e.g. Bearer or Basic,
+	// or on their own if token-like), the password of curl's
+	// -u user:password, also bundled after options without an argument as
+	// in -sku, and the password of a URL's user:password@ part
+	HTTPAuthPatternStr = `(?i)\b(?:proxy-)?authorization["']?[ \t]*[:=][ \t]*["']?(?:(?:bearer|basic|token|negotiate|ntlm|apikey)[ \t]+(?P<secret>[^\s"'\\]+)|(?P<secret>[A-Za-z0-9._~+/=:-]{8,}))` +
+		`|(?:^|\s)(?:-(?-i:[012346aBfgGhiIjJklLMnNOpqRsSvVZ#]*)u|--user)(?:[ \t]+|=)?["']?[^\s:"']+:(?P<secret>[^\s"']+)` +
+		`|\b[a-z][a-z0-9+.-]*://[^\s/:@"']+:(?P<secret>[^\s/@"']+)@`
+
+	// CookieHeaderPatternStr matches Cookie and Set-Cookie headers,
+	// capturing their value as cookies
+	CookieHeaderPatternStr = `(?im)(?:^|[\s"'{,])(?P<set>set-)?cookie["']?[ \t]*:[ \t]*["']?(?P<cookies>[^\r\n"']*)`
+
+	// SessionCookiePatternStr matches name=value pairs of well-known session
+	// and CSRF cookies anywhere, such as in URLs or scripts
+	SessionCookiePatternStr = `(?i:\b(?:jsessionid|phpsessid|asp\.net_sessionid|aspsessionid\w*|connect\.sid|laravel_session|_session_id|session_?id|csrftoken|csrf_?token|_csrf|xsrf-token))=(?P<value>[^\s;&"',?#]+)` +
+		`|(?:^|[^\w-])(?:__Secure-|__Host-)[\w.-]+=(?P<value>[^\s;&"',?#]+)`
+
+	// CurlCommandPatternStr matches the start of a curl command, after which
+	// its arguments are parsed
+	CurlCommandPatternStr = `(?:^|[^\w./-])curl(?:\.exe)?[ \t]`
+
+	// secretField matches names of query parameters, form fields and JSON
+	// keys that hold credentials, e.g. password, access_token or api_key
+	secretField = `(?i:[\w.-]*(?:password|passwd|pwd|secret|token|api[_-]?key|apikey|access[_-]?key|signature|sig|session[_-]?id|sessionid|credentials?)|key|auth)`
+
+	// SecretFieldPatternStr matches a whole credential field name
+	SecretFieldPatternStr = `^` + secretField + `$`
+
+	// SecretHeaderPatternStr matches the names of headers other than
+	// Authorization and Cookie carrying credentials, e.g. X-API-Key
+	SecretHeaderPatternStr = `(?i)^[\w-]*(?:token|api-?key|apikey|secret|password|session|signature|credential)[\w-]*$`
+
+	// JSONSecretFieldPatternStr matches a JSON string member whose name is a
+	// credential field, capturing the value without its quotes
+	JSONSecretFieldPatternStr = `"` + secretField + `"\s*:\s*"(?P<value>(?:[^"\\]|\\.)*)"`
+)
+
+var (
+	// HTTPAuthPattern is the compiled HTTPAuthPatternStr
+	HTTPAuthPattern = regexp.MustCompile(HTTPAuthPatternStr)
+
+	// CookieHeaderPattern is the compiled CookieHeaderPatternStr
+	CookieHeaderPattern = regexp.MustCompile(CookieHeaderPatternStr)
+
+	// SessionCookiePattern is the compiled SessionCookiePatternStr
+	SessionCookiePattern = regexp.MustCompile(SessionCookiePatternStr)
+
+	// CurlCommandPattern is the compiled CurlCommandPatternStr
+	CurlCommandPattern = regexp.MustCompile(CurlCommandPatternStr)
+
+	// SecretFieldPattern is the compiled SecretFieldPatternStr
+	SecretFieldPattern = regexp.MustCompile(SecretFieldPatternStr)
+
+	// SecretHeaderPattern is the compiled SecretHeaderPatternStr
+	SecretHeaderPattern = regexp.MustCompile(SecretHeaderPatternStr)
+
+	// JSONSecretFieldPattern is the compiled JSONSecretFieldPatternStr
+	JSONSecretFieldPattern = regexp.MustCompile(JSONSecretFieldPatternStr)
+)
@@ -0,0 +1,61 @@
+package patterns
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+)
+
+// LicenseContextPatternStr matches the words that introduce a license key,
+// which must precede a key for it to be detected
+const LicenseContextPatternStr = `(?i)\b(?:licen[cs]e|serial|product[ -]?key|cd[ -]?key|activation|registration|key)`
+
+// LicenseContextPattern is the compiled LicenseContextPatternStr
+var LicenseContextPattern = regexp.MustCompile(LicenseContextPatternStr)
+
+// LicenseKeyFormatPattern translates a license key format, such as
+// XXXXX-XXXXX-XXXXX, into a pattern
+func LicenseKeyFormatPattern(format string) string {
+	var b strings.Builder
+	for _, c := range format {
+		switch c {
+		case 'X':
+			b.WriteString(`[A-Za-z0-9]`)
+		case 'A':
+			b.WriteString(`[A-Za-z]`)
+		case '9':
+			b.WriteString(`[0-9]`)
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	return b.String()
+}
+
+// LicenseKeyPattern returns the pattern matching the configured license key
+// formats, or the default formats if none are. Longer formats come first so
+// that a key is not matched by a format of fewer groups.
+func (pc *PatternCache) LicenseKeyPattern(cfg *config.Config) *regexp.Regexp {
+	formats := append([]string{}, config.DefaultLicenseKeyFormats...)
+	if cfg != nil && len(cfg.LicenseKeyFormats) > 0 {
+		formats = append([]string{}, cfg.LicenseKeyFormats...)
+	}
+	sort.SliceStable(formats, func(i, j int) bool { return len(formats[i]) > len(formats[j]) })
+
+	var alternatives []string
+	for _, f := range formats {
+		if config.ValidLicenseKeyFormat(f) {
+			alternatives = append(alternatives, LicenseKeyFormatPattern(f))
+		}
+	}
+	if len(alternatives) == 0 {
+		return nil
+	}
+	pattern, err := pc.Get("licenseKey", strings.Join(alternatives, "|"))
+	if err != nil {
+		return nil
+	}
+	return pattern
+}
@@ -0,0 +1,75 @@
+package patterns
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+)
+
+// PhoneLocalePatterns are the phone number pattern packs by locale. Each
+// captures the international prefix, when present, as country.
+var PhoneLocalePatterns = map[string]string{
+	config.LocaleUS:   DefaultPhonePatternStr,
+	config.LocaleUK:   `(?:(?P<country>\+44)\s?(?:\(0\)\s?)?|\b0)[1237](?:[\s-]?\d){9}\b`,
+	config.LocaleDE:   `(?:(?P<country>\+49)\s?(?:\(0\)\s?)?|\b0)[1-9]\d{1,4}[\s/-]?\d{3,8}(?:-\d{1,4})?\b`,
+	config.LocaleFR:   `(?:(?P<country>\+33)\s?(?:\(0\)\s?)?|\b0)[1-9](?:[\s.-]?\d{2}){4}\b`,
+	config.LocaleIN:   `(?:(?P<country>\+91)[\s-]?|\b0?)[6-9]\d{4}[\s-]?\d{5}\b`,
+	config.LocaleCN:   `(?:(?P<country>\+86|\(\+86\)|\b0086)[\s-]?|\b)1[3-9]\d[\s-]?\d{4}[\s-]?\d{4}\b`,
+	config.LocaleJP:   `(?:(?P<country>\+81)[\s-]?|\b0)(?:[789]0[\s-]?\d{4}|\d{1,4}-\d{1,4})[\s-]?\d{4}\b`,
+	config.LocaleIntl: `(?P<country>\+[1-9]\d{0,2})[\s.-]?\(?\d{1,4}\)?(?:[\s.-]?\d{2,4}){2,4}\b`,
+}
+
+// SSNLocalePatterns are the national ID pattern packs by locale: the US
+// Social Security Number, UK National Insurance number, French NIR, Indian
+// Aadhaar and Chinese Resident Identity Card number
+var SSNLocalePatterns = map[string]string{
+	config.LocaleUS: DefaultSSNPatternStr,
+	config.LocaleUK: `\b[A-CEGHJ-PR-TW-Z][A-CEGHJ-NPR-TW-Z]\s?\d{2}\s?\d{2}\s?\d{2}\s?[A-D]\b`,
+	config.LocaleFR: `\b[12]\s?\d{2}\s?(?:0[1-9]|1[0-2])\s?(?:\d{2}|2[AB])\s?\d{3}\s?\d{3}\s?\d{2}\b`,
+	config.LocaleIN: `\b[2-9]\d{3}[\s-]?\d{4}[\s-]?\d{4}\b`,
+	config.LocaleCN: `\b\d{6}(?:18|19|20)\d{2}(?:0[1-9]|1[0-2])(?:0[1-9]|[12]\d|3[01])\d{3}[\dXx]\b`,
+}
+
+// MerchantIDPatternStr matches the merchant IDs of Chinese payment
+// platforms: Alipay partner IDs (2088 and 12 digits), WeChat app IDs (wx and
+// 16 hex digits) and WeChat Pay merchant numbers after a label such as
+// mch_id or 商户号, which is captured as label to be kept
+const MerchantIDPatternStr = `\b2088\d{12}\b|\bwx[0-9a-f]{16}\b` +
+	`|(?P<label>(?i:\b(?:sub_)?mch_?id|\bmerchant[ _]?(?:id|no))\s*["']?\s*[:=]\s*["']?|商户号\s*[:：]?\s*)\d{8,10}\b`
+
+// MerchantIDPattern is the compiled MerchantIDPatternStr
+var MerchantIDPattern = regexp.MustCompile(MerchantIDPatternStr)
+
+// LocalePattern returns the pattern matching the formats of locales in
+// packs, or "" if none of them has a pack
+func LocalePattern(packs map[string]string, locales []string) string {
+	var alternatives []string
+	seen := make(map[string]bool)
+	for _, l := range locales {
+		p, ok := packs[l]
+		if !ok || seen[l] {
+			continue
+		}
+		seen[l] = true
+		alternatives = append(alternatives, "(?:"+p+")")
+	}
+	return strings.Join(alternatives, "|")
+}
+
+// localePattern returns the compiled packs of locales, or fallback, the US
+// pack, when locales select only it or nothing usable
+func (pc *PatternCache) localePattern(key string, packs map[string]string, locales []string, fallback *regexp.Regexp) *regexp.Regexp {
+	if len(locales) == 0 || (len(locales) == 1 && locales[0] == config.LocaleUS) {
+		return fallback
+	}
+	combined := LocalePattern(packs, locales)
+	if combined == "" {
+		return fallback
+	}
+	pattern, err := pc.Get(key, combined)
+	if err != nil {
+		return fallback
+	}
+	return pattern
+}
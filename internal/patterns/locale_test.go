@@ -0,0 +1,143 @@
+package patterns
+
+import (
+	"regexp"
+	"testing"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+)
+
+// TestPhoneLocalePatterns tests the phone number pattern packs
+func TestPhoneLocalePatterns(t *testing.T) {
+	tests := []struct {
+		locale  string
+		match   []string
+		noMatch []string
+		country string // Expected country group of match[0]
+	}{
+		{config.LocaleUK, []string{"+44 7700 900123", "07700 900123", "020 7946 0018", "+44 (0)20 7946 0018"}, []string{"7700 900123", "0800 12"}, "+44"},
+		{config.LocaleDE, []string{"+49 30 1234567", "030 12345678", "0151 23456789", "+49 (0)151-23456789"}, []string{"30 12345678", "0 12"}, "+49"},
+		{config.LocaleFR, []string{"+33 6 12 34 56 78", "06 12 34 56 78", "01.23.45.67.89"}, []string{"6 12 34 56 78", "00 12 34 56 78"}, "+33"},
+		{config.LocaleIN, []string{"+91 98765 43210", "9876543210", "098765-43210"}, []string{"1234567890", "98765 4321"}, "+91"},
+		{config.LocaleCN, []string{"+86 138 1234 5678", "13812345678", "138-1234-5678", "0086 138 1234 5678", "(+86) 13812345678"}, []string{"12812345678", "1381234567"}, "+86"},
+		{config.LocaleJP, []string{"+81 90 1234 5678", "090-1234-5678", "03-1234-5678"}, []string{"1234-5678", "0312345678"}, "+81"},
+		{config.LocaleIntl, []string{"+41 44 668 18 00", "+351 21 123 4567"}, []string{"41 44 668 18 00", "+1"}, "+41"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.locale, func(t *testing.T) {
+			re := regexp.MustCompile(PhoneLocalePatterns[tt.locale])
+			for _, s := range tt.match {
+				if got := re.FindString(s); got != s {
+					t.Errorf("Expected %q to match in full, got %q", s, got)
+				}
+			}
+			for _, s := range tt.noMatch {
+				if re.MatchString(s) {
+					t.Errorf("Expected %q not to match, got %q", s, re.FindString(s))
+				}
+			}
+			loc := re.FindStringSubmatchIndex(tt.match[0])
+			if got := config.ExpandTemplate(re, "${country}", tt.match[0], loc); got != tt.country {
+				t.Errorf("Expected country %q, got %q", tt.country, got)
+			}
+		})
+	}
+
+	for _, l := range config.PhoneLocales {
+		if _, ok := PhoneLocalePatterns[l]; !ok {
+			t.Errorf("Expected a phone pattern pack for locale %q", l)
+		}
+	}
+}
+
+// TestSSNLocalePatterns tests the national ID pattern packs
+func TestSSNLocalePatterns(t *testing.T) {
+	tests := []struct {
+		locale  string
+		match   []string
+		noMatch []string
+	}{
+		{config.LocaleUS, []string{"123-45-6789"}, []string{"123456789"}},
+		{config.LocaleUK, []string{"AB 12 34 56 C", "JG103759A"}, []string{"DA 12 34 56 C", "AB 12 34 56 E"}},
+		{config.LocaleFR, []string{"1 85 05 78 006 084 36", "285122A123456 78"}, []string{"3 85 05 78 006 084 36", "1 85 13 78 006 084 36"}},
+		{config.LocaleIN, []string{"2345 6789 0123", "987654321098"}, []string{"1234 5678 9012", "2345 6789 012"}},
+		{config.LocaleCN, []string{"11010519491231002X", "440524188001010014"}, []string{"110105194913310021", "11010517491231002X"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.locale, func(t *testing.T) {
+			re := regexp.MustCompile(SSNLocalePatterns[tt.locale])
+			for _, s := range tt.match {
+				if got := re.FindString(s); got != s {
+					t.Errorf("Expected %q to match in full, got %q", s, got)
+				}
+			}
+			for _, s := range tt.noMatch {
+				if re.MatchString(s) {
+					t.Errorf("Expected %q not to match, got %q", s, re.FindString(s))
+				}
+			}
+		})
+	}
+
+	for _, l := range config.SSNLocales {
+		if _, ok := SSNLocalePatterns[l]; !ok {
+			t.Errorf("Expected a national ID pattern pack for locale %q", l)
+		}
+	}
+}
+
+// TestMerchantIDPattern tests the Chinese payment merchant ID pattern
+func TestMerchantIDPattern(t *testing.T) {
+	for _, s := range []string{"2088102146225135", "wx8888888888888888", "mch_id=1230000109", "sub_mch_id = 1900000109", "Merchant No: 1230000109", "商户号：1230000109"} {
+		if got := MerchantIDPattern.FindString(s); got != s {
+			t.Errorf("Expected %q to match in full, got %q", s, got)
+		}
+	}
+	for _, s := range []string{"20881021462251350", "wx88888888888888zz", "order 1230000109", "mch_id=12345"} {
+		if MerchantIDPattern.MatchString(s) {
+			t.Errorf("Expected %q not to match, got %q", s, MerchantIDPattern.FindString(s))
+		}
+	}
+}
+
+// TestPhonePattern_Locales tests choosing the phone pattern by locale
+func TestPhonePattern_Locales(t *testing.T) {
+	cache := NewPatternCache()
+
+	for _, locales := range [][]string{nil, {}, {config.LocaleUS}, {"xx"}} {
+		if got := cache.PhonePattern(&config.Config{PhoneLocales: locales}); got != defaultPhonePattern {
+			t.Errorf("Expected the default pattern for %v, got %s", locales, got)
+		}
+	}
+
+	cfg := &config.Config{PhoneLocales: []string{config.LocaleUS, config.LocaleUK, config.LocaleUK}}
+	re := cache.PhonePattern(cfg)
+	for _, s := range []string{"(555) 123-4567", "07700 900123"} {
+		if re.FindString(s) != s {
+			t.Errorf("Expected %q to match the US and UK pattern, got %q", s, re.FindString(s))
+		}
+	}
+
+	cfg.CustomPhonePattern = `\d{3}-\d{4}`
+	if got := cache.PhonePattern(cfg).String(); got != cfg.CustomPhonePattern {
+		t.Errorf("Expected the custom pattern to take precedence, got %s", got)
+	}
+
+	cfg = &config.Config{SSNLocales: []string{config.LocaleUS, config.LocaleUK}}
+	if re := cache.SSNPattern(cfg); !re.MatchString("123-45-6789") || !re.MatchString("AB 12 34 56 C") {
+		t.Errorf("Expected the SSN pattern to match US and UK numbers, got %s", re)
+	}
+	if got := cache.SSNPattern(&config.Config{}); got != defaultSSNPattern {
+		t.Errorf("Expected the default SSN pattern without locales, got %s", got)
+	}
+
+	cfg = &config.Config{ChinaPack: true}
+	if re := cache.PhonePattern(cfg); !re.MatchString("(555) 123-4567") || !re.MatchString("+86 138 1234 5678") {
+		t.Errorf("Expected the China pack to add to the default phone pattern, got %s", re)
+	}
+	if re := cache.SSNPattern(cfg); !re.MatchString("123-45-6789") || !re.MatchString("11010519491231002X") {
+		t.Errorf("Expected the China pack to add to the default SSN pattern, got %s", re)
+	}
+}
@@ -0,0 +1,33 @@
+package patterns
+
+import "regexp"
+
+// Location patterns match geographic positions copied from maps and
+// telemetry. Coordinates must fall within ±90° latitude and ±180° longitude.
+const (
+	// plusCodeChars is the Open Location Code alphabet
+	plusCodeChars = `[23456789CFGHJMPQRVWX]`
+
+	// latitudeDecimal and longitudeDecimal are degrees with at least four
+	// decimals, about 10 m, so prices and measurements are not matched
+	latitudeDecimal  = `(?:90\.0{4,}|[1-8]?\d\.\d{4,})`
+	longitudeDecimal = `(?:180\.0{4,}|(?:1[0-7]\d|[1-9]?\d)\.\d{4,})`
+
+	// dmsMinutesSeconds is the minutes and optional seconds of a position
+	// in degrees, minutes and seconds
+	dmsMinutesSeconds = `\s*[0-5]?\d(?:\.\d+)?['′]\s*(?:[0-5]?\d(?:\.\d+)?(?:"|″|'')\s*)?`
+
+	// LocationPatternStr matches decimal latitude/longitude pairs, such as
+	// "37.7749, -122.4194", "51.5074° N, 0.1278° W" or labelled as in
+	// "lat": -33.8688, "lng": 151.2093, pairs in degrees, minutes and
+	// seconds such as 40°26'46"N 79°58'56"W, and plus codes, full
+	// (849VCWC8+R9) or short (CWC8+R9)
+	LocationPatternStr = `(?i:\blat(?:itude)?)["']?\s*[:=]\s*["']?[-+]?` + latitudeDecimal + `["']?[\s,;&]*["']?(?i:lng|lon|long|longitude)["']?\s*[:=]\s*["']?[-+]?` + longitudeDecimal +
+		`|(?P<lat>(?:[-+]|\b)` + latitudeDecimal + `)°?(?:\s*[NS]\b)?(?:\s*[,;/]\s*|\s+)(?P<lon>(?:[-+]|\b)` + longitudeDecimal + `)°?(?:\s*[EW]\b)?` +
+		`|\b(?:90|[1-8]?\d)°` + dmsMinutesSeconds + `[NS][\s,]*(?:180|1[0-7]\d|0?\d?\d)°` + dmsMinutesSeconds + `[EW]\b` +
+		`|\b[2-9C][2-9CFGHJMPQRV]` + plusCodeChars + `{6}\+` + plusCodeChars + `{2,3}\b` +
+		`|\b(?:` + plusCodeChars + `{2})?` + plusCodeChars + `{4}\+` + plusCodeChars + `{2,3}\b`
+)
+
+// LocationPattern is the compiled LocationPatternStr
+var LocationPattern = regexp.MustCompile(LocationPatternStr)
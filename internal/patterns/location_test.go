@@ -0,0 +1,37 @@
+package patterns
+
+import "testing"
+
+// TestLocationPattern tests the coordinate and plus code pattern
+func TestLocationPattern(t *testing.T) {
+	match := []string{
+		"37.7749, -122.4194",
+		"37.7749,-122.4194",
+		"-33.8688 151.2093",
+		"51.5074° N, 0.1278° W",
+		"lat=-33.8688 lon=151.2093",
+		`lat": -33.8688, "lng": 151.2093`,
+		`40°26'46"N 79°58'56"W`,
+		"849VCWC8+R9",
+		"CWC8+R9",
+	}
+	for _, s := range match {
+		if got := LocationPattern.FindString(s); got != s {
+			t.Errorf("Expected %q to match in full, got %q", s, got)
+		}
+	}
+
+	noMatch := []string{
+		"91.0000, 10.0000",  // Latitude out of range
+		"45.0000, 181.0000", // Longitude out of range
+		"price 12.50, 3.99", // Too few decimals
+		"version 1.2.3.4",
+		"AB12+CD", // Not plus code characters
+		"lat: 33.8688",
+	}
+	for _, s := range noMatch {
+		if LocationPattern.MatchString(s) {
+			t.Errorf("Expected %q not to match, got %q", s, LocationPattern.FindString(s))
+		}
+	}
+}
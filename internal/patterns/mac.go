@@ -0,0 +1,20 @@
+package patterns
+
+import "regexp"
+
+// hexPair is one octet of a hardware address
+const hexPair = `[0-9A-Fa-f]{2}`
+
+// MACPatternStr matches MAC addresses (EUI-48) and EUI-64 identifiers,
+// separated by colons or hyphens (00:1a:2b:3c:4d:5e) or in Cisco's dotted
+// notation (001a.2b3c.4d5e). EUI-64 comes first so that its first six
+// octets are not matched as a MAC address.
+const MACPatternStr = `(?:` + hexPair + `:){7}` + hexPair +
+	`|(?:` + hexPair + `-){7}` + hexPair +
+	`|(?:[0-9A-Fa-f]{4}\.){3}[0-9A-Fa-f]{4}` +
+	`|(?:` + hexPair + `:){5}` + hexPair +
+	`|(?:` + hexPair + `-){5}` + hexPair +
+	`|(?:[0-9A-Fa-f]{4}\.){2}[0-9A-Fa-f]{4}`
+
+// MACPattern is the compiled MACPatternStr
+var MACPattern = regexp.MustCompile(MACPatternStr)
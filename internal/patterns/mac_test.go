@@ -0,0 +1,32 @@
+package patterns
+
+import "testing"
+
+// TestMACPattern tests the MAC address and EUI-64 pattern
+func TestMACPattern(t *testing.T) {
+	match := []string{
+		"00:1a:2b:3c:4d:5e",
+		"00-1A-2B-3C-4D-5E",
+		"001a.2b3c.4d5e",
+		"00:1a:2b:ff:fe:3c:4d:5e",
+		"00-1A-2B-FF-FE-3C-4D-5E",
+		"001a.2bff.fe3c.4d5e",
+	}
+	for _, s := range match {
+		if got := MACPattern.FindString(s); got != s {
+			t.Errorf("Expected %q to match in full, got %q", s, got)
+		}
+	}
+
+	noMatch := []string{
+		"12:34:56",
+		"00:1a:2b-3c:4d:5e", // Mixed separators
+		"2001:db8::1",
+		"1.2.3",
+	}
+	for _, s := range noMatch {
+		if got := MACPattern.FindString(s); got != "" {
+			t.Errorf("Expected no match in %q, got %q", s, got)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package patterns
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Password prose patterns find passwords given in sentences and notes, as in
+// "the password is hunter2", "pwd: hunter2" or "Passwort lautet hunter2",
+// where the value is an ordinary word that no value pattern could match
+var (
+	// PasswordKeywords maps languages to the words naming a password
+	PasswordKeywords = map[string][]string{
+		"en": {"password", "passwd", "pwd", "pw", "pass", "passphrase", "passcode", "pass phrase"},
+		"de": {"passwort", "kennwort", "zugangscode"},
+		"fr": {"mot de passe", "mdp"},
+		"es": {"contraseña", "clave"},
+		"it": {"parola d'ordine"},
+		"pt": {"senha"},
+		"nl": {"wachtwoord"},
+		"ru": {"пароль"},
+		"zh": {"密码", "口令"},
+		"ja": {"パスワード", "暗証番号"},
+		"ko": {"비밀번호", "암호"},
+	}
+
+	// PasswordConnectors are the words between a keyword and its value,
+	// besides a colon or an equals sign
+	PasswordConnectors = []string{
+		"is", "was", "will be", "should be", "ist", "lautet", "est", "es", "era", "è", "é", "is now", "set to",
+	}
+
+	// PasswordStopwords are the words following a keyword and a connector
+	// that describe the password rather than give it, as in "the password
+	// is incorrect"
+	PasswordStopwords = []string{
+		"a", "an", "the", "my", "your", "not", "too", "also", "still", "now", "being", "required", "optional",
+		"incorrect", "wrong", "invalid", "correct", "missing", "empty", "blank", "expired", "changed", "reset",
+		"set", "same", "different", "weak", "strong", "saved", "stored", "hidden", "encrypted", "hashed",
+		"protected", "needed", "case-sensitive", "sent", "below", "above", "attached", "in", "on", "for",
+		"nicht", "falsch", "ungültig", "erforderlich", "leer", "pas", "requis", "vide", "no", "incorrecta",
+		"obligatoria", "vacía", "errata", "incorreta", "onjuist", "неверный",
+	}
+
+	// passwordAttachedConnectors follow a keyword without a space, in
+	// languages written without them
+	passwordAttachedConnectors = []string{"是", "为", "為", "は", "는", "은"}
+)
+
+// PasswordProsePatternStr matches a password keyword followed by a colon,
+// an equals sign or a connector such as "is", capturing the next token,
+// possibly quoted, as value
+var PasswordProsePatternStr = passwordProsePattern()
+
+// PasswordProsePattern is the compiled PasswordProsePatternStr
+var PasswordProsePattern = regexp.MustCompile(PasswordProsePatternStr)
+
+// passwordProsePattern builds PasswordProsePatternStr from the keyword and
+// connector lists, longest first so that "pass phrase" wins over "pass"
+func passwordProsePattern() string {
+	var keywords []string
+	for _, words := range PasswordKeywords {
+		keywords = append(keywords, words...)
+	}
+	return `(?i)(?:^|[^\p{L}\p{N}_])["'` + "`" + `]?(?:` + alternation(keywords) + `)["'` + "`" + `]?` +
+		`(?:\s*[:=：]|[ \t]+(?:` + alternation(PasswordConnectors) + `)(?:[ \t]*[:=])?|(?:` + alternation(passwordAttachedConnectors) + `))` +
+		`[ \t]*["'` + "`" + `“„«]?(?P<value>[^\s"'` + "`" + `”“«»]+)`
+}
+
+// alternation joins words into an alternation, longest first, matching any
+// whitespace between their words
+func alternation(words []string) string {
+	sorted := append([]string{}, words...)
+	sort.Slice(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+	quoted := make([]string, len(sorted))
+	for i, w := range sorted {
+		quoted[i] = strings.ReplaceAll(regexp.QuoteMeta(w), " ", `\s+`)
+	}
+	return strings.Join(quoted, "|")
+}
@@ -23,11 +23,13 @@ type Pattern struct {
 	Pattern string
 }
 
-// Default patterns for sensitive data detection
+// Default patterns for sensitive data detection. Replacements may reference
+// their named groups: user and domain for emails, country for phones (see also
+// PhoneLocalePatterns) and last4 for credit cards.
 const (
-	DefaultEmailPatternStr      = `[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}`
-	DefaultPhonePatternStr      = `(\+\d{1,3}[\s-]?)?\(?\d{3}\)?[\s.-]?\d{3}[\s.-]?\d{4}`
-	DefaultCreditCardPatternStr = `\b(?:\d{4}[- ]?){3}\d{4}\b`
+	DefaultEmailPatternStr      = `(?P<user>[a-zA-Z0-9._%+-]+)@(?P<domain>[a-zA-Z0-9.-]+\.[a-zA-Z]{2,})`
+	DefaultPhonePatternStr      = `(?:(?P<country>\+\d{1,3})[\s-]?)?\(?\d{3}\)?[\s.-]?\d{3}[\s.-]?\d{4}`
+	DefaultCreditCardPatternStr = `\b(?:\d{4}[- ]?){3}(?P<last4>\d{4})\b`
 	DefaultSSNPatternStr        = `\b\d{3}-\d{2}-\d{4}\b`
 	DefaultIPV4PatternStr       = `\b((25[0-5]|2[0-4][0-9]|[01]?[0-9][0-9]?)\.){3}(25[0-5]|2[0-4][0-9]|[01]?[0-9][0-9]?)\b`
 )
@@ -41,19 +43,25 @@ var (
 	defaultIPV4Pattern       = regexp.MustCompile(DefaultIPV4PatternStr)
 )
 
-// PatternCache caches compiled regular expressions to avoid recompilation
+// PatternCache caches compiled regular expressions to avoid recompilation.
+// Entries are keyed by both the pattern type and the pattern source, so a
+// changed custom pattern is never served from a stale entry.
 type PatternCache struct {
 	mu       sync.RWMutex
 	patterns map[string]*regexp.Regexp
 }
 
-// globalCache is the global pattern cache instance
-var globalCache = &PatternCache{
-	patterns: make(map[string]*regexp.Regexp),
+// NewPatternCache creates an empty pattern cache
+func NewPatternCache() *PatternCache {
+	return &PatternCache{
+		patterns: make(map[string]*regexp.Regexp),
+	}
 }
 
 // Get retrieves a compiled pattern from cache or compiles and caches it
 func (pc *PatternCache) Get(key string, patternStr string) (*regexp.Regexp, error) {
+	key = key + "\x00" + patternStr
+
 	// Fast path: read lock for cache hit
 	pc.mu.RLock()
 	if pattern, ok := pc.patterns[key]; ok {
@@ -82,11 +90,34 @@ func (pc *PatternCache) Clear() {
 	pc.patterns = make(map[string]*regexp.Regexp)
 }
 
-// GetEmailPattern returns the appropriate email pattern based on configuration
-func GetEmailPattern(cfg *config.Config) *regexp.Regexp {
+// Set holds the compiled patterns for one configuration
+type Set struct {
+	Email      *regexp.Regexp
+	Phone      *regexp.Regexp
+	CreditCard *regexp.Regexp
+	SSN        *regexp.Regexp
+	IPV4       *regexp.Regexp
+	LicenseKey *regexp.Regexp // nil if no format is valid
+}
+
+// Compile returns the compiled patterns for cfg, using the default pattern
+// for any custom pattern that is empty or fails to compile
+func (pc *PatternCache) Compile(cfg *config.Config) Set {
+	return Set{
+		Email:      pc.EmailPattern(cfg),
+		Phone:      pc.PhonePattern(cfg),
+		CreditCard: pc.CreditCardPattern(cfg),
+		SSN:        pc.SSNPattern(cfg),
+		IPV4:       pc.IPV4Pattern(cfg),
+		LicenseKey: pc.LicenseKeyPattern(cfg),
+	}
+}
+
+// EmailPattern returns the appropriate email pattern based on configuration
+func (pc *PatternCache) EmailPattern(cfg *config.Config) *regexp.Regexp {
 	if cfg != nil && cfg.CustomEmailPattern != "" {
 		// Try to get from cache or compile custom pattern, fallback to default if it fails
-		pattern, err := globalCache.Get("email", cfg.CustomEmailPattern)
+		pattern, err := pc.Get("email", cfg.CustomEmailPattern)
 		if err == nil {
 			return pattern
 		}
@@ -94,23 +125,28 @@ func GetEmailPattern(cfg *config.Config) *regexp.Regexp {
 	return defaultEmailPattern
 }
 
-// GetPhonePattern returns the appropriate phone pattern based on configuration
-func GetPhonePattern(cfg *config.Config) *regexp.Regexp {
+// PhonePattern returns the appropriate phone pattern based on configuration:
+// the custom pattern if set, otherwise the packs of the configured locales
+func (pc *PatternCache) PhonePattern(cfg *config.Config) *regexp.Regexp {
 	if cfg != nil && cfg.CustomPhonePattern != "" {
 		// Try to get from cache or compile custom pattern, fallback to default if it fails
-		pattern, err := globalCache.Get("phone", cfg.CustomPhonePattern)
+		pattern, err := pc.Get("phone", cfg.CustomPhonePattern)
 		if err == nil {
 			return pattern
 		}
 	}
-	return defaultPhonePattern
+	if cfg == nil {
+		return defaultPhonePattern
+	}
+	locales := config.WithPack(cfg.PhoneLocales, cfg.ChinaPack, config.LocaleCN)
+	return pc.localePattern("phoneLocales", PhoneLocalePatterns, locales, defaultPhonePattern)
 }
 
-// GetCreditCardPattern returns the appropriate credit card pattern based on configuration
-func GetCreditCardPattern(cfg *config.Config) *regexp.Regexp {
+// CreditCardPattern returns the appropriate credit card pattern based on configuration
+func (pc *PatternCache) CreditCardPattern(cfg *config.Config) *regexp.Regexp {
 	if cfg != nil && cfg.CustomCreditCardPattern != "" {
 		// Try to get from cache or compile custom pattern, fallback to default if it fails
-		pattern, err := globalCache.Get("creditCard", cfg.CustomCreditCardPattern)
+		pattern, err := pc.Get("creditCard", cfg.CustomCreditCardPattern)
 		if err == nil {
 			return pattern
 		}
@@ -118,23 +154,29 @@ func GetCreditCardPattern(cfg *config.Config) *regexp.Regexp {
 	return defaultCreditCardPattern
 }
 
-// GetSSNPattern returns the appropriate SSN pattern based on configuration
-func GetSSNPattern(cfg *config.Config) *regexp.Regexp {
+// SSNPattern returns the appropriate SSN pattern based on configuration:
+// the custom pattern if set, otherwise the national ID packs of the
+// configured locales
+func (pc *PatternCache) SSNPattern(cfg *config.Config) *regexp.Regexp {
 	if cfg != nil && cfg.CustomSSNPattern != "" {
 		// Try to get from cache or compile custom pattern, fallback to default if it fails
-		pattern, err := globalCache.Get("ssn", cfg.CustomSSNPattern)
+		pattern, err := pc.Get("ssn", cfg.CustomSSNPattern)
 		if err == nil {
 			return pattern
 		}
 	}
-	return defaultSSNPattern
+	if cfg == nil {
+		return defaultSSNPattern
+	}
+	locales := config.WithPack(cfg.SSNLocales, cfg.ChinaPack, config.LocaleCN)
+	return pc.localePattern("ssnLocales", SSNLocalePatterns, locales, defaultSSNPattern)
 }
 
-// GetIPV4Pattern returns the appropriate IPv4 pattern based on configuration
-func GetIPV4Pattern(cfg *config.Config) *regexp.Regexp {
+// IPV4Pattern returns the appropriate IPv4 pattern based on configuration
+func (pc *PatternCache) IPV4Pattern(cfg *config.Config) *regexp.Regexp {
 	if cfg != nil && cfg.CustomIPV4Pattern != "" {
 		// Try to get from cache or compile custom pattern, fallback to default if it fails
-		pattern, err := globalCache.Get("ipv4", cfg.CustomIPV4Pattern)
+		pattern, err := pc.Get("ipv4", cfg.CustomIPV4Pattern)
 		if err == nil {
 			return pattern
 		}
@@ -108,39 +108,107 @@ func TestPatternCache_Concurrent(t *testing.T) {
 	}
 }
 
-// TestGetEmailPattern_WithCache tests email pattern caching
-func TestGetEmailPattern_WithCache(t *testing.T) {
-	// Clear global cache before test
-	globalCache.Clear()
+// TestPatternCache_PatternChange tests that a changed pattern under the same key is recompiled
+func TestPatternCache_PatternChange(t *testing.T) {
+	cache := &PatternCache{
+		patterns: make(map[string]*regexp.Regexp),
+	}
+
+	pattern1, err := cache.Get("email", `a@b\.com`)
+	if err != nil {
+		t.Fatalf("Failed to compile pattern: %v", err)
+	}
+
+	pattern2, err := cache.Get("email", `c@d\.com`)
+	if err != nil {
+		t.Fatalf("Failed to compile changed pattern: %v", err)
+	}
+
+	if pattern1 == pattern2 {
+		t.Fatal("Expected a new pattern instance after the pattern changed")
+	}
+	if !pattern2.MatchString("c@d.com") || pattern2.MatchString("a@b.com") {
+		t.Errorf("Expected changed pattern to be served, got %s", pattern2.String())
+	}
+}
+
+// TestEmailPattern_RuntimeChange tests that a config change takes effect without clearing the cache
+func TestEmailPattern_RuntimeChange(t *testing.T) {
+	cache := NewPatternCache()
+
+	cfg := &config.Config{CustomEmailPattern: `[a-z]+@old\.com`}
+	if !cache.EmailPattern(cfg).MatchString("user@old.com") {
+		t.Fatal("Expected initial custom pattern to match")
+	}
+
+	cfg.CustomEmailPattern = `[a-z]+@new\.com`
+	pattern := cache.EmailPattern(cfg)
+	if !pattern.MatchString("user@new.com") {
+		t.Error("Expected updated custom pattern to match")
+	}
+	if pattern.MatchString("user@old.com") {
+		t.Error("Expected stale custom pattern not to be served")
+	}
+
+	// Reverting to the default should use the default pattern
+	cfg.CustomEmailPattern = ""
+	if cache.EmailPattern(cfg) != defaultEmailPattern {
+		t.Error("Expected default email pattern after clearing custom pattern")
+	}
+}
+
+// TestPatternCache_Compile tests compiling a full pattern set from config
+func TestPatternCache_Compile(t *testing.T) {
+	cache := NewPatternCache()
+
+	set := cache.Compile(&config.Config{
+		CustomPhonePattern: `\d{11}`,
+		CustomSSNPattern:   `[invalid`,
+	})
+
+	if set.Email != defaultEmailPattern || set.CreditCard != defaultCreditCardPattern || set.IPV4 != defaultIPV4Pattern {
+		t.Error("Expected default patterns where no custom pattern is set")
+	}
+	if set.SSN != defaultSSNPattern {
+		t.Error("Expected default SSN pattern for invalid custom pattern")
+	}
+	if !set.Phone.MatchString("13800138000") {
+		t.Error("Expected custom phone pattern to be compiled")
+	}
+}
+
+// TestEmailPattern_WithCache tests email pattern caching
+func TestEmailPattern_WithCache(t *testing.T) {
+	cache := NewPatternCache()
 
 	cfg := &config.Config{
 		CustomEmailPattern: `[a-zA-Z0-9]+@test\.com`,
 	}
 
 	// First call should compile and cache
-	pattern1 := GetEmailPattern(cfg)
+	pattern1 := cache.EmailPattern(cfg)
 	if pattern1 == nil {
 		t.Fatal("Expected non-nil pattern")
 	}
 
 	// Second call should return cached pattern
-	pattern2 := GetEmailPattern(cfg)
+	pattern2 := cache.EmailPattern(cfg)
 	if pattern1 != pattern2 {
 		t.Error("Expected same pattern instance from cache")
 	}
 
 	// Verify cache contains the pattern
-	if len(globalCache.patterns) != 1 {
-		t.Errorf("Expected 1 cached pattern, got %d", len(globalCache.patterns))
+	if len(cache.patterns) != 1 {
+		t.Errorf("Expected 1 cached pattern, got %d", len(cache.patterns))
 	}
 }
 
-// TestGetEmailPattern_Default tests default pattern fallback
-func TestGetEmailPattern_Default(t *testing.T) {
-	globalCache.Clear()
+// TestEmailPattern_Default tests default pattern fallback
+func TestEmailPattern_Default(t *testing.T) {
+	cache := NewPatternCache()
 
 	// Test with nil config
-	pattern1 := GetEmailPattern(nil)
+	pattern1 := cache.EmailPattern(nil)
 	if pattern1 != defaultEmailPattern {
 		t.Error("Expected default email pattern for nil config")
 	}
@@ -149,26 +217,26 @@ func TestGetEmailPattern_Default(t *testing.T) {
 	cfg := &config.Config{
 		CustomEmailPattern: "",
 	}
-	pattern2 := GetEmailPattern(cfg)
+	pattern2 := cache.EmailPattern(cfg)
 	if pattern2 != defaultEmailPattern {
 		t.Error("Expected default email pattern for empty custom pattern")
 	}
 
 	// Cache should be empty
-	if len(globalCache.patterns) != 0 {
-		t.Errorf("Expected 0 cached patterns, got %d", len(globalCache.patterns))
+	if len(cache.patterns) != 0 {
+		t.Errorf("Expected 0 cached patterns, got %d", len(cache.patterns))
 	}
 }
 
-// TestGetEmailPattern_InvalidCustom tests fallback on invalid custom pattern
-func TestGetEmailPattern_InvalidCustom(t *testing.T) {
-	globalCache.Clear()
+// TestEmailPattern_InvalidCustom tests fallback on invalid custom pattern
+func TestEmailPattern_InvalidCustom(t *testing.T) {
+	cache := NewPatternCache()
 
 	cfg := &config.Config{
 		CustomEmailPattern: `[invalid`,
 	}
 
-	pattern := GetEmailPattern(cfg)
+	pattern := cache.EmailPattern(cfg)
 	if pattern != defaultEmailPattern {
 		t.Error("Expected default email pattern for invalid custom pattern")
 	}
@@ -176,7 +244,7 @@ func TestGetEmailPattern_InvalidCustom(t *testing.T) {
 
 // TestAllPatternGetters tests all pattern getter functions
 func TestAllPatternGetters(t *testing.T) {
-	globalCache.Clear()
+	cache := NewPatternCache()
 
 	cfg := &config.Config{
 		CustomEmailPattern:      `custom@email`,
@@ -190,11 +258,11 @@ func TestAllPatternGetters(t *testing.T) {
 		name   string
 		getter func(*config.Config) *regexp.Regexp
 	}{
-		{"Email", GetEmailPattern},
-		{"Phone", GetPhonePattern},
-		{"CreditCard", GetCreditCardPattern},
-		{"SSN", GetSSNPattern},
-		{"IPv4", GetIPV4Pattern},
+		{"Email", cache.EmailPattern},
+		{"Phone", cache.PhonePattern},
+		{"CreditCard", cache.CreditCardPattern},
+		{"SSN", cache.SSNPattern},
+		{"IPv4", cache.IPV4Pattern},
 	}
 
 	for _, tt := range tests {
@@ -213,13 +281,13 @@ func TestAllPatternGetters(t *testing.T) {
 	}
 
 	// Should have 5 cached patterns
-	if len(globalCache.patterns) != 5 {
-		t.Errorf("Expected 5 cached patterns, got %d", len(globalCache.patterns))
+	if len(cache.patterns) != 5 {
+		t.Errorf("Expected 5 cached patterns, got %d", len(cache.patterns))
 	}
 }
 
-// BenchmarkGetEmailPattern_WithoutCache benchmarks pattern compilation without cache
-func BenchmarkGetEmailPattern_WithoutCache(b *testing.B) {
+// BenchmarkEmailPattern_WithoutCache benchmarks pattern compilation without cache
+func BenchmarkEmailPattern_WithoutCache(b *testing.B) {
 	customPattern := `[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}`
 
 	b.ResetTimer()
@@ -228,9 +296,9 @@ func BenchmarkGetEmailPattern_WithoutCache(b *testing.B) {
 	}
 }
 
-// BenchmarkGetEmailPattern_WithCache benchmarks pattern retrieval with cache
-func BenchmarkGetEmailPattern_WithCache(b *testing.B) {
-	globalCache.Clear()
+// BenchmarkEmailPattern_WithCache benchmarks pattern retrieval with cache
+func BenchmarkEmailPattern_WithCache(b *testing.B) {
+	cache := NewPatternCache()
 
 	cfg := &config.Config{
 		CustomEmailPattern: `[a-zA-Z0-9._%+-]+@[a-zA-Z0-9.-]+\.[a-zA-Z]{2,}`,
@@ -238,7 +306,7 @@ func BenchmarkGetEmailPattern_WithCache(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		GetEmailPattern(cfg)
+		cache.EmailPattern(cfg)
 	}
 }
 
@@ -0,0 +1,19 @@
+package patterns
+
+import "regexp"
+
+// shellLogin is the user@host part of a shell prompt
+const shellLogin = `(?P<login>[A-Za-z_][\w.-]*@[A-Za-z0-9][\w.-]*)`
+
+// ShellPromptPatternStr matches shell prompts at the start of lines of
+// copied terminal output, capturing their user@host part as login: Debian
+// style (jane@prod-db-01:~$), Red Hat style ([jane@prod-db-01 ~]$) and zsh
+// style (jane@prod-db-01 ~ %), optionally after a virtualenv or conda
+// environment such as (venv)
+const ShellPromptPatternStr = `(?m)^[ \t]*(?:\([\w.-]+\)[ \t]*)?(?:` +
+	`\[` + shellLogin + `(?:[ \t]+[^\]\r\n]*)?\][$#%]` +
+	`|` + shellLogin + `(?::[^\s$#%>]*)?[ \t]*[$#%>](?:[ \t]|$)` +
+	`|` + shellLogin + `[ \t]+[~/]\S*[ \t]+[$#%](?:[ \t]|$))`
+
+// ShellPromptPattern is the compiled ShellPromptPatternStr
+var ShellPromptPattern = regexp.MustCompile(ShellPromptPatternStr)
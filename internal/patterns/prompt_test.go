@@ -0,0 +1,38 @@
+package patterns
+
+import "testing"
+
+// TestShellPromptPattern tests the shell prompt pattern
+func TestShellPromptPattern(t *testing.T) {
+	tests := []struct {
+		text  string
+		login string
+	}{
+		{"jane@prod-db-01:~$ ls -la", "jane@prod-db-01"},
+		{"root@web-2:/var/log# tail syslog", "root@web-2"},
+		{"[jane@prod-db-01 ~]$ uptime", "jane@prod-db-01"},
+		{"[root@ip-10-0-0-12 nginx]# nginx -t", "root@ip-10-0-0-12"},
+		{"jane@MacBook-Pro ~ % git status", "jane@MacBook-Pro"},
+		{"(venv) jane@laptop:~/src$ pytest", "jane@laptop"},
+		{"output\njane@host.example.com:~$", "jane@host.example.com"},
+		{"jane@example.com wrote:", ""},
+		{"git@github.com:owner/repo.git", ""},
+		{"contact jane@example.com: $5 off", ""},
+	}
+
+	names := ShellPromptPattern.SubexpNames()
+	for _, tt := range tests {
+		got := ""
+		if loc := ShellPromptPattern.FindStringSubmatchIndex(tt.text); loc != nil {
+			for i := 1; i < len(names); i++ {
+				if names[i] == "login" && loc[2*i] >= 0 {
+					got = tt.text[loc[2*i]:loc[2*i+1]]
+					break
+				}
+			}
+		}
+		if got != tt.login {
+			t.Errorf("Expected %q in %q, got %q", tt.login, tt.text, got)
+		}
+	}
+}
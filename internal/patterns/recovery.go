@@ -0,0 +1,31 @@
+package patterns
+
+import "regexp"
+
+// Recovery code patterns find the blocks of backup codes that sites show
+// when a second factor is set up
+const (
+	// RecoveryHeaderPatternStr matches the phrases introducing a block of
+	// codes, such as "recovery codes" or "backup verification codes"
+	RecoveryHeaderPatternStr = `(?i)\b(?:recovery|backup|emergency|scratch|2fa|two[- ]factor|mfa)[ \t]+(?:verification[ \t]+|login[ \t]+)?codes?\b`
+
+	// RecoveryCodePatternStr matches one code of 8 to 12 letters and
+	// digits, whole or in two groups separated by a hyphen or a space, as in
+	// 3a9f1c7e2b, 4f2a1-9c3e7 or 1234 5678
+	RecoveryCodePatternStr = `[A-Za-z0-9]{8,12}|[A-Za-z0-9]{3,6}[- ][A-Za-z0-9]{3,6}`
+
+	// RecoveryLinePrefixPatternStr matches the bullet or number before the
+	// codes of a line, as in "- " or "3. "
+	RecoveryLinePrefixPatternStr = `^[ \t]*(?:[-*•][ \t]+|\d{1,2}[.)][ \t]+)?`
+)
+
+var (
+	// RecoveryHeaderPattern is the compiled RecoveryHeaderPatternStr
+	RecoveryHeaderPattern = regexp.MustCompile(RecoveryHeaderPatternStr)
+
+	// RecoveryCodePattern is the compiled RecoveryCodePatternStr
+	RecoveryCodePattern = regexp.MustCompile(RecoveryCodePatternStr)
+
+	// RecoveryLinePrefixPattern is the compiled RecoveryLinePrefixPatternStr
+	RecoveryLinePrefixPattern = regexp.MustCompile(RecoveryLinePrefixPatternStr)
+)
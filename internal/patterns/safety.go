@@ -0,0 +1,37 @@
+package patterns
+
+import "regexp"
+
+// Prompt safety patterns match text that tries to subvert a model rather
+// than sensitive data: phrases overriding its instructions, attempts to give
+// it another role, and markers of data exfiltration.
+const (
+	// PromptInjectionPatternStr matches requests to ignore earlier
+	// instructions, e.g. "ignore all previous instructions"
+	PromptInjectionPatternStr = `(?i)\b(?:ignore|disregard|forget|override|bypass)\s+(?:(?:all|any|the|your|my|of|these|those)\s+)*(?:previous|prior|above|earlier|preceding|system|original)\s+(?:instructions?|prompts?|rules|directions|directives|guidelines|context)\b`
+
+	// RoleOverridePatternStr matches jailbreak role changes and chat
+	// template markers that pose as a system message
+	RoleOverridePatternStr = `(?im)\byou\s+are\s+now\s+(?:in\s+)?(?:DAN\b|developer\s+mode|jailbroken|unrestricted|unfiltered|an?\s+(?:unrestricted|unfiltered|uncensored)\b)` +
+		`|\b(?:enable|activate|enter|switch\s+to)\s+(?:developer|god|jailbreak|DAN)\s+mode\b` +
+		`|\bpretend\s+(?:that\s+)?you\s+(?:have\s+no|are\s+not\s+bound\s+by|don'?t\s+have)\s+(?:any\s+)?(?:rules|restrictions|guidelines|filters)\b` +
+		`|<\|im_start\|>\s*system|<\|system\|>|\[/?(?:INST|SYSTEM)\]|</?system>|^\s*#{2,}\s*system\s*:`
+
+	// DataExfiltrationPatternStr matches Markdown images whose URL carries
+	// query data, requests to reveal the system prompt and requests to send
+	// data to a URL or domain
+	DataExfiltrationPatternStr = `(?i)!\[[^\]]*\]\(\s*https?://[^\s)]+[?&][^\s)=]+=[^\s)]*\)` +
+		`|\b(?:reveal|print|repeat|output|show|leak|display)\s+(?:me\s+)?(?:your|the)\s+(?:(?:full|entire|hidden|initial|original)\s+)*(?:system\s+prompt|instructions|initial\s+prompt|hidden\s+prompt)\b` +
+		`|\b(?:send|post|upload|exfiltrate|forward|transmit)\s+(?:(?:all|the|this|these|any|my|our)\s+)*(?:data|conversation|chat\s+history|credentials|secrets|api\s+keys?|passwords?|tokens|files)\s+to\s+(?:https?://\S+|[a-z0-9-]+(?:\.[a-z0-9-]+)*\.[a-z]{2,}\b)`
+)
+
+var (
+	// PromptInjectionPattern is the compiled PromptInjectionPatternStr
+	PromptInjectionPattern = regexp.MustCompile(PromptInjectionPatternStr)
+
+	// RoleOverridePattern is the compiled RoleOverridePatternStr
+	RoleOverridePattern = regexp.MustCompile(RoleOverridePatternStr)
+
+	// DataExfiltrationPattern is the compiled DataExfiltrationPatternStr
+	DataExfiltrationPattern = regexp.MustCompile(DataExfiltrationPatternStr)
+)
@@ -0,0 +1,45 @@
+package patterns
+
+// Special category keywords indicate the GDPR special categories of personal
+// data (Article 9). Unlike the other patterns they are lists of lowercase
+// words and phrases, matched word by word ignoring case and diacritics, and
+// only count near a reference to a person, so that "the church on Main
+// Street" is not flagged while "she attends church" is.
+const (
+	SpecialCategoryHealth   = "health_data"
+	SpecialCategoryReligion = "religious_belief"
+	SpecialCategoryUnion    = "union_membership"
+)
+
+// SpecialCategoryKeywords maps each special category to its keywords
+var SpecialCategoryKeywords = map[string][]string{
+	SpecialCategoryHealth: {
+		"diagnosed", "diagnosis", "prognosis", "medical condition", "medical history", "medical leave", "sick leave",
+		"cancer", "tumour", "tumor", "chemotherapy", "radiotherapy", "diabetes", "diabetic", "insulin", "hiv", "aids",
+		"hepatitis", "epilepsy", "epileptic", "asthma", "dementia", "alzheimer's", "parkinson's", "multiple sclerosis",
+		"depression", "anxiety disorder", "bipolar", "schizophrenia", "adhd", "autism", "autistic", "ptsd", "eating disorder",
+		"anorexia", "bulimia", "suicidal", "self harm", "psychiatrist", "psychiatric", "therapist", "antidepressants",
+		"rehab", "addiction", "alcoholism", "pregnant", "pregnancy", "miscarriage", "ivf", "disability", "disabled",
+		"wheelchair", "surgery", "prescribed", "prescription", "medication", "mri", "biopsy", "blood test",
+	},
+	SpecialCategoryReligion: {
+		"religion", "religious", "faith", "muslim", "islam", "christian", "catholic", "protestant", "evangelical",
+		"mormon", "jehovah's witness", "jewish", "judaism", "orthodox", "hindu", "buddhist", "sikh", "atheist",
+		"agnostic", "mosque", "church", "synagogue", "gurdwara", "temple", "ramadan", "kosher", "halal", "hijab",
+		"converted to", "prays", "praying", "bible study", "sabbath",
+	},
+	SpecialCategoryUnion: {
+		"trade union", "labor union", "labour union", "union member", "union membership", "union dues", "union rep",
+		"union representative", "shop steward", "unionised", "unionized", "collective bargaining", "strike ballot",
+		"picket line", "teamsters", "afl cio",
+	},
+}
+
+// SpecialCategoryContext lists the words referring to a person, one of which
+// must be near a special category keyword for it to count
+var SpecialCategoryContext = []string{
+	"i", "me", "my", "he", "him", "his", "she", "her", "they", "them", "their", "we", "our",
+	"mr", "mrs", "ms", "dr", "patient", "employee", "employees", "colleague", "colleagues", "staff",
+	"customer", "client", "candidate", "applicant", "tenant", "student", "member", "members", "son", "daughter",
+	"wife", "husband", "partner", "mother", "father",
+}
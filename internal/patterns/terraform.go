@@ -0,0 +1,34 @@
+package patterns
+
+import "regexp"
+
+// Terraform patterns find secret attributes in HCL configuration, tfvars
+// and snippets of state or plan JSON, which do not parse as a whole
+const (
+	// terraformSecretKey matches attribute names ending like a credential,
+	// e.g. access_key, secret_key, client_secret, db_password or
+	// private_key_pem, but not access_key_id or password_length
+	terraformSecretKey = `[A-Za-z0-9_-]*(?i:password|passwd|secret|secret_key|access_key|private_key|private_key_pem|token|api_key|connection_string)`
+
+	// TerraformSecretKeyPatternStr matches a whole secret attribute name
+	TerraformSecretKeyPatternStr = `^` + terraformSecretKey + `$`
+
+	// TerraformSecretPatternStr matches an attribute assigned a quoted
+	// string, as key = "value" in HCL or "key": "value" in JSON
+	TerraformSecretPatternStr = `(?m)(?:^|[\s{,])"?(?P<key>` + terraformSecretKey + `)"?[ \t]*[:=][ \t]*"(?P<value>(?:[^"\\\n]|\\.)*)"`
+
+	// TerraformHeredocPatternStr matches an attribute assigned a heredoc, as
+	// private_key = <<-EOT, capturing the delimiter
+	TerraformHeredocPatternStr = `(?m)^[ \t]*(?P<key>` + terraformSecretKey + `)[ \t]*=[ \t]*<<-?(?P<marker>[A-Za-z_][A-Za-z0-9_]*)[ \t]*\r?$`
+)
+
+var (
+	// TerraformSecretKeyPattern is the compiled TerraformSecretKeyPatternStr
+	TerraformSecretKeyPattern = regexp.MustCompile(TerraformSecretKeyPatternStr)
+
+	// TerraformSecretPattern is the compiled TerraformSecretPatternStr
+	TerraformSecretPattern = regexp.MustCompile(TerraformSecretPatternStr)
+
+	// TerraformHeredocPattern is the compiled TerraformHeredocPatternStr
+	TerraformHeredocPattern = regexp.MustCompile(TerraformHeredocPatternStr)
+)
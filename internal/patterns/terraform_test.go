@@ -0,0 +1,17 @@
+package patterns
+
+import "testing"
+
+// TestTerraformSecretKeyPattern tests which attribute names count as secrets
+func TestTerraformSecretKeyPattern(t *testing.T) {
+	for _, key := range []string{"access_key", "secret_key", "secret", "client_secret", "db_password", "private_key_pem", "auth_token", "primary_connection_string"} {
+		if !TerraformSecretKeyPattern.MatchString(key) {
+			t.Errorf("Expected %q to be a secret attribute", key)
+		}
+	}
+	for _, key := range []string{"access_key_id", "password_length", "secret_arn", "key_name", "token_ttl"} {
+		if TerraformSecretKeyPattern.MatchString(key) {
+			t.Errorf("Expected %q not to be a secret attribute", key)
+		}
+	}
+}
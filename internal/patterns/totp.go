@@ -0,0 +1,32 @@
+package patterns
+
+import "regexp"
+
+// TOTP patterns match one-time password seeds, which let anyone generate a
+// user's second factor codes
+const (
+	// OTPAuthURIPatternStr matches otpauth:// URIs, as encoded in the QR
+	// codes of authenticator apps, and Google Authenticator export URIs,
+	// which carry the secret, issuer and account together
+	OTPAuthURIPatternStr = `(?i)\botpauth(?:-migration)?://[^\s"'<>]+`
+
+	// TOTPSeedPatternStr matches base32 seeds of 16 or more characters,
+	// contiguous or in groups of four as shown by setup pages, in upper
+	// or lower case
+	TOTPSeedPatternStr = `\b(?:[A-Z2-7]{16,}={0,6}|[a-z2-7]{16,}={0,6}|(?:[A-Z2-7]{4} ){3,}[A-Z2-7]{4}|(?:[a-z2-7]{4} ){3,}[a-z2-7]{4})`
+
+	// TOTPContextPatternStr matches the words that introduce a seed, which
+	// must precede it for it to be detected
+	TOTPContextPatternStr = `(?i)\b(?:2fa|two[- ]factor|mfa|multi[- ]factor|totp|hotp|otp|authenticator|(?:secret|setup|recovery|backup)[ _-]?key|seed)`
+)
+
+var (
+	// OTPAuthURIPattern is the compiled OTPAuthURIPatternStr
+	OTPAuthURIPattern = regexp.MustCompile(OTPAuthURIPatternStr)
+
+	// TOTPSeedPattern is the compiled TOTPSeedPatternStr
+	TOTPSeedPattern = regexp.MustCompile(TOTPSeedPatternStr)
+
+	// TOTPContextPattern is the compiled TOTPContextPatternStr
+	TOTPContextPattern = regexp.MustCompile(TOTPContextPatternStr)
+)
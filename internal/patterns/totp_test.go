@@ -0,0 +1,35 @@
+package patterns
+
+import "testing"
+
+// TestTOTPPatterns tests the otpauth URI and base32 seed patterns
+func TestTOTPPatterns(t *testing.T) {
+	uris := []string{
+		"otpauth://totp/ACME:jane@example.com?secret=JBSWY3DPEHPK3PXP&issuer=ACME",
+		"otpauth://hotp/Example?secret=JBSWY3DPEHPK3PXP&counter=0",
+		"otpauth-migration://offline?data=CjEKCkhlbGxvId6tvu8SGGFsaWNl",
+	}
+	for _, s := range uris {
+		if got := OTPAuthURIPattern.FindString(s); got != s {
+			t.Errorf("Expected %q to match in full, got %q", s, got)
+		}
+	}
+
+	seeds := []string{
+		"JBSWY3DPEHPK3PXP",
+		"JBSWY3DPEHPK3PXPJBSWY3DPEHPK3PXP==",
+		"jbsw y3dp ehpk 3pxp",
+		"JBSW Y3DP EHPK 3PXP",
+	}
+	for _, s := range seeds {
+		if got := TOTPSeedPattern.FindString(s); got != s {
+			t.Errorf("Expected %q to match in full, got %q", s, got)
+		}
+	}
+
+	for _, s := range []string{"JBSWY3DPEHPK", "JBSW y3dp EHPK 3pxp", "0123456789ABCDEF01"} {
+		if got := TOTPSeedPattern.FindString(s); got != "" {
+			t.Errorf("Expected no seed in %q, got %q", s, got)
+		}
+	}
+}
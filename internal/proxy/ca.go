@@ -0,0 +1,256 @@
+package proxy
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/hex"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Files of the local CA in the data directory
+const (
+	CACertFile = "proxy-ca.pem"
+	CAKeyFile  = "proxy-ca-key.pem"
+)
+
+const (
+	// caValidity is how long a generated CA is valid
+	caValidity = 10 * 365 * 24 * time.Hour
+
+	// leafValidity is how long a certificate issued for a host is valid
+	leafValidity = 30 * 24 * time.Hour
+
+	// leafRenewBefore is how long before it expires a cached certificate
+	// is issued again
+	leafRenewBefore = 24 * time.Hour
+
+	// maxCachedCerts bounds the certificates kept in memory
+	maxCachedCerts = 1024
+)
+
+// CA is the local certificate authority issuing the certificates the proxy
+// presents for intercepted hosts. Issued certificates are cached until
+// shortly before they expire.
+type CA struct {
+	cert     *x509.Certificate
+	domains  []string // Domains the CA may issue certificates for
+	key      *ecdsa.PrivateKey
+	certPath string
+	mu       sync.Mutex
+	cache    map[string]*tls.Certificate
+	now      func() time.Time
+}
+
+// LoadOrCreateCA loads the CA stored in dir, generating and storing a new
+// one if there is none. A new CA is name constrained to domains and their
+// subdomains, so that trusting it does not let it impersonate other sites.
+// A stored CA without name constraints is refused.
+func LoadOrCreateCA(dir string, domains []string) (*CA, error) {
+	certPath, keyPath := filepath.Join(dir, CACertFile), filepath.Join(dir, CAKeyFile)
+	certPEM, err := os.ReadFile(certPath)
+	if errors.Is(err, os.ErrNotExist) {
+		if err := createCA(certPath, keyPath, domains); err != nil {
+			return nil, err
+		}
+		certPEM, err = os.ReadFile(certPath)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA certificate: %v", err)
+	}
+	keyPEM, err := os.ReadFile(keyPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA key: %v", err)
+	}
+
+	pair, err := tls.X509KeyPair(certPEM, keyPEM)
+	if err != nil {
+		return nil, fmt.Errorf("invalid CA in %s: %v", dir, err)
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("invalid CA certificate: %v", err)
+	}
+	key, ok := pair.PrivateKey.(*ecdsa.PrivateKey)
+	if !ok || !cert.IsCA {
+		return nil, fmt.Errorf("invalid CA in %s: expected an ECDSA CA certificate", dir)
+	}
+	if len(cert.PermittedDNSDomains) == 0 {
+		return nil, fmt.Errorf("the CA in %s may issue certificates for any site; delete %s and %s to generate a name constrained CA, and trust it again", dir, CACertFile, CAKeyFile)
+	}
+	return &CA{cert: cert, domains: cert.PermittedDNSDomains, key: key, certPath: certPath, cache: make(map[string]*tls.Certificate), now: time.Now}, nil
+}
+
+// createCA generates a CA constrained to domains, excluding every IP
+// address, and writes its certificate and key, readable only by the user
+func createCA(certPath, keyPath string, domains []string) error {
+	if len(domains) == 0 {
+		return errors.New("no domains to issue certificates for")
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return err
+	}
+	hostname, _ := os.Hostname()
+	now := time.Now()
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: "prompt-security local CA " + hostname, Organization: []string{"prompt-security"}},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(caValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+
+		PermittedDNSDomainsCritical: true,
+		PermittedDNSDomains:         domains,
+		ExcludedIPRanges: []*net.IPNet{
+			{IP: net.IPv4zero, Mask: net.CIDRMask(0, 32)},
+			{IP: net.IPv6zero, Mask: net.CIDRMask(0, 128)},
+		},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return fmt.Errorf("failed to create CA certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(certPath), 0700); err != nil {
+		return fmt.Errorf("failed to create data directory: %v", err)
+	}
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		return fmt.Errorf("failed to write CA key: %v", err)
+	}
+	if err := os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		return fmt.Errorf("failed to write CA certificate: %v", err)
+	}
+	return nil
+}
+
+// CertPath returns the path of the CA certificate, which clients must trust
+func (ca *CA) CertPath() string {
+	return ca.certPath
+}
+
+// Fingerprint returns the SHA-256 fingerprint of the CA certificate
+func (ca *CA) Fingerprint() string {
+	sum := sha256.Sum256(ca.cert.Raw)
+	return strings.ToUpper(hex.EncodeToString(sum[:]))
+}
+
+// NotAfter returns when the CA certificate expires
+func (ca *CA) NotAfter() time.Time {
+	return ca.cert.NotAfter
+}
+
+// Certificate returns a certificate for host issued by the CA, from the
+// cache unless it expires soon. Hosts outside the domains of the CA are
+// refused.
+func (ca *CA) Certificate(host string) (*tls.Certificate, error) {
+	host = strings.ToLower(host)
+	if !ca.permits(host) {
+		return nil, fmt.Errorf("%s is outside the domains the CA issues certificates for", host)
+	}
+	now := ca.now()
+
+	ca.mu.Lock()
+	defer ca.mu.Unlock()
+	if cert, ok := ca.cache[host]; ok && now.Before(cert.Leaf.NotAfter.Add(-leafRenewBefore)) {
+		return cert, nil
+	}
+
+	cert, err := ca.issue(host, now)
+	if err != nil {
+		return nil, err
+	}
+	if len(ca.cache) >= maxCachedCerts {
+		ca.evict(now)
+	}
+	ca.cache[host] = cert
+	return cert, nil
+}
+
+// permits reports whether host is one of the domains of the CA or a
+// subdomain of one
+func (ca *CA) permits(host string) bool {
+	for _, d := range ca.domains {
+		d = strings.ToLower(strings.TrimPrefix(d, "."))
+		if host == d || strings.HasSuffix(host, "."+d) {
+			return true
+		}
+	}
+	return false
+}
+
+// issue creates a certificate for host. The caller must hold mu.
+func (ca *CA) issue(host string, now time.Time) (*tls.Certificate, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	serial, err := randomSerial()
+	if err != nil {
+		return nil, err
+	}
+	template := &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: host},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(leafValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	template.DNSNames = []string{host}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to issue certificate for %s: %v", host, err)
+	}
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, err
+	}
+	return &tls.Certificate{Certificate: [][]byte{der, ca.cert.Raw}, PrivateKey: key, Leaf: leaf}, nil
+}
+
+// evict drops the certificates that expire soon, or the one expiring first
+// if none do. The caller must hold mu.
+func (ca *CA) evict(now time.Time) {
+	var first string
+	for host, cert := range ca.cache {
+		if !now.Before(cert.Leaf.NotAfter.Add(-leafRenewBefore)) {
+			delete(ca.cache, host)
+		} else if first == "" || cert.Leaf.NotAfter.Before(ca.cache[first].Leaf.NotAfter) {
+			first = host
+		}
+	}
+	if len(ca.cache) >= maxCachedCerts {
+		delete(ca.cache, first)
+	}
+}
+
+// randomSerial returns a random certificate serial number
+func randomSerial() (*big.Int, error) {
+	return rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+}
@@ -0,0 +1,59 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// PACPath is where the proxy serves its PAC file
+const PACPath = "/proxy.pac"
+
+// PAC returns a proxy auto-config file sending traffic to hosts through the
+// proxy at proxyAddr and everything else, including bypassed hosts,
+// directly. Hosts and bypassed hosts are names, *. wildcards matching
+// subdomains or * for every host.
+func PAC(proxyAddr string, hosts, bypass []string) []byte {
+	var b strings.Builder
+	b.WriteString("// Generated by prompt-security: only the hosts below go through the proxy\n")
+	b.WriteString("function FindProxyForURL(url, host) {\n")
+	b.WriteString("\thost = host.toLowerCase();\n")
+	fmt.Fprintf(&b, "\tif (%s) {\n\t\treturn \"DIRECT\";\n\t}\n", pacCondition(bypass))
+	fmt.Fprintf(&b, "\tif (%s) {\n\t\treturn %s;\n\t}\n", pacCondition(hosts), pacString("PROXY "+proxyAddr))
+	b.WriteString("\treturn \"DIRECT\";\n}\n")
+	return []byte(b.String())
+}
+
+// pacCondition returns a PAC expression true for the hosts matching
+// patterns
+func pacCondition(patterns []string) string {
+	seen := make(map[string]bool)
+	var conditions []string
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		if pattern == "" || seen[pattern] {
+			continue
+		}
+		seen[pattern] = true
+		switch {
+		case pattern == "*":
+			return "true"
+		case strings.HasPrefix(pattern, "*."):
+			conditions = append(conditions, fmt.Sprintf("dnsDomainIs(host, %s)", pacString(pattern[1:])))
+		default:
+			conditions = append(conditions, fmt.Sprintf("host == %s", pacString(pattern)))
+		}
+	}
+	if len(conditions) == 0 {
+		return "false"
+	}
+	sort.Strings(conditions)
+	return strings.Join(conditions, " ||\n\t\t")
+}
+
+// pacString returns s as a JavaScript string literal
+func pacString(s string) string {
+	quoted, _ := json.Marshal(s)
+	return string(quoted)
+}
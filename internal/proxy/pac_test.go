@@ -0,0 +1,58 @@
+package proxy
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestPAC tests that routed hosts go through the proxy unless bypassed
+func TestPAC(t *testing.T) {
+	pac := string(PAC("localhost:8383", []string{"api.openai.com", "*.llm.corp", "API.openai.com"}, []string{"*.apple.com"}))
+
+	for _, want := range []string{
+		`function FindProxyForURL(url, host)`,
+		`if (dnsDomainIs(host, ".apple.com")) {
+		return "DIRECT";`,
+		`if (dnsDomainIs(host, ".llm.corp") ||
+		host == "api.openai.com") {
+		return "PROXY localhost:8383";`,
+	} {
+		if !strings.Contains(pac, want) {
+			t.Errorf("Expected the PAC file to contain %q, got:\n%s", want, pac)
+		}
+	}
+
+	pac = string(PAC("localhost:8383", []string{"*"}, nil))
+	if !strings.Contains(pac, "if (false) {\n\t\treturn \"DIRECT\"") || !strings.Contains(pac, "if (true) {\n\t\treturn \"PROXY") {
+		t.Errorf("Expected every host to go through the proxy, got:\n%s", pac)
+	}
+}
+
+// TestProxy_PAC tests that the proxy serves its PAC file for the address
+// it is reached at
+func TestProxy_PAC(t *testing.T) {
+	server := httptest.NewServer(New(Options{
+		Intercept: func(string) (http.Handler, bool) { return nil, false },
+		Allow:     func(string) bool { return false },
+		PAC: func(proxyAddr string) []byte {
+			return PAC(proxyAddr, []string{"api.openai.com"}, nil)
+		},
+	}))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + PACPath)
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Content-Type") != "application/x-ns-proxy-autoconfig" {
+		t.Errorf("Expected a PAC file, got %d %s", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	if !strings.Contains(string(body), `"PROXY `+strings.TrimPrefix(server.URL, "http://")+`"`) {
+		t.Errorf("Expected the PAC file to point at the proxy, got:\n%s", body)
+	}
+}
@@ -0,0 +1,216 @@
+// Package proxy is an intercepting HTTP proxy for apps that cannot be
+// pointed at the gateway. HTTPS connections to model provider APIs are
+// decrypted with certificates issued by a local CA, which clients must
+// trust, and redacted by the gateway; all other traffic, and traffic to
+// bypassed hosts, is tunneled without being decrypted.
+package proxy
+
+import (
+	"crypto/tls"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// dialTimeout bounds connecting to the destination of a tunnel
+const dialTimeout = 30 * time.Second
+
+// Options configure a Proxy
+type Options struct {
+	// CA issues the certificates presented for intercepted hosts
+	CA *CA
+
+	// Intercept returns the handler redacting requests to a host, false
+	// if traffic to the host is not intercepted
+	Intercept func(host string) (http.Handler, bool)
+
+	// Bypass, if set, reports whether traffic to a host is tunneled
+	// without being decrypted even though it could be intercepted
+	Bypass func(host string) bool
+
+	// Allow, if set, reports whether traffic to a host is allowed at all
+	Allow func(host string) bool
+
+	// PAC, if set, returns the PAC file served at PACPath, given the
+	// address clients reach the proxy at
+	PAC func(proxyAddr string) []byte
+}
+
+// Proxy is an HTTP proxy intercepting HTTPS traffic to model providers
+type Proxy struct {
+	opts    Options
+	forward *httputil.ReverseProxy
+	logger  *slog.Logger
+}
+
+// New creates an intercepting proxy
+func New(opts Options) *Proxy {
+	return &Proxy{
+		opts: opts,
+		// Plain HTTP requests carry absolute URLs and are sent as they are
+		forward: &httputil.ReverseProxy{Director: func(*http.Request) {}},
+		logger:  slog.New(slog.NewJSONHandler(os.Stdout, nil)),
+	}
+}
+
+// ServeHTTP tunnels or intercepts CONNECT requests and forwards plain HTTP
+// requests
+func (p *Proxy) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if p.opts.PAC != nil && r.Method == http.MethodGet && !r.URL.IsAbs() && r.URL.Path == PACPath {
+		w.Header().Set("Content-Type", "application/x-ns-proxy-autoconfig")
+		w.Write(p.opts.PAC(r.Host))
+		return
+	}
+
+	host := r.URL.Hostname()
+	if r.Method == http.MethodConnect {
+		host, _, _ = net.SplitHostPort(r.Host)
+	}
+	if p.opts.Allow != nil && !p.opts.Allow(host) {
+		p.logger.Warn("Refused proxy request to a disallowed host", "host", host)
+		http.Error(w, "Requests to "+host+" are not allowed by policy", http.StatusForbidden)
+		return
+	}
+
+	if r.Method != http.MethodConnect {
+		if !r.URL.IsAbs() {
+			http.Error(w, "This is a proxy; send absolute URLs or CONNECT", http.StatusBadRequest)
+			return
+		}
+		p.forward.ServeHTTP(w, r)
+		return
+	}
+
+	handler, intercept := p.opts.Intercept(host)
+	if intercept && p.opts.Bypass != nil && p.opts.Bypass(host) {
+		p.logger.Info("Bypassing interception", "host", host)
+		intercept = false
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "Connection cannot be taken over", http.StatusInternalServerError)
+		return
+	}
+	var upstream net.Conn
+	if !intercept {
+		// Connect first, so the client learns if the destination fails
+		var err error
+		if upstream, err = net.DialTimeout("tcp", r.Host, dialTimeout); err != nil {
+			http.Error(w, "Failed to reach "+r.Host, http.StatusBadGateway)
+			return
+		}
+	}
+	conn, _, err := hijacker.Hijack()
+	if err != nil {
+		if upstream != nil {
+			upstream.Close()
+		}
+		return
+	}
+	if _, err := io.WriteString(conn, "HTTP/1.1 200 Connection Established\r\n\r\n"); err != nil {
+		conn.Close()
+		if upstream != nil {
+			upstream.Close()
+		}
+		return
+	}
+
+	if !intercept {
+		tunnel(conn, upstream)
+		return
+	}
+	p.logger.Info("Intercepting HTTPS", "host", host)
+	p.intercept(conn, host, handler)
+}
+
+// intercept terminates TLS on conn with a certificate for host and serves
+// the requests sent over it with handler. The certificate is only ever
+// issued for the CONNECT host: a client asking for another server name is
+// refused, so it cannot have the CA issue certificates for other sites.
+func (p *Proxy) intercept(conn net.Conn, host string, handler http.Handler) {
+	tlsConn := tls.Server(conn, &tls.Config{
+		GetCertificate: func(hello *tls.ClientHelloInfo) (*tls.Certificate, error) {
+			if hello.ServerName != "" && !strings.EqualFold(hello.ServerName, host) {
+				p.logger.Warn("Refused TLS server name differing from the CONNECT host", "host", host, "server_name", hello.ServerName)
+				return nil, fmt.Errorf("server name %q does not match %s", hello.ServerName, host)
+			}
+			return p.opts.CA.Certificate(host)
+		},
+		NextProtos: []string{"http/1.1"},
+		MinVersion: tls.VersionTLS12,
+	})
+	server := &http.Server{Handler: handler, ErrorLog: slog.NewLogLogger(p.logger.Handler(), slog.LevelWarn)}
+	server.Serve(newConnListener(tlsConn))
+}
+
+// tunnel copies data both ways between two connections until either side
+// is done
+func tunnel(a, b net.Conn) {
+	done := make(chan struct{}, 2)
+	copyConn := func(dst, src net.Conn) {
+		io.Copy(dst, src)
+		done <- struct{}{}
+	}
+	go copyConn(a, b)
+	go copyConn(b, a)
+	<-done
+	a.Close()
+	b.Close()
+}
+
+// connListener is a listener accepting a single connection. Accept blocks
+// after it until the connection is closed, so the server keeps serving it.
+type connListener struct {
+	conn   net.Conn
+	closed chan struct{}
+	once   sync.Once
+	taken  bool
+	mu     sync.Mutex
+}
+
+func newConnListener(conn net.Conn) *connListener {
+	l := &connListener{closed: make(chan struct{})}
+	l.conn = &closeNotifyConn{Conn: conn, onClose: func() { l.once.Do(func() { close(l.closed) }) }}
+	return l
+}
+
+func (l *connListener) Accept() (net.Conn, error) {
+	l.mu.Lock()
+	if !l.taken {
+		l.taken = true
+		l.mu.Unlock()
+		return l.conn, nil
+	}
+	l.mu.Unlock()
+	<-l.closed
+	return nil, net.ErrClosed
+}
+
+func (l *connListener) Close() error {
+	l.once.Do(func() { close(l.closed) })
+	return nil
+}
+
+func (l *connListener) Addr() net.Addr {
+	return l.conn.LocalAddr()
+}
+
+// closeNotifyConn calls onClose when the connection is closed
+type closeNotifyConn struct {
+	net.Conn
+	onClose func()
+}
+
+func (c *closeNotifyConn) Close() error {
+	err := c.Conn.Close()
+	c.onClose()
+	return err
+}
@@ -0,0 +1,193 @@
+package proxy
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestCA tests that the CA is stored and reloaded, and that certificates
+// chain to it and are cached until shortly before they expire
+func TestCA(t *testing.T) {
+	dir := t.TempDir()
+	ca, err := LoadOrCreateCA(dir, []string{"openai.com"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	again, err := LoadOrCreateCA(dir, []string{"openai.com"})
+	if err != nil || again.Fingerprint() != ca.Fingerprint() {
+		t.Fatalf("Expected the stored CA to be loaded, got %v", err)
+	}
+
+	cert, err := ca.Certificate("API.openai.com")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	if _, err := cert.Leaf.Verify(x509.VerifyOptions{DNSName: "api.openai.com", Roots: roots}); err != nil {
+		t.Errorf("Expected the certificate to verify against the CA: %v", err)
+	}
+
+	if cached, _ := ca.Certificate("api.openai.com"); cached != cert {
+		t.Error("Expected the certificate to be cached")
+	}
+	ca.now = func() time.Time { return cert.Leaf.NotAfter.Add(-leafRenewBefore) }
+	if renewed, _ := ca.Certificate("api.openai.com"); renewed == cert {
+		t.Error("Expected a certificate expiring soon to be issued again")
+	}
+}
+
+// TestCA_NameConstraints tests that the CA only issues, and clients only
+// accept, certificates for its domains
+func TestCA_NameConstraints(t *testing.T) {
+	ca, err := LoadOrCreateCA(t.TempDir(), []string{"api.openai.com"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	for _, host := range []string{"bank.com", "openai.com", "evilapi.openai.com", "127.0.0.1"} {
+		if _, err := ca.Certificate(host); err == nil {
+			t.Errorf("Expected no certificate for %s", host)
+		}
+	}
+
+	// Even a certificate issued outside the constraints is not trusted
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	ca.domains = []string{"bank.com"}
+	cert, err := ca.Certificate("bank.com")
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if _, err := cert.Leaf.Verify(x509.VerifyOptions{DNSName: "bank.com", Roots: roots}); err == nil {
+		t.Error("Expected the name constraints to reject the certificate")
+	}
+
+	// A CA without name constraints is refused
+	dir := t.TempDir()
+	ca, err = LoadOrCreateCA(dir, []string{"openai.com"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	template := *ca.cert
+	template.PermittedDNSDomains, template.ExcludedIPRanges = nil, nil
+	der, err := x509.CreateCertificate(rand.Reader, &template, &template, &ca.key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, CACertFile), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadOrCreateCA(dir, []string{"openai.com"}); err == nil {
+		t.Error("Expected a CA without name constraints to be refused")
+	}
+}
+
+// newTestProxy serves a proxy intercepting intercepted with a handler
+// echoing the request path, and returns a client using it that trusts its
+// CA and the TLS test servers
+func newTestProxy(t *testing.T, opts Options, intercepted string, trusted ...*httptest.Server) *http.Client {
+	ca, err := LoadOrCreateCA(t.TempDir(), []string{"example.com"})
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	opts.CA = ca
+	opts.Intercept = func(host string) (http.Handler, bool) {
+		if host != intercepted {
+			return nil, false
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			io.WriteString(w, "intercepted "+r.URL.Path)
+		}), true
+	}
+	server := httptest.NewServer(New(opts))
+	t.Cleanup(server.Close)
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	for _, s := range trusted {
+		roots.AddCert(s.Certificate())
+	}
+	proxyURL, _ := url.Parse(server.URL)
+	return &http.Client{Transport: &http.Transport{
+		Proxy:           http.ProxyURL(proxyURL),
+		TLSClientConfig: &tls.Config{RootCAs: roots},
+	}}
+}
+
+// get returns the status and body of a GET request
+func get(t *testing.T, client *http.Client, url string) (int, string) {
+	resp, err := client.Get(url)
+	if err != nil {
+		return 0, err.Error()
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+// TestProxy_Intercept tests that traffic to intercepted hosts is decrypted
+// and handled, over one connection for several requests
+func TestProxy_Intercept(t *testing.T) {
+	client := newTestProxy(t, Options{}, "api.example.com")
+
+	for i := 0; i < 2; i++ {
+		if status, body := get(t, client, "https://api.example.com/v1/models"); status != http.StatusOK || body != "intercepted /v1/models" {
+			t.Errorf("Expected the request to be intercepted, got %d: %s", status, body)
+		}
+	}
+}
+
+// TestProxy_ServerNameMismatch tests that a client asking for another server
+// name than the CONNECT host gets no certificate
+func TestProxy_ServerNameMismatch(t *testing.T) {
+	client := newTestProxy(t, Options{}, "api.example.com")
+	transport := client.Transport.(*http.Transport)
+	transport.TLSClientConfig.ServerName = "bank.example.com"
+
+	if status, body := get(t, client, "https://api.example.com/v1/models"); status != 0 {
+		t.Errorf("Expected the handshake to fail, got %d: %s", status, body)
+	}
+}
+
+// TestProxy_Tunnel tests that other and bypassed hosts are tunneled to the
+// real server
+func TestProxy_Tunnel(t *testing.T) {
+	upstream := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.WriteString(w, "upstream")
+	}))
+	defer upstream.Close()
+	host, _, _ := net.SplitHostPort(strings.TrimPrefix(upstream.URL, "https://"))
+
+	client := newTestProxy(t, Options{}, "api.example.com", upstream)
+	if status, body := get(t, client, upstream.URL); status != http.StatusOK || body != "upstream" {
+		t.Errorf("Expected other hosts to be tunneled, got %d: %s", status, body)
+	}
+
+	bypass := func(string) bool { return true }
+	client = newTestProxy(t, Options{Bypass: bypass}, host, upstream)
+	if status, body := get(t, client, upstream.URL); status != http.StatusOK || body != "upstream" {
+		t.Errorf("Expected bypassed hosts to be tunneled, got %d: %s", status, body)
+	}
+}
+
+// TestProxy_Disallowed tests that traffic to disallowed hosts is refused
+func TestProxy_Disallowed(t *testing.T) {
+	allow := func(host string) bool { return host != "api.example.com" }
+	client := newTestProxy(t, Options{Allow: allow}, "api.example.com")
+
+	if status, body := get(t, client, "https://api.example.com/v1/models"); status == http.StatusOK {
+		t.Errorf("Expected the request to be refused, got %d: %s", status, body)
+	}
+}
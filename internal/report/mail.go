@@ -0,0 +1,253 @@
+package report
+
+import (
+	"bytes"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log/slog"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+	"github.com/happytaoer/prompt-security/internal/db"
+)
+
+// PasswordEnv holds the SMTP password, kept out of the database and the
+// configuration API
+const PasswordEnv = "PROMPT_SECURITY_SMTP_PASSWORD"
+
+const (
+	// mailInterval is how often Run checks for a finished week to send
+	mailInterval = time.Hour
+
+	// mailTimeout bounds connecting to the SMTP server and sending a report
+	mailTimeout = time.Minute
+)
+
+// ErrNoRecipients is returned when reports are e-mailed without any
+// configured recipients
+var ErrNoRecipients = errors.New("no report recipients are configured")
+
+// Mailer e-mails the weekly report to the configured recipients once each
+// week, Monday to Sunday, is over
+type Mailer struct {
+	manager *config.Manager
+	logger  *slog.Logger
+	now     func() time.Time
+}
+
+// NewMailer creates a mailer sending reports with the settings of manager
+func NewMailer(manager *config.Manager) *Mailer {
+	return &Mailer{
+		manager: manager,
+		logger:  slog.New(slog.NewJSONHandler(os.Stdout, nil)),
+		now:     time.Now,
+	}
+}
+
+// Run sends the report of each finished week while recipients are
+// configured (blocking). A week that fails to send is retried.
+func (m *Mailer) Run() {
+	for {
+		if cfg := m.manager.Get(); len(cfg.ReportRecipients) > 0 {
+			if err := m.sendDue(cfg); err != nil {
+				m.logger.Warn("Failed to e-mail report", "smtp_host", cfg.SMTPHost, "error", err)
+			}
+		}
+		time.Sleep(mailInterval)
+	}
+}
+
+// sendDue sends the report of the last finished week unless it was sent
+func (m *Mailer) sendDue(cfg config.Config) error {
+	until := LastWeek(m.now())
+	day := until.Format(dateFormat)
+	sent, err := db.GetReportSentUntil()
+	if err != nil || sent >= day {
+		return err
+	}
+
+	r, err := Generate(PeriodWeek, until)
+	if err != nil {
+		return err
+	}
+	if err := Send(cfg, r); err != nil {
+		return err
+	}
+	m.logger.Info("E-mailed weekly report", "recipients", len(cfg.ReportRecipients), "until", day)
+	return db.SetReportSentUntil(day)
+}
+
+// LastWeek returns the Sunday ending the last finished week before t
+func LastWeek(t time.Time) time.Time {
+	back := int(t.Weekday())
+	if back == 0 {
+		back = 7 // Sunday is not over yet
+	}
+	return time.Date(t.Year(), t.Month(), t.Day()-back, 0, 0, 0, 0, t.Location())
+}
+
+// Send e-mails a report to the recipients of cfg through its SMTP server
+func Send(cfg config.Config, r Summary) error {
+	if len(cfg.ReportRecipients) == 0 {
+		return ErrNoRecipients
+	}
+	msg, err := Message(cfg.SMTPFrom, cfg.ReportRecipients, r)
+	if err != nil {
+		return err
+	}
+
+	from, err := mail.ParseAddress(cfg.SMTPFrom)
+	if err != nil {
+		return fmt.Errorf("invalid sender %q: %v", cfg.SMTPFrom, err)
+	}
+	to := make([]string, len(cfg.ReportRecipients))
+	for i, rcpt := range cfg.ReportRecipients {
+		addr, err := mail.ParseAddress(rcpt)
+		if err != nil {
+			return fmt.Errorf("invalid recipient %q: %v", rcpt, err)
+		}
+		to[i] = addr.Address
+	}
+	return sendMail(cfg, from.Address, to, msg)
+}
+
+// sendMail delivers msg over SMTP, encrypted as cfg.SMTPTLS asks and
+// logging in if a username is configured
+func sendMail(cfg config.Config, from string, to []string, msg []byte) error {
+	port := cfg.SMTPPort
+	if port == 0 {
+		port = config.DefaultSMTPPort
+	}
+	addr := net.JoinHostPort(cfg.SMTPHost, strconv.Itoa(port))
+	tlsConfig := &tls.Config{ServerName: cfg.SMTPHost, MinVersion: tls.VersionTLS12}
+
+	dialer := &net.Dialer{Timeout: mailTimeout}
+	var conn net.Conn
+	var err error
+	if cfg.SMTPTLS == config.SMTPImplicitTLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, tlsConfig)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to connect to %s: %v", addr, err)
+	}
+	conn.SetDeadline(time.Now().Add(mailTimeout))
+
+	c, err := smtp.NewClient(conn, cfg.SMTPHost)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to greet %s: %v", addr, err)
+	}
+	defer c.Close()
+
+	if cfg.SMTPTLS == "" || cfg.SMTPTLS == config.SMTPStartTLS {
+		if ok, _ := c.Extension("STARTTLS"); !ok {
+			return fmt.Errorf("%s does not support STARTTLS", addr)
+		}
+		if err := c.StartTLS(tlsConfig); err != nil {
+			return fmt.Errorf("failed to start TLS: %v", err)
+		}
+	}
+	if cfg.SMTPUsername != "" {
+		auth := smtp.PlainAuth("", cfg.SMTPUsername, os.Getenv(PasswordEnv), cfg.SMTPHost)
+		if err := c.Auth(auth); err != nil {
+			return fmt.Errorf("failed to log in as %s: %v", cfg.SMTPUsername, err)
+		}
+	}
+
+	if err := c.Mail(from); err != nil {
+		return err
+	}
+	for _, rcpt := range to {
+		if err := c.Rcpt(rcpt); err != nil {
+			return fmt.Errorf("recipient %s refused: %v", rcpt, err)
+		}
+	}
+	w, err := c.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(msg); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return c.Quit()
+}
+
+// Message builds the e-mail of a report, with the Markdown as its plain
+// text part and the HTML page as its alternative
+func Message(from string, to []string, r Summary) ([]byte, error) {
+	page, err := r.HTML()
+	if err != nil {
+		return nil, err
+	}
+
+	var body bytes.Buffer
+	parts := multipart.NewWriter(&body)
+	for _, part := range []struct{ contentType, content string }{
+		{"text/plain; charset=utf-8", r.Markdown()},
+		{"text/html; charset=utf-8", page},
+	} {
+		w, err := parts.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		qp := quotedprintable.NewWriter(w)
+		if _, err := qp.Write([]byte(part.content)); err != nil {
+			return nil, err
+		}
+		if err := qp.Close(); err != nil {
+			return nil, err
+		}
+	}
+	if err := parts.Close(); err != nil {
+		return nil, err
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return nil, err
+	}
+	domain := "prompt-security"
+	if addr, err := mail.ParseAddress(from); err == nil {
+		if at := strings.LastIndex(addr.Address, "@"); at >= 0 {
+			domain = addr.Address[at+1:]
+		}
+	}
+
+	var msg bytes.Buffer
+	header := []struct{ name, value string }{
+		{"From", from},
+		{"To", strings.Join(to, ", ")},
+		{"Subject", mime.QEncoding.Encode("utf-8", r.Title())},
+		{"Date", time.Now().Format(time.RFC1123Z)},
+		{"Message-ID", "<" + hex.EncodeToString(id) + "@" + domain + ">"},
+		{"MIME-Version", "1.0"},
+		{"Content-Type", "multipart/alternative; boundary=" + parts.Boundary()},
+	}
+	for _, h := range header {
+		fmt.Fprintf(&msg, "%s: %s\r\n", h.name, h.value)
+	}
+	msg.WriteString("\r\n")
+	msg.Write(body.Bytes())
+	return msg.Bytes(), nil
+}
@@ -0,0 +1,126 @@
+package report
+
+import (
+	"bufio"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+)
+
+// TestLastWeek tests that the last full week ends on the Sunday before now
+func TestLastWeek(t *testing.T) {
+	sunday := time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC)
+	for _, now := range []time.Time{
+		time.Date(2026, 3, 9, 0, 30, 0, 0, time.UTC),  // Monday
+		time.Date(2026, 3, 12, 12, 0, 0, 0, time.UTC), // Thursday
+		time.Date(2026, 3, 15, 23, 0, 0, 0, time.UTC), // Sunday
+	} {
+		if got := LastWeek(now); !got.Equal(sunday) {
+			t.Errorf("%s: got %v, want %v", now.Weekday(), got, sunday)
+		}
+	}
+}
+
+// fakeSMTP accepts one message without TLS and returns its commands and
+// data
+func fakeSMTP(t *testing.T) (int, <-chan []string) {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { l.Close() })
+
+	received := make(chan []string, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r, w := bufio.NewReader(conn), bufio.NewWriter(conn)
+		reply := func(line string) { w.WriteString(line + "\r\n"); w.Flush() }
+
+		var lines []string
+		reply("220 localhost ESMTP")
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				break
+			}
+			line = strings.TrimRight(line, "\r\n")
+			lines = append(lines, line)
+			switch {
+			case strings.HasPrefix(line, "EHLO"):
+				reply("250 localhost")
+			case line == "DATA":
+				reply("354 go ahead")
+				for {
+					data, err := r.ReadString('\n')
+					if err != nil || data == ".\r\n" {
+						break
+					}
+					lines = append(lines, strings.TrimRight(data, "\r\n"))
+				}
+				reply("250 queued")
+			case line == "QUIT":
+				reply("221 bye")
+				received <- lines
+				return
+			default:
+				reply("250 ok")
+			}
+		}
+		received <- lines
+	}()
+	return l.Addr().(*net.TCPAddr).Port, received
+}
+
+// TestSend tests that the report is mailed from the configured sender to
+// every recipient, as HTML with a Markdown alternative
+func TestSend(t *testing.T) {
+	port, received := fakeSMTP(t)
+	cfg := config.Config{
+		ReportRecipients: []string{"Jane <jane@example.com>", "ops@example.com"},
+		SMTPHost:         "127.0.0.1",
+		SMTPPort:         port,
+		SMTPFrom:         "Prompt Security <reports@example.com>",
+		SMTPTLS:          config.SMTPPlain,
+	}
+	r := Summary{Period: PeriodWeek, Start: "2026-03-02", End: "2026-03-08", Detections: 3, Types: []NamedCount{{"email", 3}}}
+	if err := Send(cfg, r); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Join(<-received, "\n")
+	for _, want := range []string{
+		"MAIL FROM:<reports@example.com>",
+		"RCPT TO:<jane@example.com>",
+		"RCPT TO:<ops@example.com>",
+		"Subject: Prompt Security weekly report for 2026-03-02 to 2026-03-08",
+		"To: Jane <jane@example.com>, ops@example.com",
+		"Content-Type: text/html; charset=utf-8",
+		"| email | 3 |",
+	} {
+		if !strings.Contains(lines, want) {
+			t.Errorf("Expected %q in the SMTP session:\n%s", want, lines)
+		}
+	}
+}
+
+// TestSend_RequiresSTARTTLS tests that reports are not sent in the clear
+// unless configured, nor without recipients
+func TestSend_RequiresSTARTTLS(t *testing.T) {
+	port, _ := fakeSMTP(t)
+	cfg := config.Config{ReportRecipients: []string{"jane@example.com"}, SMTPHost: "127.0.0.1", SMTPPort: port, SMTPFrom: "reports@example.com"}
+	err := Send(cfg, Summary{Period: PeriodDay})
+	if err == nil || !strings.Contains(err.Error(), "STARTTLS") {
+		t.Errorf("Expected sending without STARTTLS support to fail, got %v", err)
+	}
+	if err := Send(config.Config{}, Summary{}); err != ErrNoRecipients {
+		t.Errorf("Expected ErrNoRecipients, got %v", err)
+	}
+}
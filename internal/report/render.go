@@ -0,0 +1,129 @@
+package report
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"strings"
+)
+
+// Report formats
+const (
+	FormatJSON     = "json"
+	FormatMarkdown = "markdown"
+	FormatHTML     = "html"
+)
+
+// ValidFormat reports whether format is a known report format
+func ValidFormat(format string) bool {
+	return format == FormatJSON || format == FormatMarkdown || format == FormatHTML
+}
+
+// Markdown renders the report as Markdown
+func (r Summary) Markdown() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n", r.Title())
+	fmt.Fprintf(&b, "- **Detections:** %d, %s\n", r.Detections, r.Trend())
+	fmt.Fprintf(&b, "- **Outbound leaks:** %d\n", r.Leaks)
+
+	if len(r.Types) > 0 {
+		b.WriteString("\n## Top types\n\n| Type | Detections |\n|------|-----------:|\n")
+		for _, c := range r.Types {
+			fmt.Fprintf(&b, "| %s | %d |\n", markdownCell(c.Name), c.Detections)
+		}
+	}
+	if len(r.Apps) > 0 {
+		b.WriteString("\n## Top applications\n\n| Application | Detections |\n|-------------|-----------:|\n")
+		for _, c := range r.Apps {
+			fmt.Fprintf(&b, "| %s | %d |\n", markdownCell(c.Name), c.Detections)
+		}
+	}
+	if len(r.Days) > 1 {
+		b.WriteString("\n## By day\n\n| Day | Detections | Leaks |\n|-----|-----------:|------:|\n")
+		for _, d := range r.Days {
+			fmt.Fprintf(&b, "| %s | %d | %d |\n", d.Date, d.Detections, d.Leaks)
+		}
+	}
+
+	b.WriteString("\n## Policy changes\n\n")
+	if len(r.Changes) == 0 {
+		b.WriteString("None.\n")
+	}
+	for _, c := range r.Changes {
+		fmt.Fprintf(&b, "- %s\n", markdownCell(c.describe()))
+	}
+	return b.String()
+}
+
+// markdownCell escapes text for a Markdown table cell or list item
+func markdownCell(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+}
+
+// htmlTemplate renders a report as a self-contained page, styled inline so
+// it survives e-mail clients
+var htmlTemplate = template.Must(template.New("report").Funcs(template.FuncMap{
+	"describe": PolicyChange.describe,
+	"counts":   func(label string, counts []NamedCount) countTable { return countTable{label, counts} },
+}).Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+</head>
+<body style="font-family: sans-serif; color: #222; max-width: 640px;">
+<h1 style="font-size: 20px;">{{.Title}}</h1>
+<p><strong>{{.Detections}}</strong> detections, {{.Trend}}.<br><strong>{{.Leaks}}</strong> outbound leaks.</p>
+{{- define "counts"}}
+<table style="border-collapse: collapse;">
+<tr><th style="text-align: left; padding: 2px 12px 2px 0;">{{.Label}}</th><th style="text-align: right;">Detections</th></tr>
+{{- range .Counts}}
+<tr><td style="padding: 2px 12px 2px 0;">{{.Name}}</td><td style="text-align: right;">{{.Detections}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+{{- if .Types}}
+<h2 style="font-size: 16px;">Top types</h2>
+{{- template "counts" (counts "Type" .Types)}}
+{{- end}}
+{{- if .Apps}}
+<h2 style="font-size: 16px;">Top applications</h2>
+{{- template "counts" (counts "Application" .Apps)}}
+{{- end}}
+{{- if gt (len .Days) 1}}
+<h2 style="font-size: 16px;">By day</h2>
+<table style="border-collapse: collapse;">
+<tr><th style="text-align: left; padding: 2px 12px 2px 0;">Day</th><th style="text-align: right; padding-right: 12px;">Detections</th><th style="text-align: right;">Leaks</th></tr>
+{{- range .Days}}
+<tr><td style="padding: 2px 12px 2px 0;">{{.Date}}</td><td style="text-align: right; padding-right: 12px;">{{.Detections}}</td><td style="text-align: right;">{{.Leaks}}</td></tr>
+{{- end}}
+</table>
+{{- end}}
+<h2 style="font-size: 16px;">Policy changes</h2>
+{{- if .Changes}}
+<ul>
+{{- range .Changes}}
+<li>{{describe .}}</li>
+{{- end}}
+</ul>
+{{- else}}
+<p>None.</p>
+{{- end}}
+</body>
+</html>
+`))
+
+// countTable is the data of a table of counts in the HTML template
+type countTable struct {
+	Label  string
+	Counts []NamedCount
+}
+
+// HTML renders the report as an HTML page
+func (r Summary) HTML() (string, error) {
+	var buf bytes.Buffer
+	if err := htmlTemplate.Execute(&buf, r); err != nil {
+		return "", fmt.Errorf("failed to render report: %v", err)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,222 @@
+// Package report summarizes a day or week of detections for people who do
+// not watch the dashboard: how many were found, of which types and from
+// which applications, the trend against the period before and the policy
+// changes made meanwhile. Reports are rendered as Markdown or HTML and
+// never include the detected text.
+package report
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/happytaoer/prompt-security/internal/db"
+)
+
+// Report periods
+const (
+	PeriodDay  = "day"
+	PeriodWeek = "week"
+)
+
+// dateFormat is the layout of the days of a report, in local time
+const dateFormat = "2006-01-02"
+
+// maxRanked bounds the types and applications listed in a report
+const maxRanked = 10
+
+// Summary is the report of a period
+type Summary struct {
+	Period     string `json:"period"` // day or week
+	Start      string `json:"start"`  // First day, YYYY-MM-DD
+	End        string `json:"end"`    // Last day, included
+	Detections int    `json:"detections"`
+	Leaks      int    `json:"leaks"` // Detections in model responses
+
+	// PreviousDetections are the detections of the period before, for the
+	// trend
+	PreviousDetections int `json:"previous_detections"`
+
+	Types   []NamedCount   `json:"types"` // Most detections first
+	Apps    []NamedCount   `json:"apps"`  // Known source applications, most detections first
+	Days    []DayCount     `json:"days"`  // Every day of the period, oldest first
+	Changes []PolicyChange `json:"changes"`
+}
+
+// NamedCount is the detections of a type or application
+type NamedCount struct {
+	Name       string `json:"name"`
+	Detections int    `json:"detections"`
+}
+
+// DayCount is the detections of one day
+type DayCount struct {
+	Date       string `json:"date"`
+	Detections int    `json:"detections"`
+	Leaks      int    `json:"leaks"`
+}
+
+// PolicyChange is a configuration or policy change
+type PolicyChange struct {
+	Time   string   `json:"time"` // RFC 3339
+	Actor  string   `json:"actor"`
+	Action string   `json:"action"`
+	Fields []string `json:"fields"` // Changed fields, if any
+}
+
+// ValidPeriod reports whether period is a known report period
+func ValidPeriod(period string) bool {
+	return period == PeriodDay || period == PeriodWeek
+}
+
+// Range returns the local days covered by a report of a period ending on
+// the day of until: from midnight of its first day until midnight after
+// its last
+func Range(period string, until time.Time) (time.Time, time.Time, error) {
+	days := 1
+	switch period {
+	case PeriodDay:
+	case PeriodWeek:
+		days = 7
+	default:
+		return time.Time{}, time.Time{}, fmt.Errorf("unknown report period %q, expected %s or %s", period, PeriodDay, PeriodWeek)
+	}
+	end := time.Date(until.Year(), until.Month(), until.Day()+1, 0, 0, 0, 0, until.Location())
+	return end.AddDate(0, 0, -days), end, nil
+}
+
+// Generate reads the report of a period ending on the day of until from
+// the database
+func Generate(period string, until time.Time) (Summary, error) {
+	start, end, err := Range(period, until)
+	if err != nil {
+		return Summary{}, err
+	}
+	previousStart := start.Add(-end.Sub(start))
+
+	events, err := db.GetDetectionEvents(previousStart, end)
+	if err != nil {
+		return Summary{}, err
+	}
+	audit, err := db.GetAuditBetween(start, end)
+	if err != nil {
+		return Summary{}, err
+	}
+	return Build(period, start, end, events, audit), nil
+}
+
+// Build summarizes the events and audit entries of the period from start
+// until before end. Events before start count towards the previous period.
+func Build(period string, start, end time.Time, events []db.DetectionEvent, audit []db.AuditEntry) Summary {
+	r := Summary{
+		Period:  period,
+		Start:   start.Format(dateFormat),
+		End:     end.AddDate(0, 0, -1).Format(dateFormat),
+		Changes: []PolicyChange{},
+	}
+
+	days := map[string]*DayCount{}
+	for d := start; d.Before(end); d = d.AddDate(0, 0, 1) {
+		r.Days = append(r.Days, DayCount{Date: d.Format(dateFormat)})
+	}
+	for i := range r.Days {
+		days[r.Days[i].Date] = &r.Days[i]
+	}
+
+	types, apps := map[string]int{}, map[string]int{}
+	for _, e := range events {
+		n := len(e.Detections) * e.Count
+		if e.Time.Before(start) {
+			if e.Kind != db.LogKindLeak {
+				r.PreviousDetections += n
+			}
+			continue
+		}
+		day := days[e.Time.In(start.Location()).Format(dateFormat)]
+		if day == nil {
+			continue
+		}
+		if e.Kind == db.LogKindLeak {
+			r.Leaks += n
+			day.Leaks += n
+			continue
+		}
+		r.Detections += n
+		day.Detections += n
+		for _, t := range e.Detections {
+			types[t] += e.Count
+		}
+		if e.App != "" {
+			apps[e.App] += n
+		}
+	}
+	r.Types, r.Apps = ranked(types), ranked(apps)
+
+	for _, a := range audit {
+		c := PolicyChange{Time: a.Timestamp, Actor: a.Actor, Action: a.Action, Fields: []string{}}
+		for _, f := range a.Changes {
+			c.Fields = append(c.Fields, f.Field)
+		}
+		r.Changes = append(r.Changes, c)
+	}
+	return r
+}
+
+// ranked returns the largest counts, most first
+func ranked(counts map[string]int) []NamedCount {
+	list := make([]NamedCount, 0, len(counts))
+	for name, n := range counts {
+		list = append(list, NamedCount{Name: name, Detections: n})
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Detections != list[j].Detections {
+			return list[i].Detections > list[j].Detections
+		}
+		return list[i].Name < list[j].Name
+	})
+	if len(list) > maxRanked {
+		list = list[:maxRanked]
+	}
+	return list
+}
+
+// Title names the report and its period
+func (r Summary) Title() string {
+	if r.Start == r.End {
+		return "Prompt Security daily report for " + r.Start
+	}
+	return fmt.Sprintf("Prompt Security weekly report for %s to %s", r.Start, r.End)
+}
+
+// Trend describes the detections against the period before, e.g. "up 25%
+// from the previous week"
+func (r Summary) Trend() string {
+	previous := "the previous " + r.Period
+	switch {
+	case r.PreviousDetections == 0 && r.Detections == 0:
+		return "none in " + previous + " either"
+	case r.PreviousDetections == 0:
+		return "none in " + previous
+	case r.Detections == r.PreviousDetections:
+		return "unchanged from " + previous
+	}
+	change := (r.Detections - r.PreviousDetections) * 100 / r.PreviousDetections
+	if change >= 0 {
+		return fmt.Sprintf("up %d%% from %s", change, previous)
+	}
+	return fmt.Sprintf("down %d%% from %s", -change, previous)
+}
+
+// describe summarizes a change for a line of the report
+func (c PolicyChange) describe() string {
+	when := c.Time
+	if t, err := time.Parse(time.RFC3339, c.Time); err == nil {
+		when = t.Format("2006-01-02 15:04")
+	}
+	text := fmt.Sprintf("%s: %s by %s", when, c.Action, c.Actor)
+	if len(c.Fields) > 0 {
+		text += " (" + strings.Join(c.Fields, ", ") + ")"
+	}
+	return text
+}
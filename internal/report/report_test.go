@@ -0,0 +1,112 @@
+package report
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/happytaoer/prompt-security/internal/db"
+)
+
+// TestRange tests that a week runs from Monday to the end of Sunday, and
+// that unknown periods are refused
+func TestRange(t *testing.T) {
+	until := time.Date(2026, 3, 8, 15, 30, 0, 0, time.UTC)
+	start, end, err := Range(PeriodWeek, until)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2026, 3, 2, 0, 0, 0, 0, time.UTC); !start.Equal(want) {
+		t.Errorf("Expected the week to start on %v, got %v", want, start)
+	}
+	if want := time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC); !end.Equal(want) {
+		t.Errorf("Expected the week to end on %v, got %v", want, end)
+	}
+	if _, _, err := Range("month", until); err == nil {
+		t.Error("Expected an error for an unknown period")
+	}
+}
+
+// TestBuild tests that a summary counts detections and leaks by day, type
+// and application against the previous period, and lists policy changes
+func TestBuild(t *testing.T) {
+	start, end, _ := Range(PeriodWeek, time.Date(2026, 3, 8, 0, 0, 0, 0, time.UTC))
+	at := func(day, hour int) time.Time { return time.Date(2026, 3, day, hour, 0, 0, 0, time.UTC) }
+	events := []db.DetectionEvent{
+		{Time: at(1, 9), Kind: db.LogKindPrompt, Detections: []string{"email"}, Count: 4},
+		{Time: at(2, 9), Kind: db.LogKindPrompt, App: "firefox", Detections: []string{"email", "phone"}, Count: 1},
+		{Time: at(2, 10), Kind: db.LogKindPrompt, App: "slack", Detections: []string{"email"}, Count: 2},
+		{Time: at(8, 23), Kind: db.LogKindLeak, Detections: []string{"ssn"}, Count: 1},
+	}
+	audit := []db.AuditEntry{{
+		Timestamp: at(3, 12).Format(time.RFC3339),
+		Actor:     "admin",
+		Action:    db.AuditActionConfigUpdate,
+		Changes:   []db.AuditChange{{Field: "ssn_action"}},
+	}}
+
+	r := Build(PeriodWeek, start, end, events, audit)
+	if r.Start != "2026-03-02" || r.End != "2026-03-08" || len(r.Days) != 7 {
+		t.Errorf("Unexpected period %s to %s with %d days", r.Start, r.End, len(r.Days))
+	}
+	if r.Detections != 4 || r.Leaks != 1 || r.PreviousDetections != 4 {
+		t.Errorf("Expected 4 detections, 1 leak and 4 before, got %d, %d and %d", r.Detections, r.Leaks, r.PreviousDetections)
+	}
+	if r.Days[0].Detections != 4 || r.Days[6].Leaks != 1 {
+		t.Errorf("Unexpected days %+v", r.Days)
+	}
+	if len(r.Types) != 2 || r.Types[0] != (NamedCount{"email", 3}) || r.Types[1] != (NamedCount{"phone", 1}) {
+		t.Errorf("Unexpected types %+v", r.Types)
+	}
+	if len(r.Apps) != 2 || r.Apps[0] != (NamedCount{"firefox", 2}) || r.Apps[1] != (NamedCount{"slack", 2}) {
+		t.Errorf("Unexpected applications %+v", r.Apps)
+	}
+	if r.Trend() != "unchanged from the previous week" {
+		t.Errorf("Unexpected trend %q", r.Trend())
+	}
+	if len(r.Changes) != 1 || strings.Join(r.Changes[0].Fields, ",") != "ssn_action" {
+		t.Errorf("Unexpected changes %+v", r.Changes)
+	}
+
+	md := r.Markdown()
+	for _, want := range []string{"# Prompt Security weekly report for 2026-03-02 to 2026-03-08", "| firefox | 2 |", "config.update by admin (ssn_action)"} {
+		if !strings.Contains(md, want) {
+			t.Errorf("Expected %q in the Markdown report:\n%s", want, md)
+		}
+	}
+}
+
+// TestTrend tests the sentence comparing detections with the previous period
+func TestTrend(t *testing.T) {
+	tests := []struct {
+		detections, previous int
+		want                 string
+	}{
+		{0, 0, "none in the previous day either"},
+		{3, 0, "none in the previous day"},
+		{5, 4, "up 25% from the previous day"},
+		{1, 4, "down 75% from the previous day"},
+	}
+	for _, tt := range tests {
+		r := Summary{Period: PeriodDay, Detections: tt.detections, PreviousDetections: tt.previous}
+		if got := r.Trend(); got != tt.want {
+			t.Errorf("%d after %d: got %q, want %q", tt.detections, tt.previous, got, tt.want)
+		}
+	}
+}
+
+// TestHTML tests that the HTML report escapes application names and actors
+func TestHTML(t *testing.T) {
+	r := Summary{Period: PeriodDay, Start: "2026-03-01", End: "2026-03-01", Days: []DayCount{{Date: "2026-03-01"}},
+		Apps:    []NamedCount{{"<script>", 1}},
+		Changes: []PolicyChange{{Time: "2026-03-01T10:00:00Z", Actor: "a&b", Action: db.AuditActionPatternCreate}}}
+	page, err := r.HTML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"daily report for 2026-03-01", "&lt;script&gt;", "pattern.create by a&amp;b"} {
+		if !strings.Contains(page, want) {
+			t.Errorf("Expected %q in the HTML report:\n%s", want, page)
+		}
+	}
+}
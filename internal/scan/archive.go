@@ -0,0 +1,194 @@
+package scan
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+
+	"github.com/happytaoer/prompt-security/internal/extract"
+)
+
+const (
+	// DefaultArchiveDepth is how many levels of nested archives are
+	// descended into by default
+	DefaultArchiveDepth = 3
+
+	// DefaultMaxArchiveSize bounds the bytes extracted from an archive by
+	// default
+	DefaultMaxArchiveSize = 256 << 20
+)
+
+// Kinds of archives
+const (
+	archiveZip  = "zip"
+	archiveTar  = "tar"
+	archiveGzip = "gzip"
+)
+
+var (
+	// errArchiveBudget stops reading an archive extracting to more than
+	// MaxArchiveSize, like a zip bomb
+	errArchiveBudget = errors.New("archive exceeds the extraction limit")
+
+	// errEntryTooLarge skips a file in an archive larger than MaxSize
+	errEntryTooLarge = errors.New("entry too large")
+)
+
+// archiveKind returns the kind of archive r holds, or "" if it is not one.
+// Zips holding Word documents are documents rather than archives.
+func archiveKind(r io.ReaderAt, size int64) string {
+	head := make([]byte, 512)
+	n, _ := r.ReadAt(head, 0)
+	head = head[:n]
+	switch {
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")):
+		zr, err := zip.NewReader(r, size)
+		if err != nil || extract.IsDOCX(zr) {
+			return ""
+		}
+		return archiveZip
+	case bytes.HasPrefix(head, []byte{0x1f, 0x8b}):
+		return archiveGzip
+	case isTar(head):
+		return archiveTar
+	}
+	return ""
+}
+
+// isTar reports whether head starts a POSIX or GNU tar archive
+func isTar(head []byte) bool {
+	return len(head) >= 262 && string(head[257:262]) == "ustar"
+}
+
+// archive scans the files in an archive named name, depth levels deep.
+// budget is what is left to extract from the outermost archive.
+func (s *Scanner) archive(name string, r io.ReaderAt, size int64, kind string, depth int, budget *int64, fn func(Result)) error {
+	switch kind {
+	case archiveZip:
+		zr, err := zip.NewReader(r, size)
+		if err != nil {
+			fn(Result{File: name, Err: fmt.Errorf("failed to open zip: %v", err)})
+			return nil
+		}
+		for _, f := range zr.File {
+			if f.FileInfo().IsDir() {
+				continue
+			}
+			member := name + "!" + f.Name
+			if f.UncompressedSize64 > uint64(s.opts.MaxSize) {
+				fn(Result{File: member, Skipped: fmt.Sprintf("larger than %d MB", s.opts.MaxSize>>20)})
+				continue
+			}
+			rc, err := f.Open()
+			if err != nil {
+				fn(Result{File: member, Err: err})
+				continue
+			}
+			data, err := s.read(rc, budget)
+			rc.Close()
+			if err := s.member(member, data, err, depth, budget, fn); err != nil {
+				return err
+			}
+		}
+		return nil
+
+	case archiveGzip:
+		gz, err := gzip.NewReader(io.NewSectionReader(r, 0, size))
+		if err != nil {
+			fn(Result{File: name, Err: fmt.Errorf("failed to open gzip: %v", err)})
+			return nil
+		}
+		defer gz.Close()
+		br := bufio.NewReader(gz)
+		if head, _ := br.Peek(512); isTar(head) {
+			return s.tar(name, br, depth, budget, fn)
+		}
+		// A single compressed file, named in the header or like the
+		// archive without .gz
+		inner := filepath.Base(gz.Name)
+		if gz.Name == "" {
+			inner = strings.TrimSuffix(filepath.Base(name[strings.LastIndex(name, "!")+1:]), ".gz")
+		}
+		data, err := s.read(br, budget)
+		return s.member(name+"!"+inner, data, err, depth, budget, fn)
+
+	case archiveTar:
+		return s.tar(name, io.NewSectionReader(r, 0, size), depth, budget, fn)
+	}
+	return nil
+}
+
+// tar scans the regular files in a tar stream
+func (s *Scanner) tar(name string, r io.Reader, depth int, budget *int64, fn func(Result)) error {
+	tr := tar.NewReader(r)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			fn(Result{File: name, Err: fmt.Errorf("failed to read tar: %v", err)})
+			return nil
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		member := name + "!" + header.Name
+		if header.Size > s.opts.MaxSize {
+			fn(Result{File: member, Skipped: fmt.Sprintf("larger than %d MB", s.opts.MaxSize>>20)})
+			continue
+		}
+		data, err := s.read(tr, budget)
+		if err := s.member(member, data, err, depth, budget, fn); err != nil {
+			return err
+		}
+	}
+}
+
+// read reads a file from an archive, charging it to budget
+func (s *Scanner) read(r io.Reader, budget *int64) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, s.opts.MaxSize+1))
+	if *budget -= int64(len(data)); *budget < 0 {
+		return nil, errArchiveBudget
+	}
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > s.opts.MaxSize {
+		return nil, errEntryTooLarge
+	}
+	return data, nil
+}
+
+// member scans a file read from an archive, descending into it if it is
+// an archive itself. Only errArchiveBudget is returned, to stop reading.
+func (s *Scanner) member(name string, data []byte, err error, depth int, budget *int64, fn func(Result)) error {
+	switch {
+	case errors.Is(err, errArchiveBudget):
+		return err
+	case errors.Is(err, errEntryTooLarge):
+		fn(Result{File: name, Skipped: fmt.Sprintf("larger than %d MB", s.opts.MaxSize>>20)})
+		return nil
+	case err != nil:
+		fn(Result{File: name, Err: err})
+		return nil
+	}
+
+	if kind := archiveKind(bytes.NewReader(data), int64(len(data))); kind != "" {
+		if depth >= s.opts.ArchiveDepth {
+			fn(Result{File: name, Skipped: "archive nested too deep"})
+			return nil
+		}
+		return s.archive(name, bytes.NewReader(data), int64(len(data)), kind, depth+1, budget, fn)
+	}
+	result, _ := s.scan(name, data)
+	fn(result)
+	return nil
+}
@@ -0,0 +1,163 @@
+package scan
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// zipOf returns a zip archive of files, by name
+func zipOf(files map[string]string) []byte {
+	var b bytes.Buffer
+	zw := zip.NewWriter(&b)
+	for _, name := range sortedKeys(files) {
+		w, _ := zw.Create(name)
+		w.Write([]byte(files[name]))
+	}
+	zw.Close()
+	return b.Bytes()
+}
+
+// tarGzOf returns a gzipped tar archive of files, by name
+func tarGzOf(files map[string]string) []byte {
+	var b bytes.Buffer
+	gz := gzip.NewWriter(&b)
+	tw := tar.NewWriter(gz)
+	for _, name := range sortedKeys(files) {
+		tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(files[name])), Typeflag: tar.TypeReg})
+		tw.Write([]byte(files[name]))
+	}
+	tw.Close()
+	gz.Close()
+	return b.Bytes()
+}
+
+// gzipOf returns content compressed with gzip
+func gzipOf(content string) []byte {
+	var b bytes.Buffer
+	gz := gzip.NewWriter(&b)
+	gz.Write([]byte(content))
+	gz.Close()
+	return b.Bytes()
+}
+
+// sortedKeys returns the keys of m in order, so archives are built the same
+// way each time
+func sortedKeys(m map[string]string) []string {
+	var keys []string
+	for k := range m {
+		keys = append(keys, k)
+	}
+	for i := 1; i < len(keys); i++ {
+		for j := i; j > 0 && keys[j] < keys[j-1]; j-- {
+			keys[j], keys[j-1] = keys[j-1], keys[j]
+		}
+	}
+	return keys
+}
+
+// scanFile writes data to a file and scans it
+func scanFile(t *testing.T, name string, data []byte, opts Options) []Result {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		t.Fatal(err)
+	}
+	var results []Result
+	testScanner(opts).File(path, func(r Result) {
+		r.File = strings.TrimPrefix(r.File, filepath.Dir(path)+string(filepath.Separator))
+		results = append(results, r)
+	})
+	return results
+}
+
+// describe lists results as name: finding types or why they were skipped
+func describe(results []Result) string {
+	var lines []string
+	for _, r := range results {
+		line := r.File + ":"
+		for _, f := range r.Findings {
+			line += " " + f.Type
+		}
+		if r.Skipped != "" {
+			line += " skipped " + r.Skipped
+		}
+		if r.Err != nil {
+			line += " error " + r.Err.Error()
+		}
+		lines = append(lines, line)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// TestArchives tests that zip, tar and gzip archives are scanned entry by
+// entry, nested down to the configured depth only
+func TestArchives(t *testing.T) {
+	export := zipOf(map[string]string{
+		"chat/1.txt":  "mail a@example.com",
+		"chat/2.txt":  "nothing",
+		"logs.tar.gz": string(tarGzOf(map[string]string{"app/server.log": "ssn 123-45-6789"})),
+		"single.gz":   string(gzipOf("b@example.com")),
+		"nested.zip":  string(zipOf(map[string]string{"deep.zip": string(zipOf(map[string]string{"x.txt": "c@example.com"}))})),
+		"picture.png": "\x89PNG\x00",
+		"empty/":      "",
+	})
+
+	got := describe(scanFile(t, "export.zip", export, Options{ArchiveDepth: 3}))
+	want := strings.Join([]string{
+		"export.zip!chat/1.txt: email",
+		"export.zip!chat/2.txt:",
+		"export.zip!logs.tar.gz!app/server.log: ssn",
+		"export.zip!nested.zip!deep.zip!x.txt: email",
+		"export.zip!picture.png: skipped binary",
+		"export.zip!single.gz!single: email",
+	}, "\n")
+	if got != want {
+		t.Errorf("Got\n%s\nwant\n%s", got, want)
+	}
+
+	got = describe(scanFile(t, "export.zip", export, Options{ArchiveDepth: 2}))
+	if !strings.Contains(got, "export.zip!nested.zip!deep.zip: skipped archive nested too deep") {
+		t.Errorf("Expected the deepest archive to be skipped, got\n%s", got)
+	}
+
+	got = describe(scanFile(t, "export.zip", export, Options{}))
+	if got != "export.zip: skipped binary" {
+		t.Errorf("Expected archives to be left alone without a depth, got\n%s", got)
+	}
+}
+
+// TestArchiveLimits tests that an archive extracting to more than its size
+// limit is abandoned, and entries above the file size limit are skipped
+func TestArchiveLimits(t *testing.T) {
+	bomb := tarGzOf(map[string]string{
+		"a.txt": strings.Repeat("a", 600<<10),
+		"b.txt": strings.Repeat("b", 600<<10),
+		"c.txt": strings.Repeat("c", 2<<20),
+	})
+	got := describe(scanFile(t, "bomb.tgz", bomb, Options{ArchiveDepth: 1, MaxSize: 1 << 20, MaxArchiveSize: 1 << 20}))
+	want := "bomb.tgz!a.txt:\nbomb.tgz: skipped extracts to more than 1 MB"
+	if got != want {
+		t.Errorf("Got\n%s\nwant\n%s", got, want)
+	}
+
+	got = describe(scanFile(t, "big.tgz", bomb, Options{ArchiveDepth: 1, MaxSize: 1 << 20, MaxArchiveSize: 8 << 20}))
+	want = "big.tgz!a.txt:\nbig.tgz!b.txt:\nbig.tgz!c.txt: skipped larger than 1 MB"
+	if got != want {
+		t.Errorf("Got\n%s\nwant\n%s", got, want)
+	}
+}
+
+// TestDOCXIsNotAnArchive tests that a Word document, though a zip file, is
+// scanned as a document
+func TestDOCXIsNotAnArchive(t *testing.T) {
+	docx := zipOf(map[string]string{"word/document.xml": `<w:document xmlns:w="w"><w:body><w:p><w:r><w:t>a@example.com</w:t></w:r></w:p></w:body></w:document>`})
+	got := describe(scanFile(t, "letter.docx", docx, Options{ArchiveDepth: 3}))
+	if got != "letter.docx: email" {
+		t.Errorf("Got %s", got)
+	}
+}
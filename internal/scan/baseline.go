@@ -0,0 +1,121 @@
+package scan
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// baselineVersion is the version of the baseline file format
+const baselineVersion = 2
+
+// Baseline is a set of accepted findings, suppressed in later scans so only
+// new sensitive data is reported. Findings are matched by file, detector
+// and the keyed hash of their value, so they survive edits moving them
+// around. The key is kept outside the baseline, so values cannot be
+// guessed from the file by hashing candidates.
+type Baseline struct {
+	Version  int             `json:"version"`
+	KeyID    string          `json:"key_id"` // Identifies the hash key, not revealing it
+	Findings []BaselineEntry `json:"findings"`
+
+	known map[BaselineEntry]bool
+}
+
+// BaselineEntry is an accepted finding
+type BaselineEntry struct {
+	File string `json:"file"`
+	Type string `json:"type"`
+	Hash string `json:"hash"`
+}
+
+// hashValue returns the hash identifying a sensitive value in a baseline,
+// an HMAC-SHA256 with key. The value itself is never stored.
+func hashValue(key []byte, value string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// keyID returns the identifier of a hash key stored in baselines, to tell
+// a baseline written with another key
+func keyID(key []byte) string {
+	return hashValue(key, "prompt-security baseline key")[:16]
+}
+
+// entryOf returns the baseline entry matching a finding
+func entryOf(f Finding) BaselineEntry {
+	return BaselineEntry{File: filepath.ToSlash(f.File), Type: f.Type, Hash: f.Hash}
+}
+
+// NewBaseline creates a baseline accepting findings, whose hashes were made
+// with key, see Options.HashKey
+func NewBaseline(findings []Finding, key []byte) *Baseline {
+	b := &Baseline{Version: baselineVersion, KeyID: keyID(key), Findings: []BaselineEntry{}, known: map[BaselineEntry]bool{}}
+	for _, f := range findings {
+		e := entryOf(f)
+		if !b.known[e] {
+			b.known[e] = true
+			b.Findings = append(b.Findings, e)
+		}
+	}
+	sort.Slice(b.Findings, func(i, j int) bool {
+		x, y := b.Findings[i], b.Findings[j]
+		if x.File != y.File {
+			return x.File < y.File
+		}
+		if x.Type != y.Type {
+			return x.Type < y.Type
+		}
+		return x.Hash < y.Hash
+	})
+	return b
+}
+
+// LoadBaseline reads a baseline file written with key
+func LoadBaseline(path string, key []byte) (*Baseline, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read baseline: %v", err)
+	}
+	var b Baseline
+	if err := json.Unmarshal(data, &b); err != nil {
+		return nil, fmt.Errorf("failed to parse baseline %s: %v", path, err)
+	}
+	if b.Version == 1 {
+		return nil, fmt.Errorf("baseline %s uses unkeyed hashes, write it again with --update-baseline", path)
+	}
+	if b.Version != baselineVersion {
+		return nil, fmt.Errorf("unsupported baseline version %d in %s", b.Version, path)
+	}
+	if b.KeyID != keyID(key) {
+		return nil, fmt.Errorf("baseline %s was written with another key", path)
+	}
+	b.known = map[BaselineEntry]bool{}
+	for _, e := range b.Findings {
+		b.known[e] = true
+	}
+	return &b, nil
+}
+
+// Save writes the baseline to path
+func (b *Baseline) Save(path string) error {
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write baseline: %v", err)
+	}
+	return nil
+}
+
+// Contains reports whether a finding is accepted
+func (b *Baseline) Contains(f Finding) bool {
+	return b.known[entryOf(f)]
+}
@@ -0,0 +1,77 @@
+package scan
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestBaseline tests that a baseline stores keyed hashes rather than values
+// and suppresses the accepted values of a file wherever they move in it, but
+// not in other files
+func TestBaseline(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "notes.txt")
+	os.WriteFile(path, []byte("a@example.com\nb@example.com\n"), 0644)
+
+	var findings []Finding
+	key := []byte("test key")
+	testScanner(Options{HashKey: key}).File(path, func(r Result) { findings = append(findings, r.Findings...) })
+	if len(findings) != 2 || findings[0].Hash == findings[1].Hash || findings[0].Hash == "" {
+		t.Fatalf("Unexpected findings %+v", findings)
+	}
+
+	baselinePath := filepath.Join(dir, "baseline.json")
+	if err := NewBaseline(findings, key).Save(baselinePath); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(baselinePath)
+	if strings.Contains(string(data), "example.com") {
+		t.Errorf("Baseline stores values: %s", data)
+	}
+	if _, err := LoadBaseline(baselinePath, []byte("other key")); err == nil || !strings.Contains(err.Error(), "another key") {
+		t.Errorf("Got %v, want a key error", err)
+	}
+	baseline, err := LoadBaseline(baselinePath, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Accepted values are suppressed wherever they move in the file, new
+	// ones are reported
+	os.WriteFile(path, []byte("c@example.com\n\nb@example.com a@example.com\n"), 0644)
+	var results []Result
+	testScanner(Options{Baseline: baseline, HashKey: key}).File(path, func(r Result) { results = append(results, r) })
+	if len(results) != 1 || results[0].Known != 2 || len(results[0].Findings) != 1 || results[0].Findings[0].Line != 1 {
+		t.Errorf("Unexpected results %+v", results)
+	}
+
+	// The same value in another file is new
+	other := filepath.Join(dir, "other.txt")
+	os.WriteFile(other, []byte("a@example.com"), 0644)
+	results = nil
+	testScanner(Options{Baseline: baseline, HashKey: key}).File(other, func(r Result) { results = append(results, r) })
+	if len(results) != 1 || results[0].Known != 0 || len(results[0].Findings) != 1 {
+		t.Errorf("Unexpected results %+v", results)
+	}
+}
+
+// TestLoadBaselineErrors tests that missing baselines, baselines of a newer
+// version and unkeyed baselines are refused
+func TestLoadBaselineErrors(t *testing.T) {
+	dir := t.TempDir()
+	key := []byte("test key")
+	if _, err := LoadBaseline(filepath.Join(dir, "missing.json"), key); err == nil {
+		t.Error("Expected an error for a missing baseline")
+	}
+	path := filepath.Join(dir, "future.json")
+	os.WriteFile(path, []byte(`{"version": 3, "findings": []}`), 0644)
+	if _, err := LoadBaseline(path, key); err == nil || !strings.Contains(err.Error(), "version 3") {
+		t.Errorf("Got %v, want a version error", err)
+	}
+	os.WriteFile(path, []byte(`{"version": 1, "findings": []}`), 0644)
+	if _, err := LoadBaseline(path, key); err == nil || !strings.Contains(err.Error(), "--update-baseline") {
+		t.Errorf("Got %v, want an unkeyed baseline error", err)
+	}
+}
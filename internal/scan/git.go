@@ -0,0 +1,155 @@
+package scan
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// commitMarker starts the header of each commit in the log History reads,
+// written %x00commit%x00 in its format
+const commitMarker = "\x00commit\x00"
+
+// History scans the lines added by the commits of the git repository in
+// dir, newest first, and calls fn with a result for each file a commit
+// added sensitive data to. revRange limits the commits, e.g. main..HEAD,
+// and is HEAD if empty. Findings carry the commit, its author and date.
+func (s *Scanner) History(dir, revRange string, fn func(Result)) error {
+	if revRange == "" {
+		revRange = "HEAD"
+	}
+	if strings.HasPrefix(revRange, "-") {
+		return fmt.Errorf("invalid revision range %q", revRange)
+	}
+	cmd := exec.Command("git", "-C", dir, "-c", "core.quotePath=false", "log", "-p", "--unified=0", "--no-color", "--no-ext-diff",
+		"--format=%x00commit%x00%H%x00%an <%ae>%x00%aI", revRange, "--")
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to run git: %v", err)
+	}
+
+	ig := newIgnorer(dir, s.opts.Exclude)
+	if err := ig.load(dir); err != nil {
+		fn(Result{File: filepath.Join(dir, IgnoreFile), Err: err})
+	}
+	s.parseLog(stdout, ig, fn)
+	io.Copy(io.Discard, stdout)
+	if err := cmd.Wait(); err != nil {
+		return fmt.Errorf("failed to read git history: %v: %s", err, strings.TrimSpace(stderr.String()))
+	}
+	return nil
+}
+
+// gitCommit is the commit of the patch being read
+type gitCommit struct {
+	sha, author, date string
+}
+
+// parseLog reads the patches of git log -p --unified=0 and scans each hunk
+// of added lines
+func (s *Scanner) parseLog(r io.Reader, ig *ignorer, fn func(Result)) {
+	br := bufio.NewReaderSize(r, 64<<10)
+	var commit gitCommit
+	var file *Result // Findings of the current commit in the current file, nil if skipped
+	var hunk []string
+	hunkStart := 0
+	inHeader := false // Between diff --git and the first hunk, where +++ names the file
+
+	flushHunk := func() {
+		if file != nil && len(hunk) > 0 {
+			s.scanHunk(file, commit, strings.Join(hunk, "\n"), hunkStart)
+		}
+		hunk = hunk[:0]
+	}
+	flushFile := func() {
+		flushHunk()
+		if file != nil && len(file.Findings)+len(file.Suppressed)+file.Known > 0 {
+			fn(*file)
+		}
+		file = nil
+	}
+
+	for {
+		line, err := br.ReadString('\n')
+		if line == "" && err != nil {
+			break
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case strings.HasPrefix(line, commitMarker):
+			flushFile()
+			fields := strings.SplitN(strings.TrimPrefix(line, commitMarker), "\x00", 3)
+			if len(fields) == 3 {
+				commit = gitCommit{sha: fields[0], author: fields[1], date: fields[2]}
+			}
+		case strings.HasPrefix(line, "diff --git "):
+			flushFile()
+			inHeader = true
+		case inHeader && strings.HasPrefix(line, "+++ "):
+			flushFile()
+			name := strings.TrimPrefix(line, "+++ ")
+			if unquoted, err := strconv.Unquote(name); err == nil {
+				name = unquoted
+			}
+			// Deleted files have no b/ side
+			if name, ok := strings.CutPrefix(name, "b/"); ok && !ig.ignoredPath(name) {
+				file = &Result{File: name, Findings: []Finding{}}
+			}
+		case strings.HasPrefix(line, "@@ "):
+			inHeader = false
+			flushHunk()
+			hunkStart = hunkLine(line)
+		case !inHeader && strings.HasPrefix(line, "+") && file != nil:
+			hunk = append(hunk, strings.TrimSuffix(line[1:], "\r"))
+		}
+		if err != nil {
+			break
+		}
+	}
+	flushFile()
+}
+
+// hunkLine returns the first line of the new file in a hunk header like
+// @@ -12,0 +13,2 @@
+func hunkLine(header string) int {
+	fields := strings.Fields(header)
+	if len(fields) < 3 || !strings.HasPrefix(fields[2], "+") {
+		return 1
+	}
+	start, _, _ := strings.Cut(fields[2][1:], ",")
+	n, err := strconv.Atoi(start)
+	if err != nil {
+		return 1
+	}
+	return n
+}
+
+// scanHunk scans lines added to a file from line start on
+func (s *Scanner) scanHunk(file *Result, commit gitCommit, text string, start int) {
+	result := s.scanText(file.File, text)
+	for _, f := range result.Findings {
+		file.Findings = append(file.Findings, commit.annotate(f, start))
+	}
+	for _, f := range result.Suppressed {
+		file.Suppressed = append(file.Suppressed, commit.annotate(f, start))
+	}
+	file.Known += result.Known
+}
+
+// annotate moves a finding in a hunk to its line in the file and adds the
+// commit to it
+func (c gitCommit) annotate(f Finding, start int) Finding {
+	f.Line += start - 1
+	f.Commit, f.Author, f.Date = c.sha, c.author, c.date
+	return f
+}
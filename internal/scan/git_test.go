@@ -0,0 +1,90 @@
+package scan
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// git runs git in dir as a fixed author
+func git(t *testing.T, dir string, args ...string) string {
+	t.Helper()
+	cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=Dev", "-c", "user.email=dev@example.org", "-c", "commit.gpgsign=false"}, args...)...)
+	out, err := cmd.CombinedOutput()
+	if err != nil {
+		t.Fatalf("git %v: %v: %s", args, err, out)
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// TestHistory tests that the history is scanned commit by commit, reporting
+// values later removed with the commit and author that added them, and that
+// ranges only cover their commits
+func TestHistory(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not installed")
+	}
+	dir := t.TempDir()
+	git(t, dir, "init", "-q")
+	write := func(name, content string) {
+		path := filepath.Join(dir, name)
+		os.MkdirAll(filepath.Dir(path), 0755)
+		os.WriteFile(path, []byte(content), 0644)
+	}
+
+	write("config.txt", "host=db\n")
+	write("vendor/lib.txt", "a@example.com\n")
+	git(t, dir, "add", "-A")
+	git(t, dir, "commit", "-q", "-m", "first")
+	first := git(t, dir, "rev-parse", "HEAD")
+
+	write("config.txt", "host=db\nssn=123-45-6789\nuser=a\n+++ b@example.com\n")
+	write("fixture.txt", "// ps:ignore-next-line\nc@example.com\n")
+	git(t, dir, "add", "-A")
+	git(t, dir, "commit", "-q", "-m", "second")
+	second := git(t, dir, "rev-parse", "HEAD")
+
+	// Removing the value later does not hide it
+	write("config.txt", "host=db\n")
+	git(t, dir, "add", "-A")
+	git(t, dir, "commit", "-q", "-m", "third")
+
+	var results []Result
+	if err := testScanner(Options{}).History(dir, "", func(r Result) { results = append(results, r) }); err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, r := range results {
+		for _, f := range r.Findings {
+			got = append(got, strings.Join([]string{f.Commit[:7], f.File, f.Type, strings.Repeat("+", f.Line), f.Author}, " "))
+		}
+		for _, f := range r.Suppressed {
+			got = append(got, "suppressed "+f.File)
+		}
+	}
+	want := []string{
+		second[:7] + " config.txt ssn ++ Dev <dev@example.org>",
+		second[:7] + " config.txt email ++++ Dev <dev@example.org>",
+		"suppressed fixture.txt",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Got\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+	if results[0].Findings[0].Date == "" {
+		t.Error("Expected the commit date")
+	}
+
+	// A range only covers its commits
+	results = nil
+	if err := testScanner(Options{}).History(dir, first+".."+first, func(r Result) { results = append(results, r) }); err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 0 {
+		t.Errorf("Expected an empty range to find nothing, got %+v", results)
+	}
+	if err := testScanner(Options{}).History(dir, "no-such-branch", func(Result) {}); err == nil {
+		t.Error("Expected an error for an unknown revision")
+	}
+}
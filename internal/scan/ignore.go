@@ -0,0 +1,197 @@
+package scan
+
+import (
+	"bufio"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// IgnoreFile lists patterns of files not to scan in the directory holding
+// it and below, like a .gitignore
+const IgnoreFile = ".psignore"
+
+// DefaultExclude are the patterns excluded unless a .psignore re-includes
+// them, e.g. with !vendor/
+var DefaultExclude = []string{".git/", ".hg/", ".svn/", "node_modules/", "vendor/"}
+
+// ignoreRule is a pattern of a .psignore or --exclude
+type ignoreRule struct {
+	segments []string // The pattern split at slashes
+	base     string   // Directory the pattern is relative to, slash separated
+	negate   bool     // Re-includes what earlier rules exclude
+	dirOnly  bool     // Only matches directories
+}
+
+// parseRule parses a line of a .psignore, returning false for blank lines
+// and comments. Patterns without a slash match at any depth; the others
+// are relative to base. ** matches any number of directories.
+func parseRule(line, base string) (ignoreRule, bool) {
+	line = strings.TrimRight(line, " \t\r")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return ignoreRule{}, false
+	}
+	rule := ignoreRule{base: base}
+	if strings.HasPrefix(line, "!") {
+		rule.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\`) {
+		line = line[1:]
+	}
+	if strings.HasSuffix(line, "/") {
+		rule.dirOnly = true
+		line = strings.TrimRight(line, "/")
+	}
+	if strings.Trim(line, "/") == "" {
+		return ignoreRule{}, false
+	}
+	if !strings.Contains(line, "/") {
+		line = "**/" + line
+	}
+	line = strings.TrimPrefix(line, "/")
+	rule.segments = strings.Split(line, "/")
+	return rule, true
+}
+
+// matches reports whether the rule matches a slash separated path
+func (r ignoreRule) matches(name string, isDir bool) bool {
+	if r.dirOnly && !isDir {
+		return false
+	}
+	if r.base != "" {
+		if !strings.HasPrefix(name, r.base+"/") {
+			return false
+		}
+		name = name[len(r.base)+1:]
+	}
+	return matchSegments(r.segments, strings.Split(name, "/"))
+}
+
+// matchSegments matches path segments against pattern segments
+func matchSegments(pattern, parts []string) bool {
+	if len(pattern) == 0 {
+		return len(parts) == 0
+	}
+	if pattern[0] == "**" {
+		for i := 0; i <= len(parts); i++ {
+			if matchSegments(pattern[1:], parts[i:]) {
+				return true
+			}
+		}
+		return false
+	}
+	if len(parts) == 0 {
+		return false
+	}
+	ok, _ := path.Match(pattern[0], parts[0])
+	return ok && matchSegments(pattern[1:], parts[1:])
+}
+
+// ignorer decides which files under a scanned directory are skipped. The
+// last matching rule wins: defaults first, then --exclude, then .psignore
+// files from the top down.
+type ignorer struct {
+	root  string
+	rules []ignoreRule
+	files map[string][]ignoreRule // Rules of the .psignore in each directory
+}
+
+func newIgnorer(root string, exclude []string) *ignorer {
+	ig := &ignorer{root: root, files: map[string][]ignoreRule{}}
+	for _, pattern := range append(append([]string{}, DefaultExclude...), exclude...) {
+		if rule, ok := parseRule(pattern, ""); ok {
+			ig.rules = append(ig.rules, rule)
+		}
+	}
+	return ig
+}
+
+// load reads the .psignore of a directory, if any
+func (ig *ignorer) load(dir string) error {
+	f, err := os.Open(filepath.Join(dir, IgnoreFile))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	base := ig.relative(dir)
+	var rules []ignoreRule
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if rule, ok := parseRule(scanner.Text(), base); ok {
+			rules = append(rules, rule)
+		}
+	}
+	ig.files[base] = rules
+	return scanner.Err()
+}
+
+// relative returns a path under the root relative to it, slash separated,
+// "" for the root itself
+func (ig *ignorer) relative(p string) string {
+	rel, err := filepath.Rel(ig.root, p)
+	if err != nil || rel == "." {
+		return ""
+	}
+	return filepath.ToSlash(rel)
+}
+
+// ignored reports whether a path under the root is skipped
+func (ig *ignorer) ignored(p string, isDir bool) bool {
+	name := ig.relative(p)
+	if name == "" {
+		return false
+	}
+	ignored := false
+	apply := func(rules []ignoreRule) {
+		for _, r := range rules {
+			if r.matches(name, isDir) {
+				ignored = !r.negate
+			}
+		}
+	}
+	apply(ig.rules)
+	apply(ig.files[""])
+	for i := 0; i < len(name); i++ {
+		if name[i] == '/' {
+			apply(ig.files[name[:i]])
+		}
+	}
+	return ignored
+}
+
+// ignoredPath reports whether a file at a slash separated path relative to
+// the root, which need not exist, or a directory holding it is skipped
+func (ig *ignorer) ignoredPath(name string) bool {
+	p := filepath.Join(ig.root, filepath.FromSlash(name))
+	for dir := filepath.Dir(p); dir != ig.root && dir != "." && len(dir) > len(ig.root); dir = filepath.Dir(dir) {
+		if ig.ignored(dir, true) {
+			return true
+		}
+	}
+	return ig.ignored(p, false)
+}
+
+// scannable are the sniffed MIME types of files worth reading: text,
+// documents and archives. Tar archives sniff as application/octet-stream.
+var scannable = []string{"text/", "application/pdf", "application/zip", "application/x-gzip", "application/json", "application/xml"}
+
+// sniffBinary reports whether a file starting with head is binary data of
+// no interest, like images, media and executables
+func sniffBinary(head []byte) bool {
+	if isTar(head) {
+		return false
+	}
+	mime := http.DetectContentType(head)
+	for _, prefix := range scannable {
+		if strings.HasPrefix(mime, prefix) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,101 @@
+package scan
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestIgnoreRules tests that ignore patterns match like .gitignore patterns,
+// anchored to the directory of their file, and that blank lines and comments
+// hold no rule
+func TestIgnoreRules(t *testing.T) {
+	tests := []struct {
+		pattern, base, name string
+		isDir               bool
+		want                bool
+	}{
+		{"*.log", "", "app.log", false, true},
+		{"*.log", "", "a/b/app.log", false, true},
+		{"*.log", "", "app.log.txt", false, false},
+		{"build/", "", "a/build", true, true},
+		{"build/", "", "a/build", false, false},
+		{"/build", "", "build", false, true},
+		{"/build", "", "a/build", false, false},
+		{"docs/*.md", "", "docs/a.md", false, true},
+		{"docs/*.md", "", "docs/x/a.md", false, false},
+		{"docs/**/*.md", "", "docs/x/y/a.md", false, true},
+		{"**/fixtures", "", "a/b/fixtures", true, true},
+		{"secret.txt", "sub", "sub/deep/secret.txt", false, true},
+		{"secret.txt", "sub", "other/secret.txt", false, false},
+		{"/secret.txt", "sub", "sub/secret.txt", false, true},
+	}
+	for _, tt := range tests {
+		rule, ok := parseRule(tt.pattern, tt.base)
+		if !ok {
+			t.Fatalf("Failed to parse %q", tt.pattern)
+		}
+		if got := rule.matches(tt.name, tt.isDir); got != tt.want {
+			t.Errorf("%q in %q matching %q: got %v, want %v", tt.pattern, tt.base, tt.name, got, tt.want)
+		}
+	}
+	for _, line := range []string{"", "  ", "# comment", "/"} {
+		if _, ok := parseRule(line, ""); ok {
+			t.Errorf("Expected %q to hold no rule", line)
+		}
+	}
+}
+
+// TestWalkIgnores tests that walks skip files matched by .psignore files,
+// excludes and built-in rules, with negations bringing files back, but still
+// scan files named explicitly
+func TestWalkIgnores(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		".psignore":                 "*.log\n!keep.log\n# fixtures hold fake data\ntestdata/\n!vendor/\n",
+		"a.txt":                     "a@example.com",
+		"debug.log":                 "b@example.com",
+		"keep.log":                  "c@example.com",
+		"node_modules/pkg/index.js": "d@example.com",
+		"vendor/lib/lib.go":         "// e@example.com",
+		"src/testdata/sample.txt":   "f@example.com",
+		"src/.psignore":             "/generated.go\n",
+		"src/generated.go":          "// g@example.com",
+		"src/other/generated.go":    "// h@example.com",
+		"dist/app.min.js":           "i@example.com",
+		"photo.png":                 "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR",
+		".git/config":               "j@example.com",
+	}
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(path), 0755)
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var scanned []string
+	err := testScanner(Options{Exclude: []string{"dist/"}}).Walk([]string{dir}, func(r Result) {
+		name, _ := filepath.Rel(dir, r.File)
+		if r.Skipped != "" {
+			name += " (" + r.Skipped + ")"
+		}
+		scanned = append(scanned, filepath.ToSlash(name))
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := strings.Join(scanned, ", ")
+	want := ".psignore, a.txt, keep.log, photo.png (binary), src/.psignore, src/other/generated.go, vendor/lib/lib.go"
+	if got != want {
+		t.Errorf("Scanned %s, want %s", got, want)
+	}
+
+	// Files named explicitly are always scanned
+	scanned = nil
+	testScanner(Options{}).Walk([]string{filepath.Join(dir, "debug.log")}, func(r Result) { scanned = append(scanned, r.File) })
+	if len(scanned) != 1 {
+		t.Errorf("Expected debug.log to be scanned, got %v", scanned)
+	}
+}
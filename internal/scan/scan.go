@@ -0,0 +1,364 @@
+// Package scan finds sensitive data in files on disk: text files, PDF and
+// Word documents, and optionally writes redacted copies of them.
+package scan
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+	"github.com/happytaoer/prompt-security/internal/extract"
+	"github.com/happytaoer/prompt-security/internal/filter"
+)
+
+// DefaultMaxSize is the size above which files are skipped
+const DefaultMaxSize = 32 << 20
+
+// SkippedBinary is why binary files other than documents are skipped
+const SkippedBinary = "binary"
+
+// redactedSuffix marks the redacted copies of files, which are not scanned
+// again
+const redactedSuffix = ".redacted"
+
+// Finding is sensitive data in a file. The value itself is never kept.
+type Finding struct {
+	File   string `json:"file"`
+	Line   int    `json:"line"`
+	Column int    `json:"column"`
+	Type   string `json:"type"`
+	Action string `json:"action"`
+	Hash   string `json:"hash"` // Keyed hash of the value, to match it against a baseline
+
+	// Commit that added the value, its author and date, in history scans
+	Commit string `json:"commit,omitempty"`
+	Author string `json:"author,omitempty"`
+	Date   string `json:"date,omitempty"`
+}
+
+// Result is the outcome of scanning a file. Files in archives are named
+// after the archive and their path in it, e.g. logs.zip!app/server.log.
+type Result struct {
+	File       string    `json:"file"`
+	Kind       string    `json:"kind,omitempty"` // extract.KindText, KindPDF or KindDOCX
+	Findings   []Finding `json:"findings"`
+	Redacted   string    `json:"redacted,omitempty"`   // Path of the redacted copy written
+	Skipped    string    `json:"skipped,omitempty"`    // Why the file was not scanned
+	Known      int       `json:"known,omitempty"`      // Findings suppressed by the baseline
+	Suppressed []Finding `json:"suppressed,omitempty"` // Findings exempted by ps:ignore annotations
+	Err        error     `json:"-"`
+}
+
+// Options configure a Scanner
+type Options struct {
+	// MaxSize is the size above which files are skipped, DefaultMaxSize if 0
+	MaxSize int64
+
+	// Redact writes a redacted copy next to each file with findings:
+	// name.redacted.ext for text files and Word documents, and the
+	// redacted text as name.redacted.txt for PDF files. Files in archives
+	// are not redacted.
+	Redact bool
+
+	// ArchiveDepth is how many levels of nested zip, tar and gzip archives
+	// are descended into, 0 to scan archives as binary files
+	ArchiveDepth int
+
+	// MaxArchiveSize bounds the bytes extracted from an archive, nested
+	// archives included, DefaultMaxArchiveSize if 0
+	MaxArchiveSize int64
+
+	// Baseline, if set, suppresses the findings it accepts
+	Baseline *Baseline
+
+	// HashKey keys the hashes of values in findings, which must be the key
+	// of Baseline. A random key is used if it is empty, so hashes can only
+	// be compared within a scan.
+	HashKey []byte
+
+	// Jobs is how many files are scanned at once, GOMAXPROCS if 0
+	Jobs int
+
+	// Exclude are patterns of files and directories not to scan, with the
+	// syntax of a .psignore, in addition to DefaultExclude
+	Exclude []string
+}
+
+// Scanner scans files with a set of detectors
+type Scanner struct {
+	detectors *filter.DetectorSet
+	opts      Options
+}
+
+// New creates a scanner
+func New(detectors *filter.DetectorSet, opts Options) *Scanner {
+	if opts.MaxSize == 0 {
+		opts.MaxSize = DefaultMaxSize
+	}
+	if opts.MaxArchiveSize == 0 {
+		opts.MaxArchiveSize = DefaultMaxArchiveSize
+	}
+	if len(opts.HashKey) == 0 {
+		opts.HashKey = make([]byte, 32)
+		rand.Read(opts.HashKey)
+	}
+	return &Scanner{detectors: detectors, opts: opts}
+}
+
+// Walk scans the files at paths, descending into directories, and calls fn
+// with each result in order. Files are scanned by Jobs workers at once, but
+// fn is only called from the calling goroutine. Files matching
+// DefaultExclude, Exclude or the patterns of a .psignore are skipped,
+// unless named in paths.
+func (s *Scanner) Walk(paths []string, fn func(Result)) error {
+	jobs := s.opts.Jobs
+	if jobs <= 0 {
+		jobs = runtime.GOMAXPROCS(0)
+	}
+
+	// Each file gets a channel for its results, queued in walk order so
+	// they are reported in that order whichever worker finishes first
+	type job struct {
+		path string
+		done chan []Result
+	}
+	work := make(chan job)
+	queue := make(chan chan []Result, 4*jobs)
+	for i := 0; i < jobs; i++ {
+		go func() {
+			for j := range work {
+				var results []Result
+				s.File(j.path, func(r Result) { results = append(results, r) })
+				j.done <- results
+			}
+		}()
+	}
+
+	walkErr := make(chan error, 1)
+	go func() {
+		defer close(queue)
+		defer close(work)
+		walkErr <- s.walk(paths, func(path string) {
+			done := make(chan []Result, 1)
+			queue <- done
+			work <- job{path: path, done: done}
+		}, func(r Result) {
+			done := make(chan []Result, 1)
+			done <- []Result{r}
+			queue <- done
+		})
+	}()
+
+	for done := range queue {
+		for _, r := range <-done {
+			fn(r)
+		}
+	}
+	return <-walkErr
+}
+
+// walk calls file with each file to scan under paths, and report with
+// errors met on the way
+func (s *Scanner) walk(paths []string, file func(path string), report func(Result)) error {
+	for _, root := range paths {
+		ig := newIgnorer(root, s.opts.Exclude)
+		err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if path == root {
+					return err
+				}
+				report(Result{File: path, Err: err})
+				return nil
+			}
+			if d.IsDir() {
+				if path != root && ig.ignored(path, true) {
+					return filepath.SkipDir
+				}
+				if err := ig.load(path); err != nil {
+					report(Result{File: filepath.Join(path, IgnoreFile), Err: err})
+				}
+				return nil
+			}
+			if !d.Type().IsRegular() {
+				return nil
+			}
+			if path != root && (strings.Contains(filepath.Base(path), redactedSuffix+".") || ig.ignored(path, false)) {
+				return nil
+			}
+			file(path)
+			return nil
+		})
+		if err != nil {
+			return fmt.Errorf("failed to scan %s: %v", root, err)
+		}
+	}
+	return nil
+}
+
+// File scans a file, or each file in it if it is an archive, and calls fn
+// with the results
+func (s *Scanner) File(path string, fn func(Result)) {
+	f, err := os.Open(path)
+	if err != nil {
+		fn(Result{File: path, Err: err})
+		return
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		fn(Result{File: path, Err: err})
+		return
+	}
+
+	// Skip images, media and executables without reading them
+	head := make([]byte, 512)
+	n, _ := f.ReadAt(head, 0)
+	if sniffBinary(head[:n]) {
+		fn(Result{File: path, Skipped: SkippedBinary})
+		return
+	}
+
+	if s.opts.ArchiveDepth > 0 {
+		if kind := archiveKind(f, info.Size()); kind != "" {
+			budget := s.opts.MaxArchiveSize
+			if err := s.archive(path, f, info.Size(), kind, 1, &budget, fn); errors.Is(err, errArchiveBudget) {
+				fn(Result{File: path, Skipped: fmt.Sprintf("extracts to more than %d MB", s.opts.MaxArchiveSize>>20)})
+			}
+			return
+		}
+	}
+
+	if info.Size() > s.opts.MaxSize {
+		fn(Result{File: path, Skipped: fmt.Sprintf("larger than %d MB", s.opts.MaxSize>>20)})
+		return
+	}
+	data, err := io.ReadAll(f)
+	if err != nil {
+		fn(Result{File: path, Err: err})
+		return
+	}
+	result, text := s.scan(path, data)
+	if s.opts.Redact && len(result.Findings)+result.Known+len(result.Suppressed) > 0 {
+		result.Redacted, result.Err = s.redact(path, data, text, result.Kind)
+	}
+	fn(result)
+}
+
+// scan scans the content of a file and returns its text
+func (s *Scanner) scan(name string, data []byte) (Result, string) {
+	result := Result{File: name, Findings: []Finding{}, Kind: extract.Kind(data)}
+	text, err := extract.Text(data)
+	if errors.Is(err, extract.ErrUnsupported) {
+		result.Skipped = SkippedBinary
+		return result, ""
+	}
+	if err != nil {
+		result.Err = fmt.Errorf("failed to extract text: %v", err)
+		return result, ""
+	}
+
+	found := s.scanText(name, text)
+	result.Findings, result.Suppressed, result.Known = found.Findings, found.Suppressed, found.Known
+	return result, text
+}
+
+// scanText returns the findings in the text of a file
+func (s *Scanner) scanText(name, text string) Result {
+	result := Result{File: name, Findings: []Finding{}}
+	_, _, summary := s.detectors.Filter(text)
+	suppressions := ParseSuppressions(text)
+	for _, r := range summary.Replacements {
+		line, column := Locate(text, r.Start)
+		action := r.Action
+		if action == "" {
+			action = config.ActionReplace
+		}
+		f := Finding{File: name, Line: line, Column: column, Type: r.Type, Action: action, Hash: hashValue(s.opts.HashKey, r.Original)}
+		if suppressions.Covers(line, r.Type) {
+			result.Suppressed = append(result.Suppressed, f)
+			continue
+		}
+		if s.opts.Baseline != nil && s.opts.Baseline.Contains(f) {
+			result.Known++
+			continue
+		}
+		result.Findings = append(result.Findings, f)
+	}
+	return result
+}
+
+// redact writes the redacted copy of a file, if anything in it is redacted
+func (s *Scanner) redact(path string, data []byte, text, kind string) (string, error) {
+	var redacted []byte
+	target := redactedPath(path, kind)
+	if kind == extract.KindPDF {
+		// PDF text cannot be rewritten in place, so write it out as text
+		edits := s.edits(text)
+		if len(edits) == 0 {
+			return "", nil
+		}
+		redacted = []byte(extract.Apply(text, edits))
+	} else {
+		var changed bool
+		var err error
+		redacted, changed, err = extract.Redact(data, s.edits)
+		if err != nil {
+			return "", fmt.Errorf("failed to redact: %v", err)
+		}
+		if !changed {
+			return "", nil
+		}
+	}
+
+	mode := os.FileMode(0644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	if err := os.WriteFile(target, redacted, mode); err != nil {
+		return "", fmt.Errorf("failed to write redacted copy: %v", err)
+	}
+	return target, nil
+}
+
+// edits returns the replacements of the values in text detectors redact,
+// leaving values suppressed by ps:ignore annotations alone
+func (s *Scanner) edits(text string) []extract.Edit {
+	_, _, summary := s.detectors.Filter(text)
+	suppressions := ParseSuppressions(text)
+	var edits []extract.Edit
+	for _, r := range summary.Replacements {
+		if line, _ := Locate(text, r.Start); suppressions.Covers(line, r.Type) {
+			continue
+		}
+		if r.Replacement != r.Original {
+			edits = append(edits, extract.Edit{Start: r.Start, End: r.End, Text: r.Replacement})
+		}
+	}
+	return edits
+}
+
+// redactedPath returns where the redacted copy of a file is written
+func redactedPath(path, kind string) string {
+	ext := filepath.Ext(path)
+	base := strings.TrimSuffix(path, ext)
+	if kind == extract.KindPDF {
+		ext = ".txt"
+	}
+	return base + redactedSuffix + ext
+}
+
+// Locate returns the line and column, both counted from 1, of a byte
+// offset in text. Columns count characters.
+func Locate(text string, offset int) (int, int) {
+	before := text[:offset]
+	lineStart := strings.LastIndexByte(before, '\n') + 1
+	return strings.Count(before, "\n") + 1, utf8.RuneCountInString(before[lineStart:]) + 1
+}
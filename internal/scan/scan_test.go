@@ -0,0 +1,173 @@
+package scan
+
+import (
+	"archive/zip"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+	"github.com/happytaoer/prompt-security/internal/extract"
+	"github.com/happytaoer/prompt-security/internal/filter"
+)
+
+// testScanner returns a scanner replacing emails and logging SSNs
+func testScanner(opts Options) *Scanner {
+	return New(filter.NewDetectorSet(config.Config{
+		DetectEmails:     true,
+		EmailReplacement: "[EMAIL]",
+		DetectSSNs:       true,
+		SSNReplacement:   "XXX-XX-XXXX",
+		SSNAction:        config.ActionLog,
+	}), opts)
+}
+
+// writeDOCX writes a Word document whose body is a single paragraph
+func writeDOCX(t *testing.T, path, body string) {
+	var b bytes.Buffer
+	zw := zip.NewWriter(&b)
+	w, _ := zw.Create("word/document.xml")
+	w.Write([]byte(`<w:document xmlns:w="w"><w:body><w:p><w:r><w:t>` + body + `</w:t></w:r></w:p></w:body></w:document>`))
+	zw.Close()
+	if err := os.WriteFile(path, b.Bytes(), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestWalk tests that a directory is scanned file by file, skipping binaries
+// and redacted copies, and that redacted copies are written next to the
+// files
+func TestWalk(t *testing.T) {
+	dir := t.TempDir()
+	os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("hello\n  ssn 123-45-6789, café a@example.com\n"), 0644)
+	os.WriteFile(filepath.Join(dir, "image.png"), []byte("\x89PNG\r\n\x1a\n\x00\x00"), 0644)
+	os.WriteFile(filepath.Join(dir, "notes.redacted.txt"), []byte("a@example.com"), 0644)
+	writeDOCX(t, filepath.Join(dir, "letter.docx"), "Reply to bob@example.org")
+
+	var results []Result
+	if err := testScanner(Options{Redact: true}).Walk([]string{dir}, func(r Result) { results = append(results, r) }); err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 3 {
+		t.Fatalf("Got %d results, want 3: %+v", len(results), results)
+	}
+
+	if r := results[0]; r.Skipped != "binary" {
+		t.Errorf("Expected image.png to be skipped, got %+v", r)
+	}
+
+	docx := results[1]
+	if docx.Kind != extract.KindDOCX || len(docx.Findings) != 1 || docx.Findings[0].Type != "email" {
+		t.Fatalf("Unexpected result %+v", docx)
+	}
+	data, err := os.ReadFile(docx.Redacted)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text, _ := extract.Text(data); text != "Reply to [EMAIL]" {
+		t.Errorf("Redacted document reads %q", text)
+	}
+
+	text := results[2]
+	want := []Finding{
+		{File: text.File, Line: 2, Column: 7, Type: "ssn", Action: config.ActionLog},
+		{File: text.File, Line: 2, Column: 25, Type: "email", Action: config.ActionReplace},
+	}
+	if len(text.Findings) != len(want) {
+		t.Fatalf("Got findings %+v, want %+v", text.Findings, want)
+	}
+	for i := range want {
+		if text.Findings[i].Hash == "" {
+			t.Errorf("Finding %d has no hash", i)
+		}
+		text.Findings[i].Hash = ""
+		if text.Findings[i] != want[i] {
+			t.Errorf("Finding %d: got %+v, want %+v", i, text.Findings[i], want[i])
+		}
+	}
+	redacted, _ := os.ReadFile(filepath.Join(dir, "notes.redacted.txt"))
+	if string(redacted) != "hello\n  ssn 123-45-6789, café [EMAIL]\n" {
+		t.Errorf("Redacted copy reads %q", redacted)
+	}
+}
+
+// TestFileTooLarge tests that files above the size limit are skipped
+func TestFileTooLarge(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "big.txt")
+	os.WriteFile(path, bytes.Repeat([]byte("a"), 2<<20), 0644)
+	var results []Result
+	testScanner(Options{MaxSize: 1 << 20}).File(path, func(r Result) { results = append(results, r) })
+	if len(results) != 1 || results[0].Skipped == "" {
+		t.Errorf("Expected the file to be skipped, got %+v", results)
+	}
+}
+
+// TestLocate tests that byte offsets are turned into lines and columns
+// counted in characters
+func TestLocate(t *testing.T) {
+	text := "ab\nçd\nef"
+	tests := []struct{ offset, line, column int }{
+		{0, 1, 1},
+		{2, 1, 3},
+		{3, 2, 1},
+		{5, 2, 2},
+		{7, 3, 1},
+	}
+	for _, tt := range tests {
+		if line, column := Locate(text, tt.offset); line != tt.line || column != tt.column {
+			t.Errorf("Locate(%d) = %d:%d, want %d:%d", tt.offset, line, column, tt.line, tt.column)
+		}
+	}
+}
+
+// TestWalkParallelOrder tests that parallel scans report files in the order
+// of a sequential scan, and still report missing paths
+func TestWalkParallelOrder(t *testing.T) {
+	dir := t.TempDir()
+	for i := 0; i < 40; i++ {
+		sub := filepath.Join(dir, fmt.Sprintf("d%d", i%4))
+		os.MkdirAll(sub, 0755)
+		os.WriteFile(filepath.Join(sub, fmt.Sprintf("f%02d.txt", i)), []byte(strings.Repeat("x ", i*100)+"a@example.com"), 0644)
+	}
+
+	walk := func(jobs int) []string {
+		var files []string
+		err := testScanner(Options{Jobs: jobs}).Walk([]string{dir, filepath.Join(dir, "missing")}, func(r Result) {
+			files = append(files, r.File)
+		})
+		if err == nil {
+			t.Error("Expected an error for the missing path")
+		}
+		return files
+	}
+	sequential, parallel := walk(1), walk(8)
+	if len(sequential) != 40 || strings.Join(sequential, "\n") != strings.Join(parallel, "\n") {
+		t.Errorf("Parallel results out of order:\n%v\n%v", sequential, parallel)
+	}
+}
+
+// TestTally tests that the tally counts files and findings and breaks them
+// down by type and file
+func TestTally(t *testing.T) {
+	var tally Tally
+	tally.Add(Result{File: "a.txt", Findings: []Finding{{Type: "email"}, {Type: "ssn"}, {Type: "email"}}})
+	tally.Add(Result{File: "b.txt", Findings: []Finding{{Type: "ssn"}}})
+	tally.Add(Result{File: "c.txt", Findings: []Finding{}})
+	tally.Add(Result{File: "d.png", Skipped: SkippedBinary})
+	tally.Add(Result{File: "e.txt", Err: os.ErrPermission})
+
+	if tally.Files != 3 || tally.Skipped != 1 || tally.Failed != 1 || tally.Findings != 4 || tally.FilesWithFindings() != 2 {
+		t.Errorf("Unexpected tally %+v", tally)
+	}
+	types := fmt.Sprint(tally.ByType())
+	if types != "[{email 2 1} {ssn 2 2}]" {
+		t.Errorf("Got types %s", types)
+	}
+	files := fmt.Sprint(tally.ByFile())
+	if files != "[{a.txt 3 [email ssn]} {b.txt 1 [ssn]}]" {
+		t.Errorf("Got files %s", files)
+	}
+}
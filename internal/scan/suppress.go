@@ -0,0 +1,62 @@
+package scan
+
+import (
+	"regexp"
+	"strings"
+)
+
+// suppressionPattern matches the annotations exempting a line from
+// findings, in a comment of any syntax:
+//
+//	secret := "a@example.com" // ps:ignore
+//	# ps:ignore-next-line rule=email,phone
+var suppressionPattern = regexp.MustCompile(`\bps:ignore(-next-line)?\b(?:[ \t]+rule=([\w.,-]+))?`)
+
+// Suppressions are the lines of a text exempted from findings by
+// ps:ignore annotations, for acknowledged test fixtures
+type Suppressions map[int]*lineSuppression
+
+// lineSuppression is what is exempted on a line
+type lineSuppression struct {
+	all   bool     // Every detector
+	rules []string // Detectors exempted, if not all
+}
+
+// ParseSuppressions finds the ps:ignore annotations of text. ps:ignore
+// exempts its own line and ps:ignore-next-line the line after it, from
+// every detector or only those listed with rule=.
+func ParseSuppressions(text string) Suppressions {
+	if !strings.Contains(text, "ps:ignore") {
+		return nil
+	}
+	s := Suppressions{}
+	for i, line := range strings.Split(text, "\n") {
+		for _, m := range suppressionPattern.FindAllStringSubmatch(line, -1) {
+			target := i + 1
+			if m[1] != "" {
+				target++
+			}
+			ls, ok := s[target]
+			if !ok {
+				ls = &lineSuppression{}
+				s[target] = ls
+			}
+			if m[2] == "" {
+				ls.all = true
+			} else {
+				ls.rules = append(ls.rules, strings.Split(m[2], ",")...)
+			}
+		}
+	}
+	return s
+}
+
+// Covers reports whether findings of a detector on a line, counted from 1,
+// are suppressed
+func (s Suppressions) Covers(line int, typ string) bool {
+	ls, ok := s[line]
+	if !ok {
+		return false
+	}
+	return ls.all || contains(ls.rules, typ)
+}
@@ -0,0 +1,69 @@
+package scan
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestSuppressions tests that ps:ignore covers its own line and
+// ps:ignore-next-line the next one, for every type or those of rule=, and
+// that similar words are not annotations
+func TestSuppressions(t *testing.T) {
+	text := `a := "x" // ps:ignore
+# ps:ignore-next-line rule=email,phone
+b
+c // ps:ignore rule=ssn ps:ignore-next-line
+d
+e // ps:ignored
+f // ps:ignore-next-line rule=email`
+	s := ParseSuppressions(text)
+	tests := []struct {
+		line int
+		typ  string
+		want bool
+	}{
+		{1, "email", true},
+		{1, "ssn", true},
+		{2, "email", false},
+		{3, "email", true},
+		{3, "phone", true},
+		{3, "ssn", false},
+		{4, "ssn", true},
+		{4, "email", false},
+		{5, "email", true},
+		{6, "email", false},
+		{7, "email", false},
+		{8, "email", true},
+	}
+	for _, tt := range tests {
+		if got := s.Covers(tt.line, tt.typ); got != tt.want {
+			t.Errorf("Covers(%d, %q) = %v, want %v", tt.line, tt.typ, got, tt.want)
+		}
+	}
+	if ParseSuppressions("no annotations") != nil {
+		t.Error("Expected no suppressions")
+	}
+}
+
+// TestScanSuppressed tests that suppressed findings are reported apart from
+// the others and left out of redacted copies
+func TestScanSuppressed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "fixture_test.go")
+	os.WriteFile(path, []byte("// ps:ignore-next-line rule=email\nconst a = \"a@example.com\"\nconst b = \"b@example.com\"\n"), 0644)
+
+	var results []Result
+	testScanner(Options{Redact: true}).File(path, func(r Result) { results = append(results, r) })
+	if len(results) != 1 || len(results[0].Findings) != 1 || len(results[0].Suppressed) != 1 {
+		t.Fatalf("Unexpected results %+v", results)
+	}
+	if f := results[0].Suppressed[0]; f.Line != 2 || f.Type != "email" {
+		t.Errorf("Unexpected suppressed finding %+v", f)
+	}
+
+	// Acknowledged values are not redacted either
+	redacted, _ := os.ReadFile(results[0].Redacted)
+	if string(redacted) != "// ps:ignore-next-line rule=email\nconst a = \"a@example.com\"\nconst b = \"[EMAIL]\"\n" {
+		t.Errorf("Redacted copy reads %q", redacted)
+	}
+}
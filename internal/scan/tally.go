@@ -0,0 +1,120 @@
+package scan
+
+import "sort"
+
+// Tally counts the results of a scan
+type Tally struct {
+	Files    int // Files scanned
+	Skipped  int // Files not scanned, binaries included
+	Failed   int // Files that could not be read
+	Findings int
+	Known    int // Findings suppressed by the baseline
+
+	// Suppressed are findings exempted by ps:ignore annotations
+	Suppressed int
+
+	types map[string]*TypeCount
+	files map[string]*FileCount
+}
+
+// TypeCount is the findings of a detector
+type TypeCount struct {
+	Type     string `json:"type"`
+	Findings int    `json:"findings"`
+	Files    int    `json:"files"`
+}
+
+// FileCount is the findings in a file
+type FileCount struct {
+	File     string   `json:"file"`
+	Findings int      `json:"findings"`
+	Types    []string `json:"types"` // Detectors with findings, sorted
+}
+
+// Add counts a result
+func (t *Tally) Add(r Result) {
+	switch {
+	case r.Err != nil:
+		t.Failed++
+		return
+	case r.Skipped != "":
+		t.Skipped++
+		return
+	}
+	t.Files++
+	t.Known += r.Known
+	t.Suppressed += len(r.Suppressed)
+	if len(r.Findings) == 0 {
+		return
+	}
+	if t.types == nil {
+		t.types = map[string]*TypeCount{}
+		t.files = map[string]*FileCount{}
+	}
+
+	// Files can have several results, e.g. from the commits of a history
+	file, ok := t.files[r.File]
+	if !ok {
+		file = &FileCount{File: r.File}
+		t.files[r.File] = file
+	}
+	for _, f := range r.Findings {
+		t.Findings++
+		file.Findings++
+		tc, ok := t.types[f.Type]
+		if !ok {
+			tc = &TypeCount{Type: f.Type}
+			t.types[f.Type] = tc
+		}
+		tc.Findings++
+		if !contains(file.Types, f.Type) {
+			file.Types = append(file.Types, f.Type)
+			tc.Files++
+		}
+	}
+	sort.Strings(file.Types)
+}
+
+// FilesWithFindings returns how many files have findings
+func (t *Tally) FilesWithFindings() int {
+	return len(t.files)
+}
+
+// ByType returns the findings of each detector, most found first
+func (t *Tally) ByType() []TypeCount {
+	counts := make([]TypeCount, 0, len(t.types))
+	for _, tc := range t.types {
+		counts = append(counts, *tc)
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Findings != counts[j].Findings {
+			return counts[i].Findings > counts[j].Findings
+		}
+		return counts[i].Type < counts[j].Type
+	})
+	return counts
+}
+
+// ByFile returns the findings in each file, most found first
+func (t *Tally) ByFile() []FileCount {
+	counts := make([]FileCount, 0, len(t.files))
+	for _, fc := range t.files {
+		counts = append(counts, *fc)
+	}
+	sort.Slice(counts, func(i, j int) bool {
+		if counts[i].Findings != counts[j].Findings {
+			return counts[i].Findings > counts[j].Findings
+		}
+		return counts[i].File < counts[j].File
+	})
+	return counts
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,220 @@
+// Package telemetry instruments the application with OpenTelemetry traces
+// and metrics. Instrumentation always goes through the global providers,
+// which do nothing until Setup installs OTLP exporters; exporting is enabled
+// by the standard OTEL_EXPORTER_OTLP_* environment variables.
+package telemetry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlpmetric/otlpmetrichttp"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/metric"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies this application's tracer and meter
+const instrumentationName = "github.com/happytaoer/prompt-security"
+
+// serviceName is the default service.name, overridable with OTEL_SERVICE_NAME
+const serviceName = "prompt-security"
+
+// Setup installs OTLP/HTTP trace and metric exporters for the signals that
+// have an endpoint configured (OTEL_EXPORTER_OTLP_ENDPOINT, or the
+// signal-specific OTEL_EXPORTER_OTLP_TRACES_ENDPOINT and
+// OTEL_EXPORTER_OTLP_METRICS_ENDPOINT). It returns a function that flushes
+// and stops the exporters. Without any endpoint it does nothing.
+func Setup(ctx context.Context) (shutdown func(context.Context) error, err error) {
+	var shutdowns []func(context.Context) error
+	shutdown = func(ctx context.Context) error {
+		var errs []error
+		for _, fn := range shutdowns {
+			errs = append(errs, fn(ctx))
+		}
+		return errors.Join(errs...)
+	}
+
+	traces, metrics := configured("TRACES"), configured("METRICS")
+	if !traces && !metrics {
+		return shutdown, nil
+	}
+
+	res, err := resource.New(ctx,
+		resource.WithAttributes(attribute.String("service.name", serviceName)),
+		resource.WithFromEnv(),
+	)
+	if err != nil {
+		return shutdown, err
+	}
+
+	if traces {
+		exporter, err := otlptracehttp.New(ctx)
+		if err != nil {
+			return shutdown, err
+		}
+		provider := sdktrace.NewTracerProvider(sdktrace.WithBatcher(exporter), sdktrace.WithResource(res))
+		otel.SetTracerProvider(provider)
+		shutdowns = append(shutdowns, provider.Shutdown)
+	}
+
+	if metrics {
+		exporter, err := otlpmetrichttp.New(ctx)
+		if err != nil {
+			return shutdown, err
+		}
+		provider := sdkmetric.NewMeterProvider(sdkmetric.WithReader(sdkmetric.NewPeriodicReader(exporter)), sdkmetric.WithResource(res))
+		otel.SetMeterProvider(provider)
+		shutdowns = append(shutdowns, provider.Shutdown)
+	}
+
+	return shutdown, nil
+}
+
+// configured reports whether an OTLP endpoint is set for signal
+func configured(signal string) bool {
+	return os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT") != "" || os.Getenv("OTEL_EXPORTER_OTLP_"+signal+"_ENDPOINT") != ""
+}
+
+// instrumentSet holds the metric instruments
+type instrumentSet struct {
+	once          sync.Once
+	filter        metric.Float64Histogram
+	replacements  metric.Int64Counter
+	clipboardRead metric.Float64Histogram
+	db            metric.Float64Histogram
+	http          metric.Float64Histogram
+}
+
+var instruments instrumentSet
+
+// meter returns the metric instruments, created on first use from the
+// global meter provider
+func meter() *instrumentSet {
+	instruments.once.Do(func() {
+		m := otel.Meter(instrumentationName)
+		// Instrument creation only fails for invalid names; the returned
+		// no-op instruments are safe to use either way
+		instruments.filter, _ = m.Float64Histogram("filter.duration",
+			metric.WithUnit("ms"), metric.WithDescription("Duration of filter runs"))
+		instruments.replacements, _ = m.Int64Counter("filter.replacements",
+			metric.WithDescription("Sensitive values replaced"))
+		instruments.clipboardRead, _ = m.Float64Histogram("clipboard.read.duration",
+			metric.WithUnit("ms"), metric.WithDescription("Duration of clipboard reads"))
+		instruments.db, _ = m.Float64Histogram("db.operation.duration",
+			metric.WithUnit("ms"), metric.WithDescription("Duration of database operations"))
+		instruments.http, _ = m.Float64Histogram("http.server.request.duration",
+			metric.WithUnit("ms"), metric.WithDescription("Duration of API requests"))
+	})
+	return &instruments
+}
+
+// tracer returns the application tracer from the global tracer provider
+func tracer() trace.Tracer {
+	return otel.Tracer(instrumentationName)
+}
+
+// milliseconds converts the time elapsed since start for histograms
+func milliseconds(start time.Time) float64 {
+	return float64(time.Since(start)) / float64(time.Millisecond)
+}
+
+// end records err on span, if any, and ends it
+func end(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// StartFilter starts tracing a filter run over size bytes. The returned
+// function ends it, recording the scan mode (e.g. "full", "chunked",
+// "incremental") and the number of replacements made.
+func StartFilter(ctx context.Context, size int) (context.Context, func(mode string, replacements int, err error)) {
+	start := time.Now()
+	ctx, span := tracer().Start(ctx, "filter", trace.WithAttributes(attribute.Int("filter.bytes", size)))
+	return ctx, func(mode string, replacements int, err error) {
+		attrs := metric.WithAttributes(attribute.String("filter.mode", mode))
+		meter().filter.Record(ctx, milliseconds(start), attrs)
+		meter().replacements.Add(ctx, int64(replacements), attrs)
+		span.SetAttributes(attribute.String("filter.mode", mode), attribute.Int("filter.replacements", replacements))
+		end(span, err)
+	}
+}
+
+// StartClipboardRead starts tracing a clipboard read. The returned function
+// ends it with the number of bytes read.
+func StartClipboardRead(ctx context.Context) (context.Context, func(size int, err error)) {
+	start := time.Now()
+	ctx, span := tracer().Start(ctx, "clipboard.read")
+	return ctx, func(size int, err error) {
+		meter().clipboardRead.Record(ctx, milliseconds(start))
+		span.SetAttributes(attribute.Int("clipboard.bytes", size))
+		end(span, err)
+	}
+}
+
+// StartDB starts tracing a database operation on table
+func StartDB(ctx context.Context, operation, table string) (context.Context, func(err error)) {
+	start := time.Now()
+	attrs := []attribute.KeyValue{
+		attribute.String("db.system", "sqlite"),
+		attribute.String("db.operation", operation),
+		attribute.String("db.sql.table", table),
+	}
+	ctx, span := tracer().Start(ctx, "db."+operation, trace.WithSpanKind(trace.SpanKindClient), trace.WithAttributes(attrs...))
+	return ctx, func(err error) {
+		meter().db.Record(ctx, milliseconds(start), metric.WithAttributes(attrs...))
+		end(span, err)
+	}
+}
+
+// HTTPHandler traces the requests served by next under route, the path
+// template of the API route
+func HTTPHandler(route string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ctx, span := tracer().Start(r.Context(), r.Method+" "+route,
+			trace.WithSpanKind(trace.SpanKindServer),
+			trace.WithAttributes(
+				attribute.String("http.request.method", r.Method),
+				attribute.String("http.route", route),
+			))
+		defer span.End()
+
+		sw := &statusWriter{ResponseWriter: w, status: http.StatusOK}
+		next(sw, r.WithContext(ctx))
+
+		span.SetAttributes(attribute.Int("http.response.status_code", sw.status))
+		if sw.status >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(sw.status))
+		}
+		meter().http.Record(ctx, milliseconds(start), metric.WithAttributes(
+			attribute.String("http.request.method", r.Method),
+			attribute.String("http.route", route),
+			attribute.Int("http.response.status_code", sw.status),
+		))
+	}
+}
+
+// statusWriter remembers the status code written to a response
+type statusWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (w *statusWriter) WriteHeader(status int) {
+	w.status = status
+	w.ResponseWriter.WriteHeader(status)
+}
@@ -0,0 +1,296 @@
+// Command clientgen generates the Go API client in /client from the web
+// route registry so the client always matches the server's JSON shapes.
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"go/format"
+	"log"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/happytaoer/prompt-security/internal/web"
+)
+
+func main() {
+	output := flag.String("o", "zz_generated.go", "Output file")
+	flag.Parse()
+
+	src, err := generate(web.Operations())
+	if err != nil {
+		log.Fatalf("Failed to generate client: %v", err)
+	}
+
+	if err := os.WriteFile(*output, src, 0644); err != nil {
+		log.Fatalf("Failed to write client: %v", err)
+	}
+}
+
+var (
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+	timeType       = reflect.TypeOf(time.Time{})
+)
+
+// generator accumulates the client source and the named types it references
+type generator struct {
+	types   map[string]reflect.Type
+	imports map[string]bool
+}
+
+// generate returns the formatted client source for ops
+func generate(ops []web.Operation) ([]byte, error) {
+	g := &generator{
+		types:   make(map[string]reflect.Type),
+		imports: map[string]bool{"context": true},
+	}
+
+	var methods bytes.Buffer
+	for _, op := range ops {
+		if err := g.writeMethod(&methods, op); err != nil {
+			return nil, err
+		}
+	}
+
+	var types bytes.Buffer
+	written := make(map[string]bool)
+	for len(written) < len(g.types) {
+		names := make([]string, 0, len(g.types))
+		for name := range g.types {
+			if !written[name] {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			g.writeStruct(&types, g.types[name])
+			written[name] = true
+		}
+	}
+
+	imports := make([]string, 0, len(g.imports))
+	for imp := range g.imports {
+		imports = append(imports, imp)
+	}
+	sort.Strings(imports)
+
+	var out bytes.Buffer
+	out.WriteString("// Code generated by clientgen from the web route registry. DO NOT EDIT.\n\n")
+	out.WriteString("package client\n\nimport (\n")
+	for _, imp := range imports {
+		fmt.Fprintf(&out, "\t%q\n", imp)
+	}
+	out.WriteString(")\n\n")
+	out.Write(types.Bytes())
+	out.Write(methods.Bytes())
+
+	return format.Source(out.Bytes())
+}
+
+// writeMethod emits the client method (and params struct) for op
+func (g *generator) writeMethod(buf *bytes.Buffer, op web.Operation) error {
+	args := []string{"ctx context.Context"}
+	query := "nil"
+
+	path, err := g.pathExpr(op)
+	if err != nil {
+		return err
+	}
+	for _, p := range op.Params {
+		goType, err := queryGoType(p.Type)
+		if err != nil {
+			return fmt.Errorf("%s: %v", op.ID, err)
+		}
+		args = append(args, p.Name+" "+goType)
+	}
+
+	if len(op.Query) > 0 {
+		g.imports["net/url"] = true
+		fmt.Fprintf(buf, "// %sParams holds the query parameters for %s\n", op.ID, op.ID)
+		fmt.Fprintf(buf, "type %sParams struct {\n", op.ID)
+		for _, q := range op.Query {
+			goType, err := queryGoType(q.Type)
+			if err != nil {
+				return fmt.Errorf("%s: %v", op.ID, err)
+			}
+			fmt.Fprintf(buf, "\t%s %s // %s\n", exportName(q.Name), goType, q.Description)
+		}
+		buf.WriteString("}\n\n")
+		args = append(args, "params "+op.ID+"Params")
+		query = "q"
+	}
+
+	if op.Request != nil {
+		args = append(args, "body "+g.goType(reflect.TypeOf(op.Request)))
+	}
+
+	respType := reflect.TypeOf(op.Response)
+	resp := g.goType(respType)
+	byPointer := respType.Kind() == reflect.Struct
+	if byPointer {
+		resp = "*" + resp
+	}
+
+	fmt.Fprintf(buf, "// %s calls %s %s (requires role %s).\n//\n// %s.\n", op.ID, op.Method, op.Path, op.Role, op.Summary)
+	fmt.Fprintf(buf, "func (c *Client) %s(%s) (%s, error) {\n", op.ID, strings.Join(args, ", "), resp)
+
+	if len(op.Query) > 0 {
+		buf.WriteString("\tq := url.Values{}\n")
+		for _, q := range op.Query {
+			field := "params." + exportName(q.Name)
+			switch q.Type {
+			case "integer":
+				g.imports["strconv"] = true
+				fmt.Fprintf(buf, "\tif %s != 0 {\n\t\tq.Set(%q, strconv.Itoa(%s))\n\t}\n", field, q.Name, field)
+			case "boolean":
+				fmt.Fprintf(buf, "\tif %s {\n\t\tq.Set(%q, \"true\")\n\t}\n", field, q.Name)
+			default:
+				fmt.Fprintf(buf, "\tif %s != \"\" {\n\t\tq.Set(%q, %s)\n\t}\n", field, q.Name, field)
+			}
+		}
+	}
+
+	body := "nil"
+	if op.Request != nil {
+		body = "body"
+	}
+
+	fmt.Fprintf(buf, "\tvar out %s\n", g.goType(respType))
+	fmt.Fprintf(buf, "\tif err := c.do(ctx, %q, %s, %s, %s, &out); err != nil {\n", op.Method, path, query, body)
+	buf.WriteString("\t\treturn nil, err\n\t}\n")
+	if byPointer {
+		buf.WriteString("\treturn &out, nil\n}\n\n")
+	} else {
+		buf.WriteString("\treturn out, nil\n}\n\n")
+	}
+	return nil
+}
+
+// pathExpr returns the Go expression building op's request path from its
+// path parameters
+func (g *generator) pathExpr(op web.Operation) (string, error) {
+	var parts []string
+	rest := op.Path
+	for {
+		start := strings.Index(rest, "{")
+		if start < 0 {
+			break
+		}
+		end := strings.Index(rest, "}")
+		if end < start {
+			return "", fmt.Errorf("%s: malformed path %s", op.ID, op.Path)
+		}
+		if start > 0 {
+			parts = append(parts, strconv.Quote(rest[:start]))
+		}
+
+		name := rest[start+1 : end]
+		param, ok := findParam(op.Params, name)
+		if !ok {
+			return "", fmt.Errorf("%s: undeclared path parameter %s", op.ID, name)
+		}
+		switch param.Type {
+		case "integer":
+			g.imports["strconv"] = true
+			parts = append(parts, "strconv.Itoa("+name+")")
+		case "string":
+			g.imports["net/url"] = true
+			parts = append(parts, "url.PathEscape("+name+")")
+		default:
+			return "", fmt.Errorf("%s: unsupported path parameter type %q", op.ID, param.Type)
+		}
+		rest = rest[end+1:]
+	}
+	if rest != "" || len(parts) == 0 {
+		parts = append(parts, strconv.Quote(rest))
+	}
+	return strings.Join(parts, " + "), nil
+}
+
+// findParam looks up a parameter by name
+func findParam(params []web.QueryParam, name string) (web.QueryParam, bool) {
+	for _, p := range params {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return web.QueryParam{}, false
+}
+
+// writeStruct emits a struct definition mirroring t's JSON shape
+func (g *generator) writeStruct(buf *bytes.Buffer, t reflect.Type) {
+	fmt.Fprintf(buf, "// %s mirrors the server's %s type\n", t.Name(), t.String())
+	fmt.Fprintf(buf, "type %s struct {\n", t.Name())
+	for _, f := range web.JSONFields(t) {
+		tag := f.Name
+		if f.OmitEmpty {
+			tag += ",omitempty"
+		}
+		fmt.Fprintf(buf, "\t%s %s `json:%q`\n", f.GoName, g.goType(f.Type), tag)
+	}
+	buf.WriteString("}\n\n")
+}
+
+// goType returns the Go source for t, registering named structs for emission
+func (g *generator) goType(t reflect.Type) string {
+	switch {
+	case t == nil:
+		return "interface{}"
+	case t == rawMessageType:
+		g.imports["encoding/json"] = true
+		return "json.RawMessage"
+	case t == timeType:
+		g.imports["time"] = true
+		return "time.Time"
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return "*" + g.goType(t.Elem())
+	case reflect.Slice:
+		return "[]" + g.goType(t.Elem())
+	case reflect.Map:
+		return "map[" + g.goType(t.Key()) + "]" + g.goType(t.Elem())
+	case reflect.Interface:
+		return "interface{}"
+	case reflect.Struct:
+		if t.Name() == "" {
+			log.Fatalf("anonymous struct types are not supported in the API: %s", t)
+		}
+		if existing, ok := g.types[t.Name()]; ok && existing != t {
+			log.Fatalf("conflicting API type name %s: %s and %s", t.Name(), existing, t)
+		}
+		g.types[t.Name()] = t
+		return t.Name()
+	default:
+		// Named basic types (e.g. web.Role) are emitted as their kind
+		return t.Kind().String()
+	}
+}
+
+// queryGoType maps an OpenAPI primitive type to a Go type
+func queryGoType(openAPIType string) (string, error) {
+	switch openAPIType {
+	case "integer":
+		return "int", nil
+	case "boolean":
+		return "bool", nil
+	case "string":
+		return "string", nil
+	}
+	return "", fmt.Errorf("unsupported query parameter type %q", openAPIType)
+}
+
+// exportName converts a JSON parameter name to an exported Go identifier
+func exportName(name string) string {
+	if name == "id" {
+		return "ID"
+	}
+	return strings.ToUpper(name[:1]) + name[1:]
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/happytaoer/prompt-security/internal/web"
+)
+
+// TestGeneratedClientUpToDate ensures client/zz_generated.go matches the route registry
+func TestGeneratedClientUpToDate(t *testing.T) {
+	want, err := generate(web.Operations())
+	if err != nil {
+		t.Fatalf("Failed to generate client: %v", err)
+	}
+
+	got, err := os.ReadFile("../../../client/zz_generated.go")
+	if err != nil {
+		t.Fatalf("Failed to read generated client: %v", err)
+	}
+
+	if !bytes.Equal(got, want) {
+		t.Error("client/zz_generated.go is out of date, run `go generate ./client`")
+	}
+}
@@ -0,0 +1,150 @@
+// Package usage reports anonymous usage statistics when enabled. Only daily
+// detection counts per type and the application version are sent, so admins
+// can follow adoption without any content leaving the machine. Counts are
+// only kept while reporting is enabled, and a day is sent once it is over.
+package usage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+	"github.com/happytaoer/prompt-security/internal/db"
+)
+
+const (
+	// sendInterval is how often Run checks for finished days to send
+	sendInterval = time.Hour
+
+	// sendTimeout bounds a single report request
+	sendTimeout = 30 * time.Second
+)
+
+// Report is the body sent to the usage endpoint
+type Report struct {
+	Version string `json:"version"`
+	Days    []Day  `json:"days"`
+}
+
+// Day holds the detection counts of one day
+type Day struct {
+	Date       string         `json:"date"` // YYYY-MM-DD, UTC
+	Detections map[string]int `json:"detections"`
+}
+
+// Build groups usage counts into a report, oldest day first
+func Build(version string, counts []db.UsageCount) Report {
+	byDay := make(map[string]map[string]int)
+	for _, c := range counts {
+		if byDay[c.Day] == nil {
+			byDay[c.Day] = make(map[string]int)
+		}
+		byDay[c.Day][c.Type] += c.Count
+	}
+
+	report := Report{Version: version, Days: make([]Day, 0, len(byDay))}
+	for date, detections := range byDay {
+		report.Days = append(report.Days, Day{Date: date, Detections: detections})
+	}
+	sort.Slice(report.Days, func(i, j int) bool { return report.Days[i].Date < report.Days[j].Date })
+	return report
+}
+
+// Reporter counts detections and sends finished days to the configured
+// endpoint
+type Reporter struct {
+	manager *config.Manager
+	version string
+	logger  *slog.Logger
+	client  *http.Client
+	now     func() time.Time
+}
+
+// New creates a reporter sending the given application version
+func New(manager *config.Manager, version string) *Reporter {
+	return &Reporter{
+		manager: manager,
+		version: version,
+		logger:  slog.New(slog.NewJSONHandler(os.Stdout, nil)),
+		client:  &http.Client{Timeout: sendTimeout},
+		now:     time.Now,
+	}
+}
+
+// Count records detections of the given types, one per entry, if reporting
+// is enabled
+func (r *Reporter) Count(types []string) {
+	if !r.manager.Get().UsageReporting {
+		return
+	}
+	if err := db.AddUsage(r.now(), types); err != nil {
+		r.logger.Error("Failed to count usage", "error", err)
+	}
+}
+
+// Pending returns the report that will be sent next: the unreported counts
+// of the days before today
+func (r *Reporter) Pending() (Report, error) {
+	counts, err := db.GetUnreportedUsage(db.UsageDay(r.now()))
+	if err != nil {
+		return Report{}, err
+	}
+	return Build(r.version, counts), nil
+}
+
+// Run sends finished days while reporting is enabled (blocking)
+func (r *Reporter) Run() {
+	for {
+		if cfg := r.manager.Get(); cfg.UsageReporting {
+			if err := r.send(context.Background(), cfg.UsageEndpoint); err != nil {
+				r.logger.Warn("Failed to send usage report", "endpoint", cfg.UsageEndpoint, "error", err)
+			}
+		}
+		time.Sleep(sendInterval)
+	}
+}
+
+// send posts the pending report to endpoint and marks its days reported.
+// Days are kept and retried if sending fails.
+func (r *Reporter) send(ctx context.Context, endpoint string) error {
+	today := db.UsageDay(r.now())
+	counts, err := db.GetUnreportedUsage(today)
+	if err != nil || len(counts) == 0 {
+		return err
+	}
+
+	if err := r.post(ctx, endpoint, Build(r.version, counts)); err != nil {
+		return err
+	}
+	return db.MarkUsageReported(today)
+}
+
+// post sends a report as JSON
+func (r *Reporter) post(ctx context.Context, endpoint string, report Report) error {
+	body, err := json.Marshal(report)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("endpoint returned %s", resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package usage
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/happytaoer/prompt-security/internal/db"
+)
+
+// TestBuild tests that counts are grouped by day, oldest first
+func TestBuild(t *testing.T) {
+	report := Build("1.2.0", []db.UsageCount{
+		{Day: "2026-03-02", Type: "email", Count: 4},
+		{Day: "2026-03-01", Type: "email", Count: 1},
+		{Day: "2026-03-01", Type: "phone", Count: 2},
+	})
+
+	want := Report{
+		Version: "1.2.0",
+		Days: []Day{
+			{Date: "2026-03-01", Detections: map[string]int{"email": 1, "phone": 2}},
+			{Date: "2026-03-02", Detections: map[string]int{"email": 4}},
+		},
+	}
+	if !reflect.DeepEqual(report, want) {
+		t.Errorf("Expected %+v, got %+v", want, report)
+	}
+}
+
+// TestPost tests that reports are posted as JSON and failures reported
+func TestPost(t *testing.T) {
+	var got Report
+	status := http.StatusNoContent
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || r.Header.Get("Content-Type") != "application/json" {
+			t.Errorf("Unexpected request %s with content type %q", r.Method, r.Header.Get("Content-Type"))
+		}
+		json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	r := &Reporter{client: server.Client()}
+	report := Report{Version: "dev", Days: []Day{{Date: "2026-03-01", Detections: map[string]int{"ssn": 1}}}}
+	if err := r.post(context.Background(), server.URL, report); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(got, report) {
+		t.Errorf("Expected %+v to be sent, got %+v", report, got)
+	}
+
+	status = http.StatusInternalServerError
+	if err := r.post(context.Background(), server.URL, report); err == nil {
+		t.Error("Expected an error for a failed request")
+	}
+}
@@ -0,0 +1,279 @@
+package web
+
+import (
+	"github.com/happytaoer/prompt-security/internal/config"
+	"github.com/happytaoer/prompt-security/internal/db"
+	"github.com/happytaoer/prompt-security/internal/editor"
+)
+
+// StatusResponse is returned by endpoints that only report success
+type StatusResponse struct {
+	Status string `json:"status"`
+}
+
+// LogsPage is a page of filter log entries
+type LogsPage struct {
+	Logs       []db.LogEntry `json:"logs"`
+	Page       int           `json:"page"`
+	PageSize   int           `json:"pageSize"`
+	TotalCount int           `json:"totalCount"`
+	TotalPages int           `json:"totalPages"`
+}
+
+// AuditPage is a page of config audit entries
+type AuditPage struct {
+	Entries    []db.AuditEntry `json:"entries"`
+	Page       int             `json:"page"`
+	PageSize   int             `json:"pageSize"`
+	TotalCount int             `json:"totalCount"`
+	TotalPages int             `json:"totalPages"`
+}
+
+// ReviewPage is a page of uncertain matches with one review status
+type ReviewPage struct {
+	Items      []db.ReviewItem `json:"items"`
+	Page       int             `json:"page"`
+	PageSize   int             `json:"pageSize"`
+	TotalCount int             `json:"totalCount"`
+	TotalPages int             `json:"totalPages"`
+}
+
+// MonitorStatus reports the state of the clipboard monitor
+type MonitorStatus struct {
+	Running  bool   `json:"running"`
+	Paused   bool   `json:"paused"`
+	Profile  string `json:"profile"`            // Active detection profile
+	Schedule string `json:"schedule,omitempty"` // Schedule that selected the profile
+}
+
+// MonitorHealth reports whether the clipboard monitor loop is alive
+type MonitorHealth struct {
+	Status            string `json:"status"` // ok, backoff, stalled or stopped
+	Running           bool   `json:"running"`
+	LastHeartbeat     string `json:"last_heartbeat,omitempty"` // RFC 3339
+	Restarts          int    `json:"restarts"`                 // Restarts after a crash
+	LastPanic         string `json:"last_panic,omitempty"`
+	ConsecutiveErrors int    `json:"consecutive_errors"` // Clipboard reads failed in a row
+	LastError         string `json:"last_error,omitempty"`
+	BackoffMs         int64  `json:"backoff_ms"` // Delay before the next retry
+}
+
+// HeldCopy is a copy redacted by an ask detector whose original may still
+// be restored
+type HeldCopy struct {
+	ID        string   `json:"id"`
+	Source    string   `json:"source"` // clipboard or primary
+	Types     []string `json:"types"`
+	Filtered  string   `json:"filtered"`   // Redacted text on the clipboard
+	ExpiresAt string   `json:"expires_at"` // RFC 3339
+}
+
+// KeyboardStatus reports the state of keyboard protection
+type KeyboardStatus struct {
+	Enabled bool   `json:"enabled"`         // Enabled in the configuration
+	Active  bool   `json:"active"`          // Key presses are being read
+	Error   string `json:"error,omitempty"` // Why protection is not working
+}
+
+// BreakerStatus reports the state of the circuit breaker
+type BreakerStatus struct {
+	Enabled   bool   `json:"enabled"`              // A threshold is configured
+	Tripped   bool   `json:"tripped"`              // Every detector blocks
+	TrippedAt string `json:"tripped_at,omitempty"` // RFC 3339
+	Until     string `json:"until,omitempty"`      // End of the cooldown, RFC 3339; empty while tripped until reset
+	Recent    int    `json:"recent"`               // High severity detections counted within the window
+}
+
+// FilterRequest is the body of a filter request
+type FilterRequest struct {
+	Text string `json:"text"`
+}
+
+// Replacement describes a single replacement made by the filter
+type Replacement struct {
+	Type        string `json:"type"`
+	Original    string `json:"original"`
+	Replacement string `json:"replacement"`
+	Action      string `json:"action"` // replace, block, ask or log
+}
+
+// FilterResponse is the result of filtering text with the current configuration
+type FilterResponse struct {
+	Filtered     string        `json:"filtered"`
+	Changed      bool          `json:"changed"`
+	Action       string        `json:"action"` // Strongest action of the replacements
+	Replacements []Replacement `json:"replacements"`
+}
+
+// PatternImportRequest is the body of a dictionary import: CSV or TSV
+// literal terms to add as string match patterns with the given settings
+type PatternImportRequest struct {
+	Content         string `json:"content"`
+	Format          string `json:"format"` // csv, tsv or empty to detect
+	DryRun          bool   `json:"dry_run"`
+	Name            string `json:"name"`
+	Replacement     string `json:"replacement"` // Defaults to the name, e.g. [EMPLOYEE_NAME]
+	CaseInsensitive bool   `json:"case_insensitive"`
+	WholeWord       bool   `json:"whole_word"`
+	Fuzzy           bool   `json:"fuzzy"` // Also match misspellings by one character
+	Action          string `json:"action"`
+	Severity        string `json:"severity"`
+}
+
+// EditorScanRequest is the body of an editor scan request: the full text of
+// a document, or the changes since the version last scanned
+type EditorScanRequest struct {
+	URI         string              `json:"uri"`
+	Session     string              `json:"session,omitempty"` // Random ID of the editor session the document is cached for
+	Version     int                 `json:"version"`
+	Text        *string             `json:"text,omitempty"`         // Full text, scanned in full
+	BaseVersion int                 `json:"base_version,omitempty"` // Version the changes apply to
+	Changes     []editor.TextChange `json:"changes,omitempty"`
+	Selection   *editor.Range       `json:"selection,omitempty"` // Only report findings in this range
+}
+
+// EditorFinding is sensitive data in a document
+type EditorFinding struct {
+	Range       editor.Range `json:"range"`
+	Type        string       `json:"type"`
+	Action      string       `json:"action"`                // replace, block, ask, log or review
+	Replacement string       `json:"replacement,omitempty"` // Empty if the detector leaves matches in place
+}
+
+// EditorScanResponse lists the sensitive data in a document
+type EditorScanResponse struct {
+	URI      string          `json:"uri"`
+	Version  int             `json:"version"`
+	Findings []EditorFinding `json:"findings"`
+}
+
+// EditorRedactRequest is the body of an editor redact request. Without a
+// text, the version of the document last scanned is redacted.
+type EditorRedactRequest struct {
+	URI       string        `json:"uri"`
+	Session   string        `json:"session,omitempty"` // Editor session the document was scanned in
+	Text      *string       `json:"text,omitempty"`
+	Selection *editor.Range `json:"selection,omitempty"` // Only redact this range, widened to whole findings
+}
+
+// EditorRedactResponse is a redacted selection or document
+type EditorRedactResponse struct {
+	Version int               `json:"version"`
+	Text    string            `json:"text"`    // The selection or document, redacted
+	Edits   []editor.TextEdit `json:"edits"`   // The edits redacting it in the document
+	Blocked bool              `json:"blocked"` // Whether a copy would be blocked
+}
+
+// ValidateFile is a file or other content to validate
+type ValidateFile struct {
+	Name     string `json:"name,omitempty"` // e.g. prompts/system.txt, reported with findings
+	Content  string `json:"content"`
+	Encoding string `json:"encoding,omitempty"` // base64 if Content is encoded, empty for text
+}
+
+// ValidateRequest is content a build system checks against the policy: a
+// single document in Content, files in Files, or both
+type ValidateRequest struct {
+	UID      string            `json:"uid,omitempty"` // Returned in the response
+	Name     string            `json:"name,omitempty"`
+	Content  string            `json:"content,omitempty"`
+	Encoding string            `json:"encoding,omitempty"`
+	Files    []ValidateFile    `json:"files,omitempty"`
+	Metadata map[string]string `json:"metadata,omitempty"` // Returned in the response, e.g. the commit
+}
+
+// ValidateFinding is sensitive data found in validated content. Values are
+// never returned, so responses are safe to show in build logs.
+type ValidateFinding struct {
+	File   string `json:"file,omitempty"`
+	Path   string `json:"path,omitempty"` // Field of an admission request object
+	Line   int    `json:"line"`           // Counted from 1
+	Column int    `json:"column"`         // In characters, counted from 1
+	Type   string `json:"type"`
+	Action string `json:"action"` // replace, block, ask, log or review
+}
+
+// ValidateResponse is the decision on validated content: denied if a
+// detector would block, redact or ask about it
+type ValidateResponse struct {
+	UID      string            `json:"uid,omitempty"`
+	Allowed  bool              `json:"allowed"`
+	Reason   string            `json:"reason,omitempty"`
+	Findings []ValidateFinding `json:"findings"`
+	Metadata map[string]string `json:"metadata,omitempty"`
+
+	// Suppressed are findings exempted by ps:ignore annotations, which do
+	// not count against the content
+	Suppressed []ValidateFinding `json:"suppressed,omitempty"`
+}
+
+// ValidationResult is the outcome of validating a configuration
+type ValidationResult struct {
+	Valid  bool                `json:"valid"`
+	Errors []config.FieldError `json:"errors"`
+}
+
+// ReplayRequest is the body of a log replay request
+type ReplayRequest struct {
+	// Limit is how many of the newest logs to replay, 100 if zero
+	Limit int `json:"limit,omitempty"`
+	// Config, if set, is replayed instead of the current configuration, so
+	// changes can be tried before they are saved
+	Config *config.Config `json:"config,omitempty"`
+}
+
+// SetupStatus reports whether first-run setup is still needed and what it
+// offers
+type SetupStatus struct {
+	// Required is true until setup is completed or the configuration is
+	// changed some other way, meaning its defaults were reviewed
+	Required         bool     `json:"required"`
+	Completed        bool     `json:"completed"`
+	CompletedAt      string   `json:"completed_at,omitempty"` // RFC 3339
+	ConfigChanged    bool     `json:"config_changed"`         // The configuration was ever saved
+	TokensConfigured bool     `json:"tokens_configured"`      // The API requires a token
+	Detectors        []string `json:"detectors"`
+	DefaultDetectors []string `json:"default_detectors"`
+	Strategies       []string `json:"strategies"`
+	DefaultStrategy  string   `json:"default_strategy"`
+}
+
+// SetupRequest is the body of a setup request
+type SetupRequest struct {
+	config.SetupChoices
+	// AdminToken, if set, names an admin API token to create, which makes
+	// the API require tokens from then on
+	AdminToken string `json:"admin_token,omitempty"`
+}
+
+// SetupResult is the outcome of completing setup
+type SetupResult struct {
+	Config config.Config `json:"config"`
+	// Token is the secret of the created admin token. It is only shown once.
+	Token string `json:"token,omitempty"`
+}
+
+// Monitor states reported in StatsSummary
+const (
+	MonitorRunning = "running"
+	MonitorPaused  = "paused"
+	MonitorStopped = "stopped"
+)
+
+// StatsSummary is a small status for tray apps and widgets to poll
+type StatsSummary struct {
+	DetectionsToday int    `json:"detections_today"`        // Since local midnight
+	LeaksToday      int    `json:"leaks_today"`             // Detections in model responses since local midnight
+	LastEventAt     string `json:"last_event_at,omitempty"` // RFC 3339
+	Monitor         string `json:"monitor"`                 // running, paused or stopped
+	Health          string `json:"health,omitempty"`        // ok, backoff, stalled or stopped
+	Profile         string `json:"profile"`                 // Active detection profile
+	Lockdown        bool   `json:"lockdown"`                // Every detector blocks because the circuit breaker tripped
+}
+
+// AppStats are the detections in text from an application
+type AppStats struct {
+	App        string         `json:"app"`        // Process name, empty for unknown applications
+	Detections int            `json:"detections"` // Across every type
+	Types      map[string]int `json:"types"`      // Detections per type
+}
@@ -0,0 +1,111 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/happytaoer/prompt-security/internal/db"
+)
+
+// Role is an access level for the web API
+type Role string
+
+// Role constants, ordered from least to most privileged
+const (
+	RoleViewer   Role = "viewer"   // Read logs and stats
+	RoleOperator Role = "operator" // Viewer plus pause/resume of monitoring
+	RoleAdmin    Role = "admin"    // Full access including configuration
+)
+
+// roleLevels ranks roles so that higher roles include lower ones
+var roleLevels = map[Role]int{
+	RoleViewer:   1,
+	RoleOperator: 2,
+	RoleAdmin:    3,
+}
+
+// ParseRole validates a role name
+func ParseRole(name string) (Role, error) {
+	role := Role(strings.ToLower(strings.TrimSpace(name)))
+	if _, ok := roleLevels[role]; !ok {
+		return "", fmt.Errorf("unknown role %q (expected viewer, operator or admin)", name)
+	}
+	return role, nil
+}
+
+// Allows reports whether r grants at least the required role
+func (r Role) Allows(required Role) bool {
+	return roleLevels[r] >= roleLevels[required]
+}
+
+// identity describes the caller of an API request
+type identity struct {
+	Name string
+	Role Role
+}
+
+type identityKey struct{}
+
+// requireRole wraps a handler so that it only runs for callers holding at
+// least the required role. When no API tokens are configured the API is open
+// and every caller is treated as admin.
+func (s *Server) requireRole(required Role, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := s.authenticate(r)
+		if errors.Is(err, db.ErrStorage) {
+			writeError(w, http.StatusServiceUnavailable, ErrCodeStorage, "Authentication unavailable", nil)
+			return
+		}
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="prompt-security"`)
+			writeError(w, http.StatusUnauthorized, ErrCodeUnauthorized, err.Error(), nil)
+			return
+		}
+
+		if !id.Role.Allows(required) {
+			writeError(w, http.StatusForbidden, ErrCodeForbidden, messagef(w, "Role %s is not allowed to perform this action", id.Role),
+				map[string]Role{"role": id.Role, "required": required})
+			return
+		}
+
+		next(w, r.WithContext(context.WithValue(r.Context(), identityKey{}, id)))
+	}
+}
+
+// authenticate resolves the caller's identity from the Authorization header.
+// Failures to read the tokens are returned as is, matching db.ErrStorage.
+func (s *Server) authenticate(r *http.Request) (identity, error) {
+	count, err := db.CountAPITokens()
+	if err != nil {
+		s.logger.Error("Failed to count API tokens", "error", err)
+		return identity{}, err
+	}
+	if count == 0 {
+		return identity{Name: remoteHost(r), Role: RoleAdmin}, nil
+	}
+
+	header := r.Header.Get("Authorization")
+	secret, ok := strings.CutPrefix(header, "Bearer ")
+	if !ok || secret == "" {
+		return identity{}, fmt.Errorf("Missing bearer token")
+	}
+
+	token, found, err := db.LookupAPIToken(secret)
+	if err != nil {
+		s.logger.Error("Failed to look up API token", "error", err)
+		return identity{}, err
+	}
+	if !found {
+		return identity{}, fmt.Errorf("Invalid bearer token")
+	}
+
+	role, err := ParseRole(token.Role)
+	if err != nil {
+		return identity{}, err
+	}
+
+	return identity{Name: token.Name, Role: role}, nil
+}
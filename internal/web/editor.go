@@ -0,0 +1,95 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+	"github.com/happytaoer/prompt-security/internal/editor"
+)
+
+// maxEditorDocuments bounds the documents whose last scan is kept for
+// incremental scanning
+const maxEditorDocuments = 100
+
+// documentKey returns the key a document is cached under, scoped to the
+// caller and its editor session so that no client reads the documents of
+// another
+func documentKey(r *http.Request, session, uri string) string {
+	id, _ := r.Context().Value(identityKey{}).(identity)
+	return id.Name + "\x00" + session + "\x00" + uri
+}
+
+// handleEditorScan finds the sensitive data in a document, scanning only
+// the lines around the changes since its last scan when it is cached.
+// Nothing is logged.
+func (s *Server) handleEditorScan(w http.ResponseWriter, r *http.Request) {
+	var req EditorScanRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "Invalid JSON body", err.Error())
+		return
+	}
+	if req.URI == "" {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "uri is required", nil)
+		return
+	}
+
+	var doc *editor.Document
+	if req.Text != nil {
+		doc = editor.Scan(s.engine, req.Version, *req.Text)
+	} else {
+		cached := s.documents.Get(documentKey(r, req.Session, req.URI))
+		if cached == nil || cached.Version != req.BaseVersion {
+			details := map[string]interface{}{"cached_version": nil}
+			if cached != nil {
+				details["cached_version"] = cached.Version
+			}
+			writeError(w, http.StatusConflict, ErrCodeConflict, "The document is not cached at base_version; send its full text", details)
+			return
+		}
+		var err error
+		if doc, err = cached.Apply(s.engine, req.Version, req.Changes); err != nil {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "Invalid changes", err.Error())
+			return
+		}
+	}
+	s.documents.Put(documentKey(r, req.Session, req.URI), doc)
+
+	response := EditorScanResponse{URI: req.URI, Version: doc.Version, Findings: []EditorFinding{}}
+	for _, f := range doc.Findings() {
+		if req.Selection == nil || f.Range.Overlaps(*req.Selection) {
+			response.Findings = append(response.Findings, EditorFinding(f))
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleEditorRedact redacts a selection or a whole document, the given
+// text or the version the caller last scanned in the same session. Nothing
+// is logged.
+func (s *Server) handleEditorRedact(w http.ResponseWriter, r *http.Request) {
+	var req EditorRedactRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "Invalid JSON body", err.Error())
+		return
+	}
+
+	var doc *editor.Document
+	if req.Text != nil {
+		doc = editor.Scan(s.engine, 0, *req.Text)
+	} else if doc = s.documents.Get(documentKey(r, req.Session, req.URI)); doc == nil {
+		writeError(w, http.StatusNotFound, ErrCodeNotFound, "The document has not been scanned; send its text", map[string]string{"uri": req.URI})
+		return
+	}
+
+	text, edits := doc.Redact(req.Selection)
+	response := EditorRedactResponse{Version: doc.Version, Text: text, Edits: edits}
+	for _, f := range doc.Findings() {
+		if f.Action == config.ActionBlock && (req.Selection == nil || f.Range.Overlaps(*req.Selection)) {
+			response.Blocked = true
+		}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
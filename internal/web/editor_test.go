@@ -0,0 +1,98 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+	"github.com/happytaoer/prompt-security/internal/filter"
+)
+
+// postJSON calls handler with body and decodes the response into out
+func postJSON(t *testing.T, handler http.HandlerFunc, body string, out interface{}) int {
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodPost, "/", strings.NewReader(body)))
+	if out != nil {
+		if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+			t.Fatalf("Invalid response %q: %v", w.Body.String(), err)
+		}
+	}
+	return w.Code
+}
+
+// TestEditorScan tests full and incremental scans and redaction of the
+// scanned document
+func TestEditorScan(t *testing.T) {
+	cfg := config.Config{DetectEmails: true, EmailReplacement: "[EMAIL]", DetectSSNs: true, SSNReplacement: "XXX-XX-XXXX", SSNAction: config.ActionBlock}
+	s := NewServer(config.NewStaticManager(cfg), filter.NewEngine(cfg))
+
+	var scan EditorScanResponse
+	if code := postJSON(t, s.handleEditorScan, `{"uri":"file:///a","version":1,"text":"ann@example.com\nhello"}`, &scan); code != http.StatusOK || len(scan.Findings) != 1 {
+		t.Fatalf("Expected one finding, got %d: %+v", code, scan)
+	}
+
+	change := `{"uri":"file:///a","version":2,"base_version":1,"changes":[{"range":{"start":{"line":1,"character":5},"end":{"line":1,"character":5}},"text":" 123-45-6789"}]}`
+	if code := postJSON(t, s.handleEditorScan, change, &scan); code != http.StatusOK || scan.Version != 2 || len(scan.Findings) != 2 {
+		t.Fatalf("Expected two findings after the change, got %d: %+v", code, scan)
+	}
+	if scan.Findings[1].Type != "ssn" || scan.Findings[1].Range.Start.Line != 1 || scan.Findings[1].Range.Start.Character != 6 {
+		t.Errorf("Expected the SSN on the second line, got %+v", scan.Findings[1])
+	}
+	var apiErr APIError
+	if code := postJSON(t, s.handleEditorScan, change, &apiErr); code != http.StatusConflict || apiErr.Code != ErrCodeConflict {
+		t.Errorf("Expected changes to an outdated version to conflict, got %d: %+v", code, apiErr)
+	}
+
+	selection := `{"uri":"file:///a","version":2,"base_version":2,"selection":{"start":{"line":0,"character":0},"end":{"line":0,"character":3}}}`
+	if code := postJSON(t, s.handleEditorScan, selection, &scan); code != http.StatusOK || len(scan.Findings) != 1 || scan.Findings[0].Type != "email" {
+		t.Errorf("Expected only the email in the selection, got %d: %+v", code, scan)
+	}
+
+	var redact EditorRedactResponse
+	if code := postJSON(t, s.handleEditorRedact, `{"uri":"file:///a"}`, &redact); code != http.StatusOK || redact.Text != "[EMAIL]\nhello XXX-XX-XXXX" || len(redact.Edits) != 2 || !redact.Blocked {
+		t.Errorf("Expected the cached document to be redacted, got %d: %+v", code, redact)
+	}
+	if code := postJSON(t, s.handleEditorRedact, `{"text":"hi bob@example.com"}`, &redact); code != http.StatusOK || redact.Text != "hi [EMAIL]" || redact.Blocked {
+		t.Errorf("Expected the text to be redacted, got %d: %+v", code, redact)
+	}
+	if code := postJSON(t, s.handleEditorRedact, `{"uri":"file:///b"}`, nil); code != http.StatusNotFound {
+		t.Errorf("Expected unknown documents not to be found, got %d", code)
+	}
+}
+
+// TestEditorDocuments_PerCaller tests that cached documents are only
+// available to the caller and editor session that scanned them
+func TestEditorDocuments_PerCaller(t *testing.T) {
+	cfg := config.Config{DetectEmails: true, EmailReplacement: "[EMAIL]"}
+	s := NewServer(config.NewStaticManager(cfg), filter.NewEngine(cfg))
+	as := func(name string, handler http.HandlerFunc) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			handler(w, r.WithContext(context.WithValue(r.Context(), identityKey{}, identity{Name: name, Role: RoleViewer})))
+		}
+	}
+
+	if code := postJSON(t, as("alice", s.handleEditorScan), `{"uri":"file:///a","session":"s1","version":1,"text":"ann@example.com"}`, nil); code != http.StatusOK {
+		t.Fatalf("Expected the document to be scanned, got %d", code)
+	}
+	for _, c := range []struct {
+		name, session string
+		want          int
+	}{
+		{"alice", "s1", http.StatusOK},
+		{"alice", "s2", http.StatusNotFound},
+		{"bob", "s1", http.StatusNotFound},
+	} {
+		body := `{"uri":"file:///a","session":"` + c.session + `"}`
+		if code := postJSON(t, as(c.name, s.handleEditorRedact), body, nil); code != c.want {
+			t.Errorf("Expected %d for %s in session %s, got %d", c.want, c.name, c.session, code)
+		}
+	}
+	change := `{"uri":"file:///a","session":"s1","version":2,"base_version":1,"changes":[]}`
+	if code := postJSON(t, as("bob", s.handleEditorScan), change, nil); code != http.StatusConflict {
+		t.Errorf("Expected changes to another caller's document to conflict, got %d", code)
+	}
+}
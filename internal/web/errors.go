@@ -0,0 +1,114 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+	"github.com/happytaoer/prompt-security/internal/db"
+	"github.com/happytaoer/prompt-security/internal/i18n"
+	"github.com/happytaoer/prompt-security/internal/monitor"
+)
+
+// Error codes used in APIError.Code
+const (
+	ErrCodeInvalidRequest   = "invalid_request"
+	ErrCodeValidation       = "validation_failed"
+	ErrCodeUnauthorized     = "unauthorized"
+	ErrCodeForbidden        = "forbidden"
+	ErrCodeNotFound         = "not_found"
+	ErrCodeConflict         = "conflict"
+	ErrCodeMethodNotAllowed = "method_not_allowed"
+	ErrCodeUnavailable      = "unavailable"
+	ErrCodeStorage          = "storage_error"
+	ErrCodeInternal         = "internal_error"
+)
+
+// APIError is the JSON error envelope returned by every API endpoint
+type APIError struct {
+	Code    string      `json:"code"`
+	Message string      `json:"message"`
+	Details interface{} `json:"details,omitempty"`
+}
+
+// writeError writes an APIError with the given HTTP status, its message
+// translated into the language of the response
+func writeError(w http.ResponseWriter, status int, code, message string, details interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(APIError{
+		Code:    code,
+		Message: i18n.Message(responseLanguage(w), message),
+		Details: details,
+	})
+}
+
+// messagef formats an API message from its format translated into the
+// language of the response
+func messagef(w http.ResponseWriter, format string, args ...interface{}) string {
+	return fmt.Sprintf(i18n.Message(responseLanguage(w), format), args...)
+}
+
+// writeValidationError writes a 422 response listing the invalid fields if
+// err is a *config.ValidationError and reports whether it did so
+func writeValidationError(w http.ResponseWriter, err error) bool {
+	var validationErr *config.ValidationError
+	if !errors.As(err, &validationErr) {
+		return false
+	}
+	writeError(w, http.StatusUnprocessableEntity, ErrCodeValidation, "Validation failed", validationErr.Fields)
+	return true
+}
+
+// notFoundErrors are the errors of operations on records that do not exist
+var notFoundErrors = []error{
+	config.ErrConfigNotFound,
+	config.ErrPatternNotFound,
+	config.ErrDomainPolicyNotFound,
+	db.ErrLogNotFound,
+	db.ErrReviewItemNotFound,
+	monitor.ErrHoldNotFound,
+}
+
+// errorStatus returns the HTTP status and error code of a failed operation
+func errorStatus(err error) (int, string) {
+	for _, target := range notFoundErrors {
+		if errors.Is(err, target) {
+			return http.StatusNotFound, ErrCodeNotFound
+		}
+	}
+	switch {
+	case errors.Is(err, config.ErrInvalidPattern):
+		return http.StatusBadRequest, ErrCodeInvalidRequest
+	case errors.Is(err, db.ErrStorage):
+		return http.StatusServiceUnavailable, ErrCodeStorage
+	}
+	return http.StatusInternalServerError, ErrCodeInternal
+}
+
+// writeFailure writes the response to an operation that failed with err:
+// 422 listing the invalid fields of a *config.ValidationError, 404 with the
+// message of err for a missing record, 400 for an invalid pattern, 503 for a
+// storage failure and 500 otherwise. Storage and other unexpected failures
+// are logged and answered with message, so that their details stay in the
+// server log.
+func (s *Server) writeFailure(w http.ResponseWriter, err error, message string) {
+	if writeValidationError(w, err) {
+		return
+	}
+	status, code := errorStatus(err)
+	if status == http.StatusNotFound || status == http.StatusBadRequest {
+		writeError(w, status, code, err.Error(), nil)
+		return
+	}
+	s.logger.Error(message, "error", err)
+	writeError(w, status, code, message, nil)
+}
+
+// handleAPINotFound answers requests for unknown API paths
+func handleAPINotFound(w http.ResponseWriter, r *http.Request) {
+	writeError(w, http.StatusNotFound, ErrCodeNotFound, "Unknown API endpoint", map[string]string{"path": r.URL.Path})
+}
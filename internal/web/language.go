@@ -0,0 +1,50 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/happytaoer/prompt-security/internal/i18n"
+)
+
+// Labels are the display names of the built-in detection types
+type Labels struct {
+	Language string            `json:"language"`
+	Types    map[string]string `json:"types"` // Detection type -> display name
+}
+
+// languageMiddleware picks the language of API responses, on the versioned
+// and the deprecated unversioned paths, the configured one or else the one
+// the Accept-Language header prefers, and announces it in the
+// Content-Language header that writeError translates into
+func (s *Server) languageMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, legacyPath(apiPrefix)+"/") {
+			lang := s.configManager.Snapshot().Config.Language
+			if lang == "" {
+				lang = i18n.Negotiate(r.Header.Get("Accept-Language"))
+				w.Header().Add("Vary", "Accept-Language")
+			}
+			w.Header().Set("Content-Language", lang)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// responseLanguage returns the language picked for the response written to
+// w, English outside languageMiddleware
+func responseLanguage(w http.ResponseWriter) string {
+	if lang := w.Header().Get("Content-Language"); lang != "" {
+		return lang
+	}
+	return i18n.English
+}
+
+// handleLabels answers the display names of the detection types in the
+// language of the response
+func (s *Server) handleLabels(w http.ResponseWriter, r *http.Request) {
+	lang := responseLanguage(w)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(Labels{Language: lang, Types: i18n.Labels(lang)})
+}
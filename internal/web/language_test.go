@@ -0,0 +1,76 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+	"github.com/happytaoer/prompt-security/internal/filter"
+)
+
+// requestLocalized makes a request with an Accept-Language header to a
+// server configured with language
+func requestLocalized(t *testing.T, language, method, path, acceptLanguage string) *httptest.ResponseRecorder {
+	t.Helper()
+	cfg := config.Config{Language: language}
+	s := NewServer(config.NewStaticManager(cfg), filter.NewEngine(cfg))
+	mux := http.NewServeMux()
+	mux.HandleFunc(apiPrefix+"/labels", s.handleLabels)
+	mux.HandleFunc(apiPrefix+"/", handleAPINotFound)
+	mux.HandleFunc(legacyPath(apiPrefix+"/config"), deprecated(s.handleConfig))
+
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("Accept-Language", acceptLanguage)
+	rec := httptest.NewRecorder()
+	s.languageMiddleware(mux).ServeHTTP(rec, req)
+	return rec
+}
+
+// TestLabels tests answering the detection type names in the language of
+// the request
+func TestLabels(t *testing.T) {
+	var labels Labels
+	rec := requestLocalized(t, "", http.MethodGet, apiPrefix+"/labels", "ja-JP,ja;q=0.9,en;q=0.8")
+	if err := json.NewDecoder(rec.Body).Decode(&labels); err != nil {
+		t.Fatal(err)
+	}
+	if labels.Language != "ja" || labels.Types["email"] != "メールアドレス" || rec.Header().Get("Content-Language") != "ja" {
+		t.Errorf("Expected Japanese labels, got %+v", labels)
+	}
+
+	// The configured language wins over the header
+	rec = requestLocalized(t, "de", http.MethodGet, apiPrefix+"/labels", "ja")
+	if err := json.NewDecoder(rec.Body).Decode(&labels); err != nil {
+		t.Fatal(err)
+	}
+	if labels.Language != "de" || labels.Types["api_key"] != "API-Schlüssel" || rec.Header().Get("Vary") != "" {
+		t.Errorf("Expected German labels, got %+v", labels)
+	}
+}
+
+// TestLocalizedErrors tests translating error messages, leaving the codes,
+// also on the deprecated unversioned paths
+func TestLocalizedErrors(t *testing.T) {
+	tests := []struct {
+		method, path   string
+		acceptLanguage string
+		code, want     string
+	}{
+		{http.MethodGet, apiPrefix + "/nowhere", "zh-CN", ErrCodeNotFound, "未知的 API 端点"},
+		{http.MethodGet, apiPrefix + "/nowhere", "fr, de;q=0.5", ErrCodeNotFound, "Unbekannter API-Endpunkt"},
+		{http.MethodGet, apiPrefix + "/nowhere", "fr", ErrCodeNotFound, "Unknown API endpoint"},
+		{http.MethodDelete, "/api/config", "de", ErrCodeMethodNotAllowed, "Methode nicht erlaubt"},
+	}
+	for _, tt := range tests {
+		var apiErr APIError
+		rec := requestLocalized(t, "", tt.method, tt.path, tt.acceptLanguage)
+		if err := json.NewDecoder(rec.Body).Decode(&apiErr); err != nil {
+			t.Fatal(err)
+		}
+		if apiErr.Code != tt.code || apiErr.Message != tt.want {
+			t.Errorf("%s %s, Accept-Language %q: expected %q, got %+v", tt.method, tt.path, tt.acceptLanguage, tt.want, apiErr)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package web
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"runtime/debug"
+	"time"
+)
+
+// requestIDHeader is the response header carrying the correlation ID of a
+// request, which its log entries share
+const requestIDHeader = "X-Request-ID"
+
+// requestMiddleware logs every request with its method, path, status and
+// duration, and turns a handler panic into a 500 response whose details hold
+// the correlation ID under which the panic and its stack are logged
+func (s *Server) requestMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		id := newRequestID()
+		w.Header().Set(requestIDHeader, id)
+		rw := &responseWriter{ResponseWriter: w, status: http.StatusOK}
+
+		defer func() {
+			if v := recover(); v != nil {
+				if v == http.ErrAbortHandler {
+					panic(v) // Aborts the response on purpose
+				}
+				s.logger.Error("Handler panicked", "request_id", id, "method", r.Method, "path", r.URL.Path,
+					"panic", fmt.Sprint(v), "stack", string(debug.Stack()))
+				if rw.wroteHeader {
+					rw.status = http.StatusInternalServerError // Too late to answer, but log it
+				} else {
+					writeError(rw, http.StatusInternalServerError, ErrCodeInternal, "Internal server error", map[string]string{"request_id": id})
+				}
+			}
+			s.logger.Info("HTTP request", "request_id", id, "method", r.Method, "path", r.URL.Path,
+				"status", rw.status, "duration", time.Since(start))
+		}()
+
+		next.ServeHTTP(rw, r)
+	})
+}
+
+// newRequestID returns a random correlation ID
+func newRequestID() string {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return fmt.Sprintf("%x", time.Now().UnixNano())
+	}
+	return hex.EncodeToString(buf)
+}
+
+// responseWriter remembers the status of a response
+type responseWriter struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+}
+
+func (w *responseWriter) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *responseWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the underlying writer, for http.ResponseController
+func (w *responseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
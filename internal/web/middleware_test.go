@@ -0,0 +1,64 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestRequestMiddleware tests that requests are logged and that a panic is
+// answered with a 500 carrying the correlation ID it is logged under
+func TestRequestMiddleware(t *testing.T) {
+	var logs bytes.Buffer
+	s := &Server{logger: slog.New(slog.NewJSONHandler(&logs, nil))}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/v1/panic", func(w http.ResponseWriter, r *http.Request) {
+		panic("boom")
+	})
+	mux.HandleFunc("/api/v1/", handleAPINotFound)
+	handler := s.requestMiddleware(mux)
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/v1/nothing", nil))
+	if rec.Code != http.StatusNotFound || rec.Header().Get(requestIDHeader) == "" {
+		t.Errorf("Expected a 404 with a request ID, got %d %v", rec.Code, rec.Header())
+	}
+	var entry map[string]interface{}
+	if err := json.Unmarshal(logs.Bytes(), &entry); err != nil {
+		t.Fatalf("Expected one log entry, got %q", logs.String())
+	}
+	if entry["method"] != "GET" || entry["path"] != "/api/v1/nothing" || entry["status"] != float64(http.StatusNotFound) ||
+		entry["request_id"] != rec.Header().Get(requestIDHeader) || entry["duration"] == nil {
+		t.Errorf("Unexpected log entry %v", entry)
+	}
+
+	logs.Reset()
+	rec = httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/v1/panic", nil))
+	var apiErr struct {
+		Code    string            `json:"code"`
+		Details map[string]string `json:"details"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&apiErr); err != nil {
+		t.Fatal(err)
+	}
+	id := rec.Header().Get(requestIDHeader)
+	if rec.Code != http.StatusInternalServerError || apiErr.Code != ErrCodeInternal || id == "" || apiErr.Details["request_id"] != id {
+		t.Errorf("Expected a 500 with the request ID %q, got %d %+v", id, rec.Code, apiErr)
+	}
+
+	lines := strings.Split(strings.TrimSpace(logs.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected the panic and the request to be logged, got %q", logs.String())
+	}
+	if !strings.Contains(lines[0], `"panic":"boom"`) || !strings.Contains(lines[0], id) || !strings.Contains(lines[0], "middleware_test.go") {
+		t.Errorf("Expected the panic with its stack, got %s", lines[0])
+	}
+	if !strings.Contains(lines[1], `"status":500`) || !strings.Contains(lines[1], id) {
+		t.Errorf("Expected the request logged with status 500, got %s", lines[1])
+	}
+}
@@ -0,0 +1,253 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	openAPIOnce sync.Once
+	openAPIDoc  []byte
+	openAPIErr  error
+)
+
+// OpenAPI returns the OpenAPI 3 document describing the route registry
+func OpenAPI() ([]byte, error) {
+	openAPIOnce.Do(func() {
+		openAPIDoc, openAPIErr = json.MarshalIndent(buildOpenAPI(Operations()), "", "  ")
+	})
+	return openAPIDoc, openAPIErr
+}
+
+// handleOpenAPI serves the OpenAPI document
+func (s *Server) handleOpenAPI(w http.ResponseWriter, r *http.Request) {
+	doc, err := OpenAPI()
+	if err != nil {
+		s.writeFailure(w, err, "Failed to build OpenAPI document")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(doc)
+}
+
+// buildOpenAPI converts operations into an OpenAPI 3 document
+func buildOpenAPI(ops []Operation) map[string]interface{} {
+	schemas := newSchemaBuilder()
+	paths := make(map[string]interface{})
+
+	errorResponse := func(description string) map[string]interface{} {
+		return map[string]interface{}{
+			"description": description,
+			"content": map[string]interface{}{
+				"application/json": map[string]interface{}{
+					"schema": schemas.schemaFor(reflect.TypeOf(APIError{})),
+				},
+			},
+		}
+	}
+
+	for _, op := range ops {
+		item, ok := paths[op.Path].(map[string]interface{})
+		if !ok {
+			item = make(map[string]interface{})
+			paths[op.Path] = item
+		}
+
+		operation := map[string]interface{}{
+			"operationId": op.ID,
+			"summary":     op.Summary,
+			"x-role":      string(op.Role),
+			"responses": map[string]interface{}{
+				"200": map[string]interface{}{
+					"description": "OK",
+					"content": map[string]interface{}{
+						"application/json": map[string]interface{}{
+							"schema": schemas.schemaFor(reflect.TypeOf(op.Response)),
+						},
+					},
+				},
+				"401":     errorResponse("Missing or invalid bearer token"),
+				"403":     errorResponse("Role not allowed"),
+				"default": errorResponse("Error"),
+			},
+		}
+
+		if len(op.Params)+len(op.Query) > 0 {
+			params := make([]interface{}, 0, len(op.Params)+len(op.Query))
+			for _, p := range op.Params {
+				params = append(params, map[string]interface{}{
+					"name":        p.Name,
+					"in":          "path",
+					"required":    true,
+					"description": p.Description,
+					"schema":      map[string]interface{}{"type": p.Type},
+				})
+			}
+			for _, q := range op.Query {
+				params = append(params, map[string]interface{}{
+					"name":        q.Name,
+					"in":          "query",
+					"description": q.Description,
+					"schema":      map[string]interface{}{"type": q.Type},
+				})
+			}
+			operation["parameters"] = params
+		}
+
+		if op.Request != nil {
+			operation["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content": map[string]interface{}{
+					"application/json": map[string]interface{}{
+						"schema": schemas.schemaFor(reflect.TypeOf(op.Request)),
+					},
+				},
+			}
+		}
+
+		item[strings.ToLower(op.Method)] = operation
+	}
+
+	return map[string]interface{}{
+		"openapi": "3.0.3",
+		"info": map[string]interface{}{
+			"title":   "Prompt Security API",
+			"version": "1.0.0",
+		},
+		"paths": paths,
+		"components": map[string]interface{}{
+			"schemas": schemas.components,
+			"securitySchemes": map[string]interface{}{
+				"bearerAuth": map[string]interface{}{"type": "http", "scheme": "bearer"},
+			},
+		},
+		"security": []interface{}{map[string]interface{}{"bearerAuth": []string{}}},
+	}
+}
+
+var (
+	rawMessageType = reflect.TypeOf(json.RawMessage{})
+	timeType       = reflect.TypeOf(time.Time{})
+)
+
+// schemaBuilder derives JSON schemas from Go types, registering named
+// structs as reusable components
+type schemaBuilder struct {
+	components map[string]interface{}
+}
+
+func newSchemaBuilder() *schemaBuilder {
+	return &schemaBuilder{components: make(map[string]interface{})}
+}
+
+// schemaFor returns the schema for t, or a $ref for named struct types
+func (b *schemaBuilder) schemaFor(t reflect.Type) map[string]interface{} {
+	if t == nil {
+		return map[string]interface{}{}
+	}
+
+	switch {
+	case t == rawMessageType:
+		return map[string]interface{}{}
+	case t == timeType:
+		return map[string]interface{}{"type": "string", "format": "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Ptr:
+		return b.schemaFor(t.Elem())
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": b.schemaFor(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": b.schemaFor(t.Elem())}
+	case reflect.Struct:
+		if t.Name() == "" {
+			return b.structSchema(t)
+		}
+		if _, ok := b.components[t.Name()]; !ok {
+			b.components[t.Name()] = map[string]interface{}{} // Placeholder for recursive types
+			b.components[t.Name()] = b.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/components/schemas/" + t.Name()}
+	default:
+		return map[string]interface{}{}
+	}
+}
+
+// structSchema builds an object schema from a struct's JSON fields
+func (b *schemaBuilder) structSchema(t reflect.Type) map[string]interface{} {
+	properties := make(map[string]interface{})
+	required := make([]string, 0)
+
+	for _, field := range JSONFields(t) {
+		properties[field.Name] = b.schemaFor(field.Type)
+		if !field.OmitEmpty {
+			required = append(required, field.Name)
+		}
+	}
+
+	schema := map[string]interface{}{
+		"type":       "object",
+		"properties": properties,
+	}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+// JSONField is a struct field as seen by encoding/json
+type JSONField struct {
+	GoName    string
+	Name      string
+	Type      reflect.Type
+	OmitEmpty bool
+}
+
+// JSONFields lists the exported fields of struct type t under their JSON
+// names, flattening embedded structs the way encoding/json does
+func JSONFields(t reflect.Type) []JSONField {
+	var fields []JSONField
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+
+		if f.Anonymous && name == "" && f.Type.Kind() == reflect.Struct {
+			fields = append(fields, JSONFields(f.Type)...)
+			continue
+		}
+
+		if name == "" {
+			name = f.Name
+		}
+		fields = append(fields, JSONField{
+			GoName:    f.Name,
+			Name:      name,
+			Type:      f.Type,
+			OmitEmpty: strings.Contains(opts, "omitempty"),
+		})
+	}
+	return fields
+}
@@ -0,0 +1,55 @@
+package web
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// TestOpenAPI_CoversRegistry tests that every registered operation is documented
+func TestOpenAPI_CoversRegistry(t *testing.T) {
+	data, err := OpenAPI()
+	if err != nil {
+		t.Fatalf("Failed to build OpenAPI document: %v", err)
+	}
+
+	var doc struct {
+		Paths map[string]map[string]struct {
+			OperationID string `json:"operationId"`
+		} `json:"paths"`
+		Components struct {
+			Schemas map[string]json.RawMessage `json:"schemas"`
+		} `json:"components"`
+	}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		t.Fatalf("OpenAPI document is not valid JSON: %v", err)
+	}
+
+	for _, op := range Operations() {
+		item, ok := doc.Paths[op.Path][strings.ToLower(op.Method)]
+		if !ok {
+			t.Errorf("Operation %s %s missing from OpenAPI document", op.Method, op.Path)
+			continue
+		}
+		if item.OperationID != op.ID {
+			t.Errorf("Expected operationId %s, got %s", op.ID, item.OperationID)
+		}
+	}
+
+	for _, name := range []string{"Config", "StringMatchPattern", "LogsPage", "FilterResponse"} {
+		if _, ok := doc.Components.Schemas[name]; !ok {
+			t.Errorf("Expected schema component %s", name)
+		}
+	}
+}
+
+// TestOperations_UniqueIDs tests that operation IDs can be used as client method names
+func TestOperations_UniqueIDs(t *testing.T) {
+	seen := make(map[string]bool)
+	for _, op := range Operations() {
+		if seen[op.ID] {
+			t.Errorf("Duplicate operation ID %s", op.ID)
+		}
+		seen[op.ID] = true
+	}
+}
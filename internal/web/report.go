@@ -0,0 +1,68 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/happytaoer/prompt-security/internal/report"
+)
+
+// reportQuery are the query parameters of the reports endpoint
+var reportQuery = []QueryParam{
+	{Name: "period", Type: "string", Description: "day or week (default week)"},
+	{Name: "until", Type: "string", Description: "Last day of the period as YYYY-MM-DD (default today)"},
+	{Name: "format", Type: "string", Description: "json, markdown or html (default json)"},
+}
+
+// handleReports summarizes the detections and policy changes of a day or
+// week, as JSON, Markdown or an HTML page
+func (s *Server) handleReports(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	period, format := query.Get("period"), query.Get("format")
+	if period == "" {
+		period = report.PeriodWeek
+	}
+	if format == "" {
+		format = report.FormatJSON
+	}
+	if !report.ValidPeriod(period) {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "Invalid report period", map[string]string{"period": period})
+		return
+	}
+	if !report.ValidFormat(format) {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "Invalid report format", map[string]string{"format": format})
+		return
+	}
+	until := time.Now()
+	if value := query.Get("until"); value != "" {
+		var err error
+		if until, err = time.ParseInLocation("2006-01-02", value, time.Local); err != nil {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "Invalid until date, expected YYYY-MM-DD", map[string]string{"until": value})
+			return
+		}
+	}
+
+	rep, err := report.Generate(period, until)
+	if err != nil {
+		s.writeFailure(w, err, "Failed to generate report")
+		return
+	}
+
+	switch format {
+	case report.FormatMarkdown:
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		w.Write([]byte(rep.Markdown()))
+	case report.FormatHTML:
+		page, err := rep.HTML()
+		if err != nil {
+			s.writeFailure(w, err, "Failed to render report")
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Write([]byte(page))
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(rep)
+	}
+}
@@ -0,0 +1,450 @@
+package web
+
+import (
+	"context"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/happytaoer/prompt-security/internal/bench"
+	"github.com/happytaoer/prompt-security/internal/config"
+	"github.com/happytaoer/prompt-security/internal/db"
+	"github.com/happytaoer/prompt-security/internal/report"
+	"github.com/happytaoer/prompt-security/internal/telemetry"
+	"github.com/happytaoer/prompt-security/internal/usage"
+)
+
+// QueryParam describes a query string or path parameter of an operation
+type QueryParam struct {
+	Name        string
+	Type        string // OpenAPI primitive type: "integer", "string" or "boolean"
+	Description string
+}
+
+// Operation describes a single API method. The route registry is the single
+// source for dispatch, access control, the OpenAPI document and the
+// generated Go client.
+type Operation struct {
+	ID       string // operationId, also the generated client method name
+	Method   string
+	Path     string
+	Summary  string
+	Role     Role
+	Query    []QueryParam
+	Params   []QueryParam // Path parameters, written as {name} in Path
+	Request  interface{}  // Zero value of the request body type, nil if none
+	Response interface{}  // Zero value of the response body type
+}
+
+// route is an API path and the operations it supports
+type route struct {
+	Path       string
+	Handler    http.HandlerFunc
+	Operations []Operation
+}
+
+// apiPrefix is the path prefix of the current API version
+const apiPrefix = "/api/v1"
+
+var paginationParams = []QueryParam{
+	{Name: "page", Type: "integer", Description: "Page number starting at 1"},
+	{Name: "pageSize", Type: "integer", Description: "Number of entries per page"},
+}
+
+// routes returns the API route registry
+func (s *Server) routes() []route {
+	return []route{
+		{
+			Path:    apiPrefix + "/config",
+			Handler: s.handleConfig,
+			Operations: []Operation{
+				{ID: "GetConfig", Method: http.MethodGet, Summary: "Get the current configuration, including the breaker webhook, allowlist and confirmed values", Role: RoleAdmin, Response: config.Config{}},
+				{ID: "UpdateConfig", Method: http.MethodPost, Summary: "Replace the configuration", Role: RoleAdmin, Request: config.Config{}, Response: StatusResponse{}},
+			},
+		},
+		{
+			Path:    apiPrefix + "/config/validate",
+			Handler: s.handleValidateConfig,
+			Operations: []Operation{
+				{ID: "ValidateConfig", Method: http.MethodPost, Summary: "Validate a configuration without saving it", Role: RoleViewer, Request: config.Config{}, Response: ValidationResult{}},
+			},
+		},
+		{
+			Path:    apiPrefix + "/patterns",
+			Handler: s.handlePatterns,
+			Operations: []Operation{
+				{ID: "ListPatterns", Method: http.MethodGet, Summary: "List string match patterns", Role: RoleAdmin, Response: []config.StringMatchPattern{}},
+				{ID: "SavePattern", Method: http.MethodPost, Summary: "Create (id 0) or update a string match pattern", Role: RoleAdmin, Request: config.StringMatchPattern{}, Response: config.StringMatchPattern{}},
+				{ID: "DeletePattern", Method: http.MethodDelete, Summary: "Delete a string match pattern", Role: RoleAdmin, Query: []QueryParam{{Name: "id", Type: "integer", Description: "Pattern ID"}}, Response: StatusResponse{}},
+			},
+		},
+		{
+			Path:    apiPrefix + "/patterns/import",
+			Handler: s.handlePatternImport,
+			Operations: []Operation{
+				{ID: "ImportPatterns", Method: http.MethodPost, Summary: "Add the literal terms of a CSV or TSV dictionary as string match patterns, skipping duplicates, or preview them on a dry run", Role: RoleAdmin, Request: PatternImportRequest{}, Response: config.ImportResult{}},
+			},
+		},
+		{
+			Path:    apiPrefix + "/domain-policies",
+			Handler: s.handleDomainPolicies,
+			Operations: []Operation{
+				{ID: "ListDomainPolicies", Method: http.MethodGet, Summary: "List the policies for traffic by destination host in gateway and proxy modes", Role: RoleViewer, Response: []config.DomainPolicy{}},
+				{ID: "SaveDomainPolicy", Method: http.MethodPost, Summary: "Create (id 0) or update a domain policy", Role: RoleAdmin, Request: config.DomainPolicy{}, Response: config.DomainPolicy{}},
+				{ID: "DeleteDomainPolicy", Method: http.MethodDelete, Summary: "Delete a domain policy", Role: RoleAdmin, Query: []QueryParam{{Name: "id", Type: "integer", Description: "Domain policy ID"}}, Response: StatusResponse{}},
+			},
+		},
+		{
+			Path:    apiPrefix + "/filter",
+			Handler: s.handleFilter,
+			Operations: []Operation{
+				{ID: "Filter", Method: http.MethodPost, Summary: "Filter text with the current configuration", Role: RoleViewer, Request: FilterRequest{}, Response: FilterResponse{}},
+			},
+		},
+		{
+			Path:    apiPrefix + "/validate",
+			Handler: s.handleValidate,
+			Operations: []Operation{
+				{ID: "Validate", Method: http.MethodPost, Summary: "Allow or deny content against the policy, e.g. prompt templates in CI; also accepts a Kubernetes AdmissionReview", Role: RoleViewer, Request: ValidateRequest{}, Response: ValidateResponse{}},
+			},
+		},
+		{
+			Path:    apiPrefix + "/editor/scan",
+			Handler: s.handleEditorScan,
+			Operations: []Operation{
+				{ID: "EditorScan", Method: http.MethodPost, Summary: "Find sensitive data in a document open in an editor, rescanning only the lines around changes since its last scan", Role: RoleViewer, Request: EditorScanRequest{}, Response: EditorScanResponse{}},
+			},
+		},
+		{
+			Path:    apiPrefix + "/editor/redact",
+			Handler: s.handleEditorRedact,
+			Operations: []Operation{
+				{ID: "EditorRedact", Method: http.MethodPost, Summary: "Redact a selection or document open in an editor, returning the redacted text and the edits", Role: RoleViewer, Request: EditorRedactRequest{}, Response: EditorRedactResponse{}},
+			},
+		},
+		{
+			Path:    apiPrefix + "/logs",
+			Handler: s.handleLogs,
+			Operations: []Operation{
+				{ID: "ListLogs", Method: http.MethodGet, Summary: "List filter logs, newest first", Role: RoleViewer, Query: paginationParams, Response: LogsPage{}},
+			},
+		},
+		{
+			Path:    apiPrefix + "/logs/{id}",
+			Handler: s.handleLogDetail,
+			Operations: []Operation{
+				{ID: "GetLog", Method: http.MethodGet, Summary: "Get a filter log with the offsets of every replacement", Role: RoleViewer, Params: []QueryParam{{Name: "id", Type: "integer", Description: "Log ID"}}, Response: db.LogDetail{}},
+			},
+		},
+		{
+			Path:    apiPrefix + "/logs/replay",
+			Handler: s.handleReplayLogs,
+			Operations: []Operation{
+				{ID: "ReplayLogs", Method: http.MethodPost, Summary: "Re-run logged copies through the current, or a candidate, configuration", Role: RoleViewer, Request: ReplayRequest{}, Response: bench.ReplayReport{}},
+			},
+		},
+		{
+			Path:    apiPrefix + "/logs/clear",
+			Handler: s.handleClearLogs,
+			Operations: []Operation{
+				{ID: "ClearLogs", Method: http.MethodPost, Summary: "Delete all filter logs", Role: RoleAdmin, Response: StatusResponse{}},
+			},
+		},
+		{
+			Path:    apiPrefix + "/review",
+			Handler: s.handleReview,
+			Operations: []Operation{
+				{ID: "ListReview", Method: http.MethodGet, Summary: "List uncertain matches by review status, newest first", Role: RoleViewer,
+					Query: append([]QueryParam{{Name: "status", Type: "string", Description: "pending (default), confirmed or ignored"}}, paginationParams...), Response: ReviewPage{}},
+			},
+		},
+		{
+			Path:    apiPrefix + "/review/{id}/confirm",
+			Handler: s.handleReviewDecision(db.ReviewConfirmed),
+			Operations: []Operation{
+				{ID: "ConfirmReview", Method: http.MethodPost, Summary: "Confirm an uncertain match as sensitive so its value is always acted upon", Role: RoleAdmin, Params: []QueryParam{{Name: "id", Type: "integer", Description: "Review item ID"}}, Response: db.ReviewItem{}},
+			},
+		},
+		{
+			Path:    apiPrefix + "/review/{id}/ignore",
+			Handler: s.handleReviewDecision(db.ReviewIgnored),
+			Operations: []Operation{
+				{ID: "IgnoreReview", Method: http.MethodPost, Summary: "Mark an uncertain match as not sensitive and allowlist its value", Role: RoleAdmin, Params: []QueryParam{{Name: "id", Type: "integer", Description: "Review item ID"}}, Response: db.ReviewItem{}},
+			},
+		},
+		{
+			Path:    apiPrefix + "/audit",
+			Handler: s.handleAudit,
+			Operations: []Operation{
+				{ID: "ListAudit", Method: http.MethodGet, Summary: "List config audit entries, newest first", Role: RoleAdmin, Query: paginationParams, Response: AuditPage{}},
+			},
+		},
+		{
+			Path:    apiPrefix + "/monitor",
+			Handler: s.handleMonitor,
+			Operations: []Operation{
+				{ID: "GetMonitorStatus", Method: http.MethodGet, Summary: "Get clipboard monitor status", Role: RoleViewer, Response: MonitorStatus{}},
+			},
+		},
+		{
+			Path:    apiPrefix + "/monitor/status",
+			Handler: s.handleMonitorHealth,
+			Operations: []Operation{
+				{ID: "GetMonitorHealth", Method: http.MethodGet, Summary: "Get clipboard monitor health", Role: RoleViewer, Response: MonitorHealth{}},
+			},
+		},
+		{
+			Path:    apiPrefix + "/monitor/pause",
+			Handler: s.handleMonitorPause,
+			Operations: []Operation{
+				{ID: "PauseMonitor", Method: http.MethodPost, Summary: "Pause clipboard filtering", Role: RoleOperator, Response: MonitorStatus{}},
+			},
+		},
+		{
+			Path:    apiPrefix + "/monitor/resume",
+			Handler: s.handleMonitorResume,
+			Operations: []Operation{
+				{ID: "ResumeMonitor", Method: http.MethodPost, Summary: "Resume clipboard filtering", Role: RoleOperator, Response: MonitorStatus{}},
+			},
+		},
+		{
+			Path:    apiPrefix + "/monitor/holds",
+			Handler: s.handleHolds,
+			Operations: []Operation{
+				{ID: "ListHolds", Method: http.MethodGet, Summary: "List redacted copies whose original may be restored", Role: RoleViewer, Response: []HeldCopy{}},
+			},
+		},
+		{
+			Path:    apiPrefix + "/monitor/holds/{id}/restore",
+			Handler: s.handleRestoreHold,
+			Operations: []Operation{
+				{ID: "RestoreHold", Method: http.MethodPost, Summary: "Put the original of a redacted copy back on the clipboard", Role: RoleOperator, Params: []QueryParam{{Name: "id", Type: "string", Description: "Hold ID"}}, Response: StatusResponse{}},
+			},
+		},
+		{
+			Path:    apiPrefix + "/keyboard",
+			Handler: s.handleKeyboard,
+			Operations: []Operation{
+				{ID: "GetKeyboardStatus", Method: http.MethodGet, Summary: "Get keyboard protection status", Role: RoleViewer, Response: KeyboardStatus{}},
+			},
+		},
+		{
+			Path:    apiPrefix + "/breaker",
+			Handler: s.handleBreaker,
+			Operations: []Operation{
+				{ID: "GetBreakerStatus", Method: http.MethodGet, Summary: "Get whether the circuit breaker tripped and the high severity detections counted in its window", Role: RoleViewer, Response: BreakerStatus{}},
+			},
+		},
+		{
+			Path:    apiPrefix + "/breaker/reset",
+			Handler: s.handleBreakerReset,
+			Operations: []Operation{
+				{ID: "ResetBreaker", Method: http.MethodPost, Summary: "Close a tripped circuit breaker, restoring the configured detector actions", Role: RoleAdmin, Response: BreakerStatus{}},
+			},
+		},
+		{
+			Path:    apiPrefix + "/usage",
+			Handler: s.handleUsage,
+			Operations: []Operation{
+				{ID: "GetUsageReport", Method: http.MethodGet, Summary: "Preview the anonymous usage report sent next: detection counts per type for finished days", Role: RoleViewer, Response: usage.Report{}},
+			},
+		},
+		{
+			Path:    apiPrefix + "/stats/summary",
+			Handler: s.handleStatsSummary,
+			Operations: []Operation{
+				{ID: "GetStatsSummary", Method: http.MethodGet, Summary: "Get today's detection count, the last event time and the monitor state, cheap enough to poll", Role: RoleViewer, Response: StatsSummary{}},
+			},
+		},
+		{
+			Path:    apiPrefix + "/stats/apps",
+			Handler: s.handleStatsApps,
+			Operations: []Operation{
+				{ID: "GetAppStats", Method: http.MethodGet, Summary: "Get the detections in prompts per source application, most first", Role: RoleViewer, Response: []AppStats{}},
+			},
+		},
+		{
+			Path:    apiPrefix + "/labels",
+			Handler: s.handleLabels,
+			Operations: []Operation{
+				{ID: "GetLabels", Method: http.MethodGet, Summary: "Get the display names of the detection types in the language of the request", Role: RoleViewer, Response: Labels{}},
+			},
+		},
+		{
+			Path:    apiPrefix + "/reports",
+			Handler: s.handleReports,
+			Operations: []Operation{
+				{ID: "GetReport", Method: http.MethodGet, Summary: "Summarize the detections, top types and applications, trend and policy changes of a day or week", Role: RoleViewer, Query: reportQuery, Response: report.Summary{}},
+			},
+		},
+		{
+			Path:    apiPrefix + "/setup",
+			Handler: s.handleSetup,
+			Operations: []Operation{
+				{ID: "GetSetupStatus", Method: http.MethodGet, Summary: "Get whether first-run setup is needed and the choices it offers", Role: RoleViewer, Response: SetupStatus{}},
+				{ID: "CompleteSetup", Method: http.MethodPost, Summary: "Choose the detectors and replacement strategy, optionally create an admin token, and mark setup complete", Role: RoleAdmin, Request: SetupRequest{}, Response: SetupResult{}},
+			},
+		},
+		{
+			Path:    apiPrefix + "/openapi.json",
+			Handler: s.handleOpenAPI,
+			Operations: []Operation{
+				{ID: "GetOpenAPI", Method: http.MethodGet, Summary: "Get the OpenAPI document for this API", Role: RoleViewer, Response: map[string]interface{}{}},
+			},
+		},
+	}
+}
+
+// Operations returns every API operation in the route registry
+func Operations() []Operation {
+	var ops []Operation
+	for _, rt := range (&Server{}).routes() {
+		for _, op := range rt.Operations {
+			op.Path = rt.Path
+			ops = append(ops, op)
+		}
+	}
+	return ops
+}
+
+// legacyPath maps a versioned API path to its deprecated unversioned form
+func legacyPath(path string) string {
+	return "/api" + strings.TrimPrefix(path, apiPrefix)
+}
+
+// legacyRequest reports whether r was made on a legacy unversioned path
+func legacyRequest(r *http.Request) bool {
+	return !strings.HasPrefix(r.URL.Path, apiPrefix+"/")
+}
+
+// deprecated marks responses from legacy unversioned paths
+func deprecated(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Deprecation", "true")
+		w.Header().Set("Link", "<"+apiPrefix+strings.TrimPrefix(r.URL.Path, "/api")+`>; rel="successor-version"`)
+		next(w, r)
+	}
+}
+
+// muxPattern returns the ServeMux pattern for a route path. Paths with
+// parameters are registered as the subtree before the first parameter.
+func muxPattern(path string) string {
+	if i := strings.Index(path, "{"); i >= 0 {
+		return path[:i]
+	}
+	return path
+}
+
+// matchPath matches a request path against a route path, returning the
+// values of its {name} parameters
+func matchPath(template, path string) (map[string]string, bool) {
+	want := strings.Split(template, "/")
+	got := strings.Split(path, "/")
+	if len(want) != len(got) {
+		return nil, false
+	}
+
+	params := make(map[string]string)
+	for i, segment := range want {
+		if strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}") {
+			if got[i] == "" {
+				return nil, false
+			}
+			params[segment[1:len(segment)-1]] = got[i]
+		} else if segment != got[i] {
+			return nil, false
+		}
+	}
+	return params, true
+}
+
+type pathParamsKey struct{}
+
+// pathParam returns the value of a route path parameter
+func pathParam(r *http.Request, name string) string {
+	params, _ := r.Context().Value(pathParamsKey{}).(map[string]string)
+	return params[name]
+}
+
+// handleRoute dispatches a request to the route's handler after checking
+// that the method is supported and the caller holds the operation's role
+func (s *Server) handleRoute(rt route) http.HandlerFunc {
+	byMethod := make(map[string]Operation, len(rt.Operations))
+	allowed := make([]string, 0, len(rt.Operations))
+	for _, op := range rt.Operations {
+		byMethod[op.Method] = op
+		allowed = append(allowed, op.Method)
+	}
+	sort.Strings(allowed)
+
+	parameterized := strings.Contains(rt.Path, "{")
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		if parameterized {
+			template := rt.Path
+			if !strings.HasPrefix(r.URL.Path, apiPrefix+"/") {
+				template = legacyPath(rt.Path)
+			}
+			params, ok := matchPath(template, r.URL.Path)
+			if !ok {
+				handleAPINotFound(w, r)
+				return
+			}
+			r = r.WithContext(context.WithValue(r.Context(), pathParamsKey{}, params))
+		}
+
+		op, ok := byMethod[r.Method]
+		if !ok {
+			w.Header().Set("Allow", strings.Join(allowed, ", "))
+			writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Method not allowed", map[string][]string{"allowed": allowed})
+			return
+		}
+
+		if s.readOnly && op.Method != http.MethodGet && op.Role != RoleViewer {
+			writeError(w, http.StatusForbidden, ErrCodeForbidden, "The server is read-only", map[string]Role{"required": op.Role})
+			return
+		}
+
+		s.requireRole(op.Role, rt.Handler)(w, r)
+	}
+}
+
+// routeHandlers returns the handler for each ServeMux pattern. Routes whose
+// paths share the subtree before their first parameter, such as
+// /review/{id}/confirm and /review/{id}/ignore, share a pattern and are told
+// apart by their full path.
+func (s *Server) routeHandlers() map[string]http.HandlerFunc {
+	byPattern := make(map[string][]route)
+	for _, rt := range s.routes() {
+		pattern := muxPattern(rt.Path)
+		byPattern[pattern] = append(byPattern[pattern], rt)
+	}
+
+	handlers := make(map[string]http.HandlerFunc, len(byPattern))
+	for pattern, rts := range byPattern {
+		if len(rts) == 1 {
+			handlers[pattern] = telemetry.HTTPHandler(rts[0].Path, s.handleRoute(rts[0]))
+			continue
+		}
+
+		rts := rts
+		routeHandlers := make([]http.HandlerFunc, len(rts))
+		for i, rt := range rts {
+			routeHandlers[i] = telemetry.HTTPHandler(rt.Path, s.handleRoute(rt))
+		}
+		handlers[pattern] = func(w http.ResponseWriter, r *http.Request) {
+			for i, rt := range rts {
+				template := rt.Path
+				if !strings.HasPrefix(r.URL.Path, apiPrefix+"/") {
+					template = legacyPath(rt.Path)
+				}
+				if _, ok := matchPath(template, r.URL.Path); ok {
+					routeHandlers[i](w, r)
+					return
+				}
+			}
+			handleAPINotFound(w, r)
+		}
+	}
+	return handlers
+}
@@ -0,0 +1,87 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// TestMatchPath tests route path parameter matching
+func TestMatchPath(t *testing.T) {
+	tests := []struct {
+		template string
+		path     string
+		ok       bool
+		id       string
+	}{
+		{"/api/v1/logs/{id}", "/api/v1/logs/42", true, "42"},
+		{"/api/v1/logs/{id}", "/api/v1/logs/", false, ""},
+		{"/api/v1/logs/{id}", "/api/v1/logs/42/extra", false, ""},
+		{"/api/v1/logs/{id}", "/api/v1/audit/42", false, ""},
+		{"/api/v1/logs", "/api/v1/logs", true, ""},
+	}
+
+	for _, tt := range tests {
+		params, ok := matchPath(tt.template, tt.path)
+		if ok != tt.ok {
+			t.Errorf("matchPath(%q, %q) ok = %v, want %v", tt.template, tt.path, ok, tt.ok)
+			continue
+		}
+		if ok && params["id"] != tt.id {
+			t.Errorf("matchPath(%q, %q) id = %q, want %q", tt.template, tt.path, params["id"], tt.id)
+		}
+	}
+}
+
+// TestMuxPattern tests that parameterized routes register their subtree
+func TestMuxPattern(t *testing.T) {
+	if got := muxPattern("/api/v1/logs/{id}"); got != "/api/v1/logs/" {
+		t.Errorf("Expected subtree pattern, got %q", got)
+	}
+	if got := muxPattern("/api/v1/logs"); got != "/api/v1/logs" {
+		t.Errorf("Expected exact pattern, got %q", got)
+	}
+}
+
+// TestRouteHandlers tests that routes sharing a subtree share one pattern
+// and that paths matching none of them are not found
+func TestRouteHandlers(t *testing.T) {
+	handlers := (&Server{}).routeHandlers()
+	handler, ok := handlers["/api/v1/review/"]
+	if !ok {
+		t.Fatal("Expected a pattern for the review decision routes")
+	}
+
+	for _, path := range []string{"/api/v1/review/1/confirm", "/api/v1/review/1/ignore"} {
+		w := httptest.NewRecorder()
+		handler(w, httptest.NewRequest(http.MethodGet, path, nil))
+		if w.Code != http.StatusMethodNotAllowed {
+			t.Errorf("GET %s: expected status %d, got %d", path, http.StatusMethodNotAllowed, w.Code)
+		}
+	}
+
+	w := httptest.NewRecorder()
+	handler(w, httptest.NewRequest(http.MethodPost, "/api/v1/review/1/other", nil))
+	if w.Code != http.StatusNotFound {
+		t.Errorf("Expected status %d for an unknown decision, got %d", http.StatusNotFound, w.Code)
+	}
+}
+
+// TestReadOnly tests that a read-only server rejects changes before
+// authenticating or running the handler
+func TestReadOnly(t *testing.T) {
+	s := &Server{}
+	s.SetReadOnly(true)
+	handlers := s.routeHandlers()
+
+	w := httptest.NewRecorder()
+	handlers["/api/v1/config"](w, httptest.NewRequest(http.MethodPost, "/api/v1/config", nil))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status %d for a config update, got %d", http.StatusForbidden, w.Code)
+	}
+	w = httptest.NewRecorder()
+	handlers["/api/v1/monitor/pause"](w, httptest.NewRequest(http.MethodPost, "/api/v1/monitor/pause", nil))
+	if w.Code != http.StatusForbidden {
+		t.Errorf("Expected status %d for pausing, got %d", http.StatusForbidden, w.Code)
+	}
+}
@@ -2,47 +2,217 @@ package web
 
 import (
 	"embed"
+	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
 	"io/fs"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
 	"strconv"
+	"time"
 
+	"github.com/happytaoer/prompt-security/internal/bench"
+	"github.com/happytaoer/prompt-security/internal/breaker"
 	"github.com/happytaoer/prompt-security/internal/config"
 	"github.com/happytaoer/prompt-security/internal/db"
+	"github.com/happytaoer/prompt-security/internal/editor"
 	"github.com/happytaoer/prompt-security/internal/filter"
+	"github.com/happytaoer/prompt-security/internal/keyboard"
+	"github.com/happytaoer/prompt-security/internal/monitor"
+	"github.com/happytaoer/prompt-security/internal/usage"
 )
 
 //go:embed static/*
 var staticFiles embed.FS
 
+// MonitorController controls the clipboard monitor
+type MonitorController interface {
+	Pause()
+	Resume()
+	Paused() bool
+	Health() monitor.Health
+	Holds() []monitor.Hold
+	Restore(id string) error
+}
+
+// KeyboardGuard reports the state of keyboard protection
+type KeyboardGuard interface {
+	Status() keyboard.Status
+}
+
+// UsageReporter counts detections for anonymous usage statistics
+type UsageReporter interface {
+	Count(types []string)
+	Pending() (usage.Report, error)
+}
+
+// RiskClassifier labels logged events with their risk in the background
+type RiskClassifier interface {
+	Submit(logID int, original, filtered string)
+	SubmitPrompt(text string, log func() (int, error))
+}
+
+// CircuitBreaker locks detection down when high severity detections pile up
+type CircuitBreaker interface {
+	Record(types []string)
+	Status() breaker.Status
+	Reset() error
+}
+
 // Server represents the web server
 type Server struct {
 	configManager *config.Manager
+	engine        *filter.Engine
+	monitor       MonitorController
+	keyboard      KeyboardGuard
+	usage         UsageReporter
+	classifier    RiskClassifier
+	breaker       CircuitBreaker
+	readOnly      bool
+	summary       summaryCache
+	documents     *editor.Cache
 	logger        *slog.Logger
 }
 
 // NewServer creates a new web server instance
-func NewServer(manager *config.Manager) *Server {
+func NewServer(manager *config.Manager, engine *filter.Engine) *Server {
 	return &Server{
 		configManager: manager,
+		engine:        engine,
+		documents:     editor.NewCache(maxEditorDocuments),
 		logger:        slog.New(slog.NewJSONHandler(os.Stdout, nil)),
 	}
 }
 
-// AddLog adds a new log entry to the database
+// SetMonitor attaches the clipboard monitor controlled by the API
+func (s *Server) SetMonitor(monitor MonitorController) {
+	s.monitor = monitor
+}
+
+// SetKeyboard attaches the keyboard guard whose status the API reports
+func (s *Server) SetKeyboard(guard KeyboardGuard) {
+	s.keyboard = guard
+}
+
+// SetUsage attaches the reporter that counts logged detections
+func (s *Server) SetUsage(reporter UsageReporter) {
+	s.usage = reporter
+}
+
+// SetClassifier attaches the classifier that labels logged events
+func (s *Server) SetClassifier(classifier RiskClassifier) {
+	s.classifier = classifier
+}
+
+// SetBreaker attaches the circuit breaker that counts logged detections
+func (s *Server) SetBreaker(b CircuitBreaker) {
+	s.breaker = b
+}
+
+// SetReadOnly rejects every change, operations that are not reads and need
+// more than the viewer role, e.g. for a demo
+func (s *Server) SetReadOnly(readOnly bool) {
+	s.readOnly = readOnly
+}
+
+// AddLog adds a new log entry to the database. In hash log mode only salted
+// hashes of the text are stored.
 func (s *Server) AddLog(originalText, filteredText string, replacements []filter.ReplacementInfo) {
-	// Build detections list
-	detections := make([]string, 0)
+	s.addLog(db.LogKindPrompt, "", originalText, filteredText, replacements)
+}
+
+// AddAppLog is like AddLog for text that came from an application, e.g.
+// copied from it, so detections can be broken down by application
+func (s *Server) AddAppLog(app, originalText, filteredText string, replacements []filter.ReplacementInfo) {
+	s.addLog(db.LogKindPrompt, app, originalText, filteredText, replacements)
+}
+
+// AddLeak logs sensitive data found in a model's response as an outbound
+// leak, counted apart from the detections in prompts
+func (s *Server) AddLeak(originalText, filteredText string, replacements []filter.ReplacementInfo) {
+	s.addLog(db.LogKindLeak, "", originalText, filteredText, replacements)
+}
+
+// addLog logs an event of a kind from an application, empty if unknown.
+// Prompts without detections are not logged but classified, if a
+// classifier is enabled, and logged only if they are not labeled safe.
+func (s *Server) addLog(kind, app, originalText, filteredText string, replacements []filter.ReplacementInfo) {
+	add := db.AddLog
+	if s.configManager.Snapshot().Config.LogMode == config.LogModeHash {
+		add = db.AddHashedLog
+	}
+	if len(replacements) == 0 {
+		if s.classifier != nil && kind == db.LogKindPrompt {
+			s.classifier.SubmitPrompt(originalText, func() (int, error) {
+				return add(kind, app, originalText, filteredText, nil)
+			})
+		}
+		return
+	}
+
+	// Locate each replacement in both texts; replacements are in text order
+	// so the filtered offsets shift by the length changes before them
+	matches := make([]db.LogMatch, 0, len(replacements))
+	blocked := filter.ReplacementSummary{Replacements: replacements}.Action() == config.ActionBlock
+	shift := 0
 	for _, r := range replacements {
-		detections = append(detections, r.Type)
+		start, end := r.Start+shift, r.Start+shift+len(r.Replacement)
+		if blocked {
+			// The block message replaced the whole text
+			start, end = 0, len(filteredText)
+		}
+		matches = append(matches, db.LogMatch{
+			Type:          r.Type,
+			Start:         r.Start,
+			End:           r.End,
+			FilteredStart: start,
+			FilteredEnd:   end,
+		})
+		shift += len(r.Replacement) - (r.End - r.Start)
 	}
 
 	// Add to database
-	if err := db.AddLog(originalText, filteredText, detections); err != nil {
+	id, err := add(kind, app, originalText, filteredText, matches)
+	if err != nil {
 		s.logger.Error("Failed to add log to database", "error", err)
+	} else if s.classifier != nil {
+		if blocked {
+			filteredText = "" // Nothing of blocked text may leave the machine
+		}
+		s.classifier.Submit(id, originalText, filteredText)
+	}
+	if kind == db.LogKindLeak {
+		// Leaks are neither usage of the detectors nor input to review
+		return
+	}
+
+	types := make([]string, len(replacements))
+	for i, r := range replacements {
+		types[i] = r.Type
+	}
+
+	// Count detections for usage statistics, if enabled
+	if s.usage != nil {
+		s.usage.Count(types)
+	}
+
+	// Trip the circuit breaker if high severity detections pile up
+	if s.breaker != nil {
+		s.breaker.Record(types)
+	}
+
+	// Queue uncertain matches for review
+	for _, r := range replacements {
+		if r.Action != config.ActionReview {
+			continue
+		}
+		if err := db.AddReviewItem(r.Type, r.Original, r.Confidence); err != nil {
+			s.logger.Error("Failed to add review item to database", "error", err)
+		}
 	}
 }
 
@@ -51,34 +221,62 @@ func (s *Server) GetConfig() config.Config {
 	return s.configManager.Get()
 }
 
-// UpdateConfig updates the configuration and notifies all listeners
-func (s *Server) UpdateConfig(cfg config.Config) error {
-	return s.configManager.Update(cfg)
+// currentConfig returns a deep copy of the configuration, which a request
+// body may be decoded onto without changing the slices and maps it shares
+// with the manager
+func (s *Server) currentConfig() (config.Config, error) {
+	var cfg config.Config
+	data, err := json.Marshal(s.GetConfig())
+	if err != nil {
+		return cfg, fmt.Errorf("failed to copy config: %v", err)
+	}
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return cfg, fmt.Errorf("failed to copy config: %v", err)
+	}
+	return cfg, nil
+}
+
+// UpdateConfig updates the configuration on behalf of actor and notifies all listeners
+func (s *Server) UpdateConfig(cfg config.Config, actor string) error {
+	return s.configManager.Update(cfg, actor)
 }
 
 // Start starts the web server
 func (s *Server) Start(addr string) error {
+	handler, err := s.Handler()
+	if err != nil {
+		return err
+	}
+
+	s.logger.Info("Starting web server", "address", addr)
+	fmt.Printf("\n🌐 Web UI available at: http://%s\n\n", addr)
+
+	return http.ListenAndServe(addr, handler)
+}
+
+// Handler returns the handler serving the web UI and the API, as Start
+// serves it
+func (s *Server) Handler() (http.Handler, error) {
 	mux := http.NewServeMux()
 
 	// Create a sub-filesystem rooted at the static directory so that
 	// visiting http://localhost:8181 serves static/index.html directly.
 	staticFS, err := fs.Sub(staticFiles, "static")
 	if err != nil {
-		return fmt.Errorf("failed to create static filesystem: %w", err)
+		return nil, fmt.Errorf("failed to create static filesystem: %w", err)
 	}
 
 	// Serve static files from the root path.
 	mux.Handle("/", http.FileServer(http.FS(staticFS)))
 
-	// API endpoints
-	mux.HandleFunc("/api/config", s.handleConfig)
-	mux.HandleFunc("/api/logs", s.handleLogs)
-	mux.HandleFunc("/api/logs/clear", s.handleClearLogs)
-
-	s.logger.Info("Starting web server", "address", addr)
-	fmt.Printf("\n🌐 Web UI available at: http://%s\n\n", addr)
+	// API endpoints, also served at their deprecated unversioned paths
+	for pattern, handler := range s.routeHandlers() {
+		mux.HandleFunc(pattern, handler)
+		mux.HandleFunc(legacyPath(pattern), deprecated(handler))
+	}
+	mux.HandleFunc(apiPrefix+"/", handleAPINotFound)
 
-	return http.ListenAndServe(addr, s.corsMiddleware(mux))
+	return s.requestMiddleware(s.corsMiddleware(s.languageMiddleware(mux))), nil
 }
 
 // corsMiddleware adds CORS headers
@@ -86,7 +284,7 @@ func (s *Server) corsMiddleware(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
 		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+		w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization")
 
 		if r.Method == "OPTIONS" {
 			w.WriteHeader(http.StatusOK)
@@ -108,53 +306,64 @@ func (s *Server) handleConfig(w http.ResponseWriter, r *http.Request) {
 
 	case http.MethodPost:
 		var cfg config.Config
+		if legacyRequest(r) {
+			// Clients of the deprecated path predate the fields added
+			// since, which keep their current values rather than failing
+			// validation
+			current, err := s.currentConfig()
+			if err != nil {
+				s.writeFailure(w, err, "Failed to update config")
+				return
+			}
+			cfg = current
+		}
 		if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "Invalid JSON body", err.Error())
 			return
 		}
 
-		if err := s.UpdateConfig(cfg); err != nil {
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+		err := s.UpdateConfig(cfg, actorFromRequest(r))
+		if err != nil {
+			s.writeFailure(w, err, "Failed to update config")
 			return
 		}
 
 		w.WriteHeader(http.StatusOK)
-		json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+		json.NewEncoder(w).Encode(StatusResponse{Status: "success"})
 
 	default:
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Method not allowed", nil)
 	}
 }
 
-// handleLogs handles log retrieval from database with pagination
-func (s *Server) handleLogs(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodGet {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+// handleValidateConfig validates a configuration without saving it
+func (s *Server) handleValidateConfig(w http.ResponseWriter, r *http.Request) {
+	var cfg config.Config
+	if err := json.NewDecoder(r.Body).Decode(&cfg); err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "Invalid JSON body", err.Error())
 		return
 	}
 
-	// Parse pagination parameters
-	query := r.URL.Query()
-	page := 1
-	pageSize := 20
-
-	if pageStr := query.Get("page"); pageStr != "" {
-		if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
-			page = p
-		}
+	result := ValidationResult{Valid: true, Errors: []config.FieldError{}}
+	var validationErr *config.ValidationError
+	if errors.As(config.Validate(cfg), &validationErr) {
+		result.Valid = false
+		result.Errors = validationErr.Fields
 	}
 
-	if sizeStr := query.Get("pageSize"); sizeStr != "" {
-		if s, err := strconv.Atoi(sizeStr); err == nil && s > 0 {
-			pageSize = s
-		}
-	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// handleLogs handles log retrieval from database with pagination
+func (s *Server) handleLogs(w http.ResponseWriter, r *http.Request) {
+	// Parse pagination parameters
+	page, pageSize := parsePagination(r)
 
 	// Get logs from database with pagination
 	logs, err := db.GetLogsWithPagination(page, pageSize)
 	if err != nil {
-		s.logger.Error("Failed to get logs from database", "error", err)
-		http.Error(w, "Failed to retrieve logs", http.StatusInternalServerError)
+		s.writeFailure(w, err, "Failed to retrieve logs")
 		return
 	}
 
@@ -169,32 +378,577 @@ func (s *Server) handleLogs(w http.ResponseWriter, r *http.Request) {
 	totalPages := (totalCount + pageSize - 1) / pageSize
 
 	// Prepare response
-	response := map[string]interface{}{
-		"logs":       logs,
-		"page":       page,
-		"pageSize":   pageSize,
-		"totalCount": totalCount,
-		"totalPages": totalPages,
+	response := LogsPage{
+		Logs:       logs,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalCount: totalCount,
+		TotalPages: totalPages,
 	}
 
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(response)
 }
 
-// handleClearLogs handles clearing all logs from database
-func (s *Server) handleClearLogs(w http.ResponseWriter, r *http.Request) {
-	if r.Method != http.MethodPost {
-		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+// handleLogDetail returns a single log entry with its replacement offsets
+func (s *Server) handleLogDetail(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(pathParam(r, "id"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "Invalid log ID", nil)
+		return
+	}
+
+	detail, err := db.GetLog(id)
+	if err != nil {
+		s.writeFailure(w, err, "Failed to retrieve log")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(detail)
+}
+
+// maxReplayLogs is the most logs one replay request may re-run
+const maxReplayLogs = 1000
+
+// handleReplayLogs re-runs the newest logged copies through the current
+// configuration, or the candidate in the request, and reports what would be
+// detected now
+func (s *Server) handleReplayLogs(w http.ResponseWriter, r *http.Request) {
+	var req ReplayRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "Invalid JSON body", err.Error())
 		return
 	}
+	if req.Limit < 0 || req.Limit > maxReplayLogs {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, messagef(w, "limit must be between 0 and %d", maxReplayLogs), nil)
+		return
+	}
+
+	ds := s.engine.Detectors()
+	if req.Config != nil {
+		if writeValidationError(w, config.Validate(*req.Config)) {
+			return
+		}
+		ds = filter.NewDetectorSet(config.ApplyProfile(*req.Config, s.configManager.Snapshot().Profile))
+	}
 
+	logs, err := db.GetLogs(req.Limit)
+	if err != nil {
+		s.writeFailure(w, err, "Failed to retrieve logs")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(bench.Replay(ds, logs))
+}
+
+// handleClearLogs handles clearing all logs from database
+func (s *Server) handleClearLogs(w http.ResponseWriter, r *http.Request) {
 	// Clear logs from database
 	if err := db.ClearLogs(); err != nil {
-		s.logger.Error("Failed to clear logs from database", "error", err)
-		http.Error(w, "Failed to clear logs", http.StatusInternalServerError)
+		s.writeFailure(w, err, "Failed to clear logs")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(StatusResponse{Status: "success"})
+}
+
+// handleReview lists uncertain matches with one review status
+func (s *Server) handleReview(w http.ResponseWriter, r *http.Request) {
+	status := r.URL.Query().Get("status")
+	switch status {
+	case "":
+		status = db.ReviewPending
+	case db.ReviewPending, db.ReviewConfirmed, db.ReviewIgnored:
+	default:
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "Invalid review status", map[string]string{"status": status})
+		return
+	}
+	page, pageSize := parsePagination(r)
+
+	items, err := db.ListReviewItems(status, page, pageSize)
+	if err != nil {
+		s.writeFailure(w, err, "Failed to retrieve review items")
+		return
+	}
+	totalCount, err := db.GetReviewCount(status)
+	if err != nil {
+		s.logger.Error("Failed to get review item count", "error", err)
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(ReviewPage{
+		Items:      items,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalCount: totalCount,
+		TotalPages: (totalCount + pageSize - 1) / pageSize,
+	})
+}
+
+// handleReviewDecision records a review decision: confirmed values are
+// always acted upon from then on, ignored values are allowlisted
+func (s *Server) handleReviewDecision(status string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id, err := strconv.Atoi(pathParam(r, "id"))
+		if err != nil {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "Invalid review item ID", nil)
+			return
+		}
+
+		item, err := db.GetReviewItem(id)
+		if err != nil {
+			s.writeFailure(w, err, "Failed to retrieve review item")
+			return
+		}
+
+		cfg := s.GetConfig()
+		if status == db.ReviewConfirmed {
+			cfg.ReviewConfirmed = addValue(cfg.ReviewConfirmed, item.Value)
+			cfg.Allowlist = removeValue(cfg.Allowlist, item.Value)
+		} else {
+			cfg.Allowlist = addValue(cfg.Allowlist, item.Value)
+			cfg.ReviewConfirmed = removeValue(cfg.ReviewConfirmed, item.Value)
+		}
+
+		actor := actorFromRequest(r)
+		if err := s.UpdateConfig(cfg, actor); err != nil {
+			s.writeFailure(w, err, "Failed to save review decision")
+			return
+		}
+
+		item, err = db.DecideReviewItem(id, status, actor)
+		if err != nil {
+			s.writeFailure(w, err, "Failed to update review item")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(item)
+	}
+}
+
+// addValue appends value to values unless already present
+func addValue(values []string, value string) []string {
+	for _, v := range values {
+		if v == value {
+			return values
+		}
+	}
+	return append(append([]string{}, values...), value)
+}
+
+// removeValue returns values without value
+func removeValue(values []string, value string) []string {
+	kept := make([]string, 0, len(values))
+	for _, v := range values {
+		if v != value {
+			kept = append(kept, v)
+		}
+	}
+	return kept
+}
+
+// handlePatterns handles listing, saving and deleting string match patterns
+func (s *Server) handlePatterns(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		json.NewEncoder(w).Encode(s.GetConfig().StringMatchPatterns)
+
+	case http.MethodPost:
+		var p config.StringMatchPattern
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "Invalid JSON body", err.Error())
+			return
+		}
+
+		saved, err := s.configManager.SavePattern(p, actorFromRequest(r))
+		if err != nil {
+			s.writeFailure(w, err, "Failed to save string match pattern")
+			return
+		}
+
+		json.NewEncoder(w).Encode(saved)
+
+	case http.MethodDelete:
+		id, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil || id <= 0 {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "Invalid pattern id", map[string]string{"id": r.URL.Query().Get("id")})
+			return
+		}
+
+		err = s.configManager.DeletePattern(id, actorFromRequest(r))
+		if err != nil {
+			s.writeFailure(w, err, "Failed to delete string match pattern")
+			return
+		}
+
+		json.NewEncoder(w).Encode(StatusResponse{Status: "success"})
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Method not allowed", nil)
+	}
+}
+
+// handlePatternImport adds the terms of a CSV or TSV dictionary as string
+// match patterns, or previews them on a dry run
+func (s *Server) handlePatternImport(w http.ResponseWriter, r *http.Request) {
+	var req PatternImportRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "Invalid JSON body", err.Error())
+		return
+	}
+	if req.Format != "" && req.Format != config.DictionaryCSV && req.Format != config.DictionaryTSV {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "Invalid dictionary format", map[string]string{"format": req.Format})
+		return
+	}
+
+	opts := config.DictionaryOptions{
+		Name:            req.Name,
+		Replacement:     req.Replacement,
+		CaseInsensitive: req.CaseInsensitive,
+		WholeWord:       req.WholeWord,
+		Fuzzy:           req.Fuzzy,
+		Action:          req.Action,
+		Severity:        req.Severity,
+	}
+	result, err := s.configManager.ImportPatterns(req.Content, req.Format, opts, req.DryRun, actorFromRequest(r))
+	var parseErr *csv.ParseError
+	if errors.As(err, &parseErr) {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "Invalid dictionary", err.Error())
+		return
+	}
+	if err != nil {
+		s.writeFailure(w, err, "Failed to import string match patterns")
 		return
 	}
 
 	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(map[string]string{"status": "success"})
+	json.NewEncoder(w).Encode(result)
+}
+
+// handleDomainPolicies handles domain policy CRUD operations
+func (s *Server) handleDomainPolicies(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+
+	switch r.Method {
+	case http.MethodGet:
+		policies := s.GetConfig().DomainPolicies
+		if policies == nil {
+			policies = []config.DomainPolicy{}
+		}
+		json.NewEncoder(w).Encode(policies)
+
+	case http.MethodPost:
+		var p config.DomainPolicy
+		if err := json.NewDecoder(r.Body).Decode(&p); err != nil {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "Invalid JSON body", err.Error())
+			return
+		}
+
+		saved, err := s.configManager.SaveDomainPolicy(p, actorFromRequest(r))
+		if err != nil {
+			s.writeFailure(w, err, "Failed to save domain policy")
+			return
+		}
+
+		json.NewEncoder(w).Encode(saved)
+
+	case http.MethodDelete:
+		id, err := strconv.Atoi(r.URL.Query().Get("id"))
+		if err != nil || id <= 0 {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "Invalid domain policy id", map[string]string{"id": r.URL.Query().Get("id")})
+			return
+		}
+
+		err = s.configManager.DeleteDomainPolicy(id, actorFromRequest(r))
+		if err != nil {
+			s.writeFailure(w, err, "Failed to delete domain policy")
+			return
+		}
+
+		json.NewEncoder(w).Encode(StatusResponse{Status: "success"})
+
+	default:
+		writeError(w, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed, "Method not allowed", nil)
+	}
+}
+
+// handleAudit handles config audit trail retrieval with pagination
+func (s *Server) handleAudit(w http.ResponseWriter, r *http.Request) {
+	page, pageSize := parsePagination(r)
+
+	entries, err := db.GetAuditWithPagination(page, pageSize)
+	if err != nil {
+		s.writeFailure(w, err, "Failed to retrieve audit entries")
+		return
+	}
+
+	totalCount, err := db.GetAuditCount()
+	if err != nil {
+		s.logger.Error("Failed to get audit count", "error", err)
+		totalCount = 0
+	}
+
+	response := AuditPage{
+		Entries:    entries,
+		Page:       page,
+		PageSize:   pageSize,
+		TotalCount: totalCount,
+		TotalPages: (totalCount + pageSize - 1) / pageSize,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleFilter filters arbitrary text with the current configuration
+func (s *Server) handleFilter(w http.ResponseWriter, r *http.Request) {
+	var req FilterRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "Invalid JSON body", err.Error())
+		return
+	}
+
+	filtered, changed, summary, err := s.engine.FilterContext(r.Context(), req.Text)
+	if err != nil {
+		writeError(w, http.StatusServiceUnavailable, ErrCodeUnavailable, "Filtering was cancelled", err.Error())
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(NewFilterResponse(filtered, changed, summary))
+}
+
+// NewFilterResponse describes the result of filtering text, for other
+// transports answering like the filter endpoint
+func NewFilterResponse(filtered string, changed bool, summary filter.ReplacementSummary) FilterResponse {
+	response := FilterResponse{
+		Filtered:     filtered,
+		Changed:      changed,
+		Action:       summary.Action(),
+		Replacements: make([]Replacement, len(summary.Replacements)),
+	}
+	for i, rep := range summary.Replacements {
+		response.Replacements[i] = Replacement{
+			Type:        rep.Type,
+			Original:    rep.Original,
+			Replacement: rep.Replacement,
+			Action:      actionOf(rep),
+		}
+	}
+	return response
+}
+
+// actionOf returns the action of a replacement, naming the default
+func actionOf(r filter.ReplacementInfo) string {
+	if r.Action == "" {
+		return config.ActionReplace
+	}
+	return r.Action
+}
+
+// handleMonitor reports the clipboard monitor status
+func (s *Server) handleMonitor(w http.ResponseWriter, r *http.Request) {
+	s.writeMonitorStatus(w)
+}
+
+// handleMonitorPause pauses clipboard filtering
+func (s *Server) handleMonitorPause(w http.ResponseWriter, r *http.Request) {
+	if s.monitor == nil {
+		writeError(w, http.StatusServiceUnavailable, ErrCodeUnavailable, "Monitor not running", nil)
+		return
+	}
+
+	s.monitor.Pause()
+	s.logger.Info("Clipboard monitoring paused", "actor", actorFromRequest(r))
+	s.writeMonitorStatus(w)
+}
+
+// handleMonitorResume resumes clipboard filtering
+func (s *Server) handleMonitorResume(w http.ResponseWriter, r *http.Request) {
+	if s.monitor == nil {
+		writeError(w, http.StatusServiceUnavailable, ErrCodeUnavailable, "Monitor not running", nil)
+		return
+	}
+
+	s.monitor.Resume()
+	s.logger.Info("Clipboard monitoring resumed", "actor", actorFromRequest(r))
+	s.writeMonitorStatus(w)
+}
+
+// handleMonitorHealth reports whether the clipboard monitor loop is alive
+func (s *Server) handleMonitorHealth(w http.ResponseWriter, r *http.Request) {
+	health := MonitorHealth{Status: monitor.StatusStopped}
+	if s.monitor != nil {
+		h := s.monitor.Health()
+		health = MonitorHealth{
+			Status:            h.Status,
+			Running:           h.Running,
+			Restarts:          h.Restarts,
+			LastPanic:         h.LastPanic,
+			ConsecutiveErrors: h.ConsecutiveErrors,
+			LastError:         h.LastError,
+			BackoffMs:         h.Backoff.Milliseconds(),
+		}
+		if !h.LastHeartbeat.IsZero() {
+			health.LastHeartbeat = h.LastHeartbeat.UTC().Format(time.RFC3339)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(health)
+}
+
+// handleHolds lists the redacted copies whose original may be restored
+func (s *Server) handleHolds(w http.ResponseWriter, r *http.Request) {
+	holds := []HeldCopy{}
+	if s.monitor != nil {
+		for _, h := range s.monitor.Holds() {
+			holds = append(holds, HeldCopy{
+				ID:        h.ID,
+				Source:    h.Source,
+				Types:     h.Types,
+				Filtered:  h.Filtered,
+				ExpiresAt: h.Expires.UTC().Format(time.RFC3339),
+			})
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(holds)
+}
+
+// handleRestoreHold puts the original of a held copy back on the clipboard
+func (s *Server) handleRestoreHold(w http.ResponseWriter, r *http.Request) {
+	if s.monitor == nil {
+		writeError(w, http.StatusServiceUnavailable, ErrCodeUnavailable, "Monitor not running", nil)
+		return
+	}
+
+	err := s.monitor.Restore(pathParam(r, "id"))
+	if err != nil {
+		s.writeFailure(w, err, "Failed to restore clipboard content")
+		return
+	}
+
+	s.logger.Info("Held clipboard content restored", "actor", actorFromRequest(r))
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(StatusResponse{Status: "success"})
+}
+
+// handleKeyboard reports the state of keyboard protection
+func (s *Server) handleKeyboard(w http.ResponseWriter, r *http.Request) {
+	var status KeyboardStatus
+	if s.keyboard != nil {
+		st := s.keyboard.Status()
+		status = KeyboardStatus{Enabled: st.Enabled, Active: st.Active, Error: st.Error}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// handleBreaker reports the state of the circuit breaker
+func (s *Server) handleBreaker(w http.ResponseWriter, r *http.Request) {
+	var status BreakerStatus
+	if s.breaker != nil {
+		st := s.breaker.Status()
+		status = BreakerStatus{Enabled: st.Enabled, Tripped: st.Tripped, Recent: st.Recent}
+		if st.TrippedAt != nil {
+			status.TrippedAt = st.TrippedAt.Format(time.RFC3339)
+		}
+		if st.Until != nil {
+			status.Until = st.Until.Format(time.RFC3339)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// handleBreakerReset closes a tripped circuit breaker, restoring the
+// configured actions
+func (s *Server) handleBreakerReset(w http.ResponseWriter, r *http.Request) {
+	if s.breaker == nil {
+		writeError(w, http.StatusServiceUnavailable, ErrCodeUnavailable, "Circuit breaker not running", nil)
+		return
+	}
+
+	if err := s.breaker.Reset(); err != nil {
+		s.writeFailure(w, err, "Failed to reset circuit breaker")
+		return
+	}
+	s.logger.Info("Circuit breaker reset", "actor", actorFromRequest(r))
+	s.handleBreaker(w, r)
+}
+
+// handleUsage previews the anonymous usage report sent next
+func (s *Server) handleUsage(w http.ResponseWriter, r *http.Request) {
+	report := usage.Report{Days: []usage.Day{}}
+	if s.usage != nil {
+		var err error
+		if report, err = s.usage.Pending(); err != nil {
+			s.writeFailure(w, err, "Failed to retrieve usage report")
+			return
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(report)
+}
+
+// writeMonitorStatus writes the monitor status as JSON
+func (s *Server) writeMonitorStatus(w http.ResponseWriter) {
+	status := MonitorStatus{
+		Running: s.monitor != nil,
+		Paused:  s.monitor != nil && s.monitor.Paused(),
+	}
+	snapshot := s.configManager.Snapshot()
+	status.Profile, status.Schedule = snapshot.Profile, snapshot.Schedule
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(status)
+}
+
+// parsePagination reads the page and pageSize query parameters
+func parsePagination(r *http.Request) (int, int) {
+	query := r.URL.Query()
+	page := 1
+	pageSize := 20
+
+	if pageStr := query.Get("page"); pageStr != "" {
+		if p, err := strconv.Atoi(pageStr); err == nil && p > 0 {
+			page = p
+		}
+	}
+
+	if sizeStr := query.Get("pageSize"); sizeStr != "" {
+		if s, err := strconv.Atoi(sizeStr); err == nil && s > 0 {
+			pageSize = s
+		}
+	}
+
+	return page, pageSize
+}
+
+// actorFromRequest identifies who made a request for the audit trail
+func actorFromRequest(r *http.Request) string {
+	if id, ok := r.Context().Value(identityKey{}).(identity); ok {
+		return id.Name
+	}
+	return remoteHost(r)
+}
+
+// remoteHost returns the host part of the request's remote address
+func remoteHost(r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
 }
@@ -0,0 +1,366 @@
+package web
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+	"github.com/happytaoer/prompt-security/internal/db"
+	"github.com/happytaoer/prompt-security/internal/filter"
+)
+
+// newTestServer serves the API and UI of a server backed by a fresh
+// in-memory database, closed at the end of the test
+func newTestServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	db.SetPath(db.MemoryPath)
+	if err := config.Initialize(); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	manager, err := config.NewManager()
+	if err != nil {
+		config.Close()
+		t.Fatalf("Failed to create manager: %v", err)
+	}
+
+	handler, err := NewServer(manager, filter.NewManagedEngine(manager)).Handler()
+	if err != nil {
+		config.Close()
+		t.Fatalf("Failed to create handler: %v", err)
+	}
+	ts := httptest.NewServer(handler)
+	t.Cleanup(func() {
+		ts.Close()
+		config.Close()
+	})
+	return ts
+}
+
+// call sends a request with body encoded as JSON, unless it is a string,
+// and a bearer token unless it is empty, and decodes the response into out
+// unless it is nil. It returns the response.
+func call(t *testing.T, ts *httptest.Server, method, path, token string, body, out interface{}) *http.Response {
+	t.Helper()
+	var reader io.Reader
+	switch b := body.(type) {
+	case nil:
+	case string:
+		reader = strings.NewReader(b)
+	default:
+		data, err := json.Marshal(b)
+		if err != nil {
+			t.Fatal(err)
+		}
+		reader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequest(method, ts.URL+path, reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatalf("%s %s: %v", method, path, err)
+	}
+	defer resp.Body.Close()
+
+	if out != nil {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("%s %s: failed to decode response: %v", method, path, err)
+		}
+	}
+	return resp
+}
+
+// expectError checks that a response failed with status and code
+func expectError(t *testing.T, what string, resp *http.Response, apiErr APIError, status int, code string) {
+	t.Helper()
+	if resp.StatusCode != status || apiErr.Code != code {
+		t.Errorf("%s: expected %d %s, got %d %+v", what, status, code, resp.StatusCode, apiErr)
+	}
+}
+
+// TestAPI_Config tests reading, updating and validating the configuration
+func TestAPI_Config(t *testing.T) {
+	ts := newTestServer(t)
+
+	var cfg config.Config
+	if resp := call(t, ts, http.MethodGet, apiPrefix+"/config", "", nil, &cfg); resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected status 200, got %d", resp.StatusCode)
+	}
+	if !cfg.DetectEmails {
+		t.Errorf("Expected email detection on by default, got %+v", cfg)
+	}
+
+	cfg.DetectEmails = false
+	var status StatusResponse
+	if resp := call(t, ts, http.MethodPost, apiPrefix+"/config", "", cfg, &status); resp.StatusCode != http.StatusOK || status.Status != "success" {
+		t.Fatalf("Expected the update to succeed, got %d %+v", resp.StatusCode, status)
+	}
+	var saved config.Config
+	call(t, ts, http.MethodGet, apiPrefix+"/config", "", nil, &saved)
+	if saved.DetectEmails {
+		t.Error("Expected the update to be saved")
+	}
+
+	// The filter uses the saved configuration
+	var filtered FilterResponse
+	call(t, ts, http.MethodPost, apiPrefix+"/filter", "", FilterRequest{Text: "mail ann@example.org"}, &filtered)
+	if filtered.Changed {
+		t.Errorf("Expected emails to be kept once disabled, got %+v", filtered)
+	}
+
+	var apiErr APIError
+	invalid := saved
+	invalid.MonitoringInterval = 1
+	resp := call(t, ts, http.MethodPost, apiPrefix+"/config", "", invalid, &apiErr)
+	expectError(t, "Invalid config", resp, apiErr, http.StatusUnprocessableEntity, ErrCodeValidation)
+	if !strings.Contains(fmt.Sprint(apiErr.Details), "monitoring_interval_ms") {
+		t.Errorf("Expected the invalid field in the details, got %v", apiErr.Details)
+	}
+	call(t, ts, http.MethodGet, apiPrefix+"/config", "", nil, &saved)
+	if saved.MonitoringInterval == 1 {
+		t.Error("Expected an invalid config not to be saved")
+	}
+
+	apiErr = APIError{}
+	resp = call(t, ts, http.MethodPost, apiPrefix+"/config", "", "{", &apiErr)
+	expectError(t, "Malformed JSON", resp, apiErr, http.StatusBadRequest, ErrCodeInvalidRequest)
+
+	apiErr = APIError{}
+	resp = call(t, ts, http.MethodPut, apiPrefix+"/config", "", cfg, &apiErr)
+	expectError(t, "PUT config", resp, apiErr, http.StatusMethodNotAllowed, ErrCodeMethodNotAllowed)
+
+	var result ValidationResult
+	call(t, ts, http.MethodPost, apiPrefix+"/config/validate", "", invalid, &result)
+	if result.Valid || len(result.Errors) == 0 {
+		t.Errorf("Expected validation errors, got %+v", result)
+	}
+}
+
+// TestAPI_Patterns tests creating, listing and deleting string match
+// patterns
+func TestAPI_Patterns(t *testing.T) {
+	ts := newTestServer(t)
+
+	var apiErr APIError
+	resp := call(t, ts, http.MethodPost, apiPrefix+"/patterns", "", config.StringMatchPattern{Pattern: "Falcon"}, &apiErr)
+	expectError(t, "Invalid pattern", resp, apiErr, http.StatusUnprocessableEntity, ErrCodeValidation)
+
+	var saved config.StringMatchPattern
+	pattern := config.StringMatchPattern{Name: "Codename", Pattern: "Falcon", Replacement: "[CODENAME]", Enabled: true}
+	if resp := call(t, ts, http.MethodPost, apiPrefix+"/patterns", "", pattern, &saved); resp.StatusCode != http.StatusOK || saved.ID == 0 {
+		t.Fatalf("Expected the pattern to be created, got %d %+v", resp.StatusCode, saved)
+	}
+
+	var list []config.StringMatchPattern
+	call(t, ts, http.MethodGet, apiPrefix+"/patterns", "", nil, &list)
+	if len(list) != 1 || list[0].ID != saved.ID {
+		t.Errorf("Expected the created pattern, got %+v", list)
+	}
+
+	var filtered FilterResponse
+	call(t, ts, http.MethodPost, apiPrefix+"/filter", "", FilterRequest{Text: "Project Falcon ships"}, &filtered)
+	if filtered.Filtered != "Project [CODENAME] ships" {
+		t.Errorf("Expected the pattern to be applied, got %q", filtered.Filtered)
+	}
+
+	path := fmt.Sprintf("%s/patterns?id=%d", apiPrefix, saved.ID)
+	if resp := call(t, ts, http.MethodDelete, path, "", nil, nil); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected the pattern to be deleted, got %d", resp.StatusCode)
+	}
+	apiErr = APIError{}
+	resp = call(t, ts, http.MethodDelete, path, "", nil, &apiErr)
+	expectError(t, "Deleted pattern", resp, apiErr, http.StatusNotFound, ErrCodeNotFound)
+
+	apiErr = APIError{}
+	resp = call(t, ts, http.MethodDelete, apiPrefix+"/patterns?id=abc", "", nil, &apiErr)
+	expectError(t, "Invalid pattern id", resp, apiErr, http.StatusBadRequest, ErrCodeInvalidRequest)
+}
+
+// TestAPI_Logs tests listing, reading and clearing filter logs
+func TestAPI_Logs(t *testing.T) {
+	ts := newTestServer(t)
+
+	id, err := db.AddLog("prompt", "", "mail ann@example.org", "mail [EMAIL]", nil)
+	if err != nil {
+		t.Fatalf("Failed to add log: %v", err)
+	}
+
+	var page LogsPage
+	call(t, ts, http.MethodGet, apiPrefix+"/logs?page=1&pageSize=10", "", nil, &page)
+	if page.TotalCount != 1 || len(page.Logs) != 1 || page.Logs[0].FilteredText != "mail [EMAIL]" {
+		t.Fatalf("Expected the added log, got %+v", page)
+	}
+
+	var detail db.LogDetail
+	if resp := call(t, ts, http.MethodGet, fmt.Sprintf("%s/logs/%d", apiPrefix, id), "", nil, &detail); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected the log, got %d", resp.StatusCode)
+	}
+
+	var apiErr APIError
+	resp := call(t, ts, http.MethodGet, apiPrefix+"/logs/999", "", nil, &apiErr)
+	expectError(t, "Missing log", resp, apiErr, http.StatusNotFound, ErrCodeNotFound)
+	apiErr = APIError{}
+	resp = call(t, ts, http.MethodGet, apiPrefix+"/logs/abc", "", nil, &apiErr)
+	expectError(t, "Invalid log ID", resp, apiErr, http.StatusBadRequest, ErrCodeInvalidRequest)
+
+	if resp := call(t, ts, http.MethodPost, apiPrefix+"/logs/clear", "", nil, nil); resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected logs to be cleared, got %d", resp.StatusCode)
+	}
+	page = LogsPage{}
+	call(t, ts, http.MethodGet, apiPrefix+"/logs", "", nil, &page)
+	if page.TotalCount != 0 || len(page.Logs) != 0 {
+		t.Errorf("Expected no logs after clearing, got %+v", page)
+	}
+}
+
+// TestAPI_Auth tests that once tokens exist, requests need a token whose
+// role allows the operation
+func TestAPI_Auth(t *testing.T) {
+	ts := newTestServer(t)
+
+	viewer, err := db.CreateAPIToken("viewer", string(RoleViewer))
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+	admin, err := db.CreateAPIToken("admin", string(RoleAdmin))
+	if err != nil {
+		t.Fatalf("Failed to create token: %v", err)
+	}
+
+	var apiErr APIError
+	resp := call(t, ts, http.MethodGet, apiPrefix+"/config", "", nil, &apiErr)
+	expectError(t, "No token", resp, apiErr, http.StatusUnauthorized, ErrCodeUnauthorized)
+	if resp.Header.Get("WWW-Authenticate") == "" {
+		t.Error("Expected a WWW-Authenticate challenge")
+	}
+	apiErr = APIError{}
+	resp = call(t, ts, http.MethodGet, apiPrefix+"/config", "ps_unknown", nil, &apiErr)
+	expectError(t, "Unknown token", resp, apiErr, http.StatusUnauthorized, ErrCodeUnauthorized)
+
+	// The config and patterns hold secrets such as the breaker webhook
+	apiErr = APIError{}
+	resp = call(t, ts, http.MethodGet, apiPrefix+"/config", viewer, nil, &apiErr)
+	expectError(t, "Viewer config read", resp, apiErr, http.StatusForbidden, ErrCodeForbidden)
+	apiErr = APIError{}
+	resp = call(t, ts, http.MethodGet, apiPrefix+"/patterns", viewer, nil, &apiErr)
+	expectError(t, "Viewer pattern read", resp, apiErr, http.StatusForbidden, ErrCodeForbidden)
+	if resp := call(t, ts, http.MethodGet, apiPrefix+"/logs", viewer, nil, nil); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected a viewer to read the logs, got %d", resp.StatusCode)
+	}
+
+	var cfg config.Config
+	if resp := call(t, ts, http.MethodGet, apiPrefix+"/config", admin, nil, &cfg); resp.StatusCode != http.StatusOK {
+		t.Fatalf("Expected an admin to read the config, got %d", resp.StatusCode)
+	}
+	apiErr = APIError{}
+	resp = call(t, ts, http.MethodPost, apiPrefix+"/config", viewer, cfg, &apiErr)
+	expectError(t, "Viewer update", resp, apiErr, http.StatusForbidden, ErrCodeForbidden)
+	apiErr = APIError{}
+	resp = call(t, ts, http.MethodDelete, apiPrefix+"/patterns?id=1", viewer, nil, &apiErr)
+	expectError(t, "Viewer delete", resp, apiErr, http.StatusForbidden, ErrCodeForbidden)
+
+	if resp := call(t, ts, http.MethodPost, apiPrefix+"/config", admin, cfg, nil); resp.StatusCode != http.StatusOK {
+		t.Errorf("Expected an admin to update the config, got %d", resp.StatusCode)
+	}
+
+	// Deprecated paths are protected alike
+	apiErr = APIError{}
+	resp = call(t, ts, http.MethodGet, "/api/config", "", nil, &apiErr)
+	expectError(t, "Legacy path without token", resp, apiErr, http.StatusUnauthorized, ErrCodeUnauthorized)
+}
+
+// TestAPI_LegacyConfig tests that the deprecated config path accepts the
+// body of clients predating the fields added since, keeping their values
+func TestAPI_LegacyConfig(t *testing.T) {
+	ts := newTestServer(t)
+
+	var before config.Config
+	call(t, ts, http.MethodGet, apiPrefix+"/config", "", nil, &before)
+
+	body := `{
+		"detect_emails": true, "detect_phones": false, "detect_credit_cards": true, "detect_ssns": true, "detect_ipv4": false,
+		"string_match_patterns": [],
+		"custom_email_pattern": "", "custom_phone_pattern": "", "custom_credit_card_pattern": "", "custom_ssn_pattern": "", "custom_ipv4_pattern": "",
+		"email_replacement": "[MAIL]", "phone_replacement": "[PHONE]", "credit_card_replacement": "[CARD]", "ssn_replacement": "[SSN]", "ipv4_replacement": "[IP]",
+		"api_key_replacement": "",
+		"monitoring_interval_ms": 750, "notify_on_filter": false
+	}`
+	var status StatusResponse
+	if resp := call(t, ts, http.MethodPost, "/api/config", "", body, &status); resp.StatusCode != http.StatusOK || status.Status != "success" {
+		t.Fatalf("Expected the legacy update to succeed, got %d %+v", resp.StatusCode, status)
+	}
+
+	var saved config.Config
+	call(t, ts, http.MethodGet, apiPrefix+"/config", "", nil, &saved)
+	if saved.EmailReplacement != "[MAIL]" || saved.MonitoringInterval != 750 || saved.DetectPhones {
+		t.Errorf("Expected the posted fields to be saved, got %+v", saved)
+	}
+	if saved.LogMode != before.LogMode || saved.LargeContentMode != before.LargeContentMode || saved.ScanTimeoutMs != before.ScanTimeoutMs {
+		t.Errorf("Expected the missing fields to keep their values, got %+v", saved)
+	}
+
+	// The versioned path still requires a complete configuration
+	var apiErr APIError
+	resp := call(t, ts, http.MethodPost, apiPrefix+"/config", "", body, &apiErr)
+	expectError(t, "Partial update", resp, apiErr, http.StatusUnprocessableEntity, ErrCodeValidation)
+}
+
+// TestAPI_Routing tests unknown API paths and the deprecated unversioned
+// paths
+func TestAPI_Routing(t *testing.T) {
+	ts := newTestServer(t)
+
+	var apiErr APIError
+	resp := call(t, ts, http.MethodGet, apiPrefix+"/nothing", "", nil, &apiErr)
+	expectError(t, "Unknown path", resp, apiErr, http.StatusNotFound, ErrCodeNotFound)
+
+	var list []config.StringMatchPattern
+	resp = call(t, ts, http.MethodGet, "/api/patterns", "", nil, &list)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("Deprecation") != "true" {
+		t.Errorf("Expected the deprecated path to answer with a Deprecation header, got %d %v", resp.StatusCode, resp.Header)
+	}
+}
+
+// TestErrorStatus tests the HTTP status and error code of typed errors
+func TestErrorStatus(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{fmt.Errorf("%w: 7", config.ErrPatternNotFound), http.StatusNotFound, ErrCodeNotFound},
+		{config.ErrConfigNotFound, http.StatusNotFound, ErrCodeNotFound},
+		{fmt.Errorf("%w: 7", db.ErrLogNotFound), http.StatusNotFound, ErrCodeNotFound},
+		{config.ValidatePattern(config.StringMatchPattern{}), http.StatusBadRequest, ErrCodeInvalidRequest},
+		{fmt.Errorf("failed to record audit entry: %w", &db.StorageError{Op: "save audit entry", Err: io.ErrUnexpectedEOF}), http.StatusServiceUnavailable, ErrCodeStorage},
+		{io.ErrUnexpectedEOF, http.StatusInternalServerError, ErrCodeInternal},
+	}
+
+	for _, tt := range tests {
+		if status, code := errorStatus(tt.err); status != tt.status || code != tt.code {
+			t.Errorf("errorStatus(%v) = %d %s, want %d %s", tt.err, status, code, tt.status, tt.code)
+		}
+	}
+}
+
+// TestAPI_StorageFailure tests that requests failing in the database are
+// answered as storage errors
+func TestAPI_StorageFailure(t *testing.T) {
+	ts := newTestServer(t)
+	config.Close()
+
+	var apiErr APIError
+	resp := call(t, ts, http.MethodGet, apiPrefix+"/logs", "", nil, &apiErr)
+	expectError(t, "Closed database", resp, apiErr, http.StatusServiceUnavailable, ErrCodeStorage)
+}
@@ -0,0 +1,103 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+	"github.com/happytaoer/prompt-security/internal/db"
+)
+
+// handleSetup reports and completes first-run setup
+func (s *Server) handleSetup(w http.ResponseWriter, r *http.Request) {
+	completedAt, changed, err := config.SetupState()
+	if err != nil {
+		s.writeFailure(w, err, "Failed to retrieve setup state")
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		tokens, err := db.CountAPITokens()
+		if err != nil {
+			s.writeFailure(w, err, "Failed to retrieve setup state")
+			return
+		}
+		status := SetupStatus{
+			Required:         completedAt == nil && !changed,
+			Completed:        completedAt != nil,
+			ConfigChanged:    changed,
+			TokensConfigured: tokens > 0,
+			Detectors:        config.Detectors,
+			DefaultDetectors: config.DefaultSetupDetectors,
+			Strategies:       config.Strategies,
+			DefaultStrategy:  config.DefaultStrategy,
+		}
+		if completedAt != nil {
+			status.CompletedAt = completedAt.Format(time.RFC3339)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(status)
+		return
+	}
+
+	if completedAt != nil {
+		writeError(w, http.StatusConflict, ErrCodeConflict, "Setup was already completed",
+			map[string]string{"completed_at": completedAt.Format(time.RFC3339)})
+		return
+	}
+	var req SetupRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "Invalid JSON body", err.Error())
+		return
+	}
+
+	// Check everything before the token is created, so that a rejected
+	// request changes nothing
+	cfg, err := config.ApplySetup(s.GetConfig(), req.SetupChoices)
+	if err == nil {
+		err = config.Validate(cfg)
+	}
+	if writeValidationError(w, err) {
+		return
+	}
+
+	var result SetupResult
+	if name := strings.TrimSpace(req.AdminToken); name != "" {
+		if result.Token, err = db.CreateAPIToken(name, string(RoleAdmin)); err != nil {
+			s.logger.Error("Failed to create admin token", "error", err)
+			writeError(w, http.StatusConflict, ErrCodeConflict, "Failed to create admin token, the name may be taken",
+				map[string]string{"admin_token": name})
+			return
+		}
+	}
+
+	// Revoke the token if setup cannot be completed, so that a failed
+	// request does not lock the caller out
+	if err := s.UpdateConfig(cfg, actorFromRequest(r)); err != nil {
+		s.revokeSetupToken(req.AdminToken, result.Token)
+		s.writeFailure(w, err, "Failed to save configuration")
+		return
+	}
+	if err := db.CompleteSetup(); err != nil {
+		s.revokeSetupToken(req.AdminToken, result.Token)
+		s.writeFailure(w, err, "Failed to complete setup")
+		return
+	}
+
+	result.Config = s.GetConfig()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(result)
+}
+
+// revokeSetupToken deletes the admin token named name created by a setup
+// request that failed, if secret shows one was created
+func (s *Server) revokeSetupToken(name, secret string) {
+	if secret == "" {
+		return
+	}
+	if err := db.DeleteAPIToken(strings.TrimSpace(name)); err != nil {
+		s.logger.Error("Failed to revoke admin token of failed setup", "name", name, "error", err)
+	}
+}
@@ -1,5 +1,52 @@
 // API Base URL
-const API_BASE = '';
+const API_BASE = '/api/v1';
+
+// Conflict priority inputs for the built-in detectors
+const PRIORITY_FIELDS = ['email_priority', 'phone_priority', 'credit_card_priority', 'ssn_priority', 'ipv4_priority'];
+
+// Action and severity selects for the built-in detectors
+const ACTION_FIELDS = ['email_action', 'phone_action', 'credit_card_action', 'ssn_action', 'ipv4_action', 'totp_action'];
+const SEVERITY_FIELDS = ['email_severity', 'phone_severity', 'credit_card_severity', 'ssn_severity', 'ipv4_severity', 'totp_severity'];
+
+// Profiles and severities of the policy table
+const POLICY_PROFILES = ['standard', 'strict'];
+const SEVERITIES = ['low', 'medium', 'high', 'critical'];
+
+// Schedules from the last loaded configuration, sent back unchanged on save
+// until the dashboard can edit them
+let loadedSchedules = [];
+
+// Display names of the detection types in the API's language, by type
+let typeLabels = {};
+
+// Whether the user agreed to key presses being read, saved with the config
+let keyboardConsent = false;
+
+// Storage key for the API bearer token
+const TOKEN_KEY = 'prompt-security-token';
+
+// Fetch wrapper that attaches the stored API token and asks for a new one
+// when the server rejects the request as unauthorized
+async function apiFetch(url, options = {}) {
+    const withToken = () => {
+        const headers = Object.assign({}, options.headers);
+        const token = localStorage.getItem(TOKEN_KEY);
+        if (token) {
+            headers['Authorization'] = `Bearer ${token}`;
+        }
+        return fetch(url, Object.assign({}, options, { headers }));
+    };
+
+    let response = await withToken();
+    if (response.status === 401) {
+        const token = prompt('This dashboard requires an API token:');
+        if (token) {
+            localStorage.setItem(TOKEN_KEY, token.trim());
+            response = await withToken();
+        }
+    }
+    return response;
+}
 
 // Switch between main tabs (Configuration vs Logs)
 function switchTab(tabName) {
@@ -25,6 +72,8 @@ function switchTab(tabName) {
         // Deactivate all config sub-tabs when switching to logs
         document.querySelectorAll('.sidebar .sub-tab').forEach(tab => tab.classList.remove('active'));
         loadLogs();
+    } else if (tabName === 'review') {
+        loadReview();
     }
 }
 
@@ -52,7 +101,7 @@ function switchConfigSection(sectionName) {
 // Load configuration from server
 async function loadConfig() {
     try {
-        const response = await fetch(`${API_BASE}/api/config`);
+        const response = await apiFetch(`${API_BASE}/config`);
         const config = await response.json();
 
         // Detection settings
@@ -60,18 +109,104 @@ async function loadConfig() {
         document.getElementById('detect_phones').checked = config.detect_phones || false;
         document.getElementById('detect_credit_cards').checked = config.detect_credit_cards || false;
         document.getElementById('detect_ssns').checked = config.detect_ssns || false;
+        document.getElementById('china_pack').checked = config.china_pack || false;
         document.getElementById('detect_ipv4').checked = config.detect_ipv4 || false;
+        document.getElementById('detect_locations').checked = config.detect_locations || false;
+        document.getElementById('detect_terraform_secrets').checked = config.detect_terraform_secrets || false;
+        document.getElementById('detect_http_auth').checked = config.detect_http_auth || false;
+        document.getElementById('detect_cookies').checked = config.detect_cookies || false;
+        document.getElementById('sanitize_curl').checked = config.sanitize_curl || false;
+        document.getElementById('detect_home_paths').checked = config.detect_home_paths || false;
+        document.getElementById('detect_shell_prompts').checked = config.detect_shell_prompts || false;
+        document.getElementById('detect_infrastructure_ids').checked = config.detect_infrastructure_ids || false;
+        document.getElementById('detect_license_keys').checked = config.detect_license_keys || false;
+        document.getElementById('detect_mac_addresses').checked = config.detect_mac_addresses || false;
+        document.getElementById('detect_totp_secrets').checked = config.detect_totp_secrets || false;
+        document.getElementById('detect_recovery_codes').checked = config.detect_recovery_codes || false;
+        document.getElementById('detect_passwords').checked = config.detect_passwords || false;
+        document.getElementById('mac_allow_local').checked = config.mac_allow_local || false;
+        document.getElementById('normalize_detectors').value = (config.normalize_detectors || []).join(', ');
+        document.getElementById('review_threshold').value = config.review_threshold || '';
+        document.getElementById('allowlist').value = (config.allowlist || []).join('\n');
+        document.getElementById('review_confirmed').value = (config.review_confirmed || []).join('\n');
+
+        // Conflict priorities
+        for (const id of PRIORITY_FIELDS) {
+            document.getElementById(id).value = config[id] || '';
+        }
 
         // Replacement values
         document.getElementById('email_replacement').value = config.email_replacement || '';
         document.getElementById('phone_replacement').value = config.phone_replacement || '';
+        document.getElementById('phone_locales').value = (config.phone_locales || []).join(', ');
         document.getElementById('credit_card_replacement').value = config.credit_card_replacement || '';
         document.getElementById('ssn_replacement').value = config.ssn_replacement || '';
+        document.getElementById('ssn_locales').value = (config.ssn_locales || []).join(', ');
         document.getElementById('ipv4_replacement').value = config.ipv4_replacement || '';
+        document.getElementById('location_replacement').value = config.location_replacement || '';
+        document.getElementById('terraform_replacement').value = config.terraform_replacement || '';
+        document.getElementById('http_auth_replacement').value = config.http_auth_replacement || '';
+        document.getElementById('cookie_replacement').value = config.cookie_replacement || '';
+        document.getElementById('home_path_replacement').value = config.home_path_replacement || '';
+        document.getElementById('shell_prompt_replacement').value = config.shell_prompt_replacement || '';
+        document.getElementById('gcp_project_replacement').value = config.gcp_project_replacement || '';
+        document.getElementById('azure_id_replacement').value = config.azure_id_replacement || '';
+        document.getElementById('license_key_replacement').value = config.license_key_replacement || '';
+        document.getElementById('mac_replacement').value = config.mac_replacement || '';
+        document.getElementById('totp_replacement').value = config.totp_replacement || '';
+        document.getElementById('recovery_code_replacement').value = config.recovery_code_replacement || '';
+        document.getElementById('password_replacement').value = config.password_replacement || '';
+        document.getElementById('license_key_formats').value = (config.license_key_formats || []).join(', ');
+
+        // Detector actions, severities and the policy
+        for (const id of [...ACTION_FIELDS, ...SEVERITY_FIELDS]) {
+            document.getElementById(id).value = config[id] || '';
+        }
+        const policies = config.policies || {};
+        for (const profile of POLICY_PROFILES) {
+            for (const severity of SEVERITIES) {
+                document.getElementById(`policy_${profile}_${severity}`).value = (policies[profile] || {})[severity] || 'replace';
+            }
+        }
+        document.getElementById('block_message').value = config.block_message ?? '';
+        document.getElementById('ask_timeout_seconds').value = config.ask_timeout_seconds || 30;
 
         // Monitoring settings
         document.getElementById('monitoring_interval_ms').value = config.monitoring_interval_ms || 500;
+        document.getElementById('monitor_clipboard').checked = config.monitor_clipboard ?? true;
+        document.getElementById('monitor_primary_selection').checked = config.monitor_primary_selection || false;
         document.getElementById('notify_on_filter').checked = config.notify_on_filter || false;
+        document.getElementById('max_clipboard_bytes').value = config.max_clipboard_bytes ?? 1048576;
+        document.getElementById('large_content_mode').value = config.large_content_mode || 'chunked';
+        document.getElementById('scan_timeout_ms').value = config.scan_timeout_ms || 5000;
+        document.getElementById('incremental_scan').checked = config.incremental_scan || false;
+        document.getElementById('log_mode').value = config.log_mode || 'full';
+        document.getElementById('file_scan_mode').value = config.file_scan_mode || 'off';
+        document.getElementById('detect_special_categories').checked = config.detect_special_categories || false;
+        document.getElementById('prompt_safety_mode').value = config.prompt_safety_mode || 'off';
+        document.getElementById('classifier_mode').value = config.classifier_mode || 'off';
+        document.getElementById('classifier_endpoint').value = config.classifier_endpoint || '';
+        document.getElementById('breaker_threshold').value = config.breaker_threshold || 0;
+        document.getElementById('breaker_window_minutes').value = config.breaker_window_minutes || 10;
+        document.getElementById('breaker_cooldown_minutes').value = config.breaker_cooldown_minutes ?? 30;
+        document.getElementById('breaker_webhook').value = config.breaker_webhook || '';
+        document.getElementById('file_scan_max_bytes').value = config.file_scan_max_bytes || 1048576;
+        document.getElementById('keyboard_protection').checked = config.keyboard_protection || false;
+        keyboardConsent = config.keyboard_consent || false;
+        document.getElementById('keyboard_apps').value = (config.keyboard_apps || []).join(', ');
+        document.getElementById('proxy_bypass').value = (config.proxy_bypass || []).join(', ');
+        document.getElementById('usage_reporting').checked = config.usage_reporting || false;
+        document.getElementById('usage_endpoint').value = config.usage_endpoint || '';
+        document.getElementById('report_recipients').value = (config.report_recipients || []).join(', ');
+        document.getElementById('smtp_host').value = config.smtp_host || '';
+        document.getElementById('smtp_port').value = config.smtp_port || 587;
+        document.getElementById('smtp_tls').value = config.smtp_tls || 'starttls';
+        document.getElementById('smtp_from').value = config.smtp_from || '';
+        document.getElementById('smtp_username').value = config.smtp_username || '';
+        document.getElementById('language').value = config.language || '';
+        loadKeyboardStatus();
+        loadBreakerStatus();
+        loadedSchedules = config.schedules || [];
 
         // Custom patterns
         document.getElementById('custom_email_pattern').value = config.custom_email_pattern || '';
@@ -80,6 +215,12 @@ async function loadConfig() {
         document.getElementById('custom_ssn_pattern').value = config.custom_ssn_pattern || '';
         document.getElementById('custom_ipv4_pattern').value = config.custom_ipv4_pattern || '';
 
+        // Patterns switched off for exceeding their time budget
+        const disabled = config.disabled_patterns || [];
+        const notice = document.getElementById('disabled_patterns_notice');
+        notice.textContent = `⚠️ Disabled for being too slow, using the built-in pattern until changed: ${disabled.join(', ')}`;
+        notice.style.display = disabled.length > 0 ? 'block' : 'none';
+
         console.log('Configuration loaded successfully');
     } catch (error) {
         console.error('Error loading configuration:', error);
@@ -87,6 +228,88 @@ async function loadConfig() {
     }
 }
 
+// Ask for consent before reading key presses
+function confirmKeyboardProtection(checkbox) {
+    if (!checkbox.checked) {
+        return;
+    }
+    checkbox.checked = confirm(
+        'Keyboard protection reads every key you press while one of the listed AI apps has focus, ' +
+        'and warns you when what you type looks like sensitive data. Typed text stays in memory ' +
+        'and is never stored or logged.\n\n' +
+        'On Linux this needs permission to read /dev/input (membership in the input group) and ' +
+        'xdotool to tell the focused window. It is not available on other systems.\n\n' +
+        'Enable keyboard protection?');
+    keyboardConsent = keyboardConsent || checkbox.checked;
+}
+
+// Load the display names of the detection types
+async function loadLabels() {
+    try {
+        const response = await apiFetch(`${API_BASE}/labels`);
+        if (response.ok) {
+            typeLabels = (await response.json()).types || {};
+        }
+    } catch (error) {
+        console.error('Error loading detection names:', error);
+    }
+}
+
+// Display name of a detection type, custom types as they are
+function typeLabel(type) {
+    return typeLabels[type] || type;
+}
+
+// Show whether keyboard protection is working
+async function loadKeyboardStatus() {
+    try {
+        const response = await apiFetch(`${API_BASE}/keyboard`);
+        const status = await response.json();
+        const line = document.getElementById('keyboard_status');
+        if (!status.enabled) {
+            line.style.display = 'none';
+            return;
+        }
+        line.textContent = status.error
+            ? `⚠️ Keyboard protection is not working: ${status.error}`
+            : (status.active ? '✅ Keyboard protection is active' : '⏳ Keyboard protection is starting');
+        line.style.display = 'block';
+    } catch (error) {
+        console.error('Error loading keyboard status:', error);
+    }
+}
+
+// Show whether the circuit breaker tripped
+async function loadBreakerStatus() {
+    try {
+        const response = await apiFetch(`${API_BASE}/breaker`);
+        const status = await response.json();
+        const line = document.getElementById('breaker_status');
+        if (!status.tripped) {
+            line.style.display = 'none';
+            return;
+        }
+        const until = status.until ? `until ${new Date(status.until).toLocaleString()}` : 'until reset';
+        document.getElementById('breaker_status_text').textContent = `🚨 Circuit breaker tripped, every detector blocks ${until}`;
+        line.style.display = 'block';
+    } catch (error) {
+        console.error('Error loading circuit breaker status:', error);
+    }
+}
+
+// Close a tripped circuit breaker
+async function resetBreaker() {
+    try {
+        const response = await apiFetch(`${API_BASE}/breaker/reset`, { method: 'POST' });
+        if (!response.ok) {
+            showError(`Failed to reset: ${await errorMessage(response)}`);
+        }
+    } catch (error) {
+        console.error('Error resetting circuit breaker:', error);
+    }
+    loadBreakerStatus();
+}
+
 // Save configuration to server
 async function saveConfig(event) {
     event.preventDefault();
@@ -96,9 +319,29 @@ async function saveConfig(event) {
         detect_phones: document.getElementById('detect_phones').checked,
         detect_credit_cards: document.getElementById('detect_credit_cards').checked,
         detect_ssns: document.getElementById('detect_ssns').checked,
+        china_pack: document.getElementById('china_pack').checked,
         detect_ipv4: document.getElementById('detect_ipv4').checked,
+        detect_locations: document.getElementById('detect_locations').checked,
+        detect_terraform_secrets: document.getElementById('detect_terraform_secrets').checked,
+        detect_http_auth: document.getElementById('detect_http_auth').checked,
+        detect_cookies: document.getElementById('detect_cookies').checked,
+        sanitize_curl: document.getElementById('sanitize_curl').checked,
+        detect_home_paths: document.getElementById('detect_home_paths').checked,
+        detect_shell_prompts: document.getElementById('detect_shell_prompts').checked,
+        detect_infrastructure_ids: document.getElementById('detect_infrastructure_ids').checked,
+        detect_license_keys: document.getElementById('detect_license_keys').checked,
+        detect_mac_addresses: document.getElementById('detect_mac_addresses').checked,
+        detect_totp_secrets: document.getElementById('detect_totp_secrets').checked,
+        detect_recovery_codes: document.getElementById('detect_recovery_codes').checked,
+        detect_passwords: document.getElementById('detect_passwords').checked,
+        mac_allow_local: document.getElementById('mac_allow_local').checked,
+        normalize_detectors: document.getElementById('normalize_detectors').value.split(',').map(s => s.trim()).filter(s => s),
+        review_threshold: parseFloat(document.getElementById('review_threshold').value) || 0,
+        allowlist: lines('allowlist'),
+        review_confirmed: lines('review_confirmed'),
         
         string_match_patterns: [], // TODO: Add UI for string patterns
+        schedules: loadedSchedules,
         
         custom_email_pattern: document.getElementById('custom_email_pattern').value,
         custom_phone_pattern: document.getElementById('custom_phone_pattern').value,
@@ -108,17 +351,70 @@ async function saveConfig(event) {
         
         email_replacement: document.getElementById('email_replacement').value,
         phone_replacement: document.getElementById('phone_replacement').value,
+        phone_locales: document.getElementById('phone_locales').value.split(',').map(s => s.trim().toLowerCase()).filter(s => s),
         credit_card_replacement: document.getElementById('credit_card_replacement').value,
         ssn_replacement: document.getElementById('ssn_replacement').value,
+        ssn_locales: document.getElementById('ssn_locales').value.split(',').map(s => s.trim().toLowerCase()).filter(s => s),
         ipv4_replacement: document.getElementById('ipv4_replacement').value,
+        location_replacement: document.getElementById('location_replacement').value,
+        terraform_replacement: document.getElementById('terraform_replacement').value,
+        http_auth_replacement: document.getElementById('http_auth_replacement').value,
+        cookie_replacement: document.getElementById('cookie_replacement').value,
+        home_path_replacement: document.getElementById('home_path_replacement').value,
+        shell_prompt_replacement: document.getElementById('shell_prompt_replacement').value,
+        gcp_project_replacement: document.getElementById('gcp_project_replacement').value,
+        azure_id_replacement: document.getElementById('azure_id_replacement').value,
+        license_key_replacement: document.getElementById('license_key_replacement').value,
+        mac_replacement: document.getElementById('mac_replacement').value,
+        totp_replacement: document.getElementById('totp_replacement').value,
+        recovery_code_replacement: document.getElementById('recovery_code_replacement').value,
+        password_replacement: document.getElementById('password_replacement').value,
+        license_key_formats: document.getElementById('license_key_formats').value.split(',').map(s => s.trim()).filter(s => s),
         api_key_replacement: '',
         
+        ...Object.fromEntries(PRIORITY_FIELDS.map(id => [id, parseInt(document.getElementById(id).value) || 0])),
+        ...Object.fromEntries([...ACTION_FIELDS, ...SEVERITY_FIELDS].map(id => [id, document.getElementById(id).value])),
+        policies: Object.fromEntries(POLICY_PROFILES.map(profile => [profile, Object.fromEntries(
+            SEVERITIES.map(severity => [severity, document.getElementById(`policy_${profile}_${severity}`).value]))])),
+        block_message: document.getElementById('block_message').value,
+        ask_timeout_seconds: parseInt(document.getElementById('ask_timeout_seconds').value),
+
         monitoring_interval_ms: parseInt(document.getElementById('monitoring_interval_ms').value),
-        notify_on_filter: document.getElementById('notify_on_filter').checked
+        monitor_clipboard: document.getElementById('monitor_clipboard').checked,
+        monitor_primary_selection: document.getElementById('monitor_primary_selection').checked,
+        notify_on_filter: document.getElementById('notify_on_filter').checked,
+        max_clipboard_bytes: parseInt(document.getElementById('max_clipboard_bytes').value) || 0,
+        large_content_mode: document.getElementById('large_content_mode').value,
+        scan_timeout_ms: parseInt(document.getElementById('scan_timeout_ms').value),
+        incremental_scan: document.getElementById('incremental_scan').checked,
+        log_mode: document.getElementById('log_mode').value,
+        file_scan_mode: document.getElementById('file_scan_mode').value,
+        detect_special_categories: document.getElementById('detect_special_categories').checked,
+        prompt_safety_mode: document.getElementById('prompt_safety_mode').value,
+        classifier_mode: document.getElementById('classifier_mode').value,
+        classifier_endpoint: document.getElementById('classifier_endpoint').value.trim(),
+        breaker_threshold: parseInt(document.getElementById('breaker_threshold').value) || 0,
+        breaker_window_minutes: parseInt(document.getElementById('breaker_window_minutes').value),
+        breaker_cooldown_minutes: parseInt(document.getElementById('breaker_cooldown_minutes').value) || 0,
+        breaker_webhook: document.getElementById('breaker_webhook').value.trim(),
+        file_scan_max_bytes: parseInt(document.getElementById('file_scan_max_bytes').value),
+        keyboard_protection: document.getElementById('keyboard_protection').checked,
+        keyboard_consent: keyboardConsent,
+        keyboard_apps: document.getElementById('keyboard_apps').value.split(',').map(s => s.trim()).filter(s => s),
+        proxy_bypass: document.getElementById('proxy_bypass').value.split(',').map(s => s.trim()).filter(s => s),
+        usage_reporting: document.getElementById('usage_reporting').checked,
+        usage_endpoint: document.getElementById('usage_endpoint').value.trim(),
+        report_recipients: document.getElementById('report_recipients').value.split(',').map(s => s.trim()).filter(s => s),
+        smtp_host: document.getElementById('smtp_host').value.trim(),
+        smtp_port: parseInt(document.getElementById('smtp_port').value) || 0,
+        smtp_tls: document.getElementById('smtp_tls').value,
+        smtp_from: document.getElementById('smtp_from').value.trim(),
+        smtp_username: document.getElementById('smtp_username').value.trim(),
+        language: document.getElementById('language').value
     };
 
     try {
-        const response = await fetch(`${API_BASE}/api/config`, {
+        const response = await apiFetch(`${API_BASE}/config`, {
             method: 'POST',
             headers: {
                 'Content-Type': 'application/json'
@@ -128,9 +424,9 @@ async function saveConfig(event) {
 
         if (response.ok) {
             showSuccess('Configuration saved successfully!');
+            loadLabels();
         } else {
-            const error = await response.text();
-            showError(`Failed to save configuration: ${error}`);
+            showError(`Failed to save configuration: ${await errorMessage(response)}`);
         }
     } catch (error) {
         console.error('Error saving configuration:', error);
@@ -138,6 +434,19 @@ async function saveConfig(event) {
     }
 }
 
+// Extract the message from an API error envelope
+async function errorMessage(response) {
+    try {
+        const error = await response.json();
+        if (Array.isArray(error.details) && error.details.length > 0 && error.details[0].field) {
+            return error.details.map(d => `${d.field} ${d.message}`).join('; ');
+        }
+        return error.message || response.statusText;
+    } catch (e) {
+        return response.statusText;
+    }
+}
+
 // Pagination state
 let currentPage = 1;
 const pageSize = 10;
@@ -145,7 +454,7 @@ const pageSize = 10;
 // Load logs from server with pagination
 async function loadLogs(page = 1) {
     try {
-        const response = await fetch(`${API_BASE}/api/logs?page=${page}&pageSize=${pageSize}`);
+        const response = await apiFetch(`${API_BASE}/logs?page=${page}&pageSize=${pageSize}`);
         const data = await response.json();
 
         const container = document.getElementById('logs-container');
@@ -159,6 +468,7 @@ async function loadLogs(page = 1) {
             `;
             document.getElementById('total-logs').textContent = '0';
             document.getElementById('filtered-count').textContent = '0';
+            document.getElementById('leak-count').textContent = '0';
             updatePaginationButtons(1, 1);
             return;
         }
@@ -168,14 +478,28 @@ async function loadLogs(page = 1) {
 
         // Update statistics
         document.getElementById('total-logs').textContent = data.totalCount || 0;
-        const totalFiltered = logs.reduce((sum, log) => sum + (log.detections?.length || 0), 0);
-        document.getElementById('filtered-count').textContent = totalFiltered;
+        const countDetections = (list) => list.reduce((sum, log) => sum + (log.detections?.length || 0) * (log.count || 1), 0);
+        const isLeak = (log) => log.kind === 'outbound_leak';
+        document.getElementById('filtered-count').textContent = countDetections(logs.filter(log => !isLeak(log)));
+        document.getElementById('leak-count').textContent = countDetections(logs.filter(isLeak));
 
         // Render logs as table
         const tableRows = logs.map(log => {
             const timestamp = new Date(log.timestamp).toLocaleString();
             const detections = log.detections || [];
-            const detectionsText = detections.length > 0 ? detections.join(', ') : '-';
+            let detectionsText = detections.length > 0 ? detections.map(typeLabel).join(', ') : '-';
+            if (isLeak(log)) {
+                detectionsText = `↩️ Outbound leak: ${detectionsText}`;
+            }
+            if (log.risk_label && log.risk_label !== 'safe') {
+                detectionsText += ` · ⚠️ ${log.risk_label} (${Math.round((log.risk_score || 0) * 100)}%)`;
+            }
+            if (log.app) {
+                detectionsText += ` · from ${log.app}`;
+            }
+            const count = log.count || 1;
+            const seenText = count > 1 ? `×${count}` : '1';
+            const lastSeen = log.last_seen ? new Date(log.last_seen).toLocaleString() : timestamp;
             
             // Truncate text for display
             const originalText = log.original ? 
@@ -184,13 +508,15 @@ async function loadLogs(page = 1) {
             const filteredText = log.filtered.length > 50 ? 
                 log.filtered.substring(0, 50) + '...' : 
                 log.filtered;
+            const hashedMark = log.hashed ? '🔒 ' : '';
             
             return `
-                <tr>
+                <tr onclick="showLogDetail(${log.id})">
                     <td>${timestamp}</td>
-                    <td title="${escapeHtml(log.original || '')}">${escapeHtml(originalText)}</td>
-                    <td title="${escapeHtml(log.filtered)}">${escapeHtml(filteredText)}</td>
+                    <td title="${escapeHtml(log.original || '')}">${hashedMark}${escapeHtml(originalText)}</td>
+                    <td title="${escapeHtml(log.filtered)}">${hashedMark}${escapeHtml(filteredText)}</td>
                     <td>${escapeHtml(detectionsText)}</td>
+                    <td title="Last seen ${escapeHtml(lastSeen)}">${seenText}</td>
                 </tr>
             `;
         }).join('');
@@ -203,6 +529,7 @@ async function loadLogs(page = 1) {
                         <th>Original</th>
                         <th>Filtered</th>
                         <th>Detections</th>
+                        <th>Seen</th>
                     </tr>
                 </thead>
                 <tbody>
@@ -249,6 +576,50 @@ function nextPage() {
     loadLogs(currentPage + 1);
 }
 
+// Show a log entry side by side with every replacement highlighted
+async function showLogDetail(id) {
+    try {
+        const response = await apiFetch(`${API_BASE}/logs/${id}`);
+        if (!response.ok) {
+            showError(await errorMessage(response));
+            return;
+        }
+        const detail = await response.json();
+        const matches = detail.matches || [];
+
+        document.getElementById('log-detail-original').innerHTML = detail.hashed ?
+            escapeHtml(detail.original) :
+            highlightRanges(detail.original, matches.map(m => [m.start, m.end, m.type]), 'original');
+        document.getElementById('log-detail-filtered').innerHTML = detail.hashed ?
+            escapeHtml(detail.filtered) :
+            highlightRanges(detail.filtered, matches.map(m => [m.filtered_start, m.filtered_end, m.type]), 'filtered');
+        document.getElementById('log-detail').style.display = 'block';
+    } catch (error) {
+        console.error('Error loading log:', error);
+        showError('Failed to load log');
+    }
+}
+
+// Hide the log detail view
+function closeLogDetail() {
+    document.getElementById('log-detail').style.display = 'none';
+}
+
+// Wrap the given [start, end, type] byte ranges of text in <mark> elements.
+// Offsets from the server count UTF-8 bytes, so slice the encoded text.
+function highlightRanges(text, ranges, className) {
+    const bytes = new TextEncoder().encode(text);
+    const decoder = new TextDecoder();
+    let html = '';
+    let last = 0;
+    for (const [start, end, type] of ranges) {
+        html += escapeHtml(decoder.decode(bytes.slice(last, start)));
+        html += `<mark class="${className}" title="${escapeHtml(type)}">${escapeHtml(decoder.decode(bytes.slice(start, end)))}</mark>`;
+        last = end;
+    }
+    return html + escapeHtml(decoder.decode(bytes.slice(last)));
+}
+
 // Clear all logs
 async function clearLogs() {
     if (!confirm('Are you sure you want to clear all logs?')) {
@@ -256,7 +627,7 @@ async function clearLogs() {
     }
 
     try {
-        const response = await fetch(`${API_BASE}/api/logs/clear`, {
+        const response = await apiFetch(`${API_BASE}/logs/clear`, {
             method: 'POST'
         });
 
@@ -298,6 +669,83 @@ function escapeHtml(text) {
     return div.innerHTML;
 }
 
+// Show redacted copies whose original may still be restored
+async function loadHolds() {
+    try {
+        const response = await apiFetch(`${API_BASE}/monitor/holds`);
+        if (!response.ok) {
+            return;
+        }
+        const holds = await response.json();
+        document.getElementById('holds').innerHTML = holds.map(hold => {
+            const seconds = Math.max(0, Math.round((new Date(hold.expires_at) - Date.now()) / 1000));
+            return `<div class="hold-item">
+                🔐 Redacted ${escapeHtml(hold.types.join(', '))} in a copy (${escapeHtml(hold.source)}).
+                Restore the original within ${seconds}s?
+                <button type="button" onclick="restoreHold('${encodeURIComponent(hold.id)}')">Restore original</button>
+            </div>`;
+        }).join('');
+    } catch (error) {
+        console.error('Error loading held copies:', error);
+    }
+}
+
+// Put the original of a held copy back on the clipboard
+async function restoreHold(id) {
+    try {
+        const response = await apiFetch(`${API_BASE}/monitor/holds/${id}/restore`, { method: 'POST' });
+        if (!response.ok) {
+            showError(`Failed to restore: ${await errorMessage(response)}`);
+        }
+    } catch (error) {
+        console.error('Error restoring held copy:', error);
+    }
+    loadHolds();
+}
+
+// Split a textarea into its non-empty lines
+function lines(id) {
+    return document.getElementById(id).value.split('\n').map(s => s.trim()).filter(s => s);
+}
+
+// Load the matches waiting for review
+async function loadReview() {
+    try {
+        const response = await apiFetch(`${API_BASE}/review?pageSize=100`);
+        if (!response.ok) {
+            return;
+        }
+        const page = await response.json();
+        const container = document.getElementById('review-container');
+        if (page.items.length === 0) {
+            container.innerHTML = '<div class="empty-state"><p>Nothing to review.</p></div>';
+            return;
+        }
+        container.innerHTML = page.items.map(item => `<div class="hold-item">
+            ${escapeHtml(item.type)} <code>${escapeHtml(item.value)}</code>
+            (confidence ${item.confidence.toFixed(2)}, seen ${item.count}×)
+            <button type="button" onclick="decideReview(${item.id}, 'confirm')">Confirm</button>
+            <button type="button" class="secondary" onclick="decideReview(${item.id}, 'ignore')">Ignore</button>
+        </div>`).join('');
+    } catch (error) {
+        console.error('Error loading review queue:', error);
+    }
+}
+
+// Confirm or ignore a queued match
+async function decideReview(id, decision) {
+    try {
+        const response = await apiFetch(`${API_BASE}/review/${id}/${decision}`, { method: 'POST' });
+        if (!response.ok) {
+            showError(`Failed to ${decision}: ${await errorMessage(response)}`);
+        }
+    } catch (error) {
+        console.error('Error deciding review item:', error);
+    }
+    loadReview();
+    loadConfig();
+}
+
 // Auto-refresh logs every 5 seconds when on logs tab
 let autoRefreshInterval;
 function startAutoRefresh() {
@@ -313,6 +761,7 @@ function startAutoRefresh() {
 document.addEventListener('DOMContentLoaded', () => {
     // Load initial configuration
     loadConfig();
+    loadLabels();
 
     // Setup form submission
     document.getElementById('config-form').addEventListener('submit', saveConfig);
@@ -325,4 +774,8 @@ document.addEventListener('DOMContentLoaded', () => {
 
     // Start auto-refresh for logs
     startAutoRefresh();
+
+    // Check for copies awaiting approval
+    loadHolds();
+    setInterval(loadHolds, 1000);
 });
@@ -0,0 +1,106 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/happytaoer/prompt-security/internal/db"
+)
+
+// summaryCache holds today's detections while the logs are unchanged, so
+// frequent polling of the summary only reads a db.LogMarker. Logs written by
+// other processes, such as the gateway, change the marker too.
+type summaryCache struct {
+	mu      sync.Mutex
+	loaded  bool
+	day     string // Local date the summary is for
+	marker  db.LogMarker
+	summary db.DetectionSummary
+}
+
+// get returns today's detections and the time of the latest event,
+// reading them from the database when the logs or the day changed
+func (c *summaryCache) get(now time.Time) (db.DetectionSummary, error) {
+	marker, err := db.GetLogMarker()
+	if err != nil {
+		return db.DetectionSummary{}, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	day := now.Format("2006-01-02")
+	if c.loaded && c.day == day && c.marker == marker {
+		return c.summary, nil
+	}
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	summary, err := db.GetDetectionSummary(midnight)
+	if err != nil {
+		return db.DetectionSummary{}, err
+	}
+	c.loaded, c.day, c.marker, c.summary = true, day, marker, summary
+	return summary, nil
+}
+
+// handleStatsSummary reports today's detections and the monitor state for
+// tray apps and widgets that poll frequently
+func (s *Server) handleStatsSummary(w http.ResponseWriter, r *http.Request) {
+	today, err := s.summary.get(time.Now())
+	if err != nil {
+		s.writeFailure(w, err, "Failed to retrieve summary")
+		return
+	}
+
+	summary := StatsSummary{DetectionsToday: today.Detections, LeaksToday: today.Leaks, Monitor: MonitorStopped}
+	if today.LastEvent != nil {
+		summary.LastEventAt = today.LastEvent.Format(time.RFC3339)
+	}
+	if s.monitor != nil {
+		summary.Monitor = MonitorRunning
+		if s.monitor.Paused() {
+			summary.Monitor = MonitorPaused
+		}
+		summary.Health = s.monitor.Health().Status
+	}
+	snapshot := s.configManager.Snapshot()
+	summary.Profile, summary.Lockdown = snapshot.Profile, snapshot.Lockdown
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	json.NewEncoder(w).Encode(summary)
+}
+
+// handleStatsApps reports the detections per application the text came
+// from, so the leakiest tools stand out
+func (s *Server) handleStatsApps(w http.ResponseWriter, r *http.Request) {
+	counts, err := db.GetAppDetectionCounts()
+	if err != nil {
+		s.writeFailure(w, err, "Failed to retrieve statistics")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(appStats(counts))
+}
+
+// appStats lists detection counts per application and type, most
+// detections first
+func appStats(counts map[string]map[string]int) []AppStats {
+	stats := make([]AppStats, 0, len(counts))
+	for app, types := range counts {
+		total := 0
+		for _, n := range types {
+			total += n
+		}
+		stats = append(stats, AppStats{App: app, Detections: total, Types: types})
+	}
+	sort.Slice(stats, func(i, j int) bool {
+		if stats[i].Detections != stats[j].Detections {
+			return stats[i].Detections > stats[j].Detections
+		}
+		return stats[i].App < stats[j].App
+	})
+	return stats
+}
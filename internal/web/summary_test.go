@@ -0,0 +1,65 @@
+package web
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+	"github.com/happytaoer/prompt-security/internal/db"
+)
+
+// TestSummaryCache tests that the summary follows logs written by other
+// processes, repeats and clearing, counting detections and leaks apart
+func TestSummaryCache(t *testing.T) {
+	db.SetPath(db.MemoryPath)
+	if err := config.Initialize(); err != nil {
+		t.Fatalf("Failed to initialize database: %v", err)
+	}
+	defer config.Close()
+
+	c := &summaryCache{}
+	expect := func(detections, leaks int) {
+		t.Helper()
+		today, err := c.get(time.Now())
+		if err != nil {
+			t.Fatalf("Unexpected error: %v", err)
+		}
+		if today.Detections != detections || today.Leaks != leaks {
+			t.Errorf("Expected %d detections and %d leaks, got %+v", detections, leaks, today)
+		}
+	}
+	email := []db.LogMatch{{Type: "email"}}
+
+	expect(0, 0)
+	// Written straight to the database, as by another process
+	if _, err := db.AddLog(db.LogKindPrompt, "", "a@b.io", "[EMAIL]", email); err != nil {
+		t.Fatal(err)
+	}
+	expect(1, 0)
+	db.AddLog(db.LogKindPrompt, "", "a@b.io", "[EMAIL]", email) // A repeat
+	expect(2, 0)
+	db.AddLog(db.LogKindLeak, "", "c@d.io", "[EMAIL]", email)
+	expect(2, 1)
+	if err := db.ClearLogs(); err != nil {
+		t.Fatal(err)
+	}
+	expect(0, 0)
+}
+
+// TestAppStats tests totaling detections per application, most first
+func TestAppStats(t *testing.T) {
+	stats := appStats(map[string]map[string]int{
+		"slack":   {"email": 2},
+		"firefox": {"email": 1, "phone": 3},
+		"":        {"ssn": 2},
+	})
+	var got []string
+	for _, s := range stats {
+		got = append(got, fmt.Sprintf("%s=%d", s.App, s.Detections))
+	}
+	if want := "firefox=4, =2, slack=2"; strings.Join(got, ", ") != want {
+		t.Errorf("Expected %s, got %s", want, strings.Join(got, ", "))
+	}
+}
@@ -0,0 +1,258 @@
+package web
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+	"github.com/happytaoer/prompt-security/internal/filter"
+	"github.com/happytaoer/prompt-security/internal/scan"
+)
+
+const (
+	// maxDenialFindings bounds the findings named in a denial message
+	maxDenialFindings = 5
+
+	// maxValidateBytes bounds the body of a validate request
+	maxValidateBytes = 32 << 20
+)
+
+// admissionReview is a Kubernetes AdmissionReview, accepted by the validate
+// endpoint so it can serve as a validating admission webhook
+type admissionReview struct {
+	APIVersion string             `json:"apiVersion"`
+	Kind       string             `json:"kind"`
+	Request    *admissionRequest  `json:"request,omitempty"`
+	Response   *admissionResponse `json:"response,omitempty"`
+}
+
+type admissionRequest struct {
+	UID       string      `json:"uid"`
+	Name      string      `json:"name"`
+	Namespace string      `json:"namespace"`
+	Object    interface{} `json:"object"`
+}
+
+type admissionResponse struct {
+	UID      string           `json:"uid"`
+	Allowed  bool             `json:"allowed"`
+	Status   *admissionStatus `json:"status,omitempty"`
+	Warnings []string         `json:"warnings,omitempty"`
+}
+
+type admissionStatus struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// handleValidate checks content from a build system or an admission
+// controller against the policy, denying it if a detector would block,
+// redact or ask about it. Nothing is logged.
+func (s *Server) handleValidate(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxValidateBytes))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		writeError(w, http.StatusRequestEntityTooLarge, ErrCodeInvalidRequest, "Body too large", map[string]string{"limit": fmt.Sprint(tooLarge.Limit)})
+		return
+	}
+	if err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "Failed to read body", err.Error())
+		return
+	}
+	var review admissionReview
+	if json.Unmarshal(body, &review) == nil && review.Kind == "AdmissionReview" {
+		s.handleAdmissionReview(w, review)
+		return
+	}
+
+	var req ValidateRequest
+	if err := json.Unmarshal(body, &req); err != nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "Invalid JSON body", err.Error())
+		return
+	}
+	files := req.Files
+	if req.Content != "" || len(files) == 0 {
+		files = append([]ValidateFile{{Name: req.Name, Content: req.Content, Encoding: req.Encoding}}, files...)
+	}
+
+	detectors := s.engine.Detectors()
+	response := ValidateResponse{UID: req.UID, Findings: []ValidateFinding{}, Metadata: req.Metadata}
+	for _, f := range files {
+		content := f.Content
+		switch f.Encoding {
+		case "", "utf-8":
+		case "base64":
+			decoded, err := base64.StdEncoding.DecodeString(f.Content)
+			if err != nil {
+				writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "Invalid base64 content", map[string]string{"name": f.Name, "error": err.Error()})
+				return
+			}
+			content = string(decoded)
+		default:
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "Unknown encoding, expected base64", map[string]string{"name": f.Name, "encoding": f.Encoding})
+			return
+		}
+		if !utf8.ValidString(content) {
+			writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "Content is not UTF-8 text", map[string]string{"name": f.Name})
+			return
+		}
+		findings, suppressed := findingsIn(detectors, f.Name, "", content)
+		response.Findings = append(response.Findings, findings...)
+		response.Suppressed = append(response.Suppressed, suppressed...)
+	}
+	response.Allowed, response.Reason = decide(response.Findings)
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(response)
+}
+
+// handleAdmissionReview validates the string fields of the object of an
+// AdmissionReview, e.g. the data of a ConfigMap holding prompts. The data of
+// a Secret is scanned decoded.
+func (s *Server) handleAdmissionReview(w http.ResponseWriter, review admissionReview) {
+	if review.Request == nil {
+		writeError(w, http.StatusBadRequest, ErrCodeInvalidRequest, "AdmissionReview without a request", nil)
+		return
+	}
+	name := review.Request.Name
+	if review.Request.Namespace != "" {
+		name = review.Request.Namespace + "/" + name
+	}
+
+	decodeSecretData(review.Request.Object)
+	detectors := s.engine.Detectors()
+	var findings []ValidateFinding
+	walkStrings(review.Request.Object, "", func(path, value string) {
+		found, _ := findingsIn(detectors, name, path, value)
+		findings = append(findings, found...)
+	})
+	allowed, reason := decide(findings)
+
+	response := &admissionResponse{UID: review.Request.UID, Allowed: allowed}
+	if !allowed {
+		response.Status = &admissionStatus{Code: http.StatusForbidden, Message: reason}
+	} else if len(findings) > 0 {
+		response.Warnings = []string{reason}
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(admissionReview{APIVersion: review.APIVersion, Kind: review.Kind, Response: response})
+}
+
+// decodeSecretData decodes in place the base64 values of the data of object
+// if it is a Secret. Values that are not base64 are left as they are.
+func decodeSecretData(object interface{}) {
+	fields, ok := object.(map[string]interface{})
+	if !ok || fields["kind"] != "Secret" {
+		return
+	}
+	data, ok := fields["data"].(map[string]interface{})
+	if !ok {
+		return
+	}
+	for key, value := range data {
+		encoded, ok := value.(string)
+		if !ok {
+			continue
+		}
+		if decoded, err := base64.StdEncoding.DecodeString(encoded); err == nil {
+			data[key] = string(decoded)
+		}
+	}
+}
+
+// findingsIn scans text and returns where its sensitive data is, and the
+// findings exempted by ps:ignore annotations
+func findingsIn(detectors *filter.DetectorSet, file, path, text string) ([]ValidateFinding, []ValidateFinding) {
+	_, _, summary := detectors.Filter(text)
+	suppressions := scan.ParseSuppressions(text)
+	findings := make([]ValidateFinding, 0, len(summary.Replacements))
+	var suppressed []ValidateFinding
+	for _, rep := range summary.Replacements {
+		line, column := scan.Locate(text, rep.Start)
+		finding := ValidateFinding{
+			File:   file,
+			Path:   path,
+			Line:   line,
+			Column: column,
+			Type:   rep.Type,
+			Action: actionOf(rep),
+		}
+		if suppressions.Covers(line, rep.Type) {
+			suppressed = append(suppressed, finding)
+		} else {
+			findings = append(findings, finding)
+		}
+	}
+	return findings, suppressed
+}
+
+// decide denies content with findings a detector blocks, redacts or asks
+// about, and explains the decision
+func decide(findings []ValidateFinding) (bool, string) {
+	if len(findings) == 0 {
+		return true, ""
+	}
+	var denying, others []string
+	for _, f := range findings {
+		where := f.Type + " at "
+		if f.File != "" {
+			where += f.File + ":"
+		}
+		if f.Path != "" {
+			where += f.Path + ":"
+		}
+		where += fmt.Sprintf("%d:%d", f.Line, f.Column)
+		if f.Action == config.ActionBlock || f.Action == config.ActionReplace || f.Action == config.ActionAsk {
+			denying = append(denying, where)
+		} else {
+			others = append(others, where)
+		}
+	}
+	if len(denying) == 0 {
+		return true, "Sensitive data allowed by the policy: " + summarize(others)
+	}
+	return false, "Sensitive data found: " + summarize(denying)
+}
+
+// summarize lists the first findings and counts the rest
+func summarize(findings []string) string {
+	if len(findings) <= maxDenialFindings {
+		return strings.Join(findings, ", ")
+	}
+	return fmt.Sprintf("%s and %d more", strings.Join(findings[:maxDenialFindings], ", "), len(findings)-maxDenialFindings)
+}
+
+// walkStrings calls fn with every string in a decoded JSON value and its
+// dotted path, in a stable order, skipping bookkeeping metadata
+func walkStrings(value interface{}, path string, fn func(path, value string)) {
+	switch v := value.(type) {
+	case string:
+		fn(path, v)
+	case []interface{}:
+		for i, item := range v {
+			walkStrings(item, fmt.Sprintf("%s[%d]", path, i), fn)
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			if k != "managedFields" {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			child := k
+			if path != "" {
+				child = path + "." + k
+			}
+			walkStrings(v[k], child, fn)
+		}
+	}
+}
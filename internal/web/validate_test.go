@@ -0,0 +1,90 @@
+package web
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/happytaoer/prompt-security/internal/config"
+	"github.com/happytaoer/prompt-security/internal/filter"
+)
+
+// TestValidate tests allowing and denying files by the actions of the
+// detectors matching them
+func TestValidate(t *testing.T) {
+	cfg := config.Config{DetectEmails: true, EmailReplacement: "[EMAIL]", EmailAction: config.ActionLog, DetectSSNs: true, SSNReplacement: "XXX-XX-XXXX"}
+	s := NewServer(config.NewStaticManager(cfg), filter.NewEngine(cfg))
+
+	var resp ValidateResponse
+	if code := postJSON(t, s.handleValidate, `{"uid":"1","content":"hello","metadata":{"commit":"abc"}}`, &resp); code != http.StatusOK || !resp.Allowed || resp.UID != "1" || resp.Metadata["commit"] != "abc" || len(resp.Findings) != 0 {
+		t.Errorf("Expected clean content to be allowed, got %d: %+v", code, resp)
+	}
+	if code := postJSON(t, s.handleValidate, `{"name":"a.txt","content":"mail ann@example.com"}`, &resp); code != http.StatusOK || !resp.Allowed || len(resp.Findings) != 1 || resp.Reason == "" {
+		t.Errorf("Expected logged data to be allowed with a finding, got %d: %+v", code, resp)
+	}
+
+	// "line\n🙂 ssn 123-45-6789" in base64
+	body := `{"files":[{"name":"a.txt","content":"hi"},{"name":"b.txt","content":"bGluZQrwn5mCIHNzbiAxMjMtNDUtNjc4OQ==","encoding":"base64"}]}`
+	if code := postJSON(t, s.handleValidate, body, &resp); code != http.StatusOK || resp.Allowed || len(resp.Findings) != 1 {
+		t.Fatalf("Expected redacted data to be denied, got %d: %+v", code, resp)
+	}
+	if f := resp.Findings[0]; f != (ValidateFinding{File: "b.txt", Line: 2, Column: 7, Type: "ssn", Action: config.ActionReplace}) || !strings.Contains(resp.Reason, "ssn at b.txt:2:7") {
+		t.Errorf("Expected the finding to be located, got %+v %q", f, resp.Reason)
+	}
+	if strings.Contains(resp.Reason, "123") {
+		t.Errorf("Expected no values in the reason, got %q", resp.Reason)
+	}
+
+	// Annotated fixtures are reported apart and do not deny the content
+	resp = ValidateResponse{}
+	body = `{"name":"fixture_test.go","content":"// ps:ignore-next-line rule=ssn\nconst ssn = \"123-45-6789\""}`
+	if code := postJSON(t, s.handleValidate, body, &resp); code != http.StatusOK || !resp.Allowed || len(resp.Findings) != 0 || len(resp.Suppressed) != 1 || resp.Suppressed[0].Line != 2 {
+		t.Errorf("Expected the annotated value to be suppressed, got %d: %+v", code, resp)
+	}
+
+	if code := postJSON(t, s.handleValidate, `{"content":"//79","encoding":"base64"}`, nil); code != http.StatusBadRequest {
+		t.Errorf("Expected binary content to be rejected, got %d", code)
+	}
+	large := `{"content":"` + strings.Repeat("a", maxValidateBytes) + `"}`
+	if code := postJSON(t, s.handleValidate, large, nil); code != http.StatusRequestEntityTooLarge {
+		t.Errorf("Expected a body over the limit to be rejected, got %d", code)
+	}
+}
+
+// TestValidate_Ask tests that data a detector asks about is denied
+func TestValidate_Ask(t *testing.T) {
+	cfg := config.Config{DetectEmails: true, EmailReplacement: "[EMAIL]", EmailAction: config.ActionAsk}
+	s := NewServer(config.NewStaticManager(cfg), filter.NewEngine(cfg))
+
+	var resp ValidateResponse
+	if code := postJSON(t, s.handleValidate, `{"content":"mail ann@example.com"}`, &resp); code != http.StatusOK || resp.Allowed || len(resp.Findings) != 1 {
+		t.Errorf("Expected the email to be denied, got %d: %+v", code, resp)
+	}
+}
+
+// TestValidate_AdmissionReview tests answering Kubernetes admission reviews
+func TestValidate_AdmissionReview(t *testing.T) {
+	cfg := config.Config{DetectSSNs: true, SSNReplacement: "XXX-XX-XXXX"}
+	s := NewServer(config.NewStaticManager(cfg), filter.NewEngine(cfg))
+
+	body := `{"apiVersion":"admission.k8s.io/v1","kind":"AdmissionReview","request":{"uid":"u1","name":"prompts","namespace":"ai",
+		"object":{"kind":"ConfigMap","data":{"system":"ok","user":["x","ssn 123-45-6789"]}}}}`
+	var review admissionReview
+	if code := postJSON(t, s.handleValidate, body, &review); code != http.StatusOK || review.Kind != "AdmissionReview" || review.Response == nil {
+		t.Fatalf("Expected an admission review, got %d: %+v", code, review)
+	}
+	if r := review.Response; r.UID != "u1" || r.Allowed || r.Status == nil || r.Status.Code != http.StatusForbidden || !strings.Contains(r.Status.Message, "ssn at ai/prompts:data.user[1]:1:5") {
+		t.Errorf("Expected the object to be denied, got %+v %+v", r, r.Status)
+	}
+
+	// "ssn 123-45-6789" in base64, as Secrets hold their data
+	body = `{"apiVersion":"admission.k8s.io/v1","kind":"AdmissionReview","request":{"uid":"u2","name":"keys","namespace":"ai",
+		"object":{"kind":"Secret","data":{"token":"c3NuIDEyMy00NS02Nzg5"}}}}`
+	review = admissionReview{}
+	if code := postJSON(t, s.handleValidate, body, &review); code != http.StatusOK || review.Response == nil {
+		t.Fatalf("Expected an admission review, got %d: %+v", code, review)
+	}
+	if r := review.Response; r.Allowed || r.Status == nil || !strings.Contains(r.Status.Message, "ssn at ai/keys:data.token:1:5") {
+		t.Errorf("Expected the decoded secret to be denied, got %+v %+v", r, r.Status)
+	}
+}
@@ -1,56 +1,315 @@
 package main
 
 import (
+	"context"
+	"errors"
 	"fmt"
-	"log"
+	"log/slog"
+	"net"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
+	"github.com/happytaoer/prompt-security/internal/breaker"
+	"github.com/happytaoer/prompt-security/internal/classifier"
 	"github.com/happytaoer/prompt-security/internal/config"
+	"github.com/happytaoer/prompt-security/internal/ctl"
+	"github.com/happytaoer/prompt-security/internal/db"
+	"github.com/happytaoer/prompt-security/internal/demo"
+	"github.com/happytaoer/prompt-security/internal/filter"
+	"github.com/happytaoer/prompt-security/internal/instance"
+	"github.com/happytaoer/prompt-security/internal/keyboard"
 	"github.com/happytaoer/prompt-security/internal/monitor"
+	"github.com/happytaoer/prompt-security/internal/report"
+	"github.com/happytaoer/prompt-security/internal/telemetry"
+	"github.com/happytaoer/prompt-security/internal/usage"
 	"github.com/happytaoer/prompt-security/internal/web"
 	"github.com/spf13/cobra"
 )
 
+// version is set at build time with -ldflags "-X main.version=..."
+var version = "dev"
+
 func main() {
-	// Initialize database
-	if err := config.Initialize(); err != nil {
-		log.Fatalf("Failed to initialize database: %v", err)
+	os.Exit(run())
+}
+
+// run runs the command line and returns the exit code. Errors are returned
+// up to here rather than exiting where they occur, so deferred cleanups such
+// as flushing telemetry run on every path.
+func run() int {
+	// Export traces and metrics when an OTLP endpoint is configured
+	shutdownTelemetry, err := telemetry.Setup(context.Background())
+	if err != nil {
+		fmt.Printf("Failed to set up telemetry: %v\n", err)
+		return 1
 	}
-	defer config.Close()
+	defer shutdownTelemetry(context.Background())
 
 	var rootCmd = &cobra.Command{
 		Use:   "prompt-security",
 		Short: "Monitor clipboard for sensitive data",
 		Long:  `A tool that monitors clipboard content and filters sensitive data before it's sent to language models.`,
-		Run: func(cmd *cobra.Command, args []string) {
+		PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+			// Flags not given fall back to PROMPT_SECURITY_* variables
+			if err := applyEnv(cmd); err != nil {
+				return err
+			}
+
+			// Initialize database
+			dataDir, _ := cmd.Flags().GetString("data-dir")
+			db.SetDataDir(dataDir)
+			dbPath, _ := cmd.Flags().GetString("db")
+			noPersist, _ := cmd.Flags().GetBool("no-persist")
+			if demoMode, _ := cmd.Flags().GetBool("demo"); demoMode || noPersist {
+				dbPath = db.MemoryPath
+			}
+			db.SetPath(dbPath)
+			if err := config.Initialize(); err != nil {
+				return fmt.Errorf("failed to initialize database: %v", err)
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			cmd.SilenceUsage = true
 			port, _ := cmd.Flags().GetString("port")
-			addr := "localhost:" + port
+			bind, _ := cmd.Flags().GetString("bind")
+			addr := net.JoinHostPort(bind, port)
+			if demoMode, _ := cmd.Flags().GetBool("demo"); demoMode {
+				return runDemo(addr)
+			}
+			if err := checkBind(bind); err != nil {
+				return err
+			}
+			interval, _ := cmd.Flags().GetInt("monitoring-interval")
+			if interval != 0 && (interval < config.MinMonitoringInterval || interval > config.MaxMonitoringInterval) {
+				return fmt.Errorf("--monitoring-interval must be between %d and %d", config.MinMonitoringInterval, config.MaxMonitoringInterval)
+			}
+
+			// Only one instance may watch the clipboard. Without persistence
+			// nothing may be written to disk, so neither the lock file nor
+			// the control socket is created.
+			var cleanups []func() error
+			if db.Persistent() {
+				takeover, _ := cmd.Flags().GetBool("takeover")
+				lock, err := lockInstance(addr, takeover)
+				if err != nil {
+					return err
+				}
+				defer lock.Release()
+				cleanups = append(cleanups, lock.Release)
+			}
 
 			// Create config manager for dynamic reload
 			configManager, err := config.NewManager()
 			if err != nil {
-				log.Fatalf("Failed to create config manager: %v", err)
+				return fmt.Errorf("failed to create config manager: %v", err)
 			}
+			if completedAt, changed, err := config.SetupState(); err == nil && completedAt == nil && !changed && db.Persistent() {
+				fmt.Println("👋 First run: choose detectors and replacements with `prompt-security init`")
+			}
+			if interval != 0 {
+				configManager.Override(func(cfg *config.Config) {
+					cfg.MonitoringInterval = interval
+				})
+			}
+
+			// Compile detectors once and recompile whenever the configuration
+			// or the scheduled profile changes
+			engine := filter.NewManagedEngine(configManager)
+			go configManager.RunScheduler()
+
+			// Pick up configuration changes made by other processes
+			go configManager.Watch()
+
+			// Fall back to the built-in pattern for custom patterns that keep
+			// exceeding their time budget
+			logger := slog.New(slog.NewJSONHandler(os.Stdout, nil))
+			engine.OnPatternDisabled(func(field string) {
+				logger.Warn("Custom pattern repeatedly exceeded its time budget and was disabled", "field", field)
+				if err := configManager.DisablePattern(field, "system"); err != nil {
+					logger.Error("Failed to disable custom pattern", "field", field, "error", err)
+				}
+			})
 
 			// Create web server with config manager
-			webServer := web.NewServer(configManager)
+			webServer := web.NewServer(configManager, engine)
 
 			// Start monitoring in background with dynamic config reload
-			go monitor.ClipboardWithManager(configManager, webServer.AddLog)
+			clipboardMonitor := monitor.New(configManager, engine, webServer.AddAppLog)
+			webServer.SetMonitor(clipboardMonitor)
+			configManager.OnProfileChange(clipboardMonitor.SetProfile)
+			go clipboardMonitor.Run()
+
+			// Accept commands from `prompt-security ctl`
+			if db.Persistent() {
+				if dir, err := db.DataDir(); err == nil {
+					ctlServer, err := ctl.Listen(ctl.SocketPath(dir), ctlHandlers(configManager, clipboardMonitor, addr))
+					if err != nil {
+						logger.Warn("Control socket unavailable", "error", err)
+					} else {
+						defer ctlServer.Close()
+						cleanups = append(cleanups, ctlServer.Close)
+						go ctlServer.Serve()
+					}
+				}
+			}
+			cleanupOnSignal(append(cleanups, func() error { return shutdownTelemetry(context.Background()) })...)
+
+			// Warn about secrets typed into AI apps once enabled
+			keyboardGuard := keyboard.New(configManager, engine)
+			webServer.SetKeyboard(keyboardGuard)
+			go keyboardGuard.Run()
+
+			// Send anonymous usage statistics once enabled
+			usageReporter := usage.New(configManager, version)
+			webServer.SetUsage(usageReporter)
+			go usageReporter.Run()
+
+			// E-mail the weekly report once recipients are configured
+			go report.NewMailer(configManager).Run()
+
+			// Label logged events with their risk once a classifier is set
+			riskClassifier := classifier.New(configManager)
+			webServer.SetClassifier(riskClassifier)
+			go riskClassifier.Run()
+
+			// Block everything once high severity detections pile up, if
+			// the circuit breaker is enabled
+			circuitBreaker := breaker.New(configManager)
+			webServer.SetBreaker(circuitBreaker)
+			go circuitBreaker.Run()
 
 			// Start web server (blocking)
 			if err := webServer.Start(addr); err != nil {
-				log.Fatalf("Failed to start web server: %v", err)
+				return fmt.Errorf("failed to start web server: %v", err)
 			}
+			return nil
 		},
 	}
 
-	// Add flags (root command controls GUI port)
+	// Add flags (root command controls GUI port). Each flag in envFlags can
+	// also be set with its PROMPT_SECURITY_* variable.
 	rootCmd.PersistentFlags().String("port", "8181", "Port for web server")
+	rootCmd.PersistentFlags().String("data-dir", "", "Directory for the database, instance lock and control socket (default ~/.prompt-security)")
+	rootCmd.PersistentFlags().String("db", "", "Database file (default config.db in the data directory)")
+	rootCmd.PersistentFlags().Bool("no-persist", false, "Write nothing to disk: keep the database in memory, losing logs and changes on exit")
+	rootCmd.Flags().String("bind", "localhost", "Address the web server listens on")
+	rootCmd.Flags().Int("monitoring-interval", 0, "Clipboard polling interval in milliseconds, overriding the configuration")
+	rootCmd.Flags().Bool("demo", false, "Serve the web UI over sample data in memory, without clipboard access or changes")
+	rootCmd.Flags().Bool("takeover", false, "Stop an instance that is already running and start this one instead")
+
+	rootCmd.AddCommand(newInitCmd())
+	rootCmd.AddCommand(newTokenCmd())
+	rootCmd.AddCommand(newBenchCmd())
+	rootCmd.AddCommand(newLogsCmd())
+	rootCmd.AddCommand(newCtlCmd())
+	rootCmd.AddCommand(newGatewayCmd())
+	rootCmd.AddCommand(newProxyCmd())
+	rootCmd.AddCommand(newLSPCmd())
+	rootCmd.AddCommand(newBotCmd())
+	rootCmd.AddCommand(newMilterCmd())
+	rootCmd.AddCommand(newNativeHostCmd())
+	rootCmd.AddCommand(newScanCmd())
+	rootCmd.AddCommand(newReportCmd())
+	rootCmd.AddCommand(newPatternsCmd())
 
 	// Execute
-	if err := rootCmd.Execute(); err != nil {
+	err = rootCmd.Execute()
+	config.Close()
+	if err != nil {
 		fmt.Println(err)
-		os.Exit(1)
+		return 1
+	}
+	return 0
+}
+
+// checkBind refuses to serve the API beyond this machine while it lets
+// anyone in, which it does until an API token is created. The demo, which
+// serves sample data read-only, is not checked.
+func checkBind(bind string) error {
+	if loopback(bind) {
+		return nil
 	}
+	count, err := db.CountAPITokens()
+	if err != nil {
+		return fmt.Errorf("failed to count API tokens: %v", err)
+	}
+	if count == 0 {
+		return fmt.Errorf("refusing to listen on %q without authentication: create an API token with `prompt-security token create` first, or bind to localhost", bind)
+	}
+	return nil
+}
+
+// loopback reports whether host only accepts connections from this machine
+func loopback(host string) bool {
+	host = strings.Trim(host, "[]")
+	if strings.EqualFold(host, "localhost") {
+		return true
+	}
+	ip := net.ParseIP(host)
+	return ip != nil && ip.IsLoopback()
+}
+
+// runDemo serves the web UI and API over sample data in the in-memory
+// database. The clipboard is never touched and changes are rejected, so the
+// dashboard can be evaluated safely, even next to a running instance.
+func runDemo(addr string) error {
+	configManager, err := config.NewManager()
+	if err != nil {
+		return fmt.Errorf("failed to create config manager: %v", err)
+	}
+	engine := filter.NewManagedEngine(configManager)
+
+	webServer := web.NewServer(configManager, engine)
+	if err := demo.Seed(configManager, engine, webServer.AddLog); err != nil {
+		return fmt.Errorf("failed to seed demo data: %v", err)
+	}
+	webServer.SetReadOnly(true)
+
+	fmt.Println("🧪 Demo mode: sample data only, the clipboard is not monitored and changes are disabled")
+	if err := webServer.Start(addr); err != nil {
+		return fmt.Errorf("failed to start web server: %v", err)
+	}
+	return nil
+}
+
+// lockInstance makes this the only running instance. If another instance is
+// running, its web UI is opened and an error returned, unless takeover is
+// set, which stops the other instance instead.
+func lockInstance(addr string, takeover bool) (*instance.Lock, error) {
+	dir, err := db.DataDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock instance: %v", err)
+	}
+
+	lock, err := instance.Acquire(dir, instance.Info{PID: os.Getpid(), Addr: addr}, takeover)
+	var running *instance.RunningError
+	if errors.As(err, &running) {
+		fmt.Printf("Prompt Security is already running (pid %d), opening its web UI at %s\n", running.Info.PID, running.Info.URL())
+		if err := instance.OpenBrowser(running.Info.URL()); err != nil {
+			fmt.Printf("Failed to open a browser: %v\n", err)
+		}
+		return nil, errors.New("use --takeover to stop it and start this one instead")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to lock instance: %v", err)
+	}
+
+	return lock, nil
+}
+
+// cleanupOnSignal runs cleanups and exits when the process is interrupted
+// or terminated, including by another instance taking over
+func cleanupOnSignal(cleanups ...func() error) {
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-signals
+		for _, cleanup := range cleanups {
+			cleanup()
+		}
+		os.Exit(0)
+	}()
 }